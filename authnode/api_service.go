@@ -15,6 +15,7 @@
 package authnode
 
 import (
+	"crypto/rand"
 	"encoding/json"
 	"net/http"
 	"strconv"
@@ -32,6 +33,9 @@ import (
 
 const (
 	nodeType = "auth"
+
+	// the size of the data keys, which are AES-256 keys
+	dataKeySize = 32
 )
 
 func (m *Server) getTicket(w http.ResponseWriter, r *http.Request) {
@@ -439,6 +443,149 @@ func (m *Server) osCapsOp(w http.ResponseWriter, r *http.Request) {
 	return
 }
 
+func (m *Server) dataKeyOp(w http.ResponseWriter, r *http.Request) {
+	var (
+		plaintext []byte
+		err       error
+		jobj      proto.AuthDataKeyReq
+		ticket    cryptoutil.Ticket
+		ts        int64
+		resp      *proto.AuthDataKeyResp
+		message   string
+	)
+
+	if plaintext, err = m.extractClientReqInfo(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPAuthReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+
+	if err = json.Unmarshal([]byte(plaintext), &jobj); err != nil {
+		sendErrReply(w, r, &proto.HTTPAuthReply{Code: proto.ErrCodeParamError, Msg: "Unmarshal AuthDataKeyReq failed: " + err.Error()})
+		return
+	}
+
+	apiReq := jobj.APIReq
+
+	switch apiReq.Type {
+	case proto.MsgAuthGenDataKeyReq:
+	case proto.MsgAuthDecryptDataKeyReq:
+		if jobj.CipherKey == "" {
+			sendErrReply(w, r, &proto.HTTPAuthReply{Code: proto.ErrCodeParamError, Msg: "cipher key is empty"})
+			return
+		}
+	default:
+		sendErrReply(w, r, &proto.HTTPAuthReply{Code: proto.ErrCodeParamError, Msg: fmt.Errorf("invalid request messge type %x", int32(apiReq.Type)).Error()})
+		return
+	}
+
+	if jobj.VolName == "" {
+		sendErrReply(w, r, &proto.HTTPAuthReply{Code: proto.ErrCodeParamError, Msg: "volume name is empty"})
+		return
+	}
+
+	if err = proto.VerifyAPIAccessReqIDs(&apiReq); err != nil {
+		sendErrReply(w, r, &proto.HTTPAuthReply{Code: proto.ErrCodeParamError, Msg: "VerifyAPIAccessReqIDs failed: " + err.Error()})
+		return
+	}
+
+	if ticket, ts, err = proto.ExtractAPIAccessTicket(&apiReq, m.cluster.AuthSecretKey); err != nil {
+		if err == proto.ErrExpiredTicket {
+			sendErrReply(w, r, &proto.HTTPAuthReply{Code: proto.ErrCodeExpiredTicket, Msg: "ExtractAPIAccessTicket failed: " + err.Error()})
+		} else {
+			sendErrReply(w, r, &proto.HTTPAuthReply{Code: proto.ErrCodeParamError, Msg: "ExtractAPIAccessTicket failed: " + err.Error()})
+		}
+		return
+	}
+
+	if err = proto.CheckAPIAccessCaps(&ticket, proto.APIRsc, apiReq.Type, proto.APIAccess); err != nil {
+		sendErrReply(w, r, &proto.HTTPAuthReply{Code: proto.ErrCodeParamError, Msg: "CheckAPIAccessCaps failed: " + err.Error()})
+		return
+	}
+
+	// the data keys of a volume are available to the clients which can mount it
+	if err = proto.CheckVOLAccessCaps(&ticket, jobj.VolName, proto.VOLAccess, proto.MasterNode); err != nil {
+		sendErrReply(w, r, &proto.HTTPAuthReply{Code: proto.ErrCodeParamError, Msg: "CheckVOLAccessCaps failed: " + err.Error()})
+		return
+	}
+
+	switch apiReq.Type {
+	case proto.MsgAuthGenDataKeyReq:
+		resp, err = m.handleGenDataKey(jobj.VolName)
+	case proto.MsgAuthDecryptDataKeyReq:
+		resp, err = m.handleDecryptDataKey(jobj.VolName, jobj.CipherKey)
+	}
+
+	if err != nil {
+		sendErrReply(w, r, &proto.HTTPAuthReply{Code: proto.ErrCodeAuthKeyStoreError, Msg: err.Error()})
+		return
+	}
+
+	if message, err = genAuthDataKeyResp(&apiReq, resp, ts, ticket.SessionKey.Key); err != nil {
+		sendErrReply(w, r, &proto.HTTPAuthReply{Code: proto.ErrCodeAuthAPIAccessGenRespError, Msg: err.Error()})
+		return
+	}
+
+	sendOkReply(w, r, newSuccessHTTPAuthReply(message))
+	return
+}
+
+// handleGenDataKey generates a random data key, and encrypts it by the master
+// key of the volume.
+func (m *Server) handleGenDataKey(volName string) (resp *proto.AuthDataKeyResp, err error) {
+	var volKey *keystore.KeyInfo
+	if volKey, err = m.cluster.GetOrCreateVolKey(volName); err != nil {
+		return
+	}
+	resp = &proto.AuthDataKeyResp{
+		VolName:  volName,
+		PlainKey: make([]byte, dataKeySize),
+	}
+	if _, err = rand.Read(resp.PlainKey); err != nil {
+		return
+	}
+	if resp.CipherKey, err = cryptoutil.EncodeMessage(resp.PlainKey, volKey.AuthKey); err != nil {
+		return
+	}
+	return
+}
+
+// handleDecryptDataKey decrypts the data key by the master key of the volume.
+func (m *Server) handleDecryptDataKey(volName, cipherKey string) (resp *proto.AuthDataKeyResp, err error) {
+	var volKey *keystore.KeyInfo
+	if volKey, err = m.cluster.GetVolKey(volName); err != nil {
+		return
+	}
+	resp = &proto.AuthDataKeyResp{
+		VolName:   volName,
+		CipherKey: cipherKey,
+	}
+	if resp.PlainKey, err = cryptoutil.DecodeMessage(cipherKey, volKey.AuthKey); err != nil {
+		return nil, fmt.Errorf("decrypt data key of vol[%v] failed: %v", volName, err)
+	}
+	return
+}
+
+func genAuthDataKeyResp(req *proto.APIAccessReq, resp *proto.AuthDataKeyResp, ts int64, key []byte) (message string, err error) {
+	var jresp []byte
+
+	resp.APIResp.Type = req.Type + 1
+	resp.APIResp.ClientID = req.ClientID
+	resp.APIResp.ServiceID = req.ServiceID
+	resp.APIResp.Verifier = ts + 1 // increase ts by one for client verify server
+
+	if jresp, err = json.Marshal(resp); err != nil {
+		err = fmt.Errorf("json marshal for response failed %s", err.Error())
+		return
+	}
+
+	if message, err = cryptoutil.EncodeMessage(jresp, key); err != nil {
+		err = fmt.Errorf("encode message for response failed %s", err.Error())
+		return
+	}
+
+	return
+}
+
 func (m *Server) genTicket(key []byte, serviceID string, IP string, caps []byte) (ticket cryptoutil.Ticket) {
 	currentTime := time.Now().Unix()
 	ticket.Version = cryptoutil.TicketVersion
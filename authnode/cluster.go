@@ -158,6 +158,36 @@ errHandler:
 	return
 }
 
+// GetOrCreateVolKey gets the master key of the volume, and creates it if it
+// does not exist.
+func (c *Cluster) GetOrCreateVolKey(volName string) (res *keystore.KeyInfo, err error) {
+	id := volKeyPrefix + volName
+	c.fsm.opKeyMutex.Lock()
+	defer c.fsm.opKeyMutex.Unlock()
+	if res, err = c.fsm.GetKey(id); err == nil {
+		return
+	}
+	res = &keystore.KeyInfo{
+		ID:   id,
+		Ts:   time.Now().Unix(),
+		Role: volKeyRole,
+	}
+	res.AuthKey = cryptoutil.GenSecretKey([]byte(c.AuthRootKey), res.Ts, id)
+	if err = c.syncAddKey(res); err != nil {
+		err = fmt.Errorf("action[GetOrCreateVolKey], clusterID[%v] vol:%v, err:%v ", c.Name, volName, err.Error())
+		log.LogError(errors.Stack(err))
+		return nil, err
+	}
+	c.fsm.PutKey(res)
+	log.LogInfof("action[GetOrCreateVolKey], clusterID[%v] vol[%v] master key created", c.Name, volName)
+	return
+}
+
+// GetVolKey gets the master key of the volume.
+func (c *Cluster) GetVolKey(volName string) (res *keystore.KeyInfo, err error) {
+	return c.GetKey(volKeyPrefix + volName)
+}
+
 // GetKey get a key from the AKstore
 func (c *Cluster) GetAKInfo(accessKey string) (akInfo *keystore.AccessKeyInfo, err error) {
 	if akInfo, err = c.fsm.GetAKInfo(accessKey); err != nil {
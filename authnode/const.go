@@ -36,4 +36,10 @@ const (
 
 	akAcronym = "ak"
 	akPrefix  = keySeparator + akAcronym + keySeparator
+
+	// The master key of a volume is kept in the keystore by the ID of the
+	// prefix, which is not a valid client ID, so that no ticket is issued
+	// for it and it is never returned by the admin APIs.
+	volKeyPrefix = "vol:"
+	volKeyRole   = "volume"
 )
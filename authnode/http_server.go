@@ -98,6 +98,8 @@ func (m *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		fallthrough
 	case proto.OSGetCaps:
 		m.osCapsOp(w, r)
+	case proto.ClientDataKey:
+		m.dataKeyOp(w, r)
 	default:
 		sendErrReply(w, r, &proto.HTTPAuthReply{Code: proto.ErrCodeParamError, Msg: "Invalid requst URL"})
 	}
@@ -116,6 +118,7 @@ func (m *Server) handleFunctions() {
 	http.Handle(proto.OSAddCaps, m.handlerWithInterceptor())
 	http.Handle(proto.OSDeleteCaps, m.handlerWithInterceptor())
 	http.Handle(proto.OSGetCaps, m.handlerWithInterceptor())
+	http.Handle(proto.ClientDataKey, m.handlerWithInterceptor())
 	return
 }
 
@@ -160,11 +160,14 @@ func (mf *KeystoreFsm) Apply(command []byte, index uint64) (resp interface{}, er
 		// Same reasons as the description above
 		if mf.id != leader {
 			mf.PutKey(&keyInfo)
-			accessKeyInfo := &keystore.AccessKeyInfo{
-				AccessKey: keyInfo.AccessKey,
-				ID:        keyInfo.ID,
+			// the master keys of volumes have no access key
+			if keyInfo.AccessKey != "" {
+				accessKeyInfo := &keystore.AccessKeyInfo{
+					AccessKey: keyInfo.AccessKey,
+					ID:        keyInfo.ID,
+				}
+				mf.PutAKInfo(accessKeyInfo)
 			}
-			mf.PutAKInfo(accessKeyInfo)
 			log.LogInfof("action[Apply], Successfully put key in node[%d]", mf.id)
 		} else {
 			log.LogInfof("action[Apply], Already put key in node[%d]", mf.id)
@@ -39,6 +39,9 @@ func (cmd *ChubaoFSCmd) newClusterCmd(client *master.MasterClient) *cobra.Comman
 		newClusterFreezeCmd(client),
 		newClusterSetThresholdCmd(client),
 		newClusterDeleteParasCmd(client),
+		newClusterBalanceCmd(client),
+		newClusterMetaBalanceCmd(client),
+		newClusterUpgradeCmd(client),
 	)
 	return clusterCmd
 }
@@ -49,6 +52,19 @@ const (
 	cmdClusterFreezeShort    = "Freeze cluster"
 	cmdClusterThresholdShort = "Set memory threshold of metanodes"
 	cmdClusterDelParaShort   = "Set delete parameters"
+	cmdClusterBalanceShort   = "Balance the data among the data nodes"
+	cmdBalanceStartShort     = "Start migrating the data partitions to balance the usage of the data nodes"
+	cmdBalanceStopShort      = "Stop planning the migrations of the data partitions"
+	cmdBalanceStatusShort    = "Show the status of the data balance and the usage of the data nodes"
+	cmdClusterMetaBalShort   = "Balance the memory usage and the request rates among the meta nodes"
+	cmdMetaBalPlanShort      = "Show the migrations of the meta partitions to balance the meta nodes without executing them"
+	cmdMetaBalStartShort     = "Start migrating the meta partitions to balance the meta nodes"
+	cmdMetaBalStopShort      = "Stop planning the migrations of the meta partitions"
+	cmdMetaBalStatusShort    = "Show the status of the meta balance and the load of the meta nodes"
+	cmdClusterUpgradeShort   = "Upgrade the data nodes or the meta nodes one by one"
+	cmdUpgradeStartShort     = "Start the rolling upgrade of the data nodes or the meta nodes"
+	cmdUpgradeStopShort      = "Stop the rolling upgrade"
+	cmdUpgradeStatusShort    = "Show the status of the rolling upgrade"
 	nodeDeleteBatchCountKey  = "batchCount"
 	nodeMarkDeleteRateKey    = "markDeleteRate"
 	nodeDeleteWorkerSleepMs  = "deleteWorkerSleepMs"
@@ -208,3 +224,348 @@ func newClusterDeleteParasCmd(client *master.MasterClient) *cobra.Command {
 
 	return cmd
 }
+
+func newClusterBalanceCmd(client *master.MasterClient) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   CliOpBalance + " [COMMAND]",
+		Short: cmdClusterBalanceShort,
+	}
+	cmd.AddCommand(
+		newClusterBalanceStartCmd(client),
+		newClusterBalanceStopCmd(client),
+		newClusterBalanceStatusCmd(client),
+	)
+	return cmd
+}
+
+func newClusterBalanceStartCmd(client *master.MasterClient) *cobra.Command {
+	var optThreshold float64
+	var optConcurrency int
+	var optBandwidth uint64
+	var cmd = &cobra.Command{
+		Use:   CliOpStart,
+		Short: cmdBalanceStartShort,
+		Long: `Start migrating the data partitions from the data nodes with higher space usage to the ones
+with lower usage in the same zone, until the difference of the usage ratios is not greater than the threshold.
+Each data partition is migrated by adding a replica on the target, waiting for it to be repaired,
+and then removing the replica on the source.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			if optThreshold < 0 || optThreshold >= 1.0 {
+				err = fmt.Errorf("Threshold should be between 0 and 1\n")
+				return
+			}
+			var config = proto.DataBalanceConfig{
+				Threshold:      optThreshold,
+				MaxConcurrency: optConcurrency,
+				MaxBandwidth:   optBandwidth * 1024 * 1024,
+			}
+			if err = client.AdminAPI().StartDataBalance(config); err != nil {
+				return
+			}
+			stdout("Start data balance successful!\n")
+		},
+	}
+	cmd.Flags().Float64Var(&optThreshold, CliFlagThreshold, 0, "Max difference of the usage ratios of the data nodes in a zone, 0.1 by default")
+	cmd.Flags().IntVar(&optConcurrency, CliFlagConcurrency, 0, "Max number of the data partitions migrating at the same time, 2 by default")
+	cmd.Flags().Uint64Var(&optBandwidth, CliFlagBandwidth, 0, "Max average MB per second of the data partitions to migrate, 0 for unlimited")
+	return cmd
+}
+
+func newClusterBalanceStopCmd(client *master.MasterClient) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   CliOpStop,
+		Short: cmdBalanceStopShort,
+		Long: `Stop planning the migrations of the data partitions.
+The migrations in progress are not interrupted.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			if err = client.AdminAPI().StopDataBalance(); err != nil {
+				return
+			}
+			stdout("Stop data balance successful!\n")
+		},
+	}
+	return cmd
+}
+
+func newClusterBalanceStatusCmd(client *master.MasterClient) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:     CliOpFullStatus,
+		Aliases: []string{CliOpStatus},
+		Short:   cmdBalanceStatusShort,
+		Run: func(cmd *cobra.Command, args []string) {
+			var (
+				err    error
+				status *proto.DataBalanceStatus
+			)
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			if status, err = client.AdminAPI().GetDataBalanceStatus(); err != nil {
+				err = fmt.Errorf("Get data balance status fail:\n%v\n", err)
+				return
+			}
+			stdout("[Data Balance]\n")
+			stdout("%v", formatDataBalanceStatus(status))
+			stdout("\n")
+		},
+	}
+	return cmd
+}
+
+func newClusterMetaBalanceCmd(client *master.MasterClient) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   CliOpMetaBalance + " [COMMAND]",
+		Short: cmdClusterMetaBalShort,
+	}
+	cmd.AddCommand(
+		newClusterMetaBalancePlanCmd(client),
+		newClusterMetaBalanceStartCmd(client),
+		newClusterMetaBalanceStopCmd(client),
+		newClusterMetaBalanceStatusCmd(client),
+	)
+	return cmd
+}
+
+func addMetaBalanceFlags(cmd *cobra.Command, config *proto.MetaBalanceConfig) {
+	cmd.Flags().Float64Var(&config.MemThreshold, CliFlagThreshold, 0, "Max difference of the memory usage ratios of the meta nodes in a zone, 0.1 by default")
+	cmd.Flags().Float64Var(&config.RateThreshold, CliFlagRateThreshold, 0, "Max difference of the request rates of the meta nodes in a zone relative to the average, 0.5 by default")
+	cmd.Flags().IntVar(&config.MaxConcurrency, CliFlagConcurrency, 0, "Max number of the meta partitions migrating at the same time, 1 by default")
+}
+
+func validateMetaBalanceConfig(config proto.MetaBalanceConfig) (err error) {
+	if config.MemThreshold < 0 || config.MemThreshold >= 1.0 {
+		return fmt.Errorf("Threshold should be between 0 and 1\n")
+	}
+	if config.RateThreshold < 0 {
+		return fmt.Errorf("Rate threshold should not be negative\n")
+	}
+	return
+}
+
+func newClusterMetaBalancePlanCmd(client *master.MasterClient) *cobra.Command {
+	var config proto.MetaBalanceConfig
+	var optCount int
+	var cmd = &cobra.Command{
+		Use:   CliOpPlan,
+		Short: cmdMetaBalPlanShort,
+		Long: `Show the migrations of the meta partitions which would be planned with the given thresholds,
+without executing them. The memory usage is balanced first, and then the request rates.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			var (
+				err  error
+				plan *proto.MetaBalancePlan
+			)
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			if err = validateMetaBalanceConfig(config); err != nil {
+				return
+			}
+			if plan, err = client.AdminAPI().PlanMetaBalance(config, optCount); err != nil {
+				err = fmt.Errorf("Plan meta balance fail:\n%v\n", err)
+				return
+			}
+			stdout("[Meta Balance Plan]\n")
+			stdout("%v", formatMetaBalancePlan(plan))
+			stdout("\n")
+		},
+	}
+	addMetaBalanceFlags(cmd, &config)
+	cmd.Flags().IntVar(&optCount, CliFlagMaxCount, 0, "Max number of the migrations to plan, 100 by default")
+	return cmd
+}
+
+func newClusterMetaBalanceStartCmd(client *master.MasterClient) *cobra.Command {
+	var config proto.MetaBalanceConfig
+	var cmd = &cobra.Command{
+		Use:   CliOpStart,
+		Short: cmdMetaBalStartShort,
+		Long: `Start migrating the meta partitions from the meta nodes with higher memory usage or request rate
+to the ones with lower usage or rate in the same zone. Each meta partition is migrated by adding a replica
+on the target, waiting for it to catch up with the leader, and then removing the replica on the source.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			if err = validateMetaBalanceConfig(config); err != nil {
+				return
+			}
+			if err = client.AdminAPI().StartMetaBalance(config); err != nil {
+				return
+			}
+			stdout("Start meta balance successful!\n")
+		},
+	}
+	addMetaBalanceFlags(cmd, &config)
+	return cmd
+}
+
+func newClusterMetaBalanceStopCmd(client *master.MasterClient) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   CliOpStop,
+		Short: cmdMetaBalStopShort,
+		Long: `Stop planning the migrations of the meta partitions.
+The migrations in progress are not interrupted.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			if err = client.AdminAPI().StopMetaBalance(); err != nil {
+				return
+			}
+			stdout("Stop meta balance successful!\n")
+		},
+	}
+	return cmd
+}
+
+func newClusterMetaBalanceStatusCmd(client *master.MasterClient) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:     CliOpFullStatus,
+		Aliases: []string{CliOpStatus},
+		Short:   cmdMetaBalStatusShort,
+		Run: func(cmd *cobra.Command, args []string) {
+			var (
+				err    error
+				status *proto.MetaBalanceStatus
+			)
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			if status, err = client.AdminAPI().GetMetaBalanceStatus(); err != nil {
+				err = fmt.Errorf("Get meta balance status fail:\n%v\n", err)
+				return
+			}
+			stdout("[Meta Balance]\n")
+			stdout("%v", formatMetaBalanceStatus(status))
+			stdout("\n")
+		},
+	}
+	return cmd
+}
+
+func newClusterUpgradeCmd(client *master.MasterClient) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   CliOpUpgrade + " [COMMAND]",
+		Short: cmdClusterUpgradeShort,
+	}
+	cmd.AddCommand(
+		newClusterUpgradeStartCmd(client),
+		newClusterUpgradeStopCmd(client),
+		newClusterUpgradeStatusCmd(client),
+	)
+	return cmd
+}
+
+func newClusterUpgradeStartCmd(client *master.MasterClient) *cobra.Command {
+	var config proto.UpgradeConfig
+	var cmd = &cobra.Command{
+		Use:   CliOpStart + " [ROLE] [VERSION]",
+		Short: cmdUpgradeStartShort,
+		Long: `Start the rolling upgrade of the data nodes or the meta nodes, whose role is datanode or metanode.
+For each node, the master marks the node as upgrading, so that no partition is placed on it, and transfers
+the raft leaders on the node to the other replicas. Then the node is restarted with the new binary by the
+operator, and the master waits for it to come back with the given version before the next node. The upgrade
+stops if a node does not come back in time.`,
+		Args: cobra.MinimumNArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			config.Role, config.Version = args[0], args[1]
+			if config.Role != proto.UpgradeDataNode && config.Role != proto.UpgradeMetaNode {
+				err = fmt.Errorf("Role should be %v or %v\n", proto.UpgradeDataNode, proto.UpgradeMetaNode)
+				return
+			}
+			if err = client.AdminAPI().StartUpgrade(config); err != nil {
+				return
+			}
+			stdout("Start upgrade successful!\n")
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return []string{proto.UpgradeDataNode, proto.UpgradeMetaNode}, cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	cmd.Flags().StringSliceVar(&config.Nodes, CliFlagNodes, nil, "Addresses of the nodes to upgrade in order, all the nodes of the role by default")
+	cmd.Flags().Int64Var(&config.TimeoutSec, CliFlagTimeout, 0, "Max seconds to wait for a node to come back with the version, 1800 by default")
+	return cmd
+}
+
+func newClusterUpgradeStopCmd(client *master.MasterClient) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   CliOpStop,
+		Short: cmdUpgradeStopShort,
+		Long: `Stop the rolling upgrade. The node being upgraded is not waited for any more, and
+partitions can be placed on it again.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			if err = client.AdminAPI().StopUpgrade(); err != nil {
+				return
+			}
+			stdout("Stop upgrade successful!\n")
+		},
+	}
+	return cmd
+}
+
+func newClusterUpgradeStatusCmd(client *master.MasterClient) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:     CliOpFullStatus,
+		Aliases: []string{CliOpStatus},
+		Short:   cmdUpgradeStatusShort,
+		Run: func(cmd *cobra.Command, args []string) {
+			var (
+				err    error
+				status *proto.UpgradeStatus
+			)
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			if status, err = client.AdminAPI().GetUpgradeStatus(); err != nil {
+				err = fmt.Errorf("Get upgrade status fail:\n%v\n", err)
+				return
+			}
+			stdout("[Upgrade]\n")
+			stdout("%v", formatUpgradeStatus(status))
+			stdout("\n")
+		},
+	}
+	return cmd
+}
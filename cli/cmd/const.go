@@ -36,6 +36,17 @@ const (
 	CliOpDelReplica        = "del-replica"
 	CliOpExpand            = "expand"
 	CliOpShrink            = "shrink"
+	CliOpBalance           = "balance"
+	CliOpStart             = "start"
+	CliOpStop              = "stop"
+	CliOpFullStatus        = "status"
+	CliOpMetaBalance       = "meta-balance"
+	CliOpPlan              = "plan"
+	CliOpMerge             = "merge"
+	CliOpResplit           = "resplit"
+	CliOpMoveStatus        = "move-status"
+	CliOpUpgrade           = "upgrade"
+	CliOpMaintenance       = "maintenance"
 
 	//Shorthand format of operation name
 	CliOpDecommissionShortHand = "dec"
@@ -73,6 +84,16 @@ const (
 	CliFlagDelWorkerSleepMs   = "delete-worker-sleep-ms"
 	CliFlagMarkDelRate        = "mark-delete-rate"
 	CliFlagCrossZone          = "crossZone"
+	CliFlagTrashDays          = "trash-days"
+	CliFlagECDataNum          = "ec-data-num"
+	CliFlagECParityNum        = "ec-parity-num"
+	CliFlagConcurrency        = "concurrency"
+	CliFlagBandwidth          = "bandwidth"
+	CliFlagRateThreshold      = "rate-threshold"
+	CliFlagMaxCount           = "max-count"
+	CliFlagNodes              = "nodes"
+	CliFlagTimeout            = "timeout"
+	CliFlagDuration           = "duration"
 
 	//CliFlagSetDataPartitionCount	= "count" use dp-count instead
 
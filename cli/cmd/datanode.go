@@ -36,6 +36,7 @@ func newDataNodeCmd(client *master.MasterClient) *cobra.Command {
 		newDataNodeListCmd(client),
 		newDataNodeInfoCmd(client),
 		newDataNodeDecommissionCmd(client),
+		newDataNodeMaintenanceCmd(client),
 	)
 	return cmd
 }
@@ -44,6 +45,9 @@ const (
 	cmdDataNodeListShort             = "List information of data nodes"
 	cmdDataNodeInfoShort             = "Show information of a data node"
 	cmdDataNodeDecommissionInfoShort = "decommission partitions in a data node to others"
+	cmdDataNodeMaintenanceShort      = "Manage the maintenance of a data node"
+	cmdDataNodeMaintenanceStartShort = "Put a data node in maintenance"
+	cmdDataNodeMaintenanceStopShort  = "Stop the maintenance of a data node"
 )
 
 func newDataNodeListCmd(client *master.MasterClient) *cobra.Command {
@@ -148,3 +152,74 @@ func newDataNodeDecommissionCmd(client *master.MasterClient) *cobra.Command {
 	}
 	return cmd
 }
+
+func newDataNodeMaintenanceCmd(client *master.MasterClient) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   CliOpMaintenance + " [COMMAND]",
+		Short: cmdDataNodeMaintenanceShort,
+		Long: `Put a data node in maintenance for a bounded window before rebooting it. Unlike decommission,
+the partitions stay on the node. During the window, no partition is placed on the node, and the replicas
+on it are neither alarmed as missing nor replaced. The maintenance ends by itself when the window ends.`,
+	}
+	cmd.AddCommand(
+		newDataNodeMaintenanceStartCmd(client),
+		newDataNodeMaintenanceStopCmd(client),
+	)
+	return cmd
+}
+
+func newDataNodeMaintenanceStartCmd(client *master.MasterClient) *cobra.Command {
+	var optDuration int64
+	var cmd = &cobra.Command{
+		Use:   CliOpStart + " [NODE ADDRESS]",
+		Short: cmdDataNodeMaintenanceStartShort,
+		Args:  cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			if err = client.NodeAPI().StartDataNodeMaintenance(args[0], optDuration); err != nil {
+				return
+			}
+			stdout("Start maintenance of data node successfully\n")
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return validDataNodes(client, toComplete), cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	cmd.Flags().Int64Var(&optDuration, CliFlagDuration, 0, "Seconds of the maintenance window, 3600 by default and 86400 at most")
+	return cmd
+}
+
+func newDataNodeMaintenanceStopCmd(client *master.MasterClient) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   CliOpStop + " [NODE ADDRESS]",
+		Short: cmdDataNodeMaintenanceStopShort,
+		Args:  cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			if err = client.NodeAPI().StopDataNodeMaintenance(args[0]); err != nil {
+				return
+			}
+			stdout("Stop maintenance of data node successfully\n")
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return validDataNodes(client, toComplete), cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	return cmd
+}
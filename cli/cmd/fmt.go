@@ -68,6 +68,203 @@ func formatClusterStat(cs *proto.ClusterStatInfo) string {
 	return sb.String()
 }
 
+var (
+	dataBalanceTaskTablePattern = "    %-12v    %-20v    %-20v    %-20v    %-10v    %-10v    %-19v    %v\n"
+	dataBalanceTaskTableHeader  = fmt.Sprintf(dataBalanceTaskTablePattern,
+		"PARTITION ID", "VOLUME", "SOURCE", "TARGET", "SIZE", "STATUS", "START TIME", "MESSAGE")
+	dataBalanceNodeTablePattern = "    %-20v    %-10v    %-10v    %-10v    %-10v\n"
+	dataBalanceNodeTableHeader  = fmt.Sprintf(dataBalanceNodeTablePattern,
+		"ADDRESS", "TOTAL", "USED", "USED RATIO", "DISK SKEW")
+)
+
+func formatDataBalanceStatus(status *proto.DataBalanceStatus) string {
+	var sb = strings.Builder{}
+	sb.WriteString(fmt.Sprintf("  Running            : %v\n", formatYesNo(status.Running)))
+	if status.StartTime > 0 {
+		sb.WriteString(fmt.Sprintf("  Start time         : %v\n", formatTime(status.StartTime)))
+	}
+	sb.WriteString(fmt.Sprintf("  Threshold          : %v\n", status.Config.Threshold))
+	sb.WriteString(fmt.Sprintf("  Max concurrency    : %v\n", status.Config.MaxConcurrency))
+	if status.Config.MaxBandwidth == 0 {
+		sb.WriteString("  Max bandwidth      : unlimited\n")
+	} else {
+		sb.WriteString(fmt.Sprintf("  Max bandwidth      : %v/s\n", formatSize(status.Config.MaxBandwidth)))
+	}
+	sb.WriteString(fmt.Sprintf("  Finished           : %v\n", status.FinishedCount))
+	sb.WriteString(fmt.Sprintf("  Failed             : %v\n", status.FailedCount))
+	sb.WriteString(fmt.Sprintf("  Moved              : %v\n", formatSize(status.MovedBytes)))
+	sb.WriteString("\n")
+	sb.WriteString("Zones:\n")
+	for _, zone := range status.Zones {
+		sb.WriteString(fmt.Sprintf("  Zone %v: used ratio %.4f, skew %.4f\n", zone.ZoneName, zone.UsageRatio, zone.Skew))
+		sb.WriteString(dataBalanceNodeTableHeader)
+		for _, node := range zone.Nodes {
+			sb.WriteString(fmt.Sprintf(dataBalanceNodeTablePattern, node.Addr, formatSize(node.Total), formatSize(node.Used),
+				fmt.Sprintf("%.4f", node.UsageRatio), fmt.Sprintf("%.4f", node.DiskSkew)))
+		}
+	}
+	sb.WriteString("\n")
+	sb.WriteString("Tasks in progress:\n")
+	sb.WriteString(dataBalanceTaskTableHeader)
+	for _, task := range status.Tasks {
+		sb.WriteString(formatDataBalanceTask(task))
+	}
+	sb.WriteString("\n")
+	sb.WriteString("Recent tasks:\n")
+	sb.WriteString(dataBalanceTaskTableHeader)
+	for _, task := range status.RecentTasks {
+		sb.WriteString(formatDataBalanceTask(task))
+	}
+	return sb.String()
+}
+
+func formatDataBalanceTask(task *proto.DataBalanceTask) string {
+	return fmt.Sprintf(dataBalanceTaskTablePattern, task.PartitionID, task.VolName, task.Source, task.Target,
+		formatSize(task.Size), task.Status, formatTime(task.StartTime), task.Msg)
+}
+
+var (
+	metaBalanceTaskTablePattern = "    %-12v    %-20v    %-20v    %-20v    %-11v    %-10v    %-10v    %-10v    %-19v    %v\n"
+	metaBalanceTaskTableHeader  = fmt.Sprintf(metaBalanceTaskTablePattern,
+		"PARTITION ID", "VOLUME", "SOURCE", "TARGET", "REASON", "MEMORY", "RATE", "STATUS", "START TIME", "MESSAGE")
+	metaBalanceNodeTablePattern = "    %-20v    %-10v    %-10v    %-10v    %-10v    %-10v\n"
+	metaBalanceNodeTableHeader  = fmt.Sprintf(metaBalanceNodeTablePattern,
+		"ADDRESS", "TOTAL", "USED", "USED RATIO", "RATE", "PARTITIONS")
+	metaMoveTaskTablePattern = "    %-20v    %-7v    %-12v    %-12v    %-20v    %-20v    %-10v    %-9v    %-19v    %v\n"
+	metaMoveTaskTableHeader  = fmt.Sprintf(metaMoveTaskTablePattern,
+		"VOLUME", "TYPE", "SOURCE", "TARGET", "START", "END", "MOVED", "STATUS", "START TIME", "MESSAGE")
+	upgradeNodeTablePattern = "    %-20v    %-12v    %-12v    %-7v    %-19v    %-19v    %v\n"
+	upgradeNodeTableHeader  = fmt.Sprintf(upgradeNodeTablePattern,
+		"ADDRESS", "OLD VERSION", "STATUS", "LEADERS", "START TIME", "END TIME", "MESSAGE")
+)
+
+func formatMetaBalanceConfig(config proto.MetaBalanceConfig) string {
+	var sb = strings.Builder{}
+	sb.WriteString(fmt.Sprintf("  Threshold          : %v\n", config.MemThreshold))
+	sb.WriteString(fmt.Sprintf("  Rate threshold     : %v\n", config.RateThreshold))
+	sb.WriteString(fmt.Sprintf("  Max concurrency    : %v\n", config.MaxConcurrency))
+	return sb.String()
+}
+
+func formatMetaBalanceZones(zones []*proto.MetaBalanceZoneUsage) string {
+	var sb = strings.Builder{}
+	sb.WriteString("Zones:\n")
+	for _, zone := range zones {
+		sb.WriteString(fmt.Sprintf("  Zone %v: used ratio %.4f, skew %.4f, average rate %.2f, rate skew %.4f\n",
+			zone.ZoneName, zone.UsageRatio, zone.Skew, zone.RequestRate, zone.RateSkew))
+		sb.WriteString(metaBalanceNodeTableHeader)
+		for _, node := range zone.Nodes {
+			sb.WriteString(fmt.Sprintf(metaBalanceNodeTablePattern, node.Addr, formatSize(node.Total), formatSize(node.Used),
+				fmt.Sprintf("%.4f", node.UsageRatio), fmt.Sprintf("%.2f", node.RequestRate), node.PartitionCount))
+		}
+	}
+	return sb.String()
+}
+
+func formatMetaBalancePlan(plan *proto.MetaBalancePlan) string {
+	var sb = strings.Builder{}
+	sb.WriteString(formatMetaBalanceConfig(plan.Config))
+	sb.WriteString("\n")
+	sb.WriteString(formatMetaBalanceZones(plan.Zones))
+	sb.WriteString("\n")
+	sb.WriteString(fmt.Sprintf("Planned tasks (%v):\n", len(plan.Tasks)))
+	sb.WriteString(metaBalanceTaskTableHeader)
+	for _, task := range plan.Tasks {
+		sb.WriteString(formatMetaBalanceTask(task))
+	}
+	return sb.String()
+}
+
+func formatMetaBalanceStatus(status *proto.MetaBalanceStatus) string {
+	var sb = strings.Builder{}
+	sb.WriteString(fmt.Sprintf("  Running            : %v\n", formatYesNo(status.Running)))
+	if status.StartTime > 0 {
+		sb.WriteString(fmt.Sprintf("  Start time         : %v\n", formatTime(status.StartTime)))
+	}
+	sb.WriteString(formatMetaBalanceConfig(status.Config))
+	sb.WriteString(fmt.Sprintf("  Finished           : %v\n", status.FinishedCount))
+	sb.WriteString(fmt.Sprintf("  Failed             : %v\n", status.FailedCount))
+	sb.WriteString("\n")
+	sb.WriteString(formatMetaBalanceZones(status.Zones))
+	sb.WriteString("\n")
+	sb.WriteString("Tasks in progress:\n")
+	sb.WriteString(metaBalanceTaskTableHeader)
+	for _, task := range status.Tasks {
+		sb.WriteString(formatMetaBalanceTask(task))
+	}
+	sb.WriteString("\n")
+	sb.WriteString("Recent tasks:\n")
+	sb.WriteString(metaBalanceTaskTableHeader)
+	for _, task := range status.RecentTasks {
+		sb.WriteString(formatMetaBalanceTask(task))
+	}
+	return sb.String()
+}
+
+func formatUpgradeStatus(status *proto.UpgradeStatus) string {
+	var sb = strings.Builder{}
+	sb.WriteString(fmt.Sprintf("  Running            : %v\n", formatYesNo(status.Running)))
+	sb.WriteString(fmt.Sprintf("  Role               : %v\n", status.Config.Role))
+	sb.WriteString(fmt.Sprintf("  Version            : %v\n", status.Config.Version))
+	sb.WriteString(fmt.Sprintf("  Timeout            : %vs\n", status.Config.TimeoutSec))
+	if status.StartTime > 0 {
+		sb.WriteString(fmt.Sprintf("  Start time         : %v\n", formatTime(status.StartTime)))
+	}
+	if status.EndTime > 0 {
+		sb.WriteString(fmt.Sprintf("  End time           : %v\n", formatTime(status.EndTime)))
+	}
+	sb.WriteString(fmt.Sprintf("  Current node       : %v\n", status.Current))
+	sb.WriteString("\n")
+	sb.WriteString("Nodes:\n")
+	sb.WriteString(upgradeNodeTableHeader)
+	for _, node := range status.Nodes {
+		var startTime, endTime string
+		if node.StartTime > 0 {
+			startTime = formatTime(node.StartTime)
+		}
+		if node.EndTime > 0 {
+			endTime = formatTime(node.EndTime)
+		}
+		sb.WriteString(fmt.Sprintf(upgradeNodeTablePattern, node.Addr, node.OldVersion, node.Status, node.Leaders,
+			startTime, endTime, node.Msg))
+	}
+	return sb.String()
+}
+
+func formatMetaMoveStatus(status *proto.MetaMoveStatus) string {
+	var sb = strings.Builder{}
+	sb.WriteString("Tasks in progress:\n")
+	sb.WriteString(metaMoveTaskTableHeader)
+	for _, task := range status.Tasks {
+		sb.WriteString(formatMetaMoveTask(task))
+	}
+	sb.WriteString("\n")
+	sb.WriteString("Recent tasks:\n")
+	sb.WriteString(metaMoveTaskTableHeader)
+	for _, task := range status.RecentTasks {
+		sb.WriteString(formatMetaMoveTask(task))
+	}
+	return sb.String()
+}
+
+func formatMetaMoveTask(task *proto.MetaMoveTask) string {
+	var startTime = ""
+	if task.StartTime > 0 {
+		startTime = formatTime(task.StartTime)
+	}
+	return fmt.Sprintf(metaMoveTaskTablePattern, task.VolName, task.Type, task.Source, task.Target, task.Start,
+		task.End, task.Moved, task.Status, startTime, task.Msg)
+}
+
+func formatMetaBalanceTask(task *proto.MetaBalanceTask) string {
+	var startTime = ""
+	if task.StartTime > 0 {
+		startTime = formatTime(task.StartTime)
+	}
+	return fmt.Sprintf(metaBalanceTaskTablePattern, task.PartitionID, task.VolName, task.Source, task.Target, task.Reason,
+		formatSize(task.Memory), fmt.Sprintf("%.2f", task.RequestRate), task.Status, startTime, task.Msg)
+}
+
 var nodeViewTableRowPattern = "%-6v    %-18v    %-8v    %-8v"
 
 func formatNodeViewTableHeader() string {
@@ -100,13 +297,15 @@ func formatSimpleVolView(svv *proto.SimpleVolView) string {
 	sb.WriteString(fmt.Sprintf("  Authenticate         : %v\n", formatEnabledDisabled(svv.Authenticate)))
 	sb.WriteString(fmt.Sprintf("  Follower read        : %v\n", formatEnabledDisabled(svv.FollowerRead)))
 	sb.WriteString(fmt.Sprintf("  Cross zone           : %v\n", formatEnabledDisabled(svv.CrossZone)))
+	sb.WriteString(fmt.Sprintf("  Trash days           : %v\n", formatTrashDays(svv.TrashDays)))
 	sb.WriteString(fmt.Sprintf("  Inode count          : %v\n", svv.InodeCount))
 	sb.WriteString(fmt.Sprintf("  Dentry count         : %v\n", svv.DentryCount))
 	sb.WriteString(fmt.Sprintf("  Max metaPartition ID : %v\n", svv.MaxMetaPartitionID))
 	sb.WriteString(fmt.Sprintf("  Meta partition count : %v\n", svv.MpCnt))
 	sb.WriteString(fmt.Sprintf("  Meta replicas        : %v\n", svv.MpReplicaNum))
 	sb.WriteString(fmt.Sprintf("  Data partition count : %v\n", svv.DpCnt))
-	sb.WriteString(fmt.Sprintf("  Data replicas        : %v", svv.DpReplicaNum))
+	sb.WriteString(fmt.Sprintf("  Data replicas        : %v\n", svv.DpReplicaNum))
+	sb.WriteString(fmt.Sprintf("  Erasure coding       : %v", formatErasureCoding(svv.ECDataNum, svv.ECParityNum)))
 	return sb.String()
 }
 
@@ -272,6 +471,79 @@ func formatUserInfoTableRow(userInfo *proto.UserInfo) string {
 		userInfo.UserID, formatUserType(userInfo.UserType), userInfo.AccessKey, userInfo.SecretKey, userInfo.CreateTime)
 }
 
+var (
+	quotaInfoTablePattern = "%-8v    %-4v    %-20v    %-10v    %-10v    %-10v    %-10v"
+	quotaInfoTableHeader  = fmt.Sprintf(quotaInfoTablePattern,
+		"ID", "TYPE", "TARGET", "USED", "MAX BYTES", "INODES", "MAX INODES")
+)
+
+func formatQuotaInfoTableRow(quota *proto.QuotaInfo) string {
+	return fmt.Sprintf(quotaInfoTablePattern,
+		quota.QuotaID, quota.Type, quota.Target, formatSize(quota.UsedBytes), formatQuotaLimit(quota.MaxBytes, formatSize),
+		quota.UsedInodes, formatQuotaLimit(quota.MaxInodes, func(n uint64) string { return strconv.FormatUint(n, 10) }))
+}
+
+func formatQuotaInfo(quota *proto.QuotaInfo) string {
+	var sb = strings.Builder{}
+	sb.WriteString(fmt.Sprintf("  Quota ID    : %v\n", quota.QuotaID))
+	sb.WriteString(fmt.Sprintf("  Type        : %v\n", quota.Type))
+	sb.WriteString(fmt.Sprintf("  Target      : %v\n", quota.Target))
+	sb.WriteString(fmt.Sprintf("  Used bytes  : %v\n", formatSize(quota.UsedBytes)))
+	sb.WriteString(fmt.Sprintf("  Max bytes   : %v\n", formatQuotaLimit(quota.MaxBytes, formatSize)))
+	sb.WriteString(fmt.Sprintf("  Used inodes : %v\n", quota.UsedInodes))
+	sb.WriteString(fmt.Sprintf("  Max inodes  : %v\n", formatQuotaLimit(quota.MaxInodes, func(n uint64) string { return strconv.FormatUint(n, 10) })))
+	sb.WriteString(fmt.Sprintf("  Exceeded    : %v\n", formatYesNo(quota.BytesExceeded() || quota.InodesExceeded())))
+	sb.WriteString(fmt.Sprintf("  Create time : %v\n", formatTime(quota.CreateTime)))
+	return sb.String()
+}
+
+func formatQuotaLimit(limit uint64, format func(uint64) string) string {
+	if limit == 0 {
+		return "unlimited"
+	}
+	return format(limit)
+}
+
+func formatDirSummary(summary *proto.DirSummary) string {
+	var sb = strings.Builder{}
+	sb.WriteString(fmt.Sprintf("  Files           : %v\n", summary.Files))
+	sb.WriteString(fmt.Sprintf("  Subdirs         : %v\n", summary.Subdirs))
+	sb.WriteString(fmt.Sprintf("  Bytes           : %v\n", formatSize(summary.Bytes)))
+	sb.WriteString(fmt.Sprintf("  Recursive files : %v\n", summary.RFiles))
+	sb.WriteString(fmt.Sprintf("  Recursive dirs  : %v\n", summary.RSubdirs))
+	sb.WriteString(fmt.Sprintf("  Recursive bytes : %v (%v)\n", formatSize(summary.RBytes), summary.RBytes))
+	return sb.String()
+}
+
+func formatErasureCoding(dataNum, parityNum uint8) string {
+	if dataNum == 0 {
+		return "Disabled"
+	}
+	return fmt.Sprintf("RS(%v+%v)", dataNum, parityNum)
+}
+
+func formatTrashDays(days uint32) string {
+	if days == 0 {
+		return "Disabled"
+	}
+	return fmt.Sprintf("%v", days)
+}
+
+var (
+	trashInfoTablePattern = "%-12v    %-10v    %-20v    %v"
+	trashInfoTableHeader  = fmt.Sprintf(trashInfoTablePattern,
+		"INODE", "SIZE", "DELETE TIME", "PATH")
+)
+
+func formatTrashInfoTableRow(info *proto.TrashInfo) string {
+	var path = info.Path
+	if path == "" {
+		path = fmt.Sprintf("%v/%v", info.ParentIno, info.Name)
+	}
+	return fmt.Sprintf(trashInfoTablePattern,
+		info.Inode, formatSize(info.Size), formatTime(info.DeleteTime), path)
+}
+
 func formatDataPartitionStatus(status int8) string {
 	switch status {
 	case 1:
@@ -441,6 +713,7 @@ func formatDataNodeDetail(dn *proto.DataNodeInfo, rowTable bool) string {
 	sb.WriteString(fmt.Sprintf("  Report time         : %v\n", formatTimeToString(dn.ReportTime)))
 	sb.WriteString(fmt.Sprintf("  Partition count     : %v\n", dn.DataPartitionCount))
 	sb.WriteString(fmt.Sprintf("  Bad disks           : %v\n", dn.BadDisks))
+	sb.WriteString(fmt.Sprintf("  Maintenance until   : %v\n", formatMaintenanceUntil(dn.MaintenanceUntil)))
 	sb.WriteString(fmt.Sprintf("  Persist partitions  : %v\n", dn.PersistenceDataPartitions))
 	return sb.String()
 }
@@ -467,10 +740,18 @@ func formatMetaNodeDetail(mn *proto.MetaNodeInfo, rowTable bool) string {
 	sb.WriteString(fmt.Sprintf("  IsActive            : %v\n", formatNodeStatus(mn.IsActive)))
 	sb.WriteString(fmt.Sprintf("  Report time         : %v\n", formatTimeToString(mn.ReportTime)))
 	sb.WriteString(fmt.Sprintf("  Partition count     : %v\n", mn.MetaPartitionCount))
+	sb.WriteString(fmt.Sprintf("  Maintenance until   : %v\n", formatMaintenanceUntil(mn.MaintenanceUntil)))
 	sb.WriteString(fmt.Sprintf("  Persist partitions  : %v\n", mn.PersistenceMetaPartitions))
 	return sb.String()
 }
 
+func formatMaintenanceUntil(until int64) string {
+	if until <= time.Now().Unix() {
+		return "-"
+	}
+	return formatTime(until)
+}
+
 func formatZoneView(zv *proto.ZoneView) string {
 	var sb = strings.Builder{}
 	sb.WriteString(fmt.Sprintf("Zone Name:   %v\n", zv.Name))
@@ -37,6 +37,7 @@ func newMetaNodeCmd(client *master.MasterClient) *cobra.Command {
 		newMetaNodeListCmd(client),
 		newMetaNodeInfoCmd(client),
 		newMetaNodeDecommissionCmd(client),
+		newMetaNodeMaintenanceCmd(client),
 	)
 	return cmd
 }
@@ -45,6 +46,9 @@ const (
 	cmdMetaNodeListShort             = "List information of meta nodes"
 	cmdMetaNodeInfoShort             = "Show information of meta nodes"
 	cmdMetaNodeDecommissionInfoShort = "Decommission partitions in a meta node to other nodes"
+	cmdMetaNodeMaintenanceShort      = "Manage the maintenance of a meta node"
+	cmdMetaNodeMaintenanceStartShort = "Put a meta node in maintenance"
+	cmdMetaNodeMaintenanceStopShort  = "Stop the maintenance of a meta node"
 )
 
 func newMetaNodeListCmd(client *master.MasterClient) *cobra.Command {
@@ -148,3 +152,74 @@ func newMetaNodeDecommissionCmd(client *master.MasterClient) *cobra.Command {
 	}
 	return cmd
 }
+
+func newMetaNodeMaintenanceCmd(client *master.MasterClient) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   CliOpMaintenance + " [COMMAND]",
+		Short: cmdMetaNodeMaintenanceShort,
+		Long: `Put a meta node in maintenance for a bounded window before rebooting it. Unlike decommission,
+the partitions stay on the node. During the window, no partition is placed on the node, and the replicas
+on it are neither alarmed as missing nor replaced. The maintenance ends by itself when the window ends.`,
+	}
+	cmd.AddCommand(
+		newMetaNodeMaintenanceStartCmd(client),
+		newMetaNodeMaintenanceStopCmd(client),
+	)
+	return cmd
+}
+
+func newMetaNodeMaintenanceStartCmd(client *master.MasterClient) *cobra.Command {
+	var optDuration int64
+	var cmd = &cobra.Command{
+		Use:   CliOpStart + " [NODE ADDRESS]",
+		Short: cmdMetaNodeMaintenanceStartShort,
+		Args:  cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			if err = client.NodeAPI().StartMetaNodeMaintenance(args[0], optDuration); err != nil {
+				return
+			}
+			stdout("Start maintenance of meta node successfully\n")
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return validMetaNodes(client, toComplete), cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	cmd.Flags().Int64Var(&optDuration, CliFlagDuration, 0, "Seconds of the maintenance window, 3600 by default and 86400 at most")
+	return cmd
+}
+
+func newMetaNodeMaintenanceStopCmd(client *master.MasterClient) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   CliOpStop + " [NODE ADDRESS]",
+		Short: cmdMetaNodeMaintenanceStopShort,
+		Args:  cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			if err = client.NodeAPI().StopMetaNodeMaintenance(args[0]); err != nil {
+				return
+			}
+			stdout("Stop maintenance of meta node successfully\n")
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return validMetaNodes(client, toComplete), cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	return cmd
+}
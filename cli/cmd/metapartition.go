@@ -39,6 +39,9 @@ func newMetaPartitionCmd(client *master.MasterClient) *cobra.Command {
 		newMetaPartitionDecommissionCmd(client),
 		newMetaPartitionReplicateCmd(client),
 		newMetaPartitionDeleteReplicaCmd(client),
+		newMetaPartitionMergeCmd(client),
+		newMetaPartitionResplitCmd(client),
+		newMetaPartitionMoveStatusCmd(client),
 	)
 	return cmd
 }
@@ -49,6 +52,9 @@ const (
 	cmdMetaPartitionDecommissionShort     = "Decommission a replication of the meta partition to a new address"
 	cmdMetaPartitionReplicateShort        = "Add a replication of the meta partition on a new address"
 	cmdMetaPartitionDeleteReplicaShort    = "Delete a replication of the meta partition on a fixed address"
+	cmdMetaPartitionMergeShort            = "Merge the next meta partition into the meta partition"
+	cmdMetaPartitionResplitShort          = "Move the tail of the meta partition into a new meta partition"
+	cmdMetaPartitionMoveStatusShort       = "Display the status of merging and re-splitting meta partitions"
 	)
 
 func newMetaPartitionGetCmd(client *master.MasterClient) *cobra.Command {
@@ -265,3 +271,104 @@ func newMetaPartitionDeleteReplicaCmd(client *master.MasterClient) *cobra.Comman
 	}
 	return cmd
 }
+
+func newMetaPartitionMergeCmd(client *master.MasterClient) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   CliOpMerge + " [VOLUME] [META PARTITION ID]",
+		Short: cmdMetaPartitionMergeShort,
+		Long: `Merge the meta partition whose range follows the given one into it, and then
+remove the merged partition. The merged partition is frozen while its inodes and
+dentries are moved, so that the writes to it fail until the merge is finished.`,
+		Args: cobra.MinimumNArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			var (
+				err         error
+				partitionID uint64
+				task        *proto.MetaMoveTask
+			)
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			if partitionID, err = strconv.ParseUint(args[1], 10, 64); err != nil {
+				return
+			}
+			if task, err = client.AdminAPI().MergeMetaPartition(args[0], partitionID); err != nil {
+				return
+			}
+			stdout("Merging meta partition %v into %v, range [%v, %v]\n", task.Source, task.Target, task.Start, task.End)
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return validVols(client, toComplete), cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	return cmd
+}
+
+func newMetaPartitionResplitCmd(client *master.MasterClient) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   CliOpResplit + " [VOLUME] [META PARTITION ID] [START]",
+		Short: cmdMetaPartitionResplitShort,
+		Long: `Move the inodes from the start to the end of the meta partition into a new meta
+partition, and then shrink the meta partition to end before the start. The meta
+partition is frozen while its inodes and dentries are moved.`,
+		Args: cobra.MinimumNArgs(3),
+		Run: func(cmd *cobra.Command, args []string) {
+			var (
+				err         error
+				partitionID uint64
+				start       uint64
+				task        *proto.MetaMoveTask
+			)
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			if partitionID, err = strconv.ParseUint(args[1], 10, 64); err != nil {
+				return
+			}
+			if start, err = strconv.ParseUint(args[2], 10, 64); err != nil {
+				return
+			}
+			if task, err = client.AdminAPI().ResplitMetaPartition(args[0], partitionID, start); err != nil {
+				return
+			}
+			stdout("Re-splitting meta partition %v, range [%v, %v]\n", task.Source, task.Start, task.End)
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return validVols(client, toComplete), cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	return cmd
+}
+
+func newMetaPartitionMoveStatusCmd(client *master.MasterClient) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   CliOpMoveStatus,
+		Short: cmdMetaPartitionMoveStatusShort,
+		Run: func(cmd *cobra.Command, args []string) {
+			var (
+				err    error
+				status *proto.MetaMoveStatus
+			)
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			if status, err = client.AdminAPI().GetMetaMoveStatus(); err != nil {
+				return
+			}
+			stdout("%v", formatMetaMoveStatus(status))
+		},
+	}
+	return cmd
+}
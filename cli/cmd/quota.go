@@ -0,0 +1,284 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/sdk/master"
+	"github.com/chubaofs/chubaofs/sdk/meta"
+	"github.com/spf13/cobra"
+)
+
+const (
+	cmdQuotaUse   = "quota [COMMAND]"
+	cmdQuotaShort = "Manage directory and user quotas of volume"
+)
+
+func newQuotaCmd(client *master.MasterClient) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   cmdQuotaUse,
+		Short: cmdQuotaShort,
+		Args:  cobra.MinimumNArgs(0),
+	}
+	cmd.AddCommand(
+		newQuotaSetCmd(client),
+		newQuotaListCmd(client),
+		newQuotaUsageCmd(client),
+		newQuotaDeleteCmd(client),
+	)
+	return cmd
+}
+
+const (
+	cmdQuotaSetUse   = "set [VOLUME NAME]"
+	cmdQuotaSetShort = "Set the quota of a directory, a user or a group"
+)
+
+func newQuotaSetCmd(client *master.MasterClient) *cobra.Command {
+	var optType string
+	var optPath string
+	var optTarget uint64
+	var optMaxBytes uint64
+	var optMaxInodes uint64
+	var cmd = &cobra.Command{
+		Use:   cmdQuotaSetUse,
+		Short: cmdQuotaSetShort,
+		Args:  cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			var volumeName = args[0]
+			var quotaType = proto.ParseQuotaType(optType)
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			if !quotaType.Valid() {
+				err = fmt.Errorf("Invalid quota type. ")
+				return
+			}
+			var svv *proto.SimpleVolView
+			if svv, err = client.AdminAPI().GetVolumeSimpleInfo(volumeName); err != nil {
+				err = fmt.Errorf("Set quota failed:\n%v\n", err)
+				return
+			}
+
+			var mw *meta.MetaWrapper
+			var target = optTarget
+			if quotaType == proto.QuotaTypeDir {
+				if optPath == "" {
+					err = fmt.Errorf("Path of directory is required for directory quota. ")
+					return
+				}
+				if mw, err = newVolMetaWrapper(client, svv); err != nil {
+					err = fmt.Errorf("Set quota failed:\n%v\n", err)
+					return
+				}
+				defer mw.Close()
+				if target, err = mw.LookupPath(optPath); err != nil {
+					err = fmt.Errorf("Lookup path [%v] failed: %v\n", optPath, err)
+					return
+				}
+			}
+
+			var quota *proto.QuotaInfo
+			if quota, err = client.AdminAPI().SetQuota(volumeName, calcAuthKey(svv.Owner), quotaType, target, optMaxBytes, optMaxInodes); err != nil {
+				err = fmt.Errorf("Set quota failed:\n%v\n", err)
+				return
+			}
+			if mw != nil {
+				// tag the existing inodes of the directory tree with the quota
+				var count int
+				if count, err = mw.ApplyDirQuota(target, quota.QuotaID); err != nil {
+					err = fmt.Errorf("Apply quota [%v] to directory [%v] failed after [%v] inodes: %v\n",
+						quota.QuotaID, optPath, count, err)
+					return
+				}
+				stdout("Apply quota to %v inodes of directory [%v].\n", count, optPath)
+			}
+			stdout("Set quota success:\n")
+			stdout("%v", formatQuotaInfo(quota))
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return validVols(client, toComplete), cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	cmd.Flags().StringVar(&optType, "type", "dir", "Specify quota type [dir | uid | gid]")
+	cmd.Flags().StringVar(&optPath, "path", "", "Specify path of directory in volume for directory quota")
+	cmd.Flags().Uint64Var(&optTarget, "target", 0, "Specify uid or gid for user or group quota")
+	cmd.Flags().Uint64Var(&optMaxBytes, "max-bytes", 0, "Specify max bytes, 0 means unlimited")
+	cmd.Flags().Uint64Var(&optMaxInodes, "max-inodes", 0, "Specify max inodes, 0 means unlimited")
+	return cmd
+}
+
+const (
+	cmdQuotaListUse   = "list [VOLUME NAME]"
+	cmdQuotaListShort = "List quotas of volume"
+)
+
+func newQuotaListCmd(client *master.MasterClient) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:     cmdQuotaListUse,
+		Short:   cmdQuotaListShort,
+		Aliases: []string{"ls"},
+		Args:    cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			var volumeName = args[0]
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			var quotas []*proto.QuotaInfo
+			if quotas, err = client.AdminAPI().ListQuotas(volumeName); err != nil {
+				err = fmt.Errorf("List quotas failed:\n%v\n", err)
+				return
+			}
+			stdout("%v\n", quotaInfoTableHeader)
+			for _, quota := range quotas {
+				stdout("%v\n", formatQuotaInfoTableRow(quota))
+			}
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return validVols(client, toComplete), cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	return cmd
+}
+
+const (
+	cmdQuotaUsageUse   = "usage [VOLUME NAME] [QUOTA ID]"
+	cmdQuotaUsageShort = "Show usage of the quota"
+)
+
+func newQuotaUsageCmd(client *master.MasterClient) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   cmdQuotaUsageUse,
+		Short: cmdQuotaUsageShort,
+		Args:  cobra.MinimumNArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			var volumeName = args[0]
+			var quotaID uint64
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			if quotaID, err = strconv.ParseUint(args[1], 10, 64); err != nil {
+				return
+			}
+			var quota *proto.QuotaInfo
+			if quota, err = client.AdminAPI().GetQuota(volumeName, quotaID); err != nil {
+				err = fmt.Errorf("Get quota failed:\n%v\n", err)
+				return
+			}
+			stdout("Summary:\n%s\n", formatQuotaInfo(quota))
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return validVols(client, toComplete), cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	return cmd
+}
+
+const (
+	cmdQuotaDeleteUse   = "delete [VOLUME NAME] [QUOTA ID]"
+	cmdQuotaDeleteShort = "Delete the quota of volume"
+)
+
+func newQuotaDeleteCmd(client *master.MasterClient) *cobra.Command {
+	var optYes bool
+	var cmd = &cobra.Command{
+		Use:   cmdQuotaDeleteUse,
+		Short: cmdQuotaDeleteShort,
+		Args:  cobra.MinimumNArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			var volumeName = args[0]
+			var quotaID uint64
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			if quotaID, err = strconv.ParseUint(args[1], 10, 64); err != nil {
+				return
+			}
+			// ask user for confirm
+			if !optYes {
+				stdout("Delete quota [%v] of volume [%v] (yes/no)[no]:", quotaID, volumeName)
+				var userConfirm string
+				_, _ = fmt.Scanln(&userConfirm)
+				if userConfirm != "yes" {
+					err = fmt.Errorf("Abort by user.\n")
+					return
+				}
+			}
+
+			var svv *proto.SimpleVolView
+			if svv, err = client.AdminAPI().GetVolumeSimpleInfo(volumeName); err != nil {
+				err = fmt.Errorf("Delete quota failed:\n%v\n", err)
+				return
+			}
+			var quota *proto.QuotaInfo
+			if quota, err = client.AdminAPI().GetQuota(volumeName, quotaID); err != nil {
+				err = fmt.Errorf("Delete quota failed:\n%v\n", err)
+				return
+			}
+			if err = client.AdminAPI().DeleteQuota(volumeName, calcAuthKey(svv.Owner), quotaID); err != nil {
+				err = fmt.Errorf("Delete quota failed:\n%v\n", err)
+				return
+			}
+			stdout("Delete quota success.\n")
+
+			if quota.Type != proto.QuotaTypeDir {
+				return
+			}
+			// the tags left on the inodes are ignored by metanode, remove them as well
+			var mw *meta.MetaWrapper
+			if mw, err = newVolMetaWrapper(client, svv); err != nil {
+				err = fmt.Errorf("Revoke quota from directory failed:\n%v\n", err)
+				return
+			}
+			defer mw.Close()
+			if _, err = mw.RevokeDirQuota(quota.Target, quotaID); err != nil {
+				err = fmt.Errorf("Revoke quota from directory failed:\n%v\n", err)
+				return
+			}
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return validVols(client, toComplete), cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	cmd.Flags().BoolVarP(&optYes, "yes", "y", false, "Answer yes for all questions")
+	return cmd
+}
@@ -62,6 +62,8 @@ func NewRootCmd(client *master.MasterClient) *ChubaoFSCmd {
 		newConfigCmd(),
 		newCompatibilityCmd(),
 		newZoneCmd(client),
+		newQuotaCmd(client),
+		newTrashCmd(client),
 	)
 	return cmd
 }
@@ -0,0 +1,229 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/sdk/master"
+	"github.com/chubaofs/chubaofs/sdk/meta"
+	"github.com/spf13/cobra"
+)
+
+const (
+	cmdTrashUse   = "trash [COMMAND]"
+	cmdTrashShort = "Manage the deleted files in trash of volume"
+)
+
+func newTrashCmd(client *master.MasterClient) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   cmdTrashUse,
+		Short: cmdTrashShort,
+		Args:  cobra.MinimumNArgs(0),
+	}
+	cmd.AddCommand(
+		newTrashListCmd(client),
+		newTrashRestoreCmd(client),
+		newTrashPurgeCmd(client),
+	)
+	return cmd
+}
+
+// openTrash returns a meta wrapper of the volume to operate the trash.
+func openTrash(client *master.MasterClient, volumeName string) (mw *meta.MetaWrapper, err error) {
+	var svv *proto.SimpleVolView
+	if svv, err = client.AdminAPI().GetVolumeSimpleInfo(volumeName); err != nil {
+		return
+	}
+	return newVolMetaWrapper(client, svv)
+}
+
+const (
+	cmdTrashListUse   = "list [VOLUME NAME]"
+	cmdTrashListShort = "List the deleted files in trash of volume"
+)
+
+func newTrashListCmd(client *master.MasterClient) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:     cmdTrashListUse,
+		Short:   cmdTrashListShort,
+		Aliases: []string{"ls"},
+		Args:    cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			var volumeName = args[0]
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			var mw *meta.MetaWrapper
+			if mw, err = openTrash(client, volumeName); err != nil {
+				err = fmt.Errorf("List trash failed:\n%v\n", err)
+				return
+			}
+			defer mw.Close()
+			var infos []*proto.TrashInfo
+			if infos, err = mw.ListTrash_ll(); err != nil {
+				err = fmt.Errorf("List trash failed:\n%v\n", err)
+				return
+			}
+			sort.Slice(infos, func(i, j int) bool { return infos[i].DeleteTime < infos[j].DeleteTime })
+			stdout("%v\n", trashInfoTableHeader)
+			for _, info := range infos {
+				stdout("%v\n", formatTrashInfoTableRow(info))
+			}
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return validVols(client, toComplete), cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	return cmd
+}
+
+const (
+	cmdTrashRestoreUse   = "restore [VOLUME NAME] [INODE]"
+	cmdTrashRestoreShort = "Restore the deleted file from trash of volume"
+)
+
+func newTrashRestoreCmd(client *master.MasterClient) *cobra.Command {
+	var optPath string
+	var cmd = &cobra.Command{
+		Use:   cmdTrashRestoreUse,
+		Short: cmdTrashRestoreShort,
+		Args:  cobra.MinimumNArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			var volumeName = args[0]
+			var ino uint64
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			if ino, err = strconv.ParseUint(args[1], 10, 64); err != nil {
+				return
+			}
+			var mw *meta.MetaWrapper
+			if mw, err = openTrash(client, volumeName); err != nil {
+				err = fmt.Errorf("Restore file failed:\n%v\n", err)
+				return
+			}
+			defer mw.Close()
+			var restored string
+			if restored, err = mw.RestoreTrash_ll(ino, optPath); err != nil {
+				err = fmt.Errorf("Restore file [%v] failed: %v\n", ino, err)
+				return
+			}
+			if restored == "" {
+				restored = "original directory"
+			}
+			stdout("Restore file [%v] to [%v] success.\n", ino, restored)
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return validVols(client, toComplete), cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	cmd.Flags().StringVar(&optPath, "path", "", "Specify path in volume to restore to, default is the original path")
+	return cmd
+}
+
+const (
+	cmdTrashPurgeUse   = "purge [VOLUME NAME] [INODE]..."
+	cmdTrashPurgeShort = "Delete the files from trash of volume permanently"
+)
+
+func newTrashPurgeCmd(client *master.MasterClient) *cobra.Command {
+	var optAll bool
+	var optYes bool
+	var cmd = &cobra.Command{
+		Use:   cmdTrashPurgeUse,
+		Short: cmdTrashPurgeShort,
+		Args:  cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			var volumeName = args[0]
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			if len(args) == 1 && !optAll {
+				err = fmt.Errorf("Specify the inodes to purge, or --all for all of the files. ")
+				return
+			}
+			var inos = make([]uint64, 0, len(args)-1)
+			for _, arg := range args[1:] {
+				var ino uint64
+				if ino, err = strconv.ParseUint(arg, 10, 64); err != nil {
+					return
+				}
+				inos = append(inos, ino)
+			}
+			var mw *meta.MetaWrapper
+			if mw, err = openTrash(client, volumeName); err != nil {
+				err = fmt.Errorf("Purge trash failed:\n%v\n", err)
+				return
+			}
+			defer mw.Close()
+			if optAll {
+				var infos []*proto.TrashInfo
+				if infos, err = mw.ListTrash_ll(); err != nil {
+					err = fmt.Errorf("Purge trash failed:\n%v\n", err)
+					return
+				}
+				inos = inos[:0]
+				for _, info := range infos {
+					inos = append(inos, info.Inode)
+				}
+			}
+			// ask user for confirm
+			if !optYes {
+				stdout("Purge %v files from trash of volume [%v] permanently (yes/no)[no]:", len(inos), volumeName)
+				var userConfirm string
+				_, _ = fmt.Scanln(&userConfirm)
+				if userConfirm != "yes" {
+					err = fmt.Errorf("Abort by user.\n")
+					return
+				}
+			}
+			for _, ino := range inos {
+				if err = mw.PurgeTrash_ll(ino); err != nil {
+					err = fmt.Errorf("Purge file [%v] failed: %v\n", ino, err)
+					return
+				}
+			}
+			stdout("Purge %v files success.\n", len(inos))
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return validVols(client, toComplete), cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	cmd.Flags().BoolVar(&optAll, "all", false, "Purge all of the files in trash")
+	cmd.Flags().BoolVarP(&optYes, "yes", "y", false, "Answer yes for all questions")
+	return cmd
+}
@@ -24,6 +24,7 @@ import (
 
 	"github.com/chubaofs/chubaofs/proto"
 	"github.com/chubaofs/chubaofs/sdk/master"
+	"github.com/chubaofs/chubaofs/sdk/meta"
 	"github.com/spf13/cobra"
 )
 
@@ -49,6 +50,7 @@ func newVolCmd(client *master.MasterClient) *cobra.Command {
 		newVolDeleteCmd(client),
 		newVolTransferCmd(client),
 		newVolAddDPCmd(client),
+		newVolSummaryCmd(client),
 	)
 	return cmd
 }
@@ -105,6 +107,8 @@ func newVolCreateCmd(client *master.MasterClient) *cobra.Command {
 	var optYes bool
 	var optCrossZone bool
 	var optZoneName string
+	var optECDataNum int
+	var optECParityNum int
 	var cmd = &cobra.Command{
 		Use:   cmdVolCreateUse,
 		Short: cmdVolCreateShort,
@@ -126,7 +130,11 @@ func newVolCreateCmd(client *master.MasterClient) *cobra.Command {
 				stdout("  Data partition size : %v GB\n", optDPSize)
 				stdout("  Meta partition count: %v\n", optMPCount)
 				stdout("  Capacity            : %v GB\n", optCapacity)
-				stdout("  Replicas            : %v\n", optReplicas)
+				if optECDataNum > 0 {
+					stdout("  Erasure coding      : %v\n", formatErasureCoding(uint8(optECDataNum), uint8(optECParityNum)))
+				} else {
+					stdout("  Replicas            : %v\n", optReplicas)
+				}
 				stdout("  Allow follower read : %v\n", formatEnabledDisabled(optFollowerRead))
 				stdout("  ZoneName            : %v\n", optZoneName)
 				stdout("  CrossZone            : %v\n", optCrossZone)
@@ -141,7 +149,7 @@ func newVolCreateCmd(client *master.MasterClient) *cobra.Command {
 
 			err = client.AdminAPI().CreateVolume(
 				volumeName, userID, optMPCount, optDPSize,
-				optCapacity, optReplicas, optFollowerRead, optZoneName, optCrossZone)
+				optCapacity, optReplicas, optFollowerRead, optZoneName, optCrossZone, optECDataNum, optECParityNum)
 			if err != nil {
 				err = fmt.Errorf("Create volume failed case:\n%v\n", err)
 				return
@@ -158,6 +166,8 @@ func newVolCreateCmd(client *master.MasterClient) *cobra.Command {
 	cmd.Flags().StringVar(&optZoneName, CliFlagZoneName, cmdVolDefaultZoneName, "Specify volume zone name")
 	cmd.Flags().BoolVarP(&optYes, "yes", "y", false, "Answer yes for all questions")
 	cmd.Flags().BoolVar(&optCrossZone, CliFlagCrossZone, cmdVolDefaultCrossZone, "Disable cross zone")
+	cmd.Flags().IntVar(&optECDataNum, CliFlagECDataNum, 0, "Specify data shards number to create erasure-coded volume")
+	cmd.Flags().IntVar(&optECParityNum, CliFlagECParityNum, 0, "Specify parity shards number to create erasure-coded volume")
 
 	return cmd
 }
@@ -173,6 +183,7 @@ func newVolSetCmd(client *master.MasterClient) *cobra.Command {
 	var optAuthenticate string
 	var optEnableToken string
 	var optZoneName string
+	var optTrashDays int64
 	var optYes bool
 	var confirmString = strings.Builder{}
 	var vv *proto.SimpleVolView
@@ -252,6 +263,13 @@ func newVolSetCmd(client *master.MasterClient) *cobra.Command {
 			if vv.CrossZone == true && "" != optZoneName {
 				err = fmt.Errorf("Can not set zone name of the volume that cross zone\n")
 			}
+			if optTrashDays >= 0 {
+				isChange = true
+				confirmString.WriteString(fmt.Sprintf("  Trash days          : %v -> %v\n", formatTrashDays(vv.TrashDays), formatTrashDays(uint32(optTrashDays))))
+				vv.TrashDays = uint32(optTrashDays)
+			} else {
+				confirmString.WriteString(fmt.Sprintf("  Trash days          : %v\n", formatTrashDays(vv.TrashDays)))
+			}
 			if err != nil {
 				return
 			}
@@ -271,7 +289,7 @@ func newVolSetCmd(client *master.MasterClient) *cobra.Command {
 				}
 			}
 			err = client.AdminAPI().UpdateVolume(vv.Name, vv.Capacity, int(vv.DpReplicaNum),
-				vv.FollowerRead, vv.Authenticate, vv.EnableToken, calcAuthKey(vv.Owner), vv.ZoneName)
+				vv.FollowerRead, vv.Authenticate, vv.EnableToken, calcAuthKey(vv.Owner), vv.ZoneName, vv.TrashDays)
 			if err != nil {
 				return
 			}
@@ -290,6 +308,7 @@ func newVolSetCmd(client *master.MasterClient) *cobra.Command {
 	cmd.Flags().StringVar(&optFollowerRead, CliFlagEnableFollowerRead, "", "Enable read form replica follower")
 	cmd.Flags().StringVar(&optAuthenticate, CliFlagAuthenticate, "", "Enable authenticate")
 	cmd.Flags().StringVar(&optZoneName, CliFlagZoneName, "", "Specify volume zone name")
+	cmd.Flags().Int64Var(&optTrashDays, CliFlagTrashDays, -1, "Specify days to keep the deleted files in trash, 0 means disabled")
 	cmd.Flags().BoolVarP(&optYes, "yes", "y", false, "Answer yes for all questions")
 	return cmd
 }
@@ -533,6 +552,59 @@ func newVolAddDPCmd(client *master.MasterClient) *cobra.Command {
 	return cmd
 }
 
+const (
+	cmdVolSummaryUse   = "summary [VOLUME] [PATH]"
+	cmdVolSummaryShort = "Show the recursive summary of a directory in volume"
+)
+
+func newVolSummaryCmd(client *master.MasterClient) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   cmdVolSummaryUse,
+		Short: cmdVolSummaryShort,
+		Args:  cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var volumeName = args[0]
+			var path = "/"
+			var err error
+			defer func() {
+				if err != nil {
+					errout("Error: %v", err)
+				}
+			}()
+			if len(args) > 1 {
+				path = args[1]
+			}
+			var svv *proto.SimpleVolView
+			if svv, err = client.AdminAPI().GetVolumeSimpleInfo(volumeName); err != nil {
+				return
+			}
+			var mw *meta.MetaWrapper
+			if mw, err = newVolMetaWrapper(client, svv); err != nil {
+				return
+			}
+			defer mw.Close()
+			var ino uint64
+			if ino, err = mw.LookupPath(path); err != nil {
+				err = fmt.Errorf("Lookup path [%v] failed: %v\n", path, err)
+				return
+			}
+			var summary *proto.DirSummary
+			if summary, err = mw.DirSummary_ll(ino); err != nil {
+				err = fmt.Errorf("Get summary of [%v] failed: %v\n", path, err)
+				return
+			}
+			stdout("Summary of [%v]:\n%v", path, formatDirSummary(summary))
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return validVols(client, toComplete), cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	return cmd
+}
+
 const (
 	cmdExpandVolCmdShort = "Expand capacity of a volume"
 	cmdShrinkVolCmdShort = "Shrink capacity of a volume"
@@ -589,3 +661,11 @@ func calcAuthKey(key string) (authKey string) {
 	cipherStr := h.Sum(nil)
 	return strings.ToLower(hex.EncodeToString(cipherStr))
 }
+
+func newVolMetaWrapper(client *master.MasterClient, svv *proto.SimpleVolView) (*meta.MetaWrapper, error) {
+	return meta.NewMetaWrapper(&meta.MetaConfig{
+		Volume:  svv.Name,
+		Owner:   svv.Owner,
+		Masters: client.Nodes(),
+	})
+}
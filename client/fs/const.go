@@ -18,7 +18,7 @@ import (
 	"syscall"
 	"time"
 
-	"bazil.org/fuse"
+	"github.com/chubaofs/chubaofs/depends/bazil.org/fuse"
 
 	"github.com/chubaofs/chubaofs/proto"
 )
@@ -30,6 +30,8 @@ const (
 const (
 	DefaultBlksize    = uint32(1) << 12
 	DefaultMaxNameLen = uint32(256)
+	// the max depth of the path of the files moved to the trash
+	MaxPathDepth = 1024
 )
 
 const (
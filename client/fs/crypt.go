@@ -0,0 +1,73 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package fs
+
+import (
+	"github.com/chubaofs/chubaofs/depends/bazil.org/fuse"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// With the encryption enabled, every file created by the client is given a
+// random data key by the authnode, which is kept in the extend attribute of
+// the file encrypted by the master key of the volume. The master key never
+// leaves the authnode, and the data key is decrypted by the authnode when the
+// file is opened, so the data nodes only see the encrypted data.
+
+// newFileKey generates the data key of the new file.
+func (s *Super) newFileKey(ino uint64) error {
+	plainKey, cipherKey, err := s.ac.API().GenDataKey(s.owner, s.clientKey, s.volname)
+	if err != nil {
+		log.LogErrorf("newFileKey: ino(%v) err(%v)", ino, err)
+		return fuse.EIO
+	}
+	if err = s.mw.XAttrSet_ll(ino, []byte(proto.CryptKeyXAttrKey), []byte(cipherKey)); err != nil {
+		log.LogErrorf("newFileKey: ino(%v) err(%v)", ino, err)
+		return ParseError(err)
+	}
+	if err = s.ec.SetFileKey(ino, plainKey); err != nil {
+		log.LogErrorf("newFileKey: ino(%v) err(%v)", ino, err)
+		return fuse.EIO
+	}
+	return nil
+}
+
+// loadFileKey loads the data key of the file if it is encrypted.
+func (s *Super) loadFileKey(ino uint64) error {
+	if s.ec.HasFileKey(ino) {
+		return nil
+	}
+	info, err := s.mw.XAttrGet_ll(ino, proto.CryptKeyXAttrKey)
+	if err != nil {
+		log.LogErrorf("loadFileKey: ino(%v) err(%v)", ino, err)
+		return ParseError(err)
+	}
+	cipherKey := info.Get(proto.CryptKeyXAttrKey)
+	if len(cipherKey) == 0 {
+		// the file is not encrypted
+		return nil
+	}
+	plainKey, err := s.ac.API().DecryptDataKey(s.owner, s.clientKey, s.volname, string(cipherKey))
+	if err != nil {
+		log.LogErrorf("loadFileKey: ino(%v) err(%v)", ino, err)
+		return fuse.EPERM
+	}
+	if err = s.ec.SetFileKey(ino, plainKey); err != nil {
+		log.LogErrorf("loadFileKey: ino(%v) err(%v)", ino, err)
+		return fuse.EIO
+	}
+	return nil
+}
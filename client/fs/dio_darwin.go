@@ -15,7 +15,7 @@
 package fs
 
 import (
-	"bazil.org/fuse"
+	"github.com/chubaofs/chubaofs/depends/bazil.org/fuse"
 )
 
 func isDirectIOEnabled(flags fuse.OpenFlags) bool {
@@ -19,8 +19,8 @@ import (
 	"syscall"
 	"time"
 
-	"bazil.org/fuse"
-	"bazil.org/fuse/fs"
+	"github.com/chubaofs/chubaofs/depends/bazil.org/fuse"
+	"github.com/chubaofs/chubaofs/depends/bazil.org/fuse/fs"
 	"golang.org/x/net/context"
 
 	"github.com/chubaofs/chubaofs/proto"
@@ -33,6 +33,11 @@ type Dir struct {
 	super  *Super
 	info   *proto.InodeInfo
 	dcache *DentryCache
+
+	// The parent inode and the name of the directory, which build the path
+	// of the files moved to the trash. Protected by super.fslock.
+	parentIno uint64
+	name      string
 }
 
 // Functions that Dir needs to implement
@@ -92,6 +97,12 @@ func (d *Dir) Create(ctx context.Context, req *fuse.CreateRequest, resp *fuse.Cr
 		return nil, nil, ParseError(err)
 	}
 
+	if d.super.encryption {
+		if err = d.super.newFileKey(info.Inode); err != nil {
+			return nil, nil, err
+		}
+	}
+
 	d.super.ic.Put(info)
 	child := NewFile(d.super, info)
 	d.super.ec.OpenStream(info.Inode)
@@ -146,6 +157,7 @@ func (d *Dir) Mkdir(ctx context.Context, req *fuse.MkdirRequest) (fs.Node, error
 	child := NewDir(d.super, info)
 
 	d.super.fslock.Lock()
+	child.(*Dir).parentIno, child.(*Dir).name = d.info.Inode, req.Name
 	d.super.nodeCache[info.Inode] = child
 	d.super.fslock.Unlock()
 
@@ -167,6 +179,20 @@ func (d *Dir) Remove(ctx context.Context, req *fuse.RemoveRequest) error {
 		metric.SetWithLabels(err, map[string]string{exporter.Vol: d.super.volname})
 	}()
 
+	if !req.Dir && d.super.mw.TrashEnabled() {
+		var moved bool
+		moved, err = d.super.mw.MoveToTrash_ll(d.info.Inode, req.Name, d.super.filePath(d, req.Name))
+		if err != nil {
+			log.LogErrorf("Remove: move to trash failed, parent(%v) name(%v) err(%v)", d.info.Inode, req.Name, err)
+			return ParseError(err)
+		}
+		if moved {
+			d.super.ic.Delete(d.info.Inode)
+			log.LogDebugf("TRACE Remove: parent(%v) req(%v) moved to trash (%v)ns", d.info.Inode, req, time.Since(start).Nanoseconds())
+			return nil
+		}
+	}
+
 	info, err := d.super.mw.Delete_ll(d.info.Inode, req.Name, req.Dir)
 	if err != nil {
 		log.LogErrorf("Remove: parent(%v) name(%v) err(%v)", d.info.Inode, req.Name, err)
@@ -228,6 +254,9 @@ func (d *Dir) Lookup(ctx context.Context, req *fuse.LookupRequest, resp *fuse.Lo
 		}
 		d.super.nodeCache[ino] = child
 	}
+	if dir, ok := child.(*Dir); ok {
+		dir.parentIno, dir.name = d.info.Inode, req.Name
+	}
 	d.super.fslock.Unlock()
 
 	resp.EntryValid = LookupValidDuration
@@ -304,6 +333,9 @@ func (d *Dir) Rename(ctx context.Context, req *fuse.RenameRequest, newDir fs.Nod
 
 	d.super.ic.Delete(d.info.Inode)
 	d.super.ic.Delete(dstDir.info.Inode)
+	if d.super.mw.TrashEnabled() {
+		d.super.renameDir(d.info.Inode, req.OldName, dstDir.info.Inode, req.NewName)
+	}
 
 	elapsed := time.Since(start)
 	log.LogDebugf("TRACE Rename: SrcParent(%v) OldName(%v) DstParent(%v) NewName(%v) (%v)ns", d.info.Inode, req.OldName, dstDir.info.Inode, req.NewName, elapsed.Nanoseconds())
@@ -440,9 +472,44 @@ func (d *Dir) Link(ctx context.Context, req *fuse.LinkRequest, old fs.Node) (fs.
 	return newFile, nil
 }
 
-// Getxattr has not been implemented yet.
+// Getxattr gets the extend attribute of the directory. The summary of the
+// directory is answered by the virtual extend attributes, such as cfs.dir.rbytes.
 func (d *Dir) Getxattr(ctx context.Context, req *fuse.GetxattrRequest, resp *fuse.GetxattrResponse) error {
-	return fuse.ENOSYS
+	if !d.super.enableXattr {
+		return fuse.ENOSYS
+	}
+	ino := d.info.Inode
+	name := req.Name
+	var value []byte
+	if proto.IsSummaryXAttr(name) {
+		summary, err := d.super.mw.DirSummary_ll(ino)
+		if err != nil {
+			log.LogErrorf("GetXattr: ino(%v) name(%v) err(%v)", ino, name, err)
+			return ParseError(err)
+		}
+		if name == proto.SummaryXAttrKey {
+			value = summary.Marshal()
+		} else {
+			v, _ := summary.XAttr(name)
+			value = []byte(v)
+		}
+	} else {
+		info, err := d.super.mw.XAttrGet_ll(ino, name)
+		if err != nil {
+			log.LogErrorf("GetXattr: ino(%v) name(%v) err(%v)", ino, name, err)
+			return ParseError(err)
+		}
+		value = info.Get(name)
+	}
+	if req.Position > 0 {
+		value = value[req.Position:]
+	}
+	if req.Size > 0 && req.Size < uint32(len(value)) {
+		value = value[:req.Size]
+	}
+	resp.Xattr = value
+	log.LogDebugf("TRACE GetXattr: ino(%v) name(%v)", ino, name)
+	return nil
 }
 
 // Listxattr has not been implemented yet.
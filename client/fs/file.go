@@ -17,10 +17,11 @@ package fs
 import (
 	"fmt"
 	"io"
+	"syscall"
 	"time"
 
-	"bazil.org/fuse"
-	"bazil.org/fuse/fs"
+	"github.com/chubaofs/chubaofs/depends/bazil.org/fuse"
+	"github.com/chubaofs/chubaofs/depends/bazil.org/fuse/fs"
 	"golang.org/x/net/context"
 
 	"sync"
@@ -105,6 +106,7 @@ func (f *File) Forget() {
 		log.LogWarnf("Forget: stream not ready to evict, ino(%v) err(%v)", ino, err)
 		return
 	}
+	f.super.ec.RemoveFileKey(ino)
 
 	if !f.super.orphan.Evict(ino) {
 		return
@@ -120,6 +122,24 @@ func (f *File) Open(ctx context.Context, req *fuse.OpenRequest, resp *fuse.OpenR
 	ino := f.info.Inode
 	start := time.Now()
 
+	if !req.Flags.IsReadOnly() {
+		// the files locked by the object lock can only be opened to read
+		var locked bool
+		if locked, err = f.super.mw.IsObjectLocked_ll(ino); err != nil {
+			log.LogErrorf("Open: ino(%v) req(%v) err(%v)", ino, req, err)
+			return nil, ParseError(err)
+		}
+		if locked {
+			return nil, fuse.EPERM
+		}
+	}
+
+	if f.super.encryption {
+		if err = f.super.loadFileKey(ino); err != nil {
+			return nil, err
+		}
+	}
+
 	f.super.ec.OpenStream(ino)
 
 	f.super.ec.RefreshExtentsCache(ino)
@@ -148,6 +168,12 @@ func (f *File) Release(ctx context.Context, req *fuse.ReleaseRequest) (err error
 		return fuse.EIO
 	}
 
+	if f.super.enableLock && req.ReleaseFlags&fuse.ReleaseFlockUnlock != 0 {
+		if err = f.releaseLocks(req.LockOwner, true); err != nil {
+			log.LogErrorf("Release: release flock failed, ino(%v) req(%v) err(%v)", ino, req, err)
+		}
+	}
+
 	f.super.ic.Delete(ino)
 	elapsed := time.Since(start)
 	log.LogDebugf("TRACE Release: ino(%v) req(%v) (%v)ns", ino, req, elapsed.Nanoseconds())
@@ -235,6 +261,10 @@ func (f *File) Write(ctx context.Context, req *fuse.WriteRequest, resp *fuse.Wri
 	}()
 
 	size, err := f.super.ec.Write(ino, int(req.Offset), req.Data, flags)
+	if err == syscall.EDQUOT {
+		log.LogWarnf("Write: ino(%v) offset(%v) len(%v) quota exceeded", ino, req.Offset, reqlen)
+		return ParseError(err)
+	}
 	if err != nil {
 		msg := fmt.Sprintf("Write: ino(%v) offset(%v) len(%v) err(%v)", ino, req.Offset, reqlen, err)
 		f.super.handleError("Write", msg)
@@ -247,7 +277,10 @@ func (f *File) Write(ctx context.Context, req *fuse.WriteRequest, resp *fuse.Wri
 	}
 
 	if waitForFlush {
-		if err = f.super.ec.Flush(ino); err != nil {
+		if err = f.super.ec.Flush(ino); err == syscall.EDQUOT {
+			log.LogWarnf("Write: ino(%v) offset(%v) len(%v) quota exceeded", ino, req.Offset, reqlen)
+			return ParseError(err)
+		} else if err != nil {
 			msg := fmt.Sprintf("Write: failed to wait for flush, ino(%v) offset(%v) len(%v) err(%v) req(%v)", ino, req.Offset, reqlen, err, req)
 			f.super.handleError("Wrtie", msg)
 			return fuse.EIO
@@ -262,7 +295,16 @@ func (f *File) Write(ctx context.Context, req *fuse.WriteRequest, resp *fuse.Wri
 
 // Flush only when fsyncOnClose is enabled.
 func (f *File) Flush(ctx context.Context, req *fuse.FlushRequest) (err error) {
+	if f.super.enableLock {
+		// POSIX locks of the owner are released upon any close of the file
+		if err = f.releaseLocks(req.LockOwner, false); err != nil {
+			log.LogErrorf("Flush: release locks failed, ino(%v) req(%v) err(%v)", f.info.Inode, req, err)
+		}
+	}
 	if !f.super.fsyncOnClose {
+		if f.super.enableLock {
+			return nil
+		}
 		return fuse.ENOSYS
 	}
 	log.LogDebugf("TRACE Flush enter: ino(%v)", f.info.Inode)
@@ -274,6 +316,10 @@ func (f *File) Flush(ctx context.Context, req *fuse.FlushRequest) (err error) {
 	}()
 
 	err = f.super.ec.Flush(f.info.Inode)
+	if err == syscall.EDQUOT {
+		log.LogWarnf("Flush: ino(%v) quota exceeded", f.info.Inode)
+		return ParseError(err)
+	}
 	if err != nil {
 		msg := fmt.Sprintf("Flush: ino(%v) err(%v)", f.info.Inode, err)
 		f.super.handleError("Flush", msg)
@@ -290,6 +336,10 @@ func (f *File) Fsync(ctx context.Context, req *fuse.FsyncRequest) (err error) {
 	log.LogDebugf("TRACE Fsync enter: ino(%v)", f.info.Inode)
 	start := time.Now()
 	err = f.super.ec.Flush(f.info.Inode)
+	if err == syscall.EDQUOT {
+		log.LogWarnf("Fsync: ino(%v) quota exceeded", f.info.Inode)
+		return ParseError(err)
+	}
 	if err != nil {
 		msg := fmt.Sprintf("Fsync: ino(%v) err(%v)", f.info.Inode, err)
 		f.super.handleError("Fsync", msg)
@@ -413,6 +463,12 @@ func (f *File) Setxattr(ctx context.Context, req *fuse.SetxattrRequest) error {
 	ino := f.info.Inode
 	name := req.Name
 	value := req.Xattr
+	if name == proto.QuotaXAttrKey || name == proto.CryptKeyXAttrKey || proto.IsSummaryXAttr(name) ||
+		name == proto.RetentionXAttrKey || name == proto.LegalHoldXAttrKey {
+		// the directory quotas are only changed by the administrator,
+		// and the object lock is only changed by the object nodes
+		return fuse.EPERM
+	}
 	// TODO： implement flag to improve compatible (Mofei Zhang)
 	if err := f.super.mw.XAttrSet_ll(ino, []byte(name), []byte(value)); err != nil {
 		log.LogErrorf("Setxattr: ino(%v) name(%v) err(%v)", ino, name, err)
@@ -429,6 +485,10 @@ func (f *File) Removexattr(ctx context.Context, req *fuse.RemovexattrRequest) er
 	}
 	ino := f.info.Inode
 	name := req.Name
+	if name == proto.QuotaXAttrKey || name == proto.CryptKeyXAttrKey || proto.IsSummaryXAttr(name) ||
+		name == proto.RetentionXAttrKey || name == proto.LegalHoldXAttrKey {
+		return fuse.EPERM
+	}
 	if err := f.super.mw.XAttrDel_ll(ino, name); err != nil {
 		log.LogErrorf("Removexattr: ino(%v) name(%v) err(%v)", ino, name, err)
 		return ParseError(err)
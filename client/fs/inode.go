@@ -17,7 +17,7 @@ package fs
 import (
 	"time"
 
-	"bazil.org/fuse"
+	"github.com/chubaofs/chubaofs/depends/bazil.org/fuse"
 
 	"github.com/chubaofs/chubaofs/proto"
 	"github.com/chubaofs/chubaofs/util/log"
@@ -0,0 +1,164 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package fs
+
+import (
+	"math"
+	"syscall"
+	"time"
+
+	"github.com/chubaofs/chubaofs/depends/bazil.org/fuse"
+	"github.com/chubaofs/chubaofs/depends/bazil.org/fuse/fs"
+	"golang.org/x/net/context"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+const (
+	lockWaitMinInterval = 10 * time.Millisecond
+	lockWaitMaxInterval = time.Second
+)
+
+var _ fs.HandleLocker = (*File)(nil)
+
+func lockInfo(owner uint64, lock fuse.FileLock, flags fuse.LockFlags) *proto.LockInfo {
+	info := &proto.LockInfo{
+		Owner: owner,
+		Pid:   lock.PID,
+		Start: lock.Start,
+		End:   lock.End,
+		Flock: flags&fuse.LockFlock != 0,
+	}
+	switch lock.Type {
+	case fuse.LockRead:
+		info.Type = proto.LockRead
+	case fuse.LockWrite:
+		info.Type = proto.LockWrite
+	default:
+		info.Type = proto.LockUnlock
+	}
+	if info.Flock {
+		info.Start, info.End = 0, math.MaxUint64
+	}
+	return info
+}
+
+func fuseLockType(t uint32) fuse.LockType {
+	switch t {
+	case proto.LockRead:
+		return fuse.LockRead
+	case proto.LockWrite:
+		return fuse.LockWrite
+	default:
+		return fuse.LockUnlock
+	}
+}
+
+// Lock tries to acquire a file lock without waiting.
+func (f *File) Lock(ctx context.Context, req *fuse.LockRequest) error {
+	if !f.super.enableLock {
+		return fuse.ENOSYS
+	}
+	ino := f.info.Inode
+	lock := lockInfo(req.LockOwner, req.Lock, req.LockFlags)
+	if err := f.super.mw.SetLock_ll(ino, lock); err != nil {
+		log.LogDebugf("Lock: ino(%v) req(%v) err(%v)", ino, req, err)
+		return ParseError(err)
+	}
+	log.LogDebugf("TRACE Lock: ino(%v) req(%v)", ino, req)
+	return nil
+}
+
+// LockWait acquires a file lock, and waits until the conflicting locks are
+// released or the request is interrupted.
+func (f *File) LockWait(ctx context.Context, req *fuse.LockWaitRequest) error {
+	if !f.super.enableLock {
+		return fuse.ENOSYS
+	}
+	ino := f.info.Inode
+	lock := lockInfo(req.LockOwner, req.Lock, req.LockFlags)
+	interval := lockWaitMinInterval
+	for {
+		err := f.super.mw.SetLock_ll(ino, lock)
+		if err == nil {
+			log.LogDebugf("TRACE LockWait: ino(%v) req(%v)", ino, req)
+			return nil
+		}
+		if err != syscall.EAGAIN {
+			log.LogErrorf("LockWait: ino(%v) req(%v) err(%v)", ino, req, err)
+			return ParseError(err)
+		}
+		select {
+		case <-ctx.Done():
+			log.LogDebugf("LockWait: interrupted, ino(%v) req(%v)", ino, req)
+			return fuse.EINTR
+		case <-time.After(interval):
+		}
+		if interval *= 2; interval > lockWaitMaxInterval {
+			interval = lockWaitMaxInterval
+		}
+	}
+}
+
+// Unlock releases a file lock.
+func (f *File) Unlock(ctx context.Context, req *fuse.UnlockRequest) error {
+	if !f.super.enableLock {
+		return fuse.ENOSYS
+	}
+	ino := f.info.Inode
+	lock := lockInfo(req.LockOwner, req.Lock, req.LockFlags)
+	if err := f.super.mw.SetLock_ll(ino, lock); err != nil {
+		log.LogErrorf("Unlock: ino(%v) req(%v) err(%v)", ino, req, err)
+		return ParseError(err)
+	}
+	log.LogDebugf("TRACE Unlock: ino(%v) req(%v)", ino, req)
+	return nil
+}
+
+// QueryLock returns a lock which conflicts with the requested one if any.
+func (f *File) QueryLock(ctx context.Context, req *fuse.QueryLockRequest, resp *fuse.QueryLockResponse) error {
+	if !f.super.enableLock {
+		return fuse.ENOSYS
+	}
+	ino := f.info.Inode
+	conflict, err := f.super.mw.GetLock_ll(ino, lockInfo(req.LockOwner, req.Lock, req.LockFlags))
+	if err != nil {
+		log.LogErrorf("QueryLock: ino(%v) req(%v) err(%v)", ino, req, err)
+		return ParseError(err)
+	}
+	if conflict.Type != proto.LockUnlock {
+		resp.Lock = fuse.FileLock{
+			Start: conflict.Start,
+			End:   conflict.End,
+			Type:  fuseLockType(conflict.Type),
+			PID:   conflict.Pid,
+		}
+	}
+	log.LogDebugf("TRACE QueryLock: ino(%v) req(%v) resp(%v)", ino, req, resp)
+	return nil
+}
+
+// releaseLocks releases all of the POSIX locks or the flock held by the owner.
+func (f *File) releaseLocks(owner uint64, flock bool) error {
+	lock := &proto.LockInfo{
+		Owner: owner,
+		Start: 0,
+		End:   math.MaxUint64,
+		Type:  proto.LockUnlock,
+		Flock: flock,
+	}
+	return f.super.mw.SetLock_ll(f.info.Inode, lock)
+}
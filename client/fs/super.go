@@ -15,8 +15,10 @@
 package fs
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"path"
 	"strconv"
 	"strings"
 	"sync"
@@ -24,10 +26,11 @@ import (
 
 	"golang.org/x/net/context"
 
-	"bazil.org/fuse"
-	"bazil.org/fuse/fs"
+	"github.com/chubaofs/chubaofs/depends/bazil.org/fuse"
+	"github.com/chubaofs/chubaofs/depends/bazil.org/fuse/fs"
 
 	"github.com/chubaofs/chubaofs/proto"
+	authSDK "github.com/chubaofs/chubaofs/sdk/auth"
 	"github.com/chubaofs/chubaofs/sdk/data/stream"
 	"github.com/chubaofs/chubaofs/sdk/meta"
 	"github.com/chubaofs/chubaofs/util/errors"
@@ -53,7 +56,13 @@ type Super struct {
 	disableDcache bool
 	fsyncOnClose  bool
 	enableXattr   bool
+	enableLock    bool
 	rootIno       uint64
+	subDir        string
+
+	encryption bool
+	ac         *authSDK.AuthClient // requests the data keys of the encrypted files
+	clientKey  string
 }
 
 // Functions that Super needs to implement
@@ -73,6 +82,7 @@ func NewSuper(opt *proto.MountOptions) (s *Super, err error) {
 		Authenticate:  opt.Authenticate,
 		TicketMess:    opt.TicketMess,
 		ValidateOwner: opt.Authenticate || opt.AccessKey == "",
+		Snapshot:      opt.Snapshot,
 	}
 	s.mw, err = meta.NewMetaWrapper(metaConfig)
 	if err != nil {
@@ -102,6 +112,12 @@ func NewSuper(opt *proto.MountOptions) (s *Super, err error) {
 	s.disableDcache = opt.DisableDcache
 	s.fsyncOnClose = opt.FsyncOnClose
 	s.enableXattr = opt.EnableXattr
+	s.enableLock = opt.EnableFileLock
+	if opt.Encryption {
+		s.encryption = true
+		s.ac = authSDK.NewAuthClient(opt.TicketMess.TicketHosts, opt.TicketMess.EnableHTTPS, opt.TicketMess.CertFile)
+		s.clientKey = opt.TicketMess.ClientKey
+	}
 
 	var extentConfig = &stream.ExtentConfig{
 		Volume:            opt.Volname,
@@ -110,6 +126,9 @@ func NewSuper(opt *proto.MountOptions) (s *Super, err error) {
 		NearRead:          opt.NearRead,
 		ReadRate:          opt.ReadRate,
 		WriteRate:         opt.WriteRate,
+		ReadCacheSize:     opt.ReadCacheSize,
+		DiskCacheDir:      opt.DiskCacheDir,
+		DiskCacheSize:     opt.DiskCacheSize,
 		OnAppendExtentKey: s.mw.AppendExtentKey,
 		OnGetExtents:      s.mw.GetExtents,
 		OnTruncate:        s.mw.Truncate,
@@ -123,6 +142,7 @@ func NewSuper(opt *proto.MountOptions) (s *Super, err error) {
 	if s.rootIno, err = s.mw.GetRootIno(opt.SubDir); err != nil {
 		return nil, err
 	}
+	s.subDir = opt.SubDir
 
 	log.LogInfof("NewSuper: cluster(%v) volname(%v) icacheExpiration(%v) LookupValidDuration(%v) AttrValidDuration(%v)", s.cluster, s.volname, inodeExpiration, LookupValidDuration, AttrValidDuration)
 	return s, nil
@@ -186,6 +206,61 @@ func (s *Super) SetRate(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// GetCacheStats returns the statistics of the disk cache in json.
+func (s *Super) GetCacheStats(w http.ResponseWriter, r *http.Request) {
+	stats := s.ec.DiskCacheStats()
+	if stats == nil {
+		w.Write([]byte("Disk cache is not enabled\n"))
+		return
+	}
+	data, err := json.Marshal(stats)
+	if err != nil {
+		w.Write([]byte(err.Error()))
+		return
+	}
+	w.Write(data)
+}
+
+// filePath returns the path of the file from the root of the volume, or empty
+// if the path of the parent directory is unknown.
+func (s *Super) filePath(parent *Dir, name string) string {
+	s.fslock.Lock()
+	defer s.fslock.Unlock()
+	names := []string{name}
+	for dir := parent; dir.info.Inode != s.rootIno; {
+		if dir.name == "" || len(names) > MaxPathDepth {
+			return ""
+		}
+		names = append(names, dir.name)
+		node, ok := s.nodeCache[dir.parentIno]
+		if !ok {
+			if dir.parentIno != s.rootIno {
+				return ""
+			}
+			break
+		}
+		if dir, ok = node.(*Dir); !ok {
+			return ""
+		}
+	}
+	for i, j := 0, len(names)-1; i < j; i, j = i+1, j-1 {
+		names[i], names[j] = names[j], names[i]
+	}
+	return path.Join("/", s.subDir, path.Join(names...))
+}
+
+// renameDir updates the parent and the name of the renamed directory.
+func (s *Super) renameDir(srcParent uint64, srcName string, dstParent uint64, dstName string) {
+	s.fslock.Lock()
+	defer s.fslock.Unlock()
+	for _, node := range s.nodeCache {
+		if dir, ok := node.(*Dir); ok && dir.parentIno == srcParent && dir.name == srcName {
+			dir.parentIno, dir.name = dstParent, dstName
+			return
+		}
+	}
+}
+
 func (s *Super) exporterKey(act string) string {
 	return fmt.Sprintf("%v_fuseclient_%v", s.cluster, act)
 }
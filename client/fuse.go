@@ -40,9 +40,9 @@ import (
 
 	sysutil "github.com/chubaofs/chubaofs/util/sys"
 
-	"bazil.org/fuse"
-	"bazil.org/fuse/fs"
 	cfs "github.com/chubaofs/chubaofs/client/fs"
+	"github.com/chubaofs/chubaofs/depends/bazil.org/fuse"
+	"github.com/chubaofs/chubaofs/depends/bazil.org/fuse/fs"
 	"github.com/chubaofs/chubaofs/proto"
 	"github.com/chubaofs/chubaofs/util/config"
 	"github.com/chubaofs/chubaofs/util/errors"
@@ -69,6 +69,7 @@ const (
 	ControlCommandSetRate      = "/rate/set"
 	ControlCommandGetRate      = "/rate/get"
 	ControlCommandFreeOSMemory = "/debug/freeosmemory"
+	ControlCommandCacheStats   = "/cache/stats"
 	Role                       = "Client"
 )
 
@@ -251,6 +252,7 @@ func mount(opt *proto.MountOptions) (fsConn *fuse.Conn, super *cfs.Super, err er
 	http.HandleFunc(ControlCommandGetRate, super.GetRate)
 	http.HandleFunc(log.SetLogLevelPath, log.SetLogLevel)
 	http.HandleFunc(ControlCommandFreeOSMemory, freeOSMemory)
+	http.HandleFunc(ControlCommandCacheStats, super.GetCacheStats)
 	http.HandleFunc(log.GetLogPath, log.GetLog)
 
 	go func() {
@@ -294,6 +296,10 @@ func mount(opt *proto.MountOptions) (fsConn *fuse.Conn, super *cfs.Super, err er
 		options = append(options, fuse.PosixACL())
 	}
 
+	if opt.EnableFileLock {
+		options = append(options, fuse.LockingPOSIX(), fuse.LockingFlock())
+	}
+
 	fsConn, err = fuse.Mount(opt.MountPoint, options...)
 	return
 }
@@ -358,6 +364,19 @@ func parseMountOption(cfg *config.Config) (*proto.MountOptions, error) {
 	opt.EnableXattr = GlobalMountOptions[proto.EnableXattr].GetBool()
 	opt.NearRead = GlobalMountOptions[proto.NearRead].GetBool()
 	opt.EnablePosixACL = GlobalMountOptions[proto.EnablePosixACL].GetBool()
+	opt.EnableFileLock = GlobalMountOptions[proto.EnableFileLock].GetBool()
+	opt.Snapshot = GlobalMountOptions[proto.Snapshot].GetString()
+	opt.ReadCacheSize = GlobalMountOptions[proto.ReadCacheSize].GetInt64()
+	opt.DiskCacheDir = GlobalMountOptions[proto.DiskCacheDir].GetString()
+	opt.DiskCacheSize = GlobalMountOptions[proto.DiskCacheSize].GetInt64()
+	opt.Encryption = GlobalMountOptions[proto.EnableEncryption].GetBool()
+	if opt.Encryption && !opt.Authenticate {
+		return nil, errors.New("invalid config file: enableEncryption requires authenticate")
+	}
+	if opt.Snapshot != "" {
+		// a snapshot is always mounted read-only
+		opt.Rdonly = true
+	}
 
 	if opt.MountPoint == "" || opt.Volname == "" || opt.Owner == "" || opt.Master == "" {
 		return nil, errors.New(fmt.Sprintf("invalid config file: lack of mandatory fields, mountPoint(%v), volName(%v), owner(%v), masterAddr(%v)", opt.MountPoint, opt.Volname, opt.Owner, opt.Master))
@@ -0,0 +1,176 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package datanode
+
+import (
+	"fmt"
+	"hash/crc32"
+	"net"
+	"sort"
+	"sync"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/repl"
+	"github.com/chubaofs/chubaofs/storage"
+	"github.com/chubaofs/chubaofs/util/ec"
+	"github.com/chubaofs/chubaofs/util/errors"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// The hosts of an erasure-coded partition hold the different shards of the
+// extents, so a shard can not be copied from another host. Instead, every host
+// repairs its own shard: the stripes of an extent missing locally, e.g. on the
+// new host replacing a lost one, are reconstructed from the shards of the
+// other hosts. The order of the hosts is the order of the shards.
+
+// repairECShard reconstructs the stripes missing in the local shard.
+func (dp *DataPartition) repairECShard() {
+	hosts := dp.getReplicaCopy()
+	shardNum := int(dp.config.ECDataNum) + int(dp.config.ECParityNum)
+	index := -1
+	for i, host := range hosts {
+		if host == net.JoinHostPort(LocalIP, serverPort) {
+			index = i
+		}
+	}
+	if index < 0 || len(hosts) != shardNum {
+		return
+	}
+	enc, err := ec.New(int(dp.config.ECDataNum), int(dp.config.ECParityNum))
+	if err != nil {
+		log.LogErrorf("action[repairECShard] partition(%v) err(%v)", dp.partitionID, err)
+		return
+	}
+
+	// the size of an extent is the size of the shards of its stripes
+	remoteSizes := make([]map[uint64]uint64, len(hosts))
+	targetSizes := make(map[uint64]uint64)
+	for i, host := range hosts {
+		if i == index {
+			continue
+		}
+		extents, err := dp.getRemoteExtentInfo(proto.NormalExtentType, nil, host)
+		if err != nil {
+			log.LogWarnf("action[repairECShard] partition(%v) shard(%v) host(%v) err(%v)", dp.partitionID, i, host, err)
+			continue
+		}
+		remoteSizes[i] = make(map[uint64]uint64, len(extents))
+		for _, extent := range extents {
+			remoteSizes[i][extent.FileID] = extent.Size
+			if extent.Size > targetSizes[extent.FileID] {
+				targetSizes[extent.FileID] = extent.Size
+			}
+		}
+	}
+	extentIDs := make([]uint64, 0, len(targetSizes))
+	for extentID := range targetSizes {
+		extentIDs = append(extentIDs, extentID)
+	}
+	sort.Slice(extentIDs, func(i, j int) bool { return extentIDs[i] < extentIDs[j] })
+	for _, extentID := range extentIDs {
+		if dp.partitionStatus == proto.Unavailable {
+			return
+		}
+		if err = dp.repairECExtent(enc, hosts, index, remoteSizes, extentID, targetSizes[extentID]); err != nil {
+			log.LogWarnf("action[repairECShard] partition(%v) extent(%v) err(%v)", dp.partitionID, extentID, err)
+		}
+	}
+}
+
+// repairECExtent appends the stripes of the extent from the local size up to
+// the target size. It stops at the first stripe which can not be
+// reconstructed, e.g. the one of a write failed on most of the hosts.
+func (dp *DataPartition) repairECExtent(enc *ec.Encoder, hosts []string, index int, remoteSizes []map[uint64]uint64, extentID, targetSize uint64) (err error) {
+	store := dp.ExtentStore()
+	if store.IsDeletedNormalExtent(extentID) {
+		return
+	}
+	var localSize uint64
+	if store.HasExtent(extentID) {
+		var info *storage.ExtentInfo
+		if info, err = store.Watermark(extentID); err != nil {
+			return
+		}
+		localSize = info.Size
+	} else if err = store.Create(extentID); err != nil {
+		return
+	}
+	shardSize := uint64(proto.ECShardSize(dp.config.ECDataNum))
+	if localSize%shardSize != 0 {
+		return fmt.Errorf("local size(%v) is not aligned to the shard size(%v)", localSize, shardSize)
+	}
+	for offset := localSize; offset+shardSize <= targetSize; offset += shardSize {
+		shards := make([][]byte, len(hosts))
+		var wg sync.WaitGroup
+		for i, host := range hosts {
+			if i == index || remoteSizes[i] == nil || remoteSizes[i][extentID] < offset+shardSize {
+				continue
+			}
+			wg.Add(1)
+			go func(i int, host string) {
+				defer wg.Done()
+				shard := make([]byte, shardSize)
+				if e := dp.readECShard(host, extentID, offset, shard); e != nil {
+					log.LogWarnf("action[repairECExtent] partition(%v) extent(%v) shard(%v) host(%v) err(%v)",
+						dp.partitionID, extentID, i, host, e)
+					return
+				}
+				shards[i] = shard
+			}(i, host)
+		}
+		wg.Wait()
+		if err = enc.Reconstruct(shards); err != nil {
+			return errors.Trace(err, "reconstruct stripe at offset(%v)", offset)
+		}
+		shard := shards[index]
+		if err = store.Write(extentID, int64(offset), int64(shardSize), shard, crc32.ChecksumIEEE(shard), storage.AppendWriteType, BufferWrite); err != nil {
+			return
+		}
+	}
+	if localSize < targetSize {
+		log.LogInfof("action[repairECExtent] partition(%v) extent(%v) repaired from(%v) to(%v)",
+			dp.partitionID, extentID, localSize, targetSize)
+	}
+	return
+}
+
+// readECShard reads the range of the extent of the shard on the host.
+func (dp *DataPartition) readECShard(host string, extentID, offset uint64, data []byte) (err error) {
+	p := repl.NewExtentRepairReadPacket(dp.partitionID, extentID, int(offset), len(data))
+	p.Opcode = proto.OpStreamFollowerRead
+	conn, err := gConnPool.GetConnect(host)
+	if err != nil {
+		return
+	}
+	defer func() {
+		gConnPool.PutConnect(conn, err != nil)
+	}()
+	if err = p.WriteToConn(conn); err != nil {
+		return
+	}
+	for readBytes := 0; readBytes < len(data); {
+		reply := repl.NewPacket()
+		if err = reply.ReadFromConn(conn, proto.ReadDeadlineTime); err != nil {
+			return
+		}
+		if reply.ResultCode != proto.OpOk || reply.ReqID != p.ReqID || reply.ExtentID != extentID ||
+			reply.ExtentOffset != int64(offset)+int64(readBytes) || reply.Size == 0 ||
+			int(reply.Size) > len(data)-readBytes || reply.CRC != crc32.ChecksumIEEE(reply.Data[:reply.Size]) {
+			return fmt.Errorf("invalid reply(%v) of request(%v)", reply.GetUniqueLogId(), p.GetUniqueLogId())
+		}
+		readBytes += copy(data[readBytes:], reply.Data[:reply.Size])
+	}
+	return
+}
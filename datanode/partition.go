@@ -65,6 +65,8 @@ type DataPartitionMetadata struct {
 	Hosts                   []string
 	DataPartitionCreateType int
 	LastTruncateID          uint64
+	ECDataNum               uint8 `json:",omitempty"`
+	ECParityNum             uint8 `json:",omitempty"`
 }
 
 type sortedPeers []proto.Peer
@@ -210,6 +212,8 @@ func LoadDataPartition(partitionDir string, disk *Disk) (dp *DataPartition, err
 		PartitionID:   meta.PartitionID,
 		Peers:         meta.Peers,
 		Hosts:         meta.Hosts,
+		ECDataNum:     meta.ECDataNum,
+		ECParityNum:   meta.ECParityNum,
 		RaftStore:     disk.space.GetRaftStore(),
 		NodeID:        disk.space.GetNodeID(),
 		ClusterID:     disk.space.GetClusterID(),
@@ -403,6 +407,12 @@ func (dp *DataPartition) IsRejectWrite() bool {
 	return dp.Disk().RejectWrite
 }
 
+// IsErasureCoded returns true if the partition holds a shard of the stripes
+// of the extents instead of a replica.
+func (dp *DataPartition) IsErasureCoded() bool {
+	return dp.config.ECDataNum > 0
+}
+
 // Status returns the partition status.
 func (dp *DataPartition) Status() int {
 	return dp.partitionStatus
@@ -455,6 +465,8 @@ func (dp *DataPartition) PersistMetadata() (err error) {
 		DataPartitionCreateType: dp.DataPartitionCreateType,
 		CreateTime:              time.Now().Format(TimeLayout),
 		LastTruncateID:          dp.lastTruncateID,
+		ECDataNum:               dp.config.ECDataNum,
+		ECParityNum:             dp.config.ECParityNum,
 	}
 	if metaData, err = json.Marshal(md); err != nil {
 		return
@@ -596,6 +608,14 @@ func (dp *DataPartition) LaunchRepair(extentType uint8) {
 		log.LogErrorf("action[LaunchRepair] partition(%v) err(%v).", dp.partitionID, err)
 		return
 	}
+	// Every host of an erasure-coded partition repairs its own shard from
+	// the shards of the other hosts, see repairECShard.
+	if dp.IsErasureCoded() {
+		if extentType == proto.NormalExtentType {
+			dp.repairECShard()
+		}
+		return
+	}
 	if !dp.isLeader {
 		return
 	}
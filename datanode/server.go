@@ -47,6 +47,7 @@ var (
 	ErrNoSpaceToCreatePartition    = errors.New("No disk space to create a data partition")
 	ErrNewSpaceManagerFailed       = errors.New("Creater new space manager failed")
 	ErrGetMasterDatanodeInfoFailed = errors.New("Failed to get datanode info from master")
+	ErrErasureCodedWrite           = errors.New("Only append write is allowed on an erasure-coded partition")
 
 	LocalIP, serverPort string
 	gConnPool           = util.NewConnectPool()
@@ -116,6 +117,10 @@ type DataNode struct {
 
 	metrics *DataNodeMetrics
 
+	snapshotVols      map[string]bool // nil until the first heartbeat from the master
+	snapshotVolsVer   int64
+	snapshotVolsMutex sync.RWMutex
+
 	control common.Control
 }
 
@@ -0,0 +1,52 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package datanode
+
+import (
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// The extents of a volume with snapshots may be referenced by the snapshots,
+// so they are not overwritten in place. The master pushes the volumes with
+// snapshots before a snapshot is created, and with every heartbeat.
+
+// updateSnapshotVols replaces the volumes with snapshots, unless the request
+// is older than the one already applied.
+func (s *DataNode) updateSnapshotVols(request *proto.HeartBeatRequest) {
+	s.snapshotVolsMutex.Lock()
+	defer s.snapshotVolsMutex.Unlock()
+	if s.snapshotVols != nil && request.SnapshotVolsVer < s.snapshotVolsVer {
+		return
+	}
+	vols := make(map[string]bool, len(request.SnapshotVols))
+	for _, vol := range request.SnapshotVols {
+		vols[vol] = true
+	}
+	if len(vols) != len(s.snapshotVols) {
+		log.LogInfof("action[updateSnapshotVols] snapshot vols(%v) ver(%v)", request.SnapshotVols, request.SnapshotVolsVer)
+	}
+	s.snapshotVols = vols
+	s.snapshotVolsVer = request.SnapshotVolsVer
+}
+
+// isCopyOnWrite returns true if the extents of the partition can not be
+// overwritten in place. It is true for every partition until the volumes
+// with snapshots are received from the master.
+func (s *DataNode) isCopyOnWrite(dp *DataPartition) bool {
+	s.snapshotVolsMutex.RLock()
+	defer s.snapshotVolsMutex.RUnlock()
+	return s.snapshotVols == nil || s.snapshotVols[dp.volumeID]
+}
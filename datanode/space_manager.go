@@ -295,6 +295,8 @@ func (manager *SpaceManager) CreatePartition(request *proto.CreateDataPartitionR
 		NodeID:        manager.nodeID,
 		ClusterID:     manager.clusterID,
 		PartitionSize: request.PartitionSize,
+		ECDataNum:     request.ECDataNum,
+		ECParityNum:   request.ECParityNum,
 	}
 	dp = manager.partitions[dpCfg.PartitionID]
 	if dp != nil {
@@ -349,6 +351,7 @@ func (s *DataNode) buildHeartBeatResponse(response *proto.DataNodeHeartbeatRespo
 	stat.Unlock()
 
 	response.ZoneName = s.zoneName
+	response.Version = proto.Version
 	response.PartitionReports = make([]*proto.PartitionReport, 0)
 	space := s.space
 	space.RangePartitions(func(partition *DataPartition) bool {
@@ -370,9 +373,17 @@ func (s *DataNode) buildHeartBeatResponse(response *proto.DataNodeHeartbeatRespo
 	})
 
 	disks := space.GetDisks()
+	response.DiskReports = make([]*proto.DiskReport, 0, len(disks))
 	for _, d := range disks {
 		if d.Status == proto.Unavailable {
 			response.BadDisks = append(response.BadDisks, d.Path)
 		}
+		response.DiskReports = append(response.DiskReports, &proto.DiskReport{
+			Path:      d.Path,
+			Total:     d.Total,
+			Used:      d.Used,
+			Available: d.Available,
+			Status:    d.Status,
+		})
 	}
 }
@@ -222,15 +222,22 @@ func (s *DataNode) handleHeartbeatPacket(p *repl.Packet) {
 	if err != nil {
 		return
 	}
+	// the volumes with snapshots are applied before the reply, since the
+	// master waits for them before creating a snapshot
+	request := &proto.HeartBeatRequest{}
+	if task.OpCode == proto.OpDataNodeHeartbeat {
+		marshaled, _ := json.Marshal(task.Request)
+		if err = json.Unmarshal(marshaled, request); err != nil {
+			return
+		}
+		s.updateSnapshotVols(request)
+	}
 
 	go func() {
-		request := &proto.HeartBeatRequest{}
 		response := &proto.DataNodeHeartbeatResponse{}
 		s.buildHeartBeatResponse(response)
 
 		if task.OpCode == proto.OpDataNodeHeartbeat {
-			marshaled, _ := json.Marshal(task.Request)
-			_ = json.Unmarshal(marshaled, request)
 			response.Status = proto.TaskSucceeds
 		} else {
 			response.Status = proto.TaskFailed
@@ -471,6 +478,11 @@ func (s *DataNode) handleRandomWritePacket(p *repl.Packet) {
 		err = raft.ErrNotLeader
 		return
 	}
+	// the client writes the data to a new extent instead
+	if s.isCopyOnWrite(partition) {
+		err = storage.CopyOnWriteError
+		return
+	}
 	metricPartitionIOLabels := GetIoMetricLabels(partition, "randwrite")
 	partitionIOMetric := exporter.NewTPCnt(MetricPartitionIOName)
 	err = partition.RandomWriteSubmit(p)
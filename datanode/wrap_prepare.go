@@ -45,6 +45,9 @@ func (s *DataNode) Prepare(p *repl.Packet) (err error) {
 	if err = s.checkPartition(p); err != nil {
 		return
 	}
+	if err = s.checkErasureCoded(p); err != nil {
+		return
+	}
 
 	// For certain packet, we meed to add some additional extent information.
 	if err = s.addExtentInfo(p); err != nil {
@@ -89,6 +92,19 @@ func (s *DataNode) checkPartition(p *repl.Packet) (err error) {
 	return
 }
 
+// The extents of an erasure-coded partition are write-once, since a shard can
+// not be updated without the other shards of the stripe.
+func (s *DataNode) checkErasureCoded(p *repl.Packet) (err error) {
+	dp := p.Object.(*DataPartition)
+	if !dp.IsErasureCoded() {
+		return
+	}
+	if p.IsRandomWrite() || (p.IsTinyExtentType() && p.IsWriteOperation()) {
+		return ErrErasureCodedWrite
+	}
+	return
+}
+
 func (s *DataNode) addExtentInfo(p *repl.Packet) error {
 	partition := p.Object.(*DataPartition)
 	store := p.Object.(*DataPartition).ExtentStore()
@@ -52,6 +52,7 @@ import "C"
 
 import (
 	"io"
+	"math"
 	"os"
 	gopath "path"
 	"reflect"
@@ -59,6 +60,7 @@ import (
 	"sync"
 	"sync/atomic"
 	"syscall"
+	"time"
 	"unsafe"
 
 	"github.com/willf/bitset"
@@ -73,6 +75,9 @@ const (
 	defaultBlkSize = uint32(1) << 12
 
 	maxFdNum uint = 1024000
+
+	lockWaitMinInterval = 10 * time.Millisecond
+	lockWaitMaxInterval = time.Second
 )
 
 var gClientManager *clientManager
@@ -88,6 +93,7 @@ var (
 	statusEMFILE  = errorToStatus(syscall.EMFILE)
 	statusENOTDIR = errorToStatus(syscall.ENOTDIR)
 	statusEISDIR  = errorToStatus(syscall.EISDIR)
+	statusEDQUOT  = errorToStatus(syscall.EDQUOT)
 )
 
 func init() {
@@ -426,6 +432,9 @@ func cfs_flush(id C.int64_t, fd C.int) C.int {
 	}
 
 	err := c.flush(f)
+	if err == syscall.EDQUOT {
+		return statusEDQUOT
+	}
 	if err != nil {
 		return statusEIO
 	}
@@ -442,6 +451,7 @@ func cfs_close(id C.int64_t, fd C.int) {
 	if f != nil {
 		c.flush(f)
 		c.closeStream(f)
+		c.releaseLocks(f)
 	}
 }
 
@@ -480,12 +490,17 @@ func cfs_write(id C.int64_t, fd C.int, buf unsafe.Pointer, size C.size_t, off C.
 	}
 
 	n, err := c.write(f, int(off), buffer, flags)
+	if err == syscall.EDQUOT {
+		return C.ssize_t(statusEDQUOT)
+	}
 	if err != nil {
 		return C.ssize_t(statusEIO)
 	}
 
 	if wait {
-		if err = c.flush(f); err != nil {
+		if err = c.flush(f); err == syscall.EDQUOT {
+			return C.ssize_t(statusEDQUOT)
+		} else if err != nil {
 			return C.ssize_t(statusEIO)
 		}
 	}
@@ -656,6 +671,14 @@ func cfs_unlink(id C.int64_t, path *C.char) C.int {
 		return statusEISDIR
 	}
 
+	moved, err := c.mw.MoveToTrash_ll(dirInfo.Inode, name, absPath)
+	if err != nil {
+		return errorToStatus(err)
+	}
+	if moved {
+		return 0
+	}
+
 	info, err := c.mw.Delete_ll(dirInfo.Inode, name, false)
 	if err != nil {
 		return errorToStatus(err)
@@ -714,6 +737,103 @@ func cfs_fchmod(id C.int64_t, fd C.int, mode C.mode_t) C.int {
 	return statusOK
 }
 
+//export cfs_flock
+func cfs_flock(id C.int64_t, fd C.int, operation C.int) C.int {
+	c, exist := getClient(int64(id))
+	if !exist {
+		return statusEINVAL
+	}
+
+	f := c.getFile(uint(fd))
+	if f == nil {
+		return statusEBADFD
+	}
+
+	lock := &proto.LockInfo{
+		Owner: uint64(f.fd),
+		Pid:   uint32(os.Getpid()),
+		Start: 0,
+		End:   math.MaxUint64,
+		Flock: true,
+	}
+	switch int(operation) &^ syscall.LOCK_NB {
+	case syscall.LOCK_SH:
+		lock.Type = proto.LockRead
+	case syscall.LOCK_EX:
+		lock.Type = proto.LockWrite
+	case syscall.LOCK_UN:
+		lock.Type = proto.LockUnlock
+	default:
+		return statusEINVAL
+	}
+
+	err := c.setLock(f, lock, int(operation)&syscall.LOCK_NB == 0)
+	return errorToStatus(err)
+}
+
+//export cfs_setlk
+func cfs_setlk(id C.int64_t, fd C.int, lk *C.struct_flock, wait C.int) C.int {
+	c, exist := getClient(int64(id))
+	if !exist {
+		return statusEINVAL
+	}
+
+	f := c.getFile(uint(fd))
+	if f == nil {
+		return statusEBADFD
+	}
+
+	lock, err := c.posixLock(f, lk)
+	if err != nil {
+		return errorToStatus(err)
+	}
+
+	err = c.setLock(f, lock, wait != 0)
+	return errorToStatus(err)
+}
+
+//export cfs_getlk
+func cfs_getlk(id C.int64_t, fd C.int, lk *C.struct_flock) C.int {
+	c, exist := getClient(int64(id))
+	if !exist {
+		return statusEINVAL
+	}
+
+	f := c.getFile(uint(fd))
+	if f == nil {
+		return statusEBADFD
+	}
+
+	lock, err := c.posixLock(f, lk)
+	if err != nil {
+		return errorToStatus(err)
+	}
+
+	conflict, err := c.mw.GetLock_ll(f.ino, lock)
+	if err != nil {
+		return errorToStatus(err)
+	}
+
+	switch conflict.Type {
+	case proto.LockRead:
+		lk.l_type = C.F_RDLCK
+	case proto.LockWrite:
+		lk.l_type = C.F_WRLCK
+	default:
+		lk.l_type = C.F_UNLCK
+		return statusOK
+	}
+	lk.l_whence = C.SEEK_SET
+	lk.l_start = C.off_t(conflict.Start)
+	if conflict.End >= math.MaxInt64 {
+		lk.l_len = 0
+	} else {
+		lk.l_len = C.off_t(conflict.End - conflict.Start + 1)
+	}
+	lk.l_pid = C.pid_t(conflict.Pid)
+	return statusOK
+}
+
 // internals
 
 func (c *client) absPath(path string) string {
@@ -789,6 +909,75 @@ func (c *client) releaseFD(fd uint) *file {
 	return f
 }
 
+// posixLock converts struct flock to the lock info. POSIX locks are owned by
+// the process, so all of them share the same owner.
+func (c *client) posixLock(f *file, lk *C.struct_flock) (*proto.LockInfo, error) {
+	lock := &proto.LockInfo{
+		Pid: uint32(os.Getpid()),
+	}
+	switch lk.l_type {
+	case C.F_RDLCK:
+		lock.Type = proto.LockRead
+	case C.F_WRLCK:
+		lock.Type = proto.LockWrite
+	case C.F_UNLCK:
+		lock.Type = proto.LockUnlock
+	default:
+		return nil, syscall.EINVAL
+	}
+
+	start := int64(lk.l_start)
+	switch lk.l_whence {
+	case C.SEEK_SET:
+	case C.SEEK_END:
+		info, err := c.mw.InodeGet_ll(f.ino)
+		if err != nil {
+			return nil, err
+		}
+		start += int64(info.Size)
+	default:
+		// there is no file offset for SEEK_CUR
+		return nil, syscall.EINVAL
+	}
+
+	length := int64(lk.l_len)
+	if length < 0 {
+		start += length
+		length = -length
+	}
+	if start < 0 {
+		return nil, syscall.EINVAL
+	}
+	lock.Start = uint64(start)
+	if length == 0 {
+		lock.End = math.MaxUint64
+	} else {
+		lock.End = uint64(start + length - 1)
+	}
+	return lock, nil
+}
+
+func (c *client) setLock(f *file, lock *proto.LockInfo, wait bool) error {
+	interval := lockWaitMinInterval
+	for {
+		err := c.mw.SetLock_ll(f.ino, lock)
+		if err != syscall.EAGAIN || !wait {
+			return err
+		}
+		time.Sleep(interval)
+		if interval *= 2; interval > lockWaitMaxInterval {
+			interval = lockWaitMaxInterval
+		}
+	}
+}
+
+// releaseLocks releases the POSIX locks of the process and the flock of the
+// file descriptor upon close.
+func (c *client) releaseLocks(f *file) {
+	c.mw.SetLock_ll(f.ino, &proto.LockInfo{Start: 0, End: math.MaxUint64, Type: proto.LockUnlock})
+	c.mw.SetLock_ll(f.ino, &proto.LockInfo{Owner: uint64(f.fd), Start: 0, End: math.MaxUint64, Type: proto.LockUnlock, Flock: true})
+}
+
 func (c *client) lookupPath(path string) (*proto.InodeInfo, error) {
 	ino, err := c.mw.LookupPath(gopath.Clean(path))
 	if err != nil {
@@ -121,6 +121,111 @@ func (m *Server) setupAutoAllocation(w http.ResponseWriter, r *http.Request) {
 	sendOkReply(w, r, newSuccessHTTPReply(fmt.Sprintf("set DisableAutoAllocate to %v successfully", status)))
 }
 
+func (m *Server) startDataBalance(w http.ResponseWriter, r *http.Request) {
+	var (
+		config proto.DataBalanceConfig
+		err    error
+	)
+	if config, err = parseRequestToStartDataBalance(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if err = m.cluster.dataBalancer.start(config); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
+		return
+	}
+	sendOkReply(w, r, newSuccessHTTPReply("start data balance successfully"))
+}
+
+func (m *Server) stopDataBalance(w http.ResponseWriter, r *http.Request) {
+	if err := m.cluster.dataBalancer.stop(); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
+		return
+	}
+	sendOkReply(w, r, newSuccessHTTPReply("stop data balance successfully"))
+}
+
+func (m *Server) getDataBalanceStatus(w http.ResponseWriter, r *http.Request) {
+	sendOkReply(w, r, newSuccessHTTPReply(m.cluster.dataBalancer.status()))
+}
+
+func (m *Server) planMetaBalance(w http.ResponseWriter, r *http.Request) {
+	var (
+		config proto.MetaBalanceConfig
+		count  int
+		err    error
+	)
+	if config, err = parseRequestToMetaBalance(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	count = defaultMetaBalancePlanCount
+	if value := r.FormValue(countKey); value != "" {
+		if count, err = strconv.Atoi(value); err != nil || count <= 0 {
+			sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: unmatchedKey(countKey).Error()})
+			return
+		}
+	}
+	sendOkReply(w, r, newSuccessHTTPReply(m.cluster.metaBalancer.plan(config, count)))
+}
+
+func (m *Server) startMetaBalance(w http.ResponseWriter, r *http.Request) {
+	var (
+		config proto.MetaBalanceConfig
+		err    error
+	)
+	if config, err = parseRequestToMetaBalance(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if err = m.cluster.metaBalancer.start(config); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
+		return
+	}
+	sendOkReply(w, r, newSuccessHTTPReply("start meta balance successfully"))
+}
+
+func (m *Server) stopMetaBalance(w http.ResponseWriter, r *http.Request) {
+	if err := m.cluster.metaBalancer.stop(); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
+		return
+	}
+	sendOkReply(w, r, newSuccessHTTPReply("stop meta balance successfully"))
+}
+
+func (m *Server) getMetaBalanceStatus(w http.ResponseWriter, r *http.Request) {
+	sendOkReply(w, r, newSuccessHTTPReply(m.cluster.metaBalancer.status()))
+}
+
+// startUpgrade starts the rolling upgrade of the data nodes or the meta nodes.
+func (m *Server) startUpgrade(w http.ResponseWriter, r *http.Request) {
+	var (
+		config proto.UpgradeConfig
+		err    error
+	)
+	if config, err = parseRequestToStartUpgrade(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if err = m.cluster.upgrader.start(config); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
+		return
+	}
+	sendOkReply(w, r, newSuccessHTTPReply("start upgrade successfully"))
+}
+
+func (m *Server) stopUpgrade(w http.ResponseWriter, r *http.Request) {
+	if err := m.cluster.upgrader.stop(); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
+		return
+	}
+	sendOkReply(w, r, newSuccessHTTPReply("stop upgrade successfully"))
+}
+
+func (m *Server) getUpgradeStatus(w http.ResponseWriter, r *http.Request) {
+	sendOkReply(w, r, newSuccessHTTPReply(m.cluster.upgrader.status()))
+}
+
 // View the topology of the cluster.
 func (m *Server) getTopology(w http.ResponseWriter, r *http.Request) {
 	tv := &TopologyView{
@@ -582,6 +687,7 @@ func (m *Server) updateVol(w http.ResponseWriter, r *http.Request) {
 		description    string
 		dpSelectorName string
 		dpSelectorParm string
+		trashDays      uint32
 		vol            *Vol
 	)
 
@@ -608,6 +714,10 @@ func (m *Server) updateVol(w http.ResponseWriter, r *http.Request) {
 		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
 		return
 	}
+	if trashDays, err = parseTrashDaysToUpdateVol(r, vol); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
 
 	newArgs := getVolVarargs(vol)
 
@@ -618,6 +728,7 @@ func (m *Server) updateVol(w http.ResponseWriter, r *http.Request) {
 	newArgs.authenticate = authenticate
 	newArgs.dpSelectorName = dpSelectorName
 	newArgs.dpSelectorParm = dpSelectorParm
+	newArgs.trashDays = trashDays
 
 	if err = m.cluster.updateVol(name, authKey, newArgs); err != nil {
 		sendErrReply(w, r, newErrHTTPReply(err))
@@ -695,6 +806,156 @@ func (m *Server) volShrink(w http.ResponseWriter, r *http.Request) {
 	sendOkReply(w, r, newSuccessHTTPReply(msg))
 }
 
+func (m *Server) createSnapshot(w http.ResponseWriter, r *http.Request) {
+	var (
+		name     string
+		authKey  string
+		snapName string
+		snap     *proto.SnapshotInfo
+		err      error
+	)
+	if name, authKey, snapName, err = parseRequestToOperateSnapshot(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if snap, err = m.cluster.createSnapshot(name, authKey, snapName); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
+		return
+	}
+	sendOkReply(w, r, newSuccessHTTPReply(snap))
+}
+
+func (m *Server) deleteSnapshot(w http.ResponseWriter, r *http.Request) {
+	var (
+		name     string
+		authKey  string
+		snapName string
+		err      error
+	)
+	if name, authKey, snapName, err = parseRequestToOperateSnapshot(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if err = m.cluster.deleteSnapshot(name, authKey, snapName); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
+		return
+	}
+	sendOkReply(w, r, newSuccessHTTPReply(fmt.Sprintf("delete snapshot[%v] of vol[%v] successfully", snapName, name)))
+}
+
+func (m *Server) listSnapshots(w http.ResponseWriter, r *http.Request) {
+	var (
+		name string
+		vol  *Vol
+		err  error
+	)
+	if name, err = parseAndExtractName(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if vol, err = m.cluster.getVol(name); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeVolNotExists, Msg: err.Error()})
+		return
+	}
+	sendOkReply(w, r, newSuccessHTTPReply(vol.cloneSnapshots()))
+}
+
+func (m *Server) setQuota(w http.ResponseWriter, r *http.Request) {
+	var (
+		name      string
+		authKey   string
+		quotaType proto.QuotaType
+		target    uint64
+		maxBytes  uint64
+		maxInodes uint64
+		quota     *proto.QuotaInfo
+		err       error
+	)
+	if name, authKey, quotaType, target, maxBytes, maxInodes, err = parseRequestToSetQuota(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if quota, err = m.cluster.setQuota(name, authKey, quotaType, target, maxBytes, maxInodes); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
+		return
+	}
+	sendOkReply(w, r, newSuccessHTTPReply(quota))
+}
+
+func (m *Server) deleteQuota(w http.ResponseWriter, r *http.Request) {
+	var (
+		name    string
+		authKey string
+		quotaID uint64
+		err     error
+	)
+	if err = r.ParseForm(); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if name, err = extractName(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if authKey, err = extractAuthKey(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if quotaID, err = extractQuotaID(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if err = m.cluster.deleteQuota(name, authKey, quotaID); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
+		return
+	}
+	sendOkReply(w, r, newSuccessHTTPReply(fmt.Sprintf("delete quota[%v] of vol[%v] successfully", quotaID, name)))
+}
+
+func (m *Server) listQuotas(w http.ResponseWriter, r *http.Request) {
+	var (
+		name string
+		vol  *Vol
+		err  error
+	)
+	if name, err = parseAndExtractName(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if vol, err = m.cluster.getVol(name); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeVolNotExists, Msg: err.Error()})
+		return
+	}
+	sendOkReply(w, r, newSuccessHTTPReply(vol.cloneQuotas()))
+}
+
+func (m *Server) getQuota(w http.ResponseWriter, r *http.Request) {
+	var (
+		name    string
+		quotaID uint64
+		vol     *Vol
+		quota   *proto.QuotaInfo
+		err     error
+	)
+	if name, err = parseAndExtractName(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if quotaID, err = extractQuotaID(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if vol, err = m.cluster.getVol(name); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeVolNotExists, Msg: err.Error()})
+		return
+	}
+	if quota, err = vol.getQuota(quotaID); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
+		return
+	}
+	sendOkReply(w, r, newSuccessHTTPReply(quota))
+}
+
 func (m *Server) createVol(w http.ResponseWriter, r *http.Request) {
 	var (
 		name         string
@@ -712,6 +973,8 @@ func (m *Server) createVol(w http.ResponseWriter, r *http.Request) {
 		defaultPriority bool
 		zoneName     string
 		description  string
+		ecDataNum    int
+		ecParityNum  int
 	)
 
 	if name, owner, zoneName, description,
@@ -722,7 +985,11 @@ func (m *Server) createVol(w http.ResponseWriter, r *http.Request) {
 		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
 		return
 	}
-	if !(dpReplicaNum == 2 || dpReplicaNum == 3) {
+	if ecDataNum, ecParityNum, err = parseECToCreateVol(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if ecDataNum == 0 && !(dpReplicaNum == 2 || dpReplicaNum == 3) {
 		err = fmt.Errorf("replicaNum can only be 2 and 3,received replicaNum is[%v]", dpReplicaNum)
 		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
 		return
@@ -730,7 +997,7 @@ func (m *Server) createVol(w http.ResponseWriter, r *http.Request) {
 	if vol, err = m.cluster.createVol(name, owner, zoneName, description,
 					mpCount, dpReplicaNum, size, capacity,
 					followerRead, authenticate, crossZone,
-					defaultPriority); err != nil {
+					defaultPriority, ecDataNum, ecParityNum); err != nil {
 		sendErrReply(w, r, newErrHTTPReply(err))
 		return
 	}
@@ -798,6 +1065,10 @@ func newSimpleView(vol *Vol) *proto.SimpleVolView {
 		DpSelectorName:     vol.dpSelectorName,
 		DpSelectorParm:     vol.dpSelectorParm,
 		DefaultZonePrior:   vol.defaultPriority,
+		SnapshotCnt:        len(vol.snapshots),
+		TrashDays:          vol.trashDays,
+		ECDataNum:          vol.ecDataNum,
+		ECParityNum:        vol.ecParityNum,
 	}
 }
 
@@ -884,6 +1155,9 @@ func (m *Server) getDataNode(w http.ResponseWriter, r *http.Request) {
 		NodeSetID:                 dataNode.NodeSetID,
 		PersistenceDataPartitions: dataNode.PersistenceDataPartitions,
 		BadDisks:                  dataNode.BadDisks,
+		Version:                   dataNode.Version,
+		Upgrading:                 dataNode.Upgrading,
+		MaintenanceUntil:          dataNode.MaintenanceUntil,
 	}
 
 	sendOkReply(w, r, newSuccessHTTPReply(dataNodeInfo))
@@ -915,6 +1189,49 @@ func (m *Server) decommissionDataNode(w http.ResponseWriter, r *http.Request) {
 	sendOkReply(w, r, newSuccessHTTPReply(rstMsg))
 }
 
+// Put a data node in maintenance for a bounded window, so that the replicas on it are neither
+// alarmed nor replaced while it is rebooted.
+func (m *Server) startDataNodeMaintenance(w http.ResponseWriter, r *http.Request) {
+	var (
+		nodeAddr    string
+		durationSec int64
+		err         error
+	)
+	if nodeAddr, durationSec, err = parseRequestToStartMaintenance(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if _, err = m.cluster.dataNode(nodeAddr); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(proto.ErrDataNodeNotExists))
+		return
+	}
+	if err = m.cluster.startDataNodeMaintenance(nodeAddr, durationSec); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
+		return
+	}
+	sendOkReply(w, r, newSuccessHTTPReply(fmt.Sprintf("data node [%v] is in maintenance for %v seconds", nodeAddr, durationSec)))
+}
+
+func (m *Server) stopDataNodeMaintenance(w http.ResponseWriter, r *http.Request) {
+	var (
+		nodeAddr string
+		err      error
+	)
+	if nodeAddr, err = parseAndExtractNodeAddr(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if _, err = m.cluster.dataNode(nodeAddr); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(proto.ErrDataNodeNotExists))
+		return
+	}
+	if err = m.cluster.stopDataNodeMaintenance(nodeAddr); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
+		return
+	}
+	sendOkReply(w, r, newSuccessHTTPReply(fmt.Sprintf("stop maintenance of data node [%v] successfully", nodeAddr)))
+}
+
 func (m *Server) setNodeInfoHandler(w http.ResponseWriter, r *http.Request) {
 	var (
 		params map[string]interface{}
@@ -1552,6 +1869,9 @@ func (m *Server) getMetaNode(w http.ResponseWriter, r *http.Request) {
 		MetaPartitionCount:        metaNode.MetaPartitionCount,
 		NodeSetID:                 metaNode.NodeSetID,
 		PersistenceMetaPartitions: metaNode.PersistenceMetaPartitions,
+		Version:                   metaNode.Version,
+		Upgrading:                 metaNode.Upgrading,
+		MaintenanceUntil:          metaNode.MaintenanceUntil,
 	}
 	sendOkReply(w, r, newSuccessHTTPReply(metaNodeInfo))
 }
@@ -1580,6 +1900,58 @@ func (m *Server) decommissionMetaPartition(w http.ResponseWriter, r *http.Reques
 	sendOkReply(w, r, newSuccessHTTPReply(msg))
 }
 
+// mergeMetaPartition merges the meta partition following the given one into it.
+func (m *Server) mergeMetaPartition(w http.ResponseWriter, r *http.Request) {
+	var (
+		volName     string
+		partitionID uint64
+		task        *proto.MetaMoveTask
+		err         error
+	)
+	if volName, err = extractName(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if partitionID, err = extractMetaPartitionID(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if task, err = m.cluster.metaMover.merge(volName, partitionID); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
+		return
+	}
+	sendOkReply(w, r, newSuccessHTTPReply(task))
+}
+
+// resplitMetaPartition moves the inodes from the given start to the end of the meta partition
+// into a new partition.
+func (m *Server) resplitMetaPartition(w http.ResponseWriter, r *http.Request) {
+	var (
+		volName     string
+		partitionID uint64
+		start       uint64
+		task        *proto.MetaMoveTask
+		err         error
+	)
+	if volName, start, err = validateRequestToCreateMetaPartition(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if partitionID, err = extractMetaPartitionID(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if task, err = m.cluster.metaMover.resplit(volName, partitionID, start); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
+		return
+	}
+	sendOkReply(w, r, newSuccessHTTPReply(task))
+}
+
+func (m *Server) getMetaMoveStatus(w http.ResponseWriter, r *http.Request) {
+	sendOkReply(w, r, newSuccessHTTPReply(m.cluster.metaMover.status()))
+}
+
 func (m *Server) loadMetaPartition(w http.ResponseWriter, r *http.Request) {
 	var (
 		msg         string
@@ -1628,6 +2000,49 @@ func (m *Server) decommissionMetaNode(w http.ResponseWriter, r *http.Request) {
 	sendOkReply(w, r, newSuccessHTTPReply(rstMsg))
 }
 
+// Put a meta node in maintenance for a bounded window, so that the replicas on it are neither
+// alarmed nor replaced while it is rebooted.
+func (m *Server) startMetaNodeMaintenance(w http.ResponseWriter, r *http.Request) {
+	var (
+		nodeAddr    string
+		durationSec int64
+		err         error
+	)
+	if nodeAddr, durationSec, err = parseRequestToStartMaintenance(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if _, err = m.cluster.metaNode(nodeAddr); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(proto.ErrMetaNodeNotExists))
+		return
+	}
+	if err = m.cluster.startMetaNodeMaintenance(nodeAddr, durationSec); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
+		return
+	}
+	sendOkReply(w, r, newSuccessHTTPReply(fmt.Sprintf("meta node [%v] is in maintenance for %v seconds", nodeAddr, durationSec)))
+}
+
+func (m *Server) stopMetaNodeMaintenance(w http.ResponseWriter, r *http.Request) {
+	var (
+		nodeAddr string
+		err      error
+	)
+	if nodeAddr, err = parseAndExtractNodeAddr(r); err != nil {
+		sendErrReply(w, r, &proto.HTTPReply{Code: proto.ErrCodeParamError, Msg: err.Error()})
+		return
+	}
+	if _, err = m.cluster.metaNode(nodeAddr); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(proto.ErrMetaNodeNotExists))
+		return
+	}
+	if err = m.cluster.stopMetaNodeMaintenance(nodeAddr); err != nil {
+		sendErrReply(w, r, newErrHTTPReply(err))
+		return
+	}
+	sendOkReply(w, r, newSuccessHTTPReply(fmt.Sprintf("stop maintenance of meta node [%v] successfully", nodeAddr)))
+}
+
 func (m *Server) handleMetaNodeTaskResponse(w http.ResponseWriter, r *http.Request) {
 	tr, err := parseRequestToGetTaskResponse(r)
 	if err != nil {
@@ -1732,6 +2147,23 @@ func parseAndExtractNodeAddr(r *http.Request) (nodeAddr string, err error) {
 	return extractNodeAddr(r)
 }
 
+func parseRequestToStartMaintenance(r *http.Request) (nodeAddr string, durationSec int64, err error) {
+	if err = r.ParseForm(); err != nil {
+		return
+	}
+	if nodeAddr, err = extractNodeAddr(r); err != nil {
+		return
+	}
+	durationSec = defaultNodeMaintenanceSec
+	if value := r.FormValue(durationKey); value != "" {
+		if durationSec, err = strconv.ParseInt(value, 10, 64); err != nil {
+			err = unmatchedKey(durationKey)
+			return
+		}
+	}
+	return
+}
+
 func parseRequestToDecommissionNode(r *http.Request) (nodeAddr, diskPath string, err error) {
 	if err = r.ParseForm(); err != nil {
 		return
@@ -1870,6 +2302,18 @@ func parseDefaultInfoToUpdateVol(r *http.Request, vol *Vol) (zoneName string, ca
 	return
 }
 
+func parseTrashDaysToUpdateVol(r *http.Request, vol *Vol) (trashDays uint32, err error) {
+	if trashDaysStr := r.FormValue(trashDaysKey); trashDaysStr != "" {
+		var days uint64
+		if days, err = strconv.ParseUint(trashDaysStr, 10, 32); err != nil {
+			err = unmatchedKey(trashDaysKey)
+			return
+		}
+		return uint32(days), nil
+	}
+	return vol.trashDays, nil
+}
+
 func parseBoolFieldToUpdateVol(r *http.Request, vol *Vol) (followerRead, authenticate bool, err error) {
 	if followerReadStr := r.FormValue(followerReadKey); followerReadStr != "" {
 		if followerRead, err = strconv.ParseBool(followerReadStr); err != nil {
@@ -1906,6 +2350,76 @@ func parseRequestToSetVolCapacity(r *http.Request) (name, authKey string, capaci
 	return
 }
 
+func parseRequestToOperateSnapshot(r *http.Request) (name, authKey, snapName string, err error) {
+	if err = r.ParseForm(); err != nil {
+		return
+	}
+	if name, err = extractName(r); err != nil {
+		return
+	}
+	if authKey, err = extractAuthKey(r); err != nil {
+		return
+	}
+	if snapName = r.FormValue(snapshotKey); snapName == "" {
+		err = keyNotFound(snapshotKey)
+		return
+	}
+	if !volNameRegexp.MatchString(snapName) {
+		err = errors.New("snapshot name can only be number and letters")
+		return
+	}
+	return
+}
+
+func parseRequestToSetQuota(r *http.Request) (name, authKey string, quotaType proto.QuotaType,
+	target, maxBytes, maxInodes uint64, err error) {
+	if err = r.ParseForm(); err != nil {
+		return
+	}
+	if name, err = extractName(r); err != nil {
+		return
+	}
+	if authKey, err = extractAuthKey(r); err != nil {
+		return
+	}
+	if quotaType = proto.ParseQuotaType(r.FormValue(quotaTypeKey)); !quotaType.Valid() {
+		err = unmatchedKey(quotaTypeKey)
+		return
+	}
+	var value string
+	if value = r.FormValue(quotaTargetKey); value == "" {
+		err = keyNotFound(quotaTargetKey)
+		return
+	}
+	if target, err = strconv.ParseUint(value, 10, 64); err != nil {
+		return
+	}
+	if quotaType == proto.QuotaTypeDir && target == 0 {
+		err = unmatchedKey(quotaTargetKey)
+		return
+	}
+	if value = r.FormValue(quotaMaxBytesKey); value != "" {
+		if maxBytes, err = strconv.ParseUint(value, 10, 64); err != nil {
+			return
+		}
+	}
+	if value = r.FormValue(quotaMaxInodesKey); value != "" {
+		if maxInodes, err = strconv.ParseUint(value, 10, 64); err != nil {
+			return
+		}
+	}
+	return
+}
+
+func extractQuotaID(r *http.Request) (quotaID uint64, err error) {
+	var value string
+	if value = r.FormValue(quotaIDKey); value == "" {
+		err = keyNotFound(quotaIDKey)
+		return
+	}
+	return strconv.ParseUint(value, 10, 64)
+}
+
 func parseRequestToCreateVol(r *http.Request) (name, owner, zoneName, description string,
 		mpCount, dpReplicaNum, size,
 		capacity int, followerRead,
@@ -1965,6 +2479,28 @@ func parseRequestToCreateVol(r *http.Request) (name, owner, zoneName, descriptio
 	return
 }
 
+// parseECToCreateVol parses the numbers of data and parity shards if the data
+// partitions of the volume to create are erasure-coded.
+func parseECToCreateVol(r *http.Request) (dataNum, parityNum int, err error) {
+	dataNumStr, parityNumStr := r.FormValue(ecDataNumKey), r.FormValue(ecParityNumKey)
+	if dataNumStr == "" && parityNumStr == "" {
+		return
+	}
+	if dataNum, err = strconv.Atoi(dataNumStr); err != nil {
+		err = unmatchedKey(ecDataNumKey)
+		return
+	}
+	if parityNum, err = strconv.Atoi(parityNumStr); err != nil {
+		err = unmatchedKey(ecParityNumKey)
+		return
+	}
+	if !proto.IsValidECShards(dataNum, parityNum) {
+		err = fmt.Errorf("invalid ecDataNum[%v] ecParityNum[%v], the total number of shards can't be larger than %v",
+			dataNum, parityNum, proto.ECMaxShards)
+	}
+	return
+}
+
 func parseRequestToCreateDataPartition(r *http.Request) (count int, name string, err error) {
 	if err = r.ParseForm(); err != nil {
 		return
@@ -2166,6 +2702,100 @@ func extractDefaulPriority(r *http.Request) (defaultPrior bool, err error) {
 }
 
 
+func parseRequestToStartDataBalance(r *http.Request) (config proto.DataBalanceConfig, err error) {
+	if err = r.ParseForm(); err != nil {
+		return
+	}
+	var value string
+	if value = r.FormValue(thresholdKey); value != "" {
+		if config.Threshold, err = strconv.ParseFloat(value, 64); err != nil {
+			return
+		}
+		if config.Threshold <= 0 || config.Threshold >= 1 {
+			err = unmatchedKey(thresholdKey)
+			return
+		}
+	}
+	if value = r.FormValue(maxConcurrencyKey); value != "" {
+		if config.MaxConcurrency, err = strconv.Atoi(value); err != nil {
+			return
+		}
+		if config.MaxConcurrency <= 0 {
+			err = unmatchedKey(maxConcurrencyKey)
+			return
+		}
+	}
+	if value = r.FormValue(maxBandwidthKey); value != "" {
+		if config.MaxBandwidth, err = strconv.ParseUint(value, 10, 64); err != nil {
+			return
+		}
+	}
+	return
+}
+
+func parseRequestToStartUpgrade(r *http.Request) (config proto.UpgradeConfig, err error) {
+	if err = r.ParseForm(); err != nil {
+		return
+	}
+	if config.Role = r.FormValue(roleKey); config.Role == "" {
+		err = keyNotFound(roleKey)
+		return
+	}
+	if config.Version = r.FormValue(versionKey); config.Version == "" {
+		err = keyNotFound(versionKey)
+		return
+	}
+	var value string
+	if value = r.FormValue(nodeHostsKey); value != "" {
+		config.Nodes = strings.Split(value, ",")
+	}
+	if value = r.FormValue(timeoutKey); value != "" {
+		if config.TimeoutSec, err = strconv.ParseInt(value, 10, 64); err != nil {
+			return
+		}
+		if config.TimeoutSec <= 0 {
+			err = unmatchedKey(timeoutKey)
+			return
+		}
+	}
+	return
+}
+
+func parseRequestToMetaBalance(r *http.Request) (config proto.MetaBalanceConfig, err error) {
+	if err = r.ParseForm(); err != nil {
+		return
+	}
+	var value string
+	if value = r.FormValue(thresholdKey); value != "" {
+		if config.MemThreshold, err = strconv.ParseFloat(value, 64); err != nil {
+			return
+		}
+		if config.MemThreshold <= 0 || config.MemThreshold >= 1 {
+			err = unmatchedKey(thresholdKey)
+			return
+		}
+	}
+	if value = r.FormValue(rateThresholdKey); value != "" {
+		if config.RateThreshold, err = strconv.ParseFloat(value, 64); err != nil {
+			return
+		}
+		if config.RateThreshold <= 0 {
+			err = unmatchedKey(rateThresholdKey)
+			return
+		}
+	}
+	if value = r.FormValue(maxConcurrencyKey); value != "" {
+		if config.MaxConcurrency, err = strconv.Atoi(value); err != nil {
+			return
+		}
+		if config.MaxConcurrency <= 0 {
+			err = unmatchedKey(maxConcurrencyKey)
+			return
+		}
+	}
+	return
+}
+
 func parseAndExtractThreshold(r *http.Request) (threshold float64, err error) {
 	if err = r.ParseForm(); err != nil {
 		return
@@ -2471,6 +3101,8 @@ func volStat(vol *Vol) (stat *proto.VolStatInfo) {
 		stat.UsedSize = stat.TotalSize
 	}
 	stat.UsedRatio = strconv.FormatFloat(float64(stat.UsedSize)/float64(stat.TotalSize), 'f', 2, 32)
+	stat.DirQuotaCnt = vol.dirQuotaCount()
+	stat.TrashDays = vol.trashDays
 	log.LogDebugf("total[%v],usedSize[%v]", stat.TotalSize, stat.UsedSize)
 	return
 }
@@ -59,6 +59,10 @@ type Cluster struct {
 	lastMasterZoneForDataNode string
 	lastMasterZoneForMetaNode string
 	zoneList                  []string
+	dataBalancer              *dataBalancer
+	metaBalancer              *metaBalancer
+	metaMover                 *metaMover
+	upgrader                  *upgrader
 }
 
 func newCluster(name string, leaderInfo *LeaderInfo, fsm *MetadataFsm, partition raftstore.Partition, cfg *clusterConfig) (c *Cluster) {
@@ -78,6 +82,10 @@ func newCluster(name string, leaderInfo *LeaderInfo, fsm *MetadataFsm, partition
 	c.partition = partition
 	c.idAlloc = newIDAllocator(c.fsm.store, c.partition)
 	c.nodeSetGrpManager = newNodeSetGrpManager(c)
+	c.dataBalancer = newDataBalancer(c)
+	c.metaBalancer = newMetaBalancer(c)
+	c.metaMover = newMetaMover(c)
+	c.upgrader = newUpgrader(c)
 	return
 }
 
@@ -95,6 +103,8 @@ func (c *Cluster) scheduleTask() {
 	c.scheduleToLoadMetaPartitions()
 	c.scheduleToReduceReplicaNum()
 	c.scheduleToCheckNodeSetGrpManagerStatus()
+	c.scheduleToBalanceData()
+	c.scheduleToBalanceMeta()
 }
 
 func (c *Cluster) masterAddr() (addr string) {
@@ -288,10 +298,11 @@ func (c *Cluster) checkLeaderAddr() {
 
 func (c *Cluster) checkDataNodeHeartbeat() {
 	tasks := make([]*proto.AdminTask, 0)
+	snapshotVols, snapshotVolsVer := c.snapshotVols()
 	c.dataNodes.Range(func(addr, dataNode interface{}) bool {
 		node := dataNode.(*DataNode)
 		node.checkLiveness()
-		task := node.createHeartbeatTask(c.masterAddr())
+		task := node.createHeartbeatTask(c.masterAddr(), snapshotVols, snapshotVolsVer)
 		tasks = append(tasks, task)
 		return true
 	})
@@ -300,16 +311,30 @@ func (c *Cluster) checkDataNodeHeartbeat() {
 
 func (c *Cluster) checkMetaNodeHeartbeat() {
 	tasks := make([]*proto.AdminTask, 0)
+	quotas := c.quotaHeartBeatInfos()
+	trashDays := c.volTrashDays()
 	c.metaNodes.Range(func(addr, metaNode interface{}) bool {
 		node := metaNode.(*MetaNode)
 		node.checkHeartbeat()
-		task := node.createHeartbeatTask(c.masterAddr())
+		task := node.createHeartbeatTask(c.masterAddr(), quotas, trashDays)
 		tasks = append(tasks, task)
 		return true
 	})
 	c.addMetaNodeTasks(tasks)
 }
 
+// volTrashDays returns the days to keep the deleted files of the volumes with
+// trash enabled, which are sent to the meta nodes to expire the trash.
+func (c *Cluster) volTrashDays() (trashDays map[string]uint32) {
+	trashDays = make(map[string]uint32)
+	for _, vol := range c.copyVols() {
+		if vol.Status != markDelete && vol.trashDays > 0 {
+			trashDays[vol.Name] = vol.trashDays
+		}
+	}
+	return
+}
+
 func (c *Cluster) scheduleToCheckMetaPartitions() {
 	go func() {
 		for {
@@ -770,7 +795,11 @@ func (c *Cluster) createDataPartition(volName string, zoneNum int) (dp *DataPart
 	defer vol.createDpMutex.Unlock()
 	errChannel := make(chan error, vol.dpReplicaNum)
 
-	if c.isFaultDomain(vol) {
+	if vol.ecDataNum > 0 {
+		if targetHosts, targetPeers, err = c.chooseTargetECDataNodes(vol); err != nil {
+			goto errHandler
+		}
+	} else if c.isFaultDomain(vol) {
 		if targetHosts, targetPeers, err = c.getAvaliableHostFromNsGrp(TypeDataPartion, vol.dpReplicaNum); err != nil {
 			goto errHandler
 		}
@@ -783,6 +812,7 @@ func (c *Cluster) createDataPartition(volName string, zoneNum int) (dp *DataPart
 		goto errHandler
 	}
 	dp = newDataPartition(partitionID, vol.dpReplicaNum, volName, vol.ID)
+	dp.ECDataNum, dp.ECParityNum = vol.ecDataNum, vol.ecParityNum
 	dp.Hosts = targetHosts
 	dp.Peers = targetPeers
 	for _, host := range targetHosts {
@@ -984,6 +1014,23 @@ result:
 	return
 }
 
+// chooseTargetECDataNodes chooses the hosts of an erasure-coded data partition,
+// which are spread over all of the zones if the volume is cross zone.
+func (c *Cluster) chooseTargetECDataNodes(vol *Vol) (hosts []string, peers []proto.Peer, err error) {
+	shardNum := int(vol.ecDataNum) + int(vol.ecParityNum)
+	zoneNum := 1
+	if vol.crossZone {
+		zoneNum = c.t.zoneLen()
+	}
+	if hosts, peers, err = c.t.allocHostsForECDataNode(zoneNum, shardNum, vol.zoneName); err != nil {
+		return
+	}
+	if len(hosts) != shardNum {
+		return nil, nil, errors.Trace(proto.ErrNoDataNodeToCreateDataPartition, "hosts len[%v],shardNum[%v]", len(hosts), shardNum)
+	}
+	return
+}
+
 func (c *Cluster) dataNode(addr string) (dataNode *DataNode, err error) {
 	value, ok := c.dataNodes.Load(addr)
 	if !ok {
@@ -1208,12 +1255,18 @@ func (c *Cluster) decommissionDataPartition(offlineAddr string, dp *DataPartitio
 			}
 		}
 	}
-	if err = c.removeDataReplica(dp, offlineAddr, false); err != nil {
-		goto errHandler
-	}
 	newAddr = targetHosts[0]
-	if err = c.addDataReplica(dp, newAddr); err != nil {
-		goto errHandler
+	if dp.isErasureCoded() {
+		if err = c.replaceECShard(dp, offlineAddr, newAddr); err != nil {
+			goto errHandler
+		}
+	} else {
+		if err = c.removeDataReplica(dp, offlineAddr, false); err != nil {
+			goto errHandler
+		}
+		if err = c.addDataReplica(dp, newAddr); err != nil {
+			goto errHandler
+		}
 	}
 	dp.Status = proto.ReadOnly
 	dp.isRecover = true
@@ -1256,6 +1309,16 @@ func (c *Cluster) validateDecommissionDataPartition(dp *DataPartition, offlineAd
 		err = fmt.Errorf("vol[%v],data partition[%v] is recovering,[%v] can't be decommissioned", vol.Name, dp.PartitionID, offlineAddr)
 		return
 	}
+
+	// the shard on the new host is reconstructed from the shards on the other hosts
+	if err = dp.canECShardBeRebuilt(offlineAddr); err != nil {
+		return
+	}
+
+	// the replica on a node in maintenance is expected to come back
+	if err = c.checkDataNodeMaintenance(offlineAddr); err != nil {
+		return
+	}
 	return
 }
 
@@ -1265,6 +1328,10 @@ func (c *Cluster) addDataReplica(dp *DataPartition, addr string) (err error) {
 			log.LogErrorf("action[addDataReplica],vol[%v],data partition[%v],err[%v]", dp.VolName, dp.PartitionID, err)
 		}
 	}()
+	if dp.isErasureCoded() {
+		err = fmt.Errorf("can't add replica to erasure-coded data partition[%v]", dp.PartitionID)
+		return
+	}
 	dataNode, err := c.dataNode(addr)
 	if err != nil {
 		return
@@ -1280,6 +1347,108 @@ func (c *Cluster) addDataReplica(dp *DataPartition, addr string) (err error) {
 	return
 }
 
+// replaceECShard replaces the offline host of an erasure-coded data partition with
+// the new host at the same index, which is the index of the shard. The hosts of the
+// partition are updated at once after the raft members are changed, so that the
+// clients never see the shards out of order. The new host reconstructs its shard
+// from the shards on the other hosts afterwards.
+func (c *Cluster) replaceECShard(dp *DataPartition, offlineAddr, newAddr string) (err error) {
+	defer func() {
+		if err != nil {
+			log.LogErrorf("action[replaceECShard],vol[%v],data partition[%v],offline[%v],new[%v],err[%v]",
+				dp.VolName, dp.PartitionID, offlineAddr, newAddr, err)
+		}
+	}()
+	vol, err := c.getVol(dp.VolName)
+	if err != nil {
+		return
+	}
+	offlineNode, err := c.dataNode(offlineAddr)
+	if err != nil {
+		return
+	}
+	newNode, err := c.dataNode(newAddr)
+	if err != nil {
+		return
+	}
+	removePeer := proto.Peer{ID: offlineNode.ID, Addr: offlineAddr}
+	addPeer := proto.Peer{ID: newNode.ID, Addr: newAddr}
+	dp.RLock()
+	newHosts := make([]string, len(dp.Hosts))
+	copy(newHosts, dp.Hosts)
+	newPeers := make([]proto.Peer, 0, len(dp.Peers))
+	for _, peer := range dp.Peers {
+		if peer.ID == removePeer.ID && peer.Addr == removePeer.Addr {
+			peer = addPeer
+		}
+		newPeers = append(newPeers, peer)
+	}
+	dp.RUnlock()
+	replaced := false
+	for i, host := range newHosts {
+		if host == offlineAddr {
+			newHosts[i] = newAddr
+			replaced = true
+		}
+	}
+	if !replaced {
+		return fmt.Errorf("host[%v] is not in the hosts[%v]", offlineAddr, newHosts)
+	}
+
+	var leaderAddr string
+	var candidateAddrs []string
+	if leaderAddr, candidateAddrs, err = dp.prepareAddRaftMember(addPeer); err != nil {
+		return
+	}
+	for index, host := range candidateAddrs {
+		if _, err = c.buildAddDataPartitionRaftMemberTaskAndSyncSendTask(dp, addPeer, host); err == nil {
+			break
+		}
+		if index < len(candidateAddrs)-1 {
+			time.Sleep(retrySendSyncTaskInternal)
+		}
+	}
+	if err != nil {
+		return
+	}
+	diskPath, err := c.syncCreateDataPartitionToDataNode(newAddr, vol.dataPartitionSize, dp, newPeers, newHosts, proto.DecommissionedCreateDataPartition)
+	if err != nil {
+		return
+	}
+	task, err := dp.createTaskToRemoveRaftMember(removePeer)
+	if err != nil {
+		return
+	}
+	leaderDataNode, err := c.dataNode(task.OperatorAddr)
+	if err != nil {
+		return
+	}
+	if _, err = leaderDataNode.TaskManager.syncSendAdminTask(task); err != nil {
+		return
+	}
+
+	dp.Lock()
+	if err = dp.afterCreation(newAddr, diskPath, c); err != nil {
+		dp.Unlock()
+		return
+	}
+	if err = dp.update("replaceECShard", dp.VolName, newPeers, newHosts, c); err != nil {
+		dp.Unlock()
+		return
+	}
+	dp.Unlock()
+	if err = c.deleteDataReplica(dp, offlineNode); err != nil {
+		return
+	}
+	if leaderAddr != offlineAddr {
+		return
+	}
+	if leaderDataNode, err = c.dataNode(dp.Hosts[0]); err != nil {
+		return
+	}
+	return dp.tryToChangeLeader(c, leaderDataNode)
+}
+
 func (c *Cluster) buildAddDataPartitionRaftMemberTaskAndSyncSendTask(dp *DataPartition, addPeer proto.Peer, leaderAddr string) (resp *proto.Packet, err error) {
 	defer func() {
 		var resultCode uint8
@@ -1607,6 +1776,7 @@ func (c *Cluster) updateVol(name, authKey string, newArgs *VolVarargs) (err erro
 		oldDescription    string
 		oldDpSelectorName string
 		oldDpSelectorParm string
+		oldTrashDays      uint32
 		volUsedSpace      uint64
 		newZoneName       string
 	)
@@ -1632,6 +1802,10 @@ func (c *Cluster) updateVol(name, authKey string, newArgs *VolVarargs) (err erro
 			vol.dpReplicaNum)
 		goto errHandler
 	}
+	if vol.ecDataNum > 0 && newArgs.dpReplicaNum != vol.dpReplicaNum {
+		err = fmt.Errorf("don't support changing replicaNum of erasure-coded vol[%v]", name)
+		goto errHandler
+	}
 
 	if newZoneName, err = c.checkVolInfo(name, vol.crossZone, newArgs.zoneName); err != nil {
 		goto errHandler
@@ -1645,6 +1819,7 @@ func (c *Cluster) updateVol(name, authKey string, newArgs *VolVarargs) (err erro
 	oldDescription = vol.description
 	oldDpSelectorName = vol.dpSelectorName
 	oldDpSelectorParm = vol.dpSelectorParm
+	oldTrashDays = vol.trashDays
 
 	vol.zoneName = newArgs.zoneName
 	vol.Capacity = newArgs.capacity
@@ -1659,6 +1834,7 @@ func (c *Cluster) updateVol(name, authKey string, newArgs *VolVarargs) (err erro
 	}
 	vol.dpSelectorName = newArgs.dpSelectorName
 	vol.dpSelectorParm = newArgs.dpSelectorParm
+	vol.trashDays = newArgs.trashDays
 
 	if err = c.syncUpdateVol(vol); err != nil {
 		vol.Capacity = oldCapacity
@@ -1669,6 +1845,7 @@ func (c *Cluster) updateVol(name, authKey string, newArgs *VolVarargs) (err erro
 		vol.description = oldDescription
 		vol.dpSelectorName = oldDpSelectorName
 		vol.dpSelectorParm = oldDpSelectorParm
+		vol.trashDays = oldTrashDays
 
 		log.LogErrorf("action[updateVol] vol[%v] err[%v]", name, err)
 		err = proto.ErrPersistenceByRaft
@@ -1724,7 +1901,8 @@ func (c *Cluster) checkVolInfo(name string, crossZone bool, zoneName string) (ne
 // By default we create 3 meta partitions and 10 data partitions during initialization.
 func (c *Cluster) createVol(name, owner, zoneName, description string,
 			mpCount, dpReplicaNum, size, capacity int,
-			followerRead, authenticate, crossZone, defaultPriority bool) (vol *Vol, err error) {
+			followerRead, authenticate, crossZone, defaultPriority bool,
+			ecDataNum, ecParityNum int) (vol *Vol, err error) {
 	var (
 		dataPartitionSize       uint64
 		readWriteDataPartitions int
@@ -1748,7 +1926,7 @@ func (c *Cluster) createVol(name, owner, zoneName, description string,
 	if vol, err = c.doCreateVol(name, owner, zoneName, description,
 						dataPartitionSize, uint64(capacity), dpReplicaNum,
 						followerRead, authenticate, crossZone,
-						defaultPriority); err != nil {
+						defaultPriority, ecDataNum, ecParityNum); err != nil {
 		goto errHandler
 	}
 	if err = vol.initMetaPartitions(c, mpCount); err != nil {
@@ -1780,7 +1958,7 @@ errHandler:
 func (c *Cluster) doCreateVol(name, owner, zoneName, description string,
 						dpSize, capacity uint64, dpReplicaNum int,
 						followerRead, authenticate, crossZone,
-						defaultPriority bool) (vol *Vol, err error) {
+						defaultPriority bool, ecDataNum, ecParityNum int) (vol *Vol, err error) {
 	var id uint64
 	c.createVolMutex.Lock()
 	defer c.createVolMutex.Unlock()
@@ -1797,6 +1975,11 @@ func (c *Cluster) doCreateVol(name, owner, zoneName, description string,
 			capacity, uint8(dpReplicaNum), defaultReplicaNum,
 			followerRead, authenticate, crossZone,
 			defaultPriority, createTime, description)
+	if ecDataNum > 0 {
+		// every data partition of an erasure-coded volume holds all of the shards
+		vol.ecDataNum, vol.ecParityNum = uint8(ecDataNum), uint8(ecParityNum)
+		vol.dpReplicaNum = uint8(ecDataNum + ecParityNum)
+	}
 	// refresh oss secure
 	vol.refreshOSSSecure()
 	if err = c.syncAddVol(vol); err != nil {
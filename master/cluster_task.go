@@ -195,6 +195,11 @@ func (c *Cluster) validateDecommissionMetaPartition(mp *MetaPartition, nodeAddr
 		err = fmt.Errorf("vol[%v],meta partition[%v] is recovering,[%v] can't be decommissioned", vol.Name, mp.PartitionID, nodeAddr)
 		return
 	}
+
+	// the replica on a node in maintenance is expected to come back
+	if err = c.checkMetaNodeMaintenance(nodeAddr); err != nil {
+		return
+	}
 	return
 }
 
@@ -994,7 +999,7 @@ func (c *Cluster) updateInodeIDUpperBound(mp *MetaPartition, mr *proto.MetaParti
 		return
 	}
 	maxPartitionID := vol.maxPartitionID()
-	if mr.PartitionID < maxPartitionID {
+	if mr.PartitionID != maxPartitionID {
 		return
 	}
 	var end uint64
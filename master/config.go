@@ -72,6 +72,32 @@ const (
 	defaultReplicaNum                                  = 3
 	defaultDiffSpaceUsage                              = 1024 * 1024 * 1024
 	defaultNodeSetGrpStep                              = 1
+	defaultDataBalanceThreshold                float64 = 0.1              // max difference of the usage ratios of the data nodes in a zone
+	defaultDataBalanceConcurrency                      = 2                // max number of the data partitions migrating at the same time
+	defaultIntervalToBalanceData                       = 60               // in terms of seconds
+	defaultIntervalToCheckDataBalanceRepair            = 10               // in terms of seconds
+	defaultDataBalanceTaskTimeOutSec                   = 6 * 60 * 60      // max time to wait for the new replica to be repaired
+	defaultDataBalanceRepairDiff                       = 16 * 1024 * 1024 // max difference of the used space of the repaired replica
+	defaultDataBalanceHistoryCount                     = 100
+	defaultMetaBalanceMemThreshold             float64 = 0.1         // max difference of the memory usage ratios of the meta nodes in a zone
+	defaultMetaBalanceRateThreshold            float64 = 0.5         // max difference of the request rates of the meta nodes in a zone, relative to the average
+	defaultMetaBalanceMinRequestRate           float64 = 100         // request rates are not balanced if the average of the zone is lower
+	defaultMetaBalanceConcurrency                      = 1           // max number of the meta partitions migrating at the same time
+	defaultMetaBalancePlanCount                        = 100         // max number of the migrations in a dry-run plan
+	defaultIntervalToBalanceMeta                       = 60          // in terms of seconds
+	defaultIntervalToCheckMetaBalanceCatchUp           = 10          // in terms of seconds
+	defaultMetaBalanceTaskTimeOutSec                   = 2 * 60 * 60 // max time to wait for the new replica to catch up with the leader
+	defaultMetaBalanceCatchUpDiff                      = 1000        // max difference of the inode and dentry counts of the caught up replica
+	defaultMetaBalanceLeaderTimeOutSec                 = 5 * 60      // max time to wait for the leader to be transferred
+	defaultMetaBalanceHistoryCount                     = 100
+	defaultMetaMoveTimeOutSec                          = 2 * 60 * 60 // max time to wait for the items to be moved between meta partitions
+	defaultIntervalToCheckMetaMove                     = 5           // in terms of seconds
+	defaultMetaMoveHistoryCount                        = 100
+	defaultUpgradeNodeTimeOutSec                       = 30 * 60      // max time to wait for a node to come back with the new version
+	defaultUpgradeTransferTimeOutSec                   = 2 * 60       // max time to transfer the raft leaders away from a node
+	defaultIntervalToCheckUpgrade                      = 10           // in terms of seconds
+	defaultNodeMaintenanceSec                          = 60 * 60      // default window of the maintenance of a node
+	defaultMaxNodeMaintenanceSec                       = 24 * 60 * 60 // max window of the maintenance of a node
 )
 
 // AddrDatabase is a map that stores the address of a given host (e.g., the leader)
@@ -55,6 +55,22 @@ const (
 	dpSelectorParmKey       = "dpSelectorParm"
 	nodeTypeKey             = "nodeType"
 	ratio                   = "ratio"
+	snapshotKey             = "snapshot"
+	quotaIDKey              = "quotaId"
+	quotaTypeKey            = "type"
+	quotaTargetKey          = "target"
+	quotaMaxBytesKey        = "maxBytes"
+	quotaMaxInodesKey       = "maxInodes"
+	trashDaysKey            = "trashDays"
+	ecDataNumKey            = "ecDataNum"
+	ecParityNumKey          = "ecParityNum"
+	maxConcurrencyKey       = "maxConcurrency"
+	maxBandwidthKey         = "maxBandwidth"
+	rateThresholdKey        = "rateThreshold"
+	roleKey                 = "role"
+	versionKey              = "version"
+	timeoutKey              = "timeout"
+	durationKey             = "duration"
 )
 
 const (
@@ -0,0 +1,554 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package master
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// dataBalancer migrates the data replicas from the data nodes with higher space usage to the ones
+// with lower usage in the same zone, until the difference of the usage ratios is below the threshold.
+// Each replica is migrated by adding a replica on the target, waiting for it to be repaired, and then
+// removing the replica on the source, so that the number of the replicas never decreases.
+//
+// The state of the balancer is kept in the memory of the leader master only. If the leader changes,
+// the balance has to be started again on the new leader.
+type dataBalancer struct {
+	sync.RWMutex
+	c          *Cluster
+	running    bool
+	config     proto.DataBalanceConfig
+	startTime  time.Time
+	lastCheck  time.Time
+	budget     float64                           // bytes allowed to start migrating, refilled by the max bandwidth
+	tasks      map[uint64]*proto.DataBalanceTask // migrating tasks by partition ID
+	history    []*proto.DataBalanceTask
+	finished   uint64
+	failed     uint64
+	movedBytes uint64
+}
+
+func newDataBalancer(c *Cluster) *dataBalancer {
+	return &dataBalancer{c: c, tasks: make(map[uint64]*proto.DataBalanceTask)}
+}
+
+func (b *dataBalancer) start(config proto.DataBalanceConfig) (err error) {
+	b.Lock()
+	defer b.Unlock()
+	if b.running {
+		return proto.ErrDataBalanceRunning
+	}
+	if config.Threshold <= 0 {
+		config.Threshold = defaultDataBalanceThreshold
+	}
+	if config.MaxConcurrency <= 0 {
+		config.MaxConcurrency = defaultDataBalanceConcurrency
+	}
+	b.running = true
+	b.config = config
+	b.startTime = time.Now()
+	b.lastCheck = b.startTime
+	b.budget = 0
+	log.LogWarnf("action[startDataBalance] threshold[%v] maxConcurrency[%v] maxBandwidth[%v]",
+		config.Threshold, config.MaxConcurrency, config.MaxBandwidth)
+	return
+}
+
+// stop stops planning the migrations. The ones in progress are not interrupted, since the source
+// replicas have to be removed to keep the number of the replicas.
+func (b *dataBalancer) stop() (err error) {
+	b.Lock()
+	defer b.Unlock()
+	if !b.running {
+		return proto.ErrDataBalanceNotRunning
+	}
+	b.running = false
+	log.LogWarnf("action[stopDataBalance] tasks in progress[%v]", len(b.tasks))
+	return
+}
+
+func (b *dataBalancer) status() (status *proto.DataBalanceStatus) {
+	var nodes = b.c.dataBalanceNodes()
+	b.RLock()
+	defer b.RUnlock()
+	status = &proto.DataBalanceStatus{
+		Running:       b.running,
+		Config:        b.config,
+		FinishedCount: b.finished,
+		FailedCount:   b.failed,
+		MovedBytes:    b.movedBytes,
+		Tasks:         make([]*proto.DataBalanceTask, 0, len(b.tasks)),
+		RecentTasks:   make([]*proto.DataBalanceTask, 0, len(b.history)),
+		Zones:         dataBalanceZoneUsages(nodes),
+	}
+	if !b.startTime.IsZero() {
+		status.StartTime = b.startTime.Unix()
+	}
+	for _, task := range b.tasks {
+		var t = *task
+		status.Tasks = append(status.Tasks, &t)
+	}
+	sort.Slice(status.Tasks, func(i, j int) bool {
+		return status.Tasks[i].PartitionID < status.Tasks[j].PartitionID
+	})
+	for i := len(b.history) - 1; i >= 0; i-- {
+		var t = *b.history[i]
+		status.RecentTasks = append(status.RecentTasks, &t)
+	}
+	return
+}
+
+func (c *Cluster) scheduleToBalanceData() {
+	go func() {
+		for {
+			if c.partition != nil && c.partition.IsRaftLeader() {
+				c.dataBalancer.check()
+			}
+			time.Sleep(time.Second * defaultIntervalToBalanceData)
+		}
+	}()
+}
+
+// check plans the migrations for the free slots of the concurrency, and starts them if the
+// bandwidth allows.
+func (b *dataBalancer) check() {
+	defer func() {
+		if r := recover(); r != nil {
+			log.LogWarnf("checkDataBalance occurred panic,err[%v]", r)
+			WarnBySpecialKey(fmt.Sprintf("%v_%v_scheduling_job_panic", b.c.Name, ModuleName),
+				"checkDataBalance occurred panic")
+		}
+	}()
+	b.Lock()
+	if !b.running {
+		b.Unlock()
+		return
+	}
+	var now = time.Now()
+	if b.config.MaxBandwidth > 0 {
+		// the budget is accumulated for one interval at most, so that the migrations are not
+		// started in a burst after a long idle time
+		var limit = float64(b.config.MaxBandwidth) * defaultIntervalToBalanceData
+		b.budget += float64(b.config.MaxBandwidth) * now.Sub(b.lastCheck).Seconds()
+		if b.budget > limit {
+			b.budget = limit
+		}
+	}
+	b.lastCheck = now
+	var config = b.config
+	var slots = config.MaxConcurrency - len(b.tasks)
+	var migrating = make([]*proto.DataBalanceTask, 0, len(b.tasks))
+	for _, task := range b.tasks {
+		migrating = append(migrating, task)
+	}
+	b.Unlock()
+	if slots <= 0 {
+		return
+	}
+
+	var nodes = b.c.dataBalanceNodes()
+	var planned = make(map[uint64]bool)
+	var nodeMap = make(map[string]*dataBalanceNode)
+	for _, node := range nodes {
+		nodeMap[node.addr] = node
+	}
+	// take the migrations in progress as finished, so that they are not planned again
+	for _, task := range migrating {
+		planned[task.PartitionID] = true
+		if source, ok := nodeMap[task.Source]; ok {
+			source.move(task.PartitionID, task.Size)
+		}
+		if target, ok := nodeMap[task.Target]; ok {
+			target.used += task.Size
+		}
+	}
+	var tasks = planDataBalance(nodes, config.Threshold, slots, planned, b.c.isDataBalanceMovable)
+
+	for _, task := range tasks {
+		b.Lock()
+		if !b.running || (config.MaxBandwidth > 0 && b.budget <= 0) {
+			b.Unlock()
+			break
+		}
+		if config.MaxBandwidth > 0 {
+			b.budget -= float64(task.Size)
+		}
+		task.Status = proto.DataBalanceTaskPending
+		task.StartTime = time.Now().Unix()
+		b.tasks[task.PartitionID] = task
+		b.Unlock()
+		log.LogWarnf("action[checkDataBalance] migrate vol[%v] data partition[%v] size[%v] from[%v] to[%v]",
+			task.VolName, task.PartitionID, task.Size, task.Source, task.Target)
+		go b.migrate(task)
+	}
+}
+
+func (b *dataBalancer) setTaskStatus(task *proto.DataBalanceTask, status string) {
+	b.Lock()
+	defer b.Unlock()
+	task.Status = status
+}
+
+// migrate adds the replica on the target, waits for it to be repaired, and then removes the
+// replica on the source. If the new replica is not repaired in time, it is removed instead.
+func (b *dataBalancer) migrate(task *proto.DataBalanceTask) {
+	var (
+		dp  *DataPartition
+		err error
+	)
+	defer func() {
+		b.finish(task, err)
+	}()
+	var c = b.c
+	if dp, err = c.getDataPartitionByID(task.PartitionID); err != nil {
+		return
+	}
+	b.setTaskStatus(task, proto.DataBalanceTaskAdding)
+	if err = c.addDataReplica(dp, task.Target); err != nil {
+		return
+	}
+	b.setTaskStatus(task, proto.DataBalanceTaskRepairing)
+	var since = time.Now()
+	for !dp.isReplicaRepaired(task.Target, since) {
+		if c.partition == nil || !c.partition.IsRaftLeader() {
+			err = fmt.Errorf("leader changed while the replica on [%v] is repairing", task.Target)
+			return
+		}
+		if time.Since(since) > time.Second*defaultDataBalanceTaskTimeOutSec {
+			err = fmt.Errorf("replica on [%v] is not repaired in %v seconds", task.Target, defaultDataBalanceTaskTimeOutSec)
+			if removeErr := c.removeDataReplica(dp, task.Target, false); removeErr != nil {
+				err = fmt.Errorf("%v, and remove it failed: %v", err, removeErr)
+			}
+			return
+		}
+		time.Sleep(time.Second * defaultIntervalToCheckDataBalanceRepair)
+	}
+	b.setTaskStatus(task, proto.DataBalanceTaskRemoving)
+	err = c.removeDataReplica(dp, task.Source, false)
+}
+
+func (b *dataBalancer) finish(task *proto.DataBalanceTask, err error) {
+	b.Lock()
+	defer b.Unlock()
+	delete(b.tasks, task.PartitionID)
+	task.EndTime = time.Now().Unix()
+	if err != nil {
+		task.Status = proto.DataBalanceTaskFailed
+		task.Msg = err.Error()
+		b.failed++
+		Warn(b.c.Name, fmt.Sprintf("clusterID[%v] migrate vol[%v] data partition[%v] from[%v] to[%v] failed, err[%v]",
+			b.c.Name, task.VolName, task.PartitionID, task.Source, task.Target, err))
+	} else {
+		task.Status = proto.DataBalanceTaskFinished
+		b.finished++
+		b.movedBytes += task.Size
+		log.LogWarnf("action[finishDataBalance] migrate vol[%v] data partition[%v] from[%v] to[%v] successfully",
+			task.VolName, task.PartitionID, task.Source, task.Target)
+	}
+	b.history = append(b.history, task)
+	if len(b.history) > defaultDataBalanceHistoryCount {
+		b.history = b.history[len(b.history)-defaultDataBalanceHistoryCount:]
+	}
+}
+
+// isReplicaRepaired returns true if the replica on the given address has been reported after the
+// given time, and its used space has caught up with the other replicas.
+func (partition *DataPartition) isReplicaRepaired(addr string, since time.Time) bool {
+	partition.RLock()
+	defer partition.RUnlock()
+	replica, ok := partition.hasReplica(addr)
+	if !ok || replica.ReportTime <= since.Unix() || !replica.isLive(defaultDataPartitionTimeOutSec) {
+		return false
+	}
+	var maxUsed uint64
+	for _, r := range partition.Replicas {
+		if r.Addr != addr && r.Used > maxUsed {
+			maxUsed = r.Used
+		}
+	}
+	if maxUsed > 0 && replica.Used == 0 {
+		return false
+	}
+	return replica.Used+defaultDataBalanceRepairDiff >= maxUsed
+}
+
+// isDataBalanceMovable returns true if the replica of the data partition on the source can be
+// migrated to the target.
+func (c *Cluster) isDataBalanceMovable(report *proto.PartitionReport, source, target *dataBalanceNode) bool {
+	dp, err := c.getDataPartitionByID(report.PartitionID)
+	if err != nil {
+		return false
+	}
+	vol, err := c.getVol(dp.VolName)
+	if err != nil {
+		return false
+	}
+	if c.isFaultDomain(vol) && source.nodeSetID != target.nodeSetID {
+		return false
+	}
+	targetNode, err := c.dataNode(target.addr)
+	if err != nil || !targetNode.isWriteAbleWithSize(vol.dataPartitionSize) {
+		return false
+	}
+	dp.RLock()
+	defer dp.RUnlock()
+	if dp.isErasureCoded() || dp.isRecover || !dp.hasHost(source.addr) || dp.hasHost(target.addr) {
+		return false
+	}
+	// all the replicas have to be alive, since one of them is removed after the migration
+	if len(dp.Hosts) != int(vol.dpReplicaNum) || len(dp.liveReplicas(defaultDataPartitionTimeOutSec)) != len(dp.Hosts) {
+		return false
+	}
+	return true
+}
+
+// dataBalanceNode is the snapshot of the space usage of a data node for the planning.
+type dataBalanceNode struct {
+	addr      string
+	zoneName  string
+	nodeSetID uint64
+	total     uint64
+	used      uint64
+	disks     map[string]*proto.DataBalanceDiskUsage
+	reports   []*proto.PartitionReport
+}
+
+func (node *dataBalanceNode) usageRatio() float64 {
+	if node.total == 0 {
+		return 0
+	}
+	return float64(node.used) / float64(node.total)
+}
+
+func diskUsageRatio(disk *proto.DataBalanceDiskUsage) float64 {
+	if disk == nil || disk.Total == 0 {
+		return 0
+	}
+	return float64(disk.Used) / float64(disk.Total)
+}
+
+// move removes the data partition from the snapshot of the node.
+func (node *dataBalanceNode) move(partitionID, size uint64) {
+	if node.used > size {
+		node.used -= size
+	} else {
+		node.used = 0
+	}
+	for i, r := range node.reports {
+		if r.PartitionID != partitionID {
+			continue
+		}
+		if disk, ok := node.disks[r.DiskPath]; ok && disk.Used > size {
+			disk.Used -= size
+		}
+		node.reports = append(node.reports[:i:i], node.reports[i+1:]...)
+		break
+	}
+}
+
+// pick picks the data partition to migrate to the target. The partitions on the disks with higher
+// usage are picked first, so that the usage of the disks is balanced as well, and the partition
+// should not make the usage of the target higher than the source after the migration.
+func (node *dataBalanceNode) pick(target *dataBalanceNode, planned map[uint64]bool,
+	movable func(report *proto.PartitionReport, source, target *dataBalanceNode) bool) *proto.PartitionReport {
+	var reports = make([]*proto.PartitionReport, len(node.reports))
+	copy(reports, node.reports)
+	sort.SliceStable(reports, func(i, j int) bool {
+		var ri, rj = diskUsageRatio(node.disks[reports[i].DiskPath]), diskUsageRatio(node.disks[reports[j].DiskPath])
+		if ri != rj {
+			return ri > rj
+		}
+		return reports[i].Used > reports[j].Used
+	})
+	for _, report := range reports {
+		if planned[report.PartitionID] || report.Used == 0 || report.Used > node.used {
+			continue
+		}
+		var sourceRatio = float64(node.used-report.Used) / float64(node.total)
+		var targetRatio = float64(target.used+report.Used) / float64(target.total)
+		if targetRatio > sourceRatio {
+			continue
+		}
+		if movable != nil && !movable(report, node, target) {
+			continue
+		}
+		return report
+	}
+	return nil
+}
+
+func (c *Cluster) dataBalanceNodes() (nodes []*dataBalanceNode) {
+	nodes = make([]*dataBalanceNode, 0)
+	c.dataNodes.Range(func(addr, value interface{}) bool {
+		dataNode := value.(*DataNode)
+		dataNode.RLock()
+		defer dataNode.RUnlock()
+		if !dataNode.isActive || dataNode.ToBeOffline || dataNode.inMaintenance() || dataNode.Total == 0 {
+			return true
+		}
+		node := &dataBalanceNode{
+			addr:      dataNode.Addr,
+			zoneName:  dataNode.ZoneName,
+			nodeSetID: dataNode.NodeSetID,
+			total:     dataNode.Total,
+			used:      dataNode.Used,
+			disks:     make(map[string]*proto.DataBalanceDiskUsage),
+			reports:   make([]*proto.PartitionReport, len(dataNode.DataPartitionReports)),
+		}
+		copy(node.reports, dataNode.DataPartitionReports)
+		for _, disk := range dataNode.DiskReports {
+			node.disks[disk.Path] = &proto.DataBalanceDiskUsage{Path: disk.Path, Total: disk.Total, Used: disk.Used}
+		}
+		nodes = append(nodes, node)
+		return true
+	})
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].addr < nodes[j].addr
+	})
+	return
+}
+
+// planDataBalance plans at most maxTasks migrations of the data partitions. In each zone, the
+// partitions on the node with the highest usage are migrated to the ones with the lowest usage,
+// until the difference of the usage ratios is not greater than the threshold. The partitions
+// in planned are skipped, and the planned ones are added to it.
+func planDataBalance(nodes []*dataBalanceNode, threshold float64, maxTasks int, planned map[uint64]bool,
+	movable func(report *proto.PartitionReport, source, target *dataBalanceNode) bool) (tasks []*proto.DataBalanceTask) {
+	var zones = make(map[string][]*dataBalanceNode)
+	var zoneNames = make([]string, 0)
+	for _, node := range nodes {
+		if _, ok := zones[node.zoneName]; !ok {
+			zoneNames = append(zoneNames, node.zoneName)
+		}
+		zones[node.zoneName] = append(zones[node.zoneName], node)
+	}
+	sort.Strings(zoneNames)
+
+	tasks = make([]*proto.DataBalanceTask, 0)
+	for _, zoneName := range zoneNames {
+		var zoneNodes = zones[zoneName]
+		// the sources from which no partition can be migrated
+		var exhausted = make(map[string]bool)
+		for len(tasks) < maxTasks {
+			sort.SliceStable(zoneNodes, func(i, j int) bool {
+				return zoneNodes[i].usageRatio() > zoneNodes[j].usageRatio()
+			})
+			var source *dataBalanceNode
+			for _, node := range zoneNodes {
+				if !exhausted[node.addr] {
+					source = node
+					break
+				}
+			}
+			if source == nil {
+				break
+			}
+			var target *dataBalanceNode
+			var report *proto.PartitionReport
+			for i := len(zoneNodes) - 1; i >= 0 && report == nil; i-- {
+				target = zoneNodes[i]
+				if source.usageRatio()-target.usageRatio() <= threshold {
+					break
+				}
+				report = source.pick(target, planned, movable)
+			}
+			if report == nil {
+				exhausted[source.addr] = true
+				continue
+			}
+			planned[report.PartitionID] = true
+			source.move(report.PartitionID, report.Used)
+			target.used += report.Used
+			tasks = append(tasks, &proto.DataBalanceTask{
+				PartitionID: report.PartitionID,
+				VolName:     report.VolName,
+				Source:      source.addr,
+				Target:      target.addr,
+				Size:        report.Used,
+			})
+		}
+	}
+	return
+}
+
+// dataBalanceZoneUsages returns the space usage of the data nodes and disks by zone.
+func dataBalanceZoneUsages(nodes []*dataBalanceNode) (zones []*proto.DataBalanceZoneUsage) {
+	var zoneMap = make(map[string]*proto.DataBalanceZoneUsage)
+	var totals = make(map[string][2]uint64)
+	zones = make([]*proto.DataBalanceZoneUsage, 0)
+	for _, node := range nodes {
+		zone, ok := zoneMap[node.zoneName]
+		if !ok {
+			zone = &proto.DataBalanceZoneUsage{ZoneName: node.zoneName}
+			zoneMap[node.zoneName] = zone
+			zones = append(zones, zone)
+		}
+		var nodeUsage = &proto.DataBalanceNodeUsage{
+			Addr:       node.addr,
+			Total:      node.total,
+			Used:       node.used,
+			UsageRatio: node.usageRatio(),
+			Disks:      make([]*proto.DataBalanceDiskUsage, 0, len(node.disks)),
+		}
+		for _, disk := range node.disks {
+			var diskUsage = *disk
+			diskUsage.UsageRatio = diskUsageRatio(disk)
+			nodeUsage.Disks = append(nodeUsage.Disks, &diskUsage)
+		}
+		sort.Slice(nodeUsage.Disks, func(i, j int) bool {
+			return nodeUsage.Disks[i].Path < nodeUsage.Disks[j].Path
+		})
+		nodeUsage.DiskSkew = dataBalanceSkew(len(nodeUsage.Disks), func(i int) float64 {
+			return nodeUsage.Disks[i].UsageRatio
+		})
+		zone.Nodes = append(zone.Nodes, nodeUsage)
+		var total = totals[node.zoneName]
+		totals[node.zoneName] = [2]uint64{total[0] + node.total, total[1] + node.used}
+	}
+	sort.Slice(zones, func(i, j int) bool {
+		return zones[i].ZoneName < zones[j].ZoneName
+	})
+	for _, zone := range zones {
+		if total := totals[zone.ZoneName]; total[0] > 0 {
+			zone.UsageRatio = float64(total[1]) / float64(total[0])
+		}
+		zone.Skew = dataBalanceSkew(len(zone.Nodes), func(i int) float64 {
+			return zone.Nodes[i].UsageRatio
+		})
+	}
+	return
+}
+
+// dataBalanceSkew returns the difference between the max and min of the n usage ratios.
+func dataBalanceSkew(n int, ratio func(i int) float64) float64 {
+	if n == 0 {
+		return 0
+	}
+	var min, max = ratio(0), ratio(0)
+	for i := 1; i < n; i++ {
+		if r := ratio(i); r < min {
+			min = r
+		} else if r > max {
+			max = r
+		}
+	}
+	return max - min
+}
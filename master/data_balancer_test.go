@@ -0,0 +1,86 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package master
+
+import (
+	"math"
+	"testing"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+func newTestDataBalanceNodes() []*dataBalanceNode {
+	return []*dataBalanceNode{
+		{
+			addr: "a", zoneName: "z1", total: 100, used: 80,
+			disks: map[string]*proto.DataBalanceDiskUsage{
+				"/d1": {Path: "/d1", Total: 50, Used: 45},
+				"/d2": {Path: "/d2", Total: 50, Used: 35},
+			},
+			reports: []*proto.PartitionReport{
+				{PartitionID: 1, VolName: "vol", DiskPath: "/d1", Used: 10},
+				{PartitionID: 2, VolName: "vol", DiskPath: "/d2", Used: 20},
+				{PartitionID: 3, VolName: "vol", DiskPath: "/d1", Used: 5},
+			},
+		},
+		{addr: "b", zoneName: "z1", total: 100, used: 20},
+		{addr: "c", zoneName: "z1", total: 100, used: 50},
+		{
+			addr: "d", zoneName: "z2", total: 100, used: 90,
+			reports: []*proto.PartitionReport{{PartitionID: 4, VolName: "vol", Used: 10}},
+		},
+	}
+}
+
+func TestPlanDataBalance(t *testing.T) {
+	tasks := planDataBalance(newTestDataBalanceNodes(), 0.1, 10, make(map[uint64]bool), nil)
+	if len(tasks) != 2 {
+		t.Fatalf("planned tasks: expect(2) actual(%v)", len(tasks))
+	}
+	// the partition on the disk with the highest usage is migrated first
+	if task := tasks[0]; task.PartitionID != 1 || task.Source != "a" || task.Target != "b" || task.Size != 10 {
+		t.Fatalf("first task: %v", task)
+	}
+	if task := tasks[1]; task.PartitionID != 2 || task.Source != "a" || task.Target != "b" {
+		t.Fatalf("second task: %v", task)
+	}
+
+	// the planned and unmovable partitions are skipped
+	tasks = planDataBalance(newTestDataBalanceNodes(), 0.1, 1, map[uint64]bool{1: true},
+		func(report *proto.PartitionReport, source, target *dataBalanceNode) bool {
+			return report.PartitionID != 2
+		})
+	if len(tasks) != 1 || tasks[0].PartitionID != 3 {
+		t.Fatalf("planned tasks: %v", tasks)
+	}
+
+	// nothing to migrate if the skew is not greater than the threshold
+	if tasks = planDataBalance(newTestDataBalanceNodes(), 0.7, 10, make(map[uint64]bool), nil); len(tasks) != 0 {
+		t.Fatalf("planned tasks: %v", tasks)
+	}
+}
+
+func TestDataBalanceZoneUsages(t *testing.T) {
+	zones := dataBalanceZoneUsages(newTestDataBalanceNodes())
+	if len(zones) != 2 || zones[0].ZoneName != "z1" || zones[1].ZoneName != "z2" {
+		t.Fatalf("zones: %v", zones)
+	}
+	if zone := zones[0]; zone.UsageRatio != 0.5 || math.Abs(zone.Skew-0.6) > 1e-9 || len(zone.Nodes) != 3 {
+		t.Fatalf("zone usage: ratio(%v) skew(%v) nodes(%v)", zone.UsageRatio, zone.Skew, len(zone.Nodes))
+	}
+	if node := zones[0].Nodes[0]; node.Addr != "a" || len(node.Disks) != 2 || node.Disks[0].UsageRatio != 0.9 {
+		t.Fatalf("node usage: %v", node)
+	}
+}
@@ -44,7 +44,11 @@ type DataNode struct {
 	NodeSetID                 uint64
 	PersistenceDataPartitions []uint64
 	BadDisks                  []string
+	DiskReports               []*proto.DiskReport
 	ToBeOffline               bool
+	Version                   string // version reported by the data node
+	Upgrading                 bool   // no data partition is placed on the node while it is being upgraded
+	MaintenanceUntil          int64  // unix time when the maintenance of the node ends
 }
 
 func newDataNode(addr, zoneName, clusterID string) (dataNode *DataNode) {
@@ -92,6 +96,8 @@ func (dataNode *DataNode) updateNodeMetric(resp *proto.DataNodeHeartbeatResponse
 	dataNode.DataPartitionCount = resp.CreatedPartitionCnt
 	dataNode.DataPartitionReports = resp.PartitionReports
 	dataNode.BadDisks = resp.BadDisks
+	dataNode.DiskReports = resp.DiskReports
+	dataNode.Version = resp.Version
 	if dataNode.Total == 0 {
 		dataNode.UsageRatio = 0.0
 	} else {
@@ -101,11 +107,23 @@ func (dataNode *DataNode) updateNodeMetric(resp *proto.DataNodeHeartbeatResponse
 	dataNode.isActive = true
 }
 
+// inMaintenance returns true if the maintenance window of the node has not ended.
+// The caller should hold the lock of the node.
+func (dataNode *DataNode) inMaintenance() bool {
+	return dataNode.MaintenanceUntil > time.Now().Unix()
+}
+
+func (dataNode *DataNode) isInMaintenance() bool {
+	dataNode.RLock()
+	defer dataNode.RUnlock()
+	return dataNode.inMaintenance()
+}
+
 func (dataNode *DataNode) isWriteAble() (ok bool) {
 	dataNode.RLock()
 	defer dataNode.RUnlock()
 
-	if dataNode.isActive == true && dataNode.AvailableSpace > 10*util.GB {
+	if dataNode.isActive == true && !dataNode.Upgrading && !dataNode.inMaintenance() && dataNode.AvailableSpace > 10*util.GB {
 		ok = true
 	}
 
@@ -116,7 +134,7 @@ func (dataNode *DataNode) isWriteAbleWithSize(size uint64) (ok bool) {
 	dataNode.RLock()
 	defer dataNode.RUnlock()
 
-	if dataNode.isActive == true && dataNode.AvailableSpace > size {
+	if dataNode.isActive == true && !dataNode.Upgrading && !dataNode.inMaintenance() && dataNode.AvailableSpace > size {
 		ok = true
 	}
 
@@ -162,10 +180,13 @@ func (dataNode *DataNode) clean() {
 	dataNode.TaskManager.exitCh <- struct{}{}
 }
 
-func (dataNode *DataNode) createHeartbeatTask(masterAddr string) (task *proto.AdminTask) {
+func (dataNode *DataNode) createHeartbeatTask(masterAddr string, snapshotVols []string,
+	snapshotVolsVer int64) (task *proto.AdminTask) {
 	request := &proto.HeartBeatRequest{
-		CurrTime:   time.Now().Unix(),
-		MasterAddr: masterAddr,
+		CurrTime:        time.Now().Unix(),
+		MasterAddr:      masterAddr,
+		SnapshotVols:    snapshotVols,
+		SnapshotVolsVer: snapshotVolsVer,
 	}
 	task = proto.NewAdminTask(proto.OpDataNodeHeartbeat, dataNode.Addr, request)
 	return
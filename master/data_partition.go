@@ -50,6 +50,8 @@ type DataPartition struct {
 	OfflinePeerID           uint64
 	FileInCoreMap           map[string]*FileInCore
 	FilesWithMissingReplica map[string]int64 // key: file name, value: last time when a missing replica is found
+	ECDataNum               uint8            // number of data shards if the partition is erasure-coded
+	ECParityNum             uint8
 }
 
 func newDataPartition(ID uint64, replicaNum uint8, volName string, volID uint64) (partition *DataPartition) {
@@ -72,6 +74,12 @@ func newDataPartition(ID uint64, replicaNum uint8, volName string, volID uint64)
 	return
 }
 
+// isErasureCoded returns true if the partition is erasure-coded, the order of
+// the hosts of which is the order of the shards and must not be changed.
+func (partition *DataPartition) isErasureCoded() bool {
+	return partition.ECDataNum > 0
+}
+
 func (partition *DataPartition) resetFilesWithMissingReplica() {
 	partition.Lock()
 	defer partition.Unlock()
@@ -146,8 +154,9 @@ func (partition *DataPartition) createTaskToRemoveRaftMember(removePeer proto.Pe
 
 func (partition *DataPartition) createTaskToCreateDataPartition(addr string, dataPartitionSize uint64, peers []proto.Peer, hosts []string, createType int) (task *proto.AdminTask) {
 
-	task = proto.NewAdminTask(proto.OpCreateDataPartition, addr, newCreateDataPartitionRequest(
-		partition.VolName, partition.PartitionID, peers, int(dataPartitionSize), hosts, createType))
+	req := newCreateDataPartitionRequest(partition.VolName, partition.PartitionID, peers, int(dataPartitionSize), hosts, createType)
+	req.ECDataNum, req.ECParityNum = partition.ECDataNum, partition.ECParityNum
+	task = proto.NewAdminTask(proto.OpCreateDataPartition, addr, req)
 	partition.resetTaskID(task)
 	return
 }
@@ -196,6 +205,26 @@ func (partition *DataPartition) canBeOffLine(offlineAddr string) (err error) {
 	return
 }
 
+// canECShardBeRebuilt checks if the shard on the offline host of an erasure-coded
+// partition can be reconstructed from the shards on the other live hosts.
+func (partition *DataPartition) canECShardBeRebuilt(offlineAddr string) (err error) {
+	if !partition.isErasureCoded() {
+		return
+	}
+	otherLiveReplicas := 0
+	for _, replica := range partition.liveReplicas(defaultDataPartitionTimeOutSec) {
+		if replica.Addr != offlineAddr {
+			otherLiveReplicas++
+		}
+	}
+	if otherLiveReplicas < int(partition.ECDataNum) {
+		err = fmt.Errorf("action[canECShardBeRebuilt],partitionID:%v,hosts:%v,offline:%v err:%v liveShards:%v dataShards:%v",
+			partition.PartitionID, partition.Hosts, offlineAddr, proto.ErrCannotBeOffLine, otherLiveReplicas, partition.ECDataNum)
+		log.LogError(err.Error())
+	}
+	return
+}
+
 // get all the valid replicas of the given data partition
 func (partition *DataPartition) availableDataReplicas() (replicas []*DataReplica) {
 	replicas = make([]*DataReplica, 0)
@@ -292,6 +321,8 @@ func (partition *DataPartition) convertToDataPartitionResponse() (dpr *proto.Dat
 	copy(dpr.Hosts, partition.Hosts)
 	dpr.LeaderAddr = partition.getLeaderAddr()
 	dpr.IsRecover = partition.isRecover
+	dpr.ECDataNum = partition.ECDataNum
+	dpr.ECParityNum = partition.ECParityNum
 	return
 }
 
@@ -95,10 +95,15 @@ func (partition *DataPartition) checkLeader(timeOut int64) {
 }
 
 // Check if there is any missing replica for a data partition.
-func (partition *DataPartition) checkMissingReplicas(clusterID, leaderAddr string, dataPartitionMissSec, dataPartitionWarnInterval int64) {
+// The replicas on the data nodes in maintenance are not alarmed.
+func (partition *DataPartition) checkMissingReplicas(c *Cluster, dataPartitionMissSec, dataPartitionWarnInterval int64) {
+	clusterID, leaderAddr := c.Name, c.leaderInfo.addr
 	partition.Lock()
 	defer partition.Unlock()
 	for _, replica := range partition.Replicas {
+		if c.isDataNodeInMaintenance(replica.Addr) {
+			continue
+		}
 		if partition.hasHost(replica.Addr) && replica.isMissing(dataPartitionMissSec) == true && partition.needToAlarmMissingDataPartition(replica.Addr, dataPartitionWarnInterval) {
 			dataNode := replica.getReplicaNode()
 			var (
@@ -118,6 +123,9 @@ func (partition *DataPartition) checkMissingReplicas(clusterID, leaderAddr strin
 	}
 
 	for _, addr := range partition.Hosts {
+		if c.isDataNodeInMaintenance(addr) {
+			continue
+		}
 		if partition.hasMissingDataPartition(addr) == true && partition.needToAlarmMissingDataPartition(addr, dataPartitionWarnInterval) {
 			msg := fmt.Sprintf("action[checkMissErr],clusterID[%v] partitionID:%v  on Node:%v  "+
 				"miss time  > :%v  but server not exsit So Migrate", clusterID, partition.PartitionID, addr, dataPartitionMissSec)
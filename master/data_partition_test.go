@@ -89,3 +89,50 @@ func loadDataPartitionTest(dp *DataPartition, t *testing.T) {
 	dp.validateCRC(server.cluster.Name)
 	dp.setToNormal()
 }
+
+func TestDecommissionECDataPartition(t *testing.T) {
+	vol, err := server.cluster.createVol("ecVol", "cfs", testZone2, "", 3, 3, 3, 100, false, false, false, false, 2, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.cluster.markDeleteVol(vol.Name, buildAuthKey("cfs"))
+	addDataServer("127.0.0.1:9107", testZone2)
+	server.cluster.checkDataNodeHeartbeat()
+	time.Sleep(5 * time.Second)
+	dp := vol.dataPartitions.partitions[0]
+	oldHosts := make([]string, len(dp.Hosts))
+	copy(oldHosts, dp.Hosts)
+	if err = server.cluster.decommissionDataPartition(oldHosts[1], dp, ""); err != nil {
+		t.Fatal(err)
+	}
+	// the new host takes the place of the shard
+	dp.RLock()
+	defer dp.RUnlock()
+	newAddr := dp.Hosts[1]
+	if len(dp.Hosts) != 3 || contains(oldHosts, newAddr) || dp.Hosts[0] != oldHosts[0] || dp.Hosts[2] != oldHosts[2] {
+		t.Fatalf("hosts[%v] after replacing shard host[%v]", dp.Hosts, oldHosts[1])
+	}
+	// the peers are not kept in the order of the shards
+	var peerAddrs = make([]string, 0, len(dp.Peers))
+	for _, peer := range dp.Peers {
+		peerAddrs = append(peerAddrs, peer.Addr)
+	}
+	if len(peerAddrs) != 3 || !contains(peerAddrs, newAddr) || contains(peerAddrs, oldHosts[1]) {
+		t.Fatalf("peers[%v] after replacing shard host[%v] with[%v]", dp.Peers, oldHosts[1], newAddr)
+	}
+	if dp.Status != proto.ReadOnly || !dp.isRecover {
+		t.Fatalf("status[%v] isRecover[%v] after decommission", dp.Status, dp.isRecover)
+	}
+	// the shard can't be rebuilt from the live shards less than the data shards
+	if err = dp.canECShardBeRebuilt(newAddr); err != nil {
+		t.Fatal(err)
+	}
+	for _, replica := range dp.Replicas {
+		if replica.Addr == oldHosts[0] {
+			replica.ReportTime = 0
+		}
+	}
+	if err = dp.canECShardBeRebuilt(newAddr); err == nil {
+		t.Fatalf("shard on [%v] is rebuilt from the live shards less than the data shards", newAddr)
+	}
+}
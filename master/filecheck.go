@@ -27,6 +27,10 @@ import (
 func (partition *DataPartition) validateCRC(clusterID string) {
 	partition.Lock()
 	defer partition.Unlock()
+	// the hosts of an erasure-coded partition hold the different shards
+	if partition.isErasureCoded() {
+		return
+	}
 	liveReplicas := partition.liveReplicas(defaultDataPartitionTimeOutSec)
 	if len(liveReplicas) == 0 {
 		return
@@ -191,7 +191,7 @@ func (s *VolumeService) createVolume(ctx context.Context, args struct {
 
 	vol, err := s.cluster.createVol(args.Name, args.Owner, args.ZoneName, args.Description, int(args.MpCount),
 						int(args.DpReplicaNum), int(args.DataPartitionSize), int(args.Capacity),
-						args.FollowerRead, args.Authenticate, args.CrossZone, args.DefaultPriority)
+						args.FollowerRead, args.Authenticate, args.CrossZone, args.DefaultPriority, 0, 0)
 	if err != nil {
 		return nil, err
 	}
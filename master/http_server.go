@@ -109,6 +109,36 @@ func (m *Server) registerAPIRoutes(router *mux.Router) {
 		Path(proto.RemoveRaftNode).
 		HandlerFunc(m.removeRaftNode)
 	router.NewRoute().Methods(http.MethodGet).Path(proto.AdminClusterStat).HandlerFunc(m.clusterStat)
+	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
+		Path(proto.AdminDataBalanceStart).
+		HandlerFunc(m.startDataBalance)
+	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
+		Path(proto.AdminDataBalanceStop).
+		HandlerFunc(m.stopDataBalance)
+	router.NewRoute().Methods(http.MethodGet).
+		Path(proto.AdminDataBalanceStatus).
+		HandlerFunc(m.getDataBalanceStatus)
+	router.NewRoute().Methods(http.MethodGet).
+		Path(proto.AdminMetaBalancePlan).
+		HandlerFunc(m.planMetaBalance)
+	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
+		Path(proto.AdminMetaBalanceStart).
+		HandlerFunc(m.startMetaBalance)
+	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
+		Path(proto.AdminMetaBalanceStop).
+		HandlerFunc(m.stopMetaBalance)
+	router.NewRoute().Methods(http.MethodGet).
+		Path(proto.AdminMetaBalanceStatus).
+		HandlerFunc(m.getMetaBalanceStatus)
+	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
+		Path(proto.AdminUpgradeStart).
+		HandlerFunc(m.startUpgrade)
+	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
+		Path(proto.AdminUpgradeStop).
+		HandlerFunc(m.stopUpgrade)
+	router.NewRoute().Methods(http.MethodGet).
+		Path(proto.AdminUpgradeStatus).
+		HandlerFunc(m.getUpgradeStatus)
 
 	// volume management APIs
 	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
@@ -129,6 +159,27 @@ func (m *Server) registerAPIRoutes(router *mux.Router) {
 	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
 		Path(proto.AdminVolExpand).
 		HandlerFunc(m.volExpand)
+	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
+		Path(proto.AdminCreateSnapshot).
+		HandlerFunc(m.createSnapshot)
+	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
+		Path(proto.AdminDeleteSnapshot).
+		HandlerFunc(m.deleteSnapshot)
+	router.NewRoute().Methods(http.MethodGet).
+		Path(proto.AdminListSnapshots).
+		HandlerFunc(m.listSnapshots)
+	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
+		Path(proto.AdminSetQuota).
+		HandlerFunc(m.setQuota)
+	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
+		Path(proto.AdminDeleteQuota).
+		HandlerFunc(m.deleteQuota)
+	router.NewRoute().Methods(http.MethodGet).
+		Path(proto.AdminListQuotas).
+		HandlerFunc(m.listQuotas)
+	router.NewRoute().Methods(http.MethodGet).
+		Path(proto.AdminGetQuota).
+		HandlerFunc(m.getQuota)
 	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
 		Path(proto.ClientVol).
 		HandlerFunc(m.getVol)
@@ -157,6 +208,15 @@ func (m *Server) registerAPIRoutes(router *mux.Router) {
 	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
 		Path(proto.AdminDecommissionMetaPartition).
 		HandlerFunc(m.decommissionMetaPartition)
+	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
+		Path(proto.AdminMergeMetaPartition).
+		HandlerFunc(m.mergeMetaPartition)
+	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
+		Path(proto.AdminResplitMetaPartition).
+		HandlerFunc(m.resplitMetaPartition)
+	router.NewRoute().Methods(http.MethodGet).
+		Path(proto.AdminMetaMoveStatus).
+		HandlerFunc(m.getMetaMoveStatus)
 	router.NewRoute().Methods(http.MethodGet).
 		Path(proto.ClientMetaPartitions).
 		HandlerFunc(m.getMetaPartitions)
@@ -206,6 +266,12 @@ func (m *Server) registerAPIRoutes(router *mux.Router) {
 	router.NewRoute().Methods(http.MethodGet).
 		Path(proto.GetMetaNode).
 		HandlerFunc(m.getMetaNode)
+	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
+		Path(proto.StartMetaNodeMaintenance).
+		HandlerFunc(m.startMetaNodeMaintenance)
+	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
+		Path(proto.StopMetaNodeMaintenance).
+		HandlerFunc(m.stopMetaNodeMaintenance)
 	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
 		Path(proto.AdminSetMetaNodeThreshold).
 		HandlerFunc(m.setMetaNodeThreshold)
@@ -235,6 +301,12 @@ func (m *Server) registerAPIRoutes(router *mux.Router) {
 	router.NewRoute().Methods(http.MethodGet).
 		Path(proto.GetDataNode).
 		HandlerFunc(m.getDataNode)
+	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
+		Path(proto.StartDataNodeMaintenance).
+		HandlerFunc(m.startDataNodeMaintenance)
+	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
+		Path(proto.StopDataNodeMaintenance).
+		HandlerFunc(m.stopDataNodeMaintenance)
 	router.NewRoute().Methods(http.MethodGet, http.MethodPost).
 		Path(proto.DecommissionDisk).
 		HandlerFunc(m.decommissionDisk)
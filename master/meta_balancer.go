@@ -0,0 +1,672 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package master
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// metaBalancer migrates the meta replicas from the meta nodes with higher memory usage to the ones
+// with lower usage in the same zone. Each replica is migrated by adding a replica on the target,
+// waiting for it to catch up with the leader, and then removing the replica on the source, so that
+// the number of the replicas never decreases.
+//
+// The requests are served by the leaders only, so the request rates are balanced by transferring
+// the leaders from the meta nodes with higher request rate to the followers on the ones with lower
+// rate instead, since a migrated replica does not take the leadership with it.
+//
+// Like the data balancer, the state of the balancer is kept in the memory of the leader master only.
+type metaBalancer struct {
+	sync.RWMutex
+	c         *Cluster
+	running   bool
+	config    proto.MetaBalanceConfig
+	startTime time.Time
+	tasks     map[uint64]*proto.MetaBalanceTask // migrating tasks by partition ID
+	history   []*proto.MetaBalanceTask
+	finished  uint64
+	failed    uint64
+}
+
+func newMetaBalancer(c *Cluster) *metaBalancer {
+	return &metaBalancer{c: c, tasks: make(map[uint64]*proto.MetaBalanceTask)}
+}
+
+func normalizeMetaBalanceConfig(config proto.MetaBalanceConfig) proto.MetaBalanceConfig {
+	if config.MemThreshold <= 0 {
+		config.MemThreshold = defaultMetaBalanceMemThreshold
+	}
+	if config.RateThreshold <= 0 {
+		config.RateThreshold = defaultMetaBalanceRateThreshold
+	}
+	if config.MaxConcurrency <= 0 {
+		config.MaxConcurrency = defaultMetaBalanceConcurrency
+	}
+	return config
+}
+
+func (b *metaBalancer) start(config proto.MetaBalanceConfig) (err error) {
+	b.Lock()
+	defer b.Unlock()
+	if b.running {
+		return proto.ErrMetaBalanceRunning
+	}
+	b.running = true
+	b.config = normalizeMetaBalanceConfig(config)
+	b.startTime = time.Now()
+	log.LogWarnf("action[startMetaBalance] memThreshold[%v] rateThreshold[%v] maxConcurrency[%v]",
+		b.config.MemThreshold, b.config.RateThreshold, b.config.MaxConcurrency)
+	return
+}
+
+// stop stops planning the migrations. The ones in progress are not interrupted.
+func (b *metaBalancer) stop() (err error) {
+	b.Lock()
+	defer b.Unlock()
+	if !b.running {
+		return proto.ErrMetaBalanceNotRunning
+	}
+	b.running = false
+	log.LogWarnf("action[stopMetaBalance] tasks in progress[%v]", len(b.tasks))
+	return
+}
+
+func (b *metaBalancer) status() (status *proto.MetaBalanceStatus) {
+	var nodes = b.c.metaBalanceNodes()
+	b.RLock()
+	defer b.RUnlock()
+	status = &proto.MetaBalanceStatus{
+		Running:       b.running,
+		Config:        b.config,
+		FinishedCount: b.finished,
+		FailedCount:   b.failed,
+		Tasks:         make([]*proto.MetaBalanceTask, 0, len(b.tasks)),
+		RecentTasks:   make([]*proto.MetaBalanceTask, 0, len(b.history)),
+		Zones:         metaBalanceZoneUsages(nodes),
+	}
+	if !b.startTime.IsZero() {
+		status.StartTime = b.startTime.Unix()
+	}
+	for _, task := range b.tasks {
+		var t = *task
+		status.Tasks = append(status.Tasks, &t)
+	}
+	sort.Slice(status.Tasks, func(i, j int) bool {
+		return status.Tasks[i].PartitionID < status.Tasks[j].PartitionID
+	})
+	for i := len(b.history) - 1; i >= 0; i-- {
+		var t = *b.history[i]
+		status.RecentTasks = append(status.RecentTasks, &t)
+	}
+	return
+}
+
+// plan returns the migrations which would be planned with the given config, without executing
+// them. The migrations in progress are taken as finished.
+func (b *metaBalancer) plan(config proto.MetaBalanceConfig, maxTasks int) (plan *proto.MetaBalancePlan) {
+	config = normalizeMetaBalanceConfig(config)
+	var nodes = b.c.metaBalanceNodes()
+	plan = &proto.MetaBalancePlan{Config: config, Zones: metaBalanceZoneUsages(nodes)}
+	plan.Tasks = planMetaBalance(nodes, config, maxTasks, b.migrating(nodes), b.c.isMetaBalanceMovable)
+	return
+}
+
+// migrating applies the migrations and the leader transfers in progress to the snapshot of the
+// nodes, and returns the IDs of the migrating partitions, so that they are not planned again.
+func (b *metaBalancer) migrating(nodes []*metaBalanceNode) (planned map[uint64]bool) {
+	planned = make(map[uint64]bool)
+	var nodeMap = make(map[string]*metaBalanceNode)
+	for _, node := range nodes {
+		nodeMap[node.addr] = node
+	}
+	b.RLock()
+	defer b.RUnlock()
+	for _, task := range b.tasks {
+		planned[task.PartitionID] = true
+		source, target := nodeMap[task.Source], nodeMap[task.Target]
+		if task.Reason == proto.MetaBalanceByRequestRate {
+			if source != nil && target != nil {
+				source.transfer(target, task.PartitionID, task.RequestRate)
+			}
+			continue
+		}
+		if source != nil {
+			source.move(task.PartitionID)
+		}
+		if target != nil {
+			target.used += task.Memory
+			target.leader[task.PartitionID] = false
+		}
+	}
+	return
+}
+
+func (c *Cluster) scheduleToBalanceMeta() {
+	go func() {
+		for {
+			if c.partition != nil && c.partition.IsRaftLeader() {
+				c.metaBalancer.check()
+			}
+			time.Sleep(time.Second * defaultIntervalToBalanceMeta)
+		}
+	}()
+}
+
+// check plans and starts the migrations for the free slots of the concurrency.
+func (b *metaBalancer) check() {
+	defer func() {
+		if r := recover(); r != nil {
+			log.LogWarnf("checkMetaBalance occurred panic,err[%v]", r)
+			WarnBySpecialKey(fmt.Sprintf("%v_%v_scheduling_job_panic", b.c.Name, ModuleName),
+				"checkMetaBalance occurred panic")
+		}
+	}()
+	b.RLock()
+	var running, config, slots = b.running, b.config, b.config.MaxConcurrency - len(b.tasks)
+	b.RUnlock()
+	if !running || slots <= 0 {
+		return
+	}
+	var nodes = b.c.metaBalanceNodes()
+	var tasks = planMetaBalance(nodes, config, slots, b.migrating(nodes), b.c.isMetaBalanceMovable)
+
+	for _, task := range tasks {
+		b.Lock()
+		if !b.running {
+			b.Unlock()
+			break
+		}
+		task.Status = proto.MetaBalanceTaskPending
+		task.StartTime = time.Now().Unix()
+		b.tasks[task.PartitionID] = task
+		b.Unlock()
+		log.LogWarnf("action[checkMetaBalance] migrate vol[%v] meta partition[%v] by[%v] from[%v] to[%v]",
+			task.VolName, task.PartitionID, task.Reason, task.Source, task.Target)
+		go b.migrate(task)
+	}
+}
+
+func (b *metaBalancer) setTaskStatus(task *proto.MetaBalanceTask, status string) {
+	b.Lock()
+	defer b.Unlock()
+	task.Status = status
+}
+
+// migrate adds the replica on the target, waits for it to catch up with the leader, and then
+// removes the replica on the source. If the new replica does not catch up in time, it is removed
+// instead. The tasks balancing the request rates transfer the leader instead.
+func (b *metaBalancer) migrate(task *proto.MetaBalanceTask) {
+	var (
+		mp  *MetaPartition
+		err error
+	)
+	defer func() {
+		b.finish(task, err)
+	}()
+	var c = b.c
+	if mp, err = c.getMetaPartitionByID(task.PartitionID); err != nil {
+		return
+	}
+	if task.Reason == proto.MetaBalanceByRequestRate {
+		err = b.transferLeader(mp, task)
+		return
+	}
+	b.setTaskStatus(task, proto.MetaBalanceTaskAdding)
+	if err = c.addMetaReplica(mp, task.Target); err != nil {
+		return
+	}
+	b.setTaskStatus(task, proto.MetaBalanceTaskCatchingUp)
+	var since = time.Now()
+	for !mp.isReplicaCaughtUp(task.Target, since) {
+		if c.partition == nil || !c.partition.IsRaftLeader() {
+			err = fmt.Errorf("leader changed while the replica on [%v] is catching up", task.Target)
+			return
+		}
+		if time.Since(since) > time.Second*defaultMetaBalanceTaskTimeOutSec {
+			err = fmt.Errorf("replica on [%v] does not catch up in %v seconds", task.Target, defaultMetaBalanceTaskTimeOutSec)
+			if removeErr := c.deleteMetaReplica(mp, task.Target, false); removeErr != nil {
+				err = fmt.Errorf("%v, and remove it failed: %v", err, removeErr)
+			}
+			return
+		}
+		time.Sleep(time.Second * defaultIntervalToCheckMetaBalanceCatchUp)
+	}
+	b.setTaskStatus(task, proto.MetaBalanceTaskRemoving)
+	err = c.deleteMetaReplica(mp, task.Source, false)
+}
+
+// transferLeader transfers the leader of the meta partition to the follower on the target, and
+// waits for the target to report the leadership.
+func (b *metaBalancer) transferLeader(mp *MetaPartition, task *proto.MetaBalanceTask) (err error) {
+	var c = b.c
+	var metaNode *MetaNode
+	if metaNode, err = c.metaNode(task.Target); err != nil {
+		return
+	}
+	b.setTaskStatus(task, proto.MetaBalanceTaskTransferring)
+	if err = mp.tryToChangeLeader(c, metaNode); err != nil {
+		return
+	}
+	var since = time.Now()
+	for !mp.isReplicaLeader(task.Target) {
+		if c.partition == nil || !c.partition.IsRaftLeader() {
+			return fmt.Errorf("leader changed while the leader is transferred to [%v]", task.Target)
+		}
+		if time.Since(since) > time.Second*defaultMetaBalanceLeaderTimeOutSec {
+			return fmt.Errorf("leader is not transferred to [%v] in %v seconds", task.Target, defaultMetaBalanceLeaderTimeOutSec)
+		}
+		time.Sleep(time.Second * defaultIntervalToCheckMetaBalanceCatchUp)
+	}
+	return
+}
+
+func (b *metaBalancer) finish(task *proto.MetaBalanceTask, err error) {
+	b.Lock()
+	defer b.Unlock()
+	delete(b.tasks, task.PartitionID)
+	task.EndTime = time.Now().Unix()
+	if err != nil {
+		task.Status = proto.MetaBalanceTaskFailed
+		task.Msg = err.Error()
+		b.failed++
+		Warn(b.c.Name, fmt.Sprintf("clusterID[%v] migrate vol[%v] meta partition[%v] from[%v] to[%v] failed, err[%v]",
+			b.c.Name, task.VolName, task.PartitionID, task.Source, task.Target, err))
+	} else {
+		task.Status = proto.MetaBalanceTaskFinished
+		b.finished++
+		log.LogWarnf("action[finishMetaBalance] migrate vol[%v] meta partition[%v] from[%v] to[%v] successfully",
+			task.VolName, task.PartitionID, task.Source, task.Target)
+	}
+	b.history = append(b.history, task)
+	if len(b.history) > defaultMetaBalanceHistoryCount {
+		b.history = b.history[len(b.history)-defaultMetaBalanceHistoryCount:]
+	}
+}
+
+// isReplicaCaughtUp returns true if the replica on the given address has been reported after the
+// given time, and its inodes and dentries have caught up with the leader.
+func (mp *MetaPartition) isReplicaCaughtUp(addr string, since time.Time) bool {
+	mp.RLock()
+	defer mp.RUnlock()
+	replica, err := mp.getMetaReplica(addr)
+	if err != nil || replica.ReportTime <= since.Unix() || !replica.isActive() {
+		return false
+	}
+	leader, err := mp.getMetaReplicaLeader()
+	if err != nil {
+		return false
+	}
+	return replica.MaxInodeID+defaultMetaBalanceCatchUpDiff >= leader.MaxInodeID &&
+		replica.InodeCount+defaultMetaBalanceCatchUpDiff >= leader.InodeCount &&
+		replica.DentryCount+defaultMetaBalanceCatchUpDiff >= leader.DentryCount
+}
+
+// isReplicaLeader returns true if the replica on the given address is reported as the leader.
+func (mp *MetaPartition) isReplicaLeader(addr string) bool {
+	mp.RLock()
+	defer mp.RUnlock()
+	leader, err := mp.getMetaReplicaLeader()
+	return err == nil && leader.Addr == addr
+}
+
+// isMetaBalanceMovable returns true if the replica of the meta partition on the source can be
+// migrated to the target, or the leader on the source can be transferred to the target for the
+// request rates.
+func (c *Cluster) isMetaBalanceMovable(report *proto.MetaPartitionReport, source, target *metaBalanceNode, reason string) bool {
+	mp, err := c.getMetaPartitionByID(report.PartitionID)
+	if err != nil {
+		return false
+	}
+	if reason == proto.MetaBalanceByRequestRate {
+		return mp.isLeaderTransferable(source.addr, target.addr)
+	}
+	vol, err := c.getVol(mp.volName)
+	if err != nil {
+		return false
+	}
+	if c.isFaultDomain(vol) && source.nodeSetID != target.nodeSetID {
+		return false
+	}
+	targetNode, err := c.metaNode(target.addr)
+	if err != nil || !targetNode.isWritable() {
+		return false
+	}
+	mp.RLock()
+	defer mp.RUnlock()
+	if mp.IsRecover || mp.OfflinePeerID != 0 || !contains(mp.Hosts, source.addr) || contains(mp.Hosts, target.addr) {
+		return false
+	}
+	// all the replicas have to be alive, since one of them is removed after the migration
+	if len(mp.Hosts) != int(vol.mpReplicaNum) || len(mp.getLiveReplicas()) != len(mp.Hosts) {
+		return false
+	}
+	return true
+}
+
+// isLeaderTransferable returns true if the leader on the source can be transferred to the live
+// follower on the target.
+func (mp *MetaPartition) isLeaderTransferable(source, target string) bool {
+	mp.RLock()
+	defer mp.RUnlock()
+	if mp.IsRecover || mp.OfflinePeerID != 0 || !contains(mp.Hosts, target) {
+		return false
+	}
+	leader, err := mp.getMetaReplicaLeader()
+	if err != nil || leader.Addr != source {
+		return false
+	}
+	replica, err := mp.getMetaReplica(target)
+	return err == nil && replica.isActive()
+}
+
+// metaBalanceNode is the snapshot of the memory usage and the request rate of a meta node for the
+// planning. The memory used by a replica is not reported, so it is estimated by the share of its
+// inodes and dentries in the node. The request rate is reported by the leaders only.
+type metaBalanceNode struct {
+	addr      string
+	zoneName  string
+	nodeSetID uint64
+	total     uint64
+	used      uint64
+	threshold float64 // max memory usage ratio of the node, 0 for unlimited
+	rate      float64
+	reports   []*proto.MetaPartitionReport
+	memory    map[uint64]uint64 // estimated memory used by the replicas by partition ID
+	leader    map[uint64]bool   // partitions of the replicas, true if the replica is the leader
+}
+
+func newMetaBalanceNode(addr, zoneName string, nodeSetID, total, used uint64,
+	reports []*proto.MetaPartitionReport) (node *metaBalanceNode) {
+	node = &metaBalanceNode{
+		addr:      addr,
+		zoneName:  zoneName,
+		nodeSetID: nodeSetID,
+		total:     total,
+		used:      used,
+		reports:   make([]*proto.MetaPartitionReport, len(reports)),
+		memory:    make(map[uint64]uint64, len(reports)),
+		leader:    make(map[uint64]bool, len(reports)),
+	}
+	copy(node.reports, reports)
+	var items uint64
+	for _, report := range reports {
+		items += report.InodeCnt + report.DentryCnt
+		node.rate += report.RequestRate
+		node.leader[report.PartitionID] = report.IsLeader
+	}
+	for _, report := range reports {
+		if items > 0 {
+			node.memory[report.PartitionID] = uint64(float64(used) * float64(report.InodeCnt+report.DentryCnt) / float64(items))
+		}
+	}
+	return
+}
+
+func (node *metaBalanceNode) usageRatio() float64 {
+	if node.total == 0 {
+		return 0
+	}
+	return float64(node.used) / float64(node.total)
+}
+
+// load returns the memory usage ratio or the request rate of the node.
+func (node *metaBalanceNode) load(reason string) float64 {
+	if reason == proto.MetaBalanceByRequestRate {
+		return node.rate
+	}
+	return node.usageRatio()
+}
+
+// move removes the meta partition from the snapshot of the node. The leadership of the removed
+// replica is taken by another existing replica, so its requests are not added to the target.
+func (node *metaBalanceNode) move(partitionID uint64) {
+	for i, r := range node.reports {
+		if r.PartitionID != partitionID {
+			continue
+		}
+		if memory := node.memory[partitionID]; node.used > memory {
+			node.used -= memory
+		} else {
+			node.used = 0
+		}
+		if node.rate > r.RequestRate {
+			node.rate -= r.RequestRate
+		} else {
+			node.rate = 0
+		}
+		node.reports = append(node.reports[:i:i], node.reports[i+1:]...)
+		delete(node.leader, partitionID)
+		break
+	}
+}
+
+// transfer transfers the leader of the meta partition with the requests on the node to the target.
+func (node *metaBalanceNode) transfer(target *metaBalanceNode, partitionID uint64, rate float64) {
+	node.leader[partitionID] = false
+	target.leader[partitionID] = true
+	if node.rate > rate {
+		node.rate -= rate
+	} else {
+		node.rate = 0
+	}
+	target.rate += rate
+}
+
+// pick picks the meta partition to migrate to the target for the reason. The partitions with the
+// higher memory usage or request rate are picked first. The partition should neither make the
+// load of the target higher than the source, nor make the memory usage of the target exceed its
+// threshold or higher than the source by more than memThreshold. For the request rate, only the
+// leaders on the node with the followers on the target are picked, the leaders of which are
+// transferred to the target.
+func (node *metaBalanceNode) pick(target *metaBalanceNode, reason string, memThreshold float64, planned map[uint64]bool,
+	movable func(report *proto.MetaPartitionReport, source, target *metaBalanceNode, reason string) bool) *proto.MetaPartitionReport {
+	var reports = make([]*proto.MetaPartitionReport, len(node.reports))
+	copy(reports, node.reports)
+	sort.SliceStable(reports, func(i, j int) bool {
+		if reason == proto.MetaBalanceByRequestRate {
+			return reports[i].RequestRate > reports[j].RequestRate
+		}
+		return node.memory[reports[i].PartitionID] > node.memory[reports[j].PartitionID]
+	})
+	for _, report := range reports {
+		if planned[report.PartitionID] {
+			continue
+		}
+		isLeader, onTarget := target.leader[report.PartitionID]
+		if reason == proto.MetaBalanceByRequestRate {
+			if !node.leader[report.PartitionID] || !onTarget || isLeader || report.RequestRate == 0 ||
+				target.rate+report.RequestRate > node.rate-report.RequestRate {
+				continue
+			}
+		} else {
+			var memory = node.memory[report.PartitionID]
+			if onTarget || memory == 0 || memory > node.used {
+				continue
+			}
+			var sourceRatio = float64(node.used-memory) / float64(node.total)
+			var targetRatio = float64(target.used+memory) / float64(target.total)
+			if targetRatio > sourceRatio || targetRatio-sourceRatio > memThreshold ||
+				(target.threshold > 0 && targetRatio > target.threshold) {
+				continue
+			}
+		}
+		if movable != nil && !movable(report, node, target, reason) {
+			continue
+		}
+		return report
+	}
+	return nil
+}
+
+func (c *Cluster) metaBalanceNodes() (nodes []*metaBalanceNode) {
+	nodes = make([]*metaBalanceNode, 0)
+	c.metaNodes.Range(func(addr, value interface{}) bool {
+		metaNode := value.(*MetaNode)
+		metaNode.RLock()
+		defer metaNode.RUnlock()
+		if !metaNode.IsActive || metaNode.ToBeOffline || metaNode.inMaintenance() || metaNode.Total == 0 {
+			return true
+		}
+		node := newMetaBalanceNode(metaNode.Addr, metaNode.ZoneName, metaNode.NodeSetID,
+			metaNode.Total, metaNode.Used, metaNode.metaPartitionInfos)
+		node.threshold = float64(metaNode.Threshold)
+		nodes = append(nodes, node)
+		return true
+	})
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].addr < nodes[j].addr
+	})
+	return
+}
+
+// planMetaBalance plans at most maxTasks migrations of the meta partitions. In each zone, the
+// memory usage is balanced first by migrating the replicas, and then the request rates by
+// transferring the leaders if the average of the zone is high enough. The partitions in planned are skipped, and the planned ones are added to it.
+func planMetaBalance(nodes []*metaBalanceNode, config proto.MetaBalanceConfig, maxTasks int, planned map[uint64]bool,
+	movable func(report *proto.MetaPartitionReport, source, target *metaBalanceNode, reason string) bool) (tasks []*proto.MetaBalanceTask) {
+	var zones = make(map[string][]*metaBalanceNode)
+	var zoneNames = make([]string, 0)
+	for _, node := range nodes {
+		if _, ok := zones[node.zoneName]; !ok {
+			zoneNames = append(zoneNames, node.zoneName)
+		}
+		zones[node.zoneName] = append(zones[node.zoneName], node)
+	}
+	sort.Strings(zoneNames)
+
+	tasks = make([]*proto.MetaBalanceTask, 0)
+	for _, zoneName := range zoneNames {
+		var zoneNodes = zones[zoneName]
+		tasks = planMetaBalanceZone(zoneNodes, proto.MetaBalanceByMemory, config.MemThreshold, config,
+			maxTasks, tasks, planned, movable)
+		var rate float64
+		for _, node := range zoneNodes {
+			rate += node.rate
+		}
+		if rate /= float64(len(zoneNodes)); rate < defaultMetaBalanceMinRequestRate {
+			continue
+		}
+		tasks = planMetaBalanceZone(zoneNodes, proto.MetaBalanceByRequestRate, config.RateThreshold*rate, config,
+			maxTasks, tasks, planned, movable)
+	}
+	return
+}
+
+// planMetaBalanceZone migrates the partitions on the node with the highest load in the zone to
+// the ones with the lowest load, until the difference of the loads is not greater than the
+// threshold.
+func planMetaBalanceZone(zoneNodes []*metaBalanceNode, reason string, threshold float64, config proto.MetaBalanceConfig,
+	maxTasks int, tasks []*proto.MetaBalanceTask, planned map[uint64]bool,
+	movable func(report *proto.MetaPartitionReport, source, target *metaBalanceNode, reason string) bool) []*proto.MetaBalanceTask {
+	// the sources from which no partition can be migrated
+	var exhausted = make(map[string]bool)
+	for len(tasks) < maxTasks {
+		sort.SliceStable(zoneNodes, func(i, j int) bool {
+			return zoneNodes[i].load(reason) > zoneNodes[j].load(reason)
+		})
+		var source *metaBalanceNode
+		for _, node := range zoneNodes {
+			if !exhausted[node.addr] {
+				source = node
+				break
+			}
+		}
+		if source == nil {
+			break
+		}
+		var target *metaBalanceNode
+		var report *proto.MetaPartitionReport
+		for i := len(zoneNodes) - 1; i >= 0 && report == nil; i-- {
+			target = zoneNodes[i]
+			if source.load(reason)-target.load(reason) <= threshold {
+				break
+			}
+			report = source.pick(target, reason, config.MemThreshold, planned, movable)
+		}
+		if report == nil {
+			exhausted[source.addr] = true
+			continue
+		}
+		var task = &proto.MetaBalanceTask{
+			PartitionID: report.PartitionID,
+			VolName:     report.VolName,
+			Source:      source.addr,
+			Target:      target.addr,
+			Reason:      reason,
+			RequestRate: report.RequestRate,
+		}
+		planned[report.PartitionID] = true
+		if reason == proto.MetaBalanceByRequestRate {
+			source.transfer(target, report.PartitionID, report.RequestRate)
+		} else {
+			task.Memory = source.memory[report.PartitionID]
+			source.move(report.PartitionID)
+			target.used += task.Memory
+			target.leader[report.PartitionID] = false
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks
+}
+
+// metaBalanceZoneUsages returns the memory usage and the request rates of the meta nodes by zone.
+func metaBalanceZoneUsages(nodes []*metaBalanceNode) (zones []*proto.MetaBalanceZoneUsage) {
+	var zoneMap = make(map[string]*proto.MetaBalanceZoneUsage)
+	var totals = make(map[string][2]uint64)
+	zones = make([]*proto.MetaBalanceZoneUsage, 0)
+	for _, node := range nodes {
+		zone, ok := zoneMap[node.zoneName]
+		if !ok {
+			zone = &proto.MetaBalanceZoneUsage{ZoneName: node.zoneName}
+			zoneMap[node.zoneName] = zone
+			zones = append(zones, zone)
+		}
+		zone.Nodes = append(zone.Nodes, &proto.MetaBalanceNodeUsage{
+			Addr:           node.addr,
+			Total:          node.total,
+			Used:           node.used,
+			UsageRatio:     node.usageRatio(),
+			RequestRate:    node.rate,
+			PartitionCount: len(node.reports),
+		})
+		zone.RequestRate += node.rate
+		var total = totals[node.zoneName]
+		totals[node.zoneName] = [2]uint64{total[0] + node.total, total[1] + node.used}
+	}
+	sort.Slice(zones, func(i, j int) bool {
+		return zones[i].ZoneName < zones[j].ZoneName
+	})
+	for _, zone := range zones {
+		if total := totals[zone.ZoneName]; total[0] > 0 {
+			zone.UsageRatio = float64(total[1]) / float64(total[0])
+		}
+		zone.Skew = dataBalanceSkew(len(zone.Nodes), func(i int) float64 {
+			return zone.Nodes[i].UsageRatio
+		})
+		zone.RequestRate /= float64(len(zone.Nodes))
+		if zone.RequestRate > 0 {
+			zone.RateSkew = dataBalanceSkew(len(zone.Nodes), func(i int) float64 {
+				return zone.Nodes[i].RequestRate
+			}) / zone.RequestRate
+		}
+	}
+	return
+}
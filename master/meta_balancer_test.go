@@ -0,0 +1,91 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package master
+
+import (
+	"math"
+	"testing"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+func newTestMetaBalanceNodes() []*metaBalanceNode {
+	return []*metaBalanceNode{
+		newMetaBalanceNode("a", "z1", 1, 100, 80, []*proto.MetaPartitionReport{
+			{PartitionID: 1, VolName: "vol", InodeCnt: 300, DentryCnt: 300, IsLeader: true, RequestRate: 10},
+			{PartitionID: 2, VolName: "vol", InodeCnt: 100, DentryCnt: 100, IsLeader: true, RequestRate: 10},
+			{PartitionID: 3, VolName: "vol", InodeCnt: 100, DentryCnt: 100, IsLeader: true, RequestRate: 10},
+		}),
+		newMetaBalanceNode("b", "z1", 1, 100, 60, []*proto.MetaPartitionReport{
+			{PartitionID: 4, VolName: "vol", InodeCnt: 50, DentryCnt: 50, IsLeader: true, RequestRate: 900},
+			{PartitionID: 5, VolName: "vol", InodeCnt: 50, DentryCnt: 50, IsLeader: true, RequestRate: 300},
+			{PartitionID: 7, VolName: "vol", InodeCnt: 500, DentryCnt: 500},
+		}),
+		newMetaBalanceNode("c", "z1", 1, 100, 40, []*proto.MetaPartitionReport{
+			{PartitionID: 6, VolName: "vol", InodeCnt: 100, DentryCnt: 100, IsLeader: true, RequestRate: 10},
+			{PartitionID: 5, VolName: "vol", InodeCnt: 50, DentryCnt: 50},
+		}),
+	}
+}
+
+func TestPlanMetaBalance(t *testing.T) {
+	var config = proto.MetaBalanceConfig{MemThreshold: 0.1, RateThreshold: 0.5}
+	tasks := planMetaBalance(newTestMetaBalanceNodes(), config, 10, make(map[uint64]bool), nil)
+	if len(tasks) != 2 {
+		t.Fatalf("planned tasks: expect(2) actual(%v)", len(tasks))
+	}
+	// the partition with the most inodes and dentries would overshoot, so a smaller one is migrated
+	if task := tasks[0]; task.PartitionID != 2 || task.Source != "a" || task.Target != "c" ||
+		task.Reason != proto.MetaBalanceByMemory || task.Memory != 16 {
+		t.Fatalf("memory task: %v", task)
+	}
+	// the leader of the hot partition is transferred from the node serving most of the requests to
+	// the follower, instead of migrating the replica
+	if task := tasks[1]; task.PartitionID != 5 || task.Source != "b" || task.Target != "c" ||
+		task.Reason != proto.MetaBalanceByRequestRate || task.Memory != 0 || task.RequestRate != 300 {
+		t.Fatalf("request rate task: %v", task)
+	}
+
+	// the planned and unmovable partitions are skipped, and the node with the next highest usage
+	// is balanced if nothing can be migrated from the highest one
+	tasks = planMetaBalance(newTestMetaBalanceNodes(), config, 1, map[uint64]bool{2: true},
+		func(report *proto.MetaPartitionReport, source, target *metaBalanceNode, reason string) bool {
+			return report.PartitionID != 3
+		})
+	if len(tasks) != 1 || tasks[0].PartitionID != 4 || tasks[0].Source != "b" || tasks[0].Target != "c" {
+		t.Fatalf("planned tasks: %v", tasks)
+	}
+
+	// nothing to migrate if the thresholds are not exceeded
+	config = proto.MetaBalanceConfig{MemThreshold: 0.5, RateThreshold: 3}
+	if tasks = planMetaBalance(newTestMetaBalanceNodes(), config, 10, make(map[uint64]bool), nil); len(tasks) != 0 {
+		t.Fatalf("planned tasks: %v", tasks)
+	}
+}
+
+func TestMetaBalanceZoneUsages(t *testing.T) {
+	zones := metaBalanceZoneUsages(newTestMetaBalanceNodes())
+	if len(zones) != 1 || len(zones[0].Nodes) != 3 {
+		t.Fatalf("zones: %v", zones)
+	}
+	var zone = zones[0]
+	if math.Abs(zone.Skew-0.4) > 1e-9 || math.Abs(zone.RequestRate-1240.0/3) > 1e-9 ||
+		math.Abs(zone.RateSkew-1190/(1240.0/3)) > 1e-9 {
+		t.Fatalf("zone usage: skew(%v) rate(%v) rate skew(%v)", zone.Skew, zone.RequestRate, zone.RateSkew)
+	}
+	if node := zone.Nodes[1]; node.Addr != "b" || node.RequestRate != 1200 || node.PartitionCount != 3 {
+		t.Fatalf("node usage: %v", node)
+	}
+}
@@ -0,0 +1,398 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package master
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// metaMover moves the items of the inodes in a range from a meta partition to another one of the
+// same volume, which either merges a sparse partition into the previous adjacent one, or re-splits
+// the tail of a hot partition into a new one. The source partition is frozen while the items are
+// moved, so that its items do not change, and the ranges of the partitions are switched after all
+// the items have been moved.
+//
+// Only one move is allowed in a volume at a time, and the automatic split of the last partition is
+// suspended during the move. Like the balancers, the tasks are kept in the memory of the leader
+// master only.
+type metaMover struct {
+	sync.RWMutex
+	c       *Cluster
+	tasks   map[string]*proto.MetaMoveTask // moving tasks by volume name
+	history []*proto.MetaMoveTask
+}
+
+func newMetaMover(c *Cluster) *metaMover {
+	return &metaMover{c: c, tasks: make(map[string]*proto.MetaMoveTask)}
+}
+
+func (m *metaMover) isMoving(volName string) bool {
+	m.RLock()
+	defer m.RUnlock()
+	_, ok := m.tasks[volName]
+	return ok
+}
+
+func (m *metaMover) status() (status *proto.MetaMoveStatus) {
+	m.RLock()
+	defer m.RUnlock()
+	status = &proto.MetaMoveStatus{
+		Tasks:       make([]*proto.MetaMoveTask, 0, len(m.tasks)),
+		RecentTasks: make([]*proto.MetaMoveTask, 0, len(m.history)),
+	}
+	for _, task := range m.tasks {
+		var t = *task
+		status.Tasks = append(status.Tasks, &t)
+	}
+	sort.Slice(status.Tasks, func(i, j int) bool {
+		return status.Tasks[i].VolName < status.Tasks[j].VolName
+	})
+	for i := len(m.history) - 1; i >= 0; i-- {
+		var t = *m.history[i]
+		status.RecentTasks = append(status.RecentTasks, &t)
+	}
+	return
+}
+
+// merge moves the items of the partition following the given one into it, and then removes the
+// following partition.
+func (m *metaMover) merge(volName string, partitionID uint64) (task *proto.MetaMoveTask, err error) {
+	var (
+		vol      *Vol
+		mp, next *MetaPartition
+	)
+	if vol, err = m.c.getVol(volName); err != nil {
+		return
+	}
+	if mp, err = vol.metaPartition(partitionID); err != nil {
+		return
+	}
+	if next, err = vol.nextMetaPartition(mp); err != nil {
+		return
+	}
+	task = &proto.MetaMoveTask{VolName: volName, Type: proto.MetaMoveMerge, Source: next.PartitionID,
+		Target: mp.PartitionID, Start: next.Start, End: next.End}
+	err = m.start(vol, task)
+	return
+}
+
+// resplit moves the items of the inodes from the given start to the end of the partition into a
+// new partition, and then shrinks the partition to end before the start.
+func (m *metaMover) resplit(volName string, partitionID, start uint64) (task *proto.MetaMoveTask, err error) {
+	var (
+		vol *Vol
+		mp  *MetaPartition
+	)
+	if vol, err = m.c.getVol(volName); err != nil {
+		return
+	}
+	if mp, err = vol.metaPartition(partitionID); err != nil {
+		return
+	}
+	if start <= mp.Start || start > mp.End {
+		err = fmt.Errorf("start[%v] is out of the range (%v,%v] of mp[%v]", start, mp.Start, mp.End, mp.PartitionID)
+		return
+	}
+	task = &proto.MetaMoveTask{VolName: volName, Type: proto.MetaMoveResplit, Source: mp.PartitionID,
+		Start: start, End: mp.End}
+	err = m.start(vol, task)
+	return
+}
+
+func (m *metaMover) start(vol *Vol, task *proto.MetaMoveTask) (err error) {
+	// the split of the last partition changes the ranges too
+	vol.createMpMutex.Lock()
+	defer vol.createMpMutex.Unlock()
+	m.Lock()
+	defer m.Unlock()
+	if _, ok := m.tasks[vol.Name]; ok {
+		return proto.ErrMetaMoveRunning
+	}
+	task.Status = proto.MetaMoveTaskFreezing
+	task.StartTime = time.Now().Unix()
+	m.tasks[vol.Name] = task
+	log.LogWarnf("action[startMetaMove] %v vol[%v] move range[%v,%v] from mp[%v] to mp[%v]",
+		task.Type, task.VolName, task.Start, task.End, task.Source, task.Target)
+	go m.run(vol, task)
+	return
+}
+
+func (m *metaMover) setTaskStatus(task *proto.MetaMoveTask, status string) {
+	m.Lock()
+	defer m.Unlock()
+	task.Status = status
+}
+
+// run freezes the source partition, moves the items to the target, and then switches the ranges.
+// If the move fails, the source is unfrozen and the moved items are removed from the target.
+func (m *metaMover) run(vol *Vol, task *proto.MetaMoveTask) {
+	var (
+		source, target *MetaPartition
+		err            error
+	)
+	defer func() {
+		m.finish(task, err)
+	}()
+	var c = m.c
+	if source, err = vol.metaPartition(task.Source); err != nil {
+		return
+	}
+	if err = c.freezeMetaPartition(source, true); err != nil {
+		m.rollback(source, nil, task)
+		return
+	}
+	if task.Type == proto.MetaMoveMerge {
+		target, err = vol.metaPartition(task.Target)
+	} else {
+		m.setTaskStatus(task, proto.MetaMoveTaskCreating)
+		vol.createMpMutex.Lock()
+		target, err = vol.doCreateMetaPartition(c, task.Start, task.End)
+		vol.createMpMutex.Unlock()
+		if err == nil {
+			m.Lock()
+			task.Target = target.PartitionID
+			m.Unlock()
+		}
+	}
+	if err == nil {
+		m.setTaskStatus(task, proto.MetaMoveTaskMoving)
+		err = m.moveItems(source, target, task)
+	}
+	if err == nil {
+		m.setTaskStatus(task, proto.MetaMoveTaskSwitching)
+		if task.Type == proto.MetaMoveMerge {
+			err = m.switchMerge(vol, source, target, task)
+		} else {
+			err = m.switchResplit(vol, source, target, task)
+		}
+		return
+	}
+	m.rollback(source, target, task)
+}
+
+// moveItems starts moving the items on the leader of the source, and polls the progress until the
+// items have been moved.
+func (m *metaMover) moveItems(source, target *MetaPartition, task *proto.MetaMoveTask) (err error) {
+	var c = m.c
+	target.RLock()
+	req := &proto.MoveMetaItemsRequest{PartitionID: source.PartitionID, VolName: task.VolName, Start: task.Start,
+		End: task.End, TargetPartitionID: target.PartitionID, TargetHosts: append([]string(nil), target.Hosts...)}
+	target.RUnlock()
+	var since = time.Now()
+	for {
+		if c.partition == nil || !c.partition.IsRaftLeader() {
+			return fmt.Errorf("leader changed while the items are being moved")
+		}
+		var resp *proto.MoveMetaItemsResponse
+		if resp, err = c.syncMoveMetaItems(source, req); err != nil {
+			// the leader of the source may be changing, which continues the move when asked again
+			log.LogWarnf("action[moveMetaItems] vol[%v] mp[%v] err[%v]", task.VolName, source.PartitionID, err)
+		} else {
+			m.Lock()
+			task.Moved = resp.Moved
+			m.Unlock()
+			switch resp.Status {
+			case proto.MetaItemsMoved:
+				return nil
+			case proto.MetaItemsFailed:
+				return fmt.Errorf("move items from mp[%v] to mp[%v] failed: %v", source.PartitionID,
+					target.PartitionID, resp.Result)
+			}
+		}
+		if time.Since(since) > time.Second*defaultMetaMoveTimeOutSec {
+			return fmt.Errorf("items are not moved in %v seconds, last err[%v]", defaultMetaMoveTimeOutSec, err)
+		}
+		time.Sleep(time.Second * defaultIntervalToCheckMetaMove)
+	}
+}
+
+// switchMerge extends the target to the end of the source, and then removes the source. If the
+// source cannot be removed from the store, it stays frozen and still serves its range, since the
+// clients look up the partition with the greatest start not greater than the inode.
+func (m *metaMover) switchMerge(vol *Vol, source, target *MetaPartition, task *proto.MetaMoveTask) (err error) {
+	var c = m.c
+	vol.createMpMutex.Lock()
+	defer vol.createMpMutex.Unlock()
+	target.Lock()
+	oldEnd := target.End
+	target.End = source.End
+	if err = c.syncUpdateMetaPartition(target); err != nil {
+		target.End = oldEnd
+		target.Unlock()
+		m.rollback(source, target, task)
+		return
+	}
+	target.updateInodeIDRangeForAllReplicas()
+	target.addUpdateMetaReplicaTask(c)
+	target.Unlock()
+	if err = c.syncDeleteMetaPartition(source); err != nil {
+		return fmt.Errorf("mp[%v] has been merged into mp[%v], but remove it failed: %v", source.PartitionID,
+			target.PartitionID, err)
+	}
+	vol.deleteMetaPartition(source.PartitionID)
+	source.RLock()
+	tasks := make([]*proto.AdminTask, 0, len(source.Replicas))
+	for _, mr := range source.Replicas {
+		tasks = append(tasks, mr.createTaskToDeleteReplica(source.PartitionID))
+	}
+	source.RUnlock()
+	c.addMetaNodeTasks(tasks)
+	return
+}
+
+// switchResplit shrinks the source to end before the start and adds the target, and then removes
+// the moved items from the source and unfreezes it.
+func (m *metaMover) switchResplit(vol *Vol, source, target *MetaPartition, task *proto.MetaMoveTask) (err error) {
+	var (
+		c     = m.c
+		start = task.Start
+	)
+	vol.createMpMutex.Lock()
+	source.Lock()
+	cmdMap := make(map[string]*RaftCmd, 0)
+	oldEnd := source.End
+	source.End = start - 1
+	updateMpRaftCmd, err := c.buildMetaPartitionRaftCmd(opSyncUpdateMetaPartition, source)
+	if err == nil {
+		cmdMap[updateMpRaftCmd.K] = updateMpRaftCmd
+		var addMpRaftCmd *RaftCmd
+		if addMpRaftCmd, err = c.buildMetaPartitionRaftCmd(opSyncAddMetaPartition, target); err == nil {
+			cmdMap[addMpRaftCmd.K] = addMpRaftCmd
+			err = c.syncBatchCommitCmd(cmdMap)
+		}
+	}
+	if err != nil {
+		source.End = oldEnd
+		source.Unlock()
+		vol.createMpMutex.Unlock()
+		m.rollback(source, target, task)
+		return
+	}
+	source.updateInodeIDRangeForAllReplicas()
+	source.Unlock()
+	vol.addMetaPartition(target)
+	vol.createMpMutex.Unlock()
+
+	// the moved items are served by the target from now on
+	var since = time.Now()
+	for {
+		if err = c.trimMetaPartition(source, start-1); err == nil {
+			break
+		}
+		if time.Since(since) > time.Second*defaultMetaMoveTimeOutSec {
+			return fmt.Errorf("trim mp[%v] to end[%v] failed: %v", source.PartitionID, start-1, err)
+		}
+		time.Sleep(time.Second * defaultIntervalToCheckMetaMove)
+	}
+	return c.freezeMetaPartition(source, false)
+}
+
+// rollback removes the moved items from the target, and unfreezes the source.
+func (m *metaMover) rollback(source, target *MetaPartition, task *proto.MetaMoveTask) {
+	var c = m.c
+	if target != nil {
+		if task.Type == proto.MetaMoveMerge {
+			target.RLock()
+			end := target.End
+			target.RUnlock()
+			if err := c.trimMetaPartition(target, end); err != nil {
+				log.LogErrorf("action[rollbackMetaMove] trim mp[%v] to end[%v] failed, err[%v]",
+					target.PartitionID, end, err)
+			}
+		} else {
+			target.RLock()
+			tasks := make([]*proto.AdminTask, 0, len(target.Replicas))
+			for _, mr := range target.Replicas {
+				tasks = append(tasks, mr.createTaskToDeleteReplica(target.PartitionID))
+			}
+			target.RUnlock()
+			c.addMetaNodeTasks(tasks)
+		}
+	}
+	if err := c.freezeMetaPartition(source, false); err != nil {
+		log.LogErrorf("action[rollbackMetaMove] unfreeze mp[%v] failed, err[%v]", source.PartitionID, err)
+	}
+}
+
+func (m *metaMover) finish(task *proto.MetaMoveTask, err error) {
+	m.Lock()
+	defer m.Unlock()
+	delete(m.tasks, task.VolName)
+	task.EndTime = time.Now().Unix()
+	if err != nil {
+		task.Status = proto.MetaMoveTaskFailed
+		task.Msg = err.Error()
+		Warn(m.c.Name, fmt.Sprintf("clusterID[%v] %v vol[%v] move range[%v,%v] from mp[%v] to mp[%v] failed, err[%v]",
+			m.c.Name, task.Type, task.VolName, task.Start, task.End, task.Source, task.Target, err))
+	} else {
+		task.Status = proto.MetaMoveTaskFinished
+		log.LogWarnf("action[finishMetaMove] %v vol[%v] move range[%v,%v] from mp[%v] to mp[%v] successfully",
+			task.Type, task.VolName, task.Start, task.End, task.Source, task.Target)
+	}
+	m.history = append(m.history, task)
+	if len(m.history) > defaultMetaMoveHistoryCount {
+		m.history = m.history[len(m.history)-defaultMetaMoveHistoryCount:]
+	}
+}
+
+// syncSendMetaLeaderTask sends the admin task to the leader of the meta partition, and waits for
+// the response.
+func (c *Cluster) syncSendMetaLeaderTask(mp *MetaPartition, opcode uint8, req interface{}) (packet *proto.Packet, err error) {
+	var (
+		mr       *MetaReplica
+		metaNode *MetaNode
+	)
+	mp.RLock()
+	mr, err = mp.getMetaReplicaLeader()
+	mp.RUnlock()
+	if err != nil {
+		return nil, fmt.Errorf("mp[%v] %v", mp.PartitionID, err)
+	}
+	if metaNode, err = c.metaNode(mr.Addr); err != nil {
+		return
+	}
+	task := proto.NewAdminTask(opcode, mr.Addr, req)
+	resetMetaPartitionTaskID(task, mp.PartitionID)
+	return metaNode.Sender.syncSendAdminTask(task)
+}
+
+func (c *Cluster) freezeMetaPartition(mp *MetaPartition, freeze bool) (err error) {
+	req := &proto.FreezeMetaPartitionRequest{PartitionID: mp.PartitionID, VolName: mp.volName, Freeze: freeze}
+	_, err = c.syncSendMetaLeaderTask(mp, proto.OpFreezeMetaPartition, req)
+	return
+}
+
+func (c *Cluster) trimMetaPartition(mp *MetaPartition, end uint64) (err error) {
+	req := &proto.TrimMetaPartitionRequest{PartitionID: mp.PartitionID, VolName: mp.volName, End: end}
+	_, err = c.syncSendMetaLeaderTask(mp, proto.OpTrimMetaPartition, req)
+	return
+}
+
+func (c *Cluster) syncMoveMetaItems(mp *MetaPartition, req *proto.MoveMetaItemsRequest) (resp *proto.MoveMetaItemsResponse, err error) {
+	var packet *proto.Packet
+	if packet, err = c.syncSendMetaLeaderTask(mp, proto.OpMoveMetaItems, req); err != nil {
+		return
+	}
+	resp = &proto.MoveMetaItemsResponse{}
+	err = json.Unmarshal(packet.Data, resp)
+	return
+}
@@ -0,0 +1,44 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package master
+
+import (
+	"testing"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+func TestMetaPartitionRanges(t *testing.T) {
+	// mp 4 is re-split from the tail of mp 1, so the last partition is not the one with the max ID
+	vol := &Vol{Name: "vol", MetaPartitions: make(map[uint64]*MetaPartition)}
+	vol.addMetaPartition(newMetaPartition(1, 1, 999, 3, vol.Name, 1))
+	vol.addMetaPartition(newMetaPartition(4, 1000, 1999, 3, vol.Name, 1))
+	vol.addMetaPartition(newMetaPartition(2, 2000, defaultMaxMetaPartitionInodeID, 3, vol.Name, 1))
+	if id := vol.maxPartitionID(); id != 2 {
+		t.Fatalf("last partition: expect(2) actual(%v)", id)
+	}
+	mp, _ := vol.metaPartition(1)
+	if next, err := vol.nextMetaPartition(mp); err != nil || next.PartitionID != 4 {
+		t.Fatalf("next partition of mp 1: %v %v", next, err)
+	}
+	mp, _ = vol.metaPartition(2)
+	if _, err := vol.nextMetaPartition(mp); err != proto.ErrNoAdjacentMetaPartition {
+		t.Fatalf("next partition of the last one: %v", err)
+	}
+	vol.deleteMetaPartition(4)
+	if _, err := vol.metaPartition(4); err != proto.ErrMetaPartitionNotExists {
+		t.Fatalf("deleted partition: %v", err)
+	}
+}
@@ -43,6 +43,9 @@ type MetaNode struct {
 	sync.RWMutex              `graphql:"-"`
 	ToBeOffline               bool
 	PersistenceMetaPartitions []uint64
+	Version                   string // version reported by the meta node
+	Upgrading                 bool   // no meta partition is placed on the node while it is being upgraded
+	MaintenanceUntil          int64  // unix time when the maintenance of the node ends
 }
 
 func newMetaNode(addr, zoneName, clusterID string) (node *MetaNode) {
@@ -85,10 +88,22 @@ func (metaNode *MetaNode) SelectNodeForWrite() {
 	metaNode.Carry = metaNode.Carry - 1.0
 }
 
+// inMaintenance returns true if the maintenance window of the node has not ended.
+// The caller should hold the lock of the node.
+func (metaNode *MetaNode) inMaintenance() bool {
+	return metaNode.MaintenanceUntil > time.Now().Unix()
+}
+
+func (metaNode *MetaNode) isInMaintenance() bool {
+	metaNode.RLock()
+	defer metaNode.RUnlock()
+	return metaNode.inMaintenance()
+}
+
 func (metaNode *MetaNode) isWritable() (ok bool) {
 	metaNode.RLock()
 	defer metaNode.RUnlock()
-	if metaNode.IsActive && metaNode.MaxMemAvailWeight > gConfig.metaNodeReservedMem &&
+	if metaNode.IsActive && !metaNode.Upgrading && !metaNode.inMaintenance() && metaNode.MaxMemAvailWeight > gConfig.metaNodeReservedMem &&
 		!metaNode.reachesThreshold() && metaNode.MetaPartitionCount < defaultMaxMetaPartitionCountOnEachNode {
 		ok = true
 	}
@@ -123,6 +138,7 @@ func (metaNode *MetaNode) updateMetric(resp *proto.MetaNodeHeartbeatResponse, th
 	}
 	metaNode.MaxMemAvailWeight = resp.Total - resp.Used
 	metaNode.ZoneName = resp.ZoneName
+	metaNode.Version = resp.Version
 	metaNode.Threshold = threshold
 }
 
@@ -133,10 +149,13 @@ func (metaNode *MetaNode) reachesThreshold() bool {
 	return float32(float64(metaNode.Used)/float64(metaNode.Total)) > metaNode.Threshold
 }
 
-func (metaNode *MetaNode) createHeartbeatTask(masterAddr string) (task *proto.AdminTask) {
+func (metaNode *MetaNode) createHeartbeatTask(masterAddr string, quotas []*proto.QuotaHeartBeatInfo,
+	trashDays map[string]uint32) (task *proto.AdminTask) {
 	request := &proto.HeartBeatRequest{
 		CurrTime:   time.Now().Unix(),
 		MasterAddr: masterAddr,
+		Quotas:     quotas,
+		TrashDays:  trashDays,
 	}
 	task = proto.NewAdminTask(proto.OpMetaNodeHeartbeat, metaNode.Addr, request)
 	return
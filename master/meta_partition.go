@@ -60,6 +60,7 @@ type MetaPartition struct {
 	OfflinePeerID uint64
 	MissNodes     map[string]int64
 	LoadResponse  []*proto.MetaPartitionLoadResponse
+	quotaUsages   []*proto.QuotaUsage
 	offlineMutex  sync.RWMutex
 	sync.RWMutex
 }
@@ -177,7 +178,7 @@ func (mp *MetaPartition) addUpdateMetaReplicaTask(c *Cluster) (err error) {
 
 func (mp *MetaPartition) checkEnd(c *Cluster, maxPartitionID uint64) {
 
-	if mp.PartitionID < maxPartitionID {
+	if mp.PartitionID != maxPartitionID {
 		return
 	}
 	vol, err := c.getVol(mp.volName)
@@ -275,7 +276,7 @@ func (mp *MetaPartition) checkStatus(clusterID string, writeLog bool, replicaNum
 		}
 	}
 
-	if mp.PartitionID >= maxPartitionID && mp.Status == proto.ReadOnly {
+	if mp.PartitionID == maxPartitionID && mp.Status == proto.ReadOnly {
 		mp.Status = proto.ReadWrite
 	}
 	if writeLog && len(liveReplicas) != int(mp.ReplicaNum) {
@@ -349,6 +350,9 @@ func (mp *MetaPartition) updateMetaPartition(mgr *proto.MetaPartitionReport, met
 		mp.addReplica(mr)
 	}
 	mr.updateMetric(mgr)
+	if mgr.IsLeader {
+		mp.quotaUsages = mgr.QuotaUsages
+	}
 	mp.setMaxInodeID()
 	mp.setInodeCount()
 	mp.setDentryCount()
@@ -442,10 +446,15 @@ func (mp *MetaPartition) shouldReportMissingReplica(addr string, interval int64)
 	return false
 }
 
-func (mp *MetaPartition) reportMissingReplicas(clusterID, leaderAddr string, seconds, interval int64) {
+// reportMissingReplicas alarms the missing replicas, except the ones on the meta nodes in maintenance.
+func (mp *MetaPartition) reportMissingReplicas(c *Cluster, seconds, interval int64) {
+	clusterID, leaderAddr := c.Name, c.leaderInfo.addr
 	mp.Lock()
 	defer mp.Unlock()
 	for _, replica := range mp.Replicas {
+		if c.isMetaNodeInMaintenance(replica.Addr) {
+			continue
+		}
 		// reduce the alarm frequency
 		if contains(mp.Hosts, replica.Addr) && replica.isMissing() && mp.shouldReportMissingReplica(replica.Addr, interval) {
 			metaNode := replica.metaNode
@@ -467,6 +476,9 @@ func (mp *MetaPartition) reportMissingReplicas(clusterID, leaderAddr string, sec
 	}
 
 	for _, addr := range mp.Hosts {
+		if c.isMetaNodeInMaintenance(addr) {
+			continue
+		}
 		if mp.isMissingReplica(addr) && mp.shouldReportMissingReplica(addr, interval) {
 			msg := fmt.Sprintf("action[reportMissingReplicas],clusterID[%v] volName[%v] partition:%v  on Node:%v  "+
 				"miss time  > %v ",
@@ -678,6 +690,12 @@ func (mp *MetaPartition) getMinusOfMaxInodeID() (minus float64) {
 	return
 }
 
+func (mp *MetaPartition) getQuotaUsages() []*proto.QuotaUsage {
+	mp.RLock()
+	defer mp.RUnlock()
+	return mp.quotaUsages
+}
+
 func (mp *MetaPartition) setMaxInodeID() {
 	var maxUsed uint64
 	for _, r := range mp.Replicas {
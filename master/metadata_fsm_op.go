@@ -98,6 +98,8 @@ type dataPartitionValue struct {
 	OfflinePeerID uint64
 	Replicas      []*replicaValue
 	IsRecover     bool
+	ECDataNum     uint8
+	ECParityNum   uint8
 }
 
 type replicaValue struct {
@@ -117,6 +119,8 @@ func newDataPartitionValue(dp *DataPartition) (dpv *dataPartitionValue) {
 		OfflinePeerID: dp.OfflinePeerID,
 		Replicas:      make([]*replicaValue, 0),
 		IsRecover:     dp.isRecover,
+		ECDataNum:     dp.ECDataNum,
+		ECParityNum:   dp.ECParityNum,
 	}
 	for _, replica := range dp.Replicas {
 		rv := &replicaValue{Addr: replica.Addr, DiskPath: replica.DiskPath}
@@ -146,6 +150,11 @@ type volValue struct {
 	DpSelectorName    string
 	DpSelectorParm    string
 	DefaultPriority   bool
+	Snapshots         []*bsProto.SnapshotInfo
+	Quotas            []*bsProto.QuotaInfo
+	TrashDays         uint32
+	ECDataNum         uint8
+	ECParityNum       uint8
 }
 
 func (v *volValue) Bytes() (raw []byte, err error) {
@@ -175,6 +184,13 @@ func newVolValue(vol *Vol) (vv *volValue) {
 		DpSelectorName:    vol.dpSelectorName,
 		DpSelectorParm:    vol.dpSelectorParm,
 		DefaultPriority:   vol.defaultPriority,
+		Snapshots:         vol.snapshots,
+		TrashDays:         vol.trashDays,
+		ECDataNum:         vol.ecDataNum,
+		ECParityNum:       vol.ecParityNum,
+	}
+	for _, quota := range vol.quotas {
+		vv.Quotas = append(vv.Quotas, quota)
 	}
 	return
 }
@@ -188,34 +204,38 @@ func newVolValueFromBytes(raw []byte) (*volValue, error) {
 }
 
 type dataNodeValue struct {
-	ID        uint64
-	NodeSetID uint64
-	Addr      string
-	ZoneName  string
+	ID               uint64
+	NodeSetID        uint64
+	Addr             string
+	ZoneName         string
+	MaintenanceUntil int64
 }
 
 func newDataNodeValue(dataNode *DataNode) *dataNodeValue {
 	return &dataNodeValue{
-		ID:        dataNode.ID,
-		NodeSetID: dataNode.NodeSetID,
-		Addr:      dataNode.Addr,
-		ZoneName:  dataNode.ZoneName,
+		ID:               dataNode.ID,
+		NodeSetID:        dataNode.NodeSetID,
+		Addr:             dataNode.Addr,
+		ZoneName:         dataNode.ZoneName,
+		MaintenanceUntil: dataNode.MaintenanceUntil,
 	}
 }
 
 type metaNodeValue struct {
-	ID        uint64
-	NodeSetID uint64
-	Addr      string
-	ZoneName  string
+	ID               uint64
+	NodeSetID        uint64
+	Addr             string
+	ZoneName         string
+	MaintenanceUntil int64
 }
 
 func newMetaNodeValue(metaNode *MetaNode) *metaNodeValue {
 	return &metaNodeValue{
-		ID:        metaNode.ID,
-		NodeSetID: metaNode.NodeSetID,
-		Addr:      metaNode.Addr,
-		ZoneName:  metaNode.ZoneName,
+		ID:               metaNode.ID,
+		NodeSetID:        metaNode.NodeSetID,
+		Addr:             metaNode.Addr,
+		ZoneName:         metaNode.ZoneName,
+		MaintenanceUntil: metaNode.MaintenanceUntil,
 	}
 }
 
@@ -732,6 +752,7 @@ func (c *Cluster) loadDataNodes() (err error) {
 		dataNode := newDataNode(dnv.Addr, dnv.ZoneName, c.Name)
 		dataNode.ID = dnv.ID
 		dataNode.NodeSetID = dnv.NodeSetID
+		dataNode.MaintenanceUntil = dnv.MaintenanceUntil
 		olddn, ok := c.dataNodes.Load(dataNode.Addr)
 		if ok {
 			if olddn.(*DataNode).ID <= dataNode.ID {
@@ -762,6 +783,7 @@ func (c *Cluster) loadMetaNodes() (err error) {
 		metaNode := newMetaNode(mnv.Addr, mnv.ZoneName, c.Name)
 		metaNode.ID = mnv.ID
 		metaNode.NodeSetID = mnv.NodeSetID
+		metaNode.MaintenanceUntil = mnv.MaintenanceUntil
 		oldmn, ok := c.metaNodes.Load(metaNode.Addr)
 		if ok {
 			if oldmn.(*MetaNode).ID <= metaNode.ID {
@@ -866,6 +888,7 @@ func (c *Cluster) loadDataPartitions() (err error) {
 		dp.Peers = dpv.Peers
 		dp.OfflinePeerID = dpv.OfflinePeerID
 		dp.isRecover = dpv.IsRecover
+		dp.ECDataNum, dp.ECParityNum = dpv.ECDataNum, dpv.ECParityNum
 		for _, rv := range dpv.Replicas {
 			if !contains(dp.Hosts, rv.Addr) {
 				continue
@@ -0,0 +1,142 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package master
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// A node is put in maintenance for a bounded window before a planned reboot or repair. Unlike
+// decommission, the partitions stay on the node. During the window, no partition is placed on the
+// node, the missing replicas on it are neither alarmed nor replaced, and the balancers leave it alone.
+// The window is persisted with the node, and the maintenance ends by itself when the window ends.
+
+func (c *Cluster) startDataNodeMaintenance(addr string, durationSec int64) (err error) {
+	var dataNode *DataNode
+	if durationSec <= 0 || durationSec > defaultMaxNodeMaintenanceSec {
+		return fmt.Errorf("duration[%v] should be in (0, %v] seconds", durationSec, defaultMaxNodeMaintenanceSec)
+	}
+	if dataNode, err = c.dataNode(addr); err != nil {
+		return
+	}
+	return c.setDataNodeMaintenance(dataNode, time.Now().Unix()+durationSec)
+}
+
+func (c *Cluster) stopDataNodeMaintenance(addr string) (err error) {
+	var dataNode *DataNode
+	if dataNode, err = c.dataNode(addr); err != nil {
+		return
+	}
+	return c.setDataNodeMaintenance(dataNode, 0)
+}
+
+func (c *Cluster) setDataNodeMaintenance(dataNode *DataNode, until int64) (err error) {
+	c.dnMutex.Lock()
+	defer c.dnMutex.Unlock()
+	dataNode.Lock()
+	oldUntil := dataNode.MaintenanceUntil
+	dataNode.MaintenanceUntil = until
+	dataNode.Unlock()
+	if err = c.syncUpdateDataNode(dataNode); err != nil {
+		dataNode.Lock()
+		dataNode.MaintenanceUntil = oldUntil
+		dataNode.Unlock()
+		return
+	}
+	log.LogWarnf("action[setDataNodeMaintenance] clusterID[%v] dataNode[%v] maintenance until[%v]",
+		c.Name, dataNode.Addr, until)
+	return
+}
+
+func (c *Cluster) startMetaNodeMaintenance(addr string, durationSec int64) (err error) {
+	var metaNode *MetaNode
+	if durationSec <= 0 || durationSec > defaultMaxNodeMaintenanceSec {
+		return fmt.Errorf("duration[%v] should be in (0, %v] seconds", durationSec, defaultMaxNodeMaintenanceSec)
+	}
+	if metaNode, err = c.metaNode(addr); err != nil {
+		return
+	}
+	return c.setMetaNodeMaintenance(metaNode, time.Now().Unix()+durationSec)
+}
+
+func (c *Cluster) stopMetaNodeMaintenance(addr string) (err error) {
+	var metaNode *MetaNode
+	if metaNode, err = c.metaNode(addr); err != nil {
+		return
+	}
+	return c.setMetaNodeMaintenance(metaNode, 0)
+}
+
+func (c *Cluster) setMetaNodeMaintenance(metaNode *MetaNode, until int64) (err error) {
+	c.mnMutex.Lock()
+	defer c.mnMutex.Unlock()
+	metaNode.Lock()
+	oldUntil := metaNode.MaintenanceUntil
+	metaNode.MaintenanceUntil = until
+	metaNode.Unlock()
+	if err = c.syncUpdateMetaNode(metaNode); err != nil {
+		metaNode.Lock()
+		metaNode.MaintenanceUntil = oldUntil
+		metaNode.Unlock()
+		return
+	}
+	log.LogWarnf("action[setMetaNodeMaintenance] clusterID[%v] metaNode[%v] maintenance until[%v]",
+		c.Name, metaNode.Addr, until)
+	return
+}
+
+func (c *Cluster) isDataNodeInMaintenance(addr string) bool {
+	dataNode, err := c.dataNode(addr)
+	return err == nil && dataNode.isInMaintenance()
+}
+
+func (c *Cluster) isMetaNodeInMaintenance(addr string) bool {
+	metaNode, err := c.metaNode(addr)
+	return err == nil && metaNode.isInMaintenance()
+}
+
+// checkDataNodeMaintenance returns an error if the replicas on the data node should not be replaced
+// because the node is in maintenance. The decommission of the node itself is allowed.
+func (c *Cluster) checkDataNodeMaintenance(addr string) (err error) {
+	dataNode, err := c.dataNode(addr)
+	if err != nil {
+		return nil
+	}
+	dataNode.RLock()
+	defer dataNode.RUnlock()
+	if dataNode.inMaintenance() && !dataNode.ToBeOffline {
+		return proto.ErrNodeInMaintenance
+	}
+	return
+}
+
+// checkMetaNodeMaintenance returns an error if the replicas on the meta node should not be replaced
+// because the node is in maintenance. The decommission of the node itself is allowed.
+func (c *Cluster) checkMetaNodeMaintenance(addr string) (err error) {
+	metaNode, err := c.metaNode(addr)
+	if err != nil {
+		return nil
+	}
+	metaNode.RLock()
+	defer metaNode.RUnlock()
+	if metaNode.inMaintenance() && !metaNode.ToBeOffline {
+		return proto.ErrNodeInMaintenance
+	}
+	return
+}
@@ -0,0 +1,76 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package master
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+func TestDataNodeMaintenance(t *testing.T) {
+	addr := "127.0.0.1:9098"
+	addDataServer(addr, DefaultZoneName)
+	server.cluster.checkDataNodeHeartbeat()
+	time.Sleep(5 * time.Second)
+	defer server.cluster.dataNodes.Delete(addr)
+	dataNode, err := server.cluster.dataNode(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	process(fmt.Sprintf("%v%v?addr=%v&duration=600", hostAddr, proto.StartDataNodeMaintenance, addr), t)
+	if !dataNode.isInMaintenance() || dataNode.isWriteAble() || persistedMaintenanceUntil(addr, t) == 0 {
+		t.Fatalf("start maintenance: until(%v) writable(%v)", dataNode.MaintenanceUntil, dataNode.isWriteAble())
+	}
+	// the replicas on the node are not replaced, unless the node is decommissioned
+	if err = server.cluster.checkDataNodeMaintenance(addr); err != proto.ErrNodeInMaintenance {
+		t.Fatalf("replace replica in maintenance: %v", err)
+	}
+	dataNode.ToBeOffline = true
+	if err = server.cluster.checkDataNodeMaintenance(addr); err != nil {
+		t.Fatalf("decommission node in maintenance: %v", err)
+	}
+	dataNode.ToBeOffline = false
+
+	process(fmt.Sprintf("%v%v?addr=%v", hostAddr, proto.StopDataNodeMaintenance, addr), t)
+	if dataNode.isInMaintenance() || !dataNode.isWriteAble() || persistedMaintenanceUntil(addr, t) != 0 {
+		t.Fatalf("stop maintenance: until(%v) writable(%v)", dataNode.MaintenanceUntil, dataNode.isWriteAble())
+	}
+	if err = server.cluster.startDataNodeMaintenance(addr, defaultMaxNodeMaintenanceSec+1); err == nil {
+		t.Fatalf("maintenance longer than %v seconds is started", defaultMaxNodeMaintenanceSec)
+	}
+}
+
+func persistedMaintenanceUntil(addr string, t *testing.T) int64 {
+	result, err := server.cluster.fsm.store.SeekForPrefix([]byte(dataNodePrefix))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, value := range result {
+		dnv := &dataNodeValue{}
+		if err = json.Unmarshal(value, dnv); err != nil {
+			t.Fatal(err)
+		}
+		if dnv.Addr == addr {
+			return dnv.MaintenanceUntil
+		}
+	}
+	t.Fatalf("data node %v is not persisted", addr)
+	return 0
+}
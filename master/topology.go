@@ -1045,6 +1045,41 @@ func (t *topology) allocZonesForDataNode(zoneNum, replicaNum int, excludeZone []
 	return
 }
 
+// allocHostsForECDataNode chooses the hosts of an erasure-coded data partition.
+// The shards are spread over the zones evenly, so that losing a zone loses as
+// few shards as possible. The order of the hosts is the order of the shards.
+func (t *topology) allocHostsForECDataNode(zoneNum, shardNum int, specifiedZone string) (hosts []string, peers []proto.Peer, err error) {
+	var zones []*Zone
+	if specifiedZone != "" {
+		var zone *Zone
+		if zone, err = t.getZone(specifiedZone); err != nil {
+			return
+		}
+		zones = []*Zone{zone}
+	} else {
+		if zoneNum > shardNum {
+			zoneNum = shardNum
+		}
+		if zones, err = t.allocZonesForDataNode(zoneNum, shardNum, nil); err != nil {
+			return
+		}
+	}
+	counts := make([]int, len(zones))
+	for i := 0; i < shardNum; i++ {
+		counts[i%len(zones)]++
+	}
+	for i, zone := range zones {
+		selectedHosts, selectedPeers, e := zone.getAvailDataNodeHosts(nil, hosts, counts[i])
+		if e != nil {
+			return nil, nil, errors.Trace(e, "zone[%v] alloc shards[%v]", zone.name, counts[i])
+		}
+		hosts = append(hosts, selectedHosts...)
+		peers = append(peers, selectedPeers...)
+	}
+	log.LogInfof("action[allocHostsForECDataNode] shardNum[%v],zoneNum[%v],selectedZones[%v],hosts[%v]", shardNum, zoneNum, len(zones), hosts)
+	return
+}
+
 func (ns *nodeSet) dataNodeCount() int {
 	var count int
 	ns.dataNodes.Range(func(key, value interface{}) bool {
@@ -0,0 +1,424 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package master
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/errors"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+var errUpgradeCanceled = errors.New("upgrade is canceled")
+
+// upgrader orchestrates the rolling upgrade of the data nodes or the meta nodes, one node at a time.
+// For each node, it marks the node as upgrading and puts it in maintenance, so that no partition is
+// placed on it and its replicas are not replaced, and transfers the raft leaders on the node to the
+// other replicas. Then it waits for the node to be restarted by
+// the operator and come back healthy with the expected version, before proceeding to the next node.
+// The upgrade stops at the first node which does not come back in time.
+//
+// Like the balancers, the state of the upgrade is kept in the memory of the leader master only.
+type upgrader struct {
+	sync.RWMutex
+	c         *Cluster
+	running   bool
+	canceled  bool
+	config    proto.UpgradeConfig
+	startTime time.Time
+	endTime   time.Time
+	current   string
+	nodes     []*proto.UpgradeNodeTask
+}
+
+func newUpgrader(c *Cluster) *upgrader {
+	return &upgrader{c: c}
+}
+
+func (u *upgrader) start(config proto.UpgradeConfig) (err error) {
+	if config.Role != proto.UpgradeDataNode && config.Role != proto.UpgradeMetaNode {
+		return fmt.Errorf("unknown role[%v]", config.Role)
+	}
+	if config.Version == "" {
+		return keyNotFound(versionKey)
+	}
+	if config.TimeoutSec <= 0 {
+		config.TimeoutSec = defaultUpgradeNodeTimeOutSec
+	}
+	if len(config.Nodes) == 0 {
+		config.Nodes = u.c.upgradeNodeAddrs(config.Role)
+	}
+	for _, addr := range config.Nodes {
+		if _, _, err = u.c.upgradeNodeVersion(config.Role, addr); err != nil {
+			return
+		}
+	}
+	u.Lock()
+	defer u.Unlock()
+	if u.running {
+		return proto.ErrUpgradeRunning
+	}
+	u.running = true
+	u.canceled = false
+	u.config = config
+	u.startTime = time.Now()
+	u.endTime = time.Time{}
+	u.current = ""
+	u.nodes = make([]*proto.UpgradeNodeTask, 0, len(config.Nodes))
+	for _, addr := range config.Nodes {
+		u.nodes = append(u.nodes, &proto.UpgradeNodeTask{Addr: addr, Status: proto.UpgradeNodePending})
+	}
+	log.LogWarnf("action[startUpgrade] role[%v] version[%v] nodes[%v] timeout[%v]",
+		config.Role, config.Version, len(config.Nodes), config.TimeoutSec)
+	go u.run(config, u.nodes)
+	return
+}
+
+// stop cancels the upgrade, and the node being upgraded is not waited for any more.
+// The upgrade keeps running until the node is released.
+func (u *upgrader) stop() (err error) {
+	u.Lock()
+	defer u.Unlock()
+	if !u.running || u.canceled {
+		return proto.ErrUpgradeNotRunning
+	}
+	u.canceled = true
+	log.LogWarnf("action[stopUpgrade] current node[%v]", u.current)
+	return
+}
+
+func (u *upgrader) isRunning() bool {
+	u.RLock()
+	defer u.RUnlock()
+	return u.running
+}
+
+func (u *upgrader) isCanceled() bool {
+	u.RLock()
+	defer u.RUnlock()
+	return u.canceled
+}
+
+func (u *upgrader) status() (status *proto.UpgradeStatus) {
+	u.RLock()
+	defer u.RUnlock()
+	status = &proto.UpgradeStatus{
+		Running: u.running,
+		Config:  u.config,
+		Current: u.current,
+		Nodes:   make([]*proto.UpgradeNodeTask, 0, len(u.nodes)),
+	}
+	if !u.startTime.IsZero() {
+		status.StartTime = u.startTime.Unix()
+	}
+	if !u.endTime.IsZero() {
+		status.EndTime = u.endTime.Unix()
+	}
+	for _, task := range u.nodes {
+		var t = *task
+		status.Nodes = append(status.Nodes, &t)
+	}
+	return
+}
+
+func (u *upgrader) run(config proto.UpgradeConfig, tasks []*proto.UpgradeNodeTask) {
+	defer func() {
+		u.Lock()
+		u.running = false
+		u.current = ""
+		u.endTime = time.Now()
+		u.Unlock()
+	}()
+	for _, task := range tasks {
+		if u.isCanceled() {
+			return
+		}
+		u.Lock()
+		u.current = task.Addr
+		task.StartTime = time.Now().Unix()
+		u.Unlock()
+		if err := u.upgradeNode(config, task); err != nil {
+			u.finish(task, err)
+			return
+		}
+		u.finish(task, nil)
+	}
+}
+
+func (u *upgrader) setTaskStatus(task *proto.UpgradeNodeTask, status string) {
+	u.Lock()
+	defer u.Unlock()
+	task.Status = status
+}
+
+// upgradeNode transfers the raft leaders away from the node, and waits for it to be restarted with
+// the version. The node is skipped if it has been running the version, and fails if any leader is
+// left on it in time, so that it is never restarted with the leaders.
+func (u *upgrader) upgradeNode(config proto.UpgradeConfig, task *proto.UpgradeNodeTask) (err error) {
+	var (
+		c       = u.c
+		version string
+	)
+	if version, _, err = c.upgradeNodeVersion(config.Role, task.Addr); err != nil {
+		return
+	}
+	u.Lock()
+	task.OldVersion = version
+	u.Unlock()
+	if version == config.Version {
+		u.setTaskStatus(task, proto.UpgradeNodeSkipped)
+		return
+	}
+	c.setNodeUpgrading(config.Role, task.Addr, true)
+	defer c.setNodeUpgrading(config.Role, task.Addr, false)
+	// the maintenance set by the operator is kept after the upgrade
+	if !c.isNodeInMaintenance(config.Role, task.Addr) {
+		until := time.Now().Unix() + defaultUpgradeTransferTimeOutSec + config.TimeoutSec
+		if err = c.setNodeMaintenance(config.Role, task.Addr, until); err != nil {
+			return
+		}
+		defer c.setNodeMaintenance(config.Role, task.Addr, 0)
+	}
+
+	u.setTaskStatus(task, proto.UpgradeNodeTransferring)
+	var since = time.Now()
+	for {
+		left := c.transferLeadersFromNode(config.Role, task.Addr)
+		u.Lock()
+		task.Leaders = left
+		u.Unlock()
+		if left == 0 {
+			break
+		}
+		if time.Since(since) > time.Second*defaultUpgradeTransferTimeOutSec {
+			return fmt.Errorf("%v raft leaders are left on the node after %v seconds", left, defaultUpgradeTransferTimeOutSec)
+		}
+		if err = u.checkRunning(); err != nil {
+			return
+		}
+		time.Sleep(time.Second * defaultIntervalToCheckUpgrade)
+	}
+
+	u.setTaskStatus(task, proto.UpgradeNodeWaiting)
+	since = time.Now()
+	for !c.isNodeUpgraded(config.Role, task.Addr, config.Version) {
+		if err = u.checkRunning(); err != nil {
+			return
+		}
+		if time.Since(since) > time.Second*time.Duration(config.TimeoutSec) {
+			return fmt.Errorf("node does not come back with version[%v] in %v seconds", config.Version, config.TimeoutSec)
+		}
+		time.Sleep(time.Second * defaultIntervalToCheckUpgrade)
+	}
+	return
+}
+
+func (u *upgrader) checkRunning() error {
+	if u.c.partition == nil || !u.c.partition.IsRaftLeader() {
+		return fmt.Errorf("leader changed while the node is being upgraded")
+	}
+	if u.isCanceled() {
+		return errUpgradeCanceled
+	}
+	return nil
+}
+
+func (u *upgrader) finish(task *proto.UpgradeNodeTask, err error) {
+	u.Lock()
+	defer u.Unlock()
+	task.EndTime = time.Now().Unix()
+	switch {
+	case err == errUpgradeCanceled:
+		task.Status = proto.UpgradeNodeCanceled
+		log.LogWarnf("action[finishUpgrade] upgrade of %v[%v] is canceled", u.config.Role, task.Addr)
+	case err != nil:
+		task.Status = proto.UpgradeNodeFailed
+		task.Msg = err.Error()
+		Warn(u.c.Name, fmt.Sprintf("clusterID[%v] upgrade %v[%v] to version[%v] failed, err[%v]",
+			u.c.Name, u.config.Role, task.Addr, u.config.Version, err))
+	case task.Status != proto.UpgradeNodeSkipped:
+		task.Status = proto.UpgradeNodeFinished
+		log.LogWarnf("action[finishUpgrade] upgrade %v[%v] from version[%v] to version[%v] successfully",
+			u.config.Role, task.Addr, task.OldVersion, u.config.Version)
+	}
+}
+
+// upgradeNodeAddrs returns the addresses of all the nodes of the role in order.
+func (c *Cluster) upgradeNodeAddrs(role string) (addrs []string) {
+	var nodes = &c.dataNodes
+	if role == proto.UpgradeMetaNode {
+		nodes = &c.metaNodes
+	}
+	nodes.Range(func(key, value interface{}) bool {
+		addrs = append(addrs, key.(string))
+		return true
+	})
+	sort.Strings(addrs)
+	return
+}
+
+func (c *Cluster) upgradeNodeVersion(role, addr string) (version string, active bool, err error) {
+	if role == proto.UpgradeMetaNode {
+		var metaNode *MetaNode
+		if metaNode, err = c.metaNode(addr); err != nil {
+			return
+		}
+		metaNode.RLock()
+		defer metaNode.RUnlock()
+		return metaNode.Version, metaNode.IsActive, nil
+	}
+	var dataNode *DataNode
+	if dataNode, err = c.dataNode(addr); err != nil {
+		return
+	}
+	dataNode.RLock()
+	defer dataNode.RUnlock()
+	return dataNode.Version, dataNode.isActive, nil
+}
+
+func (c *Cluster) setNodeUpgrading(role, addr string, upgrading bool) {
+	if role == proto.UpgradeMetaNode {
+		if metaNode, err := c.metaNode(addr); err == nil {
+			metaNode.Lock()
+			metaNode.Upgrading = upgrading
+			metaNode.Unlock()
+		}
+		return
+	}
+	if dataNode, err := c.dataNode(addr); err == nil {
+		dataNode.Lock()
+		dataNode.Upgrading = upgrading
+		dataNode.Unlock()
+	}
+}
+
+func (c *Cluster) isNodeInMaintenance(role, addr string) bool {
+	if role == proto.UpgradeMetaNode {
+		return c.isMetaNodeInMaintenance(addr)
+	}
+	return c.isDataNodeInMaintenance(addr)
+}
+
+func (c *Cluster) setNodeMaintenance(role, addr string, until int64) (err error) {
+	if role == proto.UpgradeMetaNode {
+		var metaNode *MetaNode
+		if metaNode, err = c.metaNode(addr); err != nil {
+			return
+		}
+		return c.setMetaNodeMaintenance(metaNode, until)
+	}
+	var dataNode *DataNode
+	if dataNode, err = c.dataNode(addr); err != nil {
+		return
+	}
+	return c.setDataNodeMaintenance(dataNode, until)
+}
+
+// transferLeadersFromNode asks the other replicas of the partitions led by the node to become the
+// leaders, and returns the number of the partitions still led by the node.
+func (c *Cluster) transferLeadersFromNode(role, addr string) (left int) {
+	if role == proto.UpgradeMetaNode {
+		for _, mp := range c.getAllMetaPartitionByMetaNode(addr) {
+			mp.RLock()
+			leader, err := mp.getMetaReplicaLeader()
+			hosts := append([]string(nil), mp.Hosts...)
+			mp.RUnlock()
+			if err != nil || leader.Addr != addr {
+				continue
+			}
+			left++
+			for _, host := range hosts {
+				metaNode, err := c.metaNode(host)
+				if err != nil || host == addr || !c.isLeaderCandidate(role, host) {
+					continue
+				}
+				if err = mp.tryToChangeLeader(c, metaNode); err != nil {
+					log.LogWarnf("action[transferLeadersFromNode] mp[%v] to[%v] err[%v]", mp.PartitionID, host, err)
+					continue
+				}
+				break
+			}
+		}
+		return
+	}
+	for _, dp := range c.getAllDataPartitionByDataNode(addr) {
+		if dp.getLeaderAddrWithLock() != addr {
+			continue
+		}
+		left++
+		dp.RLock()
+		hosts := append([]string(nil), dp.Hosts...)
+		dp.RUnlock()
+		for _, host := range hosts {
+			dataNode, err := c.dataNode(host)
+			if err != nil || host == addr || !c.isLeaderCandidate(role, host) {
+				continue
+			}
+			if err = dp.tryToChangeLeader(c, dataNode); err != nil {
+				log.LogWarnf("action[transferLeadersFromNode] dp[%v] to[%v] err[%v]", dp.PartitionID, host, err)
+				continue
+			}
+			break
+		}
+	}
+	return
+}
+
+// isLeaderCandidate returns true if the node is active and not being upgraded.
+func (c *Cluster) isLeaderCandidate(role, addr string) bool {
+	if role == proto.UpgradeMetaNode {
+		metaNode, err := c.metaNode(addr)
+		if err != nil {
+			return false
+		}
+		metaNode.RLock()
+		defer metaNode.RUnlock()
+		return metaNode.IsActive && !metaNode.Upgrading
+	}
+	dataNode, err := c.dataNode(addr)
+	if err != nil {
+		return false
+	}
+	dataNode.RLock()
+	defer dataNode.RUnlock()
+	return dataNode.isActive && !dataNode.Upgrading
+}
+
+// isNodeUpgraded returns true if the node is active with the version, and has reported all its
+// partitions.
+func (c *Cluster) isNodeUpgraded(role, addr, version string) bool {
+	if role == proto.UpgradeMetaNode {
+		var partitions = len(c.getAllMetaPartitionByMetaNode(addr))
+		metaNode, err := c.metaNode(addr)
+		if err != nil {
+			return false
+		}
+		metaNode.RLock()
+		defer metaNode.RUnlock()
+		return metaNode.IsActive && metaNode.Version == version && metaNode.MetaPartitionCount >= partitions
+	}
+	var partitions = len(c.getAllDataPartitionByDataNode(addr))
+	dataNode, err := c.dataNode(addr)
+	if err != nil {
+		return false
+	}
+	dataNode.RLock()
+	defer dataNode.RUnlock()
+	return dataNode.isActive && dataNode.Version == version && len(dataNode.DataPartitionReports) >= partitions
+}
@@ -0,0 +1,65 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package master
+
+import (
+	"testing"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+func TestUpgrade(t *testing.T) {
+	addr := "127.0.0.1:9097"
+	addDataServer(addr, DefaultZoneName)
+	server.cluster.checkDataNodeHeartbeat()
+	time.Sleep(5 * time.Second)
+	defer server.cluster.dataNodes.Delete(addr)
+	dataNode, err := server.cluster.dataNode(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u := server.cluster.upgrader
+	config := proto.UpgradeConfig{Role: proto.UpgradeDataNode, Version: "upgrade-test", Nodes: []string{addr}}
+	if err = u.start(config); err != nil {
+		t.Fatalf("start upgrade: %v", err)
+	}
+	if err = u.start(config); err != proto.ErrUpgradeRunning {
+		t.Fatalf("start upgrade again: %v", err)
+	}
+	// the node without leaders waits for the restart, and no partition is placed on it meanwhile
+	for i := 0; i < 10 && u.status().Nodes[0].Status != proto.UpgradeNodeWaiting; i++ {
+		time.Sleep(time.Second)
+	}
+	if status := u.status(); status.Current != addr || status.Nodes[0].Status != proto.UpgradeNodeWaiting ||
+		dataNode.isWriteAble() {
+		t.Fatalf("upgrading node: %v writable(%v)", status.Nodes[0], dataNode.isWriteAble())
+	}
+
+	if err = u.stop(); err != nil {
+		t.Fatalf("stop upgrade: %v", err)
+	}
+	for i := 0; i < 2*defaultIntervalToCheckUpgrade && u.isRunning(); i++ {
+		time.Sleep(time.Second)
+	}
+	if status := u.status(); status.Running || status.Nodes[0].Status != proto.UpgradeNodeCanceled ||
+		!dataNode.isWriteAble() {
+		t.Fatalf("canceled node: %v writable(%v)", status.Nodes[0], dataNode.isWriteAble())
+	}
+	if err = u.stop(); err != proto.ErrUpgradeNotRunning {
+		t.Fatalf("stop upgrade again: %v", err)
+	}
+}
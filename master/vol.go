@@ -35,6 +35,7 @@ type VolVarargs struct {
 	authenticate   bool
 	dpSelectorName string
 	dpSelectorParm string
+	trashDays      uint32
 }
 
 // Vol represents a set of meta partitionMap and data partitionMap
@@ -68,6 +69,14 @@ type Vol struct {
 	description        string
 	dpSelectorName     string
 	dpSelectorParm     string
+	trashDays          uint32 // days to keep the deleted files in trash, 0 means disabled
+	ecDataNum          uint8  // number of data shards if the data partitions are erasure-coded
+	ecParityNum        uint8
+	snapshots          []*proto.SnapshotInfo
+	snapshotMutex      sync.Mutex
+	creatingSnapshot   int32 // set while creating a snapshot, accessed atomically
+	quotas             map[uint64]*proto.QuotaInfo
+	quotaMutex         sync.RWMutex
 	sync.RWMutex
 }
 
@@ -129,6 +138,13 @@ func newVolFromVolValue(vv *volValue) (vol *Vol) {
 	vol.Status = vv.Status
 	vol.dpSelectorName = vv.DpSelectorName
 	vol.dpSelectorParm = vv.DpSelectorParm
+	vol.trashDays = vv.TrashDays
+	vol.ecDataNum, vol.ecParityNum = vv.ECDataNum, vv.ECParityNum
+	vol.snapshots = vv.Snapshots
+	vol.quotas = make(map[uint64]*proto.QuotaInfo, len(vv.Quotas))
+	for _, quota := range vv.Quotas {
+		vol.quotas[quota.QuotaID] = quota
+	}
 	return vol
 }
 
@@ -159,17 +175,39 @@ func (vol *Vol) metaPartition(partitionID uint64) (mp *MetaPartition, err error)
 	return
 }
 
+// deleteMetaPartition removes the partition merged into another one.
+func (vol *Vol) deleteMetaPartition(partitionID uint64) {
+	vol.mpsLock.Lock()
+	defer vol.mpsLock.Unlock()
+	delete(vol.MetaPartitions, partitionID)
+}
+
+// maxPartitionID returns the ID of the last meta partition, which has the greatest start. It is
+// also the one with the greatest ID, unless the partitions have been re-split.
 func (vol *Vol) maxPartitionID() (maxPartitionID uint64) {
 	vol.mpsLock.RLock()
 	defer vol.mpsLock.RUnlock()
-	for id := range vol.MetaPartitions {
-		if id > maxPartitionID {
-			maxPartitionID = id
+	var maxStart uint64
+	for id, mp := range vol.MetaPartitions {
+		if maxPartitionID == 0 || mp.Start > maxStart {
+			maxPartitionID, maxStart = id, mp.Start
 		}
 	}
 	return
 }
 
+// nextMetaPartition returns the partition whose range follows the one of mp.
+func (vol *Vol) nextMetaPartition(mp *MetaPartition) (next *MetaPartition, err error) {
+	vol.mpsLock.RLock()
+	defer vol.mpsLock.RUnlock()
+	for _, partition := range vol.MetaPartitions {
+		if partition.Start == mp.End+1 {
+			return partition, nil
+		}
+	}
+	return nil, proto.ErrNoAdjacentMetaPartition
+}
+
 func (vol *Vol) getDataPartitionsView() (body []byte, err error) {
 	return vol.dataPartitions.updateResponseCache(false, 0)
 }
@@ -226,7 +264,7 @@ func (vol *Vol) checkDataPartitions(c *Cluster) (cnt int) {
 		dp.checkReplicaStatus(c.cfg.DataPartitionTimeOutSec)
 		dp.checkStatus(c.Name, true, c.cfg.DataPartitionTimeOutSec)
 		dp.checkLeader(c.cfg.DataPartitionTimeOutSec)
-		dp.checkMissingReplicas(c.Name, c.leaderInfo.addr, c.cfg.MissingDataPartitionInterval, c.cfg.IntervalToAlarmMissingDataPartition)
+		dp.checkMissingReplicas(c, c.cfg.MissingDataPartitionInterval, c.cfg.IntervalToAlarmMissingDataPartition)
 		dp.checkReplicaNum(c, vol)
 		if dp.Status == proto.ReadWrite {
 			cnt++
@@ -304,7 +342,7 @@ func (vol *Vol) checkMetaPartitions(c *Cluster) {
 		mp.checkLeader()
 		mp.checkReplicaNum(c, vol.Name, vol.mpReplicaNum)
 		mp.checkEnd(c, maxPartitionID)
-		mp.reportMissingReplicas(c.Name, c.leaderInfo.addr, defaultMetaPartitionTimeOutSec, defaultIntervalToAlarmMissingMetaPartition)
+		mp.reportMissingReplicas(c, defaultMetaPartitionTimeOutSec, defaultIntervalToAlarmMissingMetaPartition)
 		tasks = append(tasks, mp.replicaCreationTasks(c.Name, vol.Name)...)
 	}
 	c.addMetaNodeTasks(tasks)
@@ -702,6 +740,10 @@ func (vol *Vol) splitMetaPartition(c *Cluster, mp *MetaPartition, end uint64) (e
 	}
 	vol.createMpMutex.Lock()
 	defer vol.createMpMutex.Unlock()
+	if c.metaMover.isMoving(vol.Name) {
+		err = fmt.Errorf("vol[%v] %v", vol.Name, proto.ErrMetaMoveRunning)
+		return
+	}
 	maxPartitionID := vol.maxPartitionID()
 	if maxPartitionID != mp.PartitionID {
 		err = fmt.Errorf("mp[%v] is not the last meta partition[%v]", mp.PartitionID, maxPartitionID)
@@ -812,5 +854,6 @@ func getVolVarargs(vol *Vol) *VolVarargs {
 		authenticate:   vol.authenticate,
 		dpSelectorName: vol.dpSelectorName,
 		dpSelectorParm: vol.dpSelectorParm,
+		trashDays:      vol.trashDays,
 	}
 }
@@ -0,0 +1,193 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package master
+
+import (
+	"sort"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+func (vol *Vol) getQuotaByTarget(quotaType proto.QuotaType, target uint64) *proto.QuotaInfo {
+	for _, quota := range vol.quotas {
+		if quota.Type == quotaType && quota.Target == target {
+			return quota
+		}
+	}
+	return nil
+}
+
+func (vol *Vol) getQuota(quotaID uint64) (quota *proto.QuotaInfo, err error) {
+	vol.quotaMutex.RLock()
+	defer vol.quotaMutex.RUnlock()
+	q, ok := vol.quotas[quotaID]
+	if !ok {
+		return nil, proto.ErrQuotaNotExists
+	}
+	info := *q
+	return &info, nil
+}
+
+// cloneQuotas returns the quotas ordered by ID.
+func (vol *Vol) cloneQuotas() []*proto.QuotaInfo {
+	vol.quotaMutex.RLock()
+	defer vol.quotaMutex.RUnlock()
+	quotas := make([]*proto.QuotaInfo, 0, len(vol.quotas))
+	for _, q := range vol.quotas {
+		info := *q
+		quotas = append(quotas, &info)
+	}
+	sort.Slice(quotas, func(i, j int) bool { return quotas[i].QuotaID < quotas[j].QuotaID })
+	return quotas
+}
+
+func (vol *Vol) dirQuotaCount() (count int) {
+	vol.quotaMutex.RLock()
+	defer vol.quotaMutex.RUnlock()
+	for _, q := range vol.quotas {
+		if q.Type == proto.QuotaTypeDir {
+			count++
+		}
+	}
+	return
+}
+
+// updateQuotaUsage sums up the usages reported by the leaders of the meta
+// partitions, and returns the quotas to be sent to the meta nodes.
+func (vol *Vol) updateQuotaUsage() (infos []*proto.QuotaHeartBeatInfo) {
+	vol.quotaMutex.RLock()
+	count := len(vol.quotas)
+	vol.quotaMutex.RUnlock()
+	if count == 0 {
+		return
+	}
+	usages := make(map[uint64]*proto.QuotaUsage)
+	for _, mp := range vol.cloneMetaPartitionMap() {
+		for _, u := range mp.getQuotaUsages() {
+			usage, ok := usages[u.QuotaID]
+			if !ok {
+				usage = &proto.QuotaUsage{QuotaID: u.QuotaID}
+				usages[u.QuotaID] = usage
+			}
+			usage.UsedBytes += u.UsedBytes
+			usage.UsedInodes += u.UsedInodes
+		}
+	}
+	vol.quotaMutex.Lock()
+	defer vol.quotaMutex.Unlock()
+	for _, q := range vol.quotas {
+		q.UsedBytes, q.UsedInodes = 0, 0
+		if usage, ok := usages[q.QuotaID]; ok {
+			q.UsedBytes, q.UsedInodes = usage.UsedBytes, usage.UsedInodes
+		}
+		infos = append(infos, &proto.QuotaHeartBeatInfo{
+			VolName:        vol.Name,
+			QuotaID:        q.QuotaID,
+			Type:           q.Type,
+			Target:         q.Target,
+			BytesExceeded:  q.BytesExceeded(),
+			InodesExceeded: q.InodesExceeded(),
+		})
+	}
+	return
+}
+
+// copyQuotas returns a copy of the quota map. The quota map is replaced
+// instead of being updated in place, so that it can be read without lock
+// while persisting the volume.
+func (vol *Vol) copyQuotas() map[uint64]*proto.QuotaInfo {
+	quotas := make(map[uint64]*proto.QuotaInfo, len(vol.quotas)+1)
+	for id, q := range vol.quotas {
+		quotas[id] = q
+	}
+	return quotas
+}
+
+// setQuota creates the quota of the target, or updates the limits of it if
+// the quota already exists.
+func (c *Cluster) setQuota(volName, authKey string, quotaType proto.QuotaType, target, maxBytes, maxInodes uint64) (quota *proto.QuotaInfo, err error) {
+	var vol *Vol
+	if vol, err = c.getVol(volName); err != nil {
+		return
+	}
+	if !matchKey(vol.Owner, authKey) {
+		err = proto.ErrVolAuthKeyNotMatch
+		return
+	}
+	vol.quotaMutex.Lock()
+	defer vol.quotaMutex.Unlock()
+	if old := vol.getQuotaByTarget(quotaType, target); old != nil {
+		info := *old
+		quota = &info
+	} else {
+		var id uint64
+		if id, err = c.idAlloc.allocateCommonID(); err != nil {
+			return
+		}
+		quota = &proto.QuotaInfo{QuotaID: id, Type: quotaType, Target: target, CreateTime: time.Now().Unix()}
+	}
+	quota.MaxBytes, quota.MaxInodes = maxBytes, maxInodes
+	oldQuotas := vol.quotas
+	quotas := vol.copyQuotas()
+	quotas[quota.QuotaID] = quota
+	vol.quotas = quotas
+	if err = c.syncUpdateVol(vol); err != nil {
+		vol.quotas = oldQuotas
+		return
+	}
+	log.LogInfof("action[setQuota] vol[%v] quota[%v] type[%v] target[%v] maxBytes[%v] maxInodes[%v]",
+		vol.Name, quota.QuotaID, quotaType, target, maxBytes, maxInodes)
+	info := *quota
+	return &info, nil
+}
+
+func (c *Cluster) deleteQuota(volName, authKey string, quotaID uint64) (err error) {
+	var vol *Vol
+	if vol, err = c.getVol(volName); err != nil {
+		return
+	}
+	if !matchKey(vol.Owner, authKey) {
+		return proto.ErrVolAuthKeyNotMatch
+	}
+	vol.quotaMutex.Lock()
+	defer vol.quotaMutex.Unlock()
+	if _, ok := vol.quotas[quotaID]; !ok {
+		return proto.ErrQuotaNotExists
+	}
+	oldQuotas := vol.quotas
+	quotas := vol.copyQuotas()
+	delete(quotas, quotaID)
+	vol.quotas = quotas
+	if err = c.syncUpdateVol(vol); err != nil {
+		vol.quotas = oldQuotas
+		return
+	}
+	log.LogInfof("action[deleteQuota] vol[%v] quota[%v]", vol.Name, quotaID)
+	return
+}
+
+// quotaHeartBeatInfos returns the quotas of all the volumes, which are sent
+// to the meta nodes to reject the requests exceeding the limits.
+func (c *Cluster) quotaHeartBeatInfos() (infos []*proto.QuotaHeartBeatInfo) {
+	for _, vol := range c.copyVols() {
+		if vol.Status == markDelete {
+			continue
+		}
+		infos = append(infos, vol.updateQuotaUsage()...)
+	}
+	return
+}
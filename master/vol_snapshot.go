@@ -0,0 +1,202 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package master
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/errors"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// defaultMaxSnapshotsPerVol is the max number of snapshots a volume keeps.
+const defaultMaxSnapshotsPerVol = 16
+
+func (vol *Vol) getSnapshot(name string) *proto.SnapshotInfo {
+	for _, snap := range vol.snapshots {
+		if snap.Name == name {
+			return snap
+		}
+	}
+	return nil
+}
+
+func (vol *Vol) cloneSnapshots() []*proto.SnapshotInfo {
+	vol.snapshotMutex.Lock()
+	defer vol.snapshotMutex.Unlock()
+	snapshots := make([]*proto.SnapshotInfo, 0, len(vol.snapshots))
+	for _, snap := range vol.snapshots {
+		info := *snap
+		snapshots = append(snapshots, &info)
+	}
+	return snapshots
+}
+
+// createSnapshot creates the snapshot on every meta partition of the volume.
+// The meta partitions are not split while creating, and the snapshot created
+// on some of the meta partitions is rolled back if any of them fails.
+func (c *Cluster) createSnapshot(volName, authKey, name string) (snap *proto.SnapshotInfo, err error) {
+	var vol *Vol
+	if vol, err = c.getVol(volName); err != nil {
+		return
+	}
+	if !matchKey(vol.Owner, authKey) {
+		err = proto.ErrVolAuthKeyNotMatch
+		return
+	}
+	vol.createMpMutex.Lock()
+	defer vol.createMpMutex.Unlock()
+	vol.snapshotMutex.Lock()
+	defer vol.snapshotMutex.Unlock()
+	if vol.getSnapshot(name) != nil {
+		err = proto.ErrDuplicateSnapshot
+		return
+	}
+	if len(vol.snapshots) >= defaultMaxSnapshotsPerVol {
+		err = proto.ErrTooManySnapshots
+		return
+	}
+	// the data nodes stop overwriting the extents of the volume in place
+	// before the extents are referenced by the snapshot
+	atomic.StoreInt32(&vol.creatingSnapshot, 1)
+	defer atomic.StoreInt32(&vol.creatingSnapshot, 0)
+	if err = c.syncSendSnapshotVols(); err != nil {
+		err = errors.NewErrorf("create snapshot[%v] of vol[%v] failed: %v", name, vol.Name, err)
+		return
+	}
+	var id uint64
+	if id, err = c.idAlloc.allocateCommonID(); err != nil {
+		return
+	}
+	snap = &proto.SnapshotInfo{ID: id, Name: name, CreateTime: time.Now().Unix()}
+	created := make([]*MetaPartition, 0)
+	for _, mp := range vol.cloneMetaPartitionMap() {
+		if err = c.syncSendMetaSnapshotTask(proto.OpCreateMetaSnapshot, vol.Name, mp, id); err != nil {
+			break
+		}
+		created = append(created, mp)
+	}
+	if err == nil {
+		vol.snapshots = append(vol.snapshots, snap)
+		if err = c.syncUpdateVol(vol); err != nil {
+			vol.snapshots = vol.snapshots[:len(vol.snapshots)-1]
+		}
+	}
+	if err != nil {
+		for _, mp := range created {
+			if rollbackErr := c.syncSendMetaSnapshotTask(proto.OpDeleteMetaSnapshot, vol.Name, mp, id); rollbackErr != nil {
+				log.LogWarnf("action[createSnapshot] vol[%v] snapshot[%v] rollback mp[%v] err[%v]",
+					vol.Name, name, mp.PartitionID, rollbackErr)
+			}
+		}
+		err = errors.NewErrorf("create snapshot[%v] of vol[%v] failed: %v", name, vol.Name, err)
+		return
+	}
+	log.LogInfof("action[createSnapshot] vol[%v] snapshot[%v] id[%v]", vol.Name, name, id)
+	return
+}
+
+// deleteSnapshot deletes the snapshot from every meta partition of the
+// volume. The snapshot is kept if any of them fails, so that it can be
+// deleted again.
+func (c *Cluster) deleteSnapshot(volName, authKey, name string) (err error) {
+	var vol *Vol
+	if vol, err = c.getVol(volName); err != nil {
+		return
+	}
+	if !matchKey(vol.Owner, authKey) {
+		return proto.ErrVolAuthKeyNotMatch
+	}
+	vol.snapshotMutex.Lock()
+	defer vol.snapshotMutex.Unlock()
+	snap := vol.getSnapshot(name)
+	if snap == nil {
+		return proto.ErrSnapshotNotExists
+	}
+	for _, mp := range vol.cloneMetaPartitionMap() {
+		if err = c.syncSendMetaSnapshotTask(proto.OpDeleteMetaSnapshot, vol.Name, mp, snap.ID); err != nil {
+			return errors.NewErrorf("delete snapshot[%v] of vol[%v] failed: %v", name, vol.Name, err)
+		}
+	}
+	snapshots := make([]*proto.SnapshotInfo, 0, len(vol.snapshots))
+	for _, s := range vol.snapshots {
+		if s.ID != snap.ID {
+			snapshots = append(snapshots, s)
+		}
+	}
+	oldSnapshots := vol.snapshots
+	vol.snapshots = snapshots
+	if err = c.syncUpdateVol(vol); err != nil {
+		vol.snapshots = oldSnapshots
+		return
+	}
+	log.LogInfof("action[deleteSnapshot] vol[%v] snapshot[%v] id[%v]", vol.Name, name, snap.ID)
+	return
+}
+
+// snapshotVols returns the volumes with snapshots or creating a snapshot,
+// whose extents are not overwritten in place by the data nodes. The version
+// is taken before the volumes, so that the data nodes can drop the older ones.
+func (c *Cluster) snapshotVols() (vols []string, ver int64) {
+	ver = time.Now().UnixNano()
+	vols = make([]string, 0)
+	for _, vol := range c.copyVols() {
+		if atomic.LoadInt32(&vol.creatingSnapshot) > 0 || len(vol.snapshots) > 0 {
+			vols = append(vols, vol.Name)
+		}
+	}
+	return
+}
+
+// syncSendSnapshotVols sends the volumes with snapshots to the active data
+// nodes and waits for them. An inactive data node receives them with the
+// heartbeat, and overwrites no extent in place until then.
+func (c *Cluster) syncSendSnapshotVols() (err error) {
+	vols, ver := c.snapshotVols()
+	c.dataNodes.Range(func(addr, node interface{}) bool {
+		dataNode := node.(*DataNode)
+		if !dataNode.isActive {
+			return true
+		}
+		task := dataNode.createHeartbeatTask(c.masterAddr(), vols, ver)
+		if _, err = dataNode.TaskManager.syncSendAdminTask(task); err != nil {
+			err = fmt.Errorf("dataNode[%v] %v", dataNode.Addr, err)
+			return false
+		}
+		return true
+	})
+	return
+}
+
+func (c *Cluster) syncSendMetaSnapshotTask(opcode uint8, volName string, mp *MetaPartition, id uint64) (err error) {
+	var (
+		mr       *MetaReplica
+		metaNode *MetaNode
+	)
+	if mr, err = mp.getMetaReplicaLeader(); err != nil {
+		return fmt.Errorf("mp[%v] %v", mp.PartitionID, err)
+	}
+	if metaNode, err = c.metaNode(mr.Addr); err != nil {
+		return
+	}
+	req := &proto.MetaSnapshotRequest{PartitionID: mp.PartitionID, VolName: volName, SnapshotID: id}
+	task := proto.NewAdminTask(opcode, mr.Addr, req)
+	resetMetaPartitionTaskID(task, mp.PartitionID)
+	_, err = metaNode.Sender.syncSendAdminTask(task)
+	return
+}
@@ -241,3 +241,75 @@ func TestConcurrentReadWriteDataPartitionMap(t *testing.T) {
 		vol.updateViewCache(server.cluster)
 	}
 }
+
+func TestVolSnapshot(t *testing.T) {
+	server.cluster.checkMetaNodeHeartbeat()
+	time.Sleep(2 * time.Second)
+	vol, err := server.cluster.getVol(commonVolName)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	authKey := buildAuthKey(vol.Owner)
+	reqURL := fmt.Sprintf("%v%v?name=%v&authKey=%v&snapshot=%v",
+		hostAddr, proto.AdminCreateSnapshot, vol.Name, authKey, "snap1")
+	process(reqURL, t)
+	if len(vol.cloneSnapshots()) != 1 {
+		t.Errorf("create snapshot failed, snapshots %v", vol.cloneSnapshots())
+		return
+	}
+	if _, err = server.cluster.createSnapshot(vol.Name, authKey, "snap1"); err != proto.ErrDuplicateSnapshot {
+		t.Errorf("create duplicate snapshot, err %v", err)
+	}
+	// the data nodes do not overwrite the extents of the volume in place
+	if vols, _ := server.cluster.snapshotVols(); len(vols) != 1 || vols[0] != vol.Name {
+		t.Errorf("snapshot vols %v", vols)
+	}
+	reqURL = fmt.Sprintf("%v%v?name=%v", hostAddr, proto.AdminListSnapshots, vol.Name)
+	process(reqURL, t)
+	reqURL = fmt.Sprintf("%v%v?name=%v&authKey=%v&snapshot=%v",
+		hostAddr, proto.AdminDeleteSnapshot, vol.Name, authKey, "snap1")
+	process(reqURL, t)
+	if len(vol.cloneSnapshots()) != 0 {
+		t.Errorf("delete snapshot failed, snapshots %v", vol.cloneSnapshots())
+	}
+	if vols, _ := server.cluster.snapshotVols(); len(vols) != 0 {
+		t.Errorf("snapshot vols after deleting the snapshot %v", vols)
+	}
+}
+
+func TestVolQuota(t *testing.T) {
+	vol, err := server.cluster.getVol(commonVolName)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	authKey := buildAuthKey(vol.Owner)
+	reqURL := fmt.Sprintf("%v%v?name=%v&authKey=%v&type=%v&target=%v&maxBytes=%v&maxInodes=%v",
+		hostAddr, proto.AdminSetQuota, vol.Name, authKey, "uid", 100, util.GB, 1000)
+	process(reqURL, t)
+	quota := vol.getQuotaByTarget(proto.QuotaTypeUid, 100)
+	if quota == nil || quota.MaxBytes != util.GB || quota.MaxInodes != 1000 {
+		t.Errorf("set quota failed, quota %v", quota)
+		return
+	}
+	// update the limits of the existing quota
+	if _, err = server.cluster.setQuota(vol.Name, authKey, proto.QuotaTypeUid, 100, 0, 2000); err != nil {
+		t.Error(err)
+		return
+	}
+	if quotas := vol.cloneQuotas(); len(quotas) != 1 || quotas[0].MaxBytes != 0 || quotas[0].MaxInodes != 2000 {
+		t.Errorf("update quota failed, quotas %v", quotas)
+		return
+	}
+	reqURL = fmt.Sprintf("%v%v?name=%v", hostAddr, proto.AdminListQuotas, vol.Name)
+	process(reqURL, t)
+	reqURL = fmt.Sprintf("%v%v?name=%v&quotaId=%v", hostAddr, proto.AdminGetQuota, vol.Name, quota.QuotaID)
+	process(reqURL, t)
+	reqURL = fmt.Sprintf("%v%v?name=%v&authKey=%v&quotaId=%v",
+		hostAddr, proto.AdminDeleteQuota, vol.Name, authKey, quota.QuotaID)
+	process(reqURL, t)
+	if _, err = vol.getQuota(quota.QuotaID); err != proto.ErrQuotaNotExists {
+		t.Errorf("delete quota failed, err %v", err)
+	}
+}
@@ -61,6 +61,10 @@ type (
 	ReadDirReq = proto.ReadDirRequest
 	// MetaNode -> Client read dir response
 	ReadDirResp = proto.ReadDirResponse
+	// Client -> MetaNode read dir in range request
+	ReadDirPlusReq = proto.ReadDirPlusRequest
+	// MetaNode -> Client read dir in range response
+	ReadDirPlusResp = proto.ReadDirPlusResponse
 	// MetaNode -> Client lookup
 	LookupReq = proto.LookupRequest
 	// Client -> MetaNode lookup
@@ -119,6 +123,30 @@ const (
 	opFSMEvictInodeBatch
 
 	opFSMExtentsAddWithCheck
+
+	opFSMSetLock
+	opFSMRenewLock
+
+	opFSMTxPrepare
+	opFSMTxCommit
+	opFSMTxAbort
+	opFSMTxPushed
+	opFSMTxRemove
+
+	opFSMCreateMetaSnapshot
+	opFSMDeleteMetaSnapshot
+	opFSMMetaSnapshotItem
+
+	opFSMSetDirSummary
+
+	opFSMPutObjectVersion
+	opFSMDeleteObjectVersion
+
+	opFSMFreezePartition
+	opFSMApplyMovedItems
+	opFSMTrimPartition
+
+	opFSMCreateInodeInQuotas
 )
 
 var (
@@ -128,6 +156,8 @@ var (
 var (
 	ErrNoLeader   = errors.New("no leader")
 	ErrNotALeader = errors.New("not a leader")
+
+	ErrPartitionFrozen = errors.New("partition is frozen")
 )
 
 // Default configuration
@@ -135,6 +165,9 @@ const (
 	defaultMetadataDir = "metadataDir"
 	defaultRaftDir     = "raftDir"
 	defaultAuthTimeout = 5 // seconds
+
+	// max number of the dentries returned by a read dir in range request
+	defaultReadDirPlusLimit = 10000
 )
 
 // Configuration keys
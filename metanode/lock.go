@@ -0,0 +1,321 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/btree"
+)
+
+// FileLock defines a lock held on an inode. A lock lives until it is released
+// by its owner or its lease expires, which happens when the client holding it
+// stops renewing.
+type FileLock struct {
+	proto.LockInfo
+	Expire int64 // unix nano
+}
+
+func (l *FileLock) expired(now int64) bool {
+	return l.Expire <= now
+}
+
+func (l *FileLock) sameOwner(o *proto.LockInfo) bool {
+	return l.Client == o.Client && l.Owner == o.Owner && l.Flock == o.Flock
+}
+
+// conflict returns true if the lock prevents the given lock from being acquired.
+// POSIX locks and flocks do not interact with each other.
+func (l *FileLock) conflict(o *proto.LockInfo) bool {
+	if l.Flock != o.Flock || (l.Client == o.Client && l.Owner == o.Owner) {
+		return false
+	}
+	if l.Type != proto.LockWrite && o.Type != proto.LockWrite {
+		return false
+	}
+	if !l.Flock && (l.End < o.Start || o.End < l.Start) {
+		return false
+	}
+	return true
+}
+
+// InodeLocks defines all of the locks held on an inode. It is immutable once
+// inserted into the lock tree, every change builds a new one.
+type InodeLocks struct {
+	inode uint64
+	locks []*FileLock
+}
+
+// NewInodeLocks returns a new InodeLocks.
+func NewInodeLocks(inode uint64) *InodeLocks {
+	return &InodeLocks{inode: inode}
+}
+
+func (il *InodeLocks) Less(than btree.Item) bool {
+	o, is := than.(*InodeLocks)
+	return is && il.inode < o.inode
+}
+
+func (il *InodeLocks) Copy() btree.Item {
+	return &InodeLocks{
+		inode: il.inode,
+		locks: append([]*FileLock{}, il.locks...),
+	}
+}
+
+func (il *InodeLocks) String() string {
+	return fmt.Sprintf("InodeLocks{inode(%v) locks(%v)}", il.inode, len(il.locks))
+}
+
+// Conflict returns the first unexpired lock which conflicts with the given one.
+func (il *InodeLocks) Conflict(lock *proto.LockInfo, now int64) *FileLock {
+	for _, l := range il.locks {
+		if !l.expired(now) && l.conflict(lock) {
+			return l
+		}
+	}
+	return nil
+}
+
+// Apply returns the locks after acquiring or releasing the given lock by its
+// owner, like what fcntl(2) and flock(2) do on a local file system.
+// The locks of the owner in the range are replaced, and adjacent POSIX locks
+// of the same type are merged.
+func (il *InodeLocks) Apply(lock *proto.LockInfo, expire, now int64) *InodeLocks {
+	res := NewInodeLocks(il.inode)
+	var owned []*FileLock
+	for _, l := range il.locks {
+		switch {
+		case l.expired(now):
+		case !l.sameOwner(lock):
+			res.locks = append(res.locks, l)
+		case lock.Flock:
+			// an owner holds one flock at most
+		default:
+			owned = append(owned, l)
+		}
+	}
+	if !lock.Flock {
+		owned = unlockRange(owned, lock.Start, lock.End)
+	}
+	if lock.Type != proto.LockUnlock {
+		owned = append(owned, &FileLock{LockInfo: *lock, Expire: expire})
+	}
+	if !lock.Flock {
+		owned = mergeLocks(owned)
+	}
+	res.locks = append(res.locks, owned...)
+	return res
+}
+
+// Renew extends the leases of the unexpired locks held by the client, and
+// drops the expired ones.
+func (il *InodeLocks) Renew(client string, expire, now int64) (res *InodeLocks, count int) {
+	res = NewInodeLocks(il.inode)
+	for _, l := range il.locks {
+		if l.expired(now) {
+			continue
+		}
+		if l.Client == client {
+			renewed := *l
+			renewed.Expire = expire
+			l = &renewed
+			count++
+		}
+		res.locks = append(res.locks, l)
+	}
+	return
+}
+
+// Len returns the number of locks.
+func (il *InodeLocks) Len() int {
+	return len(il.locks)
+}
+
+func unlockRange(locks []*FileLock, start, end uint64) []*FileLock {
+	res := make([]*FileLock, 0, len(locks)+1)
+	for _, l := range locks {
+		if l.End < start || end < l.Start {
+			res = append(res, l)
+			continue
+		}
+		if l.Start < start {
+			head := *l
+			head.End = start - 1
+			res = append(res, &head)
+		}
+		if end < l.End {
+			tail := *l
+			tail.Start = end + 1
+			res = append(res, &tail)
+		}
+	}
+	return res
+}
+
+func mergeLocks(locks []*FileLock) []*FileLock {
+	if len(locks) <= 1 {
+		return locks
+	}
+	sort.Slice(locks, func(i, j int) bool {
+		return locks[i].Start < locks[j].Start
+	})
+	res := make([]*FileLock, 0, len(locks))
+	cur := *locks[0]
+	for _, l := range locks[1:] {
+		if l.Type == cur.Type && cur.End != math.MaxUint64 && l.Start <= cur.End+1 {
+			if l.End > cur.End {
+				cur.End = l.End
+			}
+			if l.Expire > cur.Expire {
+				cur.Expire = l.Expire
+			}
+			continue
+		}
+		merged := cur
+		res = append(res, &merged)
+		cur = *l
+	}
+	res = append(res, &cur)
+	return res
+}
+
+func (il *InodeLocks) Bytes() ([]byte, error) {
+	var err error
+	var buffer = bytes.NewBuffer(nil)
+	var tmp = make([]byte, binary.MaxVarintLen64)
+	var n int
+	var writeUvarint = func(v uint64) error {
+		n = binary.PutUvarint(tmp, v)
+		_, err := buffer.Write(tmp[:n])
+		return err
+	}
+	// inode
+	if err = writeUvarint(il.inode); err != nil {
+		return nil, err
+	}
+	// number of locks
+	if err = writeUvarint(uint64(len(il.locks))); err != nil {
+		return nil, err
+	}
+	for _, l := range il.locks {
+		// client
+		if err = writeUvarint(uint64(len(l.Client))); err != nil {
+			return nil, err
+		}
+		if _, err = buffer.WriteString(l.Client); err != nil {
+			return nil, err
+		}
+		for _, v := range []uint64{l.Owner, uint64(l.Pid), l.Start, l.End, uint64(l.Type)} {
+			if err = writeUvarint(v); err != nil {
+				return nil, err
+			}
+		}
+		// flock
+		var flock byte
+		if l.Flock {
+			flock = 1
+		}
+		if err = buffer.WriteByte(flock); err != nil {
+			return nil, err
+		}
+		// expire
+		n = binary.PutVarint(tmp, l.Expire)
+		if _, err = buffer.Write(tmp[:n]); err != nil {
+			return nil, err
+		}
+	}
+	return buffer.Bytes(), nil
+}
+
+func InodeLocksFromBytes(raw []byte) (il *InodeLocks, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("decode inode locks: %v", r)
+		}
+	}()
+	var offset, n int
+	var readUvarint = func() uint64 {
+		v, n := binary.Uvarint(raw[offset:])
+		if n <= 0 {
+			panic("invalid uvarint")
+		}
+		offset += n
+		return v
+	}
+	il = NewInodeLocks(readUvarint())
+	count := readUvarint()
+	il.locks = make([]*FileLock, 0, count)
+	for i := uint64(0); i < count; i++ {
+		l := &FileLock{}
+		length := int(readUvarint())
+		l.Client = string(raw[offset : offset+length])
+		offset += length
+		l.Owner = readUvarint()
+		l.Pid = uint32(readUvarint())
+		l.Start = readUvarint()
+		l.End = readUvarint()
+		l.Type = uint32(readUvarint())
+		l.Flock = raw[offset] == 1
+		offset++
+		l.Expire, n = binary.Varint(raw[offset:])
+		if n <= 0 {
+			panic("invalid varint")
+		}
+		offset += n
+		il.locks = append(il.locks, l)
+	}
+	return
+}
+
+// LockCmd defines the raft command to acquire or release a lock.
+// Now and Expire are stamped by the leader, so that every replica expires
+// locks at the same point.
+type LockCmd struct {
+	Inode  uint64         `json:"ino"`
+	Lock   proto.LockInfo `json:"lock"`
+	Expire int64          `json:"expire"`
+	Now    int64          `json:"now"`
+}
+
+func (c *LockCmd) Marshal() ([]byte, error) {
+	return json.Marshal(c)
+}
+
+func (c *LockCmd) Unmarshal(raw []byte) error {
+	return json.Unmarshal(raw, c)
+}
+
+// RenewLockCmd defines the raft command to renew the leases of the locks
+// held by a client.
+type RenewLockCmd struct {
+	Client string `json:"cli"`
+	Expire int64  `json:"expire"`
+	Now    int64  `json:"now"`
+}
+
+func (c *RenewLockCmd) Marshal() ([]byte, error) {
+	return json.Marshal(c)
+}
+
+func (c *RenewLockCmd) Unmarshal(raw []byte) error {
+	return json.Unmarshal(raw, c)
+}
@@ -0,0 +1,89 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+func TestInodeLocks_Bytes(t *testing.T) {
+	locks := NewInodeLocks(10)
+	locks.locks = []*FileLock{
+		{LockInfo: proto.LockInfo{Client: "c1", Owner: 1, Pid: 100, Start: 0, End: 99, Type: proto.LockWrite}, Expire: 1000},
+		{LockInfo: proto.LockInfo{Client: "c2", Owner: 2, Pid: 200, Start: 0, End: math.MaxUint64, Type: proto.LockRead, Flock: true}, Expire: -1},
+	}
+	raw, err := locks.Bytes()
+	if err != nil {
+		t.Fatalf("encode inode locks fail cause: %v", err)
+	}
+	decoded, err := InodeLocksFromBytes(raw)
+	if err != nil {
+		t.Fatalf("decode inode locks fail cause: %v", err)
+	}
+	if !reflect.DeepEqual(decoded, locks) {
+		t.Fatalf("result mismatch: %v %v", decoded, locks)
+	}
+	if _, err = InodeLocksFromBytes(raw[:len(raw)-3]); err == nil {
+		t.Fatalf("decode truncated inode locks should fail")
+	}
+}
+
+func TestInodeLocks_Apply(t *testing.T) {
+	const now, expire = 100, 200
+	var posix = func(client string, start, end uint64, typ uint32) *proto.LockInfo {
+		return &proto.LockInfo{Client: client, Owner: 1, Start: start, End: end, Type: typ}
+	}
+	locks := NewInodeLocks(1)
+	locks = locks.Apply(posix("a", 0, 99, proto.LockWrite), expire, now)
+	locks = locks.Apply(posix("a", 100, 199, proto.LockWrite), expire, now)
+	if locks.Len() != 1 || locks.locks[0].End != 199 {
+		t.Fatalf("adjacent locks should be merged: %v", locks.locks)
+	}
+	if l := locks.Conflict(posix("b", 150, 300, proto.LockRead), now); l == nil {
+		t.Fatalf("overlapping lock of another client should conflict")
+	}
+	if l := locks.Conflict(posix("b", 200, 300, proto.LockWrite), now); l != nil {
+		t.Fatalf("disjoint lock should not conflict: %v", l)
+	}
+	if l := locks.Conflict(posix("b", 0, 300, proto.LockWrite), expire); l != nil {
+		t.Fatalf("expired lock should not conflict: %v", l)
+	}
+	// unlock in the middle splits the lock
+	locks = locks.Apply(posix("a", 50, 59, proto.LockUnlock), expire, now)
+	if locks.Len() != 2 || locks.locks[0].End != 49 || locks.locks[1].Start != 60 {
+		t.Fatalf("lock should be split: %v", locks.locks)
+	}
+	// POSIX locks and flocks do not interact
+	flock := &proto.LockInfo{Client: "b", Owner: 2, End: math.MaxUint64, Type: proto.LockWrite, Flock: true}
+	if l := locks.Conflict(flock, now); l != nil {
+		t.Fatalf("flock should not conflict with POSIX locks: %v", l)
+	}
+	locks = locks.Apply(flock, expire, now)
+	if locks.Len() != 3 {
+		t.Fatalf("flock should be added: %v", locks.locks)
+	}
+	renewed, count := locks.Renew("a", expire*2, now)
+	if count != 2 || renewed.Len() != 3 {
+		t.Fatalf("renew mismatch: count(%v) locks(%v)", count, renewed.locks)
+	}
+	// the flock of b expires and is dropped
+	if renewed, count = renewed.Renew("a", expire*3, expire+1); count != 2 || renewed.Len() != 2 {
+		t.Fatalf("renew after expire mismatch: count(%v) locks(%v)", count, renewed.locks)
+	}
+}
@@ -68,6 +68,8 @@ type metadataManager struct {
 	partitions         map[uint64]MetaPartition // Key: metaRangeId, Val: metaPartition
 	metaNode           *MetaNode
 	flDeleteBatchCount atomic.Value
+	volQuotas          atomic.Value // map[string][]*proto.QuotaHeartBeatInfo
+	volTrashDays       atomic.Value // map[string]uint32
 }
 
 func (m *metadataManager) getPacketLabels(p *Packet) (labels map[string]string) {
@@ -133,6 +135,10 @@ func (m *metadataManager) HandleMetadataOperation(conn net.Conn, p *Packet, remo
 		err = m.opUpdateDentry(conn, p, remoteAddr)
 	case proto.OpMetaReadDir:
 		err = m.opReadDir(conn, p, remoteAddr)
+	case proto.OpMetaReadDirPlus:
+		err = m.opReadDirPlus(conn, p, remoteAddr)
+	case proto.OpMetaGetSummaryChanges:
+		err = m.opMetaGetSummaryChanges(conn, p, remoteAddr)
 	case proto.OpCreateMetaPartition:
 		err = m.opCreateMetaPartition(conn, p, remoteAddr)
 	case proto.OpMetaNodeHeartbeat:
@@ -163,6 +169,18 @@ func (m *metadataManager) HandleMetadataOperation(conn net.Conn, p *Packet, remo
 		err = m.opRemoveMetaPartitionRaftMember(conn, p, remoteAddr)
 	case proto.OpMetaPartitionTryToLeader:
 		err = m.opMetaPartitionTryToLeader(conn, p, remoteAddr)
+	case proto.OpCreateMetaSnapshot:
+		err = m.opCreateMetaSnapshot(conn, p, remoteAddr)
+	case proto.OpDeleteMetaSnapshot:
+		err = m.opDeleteMetaSnapshot(conn, p, remoteAddr)
+	case proto.OpFreezeMetaPartition:
+		err = m.opFreezeMetaPartition(conn, p, remoteAddr)
+	case proto.OpMoveMetaItems:
+		err = m.opMoveMetaItems(conn, p, remoteAddr)
+	case proto.OpMetaApplyMovedItems:
+		err = m.opMetaApplyMovedItems(conn, p, remoteAddr)
+	case proto.OpTrimMetaPartition:
+		err = m.opTrimMetaPartition(conn, p, remoteAddr)
 	case proto.OpMetaBatchInodeGet:
 		err = m.opMetaBatchInodeGet(conn, p, remoteAddr)
 	case proto.OpMetaDeleteInode:
@@ -182,6 +200,22 @@ func (m *metadataManager) HandleMetadataOperation(conn net.Conn, p *Packet, remo
 		err = m.opMetaRemoveXAttr(conn, p, remoteAddr)
 	case proto.OpMetaListXAttr:
 		err = m.opMetaListXAttr(conn, p, remoteAddr)
+	// operations for file locks
+	case proto.OpMetaSetLock:
+		err = m.opMetaSetLock(conn, p, remoteAddr)
+	case proto.OpMetaGetLock:
+		err = m.opMetaGetLock(conn, p, remoteAddr)
+	case proto.OpMetaRenewLock:
+		err = m.opMetaRenewLock(conn, p, remoteAddr)
+	// operations for meta transactions
+	case proto.OpMetaTxPrepare:
+		err = m.opMetaTxPrepare(conn, p, remoteAddr)
+	case proto.OpMetaTxCommit:
+		err = m.opMetaTxCommit(conn, p, remoteAddr)
+	case proto.OpMetaTxAbort:
+		err = m.opMetaTxAbort(conn, p, remoteAddr)
+	case proto.OpMetaTxGetStatus:
+		err = m.opMetaTxGetStatus(conn, p, remoteAddr)
 	// operations for multipart session
 	case proto.OpCreateMultipart:
 		err = m.opCreateMultipart(conn, p, remoteAddr)
@@ -193,6 +227,15 @@ func (m *metadataManager) HandleMetadataOperation(conn net.Conn, p *Packet, remo
 		err = m.opAppendMultipart(conn, p, remoteAddr)
 	case proto.OpGetMultipart:
 		err = m.opGetMultipart(conn, p, remoteAddr)
+	// operations for object versions
+	case proto.OpPutObjectVersion:
+		err = m.opPutObjectVersion(conn, p, remoteAddr)
+	case proto.OpDeleteObjectVersion:
+		err = m.opDeleteObjectVersion(conn, p, remoteAddr)
+	case proto.OpGetObjectVersions:
+		err = m.opGetObjectVersions(conn, p, remoteAddr)
+	case proto.OpListObjectVersions:
+		err = m.opListObjectVersions(conn, p, remoteAddr)
 	default:
 		err = fmt.Errorf("%s unknown Opcode: %d, reqId: %d", remoteAddr,
 			p.Opcode, p.GetReqID())
@@ -451,6 +494,39 @@ func (m *metadataManager) deletePartition(id uint64) (err error) {
 	return
 }
 
+// setVolQuotas updates the quotas received from the master.
+func (m *metadataManager) setVolQuotas(quotas []*proto.QuotaHeartBeatInfo) {
+	volQuotas := make(map[string][]*proto.QuotaHeartBeatInfo)
+	for _, quota := range quotas {
+		volQuotas[quota.VolName] = append(volQuotas[quota.VolName], quota)
+	}
+	m.volQuotas.Store(volQuotas)
+}
+
+func (m *metadataManager) getVolQuotas(volName string) []*proto.QuotaHeartBeatInfo {
+	volQuotas, ok := m.volQuotas.Load().(map[string][]*proto.QuotaHeartBeatInfo)
+	if !ok {
+		return nil
+	}
+	return volQuotas[volName]
+}
+
+// setVolTrashDays updates the trash retention days received from the master.
+func (m *metadataManager) setVolTrashDays(trashDays map[string]uint32) {
+	if trashDays == nil {
+		trashDays = make(map[string]uint32)
+	}
+	m.volTrashDays.Store(trashDays)
+}
+
+func (m *metadataManager) getVolTrashDays(volName string) uint32 {
+	trashDays, ok := m.volTrashDays.Load().(map[string]uint32)
+	if !ok {
+		return 0
+	}
+	return trashDays[volName]
+}
+
 // Range scans all the meta partitions.
 func (m *metadataManager) Range(f func(i uint64, p MetaPartition) bool) {
 	m.mu.RLock()
@@ -38,6 +38,13 @@ func (m *metadataManager) serveProxy(conn net.Conn, mp MetaPartition,
 		reqOp      = p.Opcode
 	)
 	if leaderAddr, ok = mp.IsLeader(); ok {
+		mp.CountRequest()
+		if mp.IsFrozen() && isItemChangeOp(reqOp) {
+			ok = false
+			err = ErrPartitionFrozen
+			p.PacketErrorWithBody(proto.OpAgain, []byte(err.Error()))
+			goto end
+		}
 		return
 	}
 	if leaderAddr == "" {
@@ -0,0 +1,126 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+func TestObjectLock_CheckXAttr(t *testing.T) {
+	mp := &metaPartition{
+		config:     &MetaPartitionConfig{PartitionId: 1, VolName: "vol"},
+		inodeTree:  NewBtree(),
+		extendTree: NewBtree(),
+	}
+	mp.inodeTree.ReplaceOrInsert(NewInode(10, proto.Mode(0644)), true)
+	mp.inodeTree.ReplaceOrInsert(NewInode(11, proto.Mode(os.ModeDir|0755)), true)
+
+	var until = time.Now().Add(time.Hour).Unix()
+	var compliance = &proto.Retention{Mode: proto.RetentionModeCompliance, RetainUntil: until}
+	if status := mp.checkObjectLockXAttr(10, proto.RetentionXAttrKey, []byte("invalid")); status != proto.OpArgMismatchErr {
+		t.Fatalf("invalid retention status: %v", status)
+	}
+	if status := mp.checkObjectLockXAttr(11, proto.LegalHoldXAttrKey, []byte(proto.LegalHoldOn)); status != proto.OpArgMismatchErr {
+		t.Fatalf("directory legal hold status: %v", status)
+	}
+	if status := mp.checkObjectLockXAttr(10, proto.RetentionXAttrKey, []byte(compliance.String())); status != proto.OpOk {
+		t.Fatalf("set retention status: %v", status)
+	}
+	if mp.isInodeLocked(10) {
+		t.Fatalf("inode without lock is locked")
+	}
+
+	extend := NewExtend(10)
+	extend.Put([]byte(proto.RetentionXAttrKey), []byte(compliance.String()))
+	mp.fsmSetXAttr(extend)
+	if !mp.isInodeLocked(10) {
+		t.Fatalf("inode in retention period is not locked")
+	}
+	// the compliance retention can only be extended
+	var shorter = &proto.Retention{Mode: proto.RetentionModeCompliance, RetainUntil: until - 60}
+	if status := mp.checkObjectLockXAttr(10, proto.RetentionXAttrKey, []byte(shorter.String())); status != proto.OpNotPerm {
+		t.Fatalf("shorten retention status: %v", status)
+	}
+	var governance = &proto.Retention{Mode: proto.RetentionModeGovernance, RetainUntil: until + 60}
+	if status := mp.checkObjectLockXAttr(10, proto.RetentionXAttrKey, []byte(governance.String())); status != proto.OpNotPerm {
+		t.Fatalf("change retention mode status: %v", status)
+	}
+	if status := mp.checkObjectLockXAttr(10, proto.RetentionXAttrKey, nil); status != proto.OpNotPerm {
+		t.Fatalf("remove retention status: %v", status)
+	}
+	var longer = &proto.Retention{Mode: proto.RetentionModeCompliance, RetainUntil: until + 60}
+	if status := mp.checkObjectLockXAttr(10, proto.RetentionXAttrKey, []byte(longer.String())); status != proto.OpOk {
+		t.Fatalf("extend retention status: %v", status)
+	}
+
+	// the expired retention does not lock the inode
+	extend.Put([]byte(proto.RetentionXAttrKey), []byte((&proto.Retention{Mode: proto.RetentionModeCompliance,
+		RetainUntil: time.Now().Add(-time.Hour).Unix()}).String()))
+	mp.fsmSetXAttr(extend)
+	if mp.isInodeLocked(10) {
+		t.Fatalf("inode with expired retention is locked")
+	}
+	extend.Put([]byte(proto.LegalHoldXAttrKey), []byte(proto.LegalHoldOn))
+	mp.fsmSetXAttr(extend)
+	if !mp.isInodeLocked(10) {
+		t.Fatalf("inode under legal hold is not locked")
+	}
+}
+
+func TestObjectLock_CheckDentry(t *testing.T) {
+	mp := &metaPartition{
+		config:      &MetaPartitionConfig{PartitionId: 1, VolName: "vol", Start: 1, End: 100},
+		inodeTree:   NewBtree(),
+		dentryTree:  NewBtree(),
+		extendTree:  NewBtree(),
+		versionTree: NewBtree(),
+		txTree:      NewBtree(),
+	}
+	mp.inodeTree.ReplaceOrInsert(NewInode(1, proto.Mode(os.ModeDir)), true)
+	mp.fsmCreateDentry(&Dentry{ParentId: 1, Name: "a", Inode: 10, Type: proto.Mode(0644)}, false)
+	mp.fsmCreateDentry(&Dentry{ParentId: 1, Name: "b", Inode: 11, Type: proto.Mode(0644)}, false)
+	for _, ino := range []uint64{10, 11} {
+		extend := NewExtend(ino)
+		extend.Put([]byte(proto.LegalHoldXAttrKey), []byte(proto.LegalHoldOn))
+		mp.fsmSetXAttr(extend)
+	}
+	if status := mp.checkDentryObjectLock(1, "a"); status != proto.OpNotPerm {
+		t.Fatalf("remove dentry of locked inode status: %v", status)
+	}
+	if status := mp.checkDentryObjectLock(1, "c"); status != proto.OpOk {
+		t.Fatalf("remove unknown dentry status: %v", status)
+	}
+
+	// the locked inode kept by a version of the object is not removed with the dentry
+	version := NewObjectVersion(1, "b", "v1")
+	version.Inode = 11
+	mp.fsmPutObjectVersion(version)
+	if status := mp.checkDentryObjectLock(1, "b"); status != proto.OpOk {
+		t.Fatalf("remove dentry of versioned inode status: %v", status)
+	}
+
+	p := &Packet{}
+	if err := mp.DeleteDentry(&DeleteDentryReq{ParentID: 1, Name: "a"}, p); err != nil || p.ResultCode != proto.OpNotPerm {
+		t.Fatalf("delete dentry of locked inode result: %v err(%v)", p.ResultCode, err)
+	}
+	p = &Packet{}
+	if err := mp.UpdateDentry(&UpdateDentryReq{ParentID: 1, Name: "a", Inode: 12}, p); err != nil || p.ResultCode != proto.OpNotPerm {
+		t.Fatalf("update dentry of locked inode result: %v err(%v)", p.ResultCode, err)
+	}
+}
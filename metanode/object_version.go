@@ -0,0 +1,163 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sort"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/btree"
+)
+
+// ObjectVersion defines a version of an object of the object storage, which
+// is kept in the partition of the parent directory, the same as the dentry
+// of the object. The inode of a version which is not current is not linked
+// by any dentry, and it is released only when the version is deleted.
+// It is immutable once inserted into the version tree.
+type ObjectVersion struct {
+	proto.ObjectVersionInfo
+}
+
+// NewObjectVersion returns a new ObjectVersion.
+func NewObjectVersion(parentID uint64, name, versionID string) *ObjectVersion {
+	return &ObjectVersion{
+		ObjectVersionInfo: proto.ObjectVersionInfo{
+			ParentID:  parentID,
+			Name:      name,
+			VersionID: versionID,
+		},
+	}
+}
+
+// Less orders the versions by the parent, the name and the version ID.
+func (v *ObjectVersion) Less(than btree.Item) bool {
+	o, is := than.(*ObjectVersion)
+	if !is {
+		return false
+	}
+	if v.ParentID != o.ParentID {
+		return v.ParentID < o.ParentID
+	}
+	if v.Name != o.Name {
+		return v.Name < o.Name
+	}
+	return v.VersionID < o.VersionID
+}
+
+func (v *ObjectVersion) Copy() btree.Item {
+	newVersion := *v
+	return &newVersion
+}
+
+func (v *ObjectVersion) String() string {
+	return fmt.Sprintf("ObjectVersion{parent(%v) name(%v) version(%v) inode(%v) mtime(%v) deleteMarker(%v)}",
+		v.ParentID, v.Name, v.VersionID, v.Inode, v.ModifyTime, v.DeleteMarker)
+}
+
+func (v *ObjectVersion) Bytes() ([]byte, error) {
+	var err error
+	var buffer = bytes.NewBuffer(nil)
+	var tmp = make([]byte, binary.MaxVarintLen64)
+	var n int
+	var writeUvarint = func(v uint64) error {
+		n = binary.PutUvarint(tmp, v)
+		_, err := buffer.Write(tmp[:n])
+		return err
+	}
+	var writeString = func(s string) error {
+		if err := writeUvarint(uint64(len(s))); err != nil {
+			return err
+		}
+		_, err := buffer.WriteString(s)
+		return err
+	}
+	if err = writeUvarint(v.ParentID); err != nil {
+		return nil, err
+	}
+	if err = writeString(v.Name); err != nil {
+		return nil, err
+	}
+	if err = writeString(v.VersionID); err != nil {
+		return nil, err
+	}
+	if err = writeUvarint(v.Inode); err != nil {
+		return nil, err
+	}
+	n = binary.PutVarint(tmp, v.ModifyTime)
+	if _, err = buffer.Write(tmp[:n]); err != nil {
+		return nil, err
+	}
+	var deleteMarker uint64
+	if v.DeleteMarker {
+		deleteMarker = 1
+	}
+	if err = writeUvarint(deleteMarker); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+func ObjectVersionFromBytes(raw []byte) (v *ObjectVersion, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("decode object version: %v", r)
+		}
+	}()
+	var offset int
+	var readUvarint = func() uint64 {
+		val, n := binary.Uvarint(raw[offset:])
+		if n <= 0 {
+			panic("invalid uvarint")
+		}
+		offset += n
+		return val
+	}
+	var readString = func() string {
+		length := int(readUvarint())
+		s := string(raw[offset : offset+length])
+		offset += length
+		return s
+	}
+	v = &ObjectVersion{}
+	v.ParentID = readUvarint()
+	v.Name = readString()
+	v.VersionID = readString()
+	v.Inode = readUvarint()
+	mtime, n := binary.Varint(raw[offset:])
+	if n <= 0 {
+		panic("invalid varint")
+	}
+	offset += n
+	v.ModifyTime = mtime
+	v.DeleteMarker = readUvarint() != 0
+	return
+}
+
+// sortObjectVersions sorts the versions by the name, and then from the newest
+// to the oldest.
+func sortObjectVersions(versions []*proto.ObjectVersionInfo) {
+	sort.SliceStable(versions, func(i, j int) bool {
+		if versions[i].Name != versions[j].Name {
+			return versions[i].Name < versions[j].Name
+		}
+		if versions[i].ModifyTime != versions[j].ModifyTime {
+			return versions[i].ModifyTime > versions[j].ModifyTime
+		}
+		return versions[i].VersionID > versions[j].VersionID
+	})
+}
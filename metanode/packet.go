@@ -76,3 +76,18 @@ func NewPacketToFreeInodeOnRaftFollower(partitionID uint64, freeInodes []byte) *
 
 	return p
 }
+
+// NewPacketToMetaPartition returns a new packet to deliver a request, such as
+// a meta transaction request, to another partition.
+func NewPacketToMetaPartition(opcode uint8, partitionID uint64, data []byte) *Packet {
+	p := new(Packet)
+	p.Magic = proto.ProtoMagic
+	p.Opcode = opcode
+	p.PartitionID = partitionID
+	p.ExtentType = proto.NormalExtentType
+	p.ReqID = proto.GenerateRequestID()
+	p.Data = data
+	p.Size = uint32(len(p.Data))
+
+	return p
+}
@@ -20,7 +20,9 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"sync/atomic"
+	"time"
 
 	"fmt"
 	"io/ioutil"
@@ -64,10 +66,11 @@ type MetaPartitionConfig struct {
 	// Identity for raftStore group. RaftStore nodes in the same raftStore group must have the same groupID.
 	PartitionId uint64              `json:"partition_id"`
 	VolName     string              `json:"vol_name"`
-	Start       uint64              `json:"start"` // Minimal Inode ID of this range. (Required during initialization)
-	End         uint64              `json:"end"`   // Maximal Inode ID of this range. (Required during initialization)
-	Peers       []proto.Peer        `json:"peers"` // Peers information of the raftStore
-	Cursor      uint64              `json:"-"`     // Cursor ID of the inode that have been assigned
+	Start       uint64              `json:"start"`  // Minimal Inode ID of this range. (Required during initialization)
+	End         uint64              `json:"end"`    // Maximal Inode ID of this range. (Required during initialization)
+	Peers       []proto.Peer        `json:"peers"`  // Peers information of the raftStore
+	Frozen      bool                `json:"frozen"` // Refuse the changes while the items are moved to another partition
+	Cursor      uint64              `json:"-"`      // Cursor ID of the inode that have been assigned
 	NodeId      uint64              `json:"-"`
 	RootDir     string              `json:"-"`
 	BeforeStart func()              `json:"-"`
@@ -115,7 +118,7 @@ type OpInode interface {
 	CreateInodeLink(req *LinkInodeReq, p *Packet) (err error)
 	EvictInode(req *EvictInodeReq, p *Packet) (err error)
 	EvictInodeBatch(req *BatchEvictInodeReq, p *Packet) (err error)
-	SetAttr(reqData []byte, p *Packet) (err error)
+	SetAttr(req *SetattrRequest, reqData []byte, p *Packet) (err error)
 	GetInodeTree() *BTree
 	DeleteInode(req *proto.DeleteInodeRequest, p *Packet) (err error)
 	DeleteInodeBatch(req *proto.DeleteInodeBatchRequest, p *Packet) (err error)
@@ -136,6 +139,7 @@ type OpDentry interface {
 	DeleteDentryBatch(req *BatchDeleteDentryReq, p *Packet) (err error)
 	UpdateDentry(req *UpdateDentryReq, p *Packet) (err error)
 	ReadDir(req *ReadDirReq, p *Packet) (err error)
+	ReadDirPlus(req *ReadDirPlusReq, p *Packet) (err error)
 	Lookup(req *LookupReq, p *Packet) (err error)
 	GetDentryTree() *BTree
 }
@@ -157,6 +161,36 @@ type OpMultipart interface {
 	ListMultipart(req *proto.ListMultipartRequest, p *Packet) (err error)
 }
 
+// OpObjectVersion defines the interface for the object version operations.
+type OpObjectVersion interface {
+	PutObjectVersion(req *proto.PutObjectVersionRequest, p *Packet) (err error)
+	DeleteObjectVersion(req *proto.DeleteObjectVersionRequest, p *Packet) (err error)
+	GetObjectVersions(req *proto.GetObjectVersionsRequest, p *Packet) (err error)
+	ListObjectVersions(req *proto.ListObjectVersionsRequest, p *Packet) (err error)
+}
+
+// OpLock defines the interface for the file lock operations.
+type OpLock interface {
+	SetLock(req *proto.SetLockRequest, p *Packet) (err error)
+	GetLock(req *proto.GetLockRequest, p *Packet) (err error)
+	RenewLock(req *proto.RenewLockRequest, p *Packet) (err error)
+}
+
+// OpTx defines the interface for the meta transaction operations.
+type OpTx interface {
+	TxPrepare(req *proto.TxPrepareRequest, p *Packet) (err error)
+	TxCommit(req *proto.TxCommitRequest, p *Packet) (err error)
+	TxAbort(req *proto.TxAbortRequest, p *Packet) (err error)
+	TxGetStatus(req *proto.TxGetStatusRequest, p *Packet) (err error)
+}
+
+// OpSnapshot defines the interface for the meta snapshot operations.
+type OpSnapshot interface {
+	CreateMetaSnapshot(req *proto.MetaSnapshotRequest, p *Packet) (err error)
+	DeleteMetaSnapshot(req *proto.MetaSnapshotRequest, p *Packet) (err error)
+	GetSnapshotView(id uint64) (view MetaPartition, ok bool)
+}
+
 // OpMeta defines the interface for the metadata operations.
 type OpMeta interface {
 	OpInode
@@ -165,6 +199,10 @@ type OpMeta interface {
 	OpPartition
 	OpExtend
 	OpMultipart
+	OpObjectVersion
+	OpLock
+	OpTx
+	OpSnapshot
 }
 
 // OpPartition defines the interface for the partition operations.
@@ -182,6 +220,15 @@ type OpPartition interface {
 	TryToLeader(groupID uint64) error
 	CanRemoveRaftMember(peer proto.Peer) error
 	IsEquareCreateMetaPartitionRequst(request *proto.CreateMetaPartitionRequest) (err error)
+	GetQuotaUsages() []*proto.QuotaUsage
+	CountRequest()
+	GetRequestRate() float64
+	IsFrozen() bool
+	FreezePartition(req *proto.FreezeMetaPartitionRequest, p *Packet) (err error)
+	MoveItems(req *proto.MoveMetaItemsRequest) *proto.MoveMetaItemsResponse
+	ApplyMovedItems(req *ApplyMovedItemsReq, p *Packet) (err error)
+	TrimPartition(req *proto.TrimMetaPartitionRequest, p *Packet) (err error)
+	GetSummaryChanges(req *proto.GetSummaryChangesRequest, p *Packet) (err error)
 }
 
 // MetaPartition defines the interface for the meta partition operations.
@@ -202,12 +249,29 @@ type MetaPartition interface {
 //  +-----+             +-------+
 type metaPartition struct {
 	config                 *MetaPartitionConfig
+	requests               requestCounter
+	mover                  itemsMover
 	size                   uint64 // For partition all file size
 	applyID                uint64 // Inode/Dentry max applyID, this index will be update after restoring from the dumped data.
 	dentryTree             *BTree
 	inodeTree              *BTree // btree for inodes
 	extendTree             *BTree // btree for inode extend (XAttr) management
 	multipartTree          *BTree // collection for multipart management
+	versionTree            *BTree // btree for object versions
+	lockTree               *BTree // btree for file locks of inodes
+	txTree                 *BTree // btree for meta transactions
+	snapshots              map[uint64]*MetaSnapshot
+	snapExtents            map[snapExtentKey]int // number of snapshots referencing the extent
+	snapPending            int                   // number of snapshots whose extents are being counted
+	snapMutex              sync.RWMutex
+	quotaCounters          map[quotaKey]*quotaCounter
+	quotaMutex             sync.Mutex
+	summaryChanges         *summaryChangeLog // inodes whose size or summary changed
+	dirSummary             *dirSummaryState  // kept by the leader only
+	dirSummaryMutex        sync.Mutex
+	lockViews              []*proto.MetaPartitionView // views to check the object lock of the remote inodes
+	lockViewsTime          time.Time
+	lockViewsMutex         sync.Mutex
 	raftPartition          raftstore.Partition
 	stopC                  chan bool
 	storeChan              chan *storeMsg
@@ -294,6 +358,9 @@ func (mp *metaPartition) onStart() (err error) {
 			mp.config.PartitionId, err.Error())
 		return
 	}
+	go mp.checkTxWorker()
+	go mp.dirSummaryWorker()
+	go mp.trashWorker()
 	return
 }
 
@@ -376,18 +443,22 @@ func (mp *metaPartition) getRaftPort() (heartbeat, replica int, err error) {
 // NewMetaPartition creates a new meta partition with the specified configuration.
 func NewMetaPartition(conf *MetaPartitionConfig, manager *metadataManager) MetaPartition {
 	mp := &metaPartition{
-		config:        conf,
-		dentryTree:    NewBtree(),
-		inodeTree:     NewBtree(),
-		extendTree:    NewBtree(),
-		multipartTree: NewBtree(),
-		stopC:         make(chan bool),
-		storeChan:     make(chan *storeMsg, 100),
-		freeList:      newFreeList(),
-		extDelCh:      make(chan []proto.ExtentKey, 10000),
-		extReset:      make(chan struct{}),
-		vol:           NewVol(),
-		manager:       manager,
+		config:         conf,
+		dentryTree:     NewBtree(),
+		inodeTree:      NewBtree(),
+		extendTree:     NewBtree(),
+		multipartTree:  NewBtree(),
+		versionTree:    NewBtree(),
+		lockTree:       NewBtree(),
+		txTree:         NewBtree(),
+		stopC:          make(chan bool),
+		storeChan:      make(chan *storeMsg, 100),
+		freeList:       newFreeList(),
+		extDelCh:       make(chan []proto.ExtentKey, 10000),
+		extReset:       make(chan struct{}),
+		vol:            NewVol(),
+		summaryChanges: newSummaryChangeLog(),
+		manager:        manager,
 	}
 	return mp
 }
@@ -447,6 +518,18 @@ func (mp *metaPartition) LoadSnapshot(snapshotPath string) (err error) {
 	if err = mp.loadMultipart(snapshotPath); err != nil {
 		return
 	}
+	if err = mp.loadVersion(snapshotPath); err != nil {
+		return
+	}
+	if err = mp.loadLock(snapshotPath); err != nil {
+		return
+	}
+	if err = mp.loadTx(snapshotPath); err != nil {
+		return
+	}
+	if err = mp.loadMetaSnapshots(snapshotPath); err != nil {
+		return
+	}
 	err = mp.loadApplyID(snapshotPath)
 	return
 }
@@ -468,6 +551,18 @@ func (mp *metaPartition) load() (err error) {
 	if err = mp.loadMultipart(snapshotPath); err != nil {
 		return
 	}
+	if err = mp.loadVersion(snapshotPath); err != nil {
+		return
+	}
+	if err = mp.loadLock(snapshotPath); err != nil {
+		return
+	}
+	if err = mp.loadTx(snapshotPath); err != nil {
+		return
+	}
+	if err = mp.loadMetaSnapshots(snapshotPath); err != nil {
+		return
+	}
 	err = mp.loadApplyID(snapshotPath)
 	return
 }
@@ -495,6 +590,10 @@ func (mp *metaPartition) store(sm *storeMsg) (err error) {
 		mp.storeDentry,
 		mp.storeExtend,
 		mp.storeMultipart,
+		mp.storeVersion,
+		mp.storeLock,
+		mp.storeTx,
+		mp.storeMetaSnapshots,
 	}
 	for _, storeFunc := range storeFuncs {
 		var crc uint32
@@ -659,7 +758,7 @@ func (mp *metaPartition) Reset() (err error) {
 	mp.applyID = 0
 
 	// remove files
-	filenames := []string{applyIDFile, dentryFile, inodeFile, extendFile, multipartFile}
+	filenames := []string{applyIDFile, dentryFile, inodeFile, extendFile, multipartFile, versionFile, lockFile, txFile, metaSnapshotFile}
 	for _, filename := range filenames {
 		filepath := path.Join(mp.config.RootDir, filename)
 		if err = os.Remove(filepath); err != nil {
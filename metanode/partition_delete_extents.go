@@ -129,7 +129,14 @@ LOOP:
 		case eks := <-mp.extDelCh:
 			var data []byte
 			buf = buf[:0]
+			if !mp.waitMetaSnapshotExtents() {
+				return
+			}
 			for _, ek := range eks {
+				// the extents referenced by snapshots are kept
+				if mp.isSnapshotExtent(&ek) {
+					continue
+				}
 				if extentV2 {
 					data, err = ek.MarshalBinaryWithCheckSum()
 				} else {
@@ -56,6 +56,19 @@ func (mp *metaPartition) Apply(command []byte, index uint64) (resp interface{},
 			mp.config.Cursor = ino.Inode
 		}
 		resp = mp.fsmCreateInode(ino)
+	case opFSMCreateInodeInQuotas:
+		req := &CreateInodeInQuotasReq{}
+		if err = json.Unmarshal(msg.V, req); err != nil {
+			return
+		}
+		ino := NewInode(0, 0)
+		if err = ino.Unmarshal(req.Inode); err != nil {
+			return
+		}
+		if mp.config.Cursor < ino.Inode {
+			mp.config.Cursor = ino.Inode
+		}
+		resp = mp.fsmCreateInodeInQuotas(ino, req.QuotaIds)
 	case opFSMUnlinkInode:
 		ino := NewInode(0, 0)
 		if err = ino.Unmarshal(msg.V); err != nil {
@@ -129,6 +142,24 @@ func (mp *metaPartition) Apply(command []byte, index uint64) (resp interface{},
 			return
 		}
 		resp, err = mp.fsmUpdatePartition(req.End)
+	case opFSMFreezePartition:
+		req := &proto.FreezeMetaPartitionRequest{}
+		if err = json.Unmarshal(msg.V, req); err != nil {
+			return
+		}
+		resp, err = mp.fsmFreezePartition(req.Freeze)
+	case opFSMApplyMovedItems:
+		var items []*MetaItem
+		if err = json.Unmarshal(msg.V, &items); err != nil {
+			return
+		}
+		resp = mp.fsmApplyMovedItems(items)
+	case opFSMTrimPartition:
+		req := &proto.TrimMetaPartitionRequest{}
+		if err = json.Unmarshal(msg.V, req); err != nil {
+			return
+		}
+		resp, err = mp.fsmTrimPartition(req.End)
 	case opFSMExtentsAdd:
 		ino := NewInode(0, 0)
 		if err = ino.Unmarshal(msg.V); err != nil {
@@ -146,6 +177,9 @@ func (mp *metaPartition) Apply(command []byte, index uint64) (resp interface{},
 		dentryTree := mp.getDentryTree()
 		extendTree := mp.extendTree.GetTree()
 		multipartTree := mp.multipartTree.GetTree()
+		versionTree := mp.versionTree.GetTree()
+		lockTree := mp.lockTree.GetTree()
+		txTree := mp.txTree.GetTree()
 		msg := &storeMsg{
 			command:       opFSMStoreTick,
 			applyIndex:    index,
@@ -153,6 +187,10 @@ func (mp *metaPartition) Apply(command []byte, index uint64) (resp interface{},
 			dentryTree:    dentryTree,
 			extendTree:    extendTree,
 			multipartTree: multipartTree,
+			versionTree:   versionTree,
+			lockTree:      lockTree,
+			txTree:        txTree,
+			snapshots:     mp.metaSnapshotList(),
 		}
 		mp.storeChan <- msg
 	case opFSMInternalDeleteInode:
@@ -175,6 +213,12 @@ func (mp *metaPartition) Apply(command []byte, index uint64) (resp interface{},
 			return
 		}
 		err = mp.fsmRemoveXAttr(extend)
+	case opFSMSetDirSummary:
+		var items []*DirSummaryItem
+		if err = json.Unmarshal(msg.V, &items); err != nil {
+			return
+		}
+		mp.fsmSetDirSummary(items)
 	case opFSMCreateMultipart:
 		var multipart *Multipart
 		multipart = MultipartFromBytes(msg.V)
@@ -187,6 +231,72 @@ func (mp *metaPartition) Apply(command []byte, index uint64) (resp interface{},
 		var multipart *Multipart
 		multipart = MultipartFromBytes(msg.V)
 		resp = mp.fsmAppendMultipart(multipart)
+	case opFSMPutObjectVersion:
+		var version *ObjectVersion
+		if version, err = ObjectVersionFromBytes(msg.V); err != nil {
+			return
+		}
+		resp = mp.fsmPutObjectVersion(version)
+	case opFSMDeleteObjectVersion:
+		var version *ObjectVersion
+		if version, err = ObjectVersionFromBytes(msg.V); err != nil {
+			return
+		}
+		resp = mp.fsmDeleteObjectVersion(version)
+	case opFSMSetLock:
+		cmd := &LockCmd{}
+		if err = cmd.Unmarshal(msg.V); err != nil {
+			return
+		}
+		resp = mp.fsmSetLock(cmd)
+	case opFSMRenewLock:
+		cmd := &RenewLockCmd{}
+		if err = cmd.Unmarshal(msg.V); err != nil {
+			return
+		}
+		resp = mp.fsmRenewLock(cmd)
+	case opFSMTxPrepare:
+		var rec *TxRecord
+		if rec, err = TxRecordFromBytes(msg.V); err != nil {
+			return
+		}
+		resp = mp.fsmTxPrepare(rec)
+	case opFSMTxCommit:
+		cmd := &TxCmd{}
+		if err = cmd.Unmarshal(msg.V); err != nil {
+			return
+		}
+		resp = mp.fsmTxCommit(cmd)
+	case opFSMTxAbort:
+		cmd := &TxCmd{}
+		if err = cmd.Unmarshal(msg.V); err != nil {
+			return
+		}
+		resp = mp.fsmTxAbort(cmd)
+	case opFSMTxPushed:
+		cmd := &TxCmd{}
+		if err = cmd.Unmarshal(msg.V); err != nil {
+			return
+		}
+		resp = mp.fsmTxPushed(cmd)
+	case opFSMTxRemove:
+		cmd := &TxCmd{}
+		if err = cmd.Unmarshal(msg.V); err != nil {
+			return
+		}
+		resp = mp.fsmTxRemove(cmd)
+	case opFSMCreateMetaSnapshot:
+		cmd := &MetaSnapshotCmd{}
+		if err = cmd.Unmarshal(msg.V); err != nil {
+			return
+		}
+		resp = mp.fsmCreateMetaSnapshot(cmd)
+	case opFSMDeleteMetaSnapshot:
+		cmd := &MetaSnapshotCmd{}
+		if err = cmd.Unmarshal(msg.V); err != nil {
+			return
+		}
+		resp = mp.fsmDeleteMetaSnapshot(cmd)
 	case opFSMSyncCursor:
 		var cursor uint64
 		cursor = binary.BigEndian.Uint64(msg.V)
@@ -255,6 +365,10 @@ func (mp *metaPartition) ApplySnapshot(peers []raftproto.Peer, iter raftproto.Sn
 		dentryTree    = NewBtree()
 		extendTree    = NewBtree()
 		multipartTree = NewBtree()
+		versionTree   = NewBtree()
+		lockTree      = NewBtree()
+		txTree        = NewBtree()
+		snapshots     = make(map[uint64]*MetaSnapshot)
 	)
 	defer func() {
 		if err == io.EOF {
@@ -263,6 +377,11 @@ func (mp *metaPartition) ApplySnapshot(peers []raftproto.Peer, iter raftproto.Sn
 			mp.dentryTree = dentryTree
 			mp.extendTree = extendTree
 			mp.multipartTree = multipartTree
+			mp.versionTree = versionTree
+			mp.lockTree = lockTree
+			mp.txTree = txTree
+			mp.setMetaSnapshots(snapshots)
+			mp.resetQuotaUsage()
 			mp.config.Cursor = cursor
 			err = nil
 			// store message
@@ -273,6 +392,10 @@ func (mp *metaPartition) ApplySnapshot(peers []raftproto.Peer, iter raftproto.Sn
 				dentryTree:    mp.dentryTree,
 				extendTree:    mp.extendTree,
 				multipartTree: mp.multipartTree,
+				versionTree:   mp.versionTree,
+				lockTree:      mp.lockTree,
+				txTree:        mp.txTree,
+				snapshots:     mp.metaSnapshotList(),
 			}
 			mp.extReset <- struct{}{}
 			log.LogDebugf("ApplySnapshot: finish with EOF: partitionID(%v) applyID(%v)", mp.config.PartitionId, mp.applyID)
@@ -329,6 +452,38 @@ func (mp *metaPartition) ApplySnapshot(peers []raftproto.Peer, iter raftproto.Sn
 			var multipart = MultipartFromBytes(snap.V)
 			multipartTree.ReplaceOrInsert(multipart, true)
 			log.LogDebugf("ApplySnapshot: create multipart: partitionID(%v) multipart(%v)", mp.config.PartitionId, multipart)
+		case opFSMPutObjectVersion:
+			var version *ObjectVersion
+			if version, err = ObjectVersionFromBytes(snap.V); err != nil {
+				return
+			}
+			versionTree.ReplaceOrInsert(version, true)
+			log.LogDebugf("ApplySnapshot: put object version: partitionID(%v) version(%v)", mp.config.PartitionId, version)
+		case opFSMSetLock:
+			var locks *InodeLocks
+			if locks, err = InodeLocksFromBytes(snap.V); err != nil {
+				return
+			}
+			lockTree.ReplaceOrInsert(locks, true)
+			log.LogDebugf("ApplySnapshot: set locks: partitionID(%v) locks(%v)", mp.config.PartitionId, locks)
+		case opFSMTxPrepare:
+			var rec *TxRecord
+			if rec, err = TxRecordFromBytes(snap.V); err != nil {
+				return
+			}
+			txTree.ReplaceOrInsert(rec, true)
+			log.LogDebugf("ApplySnapshot: set transaction: partitionID(%v) tx(%v)", mp.config.PartitionId, rec)
+		case opFSMCreateMetaSnapshot:
+			cmd := &MetaSnapshotCmd{}
+			if err = cmd.Unmarshal(snap.V); err != nil {
+				return
+			}
+			snapshots[cmd.ID] = newEmptyMetaSnapshot(cmd.ID, cmd.CreateTime)
+			log.LogDebugf("ApplySnapshot: create meta snapshot: partitionID(%v) snapshot(%v)", mp.config.PartitionId, cmd.ID)
+		case opFSMMetaSnapshotItem:
+			if err = applyMetaSnapshotItem(snapshots, snap.K, snap.V); err != nil {
+				return
+			}
 		case opExtentFileSnapshot:
 			fileName := string(snap.K)
 			fileName = path.Join(mp.config.RootDir, fileName)
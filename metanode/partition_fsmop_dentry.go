@@ -53,6 +53,10 @@ func (mp *metaPartition) fsmCreateDentry(dentry *Dentry,
 			status = proto.OpArgMismatchErr
 			return
 		}
+		if mp.txLockedDentry(dentry.ParentId, dentry.Name) {
+			status = proto.OpAgain
+			return
+		}
 	}
 	if item, ok := mp.dentryTree.ReplaceOrInsert(dentry, false); !ok {
 		//do not allow directories and files to overwrite each
@@ -73,6 +77,7 @@ func (mp *metaPartition) fsmCreateDentry(dentry *Dentry,
 			parIno.IncNLink()
 			parIno.SetMtime()
 		}
+		mp.linkDirSummary(dentry, true)
 	}
 
 	return
@@ -95,6 +100,10 @@ func (mp *metaPartition) fsmDeleteDentry(dentry *Dentry, checkInode bool) (
 	resp *DentryResponse) {
 	resp = NewDentryResponse()
 	resp.Status = proto.OpOk
+	if mp.txLockedDentry(dentry.ParentId, dentry.Name) {
+		resp.Status = proto.OpAgain
+		return
+	}
 
 	var item interface{}
 	if checkInode {
@@ -128,6 +137,7 @@ func (mp *metaPartition) fsmDeleteDentry(dentry *Dentry, checkInode bool) (
 			})
 	}
 	resp.Msg = item.(*Dentry)
+	mp.linkDirSummary(resp.Msg, false)
 	return
 }
 
@@ -144,6 +154,10 @@ func (mp *metaPartition) fsmUpdateDentry(dentry *Dentry) (
 	resp *DentryResponse) {
 	resp = NewDentryResponse()
 	resp.Status = proto.OpOk
+	if mp.txLockedDentry(dentry.ParentId, dentry.Name) {
+		resp.Status = proto.OpAgain
+		return
+	}
 	mp.dentryTree.CopyFind(dentry, func(item BtreeItem) {
 		if item == nil {
 			resp.Status = proto.OpNotExistErr
@@ -152,6 +166,8 @@ func (mp *metaPartition) fsmUpdateDentry(dentry *Dentry) (
 		d := item.(*Dentry)
 		d.Inode, dentry.Inode = dentry.Inode, d.Inode
 		resp.Msg = dentry
+		mp.linkDirSummary(&Dentry{ParentId: d.ParentId, Inode: dentry.Inode, Type: d.Type}, false)
+		mp.linkDirSummary(d, true)
 	})
 	return
 }
@@ -179,3 +195,54 @@ func (mp *metaPartition) readDir(req *ReadDirReq) (resp *ReadDirResp) {
 	})
 	return
 }
+
+// readDirPlus reads the dentries of the directory after the marker and matching the prefix
+// in the order of the names. Since the dentries are ordered in the tree, the reading starts
+// from the greater one of the marker and the prefix, and stops at the first name out of the
+// prefix, which costs O(limit) no matter how many dentries the directory has.
+func (mp *metaPartition) readDirPlus(req *ReadDirPlusReq) (resp *ReadDirPlusResp) {
+	resp = &ReadDirPlusResp{}
+	var limit = req.Limit
+	if limit == 0 || limit > defaultReadDirPlusLimit {
+		limit = defaultReadDirPlusLimit
+	}
+	begDentry := &Dentry{
+		ParentId: req.ParentID,
+		Name:     req.Marker,
+	}
+	if req.Prefix > req.Marker {
+		begDentry.Name = req.Prefix
+	}
+	endDentry := &Dentry{
+		ParentId: req.ParentID + 1,
+	}
+	mp.dentryTree.AscendRange(begDentry, endDentry, func(i BtreeItem) bool {
+		d := i.(*Dentry)
+		if d.Name == req.Marker {
+			return true
+		}
+		if !strings.HasPrefix(d.Name, req.Prefix) {
+			return false
+		}
+		if uint64(len(resp.Children)) >= limit {
+			resp.More = true
+			return false
+		}
+		resp.Children = append(resp.Children, proto.Dentry{
+			Inode: d.Inode,
+			Type:  d.Type,
+			Name:  d.Name,
+		})
+		// the inodes of the children may be stored in the other partitions
+		if item := mp.inodeTree.Get(&Inode{Inode: d.Inode}); item != nil {
+			if ino := item.(*Inode); !ino.ShouldDelete() {
+				info := &proto.InodeInfo{}
+				if replyInfo(info, ino) {
+					resp.Infos = append(resp.Infos, info)
+				}
+			}
+		}
+		return true
+	})
+	return
+}
@@ -14,12 +14,18 @@
 
 package metanode
 
+import "github.com/chubaofs/chubaofs/proto"
+
 type ExtendOpResult struct {
 	Status uint8
 	Extend *Extend
 }
 
 func (mp *metaPartition) fsmSetXAttr(extend *Extend) (err error) {
+	if ino := mp.quotaXAttrInode(extend); ino != nil {
+		mp.accountInode(ino, -1)
+		defer mp.accountInode(ino, 1)
+	}
 	treeItem := mp.extendTree.CopyGet(extend)
 	var e *Extend
 	if treeItem == nil {
@@ -33,6 +39,10 @@ func (mp *metaPartition) fsmSetXAttr(extend *Extend) (err error) {
 }
 
 func (mp *metaPartition) fsmRemoveXAttr(extend *Extend) (err error) {
+	if ino := mp.quotaXAttrInode(extend); ino != nil {
+		mp.accountInode(ino, -1)
+		defer mp.accountInode(ino, 1)
+	}
 	treeItem := mp.extendTree.CopyGet(extend)
 	if treeItem == nil {
 		return
@@ -44,3 +54,16 @@ func (mp *metaPartition) fsmRemoveXAttr(extend *Extend) (err error) {
 	})
 	return
 }
+
+// quotaXAttrInode returns the inode if the directory quotas of it are changed
+// by the extend, which moves the usage of the inode between the quotas.
+func (mp *metaPartition) quotaXAttrInode(extend *Extend) *Inode {
+	if _, ok := extend.Get([]byte(proto.QuotaXAttrKey)); !ok {
+		return nil
+	}
+	item := mp.inodeTree.Get(NewInode(extend.inode, 0))
+	if item == nil || item.(*Inode).ShouldDelete() {
+		return nil
+	}
+	return item.(*Inode)
+}
@@ -38,7 +38,27 @@ func (mp *metaPartition) fsmCreateInode(ino *Inode) (status uint8) {
 	status = proto.OpOk
 	if _, ok := mp.inodeTree.ReplaceOrInsert(ino, false); !ok {
 		status = proto.OpExistErr
+		return
 	}
+	mp.accountInode(ino, 1)
+	return
+}
+
+// CreateInodeInQuotasReq defines the inode created in the directory quotas of its parent.
+type CreateInodeInQuotasReq struct {
+	Inode    []byte   `json:"ino"`
+	QuotaIds []uint64 `json:"qids"`
+}
+
+// fsmCreateInodeInQuotas creates the inode and puts it in the directory quotas
+// in one step, so the inode is never left out of the quotas.
+func (mp *metaPartition) fsmCreateInodeInQuotas(ino *Inode, quotaIds []uint64) (status uint8) {
+	if status = mp.fsmCreateInode(ino); status != proto.OpOk {
+		return
+	}
+	extend := NewExtend(ino.Inode)
+	extend.Put([]byte(proto.QuotaXAttrKey), []byte(proto.FormatQuotaIds(quotaIds)))
+	_ = mp.fsmSetXAttr(extend)
 	return
 }
 
@@ -124,6 +144,7 @@ func (mp *metaPartition) fsmUnlinkInode(ino *Inode) (resp *InodeResponse) {
 	resp.Msg = inode
 
 	if inode.IsEmptyDir() {
+		mp.accountInode(inode, -1)
 		mp.inodeTree.Delete(inode)
 	}
 
@@ -194,6 +215,9 @@ func (mp *metaPartition) internalDeleteBatch(val []byte) error {
 }
 
 func (mp *metaPartition) internalDeleteInode(ino *Inode) {
+	if item := mp.inodeTree.Get(ino); item != nil && !item.(*Inode).ShouldDelete() {
+		mp.accountInode(item.(*Inode), -1)
+	}
 	mp.inodeTree.Delete(ino)
 	mp.freeList.Remove(ino.Inode)
 	mp.extendTree.Delete(&Extend{inode: ino.Inode}) // Also delete extend attribute.
@@ -213,7 +237,12 @@ func (mp *metaPartition) fsmAppendExtents(ino *Inode) (status uint8) {
 		return
 	}
 	eks := ino.Extents.CopyExtents()
+	oldSize := ino2.Size
 	delExtents := ino2.AppendExtents(eks, ino.ModifyTime)
+	mp.accountQuota(mp.inodeQuotaKeys(ino2), int64(ino2.Size)-int64(oldSize), 0)
+	if ino2.Size != oldSize {
+		mp.summaryChanges.record(ino2.Inode)
+	}
 	log.LogInfof("fsmAppendExtents inode(%v) deleteExtents(%v)", ino2.Inode, delExtents)
 	mp.extDelCh <- delExtents
 	return
@@ -241,8 +270,13 @@ func (mp *metaPartition) fsmAppendExtentsWithCheck(ino *Inode) (status uint8) {
 	if len(eks) > 1 {
 		discardExtentKey = eks[1:]
 	}
+	oldSize := ino2.Size
 	delExtents, status := ino2.AppendExtentWithCheck(eks[0], ino.ModifyTime, discardExtentKey)
 	if status == proto.OpOk {
+		mp.accountQuota(mp.inodeQuotaKeys(ino2), int64(ino2.Size)-int64(oldSize), 0)
+		if ino2.Size != oldSize {
+			mp.summaryChanges.record(ino2.Inode)
+		}
 		mp.extDelCh <- delExtents
 	}
 	log.LogInfof("fsmAppendExtentWithCheck inode(%v) ek(%v) deleteExtents(%v) discardExtents(%v) status(%v)", ino2.Inode, eks[0], delExtents, discardExtentKey, status)
@@ -268,7 +302,12 @@ func (mp *metaPartition) fsmExtentsTruncate(ino *Inode) (resp *InodeResponse) {
 		return
 	}
 
+	oldSize := i.Size
 	delExtents := i.ExtentsTruncate(ino.Size, ino.ModifyTime)
+	mp.accountQuota(mp.inodeQuotaKeys(i), int64(i.Size)-int64(oldSize), 0)
+	if i.Size != oldSize {
+		mp.summaryChanges.record(i.Inode)
+	}
 
 	// now we should delete the extent
 	log.LogInfof("fsmExtentsTruncate inode(%v) exts(%v)", i.Inode, delExtents)
@@ -291,12 +330,14 @@ func (mp *metaPartition) fsmEvictInode(ino *Inode) (resp *InodeResponse) {
 	}
 	if proto.IsDir(i.Type) {
 		if i.IsEmptyDir() {
+			mp.accountInode(i, -1)
 			i.SetDeleteMark()
 		}
 		return
 	}
 
 	if i.IsTempFile() {
+		mp.accountInode(i, -1)
 		i.SetDeleteMark()
 		mp.freeList.Push(i.Inode)
 	}
@@ -332,6 +373,10 @@ func (mp *metaPartition) fsmSetAttr(req *SetattrRequest) (err error) {
 	if ino.ShouldDelete() {
 		return
 	}
+	if req.Valid&(proto.AttrUid|proto.AttrGid) != 0 {
+		mp.accountInode(ino, -1)
+		defer mp.accountInode(ino, 1)
+	}
 	ino.SetAttr(req)
 	return
 }
@@ -0,0 +1,54 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import "github.com/chubaofs/chubaofs/proto"
+
+func (mp *metaPartition) fsmSetLock(cmd *LockCmd) (status uint8) {
+	var stored = NewInodeLocks(cmd.Inode)
+	if item := mp.lockTree.Get(stored); item != nil {
+		stored = item.(*InodeLocks)
+	}
+	if cmd.Lock.Type != proto.LockUnlock && stored.Conflict(&cmd.Lock, cmd.Now) != nil {
+		return proto.OpLockConflictErr
+	}
+	locks := stored.Apply(&cmd.Lock, cmd.Expire, cmd.Now)
+	if locks.Len() == 0 {
+		mp.lockTree.Delete(locks)
+		return proto.OpOk
+	}
+	mp.lockTree.ReplaceOrInsert(locks, true)
+	return proto.OpOk
+}
+
+// fsmRenewLock renews the locks held by the client and purges the expired
+// locks of the whole partition. It returns the number of renewed locks.
+func (mp *metaPartition) fsmRenewLock(cmd *RenewLockCmd) (count uint32) {
+	var renewed []*InodeLocks
+	mp.lockTree.Ascend(func(i BtreeItem) bool {
+		locks, n := i.(*InodeLocks).Renew(cmd.Client, cmd.Expire, cmd.Now)
+		renewed = append(renewed, locks)
+		count += uint32(n)
+		return true
+	})
+	for _, locks := range renewed {
+		if locks.Len() == 0 {
+			mp.lockTree.Delete(locks)
+			continue
+		}
+		mp.lockTree.ReplaceOrInsert(locks, true)
+	}
+	return
+}
@@ -0,0 +1,171 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// fsmCreateMetaSnapshot creates a snapshot sharing the nodes of the live trees.
+// The btree is copy-on-write, and the fsm updates the items got by CopyGet,
+// so the snapshot is not changed by the later updates of the live trees.
+// The extents referenced by the snapshot are counted in the background, and
+// the released extents are not deleted until they are counted.
+func (mp *metaPartition) fsmCreateMetaSnapshot(cmd *MetaSnapshotCmd) (status uint8) {
+	if mp.getMetaSnapshot(cmd.ID) != nil {
+		return proto.OpOk
+	}
+	snap := &MetaSnapshot{
+		ID:         cmd.ID,
+		CreateTime: cmd.CreateTime,
+		inodeTree:  mp.inodeTree.GetTree(),
+		dentryTree: mp.dentryTree.GetTree(),
+		extendTree: mp.extendTree.GetTree(),
+	}
+
+	mp.snapMutex.Lock()
+	if mp.snapshots == nil {
+		mp.snapshots = make(map[uint64]*MetaSnapshot)
+		mp.snapExtents = make(map[snapExtentKey]int)
+	}
+	mp.snapshots[snap.ID] = snap
+	mp.snapPending++
+	mp.snapMutex.Unlock()
+	go mp.countMetaSnapshotExtents(snap)
+	log.LogInfof("fsmCreateMetaSnapshot: partitionID(%v) snapshot(%v)", mp.config.PartitionId, snap)
+	return proto.OpOk
+}
+
+// fsmDeleteMetaSnapshot removes the snapshot. The extents referenced by
+// neither the live tree nor the other snapshots are deleted in the background.
+func (mp *metaPartition) fsmDeleteMetaSnapshot(cmd *MetaSnapshotCmd) (status uint8) {
+	mp.snapMutex.Lock()
+	snap, ok := mp.snapshots[cmd.ID]
+	if !ok {
+		mp.snapMutex.Unlock()
+		return proto.OpOk
+	}
+	delete(mp.snapshots, cmd.ID)
+	mp.snapPending++
+	mp.snapMutex.Unlock()
+	go mp.releaseMetaSnapshotExtents(snap)
+	log.LogInfof("fsmDeleteMetaSnapshot: partitionID(%v) snapshot(%v)", mp.config.PartitionId, snap)
+	return proto.OpOk
+}
+
+func (mp *metaPartition) countMetaSnapshotExtents(snap *MetaSnapshot) {
+	keys := snap.extents()
+	mp.snapMutex.Lock()
+	// the snapshot may be deleted or replaced by a raft snapshot in the meantime
+	if mp.snapshots[snap.ID] == snap {
+		for key := range keys {
+			mp.snapExtents[key]++
+		}
+		snap.counted = true
+	}
+	mp.snapPending--
+	mp.snapMutex.Unlock()
+	log.LogInfof("countMetaSnapshotExtents: partitionID(%v) snapshot(%v) numExtents(%v)",
+		mp.config.PartitionId, snap, len(keys))
+}
+
+func (mp *metaPartition) releaseMetaSnapshotExtents(snap *MetaSnapshot) {
+	keys := snap.extents()
+	released := make(map[snapExtentKey]struct{})
+	mp.snapMutex.Lock()
+	// the extents of a snapshot deleted before counted are never kept
+	if snap.counted {
+		for key := range keys {
+			if mp.snapExtents[key]--; mp.snapExtents[key] <= 0 {
+				delete(mp.snapExtents, key)
+				released[key] = struct{}{}
+			}
+		}
+	}
+	mp.snapPending--
+	mp.snapMutex.Unlock()
+
+	if len(released) == 0 {
+		return
+	}
+	mp.inodeTree.Ascend(func(i BtreeItem) bool {
+		i.(*Inode).Extents.Range(func(ek proto.ExtentKey) bool {
+			delete(released, newSnapExtentKey(&ek))
+			return true
+		})
+		return len(released) > 0
+	})
+	delExtents := make([]proto.ExtentKey, 0, len(released))
+	snap.inodeTree.Ascend(func(i BtreeItem) bool {
+		i.(*Inode).Extents.Range(func(ek proto.ExtentKey) bool {
+			key := newSnapExtentKey(&ek)
+			if _, ok := released[key]; ok {
+				delete(released, key)
+				delExtents = append(delExtents, ek)
+			}
+			return true
+		})
+		return true
+	})
+	log.LogInfof("releaseMetaSnapshotExtents: partitionID(%v) snapshot(%v) numDelExtents(%v)",
+		mp.config.PartitionId, snap, len(delExtents))
+	if len(delExtents) > 0 {
+		mp.extDelCh <- delExtents
+	}
+}
+
+// applyMetaSnapshotItem adds an item of the snapshot received from the leader.
+func applyMetaSnapshotItem(snapshots map[uint64]*MetaSnapshot, key, value []byte) (err error) {
+	var (
+		id   uint64
+		item *MetaItem
+	)
+	if id, item, err = unmarshalMetaSnapshotItem(key, value); err != nil {
+		return
+	}
+	snap, ok := snapshots[id]
+	if !ok {
+		snap = newEmptyMetaSnapshot(id, 0)
+		snapshots[id] = snap
+	}
+	switch item.Op {
+	case opFSMCreateInode:
+		ino := NewInode(0, 0)
+		if err = ino.UnmarshalKey(item.K); err != nil {
+			return
+		}
+		if err = ino.UnmarshalValue(item.V); err != nil {
+			return
+		}
+		snap.inodeTree.ReplaceOrInsert(ino, true)
+	case opFSMCreateDentry:
+		dentry := &Dentry{}
+		if err = dentry.UnmarshalKey(item.K); err != nil {
+			return
+		}
+		if err = dentry.UnmarshalValue(item.V); err != nil {
+			return
+		}
+		snap.dentryTree.ReplaceOrInsert(dentry, true)
+	case opFSMSetXAttr:
+		var extend *Extend
+		if extend, err = NewExtendFromBytes(item.V); err != nil {
+			return
+		}
+		snap.extendTree.ReplaceOrInsert(extend, true)
+	}
+	return
+}
@@ -0,0 +1,284 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+func (mp *metaPartition) getTxRecord(txID string) *TxRecord {
+	item := mp.txTree.Get(newTxRecordKey(txID))
+	if item == nil {
+		return nil
+	}
+	return item.(*TxRecord)
+}
+
+// txLockedDentry returns true if the dentry is changed by a prepared transaction.
+func (mp *metaPartition) txLockedDentry(parentID uint64, name string) (locked bool) {
+	if mp.txTree.Len() == 0 {
+		return false
+	}
+	mp.txTree.Ascend(func(i BtreeItem) bool {
+		locked = i.(*TxRecord).LocksDentry(mp.config.PartitionId, parentID, name)
+		return !locked
+	})
+	return
+}
+
+// fsmTxPrepare checks that the local items of the transaction can be done,
+// and locks the dentries changed by them. The dentries to be created are
+// counted in the links of their parents at once, so that the parents are
+// not empty and can not be removed until the transaction is decided. Nothing
+// else is changed until commit, which then can not fail.
+func (mp *metaPartition) fsmTxPrepare(rec *TxRecord) (status uint8) {
+	if stored := mp.getTxRecord(rec.Tx.TxID); stored != nil {
+		if stored.State == proto.TxStateAborted {
+			return proto.OpTxAbortedErr
+		}
+		return proto.OpOk
+	}
+	for _, item := range rec.LocalItems(mp.config.PartitionId) {
+		if status = mp.checkTxItem(item); status != proto.OpOk {
+			log.LogWarnf("fsmTxPrepare: mp(%v) tx(%v) item(%v) status(%v)",
+				mp.config.PartitionId, rec.Tx.TxID, item, status)
+			return
+		}
+	}
+	mp.txTree.ReplaceOrInsert(rec, true)
+	mp.reserveTxParents(rec, true)
+	return proto.OpOk
+}
+
+// reserveTxParents adds or removes the links of the dentries to be created by
+// the transaction to or from their parents.
+func (mp *metaPartition) reserveTxParents(rec *TxRecord, reserve bool) {
+	for _, item := range rec.LocalItems(mp.config.PartitionId) {
+		if item.Op != proto.TxOpCreateDentry || item.OldInode != 0 {
+			continue
+		}
+		mp.inodeTree.CopyFind(NewInode(item.ParentID, 0), func(i BtreeItem) {
+			if i == nil {
+				return
+			}
+			if reserve {
+				i.(*Inode).IncNLink()
+			} else {
+				i.(*Inode).DecNLink()
+			}
+		})
+	}
+}
+
+func (mp *metaPartition) checkTxItem(item *proto.TxItem) uint8 {
+	switch item.Op {
+	case proto.TxOpCreateDentry:
+		if mp.txLockedDentry(item.ParentID, item.Name) {
+			return proto.OpAgain
+		}
+		parent := mp.inodeTree.Get(NewInode(item.ParentID, 0))
+		if parent == nil || parent.(*Inode).ShouldDelete() {
+			return proto.OpNotExistErr
+		}
+		if !proto.IsDir(parent.(*Inode).Type) {
+			return proto.OpArgMismatchErr
+		}
+		d := mp.dentryTree.Get(&Dentry{ParentId: item.ParentID, Name: item.Name})
+		if item.OldInode == 0 {
+			if d != nil {
+				return proto.OpExistErr
+			}
+			return proto.OpOk
+		}
+		if d == nil {
+			return proto.OpNotExistErr
+		}
+		if d.(*Dentry).Inode != item.OldInode {
+			return proto.OpExistErr
+		}
+		if proto.OsModeType(d.(*Dentry).Type) != proto.OsModeType(item.Type) {
+			return proto.OpArgMismatchErr
+		}
+	case proto.TxOpDeleteDentry:
+		if mp.txLockedDentry(item.ParentID, item.Name) {
+			return proto.OpAgain
+		}
+		d := mp.dentryTree.Get(&Dentry{ParentId: item.ParentID, Name: item.Name})
+		if d == nil || d.(*Dentry).Inode != item.Inode {
+			return proto.OpNotExistErr
+		}
+	case proto.TxOpUnlinkInode:
+		ino := mp.inodeTree.Get(NewInode(item.Inode, 0))
+		if ino == nil || ino.(*Inode).ShouldDelete() {
+			return proto.OpNotExistErr
+		}
+	case proto.TxOpSetQuotaIds:
+		ino := mp.inodeTree.Get(NewInode(item.Inode, 0))
+		if ino == nil || ino.(*Inode).ShouldDelete() {
+			return proto.OpNotExistErr
+		}
+		// the inode can not be moved into a quota which has exceeded the limits
+		current := mp.inodeQuotaKeys(ino.(*Inode))
+		added := make([]quotaKey, 0)
+		for _, key := range newDirQuotaKeys(item.QuotaIds) {
+			if !containsQuotaKey(current, key) {
+				added = append(added, key)
+			}
+		}
+		if mp.isQuotaExceeded(added, true, true) {
+			return proto.OpQuotaExceededErr
+		}
+	case proto.TxOpPutObjectVersion, proto.TxOpDeleteObjectVersion:
+		// the version is locked by the parent and the name of the item
+		v := item.Version
+		if v == nil || v.ParentID != item.ParentID || v.Name != item.Name || v.VersionID == "" {
+			return proto.OpArgMismatchErr
+		}
+		if mp.txLockedDentry(item.ParentID, item.Name) {
+			return proto.OpAgain
+		}
+		if item.Op == proto.TxOpDeleteObjectVersion && mp.versionTree.Get(NewObjectVersion(v.ParentID, v.Name, v.VersionID)) == nil {
+			return proto.OpNotExistErr
+		}
+	default:
+		return proto.OpArgMismatchErr
+	}
+	return proto.OpOk
+}
+
+// applyTxItems does the local items of a committed transaction.
+func (mp *metaPartition) applyTxItems(rec *TxRecord) {
+	for _, item := range rec.LocalItems(mp.config.PartitionId) {
+		var status uint8
+		switch item.Op {
+		case proto.TxOpCreateDentry:
+			dentry := &Dentry{ParentId: item.ParentID, Name: item.Name, Inode: item.Inode, Type: item.Type}
+			if item.OldInode == 0 {
+				// the parent is kept by the link reserved at prepare
+				if status = mp.fsmCreateDentry(dentry, true); status == proto.OpOk {
+					mp.inodeTree.CopyFind(NewInode(item.ParentID, 0), func(i BtreeItem) {
+						if i != nil {
+							i.(*Inode).SetMtime()
+						}
+					})
+				}
+			} else {
+				status = mp.fsmUpdateDentry(dentry).Status
+			}
+		case proto.TxOpDeleteDentry:
+			dentry := &Dentry{ParentId: item.ParentID, Name: item.Name, Inode: item.Inode}
+			status = mp.fsmDeleteDentry(dentry, true).Status
+		case proto.TxOpUnlinkInode:
+			if status = mp.fsmUnlinkInode(NewInode(item.Inode, 0)).Status; status == proto.OpOk {
+				status = mp.fsmEvictInode(NewInode(item.Inode, 0)).Status
+			}
+		case proto.TxOpSetQuotaIds:
+			// the usage of the inode is moved between the quotas by the extend
+			extend := NewExtend(item.Inode)
+			if len(item.QuotaIds) == 0 {
+				extend.Put([]byte(proto.QuotaXAttrKey), nil)
+				_ = mp.fsmRemoveXAttr(extend)
+			} else {
+				extend.Put([]byte(proto.QuotaXAttrKey), []byte(proto.FormatQuotaIds(item.QuotaIds)))
+				_ = mp.fsmSetXAttr(extend)
+			}
+		case proto.TxOpPutObjectVersion:
+			status = mp.fsmPutObjectVersion(&ObjectVersion{ObjectVersionInfo: *item.Version}).Status
+		case proto.TxOpDeleteObjectVersion:
+			v := item.Version
+			status = mp.fsmDeleteObjectVersion(NewObjectVersion(v.ParentID, v.Name, v.VersionID)).Status
+		}
+		// the items are checked and locked at prepare, so a failure here means
+		// the tree has been changed behind the locks
+		if status != proto.OpOk {
+			log.LogErrorf("applyTxItems: mp(%v) tx(%v) item(%v) status(%v)",
+				mp.config.PartitionId, rec.Tx.TxID, item, status)
+		}
+	}
+}
+
+// fsmTxCommit commits the transaction. The transaction manager keeps the
+// record as committed, while a participant drops it.
+// Committing an unknown transaction succeeds on a participant, since it has
+// been committed before, but fails on the transaction manager, since it has
+// been aborted.
+func (mp *metaPartition) fsmTxCommit(cmd *TxCmd) (status uint8) {
+	rec := mp.getTxRecord(cmd.TxID)
+	if rec == nil {
+		if cmd.TmID == mp.config.PartitionId {
+			return proto.OpTxAbortedErr
+		}
+		return proto.OpOk
+	}
+	if !rec.IsTM(mp.config.PartitionId) {
+		mp.txTree.Delete(rec)
+		mp.applyTxItems(rec)
+		return proto.OpOk
+	}
+	switch rec.State {
+	case proto.TxStateCommitted:
+		return proto.OpOk
+	case proto.TxStateAborted:
+		return proto.OpTxAbortedErr
+	}
+	committed := rec.Copy().(*TxRecord)
+	committed.State = proto.TxStateCommitted
+	mp.txTree.ReplaceOrInsert(committed, true)
+	mp.applyTxItems(committed)
+	return proto.OpOk
+}
+
+// fsmTxAbort aborts the transaction, which fails if it has been committed.
+func (mp *metaPartition) fsmTxAbort(cmd *TxCmd) (status uint8) {
+	rec := mp.getTxRecord(cmd.TxID)
+	if rec == nil {
+		return proto.OpOk
+	}
+	if !rec.IsTM(mp.config.PartitionId) {
+		mp.txTree.Delete(rec)
+		mp.reserveTxParents(rec, false)
+		return proto.OpOk
+	}
+	switch rec.State {
+	case proto.TxStateAborted:
+		return proto.OpOk
+	case proto.TxStateCommitted:
+		return proto.OpNotPerm
+	}
+	aborted := rec.Copy().(*TxRecord)
+	aborted.State = proto.TxStateAborted
+	mp.txTree.ReplaceOrInsert(aborted, true)
+	mp.reserveTxParents(aborted, false)
+	return proto.OpOk
+}
+
+// fsmTxPushed marks the decision of the transaction delivered to all of the
+// participants.
+func (mp *metaPartition) fsmTxPushed(cmd *TxCmd) (status uint8) {
+	rec := mp.getTxRecord(cmd.TxID)
+	if rec == nil || rec.State == proto.TxStatePrepared {
+		return proto.OpOk
+	}
+	pushed := rec.Copy().(*TxRecord)
+	pushed.Pushed = true
+	mp.txTree.ReplaceOrInsert(pushed, true)
+	return proto.OpOk
+}
+
+func (mp *metaPartition) fsmTxRemove(cmd *TxCmd) (status uint8) {
+	mp.txTree.Delete(newTxRecordKey(cmd.TxID))
+	return proto.OpOk
+}
@@ -0,0 +1,51 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import "github.com/chubaofs/chubaofs/proto"
+
+type ObjectVersionResponse struct {
+	Status uint8
+	Msg    *ObjectVersion
+}
+
+// fsmPutObjectVersion inserts the version into the version tree, and returns
+// the stored version of the same version ID which is replaced by it.
+func (mp *metaPartition) fsmPutObjectVersion(version *ObjectVersion) (resp *ObjectVersionResponse) {
+	resp = &ObjectVersionResponse{Status: proto.OpOk}
+	if mp.txLockedDentry(version.ParentID, version.Name) {
+		resp.Status = proto.OpAgain
+		return
+	}
+	if item, _ := mp.versionTree.ReplaceOrInsert(version, true); item != nil {
+		resp.Msg = item.(*ObjectVersion)
+	}
+	return
+}
+
+func (mp *metaPartition) fsmDeleteObjectVersion(version *ObjectVersion) (resp *ObjectVersionResponse) {
+	resp = &ObjectVersionResponse{Status: proto.OpOk}
+	if mp.txLockedDentry(version.ParentID, version.Name) {
+		resp.Status = proto.OpAgain
+		return
+	}
+	item := mp.versionTree.Delete(version)
+	if item == nil {
+		resp.Status = proto.OpNotExistErr
+		return
+	}
+	resp.Msg = item.(*ObjectVersion)
+	return
+}
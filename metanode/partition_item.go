@@ -129,6 +129,10 @@ type MetaItemIterator struct {
 	dentryTree    *BTree
 	extendTree    *BTree
 	multipartTree *BTree
+	versionTree   *BTree
+	lockTree      *BTree
+	txTree        *BTree
+	snapshots     []*MetaSnapshot
 
 	filenames []string
 
@@ -148,6 +152,10 @@ func newMetaItemIterator(mp *metaPartition) (si *MetaItemIterator, err error) {
 	si.dentryTree = mp.dentryTree.GetTree()
 	si.extendTree = mp.extendTree.GetTree()
 	si.multipartTree = mp.multipartTree.GetTree()
+	si.versionTree = mp.versionTree.GetTree()
+	si.lockTree = mp.lockTree.GetTree()
+	si.txTree = mp.txTree.GetTree()
+	si.snapshots = mp.metaSnapshotList()
 	si.dataCh = make(chan interface{})
 	si.errorCh = make(chan error, 1)
 	si.closeCh = make(chan struct{})
@@ -228,6 +236,41 @@ func newMetaItemIterator(mp *metaPartition) (si *MetaItemIterator, err error) {
 		if checkClose() {
 			return
 		}
+		// process object versions
+		iter.versionTree.Ascend(func(i BtreeItem) bool {
+			return produceItem(i)
+		})
+		if checkClose() {
+			return
+		}
+		// process locks
+		iter.lockTree.Ascend(func(i BtreeItem) bool {
+			return produceItem(i)
+		})
+		if checkClose() {
+			return
+		}
+		// process transactions
+		iter.txTree.Ascend(func(i BtreeItem) bool {
+			return produceItem(i)
+		})
+		if checkClose() {
+			return
+		}
+		// process meta snapshots
+		for _, snap := range iter.snapshots {
+			if !produceItem(snap) {
+				return
+			}
+			for _, tree := range []*BTree{snap.inodeTree, snap.dentryTree, snap.extendTree} {
+				tree.Ascend(func(i BtreeItem) bool {
+					return produceItem(&metaSnapshotItem{id: snap.ID, item: i})
+				})
+				if checkClose() {
+					return
+				}
+			}
+		}
 		// process extent del files
 		var err error
 		var raw []byte
@@ -309,6 +352,61 @@ func (si *MetaItemIterator) Next() (data []byte, err error) {
 			return
 		}
 		snap = NewMetaItem(opFSMCreateMultipart, nil, raw)
+	case *ObjectVersion:
+		var raw []byte
+		if raw, err = typedItem.Bytes(); err != nil {
+			si.err = err
+			si.Close()
+			return
+		}
+		snap = NewMetaItem(opFSMPutObjectVersion, nil, raw)
+	case *InodeLocks:
+		var raw []byte
+		if raw, err = typedItem.Bytes(); err != nil {
+			si.err = err
+			si.Close()
+			return
+		}
+		snap = NewMetaItem(opFSMSetLock, nil, raw)
+	case *TxRecord:
+		var raw []byte
+		if raw, err = typedItem.Bytes(); err != nil {
+			si.err = err
+			si.Close()
+			return
+		}
+		snap = NewMetaItem(opFSMTxPrepare, nil, raw)
+	case *MetaSnapshot:
+		var raw []byte
+		if raw, err = (&MetaSnapshotCmd{ID: typedItem.ID, CreateTime: typedItem.CreateTime}).Marshal(); err != nil {
+			si.err = err
+			si.Close()
+			return
+		}
+		snap = NewMetaItem(opFSMCreateMetaSnapshot, nil, raw)
+	case *metaSnapshotItem:
+		var inner *MetaItem
+		switch item := typedItem.item.(type) {
+		case *Inode:
+			inner = NewMetaItem(opFSMCreateInode, item.MarshalKey(), item.MarshalValue())
+		case *Dentry:
+			inner = NewMetaItem(opFSMCreateDentry, item.MarshalKey(), item.MarshalValue())
+		case *Extend:
+			var raw []byte
+			if raw, err = item.Bytes(); err != nil {
+				si.err = err
+				si.Close()
+				return
+			}
+			inner = NewMetaItem(opFSMSetXAttr, nil, raw)
+		}
+		var key, value []byte
+		if key, value, err = marshalMetaSnapshotItem(typedItem.id, inner); err != nil {
+			si.err = err
+			si.Close()
+			return
+		}
+		snap = NewMetaItem(opFSMMetaSnapshotItem, key, value)
 	case *fileData:
 		snap = NewMetaItem(opExtentFileSnapshot, []byte(typedItem.filename), typedItem.data)
 	default:
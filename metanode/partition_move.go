@@ -0,0 +1,462 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util"
+	"github.com/chubaofs/chubaofs/util/errors"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+const (
+	moveItemsBatchCount = 1024
+	moveItemsBatchSize  = 4 * util.MB
+)
+
+// ApplyMovedItemsReq defines the items moved from another meta partition.
+type ApplyMovedItemsReq struct {
+	PartitionID uint64      `json:"pid"`
+	Source      uint64      `json:"src"`
+	Items       []*MetaItem `json:"items"`
+}
+
+// itemsMover keeps the progress of moving the items to another partition on the leader.
+type itemsMover struct {
+	sync.Mutex
+	req    *proto.MoveMetaItemsRequest
+	status string
+	moved  uint64
+	err    error
+}
+
+func (m *itemsMover) progress() *proto.MoveMetaItemsResponse {
+	resp := &proto.MoveMetaItemsResponse{Status: m.status, Moved: m.moved}
+	if m.err != nil {
+		resp.Result = m.err.Error()
+	}
+	return resp
+}
+
+func (m *itemsMover) add(moved uint64) {
+	m.Lock()
+	defer m.Unlock()
+	m.moved += moved
+}
+
+func (m *itemsMover) finish(err error) {
+	m.Lock()
+	defer m.Unlock()
+	m.err = err
+	if err != nil {
+		m.status = proto.MetaItemsFailed
+		return
+	}
+	m.status = proto.MetaItemsMoved
+}
+
+// rangedTree defines a tree whose items belong to the partition owning the inode of their keys.
+type rangedTree struct {
+	tree  *BTree
+	pivot BtreeItem
+	inode func(i BtreeItem) uint64
+}
+
+func (mp *metaPartition) rangedTrees(start uint64) []rangedTree {
+	return []rangedTree{
+		{mp.inodeTree, NewInode(start, 0), func(i BtreeItem) uint64 { return i.(*Inode).Inode }},
+		{mp.dentryTree, &Dentry{ParentId: start}, func(i BtreeItem) uint64 { return i.(*Dentry).ParentId }},
+		{mp.extendTree, NewExtend(start), func(i BtreeItem) uint64 { return i.(*Extend).inode }},
+		{mp.versionTree, NewObjectVersion(start, "", ""), func(i BtreeItem) uint64 { return i.(*ObjectVersion).ParentID }},
+		{mp.lockTree, NewInodeLocks(start), func(i BtreeItem) uint64 { return i.(*InodeLocks).inode }},
+	}
+}
+
+// rangeItems calls fn on the snapshots of the trees with the items of the inodes in [start, end]:
+// the inodes with their extended attributes and locks, and the dentries and the object versions
+// in the directories. It stops if fn returns false.
+func (mp *metaPartition) rangeItems(start, end uint64, fn func(tree *BTree, i BtreeItem) bool) {
+	for _, t := range mp.rangedTrees(start) {
+		var ok = true
+		var inode = t.inode
+		t.tree.GetTree().AscendGreaterOrEqual(t.pivot, func(i BtreeItem) bool {
+			if inode(i) > end {
+				return false
+			}
+			ok = fn(t.tree, i)
+			return ok
+		})
+		if !ok {
+			return
+		}
+	}
+}
+
+func newMovedMetaItem(i BtreeItem) (item *MetaItem, err error) {
+	var raw []byte
+	switch typedItem := i.(type) {
+	case *Inode:
+		item = NewMetaItem(opFSMCreateInode, typedItem.MarshalKey(), typedItem.MarshalValue())
+	case *Dentry:
+		item = NewMetaItem(opFSMCreateDentry, typedItem.MarshalKey(), typedItem.MarshalValue())
+	case *Extend:
+		if raw, err = typedItem.Bytes(); err != nil {
+			return
+		}
+		item = NewMetaItem(opFSMSetXAttr, nil, raw)
+	case *ObjectVersion:
+		if raw, err = typedItem.Bytes(); err != nil {
+			return
+		}
+		item = NewMetaItem(opFSMPutObjectVersion, nil, raw)
+	case *InodeLocks:
+		if raw, err = typedItem.Bytes(); err != nil {
+			return
+		}
+		item = NewMetaItem(opFSMSetLock, nil, raw)
+	default:
+		err = fmt.Errorf("unknown item type: %T", i)
+	}
+	return
+}
+
+// isItemChangeOp returns true if the operation changes the items of the partition, which is refused
+// by a frozen partition.
+func isItemChangeOp(opcode uint8) bool {
+	switch opcode {
+	case proto.OpMetaCreateInode, proto.OpMetaLinkInode, proto.OpMetaUnlinkInode, proto.OpMetaBatchUnlinkInode,
+		proto.OpMetaEvictInode, proto.OpMetaBatchEvictInode, proto.OpMetaSetattr, proto.OpMetaCreateDentry,
+		proto.OpMetaDeleteDentry, proto.OpMetaBatchDeleteDentry, proto.OpMetaUpdateDentry, proto.OpMetaExtentsAdd,
+		proto.OpMetaExtentAddWithCheck, proto.OpMetaExtentsDel, proto.OpMetaTruncate, proto.OpMetaBatchExtentsAdd,
+		proto.OpMetaDeleteInode, proto.OpMetaBatchDeleteInode, proto.OpMetaSetXAttr, proto.OpMetaRemoveXAttr,
+		proto.OpMetaSetLock, proto.OpMetaRenewLock, proto.OpMetaTxPrepare, proto.OpCreateMultipart,
+		proto.OpRemoveMultipart, proto.OpAddMultipartPart, proto.OpPutObjectVersion, proto.OpDeleteObjectVersion,
+		proto.OpCreateMetaSnapshot:
+		return true
+	}
+	return false
+}
+
+// IsFrozen returns true if the partition refuses the changes of its items.
+func (mp *metaPartition) IsFrozen() bool {
+	return mp.config.Frozen
+}
+
+// FreezePartition freezes or unfreezes the partition.
+func (mp *metaPartition) FreezePartition(req *proto.FreezeMetaPartitionRequest, p *Packet) (err error) {
+	var val []byte
+	if val, err = json.Marshal(req); err != nil {
+		p.PacketErrorWithBody(proto.OpErr, []byte(err.Error()))
+		return
+	}
+	resp, err := mp.submit(opFSMFreezePartition, val)
+	if err != nil {
+		p.PacketErrorWithBody(proto.OpAgain, []byte(err.Error()))
+		return
+	}
+	p.PacketErrorWithBody(resp.(uint8), nil)
+	return
+}
+
+// MoveItems starts moving the items of the inodes in range to the target partition, or returns the
+// progress if the same items are being moved. The partition has to be frozen, so that the items do
+// not change while being moved, and a failed move can be started again.
+func (mp *metaPartition) MoveItems(req *proto.MoveMetaItemsRequest) *proto.MoveMetaItemsResponse {
+	m := &mp.mover
+	m.Lock()
+	defer m.Unlock()
+	if m.req != nil && m.req.TargetPartitionID == req.TargetPartitionID && m.req.Start == req.Start &&
+		m.req.End == req.End && m.status != proto.MetaItemsFailed {
+		return m.progress()
+	}
+	if m.status == proto.MetaItemsMoving {
+		return &proto.MoveMetaItemsResponse{Status: proto.MetaItemsFailed,
+			Result: fmt.Sprintf("items are being moved to mp(%v)", m.req.TargetPartitionID)}
+	}
+	if !mp.IsFrozen() {
+		return &proto.MoveMetaItemsResponse{Status: proto.MetaItemsFailed, Result: "partition is not frozen"}
+	}
+	m.req, m.status, m.moved, m.err = req, proto.MetaItemsMoving, 0, nil
+	go mp.moveItems(req)
+	return m.progress()
+}
+
+func (mp *metaPartition) moveItems(req *proto.MoveMetaItemsRequest) {
+	var err error
+	defer func() {
+		mp.mover.finish(err)
+		if err != nil {
+			log.LogErrorf("moveItems: mp(%v) req(%v) err(%v)", mp.config.PartitionId, req, err)
+			return
+		}
+		log.LogInfof("moveItems: mp(%v) req(%v) finished", mp.config.PartitionId, req)
+	}()
+	// the changes proposed before the partition was frozen are applied before the barrier
+	var val []byte
+	if val, err = json.Marshal(&proto.FreezeMetaPartitionRequest{PartitionID: mp.config.PartitionId, Freeze: true}); err != nil {
+		return
+	}
+	if _, err = mp.submit(opFSMFreezePartition, val); err != nil {
+		return
+	}
+	if err = mp.checkItemsMovable(req.Start, req.End); err != nil {
+		return
+	}
+	var (
+		batch []*MetaItem
+		size  int
+	)
+	var flush = func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := mp.sendMovedItems(req, batch); err != nil {
+			return err
+		}
+		mp.mover.add(uint64(len(batch)))
+		batch, size = nil, 0
+		return nil
+	}
+	mp.rangeItems(req.Start, req.End, func(_ *BTree, i BtreeItem) bool {
+		var item *MetaItem
+		if item, err = newMovedMetaItem(i); err != nil {
+			return false
+		}
+		batch = append(batch, item)
+		size += len(item.K) + len(item.V)
+		if len(batch) >= moveItemsBatchCount || size >= moveItemsBatchSize {
+			err = flush()
+		}
+		return err == nil
+	})
+	if err == nil {
+		err = flush()
+	}
+}
+
+// checkItemsMovable returns an error if the items cannot be moved consistently: the snapshots refer
+// to the items of the partition, the pending transactions may change them, and the multipart
+// sessions are only found in the partition they are created in.
+func (mp *metaPartition) checkItemsMovable(start, end uint64) (err error) {
+	if len(mp.metaSnapshotList()) > 0 {
+		return errors.New("meta snapshots exist")
+	}
+	mp.txTree.Ascend(func(i BtreeItem) bool {
+		rec := i.(*TxRecord)
+		if rec.State == proto.TxStatePrepared || (rec.Tx.TmID == mp.config.PartitionId && !rec.Pushed) {
+			err = fmt.Errorf("transaction(%v) is pending", rec.Tx.TxID)
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return
+	}
+	if start <= mp.config.Start && end >= mp.config.End && mp.multipartTree.Len() > 0 {
+		return errors.New("multipart sessions exist")
+	}
+	return
+}
+
+func (mp *metaPartition) sendMovedItems(req *proto.MoveMetaItemsRequest, items []*MetaItem) (err error) {
+	var data []byte
+	if data, err = json.Marshal(&ApplyMovedItemsReq{PartitionID: req.TargetPartitionID,
+		Source: mp.config.PartitionId, Items: items}); err != nil {
+		return
+	}
+	var p = NewPacketToMetaPartition(proto.OpMetaApplyMovedItems, req.TargetPartitionID, data)
+	var resp *Packet
+	for _, addr := range req.TargetHosts {
+		if resp, err = mp.sendPacket(addr, p); err == nil && resp.ResultCode == proto.OpOk {
+			return
+		}
+		if err == nil {
+			err = fmt.Errorf("apply on mp(%v) host(%v) result(%v)", req.TargetPartitionID, addr, resp.GetResultMsg())
+		}
+	}
+	if err == nil {
+		err = fmt.Errorf("no host of mp(%v)", req.TargetPartitionID)
+	}
+	return
+}
+
+// ApplyMovedItems applies the items moved from another partition.
+func (mp *metaPartition) ApplyMovedItems(req *ApplyMovedItemsReq, p *Packet) (err error) {
+	var val []byte
+	if val, err = json.Marshal(req.Items); err != nil {
+		p.PacketErrorWithBody(proto.OpErr, []byte(err.Error()))
+		return
+	}
+	resp, err := mp.submit(opFSMApplyMovedItems, val)
+	if err != nil {
+		p.PacketErrorWithBody(proto.OpAgain, []byte(err.Error()))
+		return
+	}
+	p.PacketErrorWithBody(resp.(uint8), nil)
+	return
+}
+
+// checkInodeRange replies OpInodeOutOfRange with the inode if it is out of the range of the
+// partition, which happens if the client has a stale view of the meta partitions after the range
+// is shrunk by a merge or a re-split, so that the client refreshes its view and sends the request
+// to the partition serving the inode.
+func (mp *metaPartition) checkInodeRange(ino uint64, p *Packet) bool {
+	if mp.isLocalInode(ino) {
+		return true
+	}
+	log.LogWarnf("checkInodeRange: partitionID(%v) range(%v-%v) inode(%v) out of range",
+		mp.config.PartitionId, mp.config.Start, mp.config.End, ino)
+	p.PacketErrorWithBody(proto.OpInodeOutOfRange, []byte(strconv.FormatUint(ino, 10)))
+	return false
+}
+
+// TrimPartition sets the end of the partition, and removes the items of the inodes after the end,
+// which have been moved to another partition.
+func (mp *metaPartition) TrimPartition(req *proto.TrimMetaPartitionRequest, p *Packet) (err error) {
+	var val []byte
+	if val, err = json.Marshal(req); err != nil {
+		p.PacketErrorWithBody(proto.OpErr, []byte(err.Error()))
+		return
+	}
+	resp, err := mp.submit(opFSMTrimPartition, val)
+	if err != nil {
+		p.PacketErrorWithBody(proto.OpAgain, []byte(err.Error()))
+		return
+	}
+	p.PacketErrorWithBody(resp.(uint8), nil)
+	return
+}
+
+func (mp *metaPartition) fsmFreezePartition(freeze bool) (status uint8, err error) {
+	status = proto.OpOk
+	if mp.config.Frozen == freeze {
+		return
+	}
+	mp.config.Frozen = freeze
+	defer func() {
+		if err != nil {
+			mp.config.Frozen = !freeze
+			status = proto.OpDiskErr
+		}
+	}()
+	err = mp.PersistMetadata()
+	return
+}
+
+func (mp *metaPartition) fsmApplyMovedItems(items []*MetaItem) (status uint8) {
+	status = proto.OpOk
+	for _, item := range items {
+		if err := mp.applyMovedItem(item); err != nil {
+			log.LogErrorf("fsmApplyMovedItems: mp(%v) op(%v) err(%v)", mp.config.PartitionId, item.Op, err)
+			status = proto.OpArgMismatchErr
+			break
+		}
+	}
+	mp.resetQuotaUsage()
+	return
+}
+
+func (mp *metaPartition) applyMovedItem(item *MetaItem) (err error) {
+	switch item.Op {
+	case opFSMCreateInode:
+		ino := NewInode(0, 0)
+		if err = ino.UnmarshalKey(item.K); err != nil {
+			return
+		}
+		if err = ino.UnmarshalValue(item.V); err != nil {
+			return
+		}
+		mp.inodeTree.ReplaceOrInsert(ino, true)
+		if mp.config.Cursor < ino.Inode {
+			mp.config.Cursor = ino.Inode
+		}
+		mp.checkAndInsertFreeList(ino)
+	case opFSMCreateDentry:
+		dentry := &Dentry{}
+		if err = dentry.UnmarshalKey(item.K); err != nil {
+			return
+		}
+		if err = dentry.UnmarshalValue(item.V); err != nil {
+			return
+		}
+		mp.dentryTree.ReplaceOrInsert(dentry, true)
+	case opFSMSetXAttr:
+		var extend *Extend
+		if extend, err = NewExtendFromBytes(item.V); err != nil {
+			return
+		}
+		mp.extendTree.ReplaceOrInsert(extend, true)
+	case opFSMPutObjectVersion:
+		var version *ObjectVersion
+		if version, err = ObjectVersionFromBytes(item.V); err != nil {
+			return
+		}
+		mp.versionTree.ReplaceOrInsert(version, true)
+	case opFSMSetLock:
+		var locks *InodeLocks
+		if locks, err = InodeLocksFromBytes(item.V); err != nil {
+			return
+		}
+		mp.lockTree.ReplaceOrInsert(locks, true)
+	default:
+		err = fmt.Errorf("unknown op=%d", item.Op)
+	}
+	return
+}
+
+func (mp *metaPartition) fsmTrimPartition(end uint64) (status uint8, err error) {
+	status = proto.OpOk
+	// only a frozen partition can shrink, whose items after the end have been moved
+	if end < mp.config.Start || end > mp.config.End || (end < mp.config.End && !mp.config.Frozen) {
+		log.LogWarnf("fsmTrimPartition: mp(%v) range(%v,%v) frozen(%v) refuse to trim to end(%v)",
+			mp.config.PartitionId, mp.config.Start, mp.config.End, mp.config.Frozen, end)
+		return proto.OpArgMismatchErr, nil
+	}
+	oldEnd := mp.config.End
+	mp.config.End = end
+	defer func() {
+		if err != nil {
+			mp.config.End = oldEnd
+			status = proto.OpDiskErr
+		}
+	}()
+	if err = mp.PersistMetadata(); err != nil {
+		return
+	}
+	var removed int
+	if end < math.MaxUint64 {
+		mp.rangeItems(end+1, math.MaxUint64, func(tree *BTree, i BtreeItem) bool {
+			tree.Delete(i)
+			removed++
+			return true
+		})
+	}
+	if mp.config.Cursor > end {
+		mp.config.Cursor = mp.config.Start
+		if item := mp.inodeTree.MaxItem(); item != nil && item.(*Inode).Inode > mp.config.Cursor {
+			mp.config.Cursor = item.(*Inode).Inode
+		}
+	}
+	mp.resetQuotaUsage()
+	log.LogInfof("fsmTrimPartition: mp(%v) end(%v) removed items(%v) cursor(%v)",
+		mp.config.PartitionId, end, removed, mp.config.Cursor)
+	return
+}
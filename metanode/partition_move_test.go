@@ -0,0 +1,110 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+func newTestMovePartition(t *testing.T, id, start, end uint64) *metaPartition {
+	dir, err := ioutil.TempDir("", "partition_move_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return &metaPartition{
+		config: &MetaPartitionConfig{PartitionId: id, Start: start, End: end, Cursor: start, RootDir: dir,
+			Peers: []proto.Peer{{ID: 1, Addr: "127.0.0.1"}}},
+		inodeTree:     NewBtree(),
+		dentryTree:    NewBtree(),
+		extendTree:    NewBtree(),
+		multipartTree: NewBtree(),
+		versionTree:   NewBtree(),
+		lockTree:      NewBtree(),
+		txTree:        NewBtree(),
+		freeList:      newFreeList(),
+	}
+}
+
+func TestMoveItems(t *testing.T) {
+	source := newTestMovePartition(t, 1, 100, 200)
+	for _, ino := range []uint64{101, 150, 160} {
+		source.inodeTree.ReplaceOrInsert(NewInode(ino, proto.Mode(os.ModeDir)), true)
+	}
+	source.config.Cursor = 160
+	source.dentryTree.ReplaceOrInsert(&Dentry{ParentId: 101, Name: "a", Inode: 150}, true)
+	source.dentryTree.ReplaceOrInsert(&Dentry{ParentId: 150, Name: "b", Inode: 160}, true)
+	extend := NewExtend(150)
+	extend.Put([]byte("k"), []byte("v"))
+	source.extendTree.ReplaceOrInsert(extend, true)
+	source.lockTree.ReplaceOrInsert(NewInodeLocks(160), true)
+
+	// the items of the inodes from 150 are moved to the target
+	target := newTestMovePartition(t, 2, 150, 200)
+	var items []*MetaItem
+	source.rangeItems(150, 200, func(_ *BTree, i BtreeItem) bool {
+		item, err := newMovedMetaItem(i)
+		if err != nil {
+			t.Fatalf("marshal item: %v", err)
+		}
+		items = append(items, item)
+		return true
+	})
+	if len(items) != 5 {
+		t.Fatalf("moved items: expect(5) actual(%v)", len(items))
+	}
+	if status := target.fsmApplyMovedItems(items); status != proto.OpOk {
+		t.Fatalf("apply moved items: %v", status)
+	}
+	if target.inodeTree.Len() != 2 || target.dentryTree.Len() != 1 || target.extendTree.Len() != 1 ||
+		target.lockTree.Len() != 1 || target.config.Cursor != 160 {
+		t.Fatalf("target: inodes(%v) dentries(%v) extends(%v) locks(%v) cursor(%v)", target.inodeTree.Len(),
+			target.dentryTree.Len(), target.extendTree.Len(), target.lockTree.Len(), target.config.Cursor)
+	}
+	if d := target.dentryTree.Get(&Dentry{ParentId: 150, Name: "b"}); d == nil || d.(*Dentry).Inode != 160 {
+		t.Fatalf("moved dentry: %v", d)
+	}
+
+	// an unfrozen partition refuses to shrink
+	if status, _ := source.fsmTrimPartition(149); status != proto.OpArgMismatchErr {
+		t.Fatalf("trim unfrozen partition: %v", status)
+	}
+	source.config.Frozen = true
+	if status, err := source.fsmTrimPartition(149); status != proto.OpOk || err != nil {
+		t.Fatalf("trim partition: %v %v", status, err)
+	}
+	if source.config.End != 149 || source.inodeTree.Len() != 1 || source.dentryTree.Len() != 1 ||
+		source.extendTree.Len() != 0 || source.lockTree.Len() != 0 || source.config.Cursor != 101 {
+		t.Fatalf("source: end(%v) inodes(%v) dentries(%v) extends(%v) locks(%v) cursor(%v)", source.config.End,
+			source.inodeTree.Len(), source.dentryTree.Len(), source.extendTree.Len(), source.lockTree.Len(),
+			source.config.Cursor)
+	}
+
+	// the requests for the moved inodes are rejected with the inode, so that the clients with a stale
+	// view send them to the target
+	p := &Packet{}
+	if err := source.Lookup(&LookupReq{ParentID: 150, Name: "b"}, p); err != nil ||
+		p.ResultCode != proto.OpInodeOutOfRange || string(p.Data) != "150" {
+		t.Fatalf("lookup moved dentry result: %v data(%s) err(%v)", p.ResultCode, p.Data, err)
+	}
+	p = &Packet{}
+	if err := source.Lookup(&LookupReq{ParentID: 101, Name: "a"}, p); err != nil || p.ResultCode != proto.OpOk {
+		t.Fatalf("lookup dentry result: %v err(%v)", p.ResultCode, err)
+	}
+}
@@ -0,0 +1,164 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"sort"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// objectLockViewsTTL is the time the views of the meta partitions are cached
+// to check the object lock of the inodes in the other partitions.
+const objectLockViewsTTL = time.Minute
+
+// The object lock is checked by the leader before the operations are submitted,
+// so that the locked inodes are protected from the clients as well as the
+// object nodes. The time of the leader is used to judge the retention periods.
+
+func (mp *metaPartition) getObjectLock(ino uint64) (retention, legalHold string) {
+	item := mp.extendTree.Get(NewExtend(ino))
+	if item == nil {
+		return
+	}
+	extend := item.(*Extend)
+	if value, ok := extend.Get([]byte(proto.RetentionXAttrKey)); ok {
+		retention = string(value)
+	}
+	if value, ok := extend.Get([]byte(proto.LegalHoldXAttrKey)); ok {
+		legalHold = string(value)
+	}
+	return
+}
+
+// isInodeLocked returns true if the inode is under a legal hold or in its
+// retention period.
+func (mp *metaPartition) isInodeLocked(ino uint64) bool {
+	retention, legalHold := mp.getObjectLock(ino)
+	return proto.IsObjectLocked(retention, legalHold, time.Now())
+}
+
+// checkObjectLockXAttr checks the change of an extend attribute of the inode,
+// which is removed if the value is nil. The object lock is only applied to the
+// regular files, and the retention in compliance mode can only be extended.
+func (mp *metaPartition) checkObjectLockXAttr(ino uint64, key string, value []byte) uint8 {
+	switch key {
+	case proto.LegalHoldXAttrKey:
+		if value != nil && !proto.ValidLegalHold(string(value)) {
+			return proto.OpArgMismatchErr
+		}
+	case proto.RetentionXAttrKey:
+		var retention *proto.Retention
+		if value != nil {
+			var err error
+			if retention, err = proto.ParseRetention(string(value)); err != nil {
+				return proto.OpArgMismatchErr
+			}
+		}
+		if raw, _ := mp.getObjectLock(ino); raw != "" {
+			if old, err := proto.ParseRetention(raw); err == nil && old.Compliance(time.Now()) {
+				if retention == nil || retention.Mode != proto.RetentionModeCompliance ||
+					retention.RetainUntil < old.RetainUntil {
+					return proto.OpNotPerm
+				}
+			}
+		}
+	default:
+		return proto.OpOk
+	}
+	if value == nil {
+		return proto.OpOk
+	}
+	item := mp.inodeTree.Get(NewInode(ino, 0))
+	if item == nil {
+		return proto.OpNotExistErr
+	}
+	if !proto.IsRegular(item.(*Inode).Type) {
+		return proto.OpArgMismatchErr
+	}
+	return proto.OpOk
+}
+
+// checkDentryObjectLock returns OpNotPerm if the inode of the dentry to be
+// removed or replaced is locked, unless it is kept by a version of the object.
+func (mp *metaPartition) checkDentryObjectLock(parentID uint64, name string) uint8 {
+	item := mp.dentryTree.Get(&Dentry{ParentId: parentID, Name: name})
+	if item == nil {
+		return proto.OpOk
+	}
+	dentry := item.(*Dentry)
+	if !proto.IsRegular(dentry.Type) || mp.versionedInode(parentID, name, dentry.Inode) {
+		return proto.OpOk
+	}
+	views, err := mp.objectLockViews(dentry.Inode)
+	if err != nil {
+		log.LogWarnf("checkDentryObjectLock: mp(%v) dentry(%v) err(%v)", mp.config.PartitionId, dentry, err)
+		return proto.OpAgain
+	}
+	locked, err := mp.isRemoteInodeLocked(views, dentry.Inode)
+	if err != nil {
+		log.LogWarnf("checkDentryObjectLock: mp(%v) dentry(%v) err(%v)", mp.config.PartitionId, dentry, err)
+		return proto.OpAgain
+	}
+	if locked {
+		return proto.OpNotPerm
+	}
+	return proto.OpOk
+}
+
+// objectLockViews returns the cached views of the meta partitions of the
+// volume, which are refreshed if expired or if the inode is not in any of
+// them. No view is needed for the local inode.
+func (mp *metaPartition) objectLockViews(ino uint64) (views []*proto.MetaPartitionView, err error) {
+	if mp.isLocalInode(ino) {
+		return
+	}
+	mp.lockViewsMutex.Lock()
+	defer mp.lockViewsMutex.Unlock()
+	if time.Since(mp.lockViewsTime) < objectLockViewsTTL && findMetaPartitionView(mp.lockViews, ino) != nil {
+		return mp.lockViews, nil
+	}
+	if views, err = masterClient.ClientAPI().GetMetaPartitions(mp.config.VolName); err != nil {
+		return
+	}
+	sort.Slice(views, func(i, j int) bool { return views[i].Start < views[j].Start })
+	mp.lockViews, mp.lockViewsTime = views, time.Now()
+	return
+}
+
+// isRemoteInodeLocked returns true if the inode, which may be placed in the
+// other partitions of the volume, is locked.
+func (mp *metaPartition) isRemoteInodeLocked(views []*proto.MetaPartitionView, ino uint64) (locked bool, err error) {
+	if mp.isLocalInode(ino) {
+		return mp.isInodeLocked(ino), nil
+	}
+	for view := range groupInodesByPartition(views, []uint64{ino}) {
+		req := &proto.BatchGetXAttrRequest{VolName: mp.config.VolName, PartitionId: view.PartitionID,
+			Inodes: []uint64{ino}, Keys: []string{proto.RetentionXAttrKey, proto.LegalHoldXAttrKey}}
+		resp := &proto.BatchGetXAttrResponse{}
+		if err = checkMetaPartitionStatus(mp.requestMetaPartition(view, proto.OpMetaBatchGetXAttr, req, resp)); err != nil {
+			return
+		}
+		for _, info := range resp.XAttrs {
+			if info.Inode == ino && proto.IsObjectLocked(info.XAttrs[proto.RetentionXAttrKey],
+				info.XAttrs[proto.LegalHoldXAttrKey], time.Now()) {
+				return true, nil
+			}
+		}
+	}
+	return
+}
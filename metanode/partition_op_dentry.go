@@ -23,6 +23,9 @@ import (
 
 // CreateDentry returns a new dentry.
 func (mp *metaPartition) CreateDentry(req *CreateDentryReq, p *Packet) (err error) {
+	if !mp.checkInodeRange(req.ParentID, p) {
+		return
+	}
 	if req.ParentID == req.Inode {
 		err = fmt.Errorf("parentId is equal inodeId")
 		p.PacketErrorWithBody(proto.OpExistErr, []byte(err.Error()))
@@ -50,6 +53,13 @@ func (mp *metaPartition) CreateDentry(req *CreateDentryReq, p *Packet) (err erro
 
 // DeleteDentry deletes a dentry.
 func (mp *metaPartition) DeleteDentry(req *DeleteDentryReq, p *Packet) (err error) {
+	if !mp.checkInodeRange(req.ParentID, p) {
+		return
+	}
+	if status := mp.checkDentryObjectLock(req.ParentID, req.Name); status != proto.OpOk {
+		p.PacketErrorWithBody(status, nil)
+		return
+	}
 	dentry := &Dentry{
 		ParentId: req.ParentID,
 		Name:     req.Name,
@@ -70,7 +80,8 @@ func (mp *metaPartition) DeleteDentry(req *DeleteDentryReq, p *Packet) (err erro
 	if p.ResultCode == proto.OpOk {
 		var reply []byte
 		resp := &DeleteDentryResp{
-			Inode: dentry.Inode,
+			Inode:     dentry.Inode,
+			Versioned: mp.versionedInode(dentry.ParentId, dentry.Name, dentry.Inode),
 		}
 		reply, err = json.Marshal(resp)
 		p.PacketOkWithBody(reply)
@@ -80,10 +91,16 @@ func (mp *metaPartition) DeleteDentry(req *DeleteDentryReq, p *Packet) (err erro
 
 // DeleteDentry deletes a dentry.
 func (mp *metaPartition) DeleteDentryBatch(req *BatchDeleteDentryReq, p *Packet) (err error) {
-
+	if !mp.checkInodeRange(req.ParentID, p) {
+		return
+	}
 	db := make(DentryBatch, 0, len(req.Dens))
 
 	for _, d := range req.Dens {
+		if status := mp.checkDentryObjectLock(req.ParentID, d.Name); status != proto.OpOk {
+			p.PacketErrorWithBody(status, nil)
+			return
+		}
 		db = append(db, &Dentry{
 			ParentId: req.ParentID,
 			Name:     d.Name,
@@ -144,11 +161,18 @@ func (mp *metaPartition) DeleteDentryBatch(req *BatchDeleteDentryReq, p *Packet)
 
 // UpdateDentry updates a dentry.
 func (mp *metaPartition) UpdateDentry(req *UpdateDentryReq, p *Packet) (err error) {
+	if !mp.checkInodeRange(req.ParentID, p) {
+		return
+	}
 	if req.ParentID == req.Inode {
 		err = fmt.Errorf("parentId is equal inodeId")
 		p.PacketErrorWithBody(proto.OpExistErr, []byte(err.Error()))
 		return
 	}
+	if status := mp.checkDentryObjectLock(req.ParentID, req.Name); status != proto.OpOk {
+		p.PacketErrorWithBody(status, nil)
+		return
+	}
 
 	dentry := &Dentry{
 		ParentId: req.ParentID,
@@ -170,7 +194,8 @@ func (mp *metaPartition) UpdateDentry(req *UpdateDentryReq, p *Packet) (err erro
 	if msg.Status == proto.OpOk {
 		var reply []byte
 		m := &UpdateDentryResp{
-			Inode: msg.Msg.Inode,
+			Inode:     msg.Msg.Inode,
+			Versioned: mp.versionedInode(req.ParentID, req.Name, msg.Msg.Inode),
 		}
 		reply, err = json.Marshal(m)
 		p.PacketOkWithBody(reply)
@@ -180,6 +205,9 @@ func (mp *metaPartition) UpdateDentry(req *UpdateDentryReq, p *Packet) (err erro
 
 // ReadDir reads the directory based on the given request.
 func (mp *metaPartition) ReadDir(req *ReadDirReq, p *Packet) (err error) {
+	if !mp.checkInodeRange(req.ParentID, p) {
+		return
+	}
 	resp := mp.readDir(req)
 	reply, err := json.Marshal(resp)
 	if err != nil {
@@ -190,8 +218,26 @@ func (mp *metaPartition) ReadDir(req *ReadDirReq, p *Packet) (err error) {
 	return
 }
 
+// ReadDirPlus reads the dentries of the directory in range based on the given request.
+func (mp *metaPartition) ReadDirPlus(req *ReadDirPlusReq, p *Packet) (err error) {
+	if !mp.checkInodeRange(req.ParentID, p) {
+		return
+	}
+	resp := mp.readDirPlus(req)
+	reply, err := json.Marshal(resp)
+	if err != nil {
+		p.PacketErrorWithBody(proto.OpErr, []byte(err.Error()))
+		return
+	}
+	p.PacketOkWithBody(reply)
+	return
+}
+
 // Lookup looks up the given dentry from the request.
 func (mp *metaPartition) Lookup(req *LookupReq, p *Packet) (err error) {
+	if !mp.checkInodeRange(req.ParentID, p) {
+		return
+	}
 	dentry := &Dentry{
 		ParentId: req.ParentID,
 		Name:     req.Name,
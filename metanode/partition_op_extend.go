@@ -21,6 +21,13 @@ import (
 )
 
 func (mp *metaPartition) SetXAttr(req *proto.SetXAttrRequest, p *Packet) (err error) {
+	if !mp.checkInodeRange(req.Inode, p) {
+		return
+	}
+	if status := mp.checkObjectLockXAttr(req.Inode, req.Key, []byte(req.Value)); status != proto.OpOk {
+		p.PacketErrorWithBody(status, nil)
+		return
+	}
 	var extend = NewExtend(req.Inode)
 	extend.Put([]byte(req.Key), []byte(req.Value))
 	if _, err = mp.putExtend(opFSMSetXAttr, extend); err != nil {
@@ -32,6 +39,9 @@ func (mp *metaPartition) SetXAttr(req *proto.SetXAttrRequest, p *Packet) (err er
 }
 
 func (mp *metaPartition) GetXAttr(req *proto.GetXAttrRequest, p *Packet) (err error) {
+	if !mp.checkInodeRange(req.Inode, p) {
+		return
+	}
 	var response = &proto.GetXAttrResponse{
 		VolName:     req.VolName,
 		PartitionId: req.PartitionId,
@@ -87,6 +97,13 @@ func (mp *metaPartition) BatchGetXAttr(req *proto.BatchGetXAttrRequest, p *Packe
 }
 
 func (mp *metaPartition) RemoveXAttr(req *proto.RemoveXAttrRequest, p *Packet) (err error) {
+	if !mp.checkInodeRange(req.Inode, p) {
+		return
+	}
+	if status := mp.checkObjectLockXAttr(req.Inode, req.Key, nil); status != proto.OpOk {
+		p.PacketErrorWithBody(status, nil)
+		return
+	}
 	var extend = NewExtend(req.Inode)
 	extend.Put([]byte(req.Key), nil)
 	if _, err = mp.putExtend(opFSMRemoveXAttr, extend); err != nil {
@@ -98,6 +115,9 @@ func (mp *metaPartition) RemoveXAttr(req *proto.RemoveXAttrRequest, p *Packet) (
 }
 
 func (mp *metaPartition) ListXAttr(req *proto.ListXAttrRequest, p *Packet) (err error) {
+	if !mp.checkInodeRange(req.Inode, p) {
+		return
+	}
 	var response = &proto.ListXAttrResponse{
 		VolName:     req.VolName,
 		PartitionId: req.PartitionId,
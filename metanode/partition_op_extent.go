@@ -23,6 +23,17 @@ import (
 
 // ExtentAppend appends an extent.
 func (mp *metaPartition) ExtentAppend(req *proto.AppendExtentKeyRequest, p *Packet) (err error) {
+	if !mp.checkInodeRange(req.Inode, p) {
+		return
+	}
+	if mp.isInodeLocked(req.Inode) {
+		p.PacketErrorWithBody(proto.OpNotPerm, nil)
+		return
+	}
+	if mp.isAppendQuotaExceeded(req.Inode, &req.Extent) {
+		p.PacketErrorWithBody(proto.OpQuotaExceededErr, nil)
+		return
+	}
 	ino := NewInode(req.Inode, 0)
 	ext := req.Extent
 	ino.Extents.Append(ext)
@@ -43,6 +54,17 @@ func (mp *metaPartition) ExtentAppend(req *proto.AppendExtentKeyRequest, p *Pack
 // ExtentAppendWithCheck appends an extent with discard extents check.
 // Format: one valid extent key followed by non or several discard keys.
 func (mp *metaPartition) ExtentAppendWithCheck(req *proto.AppendExtentKeyWithCheckRequest, p *Packet) (err error) {
+	if !mp.checkInodeRange(req.Inode, p) {
+		return
+	}
+	if mp.isInodeLocked(req.Inode) {
+		p.PacketErrorWithBody(proto.OpNotPerm, nil)
+		return
+	}
+	if mp.isAppendQuotaExceeded(req.Inode, &req.Extent) {
+		p.PacketErrorWithBody(proto.OpQuotaExceededErr, nil)
+		return
+	}
 	ino := NewInode(req.Inode, 0)
 	ext := req.Extent
 	ino.Extents.Append(ext)
@@ -67,6 +89,9 @@ func (mp *metaPartition) ExtentAppendWithCheck(req *proto.AppendExtentKeyWithChe
 
 // ExtentsList returns the list of extents.
 func (mp *metaPartition) ExtentsList(req *proto.GetExtentsRequest, p *Packet) (err error) {
+	if !mp.checkInodeRange(req.Inode, p) {
+		return
+	}
 	ino := NewInode(req.Inode, 0)
 	retMsg := mp.getInode(ino)
 	ino = retMsg.Msg
@@ -96,6 +121,13 @@ func (mp *metaPartition) ExtentsList(req *proto.GetExtentsRequest, p *Packet) (e
 
 // ExtentsTruncate truncates an extent.
 func (mp *metaPartition) ExtentsTruncate(req *ExtentsTruncateReq, p *Packet) (err error) {
+	if !mp.checkInodeRange(req.Inode, p) {
+		return
+	}
+	if mp.isInodeLocked(req.Inode) {
+		p.PacketErrorWithBody(proto.OpNotPerm, nil)
+		return
+	}
 	ino := NewInode(req.Inode, proto.Mode(os.ModePerm))
 	ino.Size = req.Size
 	val, err := ino.Marshal()
@@ -114,9 +146,20 @@ func (mp *metaPartition) ExtentsTruncate(req *ExtentsTruncateReq, p *Packet) (er
 }
 
 func (mp *metaPartition) BatchExtentAppend(req *proto.AppendExtentKeysRequest, p *Packet) (err error) {
+	if !mp.checkInodeRange(req.Inode, p) {
+		return
+	}
+	if mp.isInodeLocked(req.Inode) {
+		p.PacketErrorWithBody(proto.OpNotPerm, nil)
+		return
+	}
 	ino := NewInode(req.Inode, 0)
 	extents := req.Extents
 	for _, extent := range extents {
+		if mp.isAppendQuotaExceeded(req.Inode, &extent) {
+			p.PacketErrorWithBody(proto.OpQuotaExceededErr, nil)
+			return
+		}
 		ino.Extents.Append(extent)
 	}
 	val, err := ino.Marshal()
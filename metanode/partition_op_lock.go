@@ -0,0 +1,118 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+const (
+	defaultLockLease = 30 // seconds
+)
+
+func lockExpire(now int64, lease uint32) int64 {
+	if lease == 0 {
+		lease = defaultLockLease
+	}
+	return now + int64(lease)*int64(time.Second)
+}
+
+// SetLock acquires or releases a lock on the inode. It fails with
+// OpLockConflictErr if a conflicting lock is held by others.
+func (mp *metaPartition) SetLock(req *proto.SetLockRequest, p *Packet) (err error) {
+	if req.Lock.Start > req.Lock.End || req.Lock.Type > proto.LockUnlock {
+		p.PacketErrorWithBody(proto.OpArgMismatchErr, nil)
+		return
+	}
+	now := time.Now().UnixNano()
+	cmd := &LockCmd{
+		Inode:  req.Inode,
+		Lock:   req.Lock,
+		Expire: lockExpire(now, req.Lease),
+		Now:    now,
+	}
+	// fail fast without going through raft
+	if item := mp.lockTree.Get(NewInodeLocks(req.Inode)); item != nil && cmd.Lock.Type != proto.LockUnlock {
+		if item.(*InodeLocks).Conflict(&cmd.Lock, now) != nil {
+			p.PacketErrorWithBody(proto.OpLockConflictErr, nil)
+			return
+		}
+	}
+	var val []byte
+	if val, err = cmd.Marshal(); err != nil {
+		p.PacketErrorWithBody(proto.OpErr, []byte(err.Error()))
+		return
+	}
+	resp, err := mp.submit(opFSMSetLock, val)
+	if err != nil {
+		p.PacketErrorWithBody(proto.OpAgain, []byte(err.Error()))
+		return
+	}
+	p.PacketErrorWithBody(resp.(uint8), nil)
+	return
+}
+
+// GetLock returns the first lock which conflicts with the requested one, or
+// a lock of type LockUnlock if there is none.
+func (mp *metaPartition) GetLock(req *proto.GetLockRequest, p *Packet) (err error) {
+	resp := &proto.GetLockResponse{
+		Lock: proto.LockInfo{Type: proto.LockUnlock},
+	}
+	if item := mp.lockTree.Get(NewInodeLocks(req.Inode)); item != nil {
+		if l := item.(*InodeLocks).Conflict(&req.Lock, time.Now().UnixNano()); l != nil {
+			resp.Lock = l.LockInfo
+		}
+	}
+	var reply []byte
+	if reply, err = json.Marshal(resp); err != nil {
+		p.PacketErrorWithBody(proto.OpErr, []byte(err.Error()))
+		return
+	}
+	p.PacketOkWithBody(reply)
+	return
+}
+
+// RenewLock renews the leases of all of the locks held by the client.
+func (mp *metaPartition) RenewLock(req *proto.RenewLockRequest, p *Packet) (err error) {
+	now := time.Now().UnixNano()
+	cmd := &RenewLockCmd{
+		Client: req.Client,
+		Expire: lockExpire(now, req.Lease),
+		Now:    now,
+	}
+	var val []byte
+	if val, err = cmd.Marshal(); err != nil {
+		p.PacketErrorWithBody(proto.OpErr, []byte(err.Error()))
+		return
+	}
+	r, err := mp.submit(opFSMRenewLock, val)
+	if err != nil {
+		p.PacketErrorWithBody(proto.OpAgain, []byte(err.Error()))
+		return
+	}
+	resp := &proto.RenewLockResponse{
+		Count: r.(uint32),
+	}
+	var reply []byte
+	if reply, err = json.Marshal(resp); err != nil {
+		p.PacketErrorWithBody(proto.OpErr, []byte(err.Error()))
+		return
+	}
+	p.PacketOkWithBody(reply)
+	return
+}
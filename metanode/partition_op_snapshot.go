@@ -0,0 +1,61 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+// CreateMetaSnapshot creates a snapshot of the partition.
+func (mp *metaPartition) CreateMetaSnapshot(req *proto.MetaSnapshotRequest, p *Packet) (err error) {
+	return mp.submitMetaSnapshotCmd(opFSMCreateMetaSnapshot, &MetaSnapshotCmd{
+		ID:         req.SnapshotID,
+		CreateTime: time.Now().Unix(),
+	}, p)
+}
+
+// DeleteMetaSnapshot deletes the snapshot of the partition.
+func (mp *metaPartition) DeleteMetaSnapshot(req *proto.MetaSnapshotRequest, p *Packet) (err error) {
+	return mp.submitMetaSnapshotCmd(opFSMDeleteMetaSnapshot, &MetaSnapshotCmd{ID: req.SnapshotID}, p)
+}
+
+func (mp *metaPartition) submitMetaSnapshotCmd(op uint32, cmd *MetaSnapshotCmd, p *Packet) (err error) {
+	if cmd.ID == 0 {
+		p.PacketErrorWithBody(proto.OpArgMismatchErr, nil)
+		return
+	}
+	var val []byte
+	if val, err = cmd.Marshal(); err != nil {
+		p.PacketErrorWithBody(proto.OpErr, []byte(err.Error()))
+		return
+	}
+	resp, err := mp.submit(op, val)
+	if err != nil {
+		p.PacketErrorWithBody(proto.OpAgain, []byte(err.Error()))
+		return
+	}
+	p.PacketErrorWithBody(resp.(uint8), nil)
+	return
+}
+
+// GetSnapshotView returns the read-only partition of the snapshot.
+func (mp *metaPartition) GetSnapshotView(id uint64) (view MetaPartition, ok bool) {
+	if v := mp.snapshotView(id); v != nil {
+		return v, true
+	}
+	return nil, false
+}
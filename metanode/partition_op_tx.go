@@ -0,0 +1,325 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/errors"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// TxPrepare prepares the local items of the transaction. The transaction
+// manager should be prepared before the other partitions.
+func (mp *metaPartition) TxPrepare(req *proto.TxPrepareRequest, p *Packet) (err error) {
+	if req.Tx == nil || req.Tx.TxID == "" || len(req.Tx.Items) == 0 {
+		p.PacketErrorWithBody(proto.OpArgMismatchErr, nil)
+		return
+	}
+	status, err := mp.txPrepare(req.Tx)
+	if err != nil {
+		p.PacketErrorWithBody(status, []byte(err.Error()))
+		return
+	}
+	p.PacketErrorWithBody(status, nil)
+	return
+}
+
+func (mp *metaPartition) txPrepare(tx *proto.TxInfo) (status uint8, err error) {
+	rec := &TxRecord{
+		Tx:       tx,
+		State:    proto.TxStatePrepared,
+		Deadline: time.Now().Add(TxTimeout).UnixNano(),
+	}
+	// the locked inodes can not be unlinked by overwriting
+	for _, item := range rec.LocalItems(mp.config.PartitionId) {
+		if item.Op == proto.TxOpUnlinkInode && mp.isInodeLocked(item.Inode) {
+			return proto.OpNotPerm, nil
+		}
+	}
+	var val []byte
+	if val, err = rec.Bytes(); err != nil {
+		return proto.OpErr, err
+	}
+	resp, err := mp.submit(opFSMTxPrepare, val)
+	if err != nil {
+		return proto.OpAgain, err
+	}
+	return resp.(uint8), nil
+}
+
+// TxCommit commits the transaction. Once the transaction manager commits,
+// the decision is pushed to the other partitions before responding.
+func (mp *metaPartition) TxCommit(req *proto.TxCommitRequest, p *Packet) (err error) {
+	status, err := mp.submitTxCmd(opFSMTxCommit, req.TxID, req.TmID)
+	if err != nil {
+		p.PacketErrorWithBody(proto.OpAgain, []byte(err.Error()))
+		return
+	}
+	if status == proto.OpOk && req.TmID == mp.config.PartitionId {
+		mp.finishTx(req.TxID)
+	}
+	p.PacketErrorWithBody(status, nil)
+	return
+}
+
+// TxAbort aborts the transaction, which fails with OpNotPerm if the
+// transaction manager has committed it.
+func (mp *metaPartition) TxAbort(req *proto.TxAbortRequest, p *Packet) (err error) {
+	status, err := mp.submitTxCmd(opFSMTxAbort, req.TxID, req.TmID)
+	if err != nil {
+		p.PacketErrorWithBody(proto.OpAgain, []byte(err.Error()))
+		return
+	}
+	if status == proto.OpOk && req.TmID == mp.config.PartitionId {
+		mp.finishTx(req.TxID)
+	}
+	p.PacketErrorWithBody(status, nil)
+	return
+}
+
+// TxGetStatus returns the state of the transaction on the transaction
+// manager. A transaction unknown or undecided after its deadline is aborted.
+func (mp *metaPartition) TxGetStatus(req *proto.TxGetStatusRequest, p *Packet) (err error) {
+	resp := &proto.TxGetStatusResponse{State: proto.TxStateAborted}
+	if rec := mp.getTxRecord(req.TxID); rec != nil {
+		resp.State = rec.State
+		if rec.State == proto.TxStatePrepared && rec.Expired(time.Now().UnixNano()) {
+			var status uint8
+			if status, err = mp.submitTxCmd(opFSMTxAbort, rec.Tx.TxID, rec.Tx.TmID); err != nil {
+				p.PacketErrorWithBody(proto.OpAgain, []byte(err.Error()))
+				return
+			}
+			if status == proto.OpOk {
+				resp.State = proto.TxStateAborted
+			} else {
+				resp.State = proto.TxStateCommitted
+			}
+		}
+	}
+	var reply []byte
+	if reply, err = json.Marshal(resp); err != nil {
+		p.PacketErrorWithBody(proto.OpErr, []byte(err.Error()))
+		return
+	}
+	p.PacketOkWithBody(reply)
+	return
+}
+
+func (mp *metaPartition) submitTxCmd(op uint32, txID string, tmID uint64) (status uint8, err error) {
+	cmd := &TxCmd{TxID: txID, TmID: tmID}
+	var val []byte
+	if val, err = cmd.Marshal(); err != nil {
+		return
+	}
+	resp, err := mp.submit(op, val)
+	if err != nil {
+		return
+	}
+	status = resp.(uint8)
+	return
+}
+
+// finishTx pushes the decision of the transaction to the participants.
+func (mp *metaPartition) finishTx(txID string) {
+	rec := mp.getTxRecord(txID)
+	if rec == nil || rec.State == proto.TxStatePrepared || rec.Pushed {
+		return
+	}
+	if err := mp.pushTxDecision(rec); err != nil {
+		// retried by checkTxWorker after the deadline
+		log.LogWarnf("finishTx: mp(%v) tx(%v) err(%v)", mp.config.PartitionId, rec, err)
+		return
+	}
+	if _, err := mp.submitTxCmd(opFSMTxPushed, rec.Tx.TxID, rec.Tx.TmID); err != nil {
+		log.LogWarnf("finishTx: mp(%v) tx(%v) err(%v)", mp.config.PartitionId, rec, err)
+	}
+}
+
+func (mp *metaPartition) pushTxDecision(rec *TxRecord) (err error) {
+	var opcode = proto.OpMetaTxCommit
+	if rec.State == proto.TxStateAborted {
+		opcode = proto.OpMetaTxAbort
+	}
+	for _, pid := range rec.Participants() {
+		var data []byte
+		if opcode == proto.OpMetaTxCommit {
+			data, err = json.Marshal(&proto.TxCommitRequest{PartitionId: pid, TxID: rec.Tx.TxID, TmID: rec.Tx.TmID})
+		} else {
+			data, err = json.Marshal(&proto.TxAbortRequest{PartitionId: pid, TxID: rec.Tx.TxID, TmID: rec.Tx.TmID})
+		}
+		if err != nil {
+			return
+		}
+		var resp *Packet
+		if resp, err = mp.sendTxPacket(rec, pid, NewPacketToMetaPartition(opcode, pid, data)); err != nil {
+			return
+		}
+		if resp.ResultCode != proto.OpOk {
+			return fmt.Errorf("push to mp(%v) result(%v)", pid, resp.GetResultMsg())
+		}
+	}
+	return
+}
+
+func (mp *metaPartition) getTxStatusFromTM(rec *TxRecord) (state uint8, err error) {
+	var data []byte
+	if data, err = json.Marshal(&proto.TxGetStatusRequest{PartitionId: rec.Tx.TmID, TxID: rec.Tx.TxID}); err != nil {
+		return
+	}
+	var resp *Packet
+	if resp, err = mp.sendTxPacket(rec, rec.Tx.TmID, NewPacketToMetaPartition(proto.OpMetaTxGetStatus, rec.Tx.TmID, data)); err != nil {
+		return
+	}
+	if resp.ResultCode != proto.OpOk {
+		err = fmt.Errorf("get status from mp(%v) result(%v)", rec.Tx.TmID, resp.GetResultMsg())
+		return
+	}
+	status := &proto.TxGetStatusResponse{}
+	if err = json.Unmarshal(resp.Data[:resp.Size], status); err != nil {
+		return
+	}
+	return status.State, nil
+}
+
+// sendTxPacket sends the packet to the partition through the hosts recorded
+// in the transaction, or the hosts known by the master if none of them works.
+// A follower forwards the packet to the leader.
+func (mp *metaPartition) sendTxPacket(rec *TxRecord, pid uint64, p *Packet) (resp *Packet, err error) {
+	var hosts []string
+	if members := rec.Tx.Members[pid]; members != "" {
+		hosts = strings.Split(members, ",")
+	}
+	for _, addr := range hosts {
+		if resp, err = mp.sendPacket(addr, p); err == nil && resp.ResultCode != proto.OpAgain {
+			return
+		}
+	}
+	var partition *proto.MetaPartitionInfo
+	if partition, err = masterClient.ClientAPI().GetMetaPartition(pid); err != nil {
+		return
+	}
+	for _, addr := range partition.Hosts {
+		if resp, err = mp.sendPacket(addr, p); err == nil && resp.ResultCode != proto.OpAgain {
+			return
+		}
+	}
+	if err == nil {
+		err = errors.NewErrorf("no leader of mp(%v) is available", pid)
+	}
+	return
+}
+
+func (mp *metaPartition) sendPacket(addr string, p *Packet) (resp *Packet, err error) {
+	var conn *net.TCPConn
+	if conn, err = mp.config.ConnPool.GetConnect(addr); err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			mp.config.ConnPool.PutConnect(conn, ForceClosedConnect)
+		} else {
+			mp.config.ConnPool.PutConnect(conn, NoClosedConnect)
+		}
+	}()
+	resp = NewPacketToMetaPartition(p.Opcode, p.PartitionID, p.Data)
+	resp.ReqID = p.ReqID
+	if err = resp.WriteToConn(conn); err != nil {
+		return
+	}
+	if err = resp.ReadFromConn(conn, proto.ReadDeadlineTime); err != nil {
+		return
+	}
+	return
+}
+
+// checkTxWorker recovers the transactions left undecided or undelivered by
+// crashed clients or meta nodes.
+func (mp *metaPartition) checkTxWorker() {
+	t := time.NewTicker(TxCheckInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-mp.stopC:
+			return
+		case <-t.C:
+			if _, ok := mp.IsLeader(); !ok || mp.txTree.Len() == 0 {
+				continue
+			}
+			mp.checkTx()
+		}
+	}
+}
+
+func (mp *metaPartition) checkTx() {
+	var expired []*TxRecord
+	now := time.Now().UnixNano()
+	mp.txTree.Ascend(func(i BtreeItem) bool {
+		if rec := i.(*TxRecord); rec.Expired(now) {
+			expired = append(expired, rec)
+		}
+		return true
+	})
+	for _, rec := range expired {
+		var err error
+		if rec.IsTM(mp.config.PartitionId) {
+			err = mp.checkTxAsTM(rec, now)
+		} else {
+			err = mp.checkTxAsParticipant(rec)
+		}
+		if err != nil {
+			log.LogWarnf("checkTx: mp(%v) tx(%v) err(%v)", mp.config.PartitionId, rec, err)
+		}
+	}
+}
+
+func (mp *metaPartition) checkTxAsTM(rec *TxRecord, now int64) (err error) {
+	switch {
+	case rec.Pushed:
+		if rec.Deadline+int64(TxKeepTime) <= now {
+			_, err = mp.submitTxCmd(opFSMTxRemove, rec.Tx.TxID, rec.Tx.TmID)
+		}
+		return
+	case rec.State == proto.TxStatePrepared:
+		if _, err = mp.submitTxCmd(opFSMTxAbort, rec.Tx.TxID, rec.Tx.TmID); err != nil {
+			return
+		}
+	}
+	log.LogWarnf("checkTxAsTM: mp(%v) finish tx(%v)", mp.config.PartitionId, rec)
+	mp.finishTx(rec.Tx.TxID)
+	return
+}
+
+func (mp *metaPartition) checkTxAsParticipant(rec *TxRecord) (err error) {
+	var state uint8
+	if state, err = mp.getTxStatusFromTM(rec); err != nil {
+		return
+	}
+	switch state {
+	case proto.TxStateCommitted:
+		_, err = mp.submitTxCmd(opFSMTxCommit, rec.Tx.TxID, rec.Tx.TmID)
+	case proto.TxStateAborted:
+		_, err = mp.submitTxCmd(opFSMTxAbort, rec.Tx.TxID, rec.Tx.TmID)
+	default:
+		return
+	}
+	log.LogWarnf("checkTxAsParticipant: mp(%v) tx(%v) state(%v) err(%v)", mp.config.PartitionId, rec, state, err)
+	return
+}
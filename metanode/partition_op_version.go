@@ -0,0 +1,137 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"encoding/json"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+func (mp *metaPartition) PutObjectVersion(req *proto.PutObjectVersionRequest, p *Packet) (err error) {
+	if req.Version == nil || req.Version.Name == "" || req.Version.VersionID == "" {
+		p.PacketErrorWithBody(proto.OpArgMismatchErr, nil)
+		return
+	}
+	version := &ObjectVersion{ObjectVersionInfo: *req.Version}
+	var resp *ObjectVersionResponse
+	if resp, err = mp.putObjectVersion(opFSMPutObjectVersion, version); err != nil {
+		p.PacketErrorWithBody(proto.OpErr, []byte(err.Error()))
+		return
+	}
+	reply := &proto.PutObjectVersionResponse{}
+	if resp.Msg != nil {
+		reply.Replaced = &resp.Msg.ObjectVersionInfo
+	}
+	return mp.replyObjectVersion(p, resp.Status, reply)
+}
+
+func (mp *metaPartition) DeleteObjectVersion(req *proto.DeleteObjectVersionRequest, p *Packet) (err error) {
+	version := NewObjectVersion(req.ParentID, req.Name, req.VersionID)
+	var resp *ObjectVersionResponse
+	if resp, err = mp.putObjectVersion(opFSMDeleteObjectVersion, version); err != nil {
+		p.PacketErrorWithBody(proto.OpErr, []byte(err.Error()))
+		return
+	}
+	reply := &proto.DeleteObjectVersionResponse{}
+	if resp.Msg != nil {
+		reply.Deleted = &resp.Msg.ObjectVersionInfo
+	}
+	return mp.replyObjectVersion(p, resp.Status, reply)
+}
+
+func (mp *metaPartition) GetObjectVersions(req *proto.GetObjectVersionsRequest, p *Packet) (err error) {
+	reply := &proto.GetObjectVersionsResponse{
+		Versions: make([]*proto.ObjectVersionInfo, 0),
+	}
+	begin := NewObjectVersion(req.ParentID, req.Name, "")
+	end := NewObjectVersion(req.ParentID, req.Name+"\x00", "")
+	mp.versionTree.AscendRange(begin, end, func(i BtreeItem) bool {
+		version := i.(*ObjectVersion).ObjectVersionInfo
+		reply.Versions = append(reply.Versions, &version)
+		return true
+	})
+	sortObjectVersions(reply.Versions)
+	return mp.replyObjectVersion(p, proto.OpOk, reply)
+}
+
+// versionedInode returns true if the inode is kept by a version of the object,
+// in which case it must not be unlinked when the dentry is removed or updated.
+func (mp *metaPartition) versionedInode(parentID uint64, name string, inode uint64) (found bool) {
+	begin := NewObjectVersion(parentID, name, "")
+	end := NewObjectVersion(parentID, name+"\x00", "")
+	mp.versionTree.AscendRange(begin, end, func(i BtreeItem) bool {
+		version := i.(*ObjectVersion)
+		found = !version.DeleteMarker && version.Inode == inode
+		return !found
+	})
+	return
+}
+
+// ListObjectVersions returns all of the versions of at most req.Max object
+// names, so that no name is split across two pages.
+func (mp *metaPartition) ListObjectVersions(req *proto.ListObjectVersionsRequest, p *Packet) (err error) {
+	reply := &proto.ListObjectVersionsResponse{
+		Versions: make([]*proto.ObjectVersionInfo, 0),
+	}
+	var (
+		lastName string
+		names    uint64
+	)
+	mp.versionTree.AscendGreaterOrEqual(NewObjectVersion(req.ParentID, req.Marker, ""), func(i BtreeItem) bool {
+		version := i.(*ObjectVersion).ObjectVersionInfo
+		if version.ParentID != req.ParentID {
+			return false
+		}
+		if len(reply.Versions) == 0 || version.Name != lastName {
+			if req.Max > 0 && names >= req.Max {
+				return false
+			}
+			lastName = version.Name
+			names++
+		}
+		reply.Versions = append(reply.Versions, &version)
+		return true
+	})
+	sortObjectVersions(reply.Versions)
+	return mp.replyObjectVersion(p, proto.OpOk, reply)
+}
+
+func (mp *metaPartition) putObjectVersion(op uint32, version *ObjectVersion) (resp *ObjectVersionResponse, err error) {
+	var encoded []byte
+	if encoded, err = version.Bytes(); err != nil {
+		return
+	}
+	var r interface{}
+	if r, err = mp.submit(op, encoded); err != nil {
+		return
+	}
+	resp = r.(*ObjectVersionResponse)
+	return
+}
+
+func (mp *metaPartition) replyObjectVersion(p *Packet, status uint8, reply interface{}) (err error) {
+	if status != proto.OpOk {
+		p.PacketErrorWithBody(status, nil)
+		return
+	}
+	var encoded []byte
+	if encoded, err = json.Marshal(reply); err != nil {
+		p.PacketErrorWithBody(proto.OpErr, []byte(err.Error()))
+		return
+	}
+	p.PacketOkWithBody(encoded)
+	return
+}
@@ -0,0 +1,196 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+// quotaKey identifies what an inode is accounted to. The ID is the quota ID
+// of a directory quota, or the uid or gid of the inode.
+type quotaKey struct {
+	Type proto.QuotaType
+	ID   uint64
+}
+
+func newQuotaKey(info *proto.QuotaHeartBeatInfo) quotaKey {
+	if info.Type == proto.QuotaTypeDir {
+		return quotaKey{Type: info.Type, ID: info.QuotaID}
+	}
+	return quotaKey{Type: info.Type, ID: info.Target}
+}
+
+// quotaCounter is the usage of the inodes of the partition accounted to a
+// quota key.
+type quotaCounter struct {
+	bytes  int64
+	inodes int64
+}
+
+// getInodeQuotaIds returns the IDs of the directory quotas the inode belongs to.
+func (mp *metaPartition) getInodeQuotaIds(inode uint64) []uint64 {
+	item := mp.extendTree.Get(NewExtend(inode))
+	if item == nil {
+		return nil
+	}
+	value, ok := item.(*Extend).Get([]byte(proto.QuotaXAttrKey))
+	if !ok {
+		return nil
+	}
+	return proto.ParseQuotaIds(string(value))
+}
+
+func newInodeQuotaKeys(uid, gid uint32, quotaIds []uint64) []quotaKey {
+	keys := make([]quotaKey, 0, len(quotaIds)+2)
+	keys = append(keys, quotaKey{Type: proto.QuotaTypeUid, ID: uint64(uid)}, quotaKey{Type: proto.QuotaTypeGid, ID: uint64(gid)})
+	return append(keys, newDirQuotaKeys(quotaIds)...)
+}
+
+func newDirQuotaKeys(quotaIds []uint64) []quotaKey {
+	keys := make([]quotaKey, 0, len(quotaIds))
+	for _, id := range quotaIds {
+		keys = append(keys, quotaKey{Type: proto.QuotaTypeDir, ID: id})
+	}
+	return keys
+}
+
+func containsQuotaKey(keys []quotaKey, key quotaKey) bool {
+	for _, k := range keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+func (mp *metaPartition) inodeQuotaKeys(ino *Inode) []quotaKey {
+	return newInodeQuotaKeys(ino.Uid, ino.Gid, mp.getInodeQuotaIds(ino.Inode))
+}
+
+// accountInode adds the inode to the usage of its quota keys, or removes it
+// from the usage if the sign is negative.
+func (mp *metaPartition) accountInode(ino *Inode, sign int64) {
+	mp.accountQuota(mp.inodeQuotaKeys(ino), sign*int64(ino.Size), sign)
+}
+
+func (mp *metaPartition) accountQuota(keys []quotaKey, bytes, inodes int64) {
+	if bytes == 0 && inodes == 0 {
+		return
+	}
+	mp.quotaMutex.Lock()
+	defer mp.quotaMutex.Unlock()
+	if mp.quotaCounters == nil {
+		mp.quotaCounters = make(map[quotaKey]*quotaCounter)
+	}
+	for _, key := range keys {
+		counter, ok := mp.quotaCounters[key]
+		if !ok {
+			counter = &quotaCounter{}
+			mp.quotaCounters[key] = counter
+		}
+		counter.bytes += bytes
+		counter.inodes += inodes
+		if counter.bytes == 0 && counter.inodes == 0 {
+			delete(mp.quotaCounters, key)
+		}
+	}
+}
+
+// resetQuotaUsage recounts the usage of the quotas after the inode tree is
+// replaced.
+func (mp *metaPartition) resetQuotaUsage() {
+	counters := make(map[quotaKey]*quotaCounter)
+	mp.inodeTree.Ascend(func(i BtreeItem) bool {
+		ino := i.(*Inode)
+		if ino.ShouldDelete() {
+			return true
+		}
+		for _, key := range mp.inodeQuotaKeys(ino) {
+			counter, ok := counters[key]
+			if !ok {
+				counter = &quotaCounter{}
+				counters[key] = counter
+			}
+			counter.bytes += int64(ino.Size)
+			counter.inodes++
+		}
+		return true
+	})
+	mp.quotaMutex.Lock()
+	mp.quotaCounters = counters
+	mp.quotaMutex.Unlock()
+}
+
+// volQuotas returns the quotas of the volume received from the master.
+func (mp *metaPartition) volQuotas() []*proto.QuotaHeartBeatInfo {
+	if mp.manager == nil {
+		return nil
+	}
+	return mp.manager.getVolQuotas(mp.config.VolName)
+}
+
+// GetQuotaUsages returns the usages of the quotas of the volume in the partition.
+func (mp *metaPartition) GetQuotaUsages() (usages []*proto.QuotaUsage) {
+	quotas := mp.volQuotas()
+	if len(quotas) == 0 {
+		return
+	}
+	mp.quotaMutex.Lock()
+	defer mp.quotaMutex.Unlock()
+	for _, info := range quotas {
+		usage := &proto.QuotaUsage{QuotaID: info.QuotaID}
+		if counter, ok := mp.quotaCounters[newQuotaKey(info)]; ok {
+			if counter.bytes > 0 {
+				usage.UsedBytes = uint64(counter.bytes)
+			}
+			if counter.inodes > 0 {
+				usage.UsedInodes = uint64(counter.inodes)
+			}
+		}
+		usages = append(usages, usage)
+	}
+	return
+}
+
+// isQuotaExceeded returns true if any quota of the keys has exceeded the
+// limit of bytes or inodes.
+func (mp *metaPartition) isQuotaExceeded(keys []quotaKey, checkBytes, checkInodes bool) bool {
+	for _, info := range mp.volQuotas() {
+		if !(checkBytes && info.BytesExceeded) && !(checkInodes && info.InodesExceeded) {
+			continue
+		}
+		if containsQuotaKey(keys, newQuotaKey(info)) {
+			return true
+		}
+	}
+	return false
+}
+
+// isAppendQuotaExceeded returns true if the extent enlarges the inode, which
+// belongs to a quota exceeding the limit of bytes.
+func (mp *metaPartition) isAppendQuotaExceeded(inode uint64, ek *proto.ExtentKey) bool {
+	if len(mp.volQuotas()) == 0 {
+		return false
+	}
+	item := mp.inodeTree.Get(NewInode(inode, 0))
+	if item == nil {
+		return false
+	}
+	ino := item.(*Inode)
+	if ek.FileOffset+uint64(ek.Size) <= ino.Size {
+		return false
+	}
+	return mp.isQuotaExceeded(mp.inodeQuotaKeys(ino), true, false)
+}
@@ -0,0 +1,51 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// requestCounter counts the requests served by the meta partition, so that the request rate
+// can be reported to the master for balancing the load of the meta nodes.
+type requestCounter struct {
+	count      uint64
+	mu         sync.Mutex
+	lastCount  uint64
+	lastReport time.Time
+}
+
+// CountRequest counts a request served by the meta partition. Only the leader serves the requests,
+// so the followers report no requests.
+func (mp *metaPartition) CountRequest() {
+	atomic.AddUint64(&mp.requests.count, 1)
+}
+
+// GetRequestRate returns the number of the requests per second served since the last call.
+func (mp *metaPartition) GetRequestRate() (rate float64) {
+	var counter = &mp.requests
+	counter.mu.Lock()
+	defer counter.mu.Unlock()
+	var now = time.Now()
+	var count = atomic.LoadUint64(&counter.count)
+	if elapsed := now.Sub(counter.lastReport).Seconds(); !counter.lastReport.IsZero() && elapsed > 0 {
+		rate = float64(count-counter.lastCount) / elapsed
+	}
+	counter.lastCount = count
+	counter.lastReport = now
+	return
+}
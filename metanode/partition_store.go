@@ -24,6 +24,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path"
+	"strconv"
 	"strings"
 	"sync/atomic"
 
@@ -35,17 +36,22 @@ import (
 )
 
 const (
-	snapshotDir     = "snapshot"
-	snapshotDirTmp  = ".snapshot"
-	snapshotBackup  = ".snapshot_backup"
-	inodeFile       = "inode"
-	dentryFile      = "dentry"
-	extendFile      = "extend"
-	multipartFile   = "multipart"
-	applyIDFile     = "apply"
-	SnapshotSign    = ".sign"
-	metadataFile    = "meta"
-	metadataFileTmp = ".meta"
+	snapshotDir           = "snapshot"
+	snapshotDirTmp        = ".snapshot"
+	snapshotBackup        = ".snapshot_backup"
+	inodeFile             = "inode"
+	dentryFile            = "dentry"
+	extendFile            = "extend"
+	multipartFile         = "multipart"
+	versionFile           = "version"
+	lockFile              = "lock"
+	txFile                = "transaction"
+	metaSnapshotFile      = "metasnapshot"
+	metaSnapshotDirPrefix = "msnap_"
+	applyIDFile           = "apply"
+	SnapshotSign          = ".sign"
+	metadataFile          = "meta"
+	metadataFileTmp       = ".meta"
 )
 
 func (mp *metaPartition) loadMetadata() (err error) {
@@ -77,6 +83,7 @@ func (mp *metaPartition) loadMetadata() (err error) {
 	mp.config.Start = mConf.Start
 	mp.config.End = mConf.End
 	mp.config.Peers = mConf.Peers
+	mp.config.Frozen = mConf.Frozen
 	mp.config.Cursor = mp.config.Start
 
 	log.LogInfof("loadMetadata: load complete: partitionID(%v) volume(%v) range(%v,%v) cursor(%v)",
@@ -295,6 +302,135 @@ func (mp *metaPartition) loadMultipart(rootDir string) error {
 	return nil
 }
 
+func (mp *metaPartition) loadVersion(rootDir string) error {
+	var err error
+	filename := path.Join(rootDir, versionFile)
+	if _, err = os.Stat(filename); err != nil {
+		return nil
+	}
+	fp, err := os.OpenFile(filename, os.O_RDONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = fp.Close()
+	}()
+	var mem mmap.MMap
+	if mem, err = mmap.Map(fp, mmap.RDONLY, 0); err != nil {
+		return err
+	}
+	defer func() {
+		_ = mem.Unmap()
+	}()
+	var offset, n int
+	// read number of object versions
+	var numVersions uint64
+	numVersions, n = binary.Uvarint(mem)
+	offset += n
+	for i := uint64(0); i < numVersions; i++ {
+		// read length
+		var numBytes uint64
+		numBytes, n = binary.Uvarint(mem[offset:])
+		offset += n
+		var version *ObjectVersion
+		if version, err = ObjectVersionFromBytes(mem[offset : offset+int(numBytes)]); err != nil {
+			return err
+		}
+		log.LogDebugf("loadVersion: new object version from bytes: partitionID(%v) version(%v)", mp.config.PartitionId, version)
+		mp.versionTree.ReplaceOrInsert(version, true)
+		offset += int(numBytes)
+	}
+	log.LogInfof("loadVersion: load complete: partitionID(%v) numVersions(%v) filename(%v)",
+		mp.config.PartitionId, numVersions, filename)
+	return nil
+}
+
+func (mp *metaPartition) loadLock(rootDir string) error {
+	var err error
+	filename := path.Join(rootDir, lockFile)
+	if _, err = os.Stat(filename); err != nil {
+		return nil
+	}
+	fp, err := os.OpenFile(filename, os.O_RDONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = fp.Close()
+	}()
+	var mem mmap.MMap
+	if mem, err = mmap.Map(fp, mmap.RDONLY, 0); err != nil {
+		return err
+	}
+	defer func() {
+		_ = mem.Unmap()
+	}()
+	var offset, n int
+	// read number of inode locks
+	var numLocks uint64
+	numLocks, n = binary.Uvarint(mem)
+	offset += n
+	for i := uint64(0); i < numLocks; i++ {
+		// read length
+		var numBytes uint64
+		numBytes, n = binary.Uvarint(mem[offset:])
+		offset += n
+		var locks *InodeLocks
+		if locks, err = InodeLocksFromBytes(mem[offset : offset+int(numBytes)]); err != nil {
+			return err
+		}
+		log.LogDebugf("loadLock: new inode locks from bytes: partitionID(%v) locks(%v)", mp.config.PartitionId, locks)
+		mp.lockTree.ReplaceOrInsert(locks, true)
+		offset += int(numBytes)
+	}
+	log.LogInfof("loadLock: load complete: partitionID(%v) numLocks(%v) filename(%v)",
+		mp.config.PartitionId, numLocks, filename)
+	return nil
+}
+
+func (mp *metaPartition) loadTx(rootDir string) error {
+	var err error
+	filename := path.Join(rootDir, txFile)
+	if _, err = os.Stat(filename); err != nil {
+		return nil
+	}
+	fp, err := os.OpenFile(filename, os.O_RDONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = fp.Close()
+	}()
+	var mem mmap.MMap
+	if mem, err = mmap.Map(fp, mmap.RDONLY, 0); err != nil {
+		return err
+	}
+	defer func() {
+		_ = mem.Unmap()
+	}()
+	var offset, n int
+	// read number of transactions
+	var numTxs uint64
+	numTxs, n = binary.Uvarint(mem)
+	offset += n
+	for i := uint64(0); i < numTxs; i++ {
+		// read length
+		var numBytes uint64
+		numBytes, n = binary.Uvarint(mem[offset:])
+		offset += n
+		var rec *TxRecord
+		if rec, err = TxRecordFromBytes(mem[offset : offset+int(numBytes)]); err != nil {
+			return err
+		}
+		log.LogDebugf("loadTx: new transaction from bytes: partitionID(%v) tx(%v)", mp.config.PartitionId, rec)
+		mp.txTree.ReplaceOrInsert(rec, true)
+		offset += int(numBytes)
+	}
+	log.LogInfof("loadTx: load complete: partitionID(%v) numTxs(%v) filename(%v)",
+		mp.config.PartitionId, numTxs, filename)
+	return nil
+}
+
 func (mp *metaPartition) loadApplyID(rootDir string) (err error) {
 	filename := path.Join(rootDir, applyIDFile)
 	if _, err = os.Stat(filename); err != nil {
@@ -604,3 +740,296 @@ func (mp *metaPartition) storeMultipart(rootDir string, sm *storeMsg) (crc uint3
 		mp.config.PartitionId, mp.config.VolName, multipartTree.Len(), crc)
 	return
 }
+
+func (mp *metaPartition) storeVersion(rootDir string, sm *storeMsg) (crc uint32, err error) {
+	var versionTree = sm.versionTree
+	var fp = path.Join(rootDir, versionFile)
+	var f *os.File
+	f, err = os.OpenFile(fp, os.O_RDWR|os.O_TRUNC|os.O_APPEND|os.O_CREATE, 0755)
+	if err != nil {
+		return
+	}
+	defer func() {
+		closeErr := f.Close()
+		if err == nil && closeErr != nil {
+			err = closeErr
+		}
+	}()
+	var writer = bufio.NewWriterSize(f, 4*1024*1024)
+	var crc32 = crc32.NewIEEE()
+	var varintTmp = make([]byte, binary.MaxVarintLen64)
+	var n int
+	// write number of object versions
+	n = binary.PutUvarint(varintTmp, uint64(versionTree.Len()))
+	if _, err = writer.Write(varintTmp[:n]); err != nil {
+		return
+	}
+	if _, err = crc32.Write(varintTmp[:n]); err != nil {
+		return
+	}
+	versionTree.Ascend(func(i BtreeItem) bool {
+		version := i.(*ObjectVersion)
+		var raw []byte
+		if raw, err = version.Bytes(); err != nil {
+			return false
+		}
+		// write length
+		n = binary.PutUvarint(varintTmp, uint64(len(raw)))
+		if _, err = writer.Write(varintTmp[:n]); err != nil {
+			return false
+		}
+		if _, err = crc32.Write(varintTmp[:n]); err != nil {
+			return false
+		}
+		// write raw
+		if _, err = writer.Write(raw); err != nil {
+			return false
+		}
+		if _, err = crc32.Write(raw); err != nil {
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return
+	}
+
+	if err = writer.Flush(); err != nil {
+		return
+	}
+	if err = f.Sync(); err != nil {
+		return
+	}
+	crc = crc32.Sum32()
+	log.LogInfof("storeVersion: store complete: partitoinID(%v) volume(%v) numVersions(%v) crc(%v)",
+		mp.config.PartitionId, mp.config.VolName, versionTree.Len(), crc)
+	return
+}
+
+func (mp *metaPartition) storeLock(rootDir string, sm *storeMsg) (crc uint32, err error) {
+	var lockTree = sm.lockTree
+	var fp = path.Join(rootDir, lockFile)
+	var f *os.File
+	f, err = os.OpenFile(fp, os.O_RDWR|os.O_TRUNC|os.O_APPEND|os.O_CREATE, 0755)
+	if err != nil {
+		return
+	}
+	defer func() {
+		closeErr := f.Close()
+		if err == nil && closeErr != nil {
+			err = closeErr
+		}
+	}()
+	var writer = bufio.NewWriterSize(f, 4*1024*1024)
+	var crc32 = crc32.NewIEEE()
+	var varintTmp = make([]byte, binary.MaxVarintLen64)
+	var n int
+	// write number of inode locks
+	n = binary.PutUvarint(varintTmp, uint64(lockTree.Len()))
+	if _, err = writer.Write(varintTmp[:n]); err != nil {
+		return
+	}
+	if _, err = crc32.Write(varintTmp[:n]); err != nil {
+		return
+	}
+	lockTree.Ascend(func(i BtreeItem) bool {
+		locks := i.(*InodeLocks)
+		var raw []byte
+		if raw, err = locks.Bytes(); err != nil {
+			return false
+		}
+		// write length
+		n = binary.PutUvarint(varintTmp, uint64(len(raw)))
+		if _, err = writer.Write(varintTmp[:n]); err != nil {
+			return false
+		}
+		if _, err = crc32.Write(varintTmp[:n]); err != nil {
+			return false
+		}
+		// write raw
+		if _, err = writer.Write(raw); err != nil {
+			return false
+		}
+		if _, err = crc32.Write(raw); err != nil {
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return
+	}
+
+	if err = writer.Flush(); err != nil {
+		return
+	}
+	if err = f.Sync(); err != nil {
+		return
+	}
+	crc = crc32.Sum32()
+	log.LogInfof("storeLock: store complete: partitoinID(%v) volume(%v) numLocks(%v) crc(%v)",
+		mp.config.PartitionId, mp.config.VolName, lockTree.Len(), crc)
+	return
+}
+
+func (mp *metaPartition) storeTx(rootDir string, sm *storeMsg) (crc uint32, err error) {
+	var txTree = sm.txTree
+	var fp = path.Join(rootDir, txFile)
+	var f *os.File
+	f, err = os.OpenFile(fp, os.O_RDWR|os.O_TRUNC|os.O_APPEND|os.O_CREATE, 0755)
+	if err != nil {
+		return
+	}
+	defer func() {
+		closeErr := f.Close()
+		if err == nil && closeErr != nil {
+			err = closeErr
+		}
+	}()
+	var writer = bufio.NewWriterSize(f, 4*1024*1024)
+	var crc32 = crc32.NewIEEE()
+	var varintTmp = make([]byte, binary.MaxVarintLen64)
+	var n int
+	// write number of transactions
+	n = binary.PutUvarint(varintTmp, uint64(txTree.Len()))
+	if _, err = writer.Write(varintTmp[:n]); err != nil {
+		return
+	}
+	if _, err = crc32.Write(varintTmp[:n]); err != nil {
+		return
+	}
+	txTree.Ascend(func(i BtreeItem) bool {
+		rec := i.(*TxRecord)
+		var raw []byte
+		if raw, err = rec.Bytes(); err != nil {
+			return false
+		}
+		// write length
+		n = binary.PutUvarint(varintTmp, uint64(len(raw)))
+		if _, err = writer.Write(varintTmp[:n]); err != nil {
+			return false
+		}
+		if _, err = crc32.Write(varintTmp[:n]); err != nil {
+			return false
+		}
+		// write raw
+		if _, err = writer.Write(raw); err != nil {
+			return false
+		}
+		if _, err = crc32.Write(raw); err != nil {
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return
+	}
+
+	if err = writer.Flush(); err != nil {
+		return
+	}
+	if err = f.Sync(); err != nil {
+		return
+	}
+	crc = crc32.Sum32()
+	log.LogInfof("storeTx: store complete: partitoinID(%v) volume(%v) numTxs(%v) crc(%v)",
+		mp.config.PartitionId, mp.config.VolName, txTree.Len(), crc)
+	return
+}
+
+func (mp *metaPartition) loadMetaSnapshots(rootDir string) (err error) {
+	filename := path.Join(rootDir, metaSnapshotFile)
+	if _, err = os.Stat(filename); err != nil {
+		return nil
+	}
+	var data []byte
+	if data, err = ioutil.ReadFile(filename); err != nil {
+		return
+	}
+	cmds := make([]*MetaSnapshotCmd, 0)
+	if err = json.Unmarshal(data, &cmds); err != nil {
+		return
+	}
+	snapshots := make(map[uint64]*MetaSnapshot, len(cmds))
+	for _, cmd := range cmds {
+		snap := newEmptyMetaSnapshot(cmd.ID, cmd.CreateTime)
+		// load the trees of the snapshot by a partition on them
+		loader := &metaPartition{
+			config: &MetaPartitionConfig{
+				PartitionId: mp.config.PartitionId,
+				VolName:     mp.config.VolName,
+			},
+			inodeTree:  snap.inodeTree,
+			dentryTree: snap.dentryTree,
+			extendTree: snap.extendTree,
+			txTree:     NewBtree(),
+			freeList:   newFreeList(),
+		}
+		dir := path.Join(rootDir, metaSnapshotDirPrefix+strconv.FormatUint(cmd.ID, 10))
+		if err = loader.loadInode(dir); err != nil {
+			return
+		}
+		if err = loader.loadDentry(dir); err != nil {
+			return
+		}
+		if err = loader.loadExtend(dir); err != nil {
+			return
+		}
+		snapshots[snap.ID] = snap
+	}
+	mp.setMetaSnapshots(snapshots)
+	log.LogInfof("loadMetaSnapshots: load complete: partitionID(%v) numSnapshots(%v) filename(%v)",
+		mp.config.PartitionId, len(snapshots), filename)
+	return
+}
+
+// storeMetaSnapshots stores every snapshot in a sub directory. Snapshots never
+// change, so the files of the last dump are linked if they exist.
+func (mp *metaPartition) storeMetaSnapshots(rootDir string, sm *storeMsg) (crc uint32, err error) {
+	cmds := make([]*MetaSnapshotCmd, 0, len(sm.snapshots))
+	for _, snap := range sm.snapshots {
+		name := metaSnapshotDirPrefix + strconv.FormatUint(snap.ID, 10)
+		dir := path.Join(rootDir, name)
+		if err = os.MkdirAll(dir, 0755); err != nil {
+			return
+		}
+		if err = linkMetaSnapshot(path.Join(mp.config.RootDir, snapshotDir, name), dir); err != nil {
+			snapMsg := &storeMsg{
+				inodeTree:  snap.inodeTree,
+				dentryTree: snap.dentryTree,
+				extendTree: snap.extendTree,
+			}
+			if _, err = mp.storeInode(dir, snapMsg); err != nil {
+				return
+			}
+			if _, err = mp.storeDentry(dir, snapMsg); err != nil {
+				return
+			}
+			if _, err = mp.storeExtend(dir, snapMsg); err != nil {
+				return
+			}
+		}
+		cmds = append(cmds, &MetaSnapshotCmd{ID: snap.ID, CreateTime: snap.CreateTime})
+	}
+	var data []byte
+	if data, err = json.Marshal(cmds); err != nil {
+		return
+	}
+	if err = ioutil.WriteFile(path.Join(rootDir, metaSnapshotFile), data, 0644); err != nil {
+		return
+	}
+	crc = crc32.ChecksumIEEE(data)
+	log.LogInfof("storeMetaSnapshots: store complete: partitoinID(%v) volume(%v) numSnapshots(%v) crc(%v)",
+		mp.config.PartitionId, mp.config.VolName, len(cmds), crc)
+	return
+}
+
+func linkMetaSnapshot(srcDir, dstDir string) (err error) {
+	for _, filename := range []string{inodeFile, dentryFile, extendFile} {
+		dst := path.Join(dstDir, filename)
+		os.Remove(dst)
+		if err = os.Link(path.Join(srcDir, filename), dst); err != nil {
+			return
+		}
+	}
+	return
+}
@@ -31,6 +31,10 @@ type storeMsg struct {
 	dentryTree    *BTree
 	extendTree    *BTree
 	multipartTree *BTree
+	versionTree   *BTree
+	lockTree      *BTree
+	txTree        *BTree
+	snapshots     []*MetaSnapshot
 }
 
 func (mp *metaPartition) startSchedule(curIndex uint64) {
@@ -0,0 +1,746 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/errors"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+const (
+	// DirSummaryInterval is how often the leader applies the changes of the
+	// children to the summaries of the directories of the partition. The
+	// change of a directory is applied to its parent directory in another
+	// partition in the next round.
+	DirSummaryInterval = 5 * time.Second
+	// DirSummaryFullInterval is how often the leader recounts all directories
+	// of the partition, which reconciles the changes not tracked, e.g. the
+	// files linked in several directories and the moved inodes.
+	DirSummaryFullInterval  = time.Hour
+	dirSummaryRetryInterval = time.Minute
+	dirSummaryBatchSize     = 1024
+	dirSummaryMaxPasses     = 64
+)
+
+// dirChildren holds the direct children of a directory of the partition.
+// The dentries are placed in the partition of the parent inode, so the
+// children of a local directory are always known locally, while the child
+// inodes may be in the other partitions.
+type dirChildren struct {
+	files   []uint64
+	subdirs []uint64
+}
+
+// DirSummaryItem is the summary of a directory to apply by the raft.
+type DirSummaryItem struct {
+	Inode   uint64            `json:"ino"`
+	Summary *proto.DirSummary `json:"summary"`
+}
+
+type dirParent struct {
+	ino   uint64
+	isDir bool // the child is a directory
+}
+
+type summaryCursor struct {
+	epoch uint64
+	seq   uint64
+}
+
+// dirSummaryState is kept by the leader to recount only the directories of
+// which the children changed. The fsm marks the directories of the created
+// and deleted dentries as dirty, and the changes of the sizes and the
+// summaries of the children are pulled from the change logs of the partitions
+// of the children. A child linked in several directories is tracked in one
+// of them until the next full recount.
+type dirSummaryState struct {
+	// guarded by dirSummaryMutex, as they are updated by the fsm
+	parents  map[uint64]dirParent // child inode -> parent directory of the partition
+	dirty    map[uint64]bool      // directories to recount
+	fetch    map[uint64]bool      // remote children to fetch -> is directory
+	touched  map[uint64]bool      // children linked or unlinked during the full recount
+	building bool
+
+	// used by the worker only
+	sizes     map[uint64]uint64            // sizes of the remote files
+	summaries map[uint64]*proto.DirSummary // summaries of the remote directories
+	cursors   map[uint64]*summaryCursor    // partition id -> cursor of the change log
+	views     []*proto.MetaPartitionView
+	lastFull  time.Time
+}
+
+func newDirSummaryState() *dirSummaryState {
+	return &dirSummaryState{
+		parents:   make(map[uint64]dirParent),
+		dirty:     make(map[uint64]bool),
+		fetch:     make(map[uint64]bool),
+		touched:   make(map[uint64]bool),
+		sizes:     make(map[uint64]uint64),
+		summaries: make(map[uint64]*proto.DirSummary),
+		cursors:   make(map[uint64]*summaryCursor),
+	}
+}
+
+func (mp *metaPartition) dirSummaryWorker() {
+	t := time.NewTicker(DirSummaryInterval)
+	defer t.Stop()
+	var lastFailed time.Time
+	for {
+		select {
+		case <-mp.stopC:
+			return
+		case <-t.C:
+			if _, ok := mp.IsLeader(); !ok {
+				mp.setDirSummaryState(nil)
+				continue
+			}
+			s := mp.getDirSummaryState()
+			if s == nil || time.Since(s.lastFull) >= DirSummaryFullInterval {
+				if time.Since(lastFailed) < dirSummaryRetryInterval {
+					continue
+				}
+				if err := mp.updateDirSummary(); err != nil {
+					lastFailed = time.Now()
+					log.LogWarnf("dirSummaryWorker: mp(%v) full recount err(%v)", mp.config.PartitionId, err)
+				}
+				continue
+			}
+			if err := mp.updateDirtyDirSummary(s); err != nil {
+				log.LogWarnf("dirSummaryWorker: mp(%v) err(%v)", mp.config.PartitionId, err)
+			}
+		}
+	}
+}
+
+func (mp *metaPartition) getDirSummaryState() *dirSummaryState {
+	mp.dirSummaryMutex.Lock()
+	defer mp.dirSummaryMutex.Unlock()
+	return mp.dirSummary
+}
+
+func (mp *metaPartition) setDirSummaryState(s *dirSummaryState) {
+	mp.dirSummaryMutex.Lock()
+	defer mp.dirSummaryMutex.Unlock()
+	mp.dirSummary = s
+}
+
+// linkDirSummary is called by the fsm when the dentry of the child is created
+// or deleted in the directory of the partition.
+func (mp *metaPartition) linkDirSummary(d *Dentry, linked bool) {
+	mp.dirSummaryMutex.Lock()
+	defer mp.dirSummaryMutex.Unlock()
+	s := mp.dirSummary
+	if s == nil {
+		return
+	}
+	s.dirty[d.ParentId] = true
+	if s.building {
+		s.touched[d.Inode] = true
+	}
+	if linked {
+		s.parents[d.Inode] = dirParent{ino: d.ParentId, isDir: proto.IsDir(d.Type)}
+		if !mp.isLocalInode(d.Inode) {
+			s.fetch[d.Inode] = proto.IsDir(d.Type)
+		}
+	} else if parent, ok := s.parents[d.Inode]; ok && parent.ino == d.ParentId {
+		delete(s.parents, d.Inode)
+	}
+}
+
+// getDirSummary returns the summary of the directory last applied.
+func (mp *metaPartition) getDirSummary(ino uint64) *proto.DirSummary {
+	item := mp.extendTree.Get(NewExtend(ino))
+	if item == nil {
+		return nil
+	}
+	value, ok := item.(*Extend).Get([]byte(proto.SummaryXAttrKey))
+	if !ok {
+		return nil
+	}
+	summary, err := proto.ParseDirSummary(value)
+	if err != nil {
+		return nil
+	}
+	return summary
+}
+
+func (mp *metaPartition) isLocalInode(ino uint64) bool {
+	return ino >= mp.config.Start && ino <= mp.config.End
+}
+
+func (mp *metaPartition) collectDirChildren() map[uint64]*dirChildren {
+	dirs := make(map[uint64]*dirChildren)
+	mp.inodeTree.Ascend(func(i BtreeItem) bool {
+		ino := i.(*Inode)
+		if proto.IsDir(ino.Type) && !ino.ShouldDelete() {
+			dirs[ino.Inode] = &dirChildren{}
+		}
+		return true
+	})
+	mp.dentryTree.Ascend(func(i BtreeItem) bool {
+		d := i.(*Dentry)
+		children, ok := dirs[d.ParentId]
+		if !ok {
+			return true
+		}
+		if proto.IsDir(d.Type) {
+			children.subdirs = append(children.subdirs, d.Inode)
+		} else {
+			children.files = append(children.files, d.Inode)
+		}
+		return true
+	})
+	return dirs
+}
+
+// updateDirSummary recounts the summaries of all directories of the
+// partition, applies the changed ones, and starts over the state to apply
+// the following changes.
+func (mp *metaPartition) updateDirSummary() (err error) {
+	s := newDirSummaryState()
+	s.building = true
+	mp.setDirSummaryState(s)
+	defer func() {
+		if err != nil {
+			mp.setDirSummaryState(nil)
+		}
+	}()
+	start := time.Now()
+	s.cursors[mp.config.PartitionId] = mp.summaryChanges.cursor()
+	dirs := mp.collectDirChildren()
+	mp.indexDirSummary(s, dirs)
+
+	var (
+		sizes      = make(map[uint64]uint64)
+		summaries  = make(map[uint64]*proto.DirSummary)
+		remoteInos []uint64
+		remoteDirs []uint64
+	)
+	for ino, children := range dirs {
+		if summary := mp.getDirSummary(ino); summary != nil {
+			summaries[ino] = summary
+		}
+		for _, child := range children.files {
+			if !mp.isLocalInode(child) {
+				remoteInos = append(remoteInos, child)
+			} else if item := mp.inodeTree.Get(NewInode(child, 0)); item != nil {
+				sizes[child] = item.(*Inode).Size
+			}
+		}
+		for _, child := range children.subdirs {
+			if !mp.isLocalInode(child) {
+				remoteDirs = append(remoteDirs, child)
+			}
+		}
+	}
+	if len(remoteInos) > 0 || len(remoteDirs) > 0 {
+		if err = mp.refreshDirSummaryViews(s); err != nil {
+			return
+		}
+		// the cursors are taken before fetching, so no change is lost
+		for view := range groupInodesByPartition(s.views, append(remoteInos, remoteDirs...)) {
+			if _, err = mp.pullSummaryChanges(s, view); err != nil {
+				return
+			}
+		}
+		if err = mp.fetchRemoteDirSummary(s.views, remoteInos, remoteDirs, s.sizes, s.summaries); err != nil {
+			return
+		}
+		for ino, size := range s.sizes {
+			sizes[ino] = size
+		}
+		for ino, summary := range s.summaries {
+			summaries[ino] = summary
+		}
+	}
+
+	changed := recountDirSummary(dirs, sizes, summaries)
+	if err = mp.submitDirSummary(changed); err != nil {
+		return
+	}
+	s.lastFull = start
+	log.LogDebugf("updateDirSummary: mp(%v) dirs(%v) changed(%v)", mp.config.PartitionId, len(dirs), len(changed))
+	return
+}
+
+// indexDirSummary indexes the parents of the children, except the ones
+// linked or unlinked by the fsm during the scan.
+func (mp *metaPartition) indexDirSummary(s *dirSummaryState, dirs map[uint64]*dirChildren) {
+	mp.dirSummaryMutex.Lock()
+	defer mp.dirSummaryMutex.Unlock()
+	for ino, children := range dirs {
+		for _, child := range children.files {
+			if !s.touched[child] {
+				s.parents[child] = dirParent{ino: ino}
+			}
+		}
+		for _, child := range children.subdirs {
+			if !s.touched[child] {
+				s.parents[child] = dirParent{ino: ino, isDir: true}
+			}
+		}
+	}
+	s.building = false
+	s.touched = nil
+}
+
+// updateDirtyDirSummary applies the changes of the children since the last
+// round to the summaries of their directories.
+func (mp *metaPartition) updateDirtyDirSummary(s *dirSummaryState) (err error) {
+	if err = mp.pullDirSummaryChanges(s); err != nil {
+		return
+	}
+	if err = mp.fetchDirSummaryChildren(s); err != nil {
+		return
+	}
+	dirty := mp.takeDirtyDirs(s)
+	if len(dirty) == 0 {
+		return
+	}
+	changed := mp.recountDirtyDirs(s, dirty)
+	if err = mp.submitDirSummary(changed); err != nil {
+		mp.markDirtyDirs(s, dirty)
+		return
+	}
+	log.LogDebugf("updateDirtyDirSummary: mp(%v) dirty(%v) changed(%v)", mp.config.PartitionId, len(dirty), len(changed))
+	return
+}
+
+// pullDirSummaryChanges pulls the change logs of the partitions of the
+// children, and marks the parents of the changed children as dirty.
+func (mp *metaPartition) pullDirSummaryChanges(s *dirSummaryState) (err error) {
+	var local *proto.GetSummaryChangesResponse
+	if cursor, ok := s.cursors[mp.config.PartitionId]; ok {
+		local = mp.summaryChanges.changesSince(cursor.epoch, cursor.seq)
+	} else {
+		local = mp.summaryChanges.changesSince(0, 0)
+	}
+	s.cursors[mp.config.PartitionId] = &summaryCursor{epoch: local.Epoch, seq: local.Seq}
+	mp.markChangedChildren(s, mp.config.PartitionId, local)
+
+	// the new remote children may be placed in the partitions not pulled yet
+	mp.dirSummaryMutex.Lock()
+	var unknown bool
+	for ino := range s.fetch {
+		if view := findMetaPartitionView(s.views, ino); view == nil || s.cursors[view.PartitionID] == nil {
+			unknown = true
+			break
+		}
+	}
+	mp.dirSummaryMutex.Unlock()
+	if unknown {
+		if err = mp.refreshDirSummaryViews(s); err != nil {
+			return
+		}
+	}
+	for _, view := range s.views {
+		if view.PartitionID == mp.config.PartitionId {
+			continue
+		}
+		if s.cursors[view.PartitionID] == nil && !mp.hasFetchIn(s, view) {
+			continue
+		}
+		var resp *proto.GetSummaryChangesResponse
+		if resp, err = mp.pullSummaryChanges(s, view); err != nil {
+			return
+		}
+		mp.markChangedChildren(s, view.PartitionID, resp)
+	}
+	return
+}
+
+func (mp *metaPartition) hasFetchIn(s *dirSummaryState, view *proto.MetaPartitionView) bool {
+	mp.dirSummaryMutex.Lock()
+	defer mp.dirSummaryMutex.Unlock()
+	for ino := range s.fetch {
+		if ino >= view.Start && ino <= view.End {
+			return true
+		}
+	}
+	return false
+}
+
+// pullSummaryChanges gets the changes of the partition after the cursor, and
+// moves the cursor. A partition without a cursor gets a reset.
+func (mp *metaPartition) pullSummaryChanges(s *dirSummaryState, view *proto.MetaPartitionView) (resp *proto.GetSummaryChangesResponse, err error) {
+	req := &proto.GetSummaryChangesRequest{VolName: mp.config.VolName, PartitionID: view.PartitionID}
+	if cursor, ok := s.cursors[view.PartitionID]; ok {
+		req.Epoch, req.Seq = cursor.epoch, cursor.seq
+	}
+	resp = &proto.GetSummaryChangesResponse{}
+	if err = checkMetaPartitionStatus(mp.requestMetaPartition(view, proto.OpMetaGetSummaryChanges, req, resp)); err != nil {
+		return
+	}
+	s.cursors[view.PartitionID] = &summaryCursor{epoch: resp.Epoch, seq: resp.Seq}
+	return
+}
+
+// markChangedChildren marks the parents of the changed children of the
+// partition as dirty, and the remote ones to fetch. All children of the
+// partition are taken as changed on a reset.
+func (mp *metaPartition) markChangedChildren(s *dirSummaryState, partitionID uint64, resp *proto.GetSummaryChangesResponse) {
+	local := partitionID == mp.config.PartitionId
+	mp.dirSummaryMutex.Lock()
+	defer mp.dirSummaryMutex.Unlock()
+	mark := func(ino uint64, parent dirParent) {
+		// the summaries of the local directories are applied to their
+		// parents in the round the summaries changed
+		if local && parent.isDir {
+			return
+		}
+		s.dirty[parent.ino] = true
+		if !local {
+			s.fetch[ino] = parent.isDir
+		}
+	}
+	if !resp.Reset {
+		for _, ino := range resp.Inodes {
+			if parent, ok := s.parents[ino]; ok {
+				mark(ino, parent)
+			}
+		}
+		return
+	}
+	start, end := mp.config.Start, mp.config.End
+	if !local {
+		view := findMetaPartitionView(s.views, partitionID)
+		if view == nil {
+			return
+		}
+		start, end = view.Start, view.End
+	}
+	for ino, parent := range s.parents {
+		if ino >= start && ino <= end {
+			mark(ino, parent)
+		}
+	}
+}
+
+// fetchDirSummaryChildren fetches the sizes and the summaries of the remote
+// children marked to fetch.
+func (mp *metaPartition) fetchDirSummaryChildren(s *dirSummaryState) (err error) {
+	mp.dirSummaryMutex.Lock()
+	fetch := s.fetch
+	s.fetch = make(map[uint64]bool)
+	mp.dirSummaryMutex.Unlock()
+	if len(fetch) == 0 {
+		return
+	}
+	var inos, dirs []uint64
+	for ino, isDir := range fetch {
+		// the removed children are not returned
+		if isDir {
+			delete(s.summaries, ino)
+			dirs = append(dirs, ino)
+		} else {
+			delete(s.sizes, ino)
+			inos = append(inos, ino)
+		}
+	}
+	if err = mp.fetchRemoteDirSummary(s.views, inos, dirs, s.sizes, s.summaries); err != nil {
+		mp.dirSummaryMutex.Lock()
+		for ino, isDir := range fetch {
+			s.fetch[ino] = isDir
+		}
+		mp.dirSummaryMutex.Unlock()
+	}
+	return
+}
+
+func (mp *metaPartition) takeDirtyDirs(s *dirSummaryState) (dirty map[uint64]bool) {
+	mp.dirSummaryMutex.Lock()
+	defer mp.dirSummaryMutex.Unlock()
+	dirty = s.dirty
+	s.dirty = make(map[uint64]bool)
+	return
+}
+
+func (mp *metaPartition) markDirtyDirs(s *dirSummaryState, dirty map[uint64]bool) {
+	mp.dirSummaryMutex.Lock()
+	defer mp.dirSummaryMutex.Unlock()
+	for ino := range dirty {
+		s.dirty[ino] = true
+	}
+}
+
+// recountDirtyDirs recounts the dirty directories, and returns the changed
+// summaries. The change of a directory makes its local parent dirty in the
+// same round, so it repeats until the local directory tree converges.
+func (mp *metaPartition) recountDirtyDirs(s *dirSummaryState, dirty map[uint64]bool) map[uint64]*proto.DirSummary {
+	changed := make(map[uint64]*proto.DirSummary)
+	summaryOf := func(ino uint64) *proto.DirSummary {
+		if summary, ok := changed[ino]; ok {
+			return summary
+		}
+		if !mp.isLocalInode(ino) {
+			return s.summaries[ino]
+		}
+		return mp.getDirSummary(ino)
+	}
+	for pass := 0; len(dirty) > 0; pass++ {
+		if pass == dirSummaryMaxPasses {
+			mp.markDirtyDirs(s, dirty)
+			break
+		}
+		next := make(map[uint64]bool)
+		for ino := range dirty {
+			item := mp.inodeTree.Get(NewInode(ino, 0))
+			if item == nil || !proto.IsDir(item.(*Inode).Type) || item.(*Inode).ShouldDelete() {
+				continue
+			}
+			children := mp.listDirChildren(ino)
+			sizes := make(map[uint64]uint64, len(children.files))
+			for _, child := range children.files {
+				if !mp.isLocalInode(child) {
+					sizes[child] = s.sizes[child]
+				} else if item := mp.inodeTree.Get(NewInode(child, 0)); item != nil {
+					sizes[child] = item.(*Inode).Size
+				}
+			}
+			summaries := make(map[uint64]*proto.DirSummary, len(children.subdirs))
+			for _, child := range children.subdirs {
+				if summary := summaryOf(child); summary != nil {
+					summaries[child] = summary
+				}
+			}
+			summary := countDirSummary(children, sizes, summaries)
+			if old := summaryOf(ino); old != nil && *old == *summary {
+				continue
+			}
+			changed[ino] = summary
+			mp.dirSummaryMutex.Lock()
+			if parent, ok := s.parents[ino]; ok {
+				next[parent.ino] = true
+			}
+			mp.dirSummaryMutex.Unlock()
+		}
+		dirty = next
+	}
+	return changed
+}
+
+// listDirChildren returns the direct children of the directory.
+func (mp *metaPartition) listDirChildren(ino uint64) *dirChildren {
+	children := &dirChildren{}
+	begin := &Dentry{ParentId: ino}
+	end := &Dentry{ParentId: ino + 1}
+	mp.dentryTree.AscendRange(begin, end, func(i BtreeItem) bool {
+		d := i.(*Dentry)
+		if proto.IsDir(d.Type) {
+			children.subdirs = append(children.subdirs, d.Inode)
+		} else {
+			children.files = append(children.files, d.Inode)
+		}
+		return true
+	})
+	return children
+}
+
+// submitDirSummary applies the changed summaries by the raft.
+func (mp *metaPartition) submitDirSummary(changed map[uint64]*proto.DirSummary) (err error) {
+	if len(changed) == 0 {
+		return
+	}
+	items := make([]*DirSummaryItem, 0, len(changed))
+	for ino, summary := range changed {
+		items = append(items, &DirSummaryItem{Inode: ino, Summary: summary})
+	}
+	for start := 0; start < len(items); start += dirSummaryBatchSize {
+		end := start + dirSummaryBatchSize
+		if end > len(items) {
+			end = len(items)
+		}
+		var val []byte
+		if val, err = json.Marshal(items[start:end]); err != nil {
+			return
+		}
+		if _, err = mp.submit(opFSMSetDirSummary, val); err != nil {
+			return
+		}
+	}
+	return
+}
+
+func (mp *metaPartition) refreshDirSummaryViews(s *dirSummaryState) (err error) {
+	var views []*proto.MetaPartitionView
+	if views, err = masterClient.ClientAPI().GetMetaPartitions(mp.config.VolName); err != nil {
+		return
+	}
+	sort.Slice(views, func(i, j int) bool { return views[i].Start < views[j].Start })
+	s.views = views
+	return
+}
+
+// recountDirSummary recounts the summaries of the directories, and returns
+// the changed ones. The summaries of the local subdirectories are recounted in
+// the same round, so it repeats until the local directory tree converges.
+func recountDirSummary(dirs map[uint64]*dirChildren, sizes map[uint64]uint64, summaries map[uint64]*proto.DirSummary) map[uint64]*proto.DirSummary {
+	changed := make(map[uint64]*proto.DirSummary)
+	for pass := 0; pass < dirSummaryMaxPasses; pass++ {
+		var updated bool
+		for ino, children := range dirs {
+			summary := countDirSummary(children, sizes, summaries)
+			if old, ok := summaries[ino]; ok && *old == *summary {
+				continue
+			}
+			summaries[ino] = summary
+			changed[ino] = summary
+			updated = true
+		}
+		if !updated {
+			break
+		}
+	}
+	return changed
+}
+
+func countDirSummary(children *dirChildren, sizes map[uint64]uint64, summaries map[uint64]*proto.DirSummary) *proto.DirSummary {
+	summary := &proto.DirSummary{
+		Files:   uint64(len(children.files)),
+		Subdirs: uint64(len(children.subdirs)),
+	}
+	for _, child := range children.files {
+		summary.Bytes += sizes[child]
+	}
+	summary.RFiles, summary.RSubdirs, summary.RBytes = summary.Files, summary.Subdirs, summary.Bytes
+	for _, child := range children.subdirs {
+		if sub, ok := summaries[child]; ok {
+			summary.RFiles += sub.RFiles
+			summary.RSubdirs += sub.RSubdirs
+			summary.RBytes += sub.RBytes
+		}
+	}
+	return summary
+}
+
+// fetchRemoteDirSummary gets the sizes of the files and the summaries of the
+// directories placed in the other partitions of the volume.
+func (mp *metaPartition) fetchRemoteDirSummary(views []*proto.MetaPartitionView, inos, dirs []uint64, sizes map[uint64]uint64, summaries map[uint64]*proto.DirSummary) (err error) {
+	for view, group := range groupInodesByPartition(views, inos) {
+		for start := 0; start < len(group); start += dirSummaryBatchSize {
+			end := start + dirSummaryBatchSize
+			if end > len(group) {
+				end = len(group)
+			}
+			req := &proto.BatchInodeGetRequest{VolName: mp.config.VolName, PartitionID: view.PartitionID, Inodes: group[start:end]}
+			resp := &proto.BatchInodeGetResponse{}
+			if err = checkMetaPartitionStatus(mp.requestMetaPartition(view, proto.OpMetaBatchInodeGet, req, resp)); err != nil {
+				return
+			}
+			for _, info := range resp.Infos {
+				sizes[info.Inode] = info.Size
+			}
+		}
+	}
+	for view, group := range groupInodesByPartition(views, dirs) {
+		for start := 0; start < len(group); start += dirSummaryBatchSize {
+			end := start + dirSummaryBatchSize
+			if end > len(group) {
+				end = len(group)
+			}
+			req := &proto.BatchGetXAttrRequest{VolName: mp.config.VolName, PartitionId: view.PartitionID,
+				Inodes: group[start:end], Keys: []string{proto.SummaryXAttrKey}}
+			resp := &proto.BatchGetXAttrResponse{}
+			if err = checkMetaPartitionStatus(mp.requestMetaPartition(view, proto.OpMetaBatchGetXAttr, req, resp)); err != nil {
+				return
+			}
+			for _, info := range resp.XAttrs {
+				if summary, e := proto.ParseDirSummary(info.Get(proto.SummaryXAttrKey)); e == nil {
+					summaries[info.Inode] = summary
+				}
+			}
+		}
+	}
+	return
+}
+
+// groupInodesByPartition groups the inodes by the partitions sorted by the
+// start of inode range.
+func groupInodesByPartition(views []*proto.MetaPartitionView, inos []uint64) map[*proto.MetaPartitionView][]uint64 {
+	groups := make(map[*proto.MetaPartitionView][]uint64)
+	for _, ino := range inos {
+		if view := findMetaPartitionView(views, ino); view != nil {
+			groups[view] = append(groups[view], ino)
+		}
+	}
+	return groups
+}
+
+// findMetaPartitionView returns the partition of the inode in the partitions
+// sorted by the start of inode range.
+func findMetaPartitionView(views []*proto.MetaPartitionView, ino uint64) *proto.MetaPartitionView {
+	i := sort.Search(len(views), func(i int) bool { return views[i].Start > ino }) - 1
+	if i < 0 || ino > views[i].End {
+		return nil
+	}
+	return views[i]
+}
+
+// requestMetaPartition sends the request to the leader of the partition, or
+// the members if the leader is unknown, and decodes the response if succeeded.
+func (mp *metaPartition) requestMetaPartition(view *proto.MetaPartitionView, opcode uint8, req, resp interface{}) (status uint8, err error) {
+	var data []byte
+	if data, err = json.Marshal(req); err != nil {
+		return
+	}
+	var (
+		p     = NewPacketToMetaPartition(opcode, view.PartitionID, data)
+		reply *Packet
+	)
+	hosts := append([]string{view.LeaderAddr}, view.Members...)
+	for _, addr := range hosts {
+		if addr == "" {
+			continue
+		}
+		if reply, err = mp.sendPacket(addr, p); err == nil && reply.ResultCode != proto.OpAgain {
+			break
+		}
+	}
+	if err != nil {
+		return
+	}
+	if reply == nil {
+		return proto.OpAgain, errors.NewErrorf("no leader of mp(%v) is available", view.PartitionID)
+	}
+	if reply.ResultCode == proto.OpOk && resp != nil {
+		err = json.Unmarshal(reply.Data[:reply.Size], resp)
+	}
+	return reply.ResultCode, err
+}
+
+func checkMetaPartitionStatus(status uint8, err error) error {
+	if err == nil && status != proto.OpOk {
+		err = errors.NewErrorf("result code(%v)", status)
+	}
+	return err
+}
+
+func (mp *metaPartition) fsmSetDirSummary(items []*DirSummaryItem) {
+	for _, item := range items {
+		treeItem := mp.inodeTree.Get(NewInode(item.Inode, 0))
+		if treeItem == nil || !proto.IsDir(treeItem.(*Inode).Type) {
+			continue
+		}
+		extend := NewExtend(item.Inode)
+		extend.Put([]byte(proto.SummaryXAttrKey), item.Summary.Marshal())
+		_ = mp.fsmSetXAttr(extend)
+		mp.summaryChanges.record(item.Inode)
+	}
+}
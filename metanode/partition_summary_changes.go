@@ -0,0 +1,113 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"container/list"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+// summaryChangesCapacity is the max number of inodes kept by the change log.
+// A reader falling behind the dropped changes gets a reset.
+const summaryChangesCapacity = 1 << 16
+
+type summaryChange struct {
+	ino uint64
+	seq uint64
+}
+
+// summaryChangeLog records the inodes of the partition of which the size or
+// the summary changed, in the order of the last change. The leaders of the
+// partitions of the parent directories pull the changes instead of rescanning
+// the children. The log is kept in memory by every replica, and starts over
+// with a new epoch when the partition is loaded.
+type summaryChangeLog struct {
+	sync.Mutex
+	epoch   uint64
+	seq     uint64
+	trimmed uint64 // the changes up to the sequence are dropped
+	changes *list.List
+	elems   map[uint64]*list.Element
+}
+
+func newSummaryChangeLog() *summaryChangeLog {
+	return &summaryChangeLog{
+		epoch:   uint64(time.Now().UnixNano()),
+		changes: list.New(),
+		elems:   make(map[uint64]*list.Element),
+	}
+}
+
+// record is called by the fsm when the size or the summary of the inode changed.
+func (l *summaryChangeLog) record(ino uint64) {
+	if l == nil {
+		return
+	}
+	l.Lock()
+	defer l.Unlock()
+	l.seq++
+	if e, ok := l.elems[ino]; ok {
+		e.Value.(*summaryChange).seq = l.seq
+		l.changes.MoveToBack(e)
+		return
+	}
+	l.elems[ino] = l.changes.PushBack(&summaryChange{ino: ino, seq: l.seq})
+	if l.changes.Len() > summaryChangesCapacity {
+		oldest := l.changes.Remove(l.changes.Front()).(*summaryChange)
+		delete(l.elems, oldest.ino)
+		l.trimmed = oldest.seq
+	}
+}
+
+func (l *summaryChangeLog) cursor() *summaryCursor {
+	l.Lock()
+	defer l.Unlock()
+	return &summaryCursor{epoch: l.epoch, seq: l.seq}
+}
+
+// changesSince returns the inodes changed after the sequence of the epoch.
+func (l *summaryChangeLog) changesSince(epoch, seq uint64) (resp *proto.GetSummaryChangesResponse) {
+	l.Lock()
+	defer l.Unlock()
+	resp = &proto.GetSummaryChangesResponse{Epoch: l.epoch, Seq: l.seq}
+	if epoch != l.epoch || seq < l.trimmed || seq > l.seq {
+		resp.Reset = true
+		return
+	}
+	for e := l.changes.Back(); e != nil; e = e.Prev() {
+		change := e.Value.(*summaryChange)
+		if change.seq <= seq {
+			break
+		}
+		resp.Inodes = append(resp.Inodes, change.ino)
+	}
+	return
+}
+
+// GetSummaryChanges handles OpMetaGetSummaryChanges.
+func (mp *metaPartition) GetSummaryChanges(req *proto.GetSummaryChangesRequest, p *Packet) (err error) {
+	resp := mp.summaryChanges.changesSince(req.Epoch, req.Seq)
+	var reply []byte
+	if reply, err = json.Marshal(resp); err != nil {
+		p.PacketErrorWithBody(proto.OpErr, []byte(err.Error()))
+		return
+	}
+	p.PacketOkWithBody(reply)
+	return
+}
@@ -0,0 +1,252 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/errors"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+const (
+	// TrashExpireInterval is how often the leader purges the expired files
+	// from the trash of the volume.
+	TrashExpireInterval  = time.Hour
+	trashExpireBatchSize = 1024
+)
+
+func (mp *metaPartition) trashWorker() {
+	t := time.NewTicker(TrashExpireInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-mp.stopC:
+			return
+		case <-t.C:
+			if _, ok := mp.IsLeader(); !ok {
+				continue
+			}
+			days := mp.volTrashDays()
+			if days == 0 {
+				continue
+			}
+			if err := mp.expireTrash(days); err != nil {
+				log.LogWarnf("trashWorker: mp(%v) err(%v)", mp.config.PartitionId, err)
+			}
+		}
+	}
+}
+
+// volTrashDays returns the trash retention days of the volume received from
+// the master, 0 means the trash is disabled.
+func (mp *metaPartition) volTrashDays() uint32 {
+	if mp.manager == nil {
+		return 0
+	}
+	return mp.manager.getVolTrashDays(mp.config.VolName)
+}
+
+// expireTrash purges the files deleted before the retention days. The
+// entries of the trash are placed in the partition of the trash directory, so
+// only the partition holds the trash directory does the job.
+func (mp *metaPartition) expireTrash(days uint32) (err error) {
+	var views []*proto.MetaPartitionView
+	if views, err = masterClient.ClientAPI().GetMetaPartitions(mp.config.VolName); err != nil {
+		return
+	}
+	sort.Slice(views, func(i, j int) bool { return views[i].Start < views[j].Start })
+
+	var trashIno uint64
+	if trashIno, err = mp.lookupTrashDir(views); err != nil || trashIno == 0 {
+		return
+	}
+	if !mp.isLocalInode(trashIno) {
+		return
+	}
+	deadline := time.Now().Add(-time.Duration(days) * 24 * time.Hour).Unix()
+	expired := mp.expiredTrashEntries(trashIno, deadline)
+	var purged, kept int
+	defer func() {
+		if purged > 0 || kept > 0 {
+			log.LogInfof("expireTrash: mp(%v) trash(%v) expired(%v) purged(%v) kept(%v)",
+				mp.config.PartitionId, trashIno, len(expired), purged, kept)
+		}
+	}()
+	// the expired entries are purged in batches until none is left, and the
+	// job stops if the partition is stopped or loses the leadership
+	for start := 0; start < len(expired); start += trashExpireBatchSize {
+		select {
+		case <-mp.stopC:
+			return
+		default:
+		}
+		if _, ok := mp.IsLeader(); !ok {
+			return
+		}
+		end := start + trashExpireBatchSize
+		if end > len(expired) {
+			end = len(expired)
+		}
+		for _, dentry := range expired[start:end] {
+			// the locked files are kept in the trash until they are unlocked
+			var locked bool
+			if locked, err = mp.isRemoteInodeLocked(views, dentry.Inode); err != nil {
+				return
+			}
+			if locked {
+				kept++
+				continue
+			}
+			if err = mp.purgeTrashEntry(views, dentry); err != nil {
+				return
+			}
+			purged++
+		}
+	}
+	return
+}
+
+// lookupTrashDir returns the inode of the trash directory, or 0 if there is
+// no file deleted to the trash yet.
+func (mp *metaPartition) lookupTrashDir(views []*proto.MetaPartitionView) (ino uint64, err error) {
+	if mp.isLocalInode(proto.RootIno) {
+		item := mp.dentryTree.Get(&Dentry{ParentId: proto.RootIno, Name: proto.TrashDirName})
+		if item == nil {
+			return
+		}
+		return item.(*Dentry).Inode, nil
+	}
+	for view := range groupInodesByPartition(views, []uint64{proto.RootIno}) {
+		req := &proto.LookupRequest{VolName: mp.config.VolName, PartitionID: view.PartitionID,
+			ParentID: proto.RootIno, Name: proto.TrashDirName}
+		resp := &proto.LookupResponse{}
+		var status uint8
+		if status, err = mp.requestMetaPartition(view, proto.OpMetaLookup, req, resp); err != nil {
+			return
+		}
+		switch status {
+		case proto.OpOk:
+			ino = resp.Inode
+		case proto.OpNotExistErr:
+		default:
+			err = errors.NewErrorf("lookup trash: result code(%v)", status)
+		}
+	}
+	return
+}
+
+// expiredTrashEntries returns the entries of the trash deleted before the
+// deadline in seconds.
+func (mp *metaPartition) expiredTrashEntries(trashIno uint64, deadline int64) []*Dentry {
+	var expired []*Dentry
+	begin := &Dentry{ParentId: trashIno}
+	end := &Dentry{ParentId: trashIno + 1}
+	mp.dentryTree.AscendRange(begin, end, func(i BtreeItem) bool {
+		d := i.(*Dentry)
+		if _, deleteTime, ok := proto.ParseTrashEntryName(d.Name); ok && deleteTime < deadline {
+			expired = append(expired, d)
+		}
+		return true
+	})
+	return expired
+}
+
+// purgeTrashEntry deletes the entry from the trash, and unlinks and evicts
+// the inode in one meta transaction managed by the partition, so the inode is
+// neither left orphan nor unlinked with the entry kept.
+func (mp *metaPartition) purgeTrashEntry(views []*proto.MetaPartitionView, dentry *Dentry) (err error) {
+	self := findMetaPartitionView(views, dentry.ParentId)
+	view := findMetaPartitionView(views, dentry.Inode)
+	if self == nil || view == nil {
+		return errors.NewErrorf("purge trash entry(%v): partition of inode(%v) not found", dentry.Name, dentry.Inode)
+	}
+	tx := &proto.TxInfo{
+		TxID:    fmt.Sprintf("trash_%v_%v", mp.config.PartitionId, time.Now().UnixNano()),
+		TmID:    mp.config.PartitionId,
+		Members: make(map[uint64]string),
+		Items: []*proto.TxItem{
+			{PartitionID: mp.config.PartitionId, Op: proto.TxOpDeleteDentry, ParentID: dentry.ParentId, Name: dentry.Name, Inode: dentry.Inode},
+			{PartitionID: view.PartitionID, Op: proto.TxOpUnlinkInode, Inode: dentry.Inode},
+		},
+	}
+	for _, v := range []*proto.MetaPartitionView{self, view} {
+		hosts := make([]string, 0, len(v.Members)+1)
+		if v.LeaderAddr != "" {
+			hosts = append(hosts, v.LeaderAddr)
+		}
+		for _, addr := range v.Members {
+			if addr != v.LeaderAddr {
+				hosts = append(hosts, addr)
+			}
+		}
+		tx.Members[v.PartitionID] = strings.Join(hosts, ",")
+	}
+
+	var status uint8
+	if status, err = mp.txPrepare(tx); err != nil || status != proto.OpOk {
+		if err == nil && status == proto.OpNotExistErr && view.PartitionID == mp.config.PartitionId {
+			// the inode has been freed, only the entry is left
+			return mp.deleteTrashEntry(dentry)
+		}
+		return errors.NewErrorf("purge trash entry(%v): prepare result code(%v) err(%v)", dentry.Name, status, err)
+	}
+	if view.PartitionID != mp.config.PartitionId {
+		req := &proto.TxPrepareRequest{VolName: mp.config.VolName, PartitionId: view.PartitionID, Tx: tx}
+		if status, err = mp.requestMetaPartition(view, proto.OpMetaTxPrepare, req, nil); err != nil || status != proto.OpOk {
+			mp.abortTrashTx(tx)
+			if err == nil && status == proto.OpNotExistErr {
+				// the inode has been freed, only the entry is left
+				return mp.deleteTrashEntry(dentry)
+			}
+			return errors.NewErrorf("purge trash entry(%v): prepare mp(%v) result code(%v) err(%v)",
+				dentry.Name, view.PartitionID, status, err)
+		}
+	}
+	if status, err = mp.submitTxCmd(opFSMTxCommit, tx.TxID, tx.TmID); err != nil || status != proto.OpOk {
+		// the prepared transaction is aborted by the recovery after the deadline
+		return errors.NewErrorf("purge trash entry(%v): commit result code(%v) err(%v)", dentry.Name, status, err)
+	}
+	// the decision is retried by the recovery if failed to push
+	mp.finishTx(tx.TxID)
+	return
+}
+
+func (mp *metaPartition) abortTrashTx(tx *proto.TxInfo) {
+	if status, err := mp.submitTxCmd(opFSMTxAbort, tx.TxID, tx.TmID); err != nil || status != proto.OpOk {
+		log.LogWarnf("abortTrashTx: mp(%v) tx(%v) status(%v) err(%v)", mp.config.PartitionId, tx.TxID, status, err)
+		return
+	}
+	mp.finishTx(tx.TxID)
+}
+
+func (mp *metaPartition) deleteTrashEntry(dentry *Dentry) (err error) {
+	var val []byte
+	if val, err = dentry.Marshal(); err != nil {
+		return
+	}
+	var r interface{}
+	if r, err = mp.submit(opFSMDeleteDentry, val); err != nil {
+		return
+	}
+	if status := r.(*DentryResponse).Status; status != proto.OpOk && status != proto.OpNotExistErr {
+		return errors.NewErrorf("delete trash entry(%v): result code(%v)", dentry.Name, status)
+	}
+	return
+}
@@ -0,0 +1,161 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"testing"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+func newTestQuotaPartition() *metaPartition {
+	manager := &metadataManager{}
+	manager.setVolQuotas([]*proto.QuotaHeartBeatInfo{
+		{VolName: "vol", QuotaID: 7, Type: proto.QuotaTypeDir, Target: 1},
+		{VolName: "vol", QuotaID: 8, Type: proto.QuotaTypeUid, Target: 100, InodesExceeded: true},
+		{VolName: "other", QuotaID: 9, Type: proto.QuotaTypeGid, Target: 100, BytesExceeded: true},
+	})
+	return &metaPartition{
+		config:     &MetaPartitionConfig{PartitionId: 1, VolName: "vol"},
+		inodeTree:  NewBtree(),
+		dentryTree: NewBtree(),
+		extendTree: NewBtree(),
+		freeList:   newFreeList(),
+		extDelCh:   make(chan []proto.ExtentKey, 10),
+		manager:    manager,
+	}
+}
+
+func checkQuotaUsage(t *testing.T, mp *metaPartition, quotaID, bytes, inodes uint64) {
+	for _, usage := range mp.GetQuotaUsages() {
+		if usage.QuotaID == quotaID {
+			if usage.UsedBytes != bytes || usage.UsedInodes != inodes {
+				t.Fatalf("quota(%v) usage: expect(%v, %v) actual(%v, %v)",
+					quotaID, bytes, inodes, usage.UsedBytes, usage.UsedInodes)
+			}
+			return
+		}
+	}
+	t.Fatalf("quota(%v) usage not found", quotaID)
+}
+
+func TestQuota_Account(t *testing.T) {
+	mp := newTestQuotaPartition()
+	ino := NewInode(10, proto.Mode(0644))
+	ino.Uid, ino.Gid = 100, 100
+	if status := mp.fsmCreateInode(ino); status != proto.OpOk {
+		t.Fatalf("create inode status: %v", status)
+	}
+	checkQuotaUsage(t, mp, 7, 0, 0)
+	checkQuotaUsage(t, mp, 8, 0, 1)
+
+	// the inode is moved into the directory quota
+	extend := NewExtend(10)
+	extend.Put([]byte(proto.QuotaXAttrKey), []byte(proto.FormatQuotaIds([]uint64{7})))
+	mp.fsmSetXAttr(extend)
+	checkQuotaUsage(t, mp, 7, 0, 1)
+
+	appended := NewInode(10, 0)
+	appended.Extents.Append(proto.ExtentKey{FileOffset: 0, PartitionId: 1, ExtentId: 1025, Size: 4096})
+	if status := mp.fsmAppendExtents(appended); status != proto.OpOk {
+		t.Fatalf("append extents status: %v", status)
+	}
+	<-mp.extDelCh
+	checkQuotaUsage(t, mp, 7, 4096, 1)
+	checkQuotaUsage(t, mp, 8, 4096, 1)
+
+	counters := mp.quotaCounters
+	mp.resetQuotaUsage()
+	for key, counter := range counters {
+		if *mp.quotaCounters[key] != *counter {
+			t.Fatalf("quota key(%v) recounted(%v) incremental(%v)", key, mp.quotaCounters[key], counter)
+		}
+	}
+
+	if !mp.isQuotaExceeded(newInodeQuotaKeys(100, 0, nil), false, true) {
+		t.Fatalf("inode quota of uid 100 should be exceeded")
+	}
+	if mp.isQuotaExceeded(newInodeQuotaKeys(100, 100, []uint64{7}), true, false) {
+		t.Fatalf("bytes quota of other volume should be ignored")
+	}
+
+	mp.fsmUnlinkInode(NewInode(10, 0))
+	mp.fsmEvictInode(NewInode(10, 0))
+	checkQuotaUsage(t, mp, 7, 0, 0)
+	checkQuotaUsage(t, mp, 8, 0, 0)
+}
+
+func TestQuota_CreateInodeInQuotas(t *testing.T) {
+	mp := newTestQuotaPartition()
+	if status := mp.fsmCreateInodeInQuotas(NewInode(10, proto.Mode(0644)), []uint64{7}); status != proto.OpOk {
+		t.Fatalf("create inode status: %v", status)
+	}
+	checkQuotaUsage(t, mp, 7, 0, 1)
+	if ids := mp.getInodeQuotaIds(10); len(ids) != 1 || ids[0] != 7 {
+		t.Fatalf("quota ids of created inode: %v", ids)
+	}
+
+	// the quotas of an existing inode are not changed
+	if status := mp.fsmCreateInodeInQuotas(NewInode(10, proto.Mode(0644)), []uint64{9}); status != proto.OpExistErr {
+		t.Fatalf("create existing inode status: %v", status)
+	}
+	if ids := mp.getInodeQuotaIds(10); len(ids) != 1 || ids[0] != 7 {
+		t.Fatalf("quota ids of existing inode: %v", ids)
+	}
+	checkQuotaUsage(t, mp, 7, 0, 1)
+}
+
+func TestQuota_RenameTx(t *testing.T) {
+	mp := newTestQuotaPartition()
+	mp.txTree = NewBtree()
+	ino := NewInode(10, proto.Mode(0644))
+	ino.Size = 4096
+	mp.fsmCreateInode(ino)
+	extend := NewExtend(10)
+	extend.Put([]byte(proto.QuotaXAttrKey), []byte(proto.FormatQuotaIds([]uint64{7})))
+	mp.fsmSetXAttr(extend)
+	checkQuotaUsage(t, mp, 7, 4096, 1)
+
+	// the file is renamed out of the directory quota
+	rec := &TxRecord{Tx: &proto.TxInfo{
+		TxID:  "tx1",
+		TmID:  2,
+		Items: []*proto.TxItem{{PartitionID: 1, Op: proto.TxOpSetQuotaIds, Inode: 10}},
+	}}
+	if status := mp.fsmTxPrepare(rec); status != proto.OpOk {
+		t.Fatalf("prepare status: %v", status)
+	}
+	checkQuotaUsage(t, mp, 7, 4096, 1)
+	if status := mp.fsmTxCommit(&TxCmd{TxID: "tx1", TmID: 2}); status != proto.OpOk {
+		t.Fatalf("commit status: %v", status)
+	}
+	checkQuotaUsage(t, mp, 7, 0, 0)
+	if ids := mp.getInodeQuotaIds(10); len(ids) != 0 {
+		t.Fatalf("quota ids after rename: %v", ids)
+	}
+
+	// and can not be renamed back once the quota has exceeded the limits
+	mp.manager.setVolQuotas([]*proto.QuotaHeartBeatInfo{
+		{VolName: "vol", QuotaID: 7, Type: proto.QuotaTypeDir, Target: 1, BytesExceeded: true},
+	})
+	rec = &TxRecord{Tx: &proto.TxInfo{
+		TxID:  "tx2",
+		TmID:  2,
+		Items: []*proto.TxItem{{PartitionID: 1, Op: proto.TxOpSetQuotaIds, Inode: 10, QuotaIds: []uint64{7}}},
+	}}
+	if status := mp.fsmTxPrepare(rec); status != proto.OpQuotaExceededErr {
+		t.Fatalf("prepare status of exceeded quota: %v", status)
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"testing"
+)
+
+func TestReadDirPlus(t *testing.T) {
+	mp := &metaPartition{
+		config:     &MetaPartitionConfig{PartitionId: 1, VolName: "vol", Start: 1, End: 100},
+		dentryTree: NewBtree(),
+		inodeTree:  NewBtree(),
+	}
+	const parentIno = 10
+	for i, name := range []string{"a", "b1", "b2", "b3", "c"} {
+		ino := uint64(20 + i)
+		mp.dentryTree.ReplaceOrInsert(&Dentry{ParentId: parentIno, Name: name, Inode: ino, Type: 0644}, true)
+		if name != "c" {
+			// the inode of "c" is stored in the other partition
+			mp.inodeTree.ReplaceOrInsert(NewInode(ino, 0644), true)
+		}
+	}
+	mp.dentryTree.ReplaceOrInsert(&Dentry{ParentId: parentIno + 1, Name: "b4", Inode: 30}, true)
+
+	names := func(resp *ReadDirPlusResp) (names []string) {
+		for _, child := range resp.Children {
+			names = append(names, child.Name)
+		}
+		return
+	}
+
+	resp := mp.readDirPlus(&ReadDirPlusReq{ParentID: parentIno})
+	if len(resp.Children) != 5 || len(resp.Infos) != 4 || resp.More {
+		t.Fatalf("read all: children(%v) infos(%v) more(%v)", names(resp), len(resp.Infos), resp.More)
+	}
+
+	// the marker is exclusive
+	resp = mp.readDirPlus(&ReadDirPlusReq{ParentID: parentIno, Marker: "b1", Limit: 2})
+	if got := names(resp); len(got) != 2 || got[0] != "b2" || got[1] != "b3" || !resp.More {
+		t.Fatalf("read after marker: children(%v) more(%v)", got, resp.More)
+	}
+	if resp.Infos[0].Inode != 22 || resp.Infos[0].Mode != 0644 {
+		t.Fatalf("read after marker: info(%v)", resp.Infos[0])
+	}
+
+	resp = mp.readDirPlus(&ReadDirPlusReq{ParentID: parentIno, Prefix: "b"})
+	if got := names(resp); len(got) != 3 || got[0] != "b1" || resp.More {
+		t.Fatalf("read with prefix: children(%v) more(%v)", got, resp.More)
+	}
+	resp = mp.readDirPlus(&ReadDirPlusReq{ParentID: parentIno, Prefix: "b", Marker: "b2"})
+	if got := names(resp); len(got) != 1 || got[0] != "b3" {
+		t.Fatalf("read with prefix and marker: children(%v)", got)
+	}
+	resp = mp.readDirPlus(&ReadDirPlusReq{ParentID: parentIno, Prefix: "b", Marker: "c"})
+	if len(resp.Children) != 0 {
+		t.Fatalf("read with marker after prefix: children(%v)", names(resp))
+	}
+}
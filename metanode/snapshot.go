@@ -0,0 +1,213 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/storage"
+)
+
+// MetaSnapshot defines a read-only copy of the inodes, dentries and extends
+// of the partition at the time it is created.
+//
+// The extents referenced by a snapshot are kept by the data nodes until the
+// snapshot is deleted, even if they are released by the live tree.
+type MetaSnapshot struct {
+	ID         uint64
+	CreateTime int64
+	inodeTree  *BTree
+	dentryTree *BTree
+	extendTree *BTree
+	counted    bool // the extents are counted in snapExtents, protected by snapMutex
+}
+
+func newEmptyMetaSnapshot(id uint64, createTime int64) *MetaSnapshot {
+	return &MetaSnapshot{
+		ID:         id,
+		CreateTime: createTime,
+		inodeTree:  NewBtree(),
+		dentryTree: NewBtree(),
+		extendTree: NewBtree(),
+	}
+}
+
+func (s *MetaSnapshot) String() string {
+	return fmt.Sprintf("MetaSnapshot{id(%v) ctime(%v) inodes(%v) dentries(%v)}",
+		s.ID, s.CreateTime, s.inodeTree.Len(), s.dentryTree.Len())
+}
+
+// extents returns the extents referenced by the snapshot.
+func (s *MetaSnapshot) extents() map[snapExtentKey]struct{} {
+	keys := make(map[snapExtentKey]struct{})
+	s.inodeTree.Ascend(func(i BtreeItem) bool {
+		// the inodes to be freed are not part of the snapshot
+		if i.(*Inode).ShouldDelete() {
+			return true
+		}
+		i.(*Inode).Extents.Range(func(ek proto.ExtentKey) bool {
+			keys[newSnapExtentKey(&ek)] = struct{}{}
+			return true
+		})
+		return true
+	})
+	return keys
+}
+
+// MetaSnapshotCmd defines the raft command to create or delete a snapshot.
+type MetaSnapshotCmd struct {
+	ID         uint64 `json:"id"`
+	CreateTime int64  `json:"ctime"`
+}
+
+func (c *MetaSnapshotCmd) Marshal() ([]byte, error) {
+	return json.Marshal(c)
+}
+
+func (c *MetaSnapshotCmd) Unmarshal(raw []byte) error {
+	return json.Unmarshal(raw, c)
+}
+
+// metaSnapshotItem is an item of a snapshot produced by the MetaItemIterator.
+type metaSnapshotItem struct {
+	id   uint64
+	item BtreeItem
+}
+
+// marshalMetaSnapshotItem wraps the meta item of a snapshot with the snapshot ID.
+func marshalMetaSnapshotItem(id uint64, item *MetaItem) (key, value []byte, err error) {
+	if value, err = item.MarshalBinary(); err != nil {
+		return
+	}
+	key = make([]byte, 8)
+	binary.BigEndian.PutUint64(key, id)
+	return
+}
+
+func unmarshalMetaSnapshotItem(key, value []byte) (id uint64, item *MetaItem, err error) {
+	if len(key) != 8 {
+		err = fmt.Errorf("invalid snapshot item key length %v", len(key))
+		return
+	}
+	id = binary.BigEndian.Uint64(key)
+	item = NewMetaItem(0, nil, nil)
+	err = item.UnmarshalBinary(value)
+	return
+}
+
+// snapExtentKey identifies an extent on the data nodes. A normal extent is
+// deleted as a whole, while a tiny extent is deleted by range.
+type snapExtentKey struct {
+	PartitionId  uint64
+	ExtentId     uint64
+	ExtentOffset uint64
+}
+
+func newSnapExtentKey(ek *proto.ExtentKey) snapExtentKey {
+	key := snapExtentKey{PartitionId: ek.PartitionId, ExtentId: ek.ExtentId}
+	if storage.IsTinyExtent(ek.ExtentId) {
+		key.ExtentOffset = ek.ExtentOffset
+	}
+	return key
+}
+
+func (mp *metaPartition) getMetaSnapshot(id uint64) *MetaSnapshot {
+	mp.snapMutex.RLock()
+	defer mp.snapMutex.RUnlock()
+	return mp.snapshots[id]
+}
+
+// metaSnapshotList returns the snapshots ordered by ID.
+func (mp *metaPartition) metaSnapshotList() []*MetaSnapshot {
+	mp.snapMutex.RLock()
+	list := make([]*MetaSnapshot, 0, len(mp.snapshots))
+	for _, snap := range mp.snapshots {
+		list = append(list, snap)
+	}
+	mp.snapMutex.RUnlock()
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list
+}
+
+// setMetaSnapshots replaces all of the snapshots and recounts the extents
+// referenced by them.
+func (mp *metaPartition) setMetaSnapshots(snapshots map[uint64]*MetaSnapshot) {
+	refs := make(map[snapExtentKey]int)
+	for _, snap := range snapshots {
+		for key := range snap.extents() {
+			refs[key]++
+		}
+		snap.counted = true
+	}
+	mp.snapMutex.Lock()
+	mp.snapshots = snapshots
+	mp.snapExtents = refs
+	mp.snapMutex.Unlock()
+}
+
+// waitMetaSnapshotExtents waits until the extents of the snapshots being
+// created or deleted are counted, so that isSnapshotExtent is accurate. It
+// returns false if the partition is stopped.
+func (mp *metaPartition) waitMetaSnapshotExtents() bool {
+	for {
+		mp.snapMutex.RLock()
+		pending := mp.snapPending
+		mp.snapMutex.RUnlock()
+		if pending == 0 {
+			return true
+		}
+		select {
+		case <-mp.stopC:
+			return false
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+// isSnapshotExtent returns true if the extent is referenced by any snapshot.
+func (mp *metaPartition) isSnapshotExtent(ek *proto.ExtentKey) bool {
+	mp.snapMutex.RLock()
+	defer mp.snapMutex.RUnlock()
+	if len(mp.snapExtents) == 0 {
+		return false
+	}
+	return mp.snapExtents[newSnapExtentKey(ek)] > 0
+}
+
+// snapshotView returns a read-only partition on the trees of the snapshot,
+// which serves the read operations only.
+func (mp *metaPartition) snapshotView(id uint64) *metaPartition {
+	snap := mp.getMetaSnapshot(id)
+	if snap == nil {
+		return nil
+	}
+	return &metaPartition{
+		config:        mp.config,
+		inodeTree:     snap.inodeTree,
+		dentryTree:    snap.dentryTree,
+		extendTree:    snap.extendTree,
+		multipartTree: NewBtree(),
+		versionTree:   NewBtree(),
+		lockTree:      NewBtree(),
+		txTree:        NewBtree(),
+		vol:           mp.vol,
+		manager:       mp.manager,
+	}
+}
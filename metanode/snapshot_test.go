@@ -0,0 +1,86 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"testing"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+func newTestSnapshotPartition() *metaPartition {
+	mp := &metaPartition{
+		config:     &MetaPartitionConfig{PartitionId: 1},
+		inodeTree:  NewBtree(),
+		dentryTree: NewBtree(),
+		extendTree: NewBtree(),
+		txTree:     NewBtree(),
+		freeList:   newFreeList(),
+		extDelCh:   make(chan []proto.ExtentKey, 10),
+	}
+	ino := NewInode(10, proto.Mode(0644))
+	ino.Extents.Append(proto.ExtentKey{FileOffset: 0, PartitionId: 1, ExtentId: 1025, Size: 4096})
+	ino.Extents.Append(proto.ExtentKey{FileOffset: 4096, PartitionId: 1, ExtentId: 1026, Size: 4096})
+	ino.Size = 8192
+	mp.inodeTree.ReplaceOrInsert(ino, true)
+	mp.dentryTree.ReplaceOrInsert(&Dentry{ParentId: 1, Name: "a", Inode: 10, Type: proto.Mode(0644)}, true)
+	return mp
+}
+
+func TestMetaSnapshot_KeepExtents(t *testing.T) {
+	mp := newTestSnapshotPartition()
+	mp.fsmCreateMetaSnapshot(&MetaSnapshotCmd{ID: 100, CreateTime: 1})
+	mp.fsmCreateMetaSnapshot(&MetaSnapshotCmd{ID: 200, CreateTime: 2})
+	mp.waitMetaSnapshotExtents()
+
+	// the live inode is truncated, while the snapshots still see the old one
+	resp := mp.fsmExtentsTruncate(&Inode{Inode: 10, Size: 4096})
+	if resp.Status != proto.OpOk {
+		t.Fatalf("truncate status: %v", resp.Status)
+	}
+	released := <-mp.extDelCh
+	if len(released) != 1 || !mp.isSnapshotExtent(&released[0]) {
+		t.Fatalf("released extents should be kept by snapshots: %v", released)
+	}
+	view := mp.snapshotView(100)
+	if view == nil {
+		t.Fatalf("snapshot view not found")
+	}
+	if ino := view.getInode(NewInode(10, 0)); ino.Msg.Size != 8192 {
+		t.Fatalf("snapshot inode changed: %v", ino.Msg)
+	}
+	if d, status := view.getDentry(&Dentry{ParentId: 1, Name: "a"}); status != proto.OpOk || d.Inode != 10 {
+		t.Fatalf("snapshot dentry: %v %v", d, status)
+	}
+
+	// the extent is still referenced by the other snapshot
+	mp.fsmDeleteMetaSnapshot(&MetaSnapshotCmd{ID: 100})
+	mp.waitMetaSnapshotExtents()
+	if len(mp.extDelCh) != 0 || !mp.isSnapshotExtent(&released[0]) {
+		t.Fatalf("extent referenced by snapshot 200 is deleted")
+	}
+	mp.fsmDeleteMetaSnapshot(&MetaSnapshotCmd{ID: 200})
+	mp.waitMetaSnapshotExtents()
+	if mp.isSnapshotExtent(&released[0]) {
+		t.Fatalf("extent is still referenced after deleting all snapshots")
+	}
+	deleted := <-mp.extDelCh
+	if len(deleted) != 1 || deleted[0].ExtentId != released[0].ExtentId {
+		t.Fatalf("extents deleted with the last snapshot: %v", deleted)
+	}
+	if mp.snapshotView(200) != nil {
+		t.Fatalf("snapshot view of deleted snapshot exists")
+	}
+}
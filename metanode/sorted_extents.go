@@ -29,14 +29,33 @@ func (se *SortedExtents) String() string {
 	return string(data)
 }
 
+// MarshalBinary marshals the extent keys. The extent keys of an encrypted
+// file are marshaled with the nonces after the header, so the format of the
+// other files is not changed.
 func (se *SortedExtents) MarshalBinary() ([]byte, error) {
 	var data []byte
 
 	se.RLock()
 	defer se.RUnlock()
 
+	withNonce := false
 	for _, ek := range se.eks {
-		ekdata, err := ek.MarshalBinary()
+		if ek.Nonce != 0 {
+			withNonce = true
+			data = append(data, proto.ExtentNonceHeader...)
+			break
+		}
+	}
+	for _, ek := range se.eks {
+		var (
+			ekdata []byte
+			err    error
+		)
+		if withNonce {
+			ekdata, err = ek.MarshalBinaryWithNonce()
+		} else {
+			ekdata, err = ek.MarshalBinary()
+		}
 		if err != nil {
 			return nil, err
 		}
@@ -51,12 +70,22 @@ func (se *SortedExtents) UnmarshalBinary(data []byte) error {
 	se.Lock()
 	defer se.Unlock()
 
+	withNonce := bytes.HasPrefix(data, proto.ExtentNonceHeader)
+	if withNonce {
+		data = data[len(proto.ExtentNonceHeader):]
+	}
 	buf := bytes.NewBuffer(data)
 	for {
 		if buf.Len() == 0 {
 			break
 		}
-		if err := ek.UnmarshalBinary(buf); err != nil {
+		var err error
+		if withNonce {
+			err = ek.UnmarshalBinaryWithNonce(buf)
+		} else {
+			err = ek.UnmarshalBinary(buf)
+		}
+		if err != nil {
 			return err
 		}
 		// Don't use se.Append here, since we need to retain the raw ek order.
@@ -219,6 +248,8 @@ func (se *SortedExtents) Truncate(offset uint64) (deleteExtents []proto.ExtentKe
 		lastKey := &se.eks[numKeys-1]
 		if lastKey.FileOffset+uint64(lastKey.Size) > offset {
 			lastKey.Size = uint32(offset - lastKey.FileOffset)
+			// the CRC does not cover the truncated key any more
+			lastKey.CRC = 0
 		}
 	}
 	return
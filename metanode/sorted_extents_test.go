@@ -82,15 +82,39 @@ func TestAppend04(t *testing.T) {
 
 func TestTruncate01(t *testing.T) {
 	se := NewSortedExtents()
-	delExtents, _ := se.AppendWithCheck(proto.ExtentKey{FileOffset: 0, Size: 1000, ExtentId: 1}, nil)
+	delExtents, _ := se.AppendWithCheck(proto.ExtentKey{FileOffset: 0, Size: 1000, ExtentId: 1, CRC: 1}, nil)
 	t.Logf("\ndel: %v\neks: %v", delExtents, se.eks)
 	delExtents, _ = se.AppendWithCheck(proto.ExtentKey{FileOffset: 2000, Size: 1000, ExtentId: 2}, nil)
 	t.Logf("\ndel: %v\neks: %v", delExtents, se.eks)
 	delExtents = se.Truncate(500)
 	t.Logf("\ndel: %v\neks: %v", delExtents, se.eks)
 	if len(delExtents) != 1 || delExtents[0].ExtentId != 2 ||
-		len(se.eks) != 1 || se.eks[0].ExtentId != 1 ||
+		len(se.eks) != 1 || se.eks[0].ExtentId != 1 || se.eks[0].CRC != 0 ||
 		se.Size() != 500 {
 		t.Fail()
 	}
 }
+
+func TestMarshalNonce01(t *testing.T) {
+	for _, nonce := range []uint64{0, 7} {
+		se := NewSortedExtents()
+		se.AppendWithCheck(proto.ExtentKey{FileOffset: 0, Size: 1000, ExtentId: 1, CRC: 1}, nil)
+		se.AppendWithCheck(proto.ExtentKey{FileOffset: 1000, Size: 1000, ExtentId: 2, Nonce: nonce}, nil)
+		data, err := se.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the extent keys without the nonces are in the format of the earlier versions
+		if nonce == 0 && len(data) != 2*proto.ExtentLength {
+			t.Fatalf("size(%v) of the extent keys without the nonces", len(data))
+		}
+		loaded := NewSortedExtents()
+		if err = loaded.UnmarshalBinary(data); err != nil {
+			t.Fatal(err)
+		}
+		t.Logf("\neks: %v", loaded.eks)
+		if len(loaded.eks) != 2 || loaded.eks[0] != se.eks[0] || loaded.eks[1] != se.eks[1] {
+			t.Fail()
+		}
+	}
+}
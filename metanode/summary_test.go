@@ -0,0 +1,177 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"os"
+	"testing"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+func TestDirSummary_Recount(t *testing.T) {
+	mp := &metaPartition{
+		config:     &MetaPartitionConfig{PartitionId: 1, Start: 1, End: 100},
+		inodeTree:  NewBtree(),
+		dentryTree: NewBtree(),
+		extendTree: NewBtree(),
+	}
+	dirMode := proto.Mode(os.ModeDir | 0755)
+	fileMode := proto.Mode(0644)
+	// 1 -> {2 -> {4, 5 -> {}}, 3, 200 -> remote}
+	for _, ino := range []uint64{1, 2, 5} {
+		mp.inodeTree.ReplaceOrInsert(NewInode(ino, dirMode), true)
+	}
+	for ino, size := range map[uint64]uint64{3: 100, 4: 50} {
+		file := NewInode(ino, fileMode)
+		file.Size = size
+		mp.inodeTree.ReplaceOrInsert(file, true)
+	}
+	mp.dentryTree.ReplaceOrInsert(&Dentry{ParentId: 1, Name: "a", Inode: 2, Type: dirMode}, true)
+	mp.dentryTree.ReplaceOrInsert(&Dentry{ParentId: 1, Name: "b", Inode: 3, Type: fileMode}, true)
+	mp.dentryTree.ReplaceOrInsert(&Dentry{ParentId: 1, Name: "c", Inode: 200, Type: dirMode}, true)
+	mp.dentryTree.ReplaceOrInsert(&Dentry{ParentId: 2, Name: "d", Inode: 4, Type: fileMode}, true)
+	mp.dentryTree.ReplaceOrInsert(&Dentry{ParentId: 2, Name: "e", Inode: 5, Type: dirMode}, true)
+
+	dirs := mp.collectDirChildren()
+	if len(dirs) != 3 || len(dirs[1].files) != 1 || len(dirs[1].subdirs) != 2 {
+		t.Fatalf("collect dir children: %v", dirs)
+	}
+	sizes := map[uint64]uint64{3: 100, 4: 50}
+	summaries := map[uint64]*proto.DirSummary{
+		200: {Files: 2, Bytes: 1000, RFiles: 2, RBytes: 1000},
+	}
+	changed := recountDirSummary(dirs, sizes, summaries)
+	if len(changed) != 3 {
+		t.Fatalf("changed summaries: %v", changed)
+	}
+	expect := proto.DirSummary{Files: 1, Subdirs: 2, Bytes: 100, RFiles: 4, RSubdirs: 3, RBytes: 1150}
+	if *changed[1] != expect {
+		t.Fatalf("summary of root: expect(%+v) actual(%+v)", expect, *changed[1])
+	}
+	if changed = recountDirSummary(dirs, sizes, summaries); len(changed) != 0 {
+		t.Fatalf("summaries should be unchanged: %v", changed)
+	}
+
+	mp.fsmSetDirSummary([]*DirSummaryItem{{Inode: 1, Summary: &expect}, {Inode: 3, Summary: &expect}})
+	if summary := mp.getDirSummary(1); summary == nil || *summary != expect {
+		t.Fatalf("applied summary of root: %v", summary)
+	}
+	if mp.getDirSummary(3) != nil {
+		t.Fatalf("summary should not be set to file")
+	}
+}
+
+func TestDirSummary_Incremental(t *testing.T) {
+	mp := &metaPartition{
+		config:         &MetaPartitionConfig{PartitionId: 1, Start: 1, End: 100},
+		inodeTree:      NewBtree(),
+		dentryTree:     NewBtree(),
+		extendTree:     NewBtree(),
+		txTree:         NewBtree(),
+		extDelCh:       make(chan []proto.ExtentKey, 10),
+		summaryChanges: newSummaryChangeLog(),
+	}
+	dirMode := proto.Mode(os.ModeDir | 0755)
+	fileMode := proto.Mode(0644)
+	// 1 -> {2 -> {3}}
+	for _, ino := range []uint64{1, 2} {
+		mp.inodeTree.ReplaceOrInsert(NewInode(ino, dirMode), true)
+	}
+	file := NewInode(3, fileMode)
+	file.Size = 100
+	mp.inodeTree.ReplaceOrInsert(file, true)
+	mp.dentryTree.ReplaceOrInsert(&Dentry{ParentId: 1, Name: "a", Inode: 2, Type: dirMode}, true)
+	mp.dentryTree.ReplaceOrInsert(&Dentry{ParentId: 2, Name: "b", Inode: 3, Type: fileMode}, true)
+
+	s := newDirSummaryState()
+	s.building = true
+	mp.dirSummary = s
+	s.cursors[1] = mp.summaryChanges.cursor()
+	dirs := mp.collectDirChildren()
+	mp.indexDirSummary(s, dirs)
+	changed := recountDirSummary(dirs, map[uint64]uint64{3: 100}, make(map[uint64]*proto.DirSummary))
+	items := make([]*DirSummaryItem, 0, len(changed))
+	for ino, summary := range changed {
+		items = append(items, &DirSummaryItem{Inode: ino, Summary: summary})
+	}
+	mp.fsmSetDirSummary(items)
+
+	// the summaries applied by the leader do not make the local parents dirty
+	if err := mp.pullDirSummaryChanges(s); err != nil {
+		t.Fatal(err)
+	}
+	if dirty := mp.takeDirtyDirs(s); len(dirty) != 0 {
+		t.Fatalf("dirty dirs after applying summaries: %v", dirty)
+	}
+
+	// a file is created in 1, and 3 grows
+	created := NewInode(4, fileMode)
+	created.Size = 10
+	mp.inodeTree.ReplaceOrInsert(created, true)
+	if status := mp.fsmCreateDentry(&Dentry{ParentId: 1, Name: "c", Inode: 4, Type: fileMode}, false); status != proto.OpOk {
+		t.Fatalf("create dentry: %v", status)
+	}
+	if resp := mp.fsmExtentsTruncate(&Inode{Inode: 3, Size: 300}); resp.Status != proto.OpOk {
+		t.Fatalf("truncate: %v", resp.Status)
+	}
+	if err := mp.pullDirSummaryChanges(s); err != nil {
+		t.Fatal(err)
+	}
+	dirty := mp.takeDirtyDirs(s)
+	if len(dirty) != 2 || !dirty[1] || !dirty[2] {
+		t.Fatalf("dirty dirs: %v", dirty)
+	}
+	changed = mp.recountDirtyDirs(s, dirty)
+	expect := proto.DirSummary{Files: 1, Subdirs: 1, Bytes: 10, RFiles: 2, RSubdirs: 1, RBytes: 310}
+	if len(changed) != 2 || changed[1] == nil || *changed[1] != expect {
+		t.Fatalf("changed summaries: expect root(%+v) actual(%v)", expect, changed)
+	}
+
+	// the deleted file makes its directory dirty, and is not tracked any more
+	if resp := mp.fsmDeleteDentry(&Dentry{ParentId: 2, Name: "b"}, false); resp.Status != proto.OpOk {
+		t.Fatalf("delete dentry: %v", resp.Status)
+	}
+	if dirty = mp.takeDirtyDirs(s); len(dirty) != 1 || !dirty[2] {
+		t.Fatalf("dirty dirs after delete: %v", dirty)
+	}
+	if _, ok := s.parents[3]; ok {
+		t.Fatalf("deleted file is still tracked")
+	}
+}
+
+func TestSummaryChangeLog(t *testing.T) {
+	l := newSummaryChangeLog()
+	cursor := l.cursor()
+	l.record(1)
+	l.record(2)
+	l.record(1)
+	resp := l.changesSince(cursor.epoch, cursor.seq)
+	if resp.Reset || len(resp.Inodes) != 2 || resp.Seq != cursor.seq+3 {
+		t.Fatalf("changes: %+v", resp)
+	}
+	if resp = l.changesSince(resp.Epoch, resp.Seq); resp.Reset || len(resp.Inodes) != 0 {
+		t.Fatalf("no changes: %+v", resp)
+	}
+	if resp = l.changesSince(cursor.epoch+1, cursor.seq); !resp.Reset {
+		t.Fatalf("changes of another epoch: %+v", resp)
+	}
+	for ino := uint64(10); ino < 10+summaryChangesCapacity; ino++ {
+		l.record(ino)
+	}
+	if resp = l.changesSince(cursor.epoch, cursor.seq); !resp.Reset {
+		t.Fatalf("dropped changes should be reset: %+v", len(resp.Inodes))
+	}
+}
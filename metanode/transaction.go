@@ -0,0 +1,145 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/btree"
+)
+
+const (
+	// TxTimeout is how long a prepared transaction may stay undecided.
+	// A transaction still prepared after that is aborted by the recovery.
+	TxTimeout = 10 * time.Second
+	// TxKeepTime is how long the transaction manager keeps a finished
+	// transaction, so that retried requests of it get the same answer.
+	TxKeepTime      = time.Minute
+	TxCheckInterval = 2 * time.Second
+)
+
+// TxRecord defines a meta transaction known by the partition, either as
+// the transaction manager (TM) or as a participant. A participant record
+// only lives while prepared, it is removed once committed or aborted.
+//
+// The dentries changed by a prepared transaction are locked, and other
+// operations on them fail with OpAgain until the transaction is decided.
+type TxRecord struct {
+	Tx       *proto.TxInfo `json:"tx"`
+	State    uint8         `json:"state"`
+	Deadline int64         `json:"deadline"` // unix nano
+	Pushed   bool          `json:"pushed"`   // whether the decision is delivered to all of the participants
+}
+
+func (r *TxRecord) Less(than btree.Item) bool {
+	o, is := than.(*TxRecord)
+	return is && r.Tx.TxID < o.Tx.TxID
+}
+
+func (r *TxRecord) Copy() btree.Item {
+	rec := *r
+	return &rec
+}
+
+func (r *TxRecord) String() string {
+	return fmt.Sprintf("TxRecord{id(%v) tm(%v) state(%v) deadline(%v) pushed(%v) items(%v)}",
+		r.Tx.TxID, r.Tx.TmID, r.State, r.Deadline, r.Pushed, r.Tx.Items)
+}
+
+func newTxRecordKey(txID string) *TxRecord {
+	return &TxRecord{Tx: &proto.TxInfo{TxID: txID}}
+}
+
+// IsTM returns true if the partition is the transaction manager.
+func (r *TxRecord) IsTM(partitionID uint64) bool {
+	return r.Tx.TmID == partitionID
+}
+
+// LocalItems returns the items to be done in the partition.
+func (r *TxRecord) LocalItems(partitionID uint64) []*proto.TxItem {
+	items := make([]*proto.TxItem, 0, len(r.Tx.Items))
+	for _, item := range r.Tx.Items {
+		if item.PartitionID == partitionID {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// Participants returns the partitions involved except the TM.
+func (r *TxRecord) Participants() []uint64 {
+	pids := make([]uint64, 0, len(r.Tx.Items))
+	seen := make(map[uint64]bool)
+	for _, item := range r.Tx.Items {
+		if item.PartitionID == r.Tx.TmID || seen[item.PartitionID] {
+			continue
+		}
+		seen[item.PartitionID] = true
+		pids = append(pids, item.PartitionID)
+	}
+	return pids
+}
+
+// LocksDentry returns true if the prepared transaction changes the dentry.
+func (r *TxRecord) LocksDentry(partitionID, parentID uint64, name string) bool {
+	if r.State != proto.TxStatePrepared {
+		return false
+	}
+	for _, item := range r.Tx.Items {
+		if item.PartitionID != partitionID || item.Op == proto.TxOpUnlinkInode {
+			continue
+		}
+		if item.ParentID == parentID && item.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *TxRecord) Expired(now int64) bool {
+	return r.Deadline <= now
+}
+
+func (r *TxRecord) Bytes() ([]byte, error) {
+	return json.Marshal(r)
+}
+
+func TxRecordFromBytes(raw []byte) (*TxRecord, error) {
+	rec := &TxRecord{}
+	if err := json.Unmarshal(raw, rec); err != nil {
+		return nil, err
+	}
+	if rec.Tx == nil {
+		return nil, fmt.Errorf("transaction info is missing")
+	}
+	return rec, nil
+}
+
+// TxCmd defines the raft command to decide or clean up a transaction.
+type TxCmd struct {
+	TxID string `json:"tx"`
+	TmID uint64 `json:"tm"`
+}
+
+func (c *TxCmd) Marshal() ([]byte, error) {
+	return json.Marshal(c)
+}
+
+func (c *TxCmd) Unmarshal(raw []byte) error {
+	return json.Unmarshal(raw, c)
+}
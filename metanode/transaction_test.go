@@ -0,0 +1,220 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+func newTestTxPartition(pid uint64) *metaPartition {
+	mp := &metaPartition{
+		config:      &MetaPartitionConfig{PartitionId: pid},
+		inodeTree:   NewBtree(),
+		dentryTree:  NewBtree(),
+		txTree:      NewBtree(),
+		versionTree: NewBtree(),
+		freeList:    newFreeList(),
+	}
+	dir := NewInode(1, proto.Mode(os.ModeDir))
+	dir.NLink = 2
+	mp.inodeTree.ReplaceOrInsert(dir, true)
+	return mp
+}
+
+func newTestRenameTx(srcName, dstName string) *proto.TxInfo {
+	return &proto.TxInfo{
+		TxID: "tx1",
+		TmID: 2,
+		Items: []*proto.TxItem{
+			{PartitionID: 1, Op: proto.TxOpDeleteDentry, ParentID: 1, Name: srcName, Inode: 10},
+			{PartitionID: 2, Op: proto.TxOpCreateDentry, ParentID: 1, Name: dstName, Inode: 10, Type: proto.Mode(0644)},
+		},
+	}
+}
+
+func TestTxRecord_Bytes(t *testing.T) {
+	rec := &TxRecord{Tx: newTestRenameTx("a", "b"), State: proto.TxStateCommitted, Deadline: 1000, Pushed: true}
+	raw, err := rec.Bytes()
+	if err != nil {
+		t.Fatalf("encode tx record fail cause: %v", err)
+	}
+	decoded, err := TxRecordFromBytes(raw)
+	if err != nil {
+		t.Fatalf("decode tx record fail cause: %v", err)
+	}
+	if !reflect.DeepEqual(decoded, rec) {
+		t.Fatalf("result mismatch: %v %v", decoded, rec)
+	}
+	if pids := rec.Participants(); !reflect.DeepEqual(pids, []uint64{1}) {
+		t.Fatalf("participants mismatch: %v", pids)
+	}
+}
+
+func TestTx_CommitParticipant(t *testing.T) {
+	mp := newTestTxPartition(1)
+	mp.fsmCreateDentry(&Dentry{ParentId: 1, Name: "a", Inode: 10, Type: proto.Mode(0644)}, false)
+
+	rec := &TxRecord{Tx: newTestRenameTx("a", "b"), State: proto.TxStatePrepared}
+	if status := mp.fsmTxPrepare(rec); status != proto.OpOk {
+		t.Fatalf("prepare status: %v", status)
+	}
+	// the dentry is locked until the transaction is decided
+	if resp := mp.fsmDeleteDentry(&Dentry{ParentId: 1, Name: "a"}, false); resp.Status != proto.OpAgain {
+		t.Fatalf("delete locked dentry status: %v", resp.Status)
+	}
+	// a conflicting transaction fails to prepare
+	other := &TxRecord{Tx: newTestRenameTx("a", "c"), State: proto.TxStatePrepared}
+	other.Tx.TxID = "tx2"
+	if status := mp.fsmTxPrepare(other); status != proto.OpAgain {
+		t.Fatalf("prepare conflicting tx status: %v", status)
+	}
+
+	if status := mp.fsmTxCommit(&TxCmd{TxID: "tx1", TmID: 2}); status != proto.OpOk {
+		t.Fatalf("commit status: %v", status)
+	}
+	if _, status := mp.getDentry(&Dentry{ParentId: 1, Name: "a"}); status != proto.OpNotExistErr {
+		t.Fatalf("dentry still exists after commit")
+	}
+	if mp.txTree.Len() != 0 {
+		t.Fatalf("tx record of participant remains after commit")
+	}
+	// committing again is a no-op
+	if status := mp.fsmTxCommit(&TxCmd{TxID: "tx1", TmID: 2}); status != proto.OpOk {
+		t.Fatalf("commit again status: %v", status)
+	}
+}
+
+func TestTx_AbortManager(t *testing.T) {
+	mp := newTestTxPartition(2)
+	mp.fsmCreateDentry(&Dentry{ParentId: 1, Name: "b", Inode: 20, Type: proto.Mode(0644)}, false)
+
+	tx := newTestRenameTx("a", "b")
+	rec := &TxRecord{Tx: tx, State: proto.TxStatePrepared}
+	if status := mp.fsmTxPrepare(rec); status != proto.OpExistErr {
+		t.Fatalf("prepare to overwrite without old inode status: %v", status)
+	}
+	tx.Items[1].OldInode = 20
+	if status := mp.fsmTxPrepare(rec); status != proto.OpOk {
+		t.Fatalf("prepare status: %v", status)
+	}
+	if status := mp.fsmTxAbort(&TxCmd{TxID: "tx1", TmID: 2}); status != proto.OpOk {
+		t.Fatalf("abort status: %v", status)
+	}
+	if status := mp.fsmTxCommit(&TxCmd{TxID: "tx1", TmID: 2}); status != proto.OpTxAbortedErr {
+		t.Fatalf("commit aborted tx status: %v", status)
+	}
+	if d, _ := mp.getDentry(&Dentry{ParentId: 1, Name: "b"}); d == nil || d.Inode != 20 {
+		t.Fatalf("dentry changed by aborted tx: %v", d)
+	}
+	// the transaction manager keeps the record until it is removed
+	if status := mp.fsmTxRemove(&TxCmd{TxID: "tx1", TmID: 2}); status != proto.OpOk || mp.txTree.Len() != 0 {
+		t.Fatalf("remove status: %v, len %v", status, mp.txTree.Len())
+	}
+	if status := mp.fsmTxCommit(&TxCmd{TxID: "tx1", TmID: 2}); status != proto.OpTxAbortedErr {
+		t.Fatalf("commit unknown tx on manager status: %v", status)
+	}
+}
+
+func TestTx_ReserveParent(t *testing.T) {
+	mp := newTestTxPartition(2)
+	parentLinks := func() uint32 {
+		return mp.inodeTree.Get(NewInode(1, 0)).(*Inode).NLink
+	}
+
+	// the dentry to be created keeps its parent from being removed as empty
+	rec := &TxRecord{Tx: newTestRenameTx("a", "b"), State: proto.TxStatePrepared}
+	if status := mp.fsmTxPrepare(rec); status != proto.OpOk || parentLinks() != 3 {
+		t.Fatalf("prepare status: %v, parent links %v", status, parentLinks())
+	}
+	if status := mp.fsmTxCommit(&TxCmd{TxID: "tx1", TmID: 2}); status != proto.OpOk {
+		t.Fatalf("commit status: %v", status)
+	}
+	if d, _ := mp.getDentry(&Dentry{ParentId: 1, Name: "b"}); d == nil || d.Inode != 10 || parentLinks() != 3 {
+		t.Fatalf("dentry after commit: %v, parent links %v", d, parentLinks())
+	}
+
+	// the link is given back on abort
+	rec = &TxRecord{Tx: newTestRenameTx("a", "c"), State: proto.TxStatePrepared}
+	rec.Tx.TxID = "tx2"
+	if status := mp.fsmTxPrepare(rec); status != proto.OpOk || parentLinks() != 4 {
+		t.Fatalf("prepare status: %v, parent links %v", status, parentLinks())
+	}
+	if status := mp.fsmTxAbort(&TxCmd{TxID: "tx2", TmID: 2}); status != proto.OpOk || parentLinks() != 3 {
+		t.Fatalf("abort status: %v, parent links %v", status, parentLinks())
+	}
+}
+
+func TestTx_ObjectVersion(t *testing.T) {
+	mp := newTestTxPartition(1)
+	mp.fsmCreateDentry(&Dentry{ParentId: 1, Name: "a", Inode: 10, Type: proto.Mode(0644)}, false)
+	old := NewObjectVersion(1, "a", "v1")
+	old.Inode = 10
+	mp.fsmPutObjectVersion(old)
+
+	// the new version and the dentry pointing to it are changed together
+	version := &proto.ObjectVersionInfo{ParentID: 1, Name: "a", VersionID: "v2", Inode: 20}
+	rec := &TxRecord{Tx: &proto.TxInfo{TxID: "tx1", TmID: 1, Items: []*proto.TxItem{
+		{PartitionID: 1, Op: proto.TxOpPutObjectVersion, ParentID: 1, Name: "a", Version: version},
+		{PartitionID: 1, Op: proto.TxOpCreateDentry, ParentID: 1, Name: "a", Inode: 20, Type: proto.Mode(0644), OldInode: 10},
+	}}, State: proto.TxStatePrepared}
+	if status := mp.fsmTxPrepare(rec); status != proto.OpOk {
+		t.Fatalf("prepare status: %v", status)
+	}
+	// the versions of the object are locked until the transaction is decided
+	other := NewObjectVersion(1, "a", "v3")
+	if resp := mp.fsmPutObjectVersion(other); resp.Status != proto.OpAgain {
+		t.Fatalf("put locked version status: %v", resp.Status)
+	}
+	if resp := mp.fsmDeleteObjectVersion(old); resp.Status != proto.OpAgain {
+		t.Fatalf("delete locked version status: %v", resp.Status)
+	}
+	if status := mp.fsmTxCommit(&TxCmd{TxID: "tx1", TmID: 1}); status != proto.OpOk {
+		t.Fatalf("commit status: %v", status)
+	}
+	if d, _ := mp.getDentry(&Dentry{ParentId: 1, Name: "a"}); d == nil || d.Inode != 20 {
+		t.Fatalf("dentry after commit: %v", d)
+	}
+	if mp.versionTree.Get(NewObjectVersion(1, "a", "v2")) == nil {
+		t.Fatalf("version is not put by commit")
+	}
+	// the replaced inode is kept by its version
+	if !mp.versionedInode(1, "a", 10) || mp.versionedInode(1, "a", 30) {
+		t.Fatalf("versioned inode mismatch")
+	}
+
+	// the transaction removing an unknown version fails to prepare
+	rec = &TxRecord{Tx: &proto.TxInfo{TxID: "tx2", TmID: 1, Items: []*proto.TxItem{
+		{PartitionID: 1, Op: proto.TxOpDeleteObjectVersion, ParentID: 1, Name: "a",
+			Version: &proto.ObjectVersionInfo{ParentID: 1, Name: "a", VersionID: "v3"}},
+		{PartitionID: 1, Op: proto.TxOpDeleteDentry, ParentID: 1, Name: "a", Inode: 20},
+	}}, State: proto.TxStatePrepared}
+	if status := mp.fsmTxPrepare(rec); status != proto.OpNotExistErr {
+		t.Fatalf("prepare to delete unknown version status: %v", status)
+	}
+	rec.Tx.Items[0].Version.VersionID = "v2"
+	if status := mp.fsmTxPrepare(rec); status != proto.OpOk {
+		t.Fatalf("prepare status: %v", status)
+	}
+	if status := mp.fsmTxCommit(&TxCmd{TxID: "tx2", TmID: 1}); status != proto.OpOk {
+		t.Fatalf("commit status: %v", status)
+	}
+	if _, status := mp.getDentry(&Dentry{ParentId: 1, Name: "a"}); status != proto.OpNotExistErr || mp.versionTree.Len() != 1 {
+		t.Fatalf("dentry status(%v) versions(%v) after commit", status, mp.versionTree.Len())
+	}
+}
@@ -0,0 +1,52 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"testing"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+func TestTrash_ExpiredEntries(t *testing.T) {
+	manager := &metadataManager{}
+	mp := &metaPartition{
+		config:     &MetaPartitionConfig{PartitionId: 1, VolName: "vol", Start: 1, End: 100},
+		dentryTree: NewBtree(),
+		manager:    manager,
+	}
+	if mp.volTrashDays() != 0 {
+		t.Fatalf("trash should be disabled by default")
+	}
+	manager.setVolTrashDays(map[string]uint32{"vol": 7})
+	if days := mp.volTrashDays(); days != 7 {
+		t.Fatalf("trash days: expect(7) actual(%v)", days)
+	}
+
+	const trashIno = 10
+	mp.dentryTree.ReplaceOrInsert(&Dentry{ParentId: proto.RootIno, Name: proto.TrashDirName, Inode: trashIno}, true)
+	mp.dentryTree.ReplaceOrInsert(&Dentry{ParentId: trashIno, Name: proto.TrashEntryName(20, 100), Inode: 20}, true)
+	mp.dentryTree.ReplaceOrInsert(&Dentry{ParentId: trashIno, Name: proto.TrashEntryName(21, 300), Inode: 21}, true)
+	mp.dentryTree.ReplaceOrInsert(&Dentry{ParentId: trashIno, Name: "invalid", Inode: 22}, true)
+	mp.dentryTree.ReplaceOrInsert(&Dentry{ParentId: trashIno + 1, Name: proto.TrashEntryName(23, 100), Inode: 23}, true)
+
+	if ino, err := mp.lookupTrashDir(nil); err != nil || ino != trashIno {
+		t.Fatalf("lookup trash dir: ino(%v) err(%v)", ino, err)
+	}
+	expired := mp.expiredTrashEntries(trashIno, 200)
+	if len(expired) != 1 || expired[0].Inode != 20 {
+		t.Fatalf("expired entries: %v", expired)
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package metanode
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+func TestObjectVersion_Bytes(t *testing.T) {
+	version := NewObjectVersion(10, "object", "00000001")
+	version.Inode = 20
+	version.ModifyTime = -1
+	version.DeleteMarker = true
+	raw, err := version.Bytes()
+	if err != nil {
+		t.Fatalf("encode object version fail cause: %v", err)
+	}
+	decoded, err := ObjectVersionFromBytes(raw)
+	if err != nil {
+		t.Fatalf("decode object version fail cause: %v", err)
+	}
+	if !reflect.DeepEqual(decoded, version) {
+		t.Fatalf("result mismatch: %v %v", decoded, version)
+	}
+	if _, err = ObjectVersionFromBytes(raw[:len(raw)-3]); err == nil {
+		t.Fatalf("decode truncated object version should fail")
+	}
+}
+
+func TestObjectVersion_List(t *testing.T) {
+	mp := &metaPartition{
+		config:      &MetaPartitionConfig{PartitionId: 1, VolName: "vol"},
+		txTree:      NewBtree(),
+		versionTree: NewBtree(),
+	}
+	var put = func(parent uint64, name, vid string, inode uint64, mtime int64) *ObjectVersionResponse {
+		version := NewObjectVersion(parent, name, vid)
+		version.Inode = inode
+		version.ModifyTime = mtime
+		return mp.fsmPutObjectVersion(version)
+	}
+	put(1, "a", "v1", 10, 100)
+	put(1, "a", "v2", 11, 200)
+	put(1, "b", proto.NullVersionID, 12, 100)
+	put(1, "c", "v1", 13, 100)
+	put(2, "a", "v1", 14, 100)
+	if resp := put(1, "b", proto.NullVersionID, 15, 300); resp.Msg == nil || resp.Msg.Inode != 12 {
+		t.Fatalf("replaced null version: %v", resp.Msg)
+	}
+
+	p := &Packet{}
+	if err := mp.GetObjectVersions(&proto.GetObjectVersionsRequest{ParentID: 1, Name: "a"}, p); err != nil {
+		t.Fatalf("get object versions fail cause: %v", err)
+	}
+	getResp := &proto.GetObjectVersionsResponse{}
+	if err := json.Unmarshal(p.Data, getResp); err != nil {
+		t.Fatalf("unmarshal response fail cause: %v", err)
+	}
+	if len(getResp.Versions) != 2 || getResp.Versions[0].VersionID != "v2" || getResp.Versions[1].VersionID != "v1" {
+		t.Fatalf("versions should be ordered from the newest: %v", getResp.Versions)
+	}
+
+	// versions of one name are never split across pages
+	p = &Packet{}
+	if err := mp.ListObjectVersions(&proto.ListObjectVersionsRequest{ParentID: 1, Max: 2}, p); err != nil {
+		t.Fatalf("list object versions fail cause: %v", err)
+	}
+	listResp := &proto.ListObjectVersionsResponse{}
+	if err := json.Unmarshal(p.Data, listResp); err != nil {
+		t.Fatalf("unmarshal response fail cause: %v", err)
+	}
+	if len(listResp.Versions) != 3 || listResp.Versions[2].Name != "b" || listResp.Versions[2].Inode != 15 {
+		t.Fatalf("list versions: %v", listResp.Versions)
+	}
+	p = &Packet{}
+	if err := mp.ListObjectVersions(&proto.ListObjectVersionsRequest{ParentID: 1, Marker: "c"}, p); err != nil {
+		t.Fatalf("list object versions fail cause: %v", err)
+	}
+	listResp = &proto.ListObjectVersionsResponse{}
+	if err := json.Unmarshal(p.Data, listResp); err != nil {
+		t.Fatalf("unmarshal response fail cause: %v", err)
+	}
+	if len(listResp.Versions) != 1 || listResp.Versions[0].Inode != 13 {
+		t.Fatalf("list versions from marker: %v", listResp.Versions)
+	}
+
+	if resp := mp.fsmDeleteObjectVersion(NewObjectVersion(1, "a", "v2")); resp.Status != proto.OpOk || resp.Msg.Inode != 11 {
+		t.Fatalf("delete version: status(%v) version(%v)", resp.Status, resp.Msg)
+	}
+	if resp := mp.fsmDeleteObjectVersion(NewObjectVersion(1, "a", "v2")); resp.Status != proto.OpNotExistErr {
+		t.Fatalf("delete absent version: status(%v)", resp.Status)
+	}
+}
@@ -74,6 +74,7 @@ var (
 		ReadPermission: {
 			proto.OSSGetObjectAction,
 			proto.OSSGetObjectTorrentAction,
+			proto.OSSSelectObjectContentAction,
 		},
 		WritePermission: {},
 		ReadACPPermission: {
@@ -84,6 +85,7 @@ var (
 		FullControlPermission: {
 			proto.OSSGetObjectAction,
 			proto.OSSGetObjectTorrentAction,
+			proto.OSSSelectObjectContentAction,
 			proto.OSSGetObjectAclAction,
 			proto.OSSPutObjectAclAction,
 		},
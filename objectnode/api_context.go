@@ -26,6 +26,7 @@ import (
 const (
 	ContextKeyRequestID     = "ctx_request_id"
 	ContextKeyRequestAction = "ctx_request_action"
+	ContextKeyAccessKey     = "ctx_access_key"
 	ContextKeyStatusCode    = "status_code"
 	ContextKeyErrorMessage  = "error_message"
 )
@@ -46,6 +47,16 @@ func GetActionFromContext(r *http.Request) (action proto.Action) {
 	return proto.ParseAction(mux.Vars(r)[ContextKeyRequestAction])
 }
 
+// SetRequestAccessKey sets the access key of the request authenticated by the form
+// fields instead of the headers or the queries.
+func SetRequestAccessKey(r *http.Request, accessKey string) {
+	mux.Vars(r)[ContextKeyAccessKey] = accessKey
+}
+
+func GetAccessKeyFromContext(r *http.Request) string {
+	return mux.Vars(r)[ContextKeyAccessKey]
+}
+
 func SetResponseStatusCode(r *http.Request, code ErrorCode) {
 	mux.Vars(r)[ContextKeyStatusCode] = strconv.Itoa(code.StatusCode)
 }
@@ -21,6 +21,7 @@ import (
 	"strconv"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/chubaofs/chubaofs/proto"
 	"github.com/chubaofs/chubaofs/util/log"
@@ -89,6 +90,18 @@ func (o *ObjectNode) createMultipleUploadHandler(w http.ResponseWriter, r *http.
 			return
 		}
 	}
+	// Check server side encryption headers
+	var sse *SSEOption
+	if sse, err = requestSSEOption(r, vol); err != nil {
+		errorCode = sseErrorCode(err)
+		return
+	}
+	// Check object lock headers
+	var objectLock *ObjectLockOption
+	if objectLock, err = parseObjectLockOption(r.Header, time.Now()); err != nil {
+		errorCode = objectLockErrorCode(err)
+		return
+	}
 	var opt = &PutFileOption{
 		MIMEType:     contentType,
 		Disposition:  contentDisposition,
@@ -96,13 +109,17 @@ func (o *ObjectNode) createMultipleUploadHandler(w http.ResponseWriter, r *http.
 		Metadata:     metadata,
 		CacheControl: cacheControl,
 		Expires:      expires,
+		SSE:          sse,
+		ObjectLock:   objectLock,
+
+		ReplicationStatus: requestReplicationStatus(r),
 	}
 
 	var uploadID string
 	if uploadID, err = vol.InitMultipart(param.Object(), opt); err != nil {
 		log.LogErrorf("createMultipleUploadHandler:  init multipart fail, requestID(%v) err(%v)",
 			GetRequestID(r), err)
-		errorCode = InternalErrorCode(err)
+		errorCode = sseErrorCode(err)
 		return
 	}
 
@@ -122,6 +139,11 @@ func (o *ObjectNode) createMultipleUploadHandler(w http.ResponseWriter, r *http.
 	}
 
 	// set response header
+	if sse.Customer() {
+		setSSEResponseHeaders(w, "", sse.CustomerKeyMD5)
+	} else if sse != nil {
+		setSSEResponseHeaders(w, SSEAlgorithmAES256, "")
+	}
 	w.Header()[HeaderNameContentType] = []string{HeaderValueContentTypeXML}
 	w.Header()[HeaderNameContentLength] = []string{strconv.Itoa(len(bytes))}
 	if _, err = w.Write(bytes); err != nil {
@@ -185,13 +207,28 @@ func (o *ObjectNode) uploadPartHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Check server side encryption headers, only the customer key is specified for the parts
+	var sse *SSEOption
+	if sse, err = parseSSEOption(r.Header); err != nil {
+		errorCode = sseErrorCode(err)
+		return
+	}
+	if sse != nil && !sse.Customer() {
+		errorCode = SSENotApplicable
+		return
+	}
+
 	// handle exception
 	var fsFileInfo *FSFileInfo
-	fsFileInfo, err = vol.WritePart(param.Object(), uploadId, uint16(partNumberInt), r.Body)
+	fsFileInfo, err = vol.WritePart(param.Object(), uploadId, uint16(partNumberInt), r.Body, sse)
 	if err == syscall.ENOENT {
 		errorCode = NoSuchUpload
 		return
 	}
+	if isSSEError(err) {
+		errorCode = sseErrorCode(err)
+		return
+	}
 	if err == io.ErrUnexpectedEOF {
 		log.LogWarnf("uploadPartHandler: write part fail cause unexpected EOF: requestID(%v) volume(%v) path(%v) uploadId(%v) part(%v) remote(%v) err(%v)",
 			GetRequestID(r), vol.Name(), param.Object(), uploadId, partNumberInt, getRequestIP(r), err)
@@ -210,6 +247,7 @@ func (o *ObjectNode) uploadPartHandler(w http.ResponseWriter, r *http.Request) {
 		GetRequestID(r), vol.Name(), param.Object(), uploadId, partNumberInt, fsFileInfo)
 
 	// write header to response
+	setSSEResponseHeaders(w, fsFileInfo.SSE, fsFileInfo.SSECustomerKeyMD5)
 	w.Header()[HeaderNameContentLength] = []string{"0"}
 	w.Header()[HeaderNameETag] = []string{fsFileInfo.ETag}
 	return
@@ -466,6 +504,10 @@ func (o *ObjectNode) completeMultipartUploadHandler(w http.ResponseWriter, r *ht
 		errorCode = ObjectModeConflict
 		return
 	}
+	if err == errObjectLocked {
+		errorCode = ObjectLocked
+		return
+	}
 	if err != nil {
 		log.LogErrorf("completeMultipartUploadHandler: complete multipart fail, requestID(%v) uploadID(%v) err(%v)",
 			GetRequestID(r), uploadId, err)
@@ -474,6 +516,11 @@ func (o *ObjectNode) completeMultipartUploadHandler(w http.ResponseWriter, r *ht
 	}
 	log.LogDebugf("completeMultipartUploadHandler: complete multipart, requestID(%v) uploadID(%v) path(%v)",
 		GetRequestID(r), uploadId, param.Object())
+	if !isReplicaRequest(r) {
+		o.replicator.SubmitPut(vol, fsFileInfo)
+	}
+	o.notifyObjectEvent(r, vol, EventObjectCreatedCompleteMultipartUpload, param.Object(), fsFileInfo.Size,
+		fsFileInfo.ETag, fsFileInfo.VersionID)
 
 	// write response
 	completeResult := CompleteMultipartResult{
@@ -493,6 +540,11 @@ func (o *ObjectNode) completeMultipartUploadHandler(w http.ResponseWriter, r *ht
 	// set response header
 	w.Header()[HeaderNameContentType] = []string{HeaderValueContentTypeXML}
 	w.Header()[HeaderNameContentLength] = []string{strconv.Itoa(len(bytes))}
+	if len(fsFileInfo.VersionID) > 0 {
+		w.Header()[HeaderNameXAmzVersionId] = []string{fsFileInfo.VersionID}
+	}
+	setSSEResponseHeaders(w, fsFileInfo.SSE, fsFileInfo.SSECustomerKeyMD5)
+	setObjectLockResponseHeaders(w, fsFileInfo)
 	if _, err = w.Write(bytes); err != nil {
 		log.LogErrorf("completeMultipartUploadHandler: write response body fail, requestID(%v) err(%v)", GetRequestID(r), err)
 		return
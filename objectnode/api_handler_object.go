@@ -15,10 +15,12 @@
 package objectnode
 
 import (
+	"crypto/md5"
 	"encoding/base64"
 	"encoding/hex"
 	"encoding/xml"
 	"fmt"
+	"hash"
 	"io"
 	"io/ioutil"
 	"net/http"
@@ -27,6 +29,7 @@ import (
 	"strconv"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/chubaofs/chubaofs/proto"
 	"github.com/chubaofs/chubaofs/util/log"
@@ -133,10 +136,19 @@ func (o *ObjectNode) getObjectHandler(w http.ResponseWriter, r *http.Request) {
 	responseContentDisposition := r.URL.Query().Get(ParamResponseContentDisposition)
 
 	// get object meta
+	var versionID = r.URL.Query().Get(ParamVersionId)
 	var fileInfo *FSFileInfo
-	fileInfo, err = vol.ObjectMeta(param.Object())
+	fileInfo, err = vol.ObjectVersionMeta(param.Object(), versionID)
 	if err == syscall.ENOENT {
 		errorCode = NoSuchKey
+		if versionID != "" {
+			errorCode = NoSuchVersion
+		}
+		return
+	}
+	if err == errDeleteMarker {
+		w.Header()[HeaderNameXAmzDeleteMarker] = []string{"true"}
+		errorCode = MethodNotAllowed
 		return
 	}
 	if err != nil {
@@ -146,6 +158,16 @@ func (o *ObjectNode) getObjectHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Checking the customer key of the server side encryption
+	var sse *SSEOption
+	if sse, err = parseSSEOption(r.Header); err == nil {
+		err = checkObjectSSE(fileInfo, sse)
+	}
+	if err != nil {
+		errorCode = sseErrorCode(err)
+		return
+	}
+
 	// parse request header
 	match := r.Header.Get(HeaderNameIfMatch)
 	noneMatch := r.Header.Get(HeaderNameIfNoneMatch)
@@ -233,6 +255,12 @@ func (o *ObjectNode) getObjectHandler(w http.ResponseWriter, r *http.Request) {
 
 	// set response header for GetObject
 	w.Header()[HeaderNameAcceptRange] = []string{HeaderValueAcceptRange}
+	if len(fileInfo.VersionID) > 0 {
+		w.Header()[HeaderNameXAmzVersionId] = []string{fileInfo.VersionID}
+	}
+	setSSEResponseHeaders(w, fileInfo.SSE, fileInfo.SSECustomerKeyMD5)
+	setObjectLockResponseHeaders(w, fileInfo)
+	setReplicationResponseHeaders(w, fileInfo)
 	w.Header()[HeaderNameLastModified] = []string{formatTimeRFC1123(fileInfo.ModifyTime)}
 	if len(responseContentType) > 0 {
 		w.Header()[HeaderNameContentType] = []string{responseContentType}
@@ -314,11 +342,15 @@ func (o *ObjectNode) getObjectHandler(w http.ResponseWriter, r *http.Request) {
 			size = rangeUpper - rangeLower + 1
 		}
 	}
-	err = vol.ReadFile(param.Object(), w, offset, size)
+	err = vol.ReadFileVersion(param.Object(), versionID, w, offset, size, sse)
 	if err == syscall.ENOENT {
 		errorCode = NoSuchKey
 		return
 	}
+	if isSSEError(err) {
+		errorCode = sseErrorCode(err)
+		return
+	}
 	if err != nil {
 		log.LogErrorf("getObjectHandler: read from Volume fail: requestId(%v) volume(%v) path(%v) offset(%v) size(%v) err(%v)",
 			GetRequestID(r), param.Bucket(), param.Object(), offset, size, err)
@@ -363,10 +395,19 @@ func (o *ObjectNode) headObjectHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// get object meta
+	var versionID = r.URL.Query().Get(ParamVersionId)
 	var fileInfo *FSFileInfo
-	fileInfo, err = vol.ObjectMeta(param.Object())
+	fileInfo, err = vol.ObjectVersionMeta(param.Object(), versionID)
 	if err == syscall.ENOENT {
 		errorCode = NoSuchKey
+		if versionID != "" {
+			errorCode = NoSuchVersion
+		}
+		return
+	}
+	if err == errDeleteMarker {
+		w.Header()[HeaderNameXAmzDeleteMarker] = []string{"true"}
+		errorCode = MethodNotAllowed
 		return
 	}
 	if err != nil {
@@ -376,6 +417,16 @@ func (o *ObjectNode) headObjectHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Checking the customer key of the server side encryption
+	var sse *SSEOption
+	if sse, err = parseSSEOption(r.Header); err == nil {
+		err = checkObjectSSE(fileInfo, sse)
+	}
+	if err != nil {
+		errorCode = sseErrorCode(err)
+		return
+	}
+
 	// parse request header
 	match := r.Header.Get(HeaderNameIfMatch)
 	noneMatch := r.Header.Get(HeaderNameIfNoneMatch)
@@ -439,6 +490,12 @@ func (o *ObjectNode) headObjectHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header()[HeaderNameAcceptRange] = []string{HeaderValueAcceptRange}
 	w.Header()[HeaderNameLastModified] = []string{formatTimeRFC1123(fileInfo.ModifyTime)}
 	w.Header()[HeaderNameContentMD5] = []string{EmptyContentMD5String}
+	if len(fileInfo.VersionID) > 0 {
+		w.Header()[HeaderNameXAmzVersionId] = []string{fileInfo.VersionID}
+	}
+	setSSEResponseHeaders(w, fileInfo.SSE, fileInfo.SSECustomerKeyMD5)
+	setObjectLockResponseHeaders(w, fileInfo)
+	setReplicationResponseHeaders(w, fileInfo)
 	if len(fileInfo.MIMEType) > 0 {
 		w.Header()[HeaderNameContentType] = []string{fileInfo.MIMEType}
 	} else {
@@ -565,18 +622,35 @@ func (o *ObjectNode) deleteObjectsHandler(w http.ResponseWriter, r *http.Request
 		return deleteReq.Objects[i].Key > deleteReq.Objects[j].Key
 	})
 
+	var bypass = bypassGovernanceRetention(r) && o.canBypassGovernance(param, vol)
 	var objectKeys = make([]string, 0, len(deleteReq.Objects))
 	for _, object := range deleteReq.Objects {
 		objectKeys = append(objectKeys, object.Key)
-		err = vol.DeletePath(object.Key)
-		log.LogWarnf("deleteObjectsHandler: delete: requestID(%v) volume(%v) path(%v)",
-			GetRequestID(r), vol.Name(), object.Key)
-		if err != nil {
-			deletedErrors = append(deletedErrors, Error{Key: object.Key, Message: err.Error()})
+		var deletedVersionID string
+		var deleteMarker bool
+		deletedVersionID, deleteMarker, err = vol.DeleteObject(object.Key, object.VersionId, bypass)
+		log.LogWarnf("deleteObjectsHandler: delete: requestID(%v) volume(%v) path(%v) versionID(%v)",
+			GetRequestID(r), vol.Name(), object.Key, object.VersionId)
+		if err == errObjectLocked {
+			deletedErrors = append(deletedErrors, Error{Key: object.Key, VersionId: object.VersionId,
+				Code: ObjectLocked.ErrorCode, Message: ObjectLocked.ErrorMessage})
+		} else if err != nil {
+			deletedErrors = append(deletedErrors, Error{Key: object.Key, VersionId: object.VersionId, Message: err.Error()})
 			log.LogErrorf("deleteObjectsHandler: delete object failed: requestID(%v) volume(%v) path(%v) err(%v)",
 				GetRequestID(r), vol.Name(), object.Key, err)
 		} else {
-			deletedObjects = append(deletedObjects, Deleted{Key: object.Key})
+			if object.VersionId == "" && !isReplicaRequest(r) {
+				o.replicator.SubmitDelete(vol, object.Key)
+			}
+			var deleted = Deleted{Key: object.Key, VersionId: object.VersionId}
+			if deleteMarker {
+				deleted.DeleteMarker = "true"
+				deleted.DeleteMarkerVersionId = deletedVersionID
+				o.notifyObjectEvent(r, vol, EventObjectRemovedDeleteMarkerCreated, object.Key, 0, "", deletedVersionID)
+			} else {
+				o.notifyObjectEvent(r, vol, EventObjectRemovedDelete, object.Key, 0, "", object.VersionId)
+			}
+			deletedObjects = append(deletedObjects, deleted)
 			log.LogDebugf("deleteObjectsHandler: delete object success: requestID(%v) volume(%v) path(%v)", GetRequestID(r),
 				vol.Name(), object.Key)
 		}
@@ -611,11 +685,16 @@ func (o *ObjectNode) deleteObjectsHandler(w http.ResponseWriter, r *http.Request
 	return
 }
 
-func parseCopySourceInfo(r *http.Request) (sourceBucket, sourceObject string) {
+func parseCopySourceInfo(r *http.Request) (sourceBucket, sourceObject, sourceVersionID string) {
 	var copySource = r.Header.Get(HeaderNameXAmzCopySource)
 	if strings.HasPrefix(copySource, "/") {
 		copySource = copySource[1:]
 	}
+	// The version of the source object is specified by the versionId query.
+	if position := strings.LastIndex(copySource, "?"+ParamVersionId+"="); position >= 0 {
+		sourceVersionID = copySource[position+len(ParamVersionId)+2:]
+		copySource = copySource[:position]
+	}
 	position := strings.Index(copySource, "/")
 	var bucket, object string
 	if position >= 0 {
@@ -682,15 +761,33 @@ func (o *ObjectNode) copyObjectHandler(w http.ResponseWriter, r *http.Request) {
 	if len(metadataDirective) == 0 {
 		metadataDirective = MetadataDirectiveCopy
 	}
+	// Check server side encryption headers of the target and the source object
+	var sse, sourceSSE *SSEOption
+	if sse, err = requestSSEOption(r, vol); err != nil {
+		errorCode = sseErrorCode(err)
+		return
+	}
+	if sourceSSE, err = parseCopySourceSSEOption(r.Header); err != nil {
+		errorCode = sseErrorCode(err)
+		return
+	}
+	// Check object lock headers of the target object
+	var objectLock *ObjectLockOption
+	if objectLock, err = parseObjectLockOption(r.Header, time.Now()); err != nil {
+		errorCode = objectLockErrorCode(err)
+		return
+	}
 	var opt = &PutFileOption{
 		MIMEType:     contentType,
 		Disposition:  contentDisposition,
 		Metadata:     metadata,
 		CacheControl: cacheControl,
 		Expires:      expires,
+		SSE:          sse,
+		ObjectLock:   objectLock,
 	}
 
-	sourceBucket, sourceObject := parseCopySourceInfo(r)
+	sourceBucket, sourceObject, sourceVersionID := parseCopySourceInfo(r)
 
 	// check permission, must have read permission to source bucket
 	var userInfo *proto.UserInfo
@@ -708,18 +805,38 @@ func (o *ObjectNode) copyObjectHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// open source object stream
+	var sourceVol *Volume
+	if sourceVol, err = o.getVol(sourceBucket); err != nil {
+		log.LogErrorf("copyObjectHandler: load source volume fail: vol(%v) requestID(%v) err(%v)",
+			sourceBucket, getRequestIP(r), err)
+		errorCode = NoSuchBucket
+		return
+	}
+
 	// get object meta
 	var fileInfo *FSFileInfo
-	fileInfo, err = vol.ObjectMeta(sourceObject)
+	fileInfo, err = sourceVol.ObjectVersionMeta(sourceObject, sourceVersionID)
 	if err != nil {
 		if err == syscall.ENOENT {
 			errorCode = NoSuchKey
+			if sourceVersionID != "" {
+				errorCode = NoSuchVersion
+			}
+			return
+		}
+		if err == errDeleteMarker {
+			errorCode = InvalidArgument
 			return
 		}
 		log.LogErrorf("copyObjectHandler: volume get file info fail: requestID(%v) err(%v)", GetRequestID(r), err)
 		errorCode = InternalErrorCode(err)
 		return
 	}
+	if err = checkObjectSSE(fileInfo, sourceSSE); err != nil {
+		errorCode = sseErrorCode(err)
+		return
+	}
 
 	// get header
 	copyMatch := r.Header.Get(HeaderNameXAmzCopyMatch)
@@ -767,16 +884,15 @@ func (o *ObjectNode) copyObjectHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// open source object stream
-	var sourceVol *Volume
-	if sourceVol, err = o.getVol(sourceBucket); err != nil {
-		log.LogErrorf("copyObjectHandler: load source volume fail: vol(%v) requestID(%v) err(%v)",
-			sourceBucket, getRequestIP(r), err)
-		errorCode = NoSuchBucket
+	fsFileInfo, err := vol.CopyFile(sourceVol, sourceObject, sourceVersionID, sourceSSE, param.Object(), metadataDirective, opt)
+	if isSSEError(err) {
+		errorCode = sseErrorCode(err)
+		return
+	}
+	if err == errObjectLocked {
+		errorCode = ObjectLocked
 		return
 	}
-
-	fsFileInfo, err := vol.CopyFile(sourceVol, sourceObject, param.Object(), metadataDirective, opt)
 	if err != nil && err != syscall.EINVAL && err != syscall.EFBIG {
 		log.LogErrorf("copyObjectHandler: Volume copy file fail: requestID(%v) Volume(%v) source(%v) target(%v) err(%v)",
 			GetRequestID(r), param.Bucket(), sourceObject, param.Object(), err)
@@ -795,6 +911,8 @@ func (o *ObjectNode) copyObjectHandler(w http.ResponseWriter, r *http.Request) {
 		errorCode = CopySourceSizeTooLarge
 		return
 	}
+	o.replicator.SubmitPut(vol, fsFileInfo)
+	o.notifyObjectEvent(r, vol, EventObjectCreatedCopy, param.Object(), fsFileInfo.Size, fsFileInfo.ETag, fsFileInfo.VersionID)
 
 	copyResult := CopyResult{
 		ETag:         fsFileInfo.ETag,
@@ -811,6 +929,14 @@ func (o *ObjectNode) copyObjectHandler(w http.ResponseWriter, r *http.Request) {
 	// set response header
 	w.Header()[HeaderNameContentType] = []string{HeaderValueContentTypeXML}
 	w.Header()[HeaderNameContentLength] = []string{strconv.Itoa(len(bytes))}
+	if len(fileInfo.VersionID) > 0 {
+		w.Header()[HeaderNameXAmzCopySourceVersionId] = []string{fileInfo.VersionID}
+	}
+	if len(fsFileInfo.VersionID) > 0 {
+		w.Header()[HeaderNameXAmzVersionId] = []string{fsFileInfo.VersionID}
+	}
+	setSSEResponseHeaders(w, fsFileInfo.SSE, fsFileInfo.SSECustomerKeyMD5)
+	setObjectLockResponseHeaders(w, fsFileInfo)
 	_, _ = w.Write(bytes)
 	return
 }
@@ -1165,6 +1291,27 @@ func (o *ObjectNode) putObjectHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Check server side encryption headers
+	var sse *SSEOption
+	if sse, err = requestSSEOption(r, vol); err != nil {
+		errorCode = sseErrorCode(err)
+		return
+	}
+	// Check object lock headers
+	var objectLock *ObjectLockOption
+	if objectLock, err = parseObjectLockOption(r.Header, time.Now()); err != nil {
+		errorCode = objectLockErrorCode(err)
+		return
+	}
+	// The ETag of an object encrypted with a customer key is not the MD5 of the data,
+	// so the MD5 is computed while reading the request body.
+	var reader io.Reader = r.Body
+	var contentHash hash.Hash
+	if checkMD5 && sse.Customer() {
+		contentHash = md5.New()
+		reader = io.TeeReader(r.Body, contentHash)
+	}
+
 	// Audit file write
 	log.LogInfof("Audit: put object: requestID(%v) remote(%v) volume(%v) path(%v) type(%v)",
 		GetRequestID(r), getRequestIP(r), vol.Name(), param.Object(), contentType)
@@ -1177,12 +1324,24 @@ func (o *ObjectNode) putObjectHandler(w http.ResponseWriter, r *http.Request) {
 		Metadata:     metadata,
 		CacheControl: cacheControl,
 		Expires:      expires,
+		SSE:          sse,
+		ObjectLock:   objectLock,
+
+		ReplicationStatus: requestReplicationStatus(r),
 	}
-	fsFileInfo, err = vol.PutObject(param.Object(), r.Body, opt)
+	fsFileInfo, err = vol.PutObject(param.Object(), reader, opt)
 	if err == syscall.EINVAL {
 		errorCode = ObjectModeConflict
 		return
 	}
+	if err == errObjectLocked {
+		errorCode = ObjectLocked
+		return
+	}
+	if isSSEError(err) {
+		errorCode = sseErrorCode(err)
+		return
+	}
 	if err == io.ErrUnexpectedEOF {
 		log.LogWarnf("putObjectHandler: put object fail cause unexpected EOF: requestID(%v) volume(%v) path(%v) remote(%v) err(%v)",
 			GetRequestID(r), vol.Name(), param.Object(), getRequestIP(r), err)
@@ -1210,16 +1369,29 @@ func (o *ObjectNode) putObjectHandler(w http.ResponseWriter, r *http.Request) {
 		requestMD5 = hex.EncodeToString(decoded)
 	}
 	// check content MD5
-	if checkMD5 && requestMD5 != fsFileInfo.ETag {
+	var serverMD5 = fsFileInfo.ETag
+	if contentHash != nil {
+		serverMD5 = hex.EncodeToString(contentHash.Sum(nil))
+	}
+	if checkMD5 && requestMD5 != serverMD5 {
 		log.LogErrorf("putObjectHandler: MD5 validate fail: requestID(%v) requestMD5(%v) serverMD5(%v)",
-			r.URL.EscapedPath(), requestMD5, fsFileInfo.ETag)
+			r.URL.EscapedPath(), requestMD5, serverMD5)
 		errorCode = BadDigest
 		return
 	}
+	if !isReplicaRequest(r) {
+		o.replicator.SubmitPut(vol, fsFileInfo)
+	}
+	o.notifyObjectEvent(r, vol, EventObjectCreatedPut, param.Object(), fsFileInfo.Size, fsFileInfo.ETag, fsFileInfo.VersionID)
 
 	// set response header
 	w.Header()[HeaderNameETag] = []string{wrapUnescapedQuot(fsFileInfo.ETag)}
 	w.Header()[HeaderNameContentLength] = []string{"0"}
+	if len(fsFileInfo.VersionID) > 0 {
+		w.Header()[HeaderNameXAmzVersionId] = []string{fsFileInfo.VersionID}
+	}
+	setSSEResponseHeaders(w, fsFileInfo.SSE, fsFileInfo.SSECustomerKeyMD5)
+	setObjectLockResponseHeaders(w, fsFileInfo)
 	return
 }
 
@@ -1258,17 +1430,40 @@ func (o *ObjectNode) deleteObjectHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	var versionID = r.URL.Query().Get(ParamVersionId)
+
 	// Audit deletion
-	log.LogInfof("Audit: delete object: requestID(%v) remote(%v) volume(%v) path(%v)",
-		GetRequestID(r), getRequestIP(r), vol.Name(), param.Object())
+	log.LogInfof("Audit: delete object: requestID(%v) remote(%v) volume(%v) path(%v) versionID(%v)",
+		GetRequestID(r), getRequestIP(r), vol.Name(), param.Object(), versionID)
 
-	err = vol.DeletePath(param.Object())
+	var deletedVersionID string
+	var deleteMarker bool
+	var bypass = bypassGovernanceRetention(r) && o.canBypassGovernance(param, vol)
+	deletedVersionID, deleteMarker, err = vol.DeleteObject(param.Object(), versionID, bypass)
+	if err == errObjectLocked {
+		errorCode = ObjectLocked
+		return
+	}
 	if err != nil {
 		log.LogErrorf("deleteObjectHandler: Volume delete file fail: "+
 			"requestID(%v) volume(%v) path(%v) err(%v)", GetRequestID(r), vol.Name(), param.Object(), err)
 		errorCode = InternalErrorCode(err)
 		return
 	}
+	if versionID == "" && !isReplicaRequest(r) {
+		o.replicator.SubmitDelete(vol, param.Object())
+	}
+	if deleteMarker {
+		o.notifyObjectEvent(r, vol, EventObjectRemovedDeleteMarkerCreated, param.Object(), 0, "", deletedVersionID)
+	} else {
+		o.notifyObjectEvent(r, vol, EventObjectRemovedDelete, param.Object(), 0, "", versionID)
+	}
+	if len(deletedVersionID) > 0 {
+		w.Header()[HeaderNameXAmzVersionId] = []string{deletedVersionID}
+	}
+	if deleteMarker {
+		w.Header()[HeaderNameXAmzDeleteMarker] = []string{"true"}
+	}
 
 	w.WriteHeader(http.StatusNoContent)
 	return
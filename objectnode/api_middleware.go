@@ -151,6 +151,12 @@ func (o *ObjectNode) authMiddleware(next http.Handler) http.Handler {
 				next.ServeHTTP(w, r)
 				return
 			}
+			// The POST object requests are authenticated by the handler with the signatures
+			// of the policies in the form fields.
+			if currentAction == proto.OSSPostObjectAction {
+				next.ServeHTTP(w, r)
+				return
+			}
 
 			var (
 				pass bool
@@ -199,6 +205,11 @@ func (o *ObjectNode) policyCheckMiddleware(next http.Handler) http.Handler {
 				next.ServeHTTP(w, r)
 				return
 			}
+			// The POST object requests are checked by the handler once they are authenticated.
+			if action == proto.OSSPostObjectAction {
+				next.ServeHTTP(w, r)
+				return
+			}
 			wrappedNext := o.policyCheck(next.ServeHTTP)
 			wrappedNext.ServeHTTP(w, r)
 			return
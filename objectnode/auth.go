@@ -25,6 +25,7 @@ const (
 	SignatrueV4          = "signature_v4"
 	PresignedV2          = "presigned_v2"
 	PresignedV4          = "presigned_v4"
+	PostPolicyAuth       = "post_policy"
 )
 
 type RequestAuthInfo struct {
@@ -58,6 +59,9 @@ func parseRequestAuthInfo(r *http.Request) *RequestAuthInfo {
 		if ai != nil {
 			auth.accessKey = ai.Credential.AccessKey
 		}
+	} else if accessKey := GetAccessKeyFromContext(r); accessKey != "" {
+		auth.authType = PostPolicyAuth
+		auth.accessKey = accessKey
 	}
 
 	return auth
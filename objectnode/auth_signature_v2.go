@@ -70,6 +70,7 @@ var SignatureV2WhiteQueries = map[string]struct{}{
 	"versionId":                    struct{}{},
 	"versioning":                   struct{}{},
 	"versions":                     struct{}{},
+	"website":                      struct{}{},
 }
 
 //
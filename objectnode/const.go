@@ -37,6 +37,7 @@ const (
 	HeaderNameRange              = "Range"
 	HeaderNameExpect             = "Expect"
 	HeaderNameXForwardedExpect   = "X-Forwarded-Expect"
+	HeaderNameXForwardedProto    = "X-Forwarded-Proto"
 	HeaderNameLocation           = "Location"
 	HeaderNameCacheControl       = "Cache-Control"
 	HeaderNameExpires            = "Expires"
@@ -66,6 +67,24 @@ const (
 	HeaderNameXAmzMetadataDirective   = "x-amz-metadata-directive"
 	HeaderNameXAmzBucketRegion        = "x-amz-bucket-region"
 	HeaderNameXAmzTaggingCount        = "x-amz-tagging-count"
+	HeaderNameXAmzVersionId           = "x-amz-version-id"
+	HeaderNameXAmzDeleteMarker        = "x-amz-delete-marker"
+	HeaderNameXAmzCopySourceVersionId = "x-amz-copy-source-version-id"
+
+	HeaderNameXAmzServerSideEncryption           = "x-amz-server-side-encryption"
+	HeaderNameXAmzSSECustomerAlgorithm           = "x-amz-server-side-encryption-customer-algorithm"
+	HeaderNameXAmzSSECustomerKey                 = "x-amz-server-side-encryption-customer-key"
+	HeaderNameXAmzSSECustomerKeyMD5              = "x-amz-server-side-encryption-customer-key-MD5"
+	HeaderNameXAmzCopySourceSSECustomerAlgorithm = "x-amz-copy-source-server-side-encryption-customer-algorithm"
+	HeaderNameXAmzCopySourceSSECustomerKey       = "x-amz-copy-source-server-side-encryption-customer-key"
+	HeaderNameXAmzCopySourceSSECustomerKeyMD5    = "x-amz-copy-source-server-side-encryption-customer-key-MD5"
+
+	HeaderNameXAmzObjectLockMode            = "x-amz-object-lock-mode"
+	HeaderNameXAmzObjectLockRetainUntilDate = "x-amz-object-lock-retain-until-date"
+	HeaderNameXAmzObjectLockLegalHold       = "x-amz-object-lock-legal-hold"
+	HeaderNameXAmzBypassGovernanceRetention = "x-amz-bypass-governance-retention"
+
+	HeaderNameXAmzReplicationStatus = "x-amz-replication-status"
 
 	HeaderNameIfMatch           = "If-Match"
 	HeaderNameIfNoneMatch       = "If-None-Match"
@@ -78,6 +97,7 @@ const (
 	HeaderValueAcceptRange          = "bytes"
 	HeaderValueTypeStream           = "application/octet-stream"
 	HeaderValueContentTypeXML       = "application/xml"
+	HeaderValueContentTypeJSON      = "application/json"
 	HeaderValueContentTypeDirectory = "application/directory"
 )
 
@@ -97,6 +117,7 @@ const (
 	ParamMaxKeys    = "max-keys"
 	ParamStartAfter = "start-after"
 	ParamKey        = "key"
+	ParamVersionId  = "versionId"
 
 	ParamMaxParts       = "max-parts"
 	ParamUploadIdMarker = "upload-id-marker"
@@ -105,6 +126,8 @@ const (
 	ParamPartDelimiter  = "delimiter"
 	ParamEncodingType   = "encoding-type"
 
+	ParamVersionIdMarker = "version-id-marker"
+
 	ParamResponseCacheControl       = "response-cache-control"
 	ParamResponseContentType        = "response-content-type"
 	ParamResponseContentDisposition = "response-content-disposition"
@@ -132,6 +155,17 @@ const (
 	XAttrKeyOSSCORS         = "oss:cors"
 	XAttrKeyOSSCacheControl = "oss:cache"
 	XAttrKeyOSSExpires      = "oss:expires"
+	XAttrKeyOSSVersioning   = "oss:versioning"
+	XAttrKeyOSSLifecycle    = "oss:lifecycle"
+	XAttrKeyOSSEncryption   = "oss:encryption"
+	XAttrKeyOSSWebsite      = "oss:website"
+	XAttrKeyOSSReplication  = "oss:replication"
+	XAttrKeyOSSNotification = "oss:notification"
+
+	XAttrKeyOSSReplicationStatus = "oss:replication-status"
+
+	XAttrKeyOSSSSECustomerKey    = "oss:sse-c-key"
+	XAttrKeyOSSSSECustomerKeyMD5 = "oss:sse-c-key-md5"
 
 	// Deprecated
 	XAttrKeyOSSETagDeprecated = "oss:tag"
@@ -18,6 +18,8 @@ import (
 	"os"
 	"sort"
 	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
 )
 
 type FSFileInfo struct {
@@ -28,11 +30,20 @@ type FSFileInfo struct {
 	CreateTime time.Time
 	ETag         string
 	Inode        uint64
+	VersionID    string
 	MIMEType     string
 	Disposition  string
 	CacheControl string
 	Expires      string
 	Metadata   map[string]string `graphql:"-"` // User-defined metadata
+
+	SSE               string // Server side encryption algorithm with the managed keys
+	SSECustomerKeyMD5 string // MD5 of the customer key of the server side encryption
+
+	Retention *proto.Retention `graphql:"-"` // Retention period of the object lock
+	LegalHold string           // Legal hold status of the object lock
+
+	ReplicationStatus string // Replication status of the object
 }
 
 type Prefixes []string
@@ -41,6 +41,7 @@ type VolumeLoader struct {
 	closeOnce  sync.Once
 	closeCh    chan struct{}
 	metaStrict bool
+	keyClient  *DataKeyClient
 }
 
 func (loader *VolumeLoader) blacklistCleanup() {
@@ -136,6 +137,7 @@ func (loader *VolumeLoader) loadVolume(volName string) (*Volume, error) {
 			Store:            loader.store,
 			OnAsyncTaskError: onAsyncTaskError,
 			MetaStrict:       loader.metaStrict,
+			KeyClient:        loader.keyClient,
 		}
 		if volume, err = NewVolume(config); err != nil {
 			if err != proto.ErrVolNotExists {
@@ -213,6 +215,15 @@ func (m *VolumeManager) Close() {
 	})
 }
 
+// SetDataKeyClient sets the client of the data keys for the server side
+// encryption with the keys managed by the authnode. It must be set before
+// any volume is loaded.
+func (m *VolumeManager) SetDataKeyClient(kc *DataKeyClient) {
+	for _, loader := range m.loaders {
+		loader.keyClient = kc
+	}
+}
+
 func (m *VolumeManager) Release(volName string) {
 	m.selectLoader(volName).Release(volName)
 }
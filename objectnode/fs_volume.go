@@ -76,6 +76,10 @@ type VolumeConfig struct {
 
 	// Get OSSMeta from the MetaNode every time if it is set true.
 	MetaStrict bool
+
+	// Client of the data keys of the objects encrypted with the keys managed by the authnode.
+	// This is a optional configuration item.
+	KeyClient *DataKeyClient
 }
 
 type PutFileOption struct {
@@ -85,6 +89,10 @@ type PutFileOption struct {
 	Metadata     map[string]string
 	CacheControl string
 	Expires      string
+	SSE          *SSEOption
+	ObjectLock   *ObjectLockOption
+
+	ReplicationStatus string // Replication status of the object, set on the replicas
 }
 
 type ListFilesV1Option struct {
@@ -127,6 +135,7 @@ type Volume struct {
 	store      Store // Storage for ACP management
 	name       string
 	metaLoader ossMetaLoader
+	keyClient  *DataKeyClient
 	ticker     *time.Ticker
 	createTime int64
 
@@ -174,6 +183,42 @@ func (v *Volume) loadOSSMeta() {
 		return
 	}
 	v.metaLoader.storeCors(cors)
+
+	var versioning *VersioningConfiguration
+	if versioning, err = v.loadBucketVersioning(); err != nil {
+		return
+	}
+	v.metaLoader.storeVersioning(versioning)
+
+	var lifecycle *LifecycleConfiguration
+	if lifecycle, err = v.loadBucketLifecycle(); err != nil {
+		return
+	}
+	v.metaLoader.storeLifecycle(lifecycle)
+
+	var encryption *ServerSideEncryptionConfiguration
+	if encryption, err = v.loadBucketEncryption(); err != nil {
+		return
+	}
+	v.metaLoader.storeEncryption(encryption)
+
+	var website *WebsiteConfiguration
+	if website, err = v.loadBucketWebsite(); err != nil {
+		return
+	}
+	v.metaLoader.storeWebsite(website)
+
+	var replication *ReplicationConfiguration
+	if replication, err = v.loadBucketReplication(); err != nil {
+		return
+	}
+	v.metaLoader.storeReplication(replication)
+
+	var notification *NotificationConfiguration
+	if notification, err = v.loadBucketNotification(); err != nil {
+		return
+	}
+	v.metaLoader.storeNotification(notification)
 }
 
 func (v *Volume) Name() string {
@@ -236,6 +281,96 @@ func (v *Volume) loadBucketCors() (configuration *CORSConfiguration, err error)
 	return configuration, nil
 }
 
+func (v *Volume) loadBucketVersioning() (versioning *VersioningConfiguration, err error) {
+	var raw []byte
+	if raw, err = v.store.Get(v.name, bucketRootPath, XAttrKeyOSSVersioning); err != nil {
+		return
+	}
+	if len(raw) == 0 {
+		return
+	}
+	versioning = &VersioningConfiguration{}
+	if err = xml.Unmarshal(raw, versioning); err != nil {
+		return
+	}
+	return versioning, nil
+}
+
+func (v *Volume) loadBucketLifecycle() (lifecycle *LifecycleConfiguration, err error) {
+	var raw []byte
+	if raw, err = v.store.Get(v.name, bucketRootPath, XAttrKeyOSSLifecycle); err != nil {
+		return
+	}
+	if len(raw) == 0 {
+		return
+	}
+	lifecycle = &LifecycleConfiguration{}
+	if err = xml.Unmarshal(raw, lifecycle); err != nil {
+		return
+	}
+	return lifecycle, nil
+}
+
+func (v *Volume) loadBucketEncryption() (encryption *ServerSideEncryptionConfiguration, err error) {
+	var raw []byte
+	if raw, err = v.store.Get(v.name, bucketRootPath, XAttrKeyOSSEncryption); err != nil {
+		return
+	}
+	if len(raw) == 0 {
+		return
+	}
+	encryption = &ServerSideEncryptionConfiguration{}
+	if err = xml.Unmarshal(raw, encryption); err != nil {
+		return
+	}
+	return encryption, nil
+}
+
+func (v *Volume) loadBucketWebsite() (website *WebsiteConfiguration, err error) {
+	var raw []byte
+	if raw, err = v.store.Get(v.name, bucketRootPath, XAttrKeyOSSWebsite); err != nil {
+		return
+	}
+	if len(raw) == 0 {
+		return
+	}
+	website = &WebsiteConfiguration{}
+	if err = xml.Unmarshal(raw, website); err != nil {
+		return
+	}
+	return website, nil
+}
+
+func (v *Volume) loadBucketReplication() (replication *ReplicationConfiguration, err error) {
+	var raw []byte
+	if raw, err = v.store.Get(v.name, bucketRootPath, XAttrKeyOSSReplication); err != nil {
+		return
+	}
+	if len(raw) == 0 {
+		return
+	}
+	replication = &ReplicationConfiguration{}
+	if err = xml.Unmarshal(raw, replication); err != nil {
+		return
+	}
+	return replication, nil
+}
+
+func (v *Volume) loadBucketNotification() (notification *NotificationConfiguration, err error) {
+	var raw []byte
+	if raw, err = v.store.Get(v.name, bucketRootPath, XAttrKeyOSSNotification); err != nil {
+		return
+	}
+	if len(raw) == 0 {
+		return
+	}
+	notification = &NotificationConfiguration{}
+	if err = xml.Unmarshal(raw, notification); err != nil {
+		return
+	}
+	return notification, nil
+}
+
 func (v *Volume) getInodeFromPath(path string) (inode uint64, err error) {
 	if path == "/" {
 		return volumeRootInode, nil
@@ -489,9 +624,11 @@ func (v *Volume) PutObject(path string, reader io.Reader, opt *PutFileOption) (f
 	if invisibleTempDataInode, err = v.mw.InodeCreate_ll(DefaultFileMode, 0, 0, nil); err != nil {
 		return
 	}
+	// The inode must not be released once it is applied to the object.
+	var applied bool
 	defer func() {
 		// An error has caused the entire process to fail. Delete the inode and release the written data.
-		if err != nil {
+		if err != nil && !applied {
 			log.LogWarnf("PutObject: unlink temp inode: volume(%v) path(%v) inode(%v)",
 				v.name, path, invisibleTempDataInode.Inode)
 			_, _ = v.mw.InodeUnlink_ll(invisibleTempDataInode.Inode)
@@ -500,6 +637,21 @@ func (v *Volume) PutObject(path string, reader io.Reader, opt *PutFileOption) (f
 			_ = v.mw.Evict(invisibleTempDataInode.Inode)
 		}
 	}()
+	// The data is encrypted by the extent client if the encryption is requested.
+	// The data key must be removed after the stream is closed.
+	var (
+		dataKey   []byte
+		sseXAttrs map[string]string
+	)
+	if opt != nil && opt.SSE != nil {
+		if dataKey, sseXAttrs, err = v.newObjectKey(opt.SSE); err != nil {
+			return
+		}
+		if err = v.ec.SetFileKey(invisibleTempDataInode.Inode, dataKey); err != nil {
+			return
+		}
+		defer v.ec.RemoveFileKey(invisibleTempDataInode.Inode)
+	}
 	if err = v.ec.OpenStream(invisibleTempDataInode.Inode); err != nil {
 		return
 	}
@@ -519,6 +671,9 @@ func (v *Volume) PutObject(path string, reader io.Reader, opt *PutFileOption) (f
 	}
 	// compute file md5
 	md5Value = hex.EncodeToString(md5Hash.Sum(nil))
+	if opt != nil && opt.SSE.Customer() {
+		md5Value = customerETag(md5Value, dataKey)
+	}
 
 	// flush
 	if err = v.ec.Flush(invisibleTempDataInode.Inode); err != nil {
@@ -545,6 +700,14 @@ func (v *Volume) PutObject(path string, reader io.Reader, opt *PutFileOption) (f
 			v.name, path, invisibleTempDataInode.Inode, XAttrKeyOSSETag, md5Value, err)
 		return nil, err
 	}
+	// Save the encrypted data key
+	for key, value := range sseXAttrs {
+		if err = v.mw.XAttrSet_ll(invisibleTempDataInode.Inode, []byte(key), []byte(value)); err != nil {
+			log.LogErrorf("PutObject: store data key fail: volume(%v) path(%v) inode(%v) key(%v) err(%v)",
+				v.name, path, invisibleTempDataInode.Inode, key, err)
+			return nil, err
+		}
+	}
 	// If MIME information is valid, use extended attributes for storage.
 	if opt != nil && opt.MIMEType != "" {
 		if err = v.mw.XAttrSet_ll(invisibleTempDataInode.Inode, []byte(XAttrKeyOSSMIME), []byte(opt.MIMEType)); err != nil {
@@ -585,6 +748,14 @@ func (v *Volume) PutObject(path string, reader io.Reader, opt *PutFileOption) (f
 			return nil, err
 		}
 	}
+	// If the object is a replica, store the replication status to xattr
+	if opt != nil && opt.ReplicationStatus != "" {
+		if err = v.mw.XAttrSet_ll(invisibleTempDataInode.Inode, []byte(XAttrKeyOSSReplicationStatus), []byte(opt.ReplicationStatus)); err != nil {
+			log.LogErrorf("PutObject: store replication status fail: volume(%v) path(%v) inode(%v) status(%v) err(%v)",
+				v.name, path, invisibleTempDataInode.Inode, opt.ReplicationStatus, err)
+			return nil, err
+		}
+	}
 	// If user-defined metadata have been specified, use extend attributes for storage.
 	if opt != nil && len(opt.Metadata) > 0 {
 		for name, value := range opt.Metadata {
@@ -610,20 +781,29 @@ func (v *Volume) PutObject(path string, reader io.Reader, opt *PutFileOption) (f
 		ETag:       etagValue.ETag(),
 		Inode:      finalInode.Inode,
 	}
+	setSSEFileInfo(fsInfo, sseXAttrs)
 
 	// apply new inode to dentry
-	err = v.applyInodeToDEntry(parentId, lastPathItem.Name, invisibleTempDataInode.Inode)
+	fsInfo.VersionID, err = v.applyInodeToObject(parentId, lastPathItem.Name, invisibleTempDataInode.Inode)
 	if err != nil {
 		log.LogErrorf("PutObject: apply new inode to dentry fail: parentID(%v) name(%v) inode(%v) err(%v)",
 			parentId, lastPathItem.Name, invisibleTempDataInode.Inode, err)
 		return
 	}
+	applied = true
+	if opt != nil && opt.ObjectLock != nil {
+		if err = v.setObjectLock(invisibleTempDataInode.Inode, opt.ObjectLock); err != nil {
+			return nil, err
+		}
+		fsInfo.Retention, fsInfo.LegalHold = opt.ObjectLock.Retention, opt.ObjectLock.LegalHold
+	}
 	return fsInfo, nil
 }
 
 func (v *Volume) applyInodeToDEntry(parentId uint64, name string, inode uint64) (err error) {
+	var existInode uint64
 	var existMode uint32
-	_, existMode, err = v.mw.Lookup_ll(parentId, name)
+	existInode, existMode, err = v.mw.Lookup_ll(parentId, name)
 	if err != nil && err != syscall.ENOENT {
 		log.LogErrorf("applyInodeToDEntry: meta lookup fail: parentID(%v) name(%v) err(%v)", parentId, name, err)
 		return
@@ -644,6 +824,10 @@ func (v *Volume) applyInodeToDEntry(parentId uint64, name string, inode uint64)
 			err = syscall.EINVAL
 			return
 		}
+		// the object protected by the object lock can not be overwritten
+		if err = v.checkObjectLock(existInode, false); err != nil {
+			return
+		}
 		if err = v.applyInodeToExistDentry(parentId, name, inode); err != nil {
 			log.LogErrorf("applyInodeToDEntry: apply inode to exist dentry fail: parentID(%v) name(%v) inode(%v) err(%v)",
 				parentId, name, inode, err)
@@ -661,6 +845,10 @@ func (v *Volume) applyInodeToDEntry(parentId uint64, name string, inode uint64)
 // This method will only returns internal system errors.
 // This method will not return syscall.ENOENT error
 func (v *Volume) DeletePath(path string) (err error) {
+	return v.deletePath(path, false)
+}
+
+func (v *Volume) deletePath(path string, bypassGovernance bool) (err error) {
 	defer func() {
 		// Audit behavior
 		log.LogInfof("Audit: DeletePath: volume(%v) path(%v), err(%v)", v.name, path, err)
@@ -690,6 +878,8 @@ func (v *Volume) DeletePath(path string) (err error) {
 		if err != nil || len(dentries) > 0 {
 			return
 		}
+	} else if err = v.checkObjectLock(ino, bypassGovernance); err != nil {
+		return
 	}
 	log.LogWarnf("DeletePath: delete: volume(%v) path(%v) inode(%v)", v.name, path, ino)
 	if _, err = v.mw.Delete_ll(parent, name, mode.IsDir()); err != nil {
@@ -741,6 +931,30 @@ func (v *Volume) InitMultipart(path string, opt *PutFileOption) (multipartID str
 		var encoded = opt.Tagging.Encode()
 		extend[XAttrKeyOSSTagging] = encoded
 	}
+	// If the object is a replica, store the replication status to xattr
+	if opt != nil && opt.ReplicationStatus != "" {
+		extend[XAttrKeyOSSReplicationStatus] = opt.ReplicationStatus
+	}
+	// If the encryption is requested, the data key is generated for all of the parts,
+	// and kept encrypted in the extend attributes of the completed object.
+	if opt != nil && opt.SSE != nil {
+		var sseXAttrs map[string]string
+		if _, sseXAttrs, err = v.newObjectKey(opt.SSE); err != nil {
+			return "", err
+		}
+		for key, value := range sseXAttrs {
+			extend[key] = value
+		}
+	}
+	// The object lock is kept with the multipart, and applied to the completed object.
+	if opt != nil && opt.ObjectLock != nil {
+		if opt.ObjectLock.Retention != nil {
+			extend[proto.RetentionXAttrKey] = opt.ObjectLock.Retention.String()
+		}
+		if opt.ObjectLock.LegalHold != "" {
+			extend[proto.LegalHoldXAttrKey] = opt.ObjectLock.LegalHold
+		}
+	}
 
 	// Iterate all the meta partition to create multipart id
 	multipartID, err = v.mw.InitMultipart_ll(path, extend)
@@ -751,7 +965,7 @@ func (v *Volume) InitMultipart(path string, opt *PutFileOption) (multipartID str
 	return multipartID, nil
 }
 
-func (v *Volume) WritePart(path string, multipartId string, partId uint16, reader io.Reader) (*FSFileInfo, error) {
+func (v *Volume) WritePart(path string, multipartId string, partId uint16, reader io.Reader, sse *SSEOption) (*FSFileInfo, error) {
 	var exist bool
 	var err error
 	defer func() {
@@ -763,6 +977,17 @@ func (v *Volume) WritePart(path string, multipartId string, partId uint16, reade
 	var fInfo *FSFileInfo
 	_, fileName := splitPath(path)
 
+	// The part is encrypted with the data key of the multipart upload.
+	var multipartInfo *proto.MultipartInfo
+	if multipartInfo, err = v.mw.GetMultipart_ll(path, multipartId); err != nil {
+		log.LogErrorf("WritePart: meta get multipart fail: volume(%v) path(%v) multipartID(%v) err(%v)",
+			v.name, path, multipartId, err)
+		return nil, err
+	}
+	var dataKey []byte
+	if dataKey, err = v.unwrapObjectKey(multipartInfo.Extend, sse); err != nil {
+		return nil, err
+	}
 	// create temp file (inode only, invisible for user)
 	var tempInodeInfo *proto.InodeInfo
 	if tempInodeInfo, err = v.mw.InodeCreate_ll(DefaultFileMode, 0, 0, nil); err != nil {
@@ -785,6 +1010,14 @@ func (v *Volume) WritePart(path string, multipartId string, partId uint16, reade
 		}
 	}()
 
+	if dataKey != nil {
+		if err = v.ec.SetFileKey(tempInodeInfo.Inode, dataKey); err != nil {
+			log.LogErrorf("WritePart: set data key fail: volume(%v) path(%v) multipartID(%v) partID(%v) inode(%v) err(%v)",
+				v.name, path, multipartId, partId, tempInodeInfo.Inode, err)
+			return nil, err
+		}
+		defer v.ec.RemoveFileKey(tempInodeInfo.Inode)
+	}
 	if err = v.ec.OpenStream(tempInodeInfo.Inode); err != nil {
 		log.LogErrorf("WritePart: data open stream fail: volume(%v) path(%v) multipartID(%v) partID(%v) inode(%v) err(%v)",
 			v.name, path, multipartId, partId, tempInodeInfo.Inode, err)
@@ -807,6 +1040,9 @@ func (v *Volume) WritePart(path string, multipartId string, partId uint16, reade
 	}
 	// compute file md5
 	etag = hex.EncodeToString(md5Hash.Sum(nil))
+	if sse.Customer() {
+		etag = customerETag(etag, dataKey)
+	}
 
 	// flush
 	if err = v.ec.Flush(tempInodeInfo.Inode); err != nil {
@@ -837,6 +1073,7 @@ func (v *Volume) WritePart(path string, multipartId string, partId uint16, reade
 		ETag:       etag,
 		Inode:      tempInodeInfo.Inode,
 	}
+	setSSEFileInfo(fInfo, multipartInfo.Extend)
 	return fInfo, nil
 }
 
@@ -899,8 +1136,9 @@ func (v *Volume) CompleteMultipart(path, multipartID string, multipartInfo *prot
 	}
 	log.LogDebugf("CompleteMultipart: meta inode create: volume(%v) path(%v) multipartID(%v) inode(%v)",
 		v.name, path, multipartID, completeInodeInfo.Inode)
+	var applied bool
 	defer func() {
-		if err != nil {
+		if err != nil && !applied {
 			log.LogWarnf("CompleteMultipart: destroy inode: volume(%v) path(%v) multipartID(%v) inode(%v)",
 				v.name, path, multipartID, completeInodeInfo.Inode)
 			if deleteErr := v.mw.InodeDelete_ll(completeInodeInfo.Inode); deleteErr != nil {
@@ -980,8 +1218,15 @@ func (v *Volume) CompleteMultipart(path, multipartID string, multipartInfo *prot
 	}
 	// set user modified system metadata, self defined metadata and tag
 	extend := multipartInfo.Extend
+	var objectLock *ObjectLockOption
 	if len(extend) > 0 {
 		for key, value := range extend {
+			if isObjectLockXAttrKey(key) {
+				if objectLock == nil {
+					objectLock = &ObjectLockOption{}
+				}
+				continue
+			}
 			if err = v.mw.XAttrSet_ll(completeInodeInfo.Inode, []byte(key), []byte(value)); err != nil {
 				log.LogErrorf("CompleteMultipart: store multipart extend fail: volume(%v) path(%v) inode(%v) key(%v) value(%v) err(%v)",
 					v.name, path, completeInodeInfo.Inode, key, value, err)
@@ -989,7 +1234,6 @@ func (v *Volume) CompleteMultipart(path, multipartID string, multipartInfo *prot
 			}
 		}
 	}
-
 	// remove multipart
 	err = v.mw.RemoveMultipart_ll(path, multipartID)
 	if err == syscall.ENOENT {
@@ -1024,12 +1268,27 @@ func (v *Volume) CompleteMultipart(path, multipartID string, multipartInfo *prot
 		ETag:       etagValue.ETag(),
 		Inode:      finalInode.Inode,
 	}
+	setSSEFileInfo(fInfo, extend)
 
 	// apply new inode to dentry
-	err = v.applyInodeToDEntry(parentId, filename, completeInodeInfo.Inode)
+	fInfo.VersionID, err = v.applyInodeToObject(parentId, filename, completeInodeInfo.Inode)
 	if err != nil {
 		log.LogErrorf("CompleteMultipart: apply new inode to dentry fail, parent id (%v), file name(%v), inode(%v)",
 			parentId, filename, completeInodeInfo.Inode)
+		return nil, err
+	}
+	applied = true
+	if objectLock != nil {
+		if raw := extend[proto.RetentionXAttrKey]; raw != "" {
+			if objectLock.Retention, err = proto.ParseRetention(raw); err != nil {
+				return nil, err
+			}
+		}
+		objectLock.LegalHold = extend[proto.LegalHoldXAttrKey]
+		if err = v.setObjectLock(completeInodeInfo.Inode, objectLock); err != nil {
+			return nil, err
+		}
+		fInfo.Retention, fInfo.LegalHold = objectLock.Retention, objectLock.LegalHold
 	}
 	return fInfo, nil
 }
@@ -1134,6 +1393,10 @@ func (v *Volume) applyInodeToExistDentry(parentID uint64, name string, inode uin
 			parentID, name, inode, err)
 		return
 	}
+	if oldInode == 0 {
+		// the old inode is kept by a version of the object
+		return
+	}
 
 	// unlink and evict old inode
 	log.LogWarnf("applyInodeToExistDentry: unlink inode: volume(%v) inode(%v)", v.name, oldInode)
@@ -1160,7 +1423,8 @@ func (v *Volume) loadUserDefinedMetadata(inode uint64) (metadata map[string]stri
 	}
 	var xattrKeys = make([]string, 0)
 	for _, storedXAttrKey := range storedXAttrKeys {
-		if !strings.HasPrefix(storedXAttrKey, "oss:") {
+		if !strings.HasPrefix(storedXAttrKey, "oss:") && !isSSEXAttrKey(storedXAttrKey) &&
+			!isObjectLockXAttrKey(storedXAttrKey) {
 			xattrKeys = append(xattrKeys, storedXAttrKey)
 		}
 	}
@@ -1180,7 +1444,13 @@ func (v *Volume) loadUserDefinedMetadata(inode uint64) (metadata map[string]stri
 	return
 }
 
+// ReadFile reads the data of the object, which is decrypted if the object is
+// encrypted with the managed keys.
 func (v *Volume) ReadFile(path string, writer io.Writer, offset, size uint64) error {
+	return v.readFile(path, writer, offset, size, nil)
+}
+
+func (v *Volume) readFile(path string, writer io.Writer, offset, size uint64, sse *SSEOption) error {
 	var err error
 
 	var ino uint64
@@ -1191,12 +1461,23 @@ func (v *Volume) ReadFile(path string, writer io.Writer, offset, size uint64) er
 	if mode.IsDir() {
 		return nil
 	}
+	return v.readInode(path, ino, writer, offset, size, sse)
+}
+
+func (v *Volume) readInode(path string, ino uint64, writer io.Writer, offset, size uint64, sse *SSEOption) error {
+	var err error
 
 	// read file data
 	var inoInfo *proto.InodeInfo
 	if inoInfo, err = v.mw.InodeGet_ll(ino); err != nil {
 		return err
 	}
+	// The data is decrypted here instead of by the extent client, so that the
+	// data key is not kept by the extent client after the read.
+	var c *stream.FileCipher
+	if c, err = v.objectCipher(ino, sse); err != nil {
+		return err
+	}
 
 	if err = v.ec.OpenStream(ino); err != nil {
 		log.LogErrorf("ReadFile: data open stream fail, Inode(%v) err(%v)", ino, err)
@@ -1233,6 +1514,11 @@ func (v *Volume) ReadFile(path string, writer io.Writer, offset, size uint64) er
 			return err
 		}
 		if n > 0 {
+			if c != nil {
+				if err = v.ec.Decrypt(ino, c, int(offset), tmp[:n]); err != nil {
+					return err
+				}
+			}
 			if _, err = writer.Write(tmp[:n]); err != nil {
 				return err
 			}
@@ -1248,13 +1534,15 @@ func (v *Volume) ReadFile(path string, writer io.Writer, offset, size uint64) er
 func (v *Volume) ObjectMeta(path string) (info *FSFileInfo, err error) {
 
 	// process path
+	var parent uint64
 	var inode uint64
+	var name string
 	var mode os.FileMode
 	var inoInfo *proto.InodeInfo
 
 	var retry = 0
 	for {
-		if _, inode, _, mode, err = v.recursiveLookupTarget(path); err != nil {
+		if parent, inode, name, mode, err = v.recursiveLookupTarget(path); err != nil {
 			return
 		}
 
@@ -1270,12 +1558,32 @@ func (v *Volume) ObjectMeta(path string) (info *FSFileInfo, err error) {
 		break
 	}
 
+	if info, err = v.inodeMeta(path, inoInfo); err != nil {
+		return
+	}
+	if !mode.IsDir() {
+		if info.VersionID, err = v.objectVersionID(parent, name, inode); err != nil {
+			log.LogErrorf("ObjectMeta: get version ID fail: volume(%v) path(%v) inode(%v) err(%v)",
+				v.name, path, inode, err)
+			return
+		}
+	}
+	return
+}
+
+func (v *Volume) inodeMeta(path string, inoInfo *proto.InodeInfo) (info *FSFileInfo, err error) {
+	var (
+		inode = inoInfo.Inode
+		mode  = os.FileMode(inoInfo.Mode)
+	)
+
 	var (
 		etagValue    ETagValue
 		mimeType     string
 		disposition  string
 		cacheControl string
 		expires      string
+		xattrValues  map[string]string
 	)
 
 	if mode.IsDir() {
@@ -1289,7 +1597,8 @@ func (v *Volume) ObjectMeta(path string) (info *FSFileInfo, err error) {
 		// 2. MIME type
 		var xattrs []*proto.XAttrInfo
 		var xattrKeys = []string{XAttrKeyOSSETag, XAttrKeyOSSETagDeprecated, XAttrKeyOSSMIME, XAttrKeyOSSDISPOSITION,
-			XAttrKeyOSSCacheControl, XAttrKeyOSSExpires}
+			XAttrKeyOSSCacheControl, XAttrKeyOSSExpires, proto.CryptKeyXAttrKey, XAttrKeyOSSSSECustomerKey,
+			XAttrKeyOSSSSECustomerKeyMD5, proto.RetentionXAttrKey, proto.LegalHoldXAttrKey, XAttrKeyOSSReplicationStatus}
 		if xattrs, err = v.mw.BatchGetXAttr([]uint64{inode}, xattrKeys); err != nil {
 			log.LogErrorf("ObjectMeta: meta get xattr fail, volume(%v) inode(%v) path(%v) keys(%v) err(%v)",
 				v.name, inode, path, strings.Join(xattrKeys, ","), err)
@@ -1309,6 +1618,7 @@ func (v *Volume) ObjectMeta(path string) (info *FSFileInfo, err error) {
 			disposition = string(xattr.Get(XAttrKeyOSSDISPOSITION))
 			cacheControl = string(xattr.Get(XAttrKeyOSSCacheControl))
 			expires = string(xattr.Get(XAttrKeyOSSExpires))
+			xattrValues = xattr.XAttrs
 		}
 	}
 
@@ -1344,7 +1654,11 @@ func (v *Volume) ObjectMeta(path string) (info *FSFileInfo, err error) {
 		CacheControl: cacheControl,
 		Expires:      expires,
 		Metadata:     metadata,
+
+		ReplicationStatus: xattrValues[XAttrKeyOSSReplicationStatus],
 	}
+	setSSEFileInfo(info, xattrValues)
+	setObjectLockFileInfo(info, xattrValues)
 	return
 }
 
@@ -1505,7 +1819,7 @@ func (v *Volume) lookupDirectories(dirs []string, autoCreate bool) (inode uint64
 }
 
 func (v *Volume) listFilesV1(prefix, marker, delimiter string, maxKeys uint64) (infos []*FSFileInfo, prefixes Prefixes, nextMarker string, err error) {
-	var prefixMap = PrefixMap(make(map[string]struct{}))
+	var prefixMap PrefixMap
 
 	parentId, dirs, err := v.findParentId(prefix)
 
@@ -1523,19 +1837,15 @@ func (v *Volume) listFilesV1(prefix, marker, delimiter string, maxKeys uint64) (
 
 	log.LogDebugf("listFilesV1: find parent ID, prefix(%v) marker(%v) delimiter(%v) parentId(%v) dirs(%v)", prefix, marker, delimiter, parentId, len(dirs))
 
-	// Init the value that queried result count.
-	// Check this value when adding key to contents or common prefix,
-	// return if it reach to max keys
-	var rc uint64
-	// recursion scan
-	infos, prefixMap, nextMarker, _, err = v.recursiveScan(infos, prefixMap, parentId, maxKeys, rc, dirs, prefix, marker, delimiter)
-	if err != nil {
+	var scanner = v.newListScanner(prefix, marker, delimiter, maxKeys)
+	if err = scanner.scan(parentId, dirs); err != nil {
 		log.LogErrorf("listFilesV1: volume list dir fail: Volume(%v) err(%v)", v.name, err)
 		return
 	}
+	infos, prefixMap, nextMarker = scanner.fileInfos, scanner.prefixMap, scanner.nextMarker
 
 	// Supplementary file information, such as file modification time, MIME type, Etag information, etc.
-	if err = v.supplyListFileInfo(infos); err != nil {
+	if err = v.supplyListFileInfo(infos, scanner.inodeInfos); err != nil {
 		log.LogDebugf("listFilesV1: supply list file info fail, err(%v)", err)
 		return
 	}
@@ -1549,7 +1859,7 @@ func (v *Volume) listFilesV1(prefix, marker, delimiter string, maxKeys uint64) (
 }
 
 func (v *Volume) listFilesV2(prefix, startAfter, contToken, delimiter string, maxKeys uint64) (infos []*FSFileInfo, prefixes Prefixes, nextMarker string, err error) {
-	var prefixMap = PrefixMap(make(map[string]struct{}))
+	var prefixMap PrefixMap
 
 	var marker string
 	if startAfter != "" {
@@ -1574,19 +1884,15 @@ func (v *Volume) listFilesV2(prefix, startAfter, contToken, delimiter string, ma
 
 	log.LogDebugf("listFilesV2: find parent ID, prefix(%v) marker(%v) delimiter(%v) parentId(%v) dirs(%v)", prefix, marker, delimiter, parentId, len(dirs))
 
-	// Init the value that queried result count.
-	// Check this value when adding key to contents or common prefix,
-	// return if it reach to max keys
-	var rc uint64
-	// recursion scan
-	infos, prefixMap, nextMarker, _, err = v.recursiveScan(infos, prefixMap, parentId, maxKeys, rc, dirs, prefix, marker, delimiter)
-	if err != nil {
+	var scanner = v.newListScanner(prefix, marker, delimiter, maxKeys)
+	if err = scanner.scan(parentId, dirs); err != nil {
 		log.LogErrorf("listFilesV2: Volume list dir fail, Volume(%v) err(%v)", v.name, err)
 		return
 	}
+	infos, prefixMap, nextMarker = scanner.fileInfos, scanner.prefixMap, scanner.nextMarker
 
 	// Supplementary file information, such as file modification time, MIME type, Etag information, etc.
-	err = v.supplyListFileInfo(infos)
+	err = v.supplyListFileInfo(infos, scanner.inodeInfos)
 	if err != nil {
 		log.LogDebugf("listFilesV2: supply list file info fail, err(%v)", err)
 		return
@@ -1648,142 +1954,278 @@ func (v *Volume) findParentId(prefix string) (inode uint64, prefixDirs []string,
 	return
 }
 
-// Recursive scan of the directory starting from the given parentID. Match files and directories
-// that match the prefix and delimiter criteria. Stop when the number of matches reaches a threshold
-// or all files and directories are scanned.
-func (v *Volume) recursiveScan(fileInfos []*FSFileInfo, prefixMap PrefixMap, parentId, maxKeys, rc uint64,
-	dirs []string, prefix, marker, delimiter string) ([]*FSFileInfo, PrefixMap, string, uint64, error) {
-	var err error
-	var nextMarker string
+// listScanReadDirLimit is the max number of dentries read from a directory at a time.
+const listScanReadDirLimit = 1000
 
+// listScanner scans the directories recursively starting from the given parentID. Match files
+// and directories that match the prefix and delimiter criteria. Stop when the number of matches
+// reaches a threshold or all files and directories are scanned.
+//
+// The directories are read in pages starting from the marker, and the subdirectories whose keys
+// are all before the marker are skipped, so that the cost of listing a page of results depends
+// on the size of the page rather than the number of the files before the marker.
+//
+// The keys are listed in the order of the keys rather than the names of the dentries. The keys of
+// a directory start with its name followed by the path separator, so the directory is scanned
+// after the siblings whose names are less than that, for example "a-b" is listed before "a/x".
+type listScanner struct {
+	prefix    string
+	marker    string
+	delimiter string
+	maxKeys   uint64
+
+	readDir func(parentID uint64, marker, prefix string, limit uint64) ([]proto.Dentry, []*proto.InodeInfo, string, error)
+	lookup  func(parentID uint64, name string) (uint64, uint32, error)
+
+	fileInfos  []*FSFileInfo
+	inodeInfos map[uint64]*proto.InodeInfo // inode infos returned with the dentries
+	prefixMap  PrefixMap
+	rc         uint64 // queried result count
+	nextMarker string
+}
+
+func (v *Volume) newListScanner(prefix, marker, delimiter string, maxKeys uint64) *listScanner {
+	return &listScanner{
+		prefix:     prefix,
+		marker:     marker,
+		delimiter:  delimiter,
+		maxKeys:    maxKeys,
+		readDir:    v.mw.ReadDirPlus_ll,
+		lookup:     v.mw.Lookup_ll,
+		inodeInfos: make(map[uint64]*proto.InodeInfo),
+		prefixMap:  PrefixMap(make(map[string]struct{})),
+	}
+}
+
+// add adds the file info to the results. If the number of matches reaches the threshold given
+// by maxKey, the path is used as the next marker and false is returned.
+func (s *listScanner) add(fileInfo *FSFileInfo) bool {
+	if s.rc >= s.maxKeys {
+		s.nextMarker = fileInfo.Path
+		return false
+	}
+	s.fileInfos = append(s.fileInfos, fileInfo)
+	s.rc++
+	return true
+}
+
+func (s *listScanner) addPrefix(commonPrefix string) bool {
+	if s.rc >= s.maxKeys {
+		s.nextMarker = commonPrefix
+		return false
+	}
+	s.prefixMap.AddPrefix(commonPrefix)
+	s.rc++
+	return true
+}
+
+func (s *listScanner) truncated() bool {
+	return s.nextMarker != ""
+}
+
+func (s *listScanner) scan(parentID uint64, dirs []string) (err error) {
 	var currentPath = strings.Join(dirs, pathSep) + pathSep
 
-	if len(dirs) > 0 && prefix != "" && strings.HasSuffix(currentPath, prefix) {
+	if len(dirs) > 0 && s.prefix != "" && strings.HasSuffix(currentPath, s.prefix) {
 		// When the current scanning position is not the root directory, a prefix matching
 		// check is performed on the current directory first.
 		//
 		// The reason for this is that according to the definition of Amazon S3's ListObjects
 		// interface, directory entries that meet the exact prefix match will be returned as
 		// a Content result, not as a CommonPrefix.
-
-		// Add check to marker, if request contain marker, current path must greater than marker,
-		// otherwise can not put it to prefix map
-		if (len(marker) > 0 && currentPath > marker) || len(marker) == 0 {
-			fileInfo := &FSFileInfo{
-				Inode: parentId,
-				Path:  currentPath,
+		if s.marker == "" || currentPath > s.marker {
+			if !s.add(&FSFileInfo{Inode: parentID, Path: currentPath}) {
+				return nil
 			}
-			// If the number of matches reaches the threshold given by maxKey,
-			// stop scanning and return results.
-			// To get the next marker, first compare the result counts with the max key
-			// it means that when result count reach the amx key, continue to find the next key as the next marker
-			if rc >= maxKeys {
-				return fileInfos, prefixMap, currentPath, rc, nil
-			}
-			fileInfos = append(fileInfos, fileInfo)
-			rc++
 		}
 	}
 
-	// During the process of scanning the child nodes of the current directory, there may be other
-	// parallel operations that may delete the current directory.
-	// If got the syscall.ENOENT error when invoke readdir, it means that the above situation has occurred.
-	// At this time, stops process and returns success.
-	var children []proto.Dentry
-	children, err = v.mw.ReadDir_ll(parentId)
-	if err != nil && err != syscall.ENOENT {
-		return fileInfos, prefixMap, "", 0, err
+	// The path of the children is the base path followed by the names of the dentries.
+	var base string
+	if len(dirs) > 0 {
+		base = currentPath
 	}
-	if err == syscall.ENOENT {
-		return fileInfos, prefixMap, "", 0, nil
+	// The directories are pending until the siblings before their keys are scanned. The keys of
+	// the pending directories are in descending order, since each of them is pushed only if its
+	// key is less than the ones pushed before.
+	var pending []proto.Dentry
+	var flush = func(key string) error {
+		for len(pending) > 0 {
+			var dir = pending[len(pending)-1]
+			if key != "" && dir.Name+pathSep > key {
+				return nil
+			}
+			pending = pending[:len(pending)-1]
+			if err := s.scanChild(dir, dirs); err != nil || s.truncated() {
+				return err
+			}
+		}
+		return nil
 	}
-
-	for _, child := range children {
-		var path = strings.Join(append(dirs, child.Name), pathSep)
-		if os.FileMode(child.Type).IsDir() {
-			path += pathSep
+	var visit = func(child proto.Dentry) error {
+		var isDir = os.FileMode(child.Type).IsDir()
+		var key = child.Name
+		if isDir {
+			key += pathSep
 		}
-		if prefix != "" && !strings.HasPrefix(path, prefix) {
-			continue
+		if err := flush(key); err != nil || s.truncated() {
+			return err
+		}
+		if isDir {
+			pending = append(pending, child)
+			return nil
 		}
+		return s.scanChild(child, dirs)
+	}
 
-		if marker != "" {
-			if !os.FileMode(child.Type).IsDir() && path < marker {
-				continue
-			}
-			if os.FileMode(child.Type).IsDir() && path < marker {
-				fileInfos, prefixMap, nextMarker, rc, err = v.recursiveScan(fileInfos, prefixMap, child.Inode, maxKeys, rc, append(dirs, child.Name), prefix, marker, delimiter)
-				if err != nil {
-					return fileInfos, prefixMap, nextMarker, rc, err
-				}
-				if rc >= maxKeys && nextMarker != "" {
-					return fileInfos, prefixMap, nextMarker, rc, err
+	var namePrefix, after string
+	if strings.HasPrefix(s.prefix, base) {
+		namePrefix = s.prefix[len(base):]
+		if idx := strings.Index(namePrefix, pathSep); idx >= 0 {
+			namePrefix = namePrefix[:idx]
+		}
+	}
+	if s.marker != "" && strings.HasPrefix(s.marker, base) {
+		var name = s.marker[len(base):]
+		if idx := strings.Index(name, pathSep); idx >= 0 {
+			name = name[:idx]
+		}
+		// Before the name in the marker, only the directories named with the prefixes of it may
+		// have keys after the marker, if the prefixes are followed by the characters less than
+		// the path separator. For example, the key "a/" of directory "a" is after marker "a-b".
+		for i := 1; i < len(name); i++ {
+			if name[i] < pathSep[0] {
+				if err = s.scanName(parentID, name[:i], true, visit); err != nil || s.truncated() {
+					return
 				}
-				continue
 			}
 		}
-
-		if delimiter != "" {
-			var nonPrefixPart = strings.Replace(path, prefix, "", 1)
-			if idx := strings.Index(nonPrefixPart, delimiter); idx >= 0 {
-				var commonPrefix = prefix + util.SubString(nonPrefixPart, 0, idx) + delimiter
-				if prefixMap.contain(commonPrefix) {
-					continue
-				}
-				if rc >= maxKeys {
-					return fileInfos, prefixMap, commonPrefix, rc, nil
-				}
-				prefixMap.AddPrefix(commonPrefix)
-				rc++
-				continue
+		if name != "" {
+			if err = s.scanName(parentID, name, false, visit); err != nil || s.truncated() {
+				return
 			}
 		}
+		after = name
+	} else if s.marker != "" && s.marker > base {
+		// all keys in the directory are before the marker
+		return nil
+	}
 
-		fileInfo := &FSFileInfo{
-			Inode: child.Inode,
-			Path:  path,
+	// During the process of scanning the child nodes of the current directory, there may be other
+	// parallel operations that may delete the current directory.
+	// If got the syscall.ENOENT error when invoke readdir, it means that the above situation has occurred.
+	// At this time, stops process and returns success.
+	for {
+		var limit = s.maxKeys - s.rc + 1
+		if limit > listScanReadDirLimit {
+			limit = listScanReadDirLimit
 		}
-		if rc >= maxKeys {
-			return fileInfos, prefixMap, path, rc, nil
+		var children []proto.Dentry
+		var infos []*proto.InodeInfo
+		children, infos, after, err = s.readDir(parentID, after, namePrefix, limit)
+		if err == syscall.ENOENT {
+			return nil
 		}
-		fileInfos = append(fileInfos, fileInfo)
-		rc++
-
-		if os.FileMode(child.Type).IsDir() {
-			fileInfos, prefixMap, nextMarker, rc, err = v.recursiveScan(fileInfos, prefixMap, child.Inode, maxKeys, rc, append(dirs, child.Name), prefix, marker, delimiter)
-			if err != nil {
-				return fileInfos, prefixMap, nextMarker, rc, err
+		if err != nil {
+			return
+		}
+		for _, info := range infos {
+			s.inodeInfos[info.Inode] = info
+		}
+		for _, child := range children {
+			if err = visit(child); err != nil || s.truncated() {
+				return
 			}
-			if rc >= maxKeys && nextMarker != "" {
-				return fileInfos, prefixMap, nextMarker, rc, err
+		}
+		if after == "" {
+			return flush("")
+		}
+	}
+}
+
+// scanName visits the child with the given name, which is skipped if it does not exist or
+// is not a directory while dirOnly is true.
+func (s *listScanner) scanName(parentID uint64, name string, dirOnly bool, visit func(child proto.Dentry) error) error {
+	inode, mode, err := s.lookup(parentID, name)
+	if err == syscall.ENOENT {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if dirOnly && !os.FileMode(mode).IsDir() {
+		return nil
+	}
+	return visit(proto.Dentry{Name: name, Inode: inode, Type: mode})
+}
+
+func (s *listScanner) scanChild(child proto.Dentry, dirs []string) error {
+	var isDir = os.FileMode(child.Type).IsDir()
+	var path = strings.Join(append(dirs, child.Name), pathSep)
+	if isDir {
+		path += pathSep
+	}
+	if s.prefix != "" && !strings.HasPrefix(path, s.prefix) {
+		return nil
+	}
+
+	if s.marker != "" && path < s.marker {
+		// The directory before the marker is scanned only if the marker is in it,
+		// since all keys in the other ones are before the marker.
+		if isDir && strings.HasPrefix(s.marker, path) {
+			return s.scan(child.Inode, append(dirs, child.Name))
+		}
+		return nil
+	}
+
+	if s.delimiter != "" {
+		var nonPrefixPart = strings.Replace(path, s.prefix, "", 1)
+		if idx := strings.Index(nonPrefixPart, s.delimiter); idx >= 0 {
+			var commonPrefix = s.prefix + util.SubString(nonPrefixPart, 0, idx) + s.delimiter
+			if !s.prefixMap.contain(commonPrefix) {
+				s.addPrefix(commonPrefix)
 			}
+			return nil
 		}
 	}
-	return fileInfos, prefixMap, nextMarker, rc, nil
+
+	if !s.add(&FSFileInfo{Inode: child.Inode, Path: path}) {
+		return nil
+	}
+	if isDir {
+		return s.scan(child.Inode, append(dirs, child.Name))
+	}
+	return nil
 }
 
 // This method is used to supplement file metadata. Supplement the specified file
 // information with Size, ModifyTIme, Mode, Etag, and MIME type information.
-func (v *Volume) supplyListFileInfo(fileInfos []*FSFileInfo) (err error) {
-	var inodes []uint64
+// The inode infos already known are given by inodeInfos, which may be nil.
+func (v *Volume) supplyListFileInfo(fileInfos []*FSFileInfo, inodeInfos map[uint64]*proto.InodeInfo) (err error) {
+	var inodes, missing []uint64
 	for _, fileInfo := range fileInfos {
 		inodes = append(inodes, fileInfo.Inode)
+		if _, found := inodeInfos[fileInfo.Inode]; !found {
+			missing = append(missing, fileInfo.Inode)
+		}
 	}
 
-	// Get size information in batches, then update to fileInfos
-	inodeInfos := v.mw.BatchInodeGet(inodes)
-	sort.SliceStable(inodeInfos, func(i, j int) bool {
-		return inodeInfos[i].Inode < inodeInfos[j].Inode
-	})
+	// Get size information of the inodes not known in batches, then update to fileInfos
+	var infoMap = make(map[uint64]*proto.InodeInfo, len(fileInfos))
+	for inode, info := range inodeInfos {
+		infoMap[inode] = info
+	}
+	if len(missing) > 0 {
+		for _, info := range v.mw.BatchInodeGet(missing) {
+			infoMap[info.Inode] = info
+		}
+	}
 	for _, fileInfo := range fileInfos {
-		i := sort.Search(len(inodeInfos), func(i int) bool {
-			return inodeInfos[i].Inode >= fileInfo.Inode
-		})
-		if i >= 0 && i < len(inodeInfos) && inodeInfos[i].Inode == fileInfo.Inode {
-			fileInfo.Size = int64(inodeInfos[i].Size)
-			fileInfo.ModifyTime = inodeInfos[i].ModifyTime
-			fileInfo.CreateTime = inodeInfos[i].CreateTime
-			fileInfo.Mode = os.FileMode(inodeInfos[i].Mode)
+		if info, found := infoMap[fileInfo.Inode]; found {
+			fileInfo.Size = int64(info.Size)
+			fileInfo.ModifyTime = info.ModifyTime
+			fileInfo.CreateTime = info.CreateTime
+			fileInfo.Mode = os.FileMode(info.Mode)
 		}
 	}
 
@@ -1944,10 +2386,10 @@ func (v *Volume) ListParts(path, uploadId string, maxParts, partNumberMarker uin
 	return parts, nextMarker, isTruncated, nil
 }
 
-func (v *Volume) CopyFile(sv *Volume, sourcePath, targetPath, metaDirective string, opt *PutFileOption) (info *FSFileInfo, err error) {
+func (v *Volume) CopyFile(sv *Volume, sourcePath, sourceVersionID string, sourceSSE *SSEOption, targetPath, metaDirective string, opt *PutFileOption) (info *FSFileInfo, err error) {
 	defer func() {
-		log.LogInfof("Audit: copy file: source path(%v) target path(%v) err(%v)",
-			sourcePath, targetPath, err)
+		log.LogInfof("Audit: copy file: source path(%v) source version(%v) target path(%v) err(%v)",
+			sourcePath, sourceVersionID, targetPath, err)
 	}()
 
 	// operation at source object
@@ -1956,9 +2398,22 @@ func (v *Volume) CopyFile(sv *Volume, sourcePath, targetPath, metaDirective stri
 		sMode      os.FileMode
 		sInodeInfo *proto.InodeInfo
 	)
-	if _, sInode, _, sMode, err = sv.recursiveLookupTarget(sourcePath); err != nil {
-		log.LogErrorf("CopyFile: look up source path fail, source path(%v) err(%v)", sourcePath, err)
-		return
+	if sourceVersionID == "" {
+		if _, sInode, _, sMode, err = sv.recursiveLookupTarget(sourcePath); err != nil {
+			log.LogErrorf("CopyFile: look up source path fail, source path(%v) err(%v)", sourcePath, err)
+			return
+		}
+	} else {
+		var sVersion *proto.ObjectVersionInfo
+		if sVersion, err = sv.lookupObjectVersion(sourcePath, sourceVersionID); err != nil {
+			log.LogErrorf("CopyFile: look up source version fail, source path(%v) version(%v) err(%v)",
+				sourcePath, sourceVersionID, err)
+			return
+		}
+		if sVersion.DeleteMarker {
+			return nil, errDeleteMarker
+		}
+		sInode, sMode = sVersion.Inode, DefaultFileMode
 	}
 	if sInodeInfo, err = sv.mw.InodeGet_ll(sInode); err != nil {
 		log.LogErrorf("CopyFile: get source path inode info fail, source path(%v) err(%v)", sourcePath, err)
@@ -1968,6 +2423,12 @@ func (v *Volume) CopyFile(sv *Volume, sourcePath, targetPath, metaDirective stri
 		log.LogErrorf("CopyFile: copy source path file size greater than 5GB, source path(%v), target path(%v)", sourcePath, targetPath)
 		return nil, syscall.EFBIG
 	}
+	// the source data is decrypted while copying
+	var sCipher *stream.FileCipher
+	if sCipher, err = sv.objectCipher(sInode, sourceSSE); err != nil {
+		log.LogErrorf("CopyFile: load source cipher fail, source path(%v) err(%v)", sourcePath, err)
+		return
+	}
 	if err = sv.ec.OpenStream(sInode); err != nil {
 		log.LogErrorf("CopyFile: open source path stream fail, source path(%v) source path inode(%v) err(%v)",
 			sourcePath, sInode, err)
@@ -1982,7 +2443,9 @@ func (v *Volume) CopyFile(sv *Volume, sourcePath, targetPath, metaDirective stri
 
 	// if source path is same with target path, just reset file metadata
 	// source path is same with target path, and metadata directive is not 'REPLACE', object node do nothing
-	if targetPath == sourcePath {
+	// A former version copied to the same path becomes the new current version.
+	// The object is rewritten if it is copied to itself with the encryption changed.
+	if targetPath == sourcePath && sourceVersionID == "" && (opt == nil || opt.SSE == nil) {
 		if metaDirective != MetadataDirectiveReplace {
 			log.LogInfof("CopyFile: target path is equal with source path, object node do nothing, source path(%v) target path(%v) err(%v)",
 				sourcePath, targetPath, err)
@@ -2101,9 +2564,10 @@ func (v *Volume) CopyFile(sv *Volume, sourcePath, targetPath, metaDirective stri
 	if tInodeInfo, err = v.mw.InodeCreate_ll(uint32(sMode), 0, 0, nil); err != nil {
 		return
 	}
+	var applied bool
 	defer func() {
 		// An error has caused the entire process to fail. Delete the inode and release the written data.
-		if err != nil {
+		if err != nil && !applied {
 			log.LogWarnf("CopyFile: unlink target temp inode: volume(%v) path(%v) inode(%v) ",
 				v.name, targetPath, tInodeInfo.Inode)
 			_, _ = v.mw.InodeUnlink_ll(tInodeInfo.Inode)
@@ -2112,6 +2576,20 @@ func (v *Volume) CopyFile(sv *Volume, sourcePath, targetPath, metaDirective stri
 			_ = v.mw.Evict(tInodeInfo.Inode)
 		}
 	}()
+	// the target data is encrypted with a new data key if the encryption is requested
+	var (
+		tDataKey   []byte
+		tSSEXAttrs map[string]string
+	)
+	if opt != nil && opt.SSE != nil {
+		if tDataKey, tSSEXAttrs, err = v.newObjectKey(opt.SSE); err != nil {
+			return
+		}
+		if err = v.ec.SetFileKey(tInodeInfo.Inode, tDataKey); err != nil {
+			return
+		}
+		defer v.ec.RemoveFileKey(tInodeInfo.Inode)
+	}
 	if err = v.ec.OpenStream(tInodeInfo.Inode); err != nil {
 		return
 	}
@@ -2148,6 +2626,11 @@ func (v *Volume) CopyFile(sv *Volume, sourcePath, targetPath, metaDirective stri
 			return
 		}
 		if readN > 0 {
+			if sCipher != nil {
+				if err = sv.ec.Decrypt(sInode, sCipher, readOffset, buf[:readN]); err != nil {
+					return
+				}
+			}
 			if writeN, err = v.ec.Write(tInodeInfo.Inode, writeOffset, buf[:readN], 0); err != nil {
 				log.LogErrorf("CopyFile: write target path from source fail, volume(%v) path(%v) inode(%v) target offset(%v) err(%v)",
 					v.name, targetPath, tInodeInfo.Inode, writeOffset, err)
@@ -2170,6 +2653,9 @@ func (v *Volume) CopyFile(sv *Volume, sourcePath, targetPath, metaDirective stri
 		return
 	}
 	md5Value = hex.EncodeToString(md5Hash.Sum(nil))
+	if opt != nil && opt.SSE.Customer() {
+		md5Value = customerETag(md5Value, tDataKey)
+	}
 	log.LogDebugf("Audit: copy file: write file finished, volume(%v), path(%v), etag(%v)", v.name, targetPath, md5Value)
 
 	var finalInode *proto.InodeInfo
@@ -2190,6 +2676,14 @@ func (v *Volume) CopyFile(sv *Volume, sourcePath, targetPath, metaDirective stri
 			v.name, targetPath, tInodeInfo.Inode, XAttrKeyOSSETag, md5Value, err)
 		return
 	}
+	// Save target file encrypted data key
+	for key, value := range tSSEXAttrs {
+		if err = v.mw.XAttrSet_ll(tInodeInfo.Inode, []byte(key), []byte(value)); err != nil {
+			log.LogErrorf("CopyFile: store target file data key fail: volume(%v) path(%v) inode(%v) key(%v) err(%v)",
+				v.name, targetPath, tInodeInfo.Inode, key, err)
+			return
+		}
+	}
 
 	// copy source file metadata to write target file metadata
 	if metaDirective != MetadataDirectiveReplace {
@@ -2215,7 +2709,7 @@ func (v *Volume) CopyFile(sv *Volume, sourcePath, targetPath, metaDirective stri
 		// set tar xattr
 		if len(xattrs) > 0 {
 			for xk, xv := range xattrs[0].XAttrs {
-				if xk == XAttrKeyOSSETag {
+				if xk == XAttrKeyOSSETag || xk == XAttrKeyOSSReplicationStatus || isSSEXAttrKey(xk) || isObjectLockXAttrKey(xk) {
 					continue
 				}
 				if err = v.mw.XAttrSet_ll(tInodeInfo.Inode, []byte(xk), []byte(xv)); err != nil {
@@ -2277,12 +2771,21 @@ func (v *Volume) CopyFile(sv *Volume, sourcePath, targetPath, metaDirective stri
 		ETag:       md5Value,
 		Inode:      tInodeInfo.Inode,
 	}
+	setSSEFileInfo(info, tSSEXAttrs)
 
 	// apply new inode to dentry
-	err = v.applyInodeToDEntry(tParentId, tLastName, tInodeInfo.Inode)
+	info.VersionID, err = v.applyInodeToObject(tParentId, tLastName, tInodeInfo.Inode)
 	if err != nil {
 		log.LogErrorf("CopyFile: apply inode to new dentry fail: path(%v) parentID(%v) name(%v) inode(%v) err(%v)",
 			targetPath, tParentId, tLastName, tInodeInfo.Inode, err)
+		return
+	}
+	applied = true
+	if opt != nil && opt.ObjectLock != nil {
+		if err = v.setObjectLock(tInodeInfo.Inode, opt.ObjectLock); err != nil {
+			return
+		}
+		info.Retention, info.LegalHold = opt.ObjectLock.Retention, opt.ObjectLock.LegalHold
 	}
 	return
 }
@@ -2337,6 +2840,7 @@ func NewVolume(config *VolumeConfig) (*Volume, error) {
 		ec:         extentClient,
 		name:       config.Volume,
 		store:      config.Store,
+		keyClient:  config.KeyClient,
 		createTime: metaWrapper.VolCreateTime(),
 		closeCh:    make(chan struct{}),
 		onAsyncTaskError: func(err error) {
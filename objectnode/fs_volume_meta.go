@@ -20,9 +20,21 @@ type ossMetaLoader interface {
 	loadPolicy() (p *Policy, err error)
 	loadACL() (p *AccessControlPolicy, err error)
 	loadCors() (cors *CORSConfiguration, err error)
+	loadVersioning() (versioning *VersioningConfiguration, err error)
+	loadLifecycle() (lifecycle *LifecycleConfiguration, err error)
+	loadEncryption() (encryption *ServerSideEncryptionConfiguration, err error)
+	loadWebsite() (website *WebsiteConfiguration, err error)
+	loadReplication() (replication *ReplicationConfiguration, err error)
+	loadNotification() (notification *NotificationConfiguration, err error)
 	storePolicy(p *Policy)
 	storeACL(p *AccessControlPolicy)
 	storeCors(cors *CORSConfiguration)
+	storeVersioning(versioning *VersioningConfiguration)
+	storeLifecycle(lifecycle *LifecycleConfiguration)
+	storeEncryption(encryption *ServerSideEncryptionConfiguration)
+	storeWebsite(website *WebsiteConfiguration)
+	storeReplication(replication *ReplicationConfiguration)
+	storeNotification(notification *NotificationConfiguration)
 }
 
 type strictMetaLoader struct {
@@ -35,12 +47,24 @@ type cacheMetaLoader struct {
 
 // OSSMeta is bucket policy and ACL metadata.
 type OSSMeta struct {
-	policy     *Policy
-	acl        *AccessControlPolicy
-	corsConfig *CORSConfiguration
-	policyLock sync.RWMutex
-	aclLock    sync.RWMutex
-	corsLock   sync.RWMutex
+	policy       *Policy
+	acl          *AccessControlPolicy
+	corsConfig   *CORSConfiguration
+	versioning   *VersioningConfiguration
+	lifecycle    *LifecycleConfiguration
+	encryption   *ServerSideEncryptionConfiguration
+	website      *WebsiteConfiguration
+	replication  *ReplicationConfiguration
+	notification *NotificationConfiguration
+	policyLock   sync.RWMutex
+	aclLock      sync.RWMutex
+	corsLock     sync.RWMutex
+	verLock      sync.RWMutex
+	lcLock       sync.RWMutex
+	encLock      sync.RWMutex
+	webLock      sync.RWMutex
+	replLock     sync.RWMutex
+	notifyLock   sync.RWMutex
 }
 
 func (c *cacheMetaLoader) loadPolicy() (p *Policy, err error) {
@@ -85,6 +109,90 @@ func (c *cacheMetaLoader) storeCors(cors *CORSConfiguration) {
 	return
 }
 
+func (c *cacheMetaLoader) loadVersioning() (versioning *VersioningConfiguration, err error) {
+	c.om.verLock.RLock()
+	versioning = c.om.versioning
+	c.om.verLock.RUnlock()
+	return
+}
+
+func (c *cacheMetaLoader) storeVersioning(versioning *VersioningConfiguration) {
+	c.om.verLock.Lock()
+	c.om.versioning = versioning
+	c.om.verLock.Unlock()
+	return
+}
+
+func (c *cacheMetaLoader) loadLifecycle() (lifecycle *LifecycleConfiguration, err error) {
+	c.om.lcLock.RLock()
+	lifecycle = c.om.lifecycle
+	c.om.lcLock.RUnlock()
+	return
+}
+
+func (c *cacheMetaLoader) storeLifecycle(lifecycle *LifecycleConfiguration) {
+	c.om.lcLock.Lock()
+	c.om.lifecycle = lifecycle
+	c.om.lcLock.Unlock()
+	return
+}
+
+func (c *cacheMetaLoader) loadEncryption() (encryption *ServerSideEncryptionConfiguration, err error) {
+	c.om.encLock.RLock()
+	encryption = c.om.encryption
+	c.om.encLock.RUnlock()
+	return
+}
+
+func (c *cacheMetaLoader) storeEncryption(encryption *ServerSideEncryptionConfiguration) {
+	c.om.encLock.Lock()
+	c.om.encryption = encryption
+	c.om.encLock.Unlock()
+	return
+}
+
+func (c *cacheMetaLoader) loadWebsite() (website *WebsiteConfiguration, err error) {
+	c.om.webLock.RLock()
+	website = c.om.website
+	c.om.webLock.RUnlock()
+	return
+}
+
+func (c *cacheMetaLoader) storeWebsite(website *WebsiteConfiguration) {
+	c.om.webLock.Lock()
+	c.om.website = website
+	c.om.webLock.Unlock()
+	return
+}
+
+func (c *cacheMetaLoader) loadReplication() (replication *ReplicationConfiguration, err error) {
+	c.om.replLock.RLock()
+	replication = c.om.replication
+	c.om.replLock.RUnlock()
+	return
+}
+
+func (c *cacheMetaLoader) storeReplication(replication *ReplicationConfiguration) {
+	c.om.replLock.Lock()
+	c.om.replication = replication
+	c.om.replLock.Unlock()
+	return
+}
+
+func (c *cacheMetaLoader) loadNotification() (notification *NotificationConfiguration, err error) {
+	c.om.notifyLock.RLock()
+	notification = c.om.notification
+	c.om.notifyLock.RUnlock()
+	return
+}
+
+func (c *cacheMetaLoader) storeNotification(notification *NotificationConfiguration) {
+	c.om.notifyLock.Lock()
+	c.om.notification = notification
+	c.om.notifyLock.Unlock()
+	return
+}
+
 func (s *strictMetaLoader) loadPolicy() (p *Policy, err error) {
 	return s.v.loadBucketPolicy()
 }
@@ -102,3 +210,39 @@ func (s *strictMetaLoader) loadCors() (cors *CORSConfiguration, err error) {
 }
 
 func (s *strictMetaLoader) storeCors(cors *CORSConfiguration) {}
+
+func (s *strictMetaLoader) loadVersioning() (versioning *VersioningConfiguration, err error) {
+	return s.v.loadBucketVersioning()
+}
+
+func (s *strictMetaLoader) storeVersioning(versioning *VersioningConfiguration) {}
+
+func (s *strictMetaLoader) loadLifecycle() (lifecycle *LifecycleConfiguration, err error) {
+	return s.v.loadBucketLifecycle()
+}
+
+func (s *strictMetaLoader) storeLifecycle(lifecycle *LifecycleConfiguration) {}
+
+func (s *strictMetaLoader) loadEncryption() (encryption *ServerSideEncryptionConfiguration, err error) {
+	return s.v.loadBucketEncryption()
+}
+
+func (s *strictMetaLoader) storeEncryption(encryption *ServerSideEncryptionConfiguration) {}
+
+func (s *strictMetaLoader) loadWebsite() (website *WebsiteConfiguration, err error) {
+	return s.v.loadBucketWebsite()
+}
+
+func (s *strictMetaLoader) storeWebsite(website *WebsiteConfiguration) {}
+
+func (s *strictMetaLoader) loadReplication() (replication *ReplicationConfiguration, err error) {
+	return s.v.loadBucketReplication()
+}
+
+func (s *strictMetaLoader) storeReplication(replication *ReplicationConfiguration) {}
+
+func (s *strictMetaLoader) loadNotification() (notification *NotificationConfiguration, err error) {
+	return s.v.loadBucketNotification()
+}
+
+func (s *strictMetaLoader) storeNotification(notification *NotificationConfiguration) {}
@@ -0,0 +1,202 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"syscall"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// The object lock is kept in the extend attributes of the inode of the object,
+// which are checked by the meta node as well, so that a locked object can not
+// be removed or overwritten through the clients mounting the same volume either.
+// The retention in governance mode is removed by the object node before the
+// object is removed with the governance retention bypassed, since the meta node
+// knows nothing about the permissions of the users.
+
+var objectLockXAttrKeys = []string{proto.RetentionXAttrKey, proto.LegalHoldXAttrKey}
+
+func isObjectLockXAttrKey(key string) bool {
+	return key == proto.RetentionXAttrKey || key == proto.LegalHoldXAttrKey
+}
+
+// objectInode returns the inode of the specified version of the object,
+// or the current version if the version ID is empty.
+func (v *Volume) objectInode(path, versionID string) (inode uint64, err error) {
+	if versionID == "" {
+		var mode uint32
+		var parentID uint64
+		var name string
+		if parentID, name, err = v.lookupObjectParent(path); err != nil {
+			if err == syscall.EINVAL {
+				err = syscall.ENOENT
+			}
+			return
+		}
+		if inode, mode, err = v.mw.Lookup_ll(parentID, name); err != nil {
+			return
+		}
+		if !proto.IsRegular(mode) {
+			return 0, syscall.ENOENT
+		}
+		return
+	}
+	var version *proto.ObjectVersionInfo
+	if version, err = v.lookupObjectVersion(path, versionID); err != nil {
+		return
+	}
+	if version.DeleteMarker {
+		return 0, errDeleteMarker
+	}
+	return version.Inode, nil
+}
+
+func (v *Volume) getObjectLock(inode uint64) (retention *proto.Retention, legalHold string, err error) {
+	var xattrs []*proto.XAttrInfo
+	if xattrs, err = v.mw.BatchGetXAttr([]uint64{inode}, objectLockXAttrKeys); err != nil {
+		log.LogErrorf("getObjectLock: meta get xattr fail: volume(%v) inode(%v) err(%v)", v.name, inode, err)
+		return
+	}
+	if len(xattrs) == 0 || xattrs[0].Inode != inode {
+		return
+	}
+	if raw := xattrs[0].XAttrs[proto.RetentionXAttrKey]; raw != "" {
+		if retention, err = proto.ParseRetention(raw); err != nil {
+			return
+		}
+	}
+	legalHold = xattrs[0].XAttrs[proto.LegalHoldXAttrKey]
+	return
+}
+
+// GetObjectLock returns the retention and the legal hold status of the object.
+func (v *Volume) GetObjectLock(path, versionID string) (retention *proto.Retention, legalHold string, err error) {
+	var inode uint64
+	if inode, err = v.objectInode(path, versionID); err != nil {
+		return
+	}
+	return v.getObjectLock(inode)
+}
+
+// PutObjectRetention changes the retention of the object. The retention in
+// compliance mode can only be extended, and so does the retention in governance
+// mode unless it is bypassed.
+func (v *Volume) PutObjectRetention(path, versionID string, retention *proto.Retention, bypassGovernance bool) (err error) {
+	var inode uint64
+	if inode, err = v.objectInode(path, versionID); err != nil {
+		return
+	}
+	var old *proto.Retention
+	if old, _, err = v.getObjectLock(inode); err != nil {
+		return
+	}
+	if old.Active(time.Now()) && (retention.RetainUntil < old.RetainUntil ||
+		old.Mode == proto.RetentionModeCompliance && retention.Mode != proto.RetentionModeCompliance) {
+		if old.Mode == proto.RetentionModeCompliance || !bypassGovernance {
+			return errObjectLocked
+		}
+	}
+	if err = v.mw.XAttrSet_ll(inode, []byte(proto.RetentionXAttrKey), []byte(retention.String())); err != nil {
+		log.LogErrorf("PutObjectRetention: meta set xattr fail: volume(%v) path(%v) inode(%v) err(%v)",
+			v.name, path, inode, err)
+		if err == syscall.EPERM {
+			err = errObjectLocked
+		}
+		return
+	}
+	log.LogInfof("Audit: PutObjectRetention: volume(%v) path(%v) versionID(%v) inode(%v) retention(%v) old(%v)",
+		v.name, path, versionID, inode, retention, old)
+	return
+}
+
+// PutObjectLegalHold changes the legal hold status of the object.
+func (v *Volume) PutObjectLegalHold(path, versionID, status string) (err error) {
+	var inode uint64
+	if inode, err = v.objectInode(path, versionID); err != nil {
+		return
+	}
+	if err = v.mw.XAttrSet_ll(inode, []byte(proto.LegalHoldXAttrKey), []byte(status)); err != nil {
+		log.LogErrorf("PutObjectLegalHold: meta set xattr fail: volume(%v) path(%v) inode(%v) err(%v)",
+			v.name, path, inode, err)
+		return
+	}
+	log.LogInfof("Audit: PutObjectLegalHold: volume(%v) path(%v) versionID(%v) inode(%v) status(%v)",
+		v.name, path, versionID, inode, status)
+	return
+}
+
+// checkObjectLock returns errObjectLocked if the inode of an object which is
+// going to be removed is locked. If the governance retention is bypassed, it is
+// removed so that the inode can be unlinked.
+func (v *Volume) checkObjectLock(inode uint64, bypassGovernance bool) (err error) {
+	var retention *proto.Retention
+	var legalHold string
+	if retention, legalHold, err = v.getObjectLock(inode); err != nil {
+		return
+	}
+	if legalHold == proto.LegalHoldOn {
+		return errObjectLocked
+	}
+	if !retention.Active(time.Now()) {
+		return
+	}
+	if retention.Mode == proto.RetentionModeCompliance || !bypassGovernance {
+		return errObjectLocked
+	}
+	if err = v.mw.XAttrDel_ll(inode, proto.RetentionXAttrKey); err != nil {
+		log.LogErrorf("checkObjectLock: meta remove xattr fail: volume(%v) inode(%v) err(%v)", v.name, inode, err)
+		return
+	}
+	log.LogInfof("Audit: bypass governance retention: volume(%v) inode(%v) retention(%v)", v.name, inode, retention)
+	return
+}
+
+// checkVersionLock checks the object lock of the version which is going to be
+// removed or overwritten.
+func (v *Volume) checkVersionLock(versions []*proto.ObjectVersionInfo, versionID string, bypassGovernance bool) error {
+	for _, version := range versions {
+		if version.VersionID == versionID && !version.DeleteMarker {
+			return v.checkObjectLock(version.Inode, bypassGovernance)
+		}
+	}
+	return nil
+}
+
+// setObjectLock applies the object lock to the inode of a new object, which
+// is done after the inode is applied to the object, since a locked inode can
+// not be released if it fails.
+func (v *Volume) setObjectLock(inode uint64, opt *ObjectLockOption) (err error) {
+	if opt == nil {
+		return
+	}
+	if opt.Retention != nil {
+		if err = v.mw.XAttrSet_ll(inode, []byte(proto.RetentionXAttrKey), []byte(opt.Retention.String())); err != nil {
+			log.LogErrorf("setObjectLock: meta set xattr fail: volume(%v) inode(%v) key(%v) err(%v)",
+				v.name, inode, proto.RetentionXAttrKey, err)
+			return
+		}
+	}
+	if opt.LegalHold != "" {
+		if err = v.mw.XAttrSet_ll(inode, []byte(proto.LegalHoldXAttrKey), []byte(opt.LegalHold)); err != nil {
+			log.LogErrorf("setObjectLock: meta set xattr fail: volume(%v) inode(%v) key(%v) err(%v)",
+				v.name, inode, proto.LegalHoldXAttrKey, err)
+			return
+		}
+	}
+	return
+}
@@ -0,0 +1,45 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"io"
+
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// The replication status is kept in the extend attributes of the inode of the
+// object, so that the status of an overwritten object is never updated by the
+// replication of the previous data.
+
+// setReplicationStatus sets the replication status of the object with the inode.
+func (v *Volume) setReplicationStatus(inode uint64, status string) (err error) {
+	if err = v.mw.XAttrSet_ll(inode, []byte(XAttrKeyOSSReplicationStatus), []byte(status)); err != nil {
+		log.LogErrorf("setReplicationStatus: store replication status fail: volume(%v) inode(%v) status(%v) err(%v)",
+			v.name, inode, status, err)
+	}
+	return
+}
+
+// objectReader returns a reader of the range of the data of the object with the inode,
+// which must be closed after read. The objects encrypted with the customer keys can not
+// be read since the keys are unknown.
+func (v *Volume) objectReader(path string, inode uint64, offset, size uint64) io.ReadCloser {
+	var reader, writer = io.Pipe()
+	go func() {
+		_ = writer.CloseWithError(v.readInode(path, inode, writer, offset, size, nil))
+	}()
+	return reader
+}
@@ -0,0 +1,123 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"crypto/rand"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/sdk/data/stream"
+	"github.com/chubaofs/chubaofs/util/cryptoutil"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// The data of an encrypted object is encrypted by the extent client with the
+// data key of the object, the same as the files encrypted by the clients.
+// The data key encrypted by the authnode is kept in the same extend attribute
+// as the files, so the objects encrypted with the managed keys are readable
+// by the clients with the encryption enabled, and vice versa. The data key of
+// an object encrypted with a customer key is encrypted by the customer key.
+// The parts of a multipart upload are encrypted with the data key of the
+// upload, and every extent key of them keeps the nonce of its own, so the
+// extent keys of the parts are moved to the completed object as they are.
+
+// The extend attributes of an object about the encryption.
+var sseXAttrKeys = []string{proto.CryptKeyXAttrKey, XAttrKeyOSSSSECustomerKey, XAttrKeyOSSSSECustomerKeyMD5}
+
+func isSSEXAttrKey(key string) bool {
+	for _, sseKey := range sseXAttrKeys {
+		if key == sseKey {
+			return true
+		}
+	}
+	return false
+}
+
+// newObjectKey generates the data key of a new object, and returns it with
+// the extend attributes keeping the data key encrypted.
+func (v *Volume) newObjectKey(sse *SSEOption) (key []byte, xattrs map[string]string, err error) {
+	if !sse.Customer() {
+		var cipherKey string
+		if key, cipherKey, err = v.keyClient.GenDataKey(v.name); err != nil {
+			log.LogErrorf("newObjectKey: generate data key fail: volume(%v) err(%v)", v.name, err)
+			return
+		}
+		return key, map[string]string{proto.CryptKeyXAttrKey: cipherKey}, nil
+	}
+	key = make([]byte, sseKeySize)
+	if _, err = rand.Read(key); err != nil {
+		return
+	}
+	var wrappedKey string
+	if wrappedKey, err = cryptoutil.EncodeMessage(key, sse.CustomerKey); err != nil {
+		return
+	}
+	xattrs = map[string]string{
+		XAttrKeyOSSSSECustomerKey:    wrappedKey,
+		XAttrKeyOSSSSECustomerKeyMD5: sse.CustomerKeyMD5,
+	}
+	return
+}
+
+// unwrapObjectKey returns the data key kept in the extend attributes of an
+// object, or nil if the object is not encrypted. The customer key must be
+// provided if and only if the object is encrypted with a customer key.
+func (v *Volume) unwrapObjectKey(xattrs map[string]string, sse *SSEOption) (key []byte, err error) {
+	if cipherKey := xattrs[proto.CryptKeyXAttrKey]; cipherKey != "" {
+		if sse.Customer() {
+			return nil, errSSENotApplicable
+		}
+		if key, err = v.keyClient.DecryptDataKey(v.name, cipherKey); err != nil {
+			log.LogErrorf("unwrapObjectKey: decrypt data key fail: volume(%v) err(%v)", v.name, err)
+			return
+		}
+		return
+	}
+	if wrappedKey := xattrs[XAttrKeyOSSSSECustomerKey]; wrappedKey != "" {
+		if !sse.Customer() {
+			return nil, errSSECustomerKeyRequired
+		}
+		if xattrs[XAttrKeyOSSSSECustomerKeyMD5] != sse.CustomerKeyMD5 {
+			return nil, errSSECustomerKeyMismatch
+		}
+		if key, err = cryptoutil.DecodeMessage(wrappedKey, sse.CustomerKey); err != nil {
+			return nil, errSSECustomerKeyMismatch
+		}
+		return
+	}
+	if sse.Customer() {
+		return nil, errSSENotApplicable
+	}
+	return nil, nil
+}
+
+// objectCipher returns the cipher of the object, or nil if the object is not
+// encrypted.
+func (v *Volume) objectCipher(inode uint64, sse *SSEOption) (c *stream.FileCipher, err error) {
+	var xattrs []*proto.XAttrInfo
+	if xattrs, err = v.mw.BatchGetXAttr([]uint64{inode}, sseXAttrKeys); err != nil {
+		log.LogErrorf("objectCipher: meta get xattr fail: volume(%v) inode(%v) err(%v)", v.name, inode, err)
+		return
+	}
+	var values map[string]string
+	if len(xattrs) > 0 && xattrs[0].Inode == inode {
+		values = xattrs[0].XAttrs
+	}
+	var key []byte
+	if key, err = v.unwrapObjectKey(values, sse); err != nil || key == nil {
+		return
+	}
+	return stream.NewFileCipher(key)
+}
@@ -13,3 +13,163 @@
 // permissions and limitations under the License.
 
 package objectnode
+
+import (
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+// testListTree is an in-memory directory tree for the list scanner.
+type testListTree struct {
+	children map[uint64][]proto.Dentry
+	nextIno  uint64
+	reads    map[uint64]int // number of reads of each directory
+}
+
+func newTestListTree(paths ...string) *testListTree {
+	var tree = &testListTree{children: make(map[uint64][]proto.Dentry), nextIno: proto.RootIno, reads: make(map[uint64]int)}
+	for _, path := range paths {
+		var parent = proto.RootIno
+		var names = strings.Split(strings.TrimSuffix(path, pathSep), pathSep)
+		for i, name := range names {
+			var isDir = i < len(names)-1 || strings.HasSuffix(path, pathSep)
+			if inode, _, err := tree.lookup(parent, name); err == nil {
+				parent = inode
+				continue
+			}
+			tree.nextIno++
+			var mode = uint32(0644)
+			if isDir {
+				mode = uint32(os.ModeDir | 0755)
+			}
+			tree.children[parent] = append(tree.children[parent], proto.Dentry{Name: name, Inode: tree.nextIno, Type: mode})
+			sort.Slice(tree.children[parent], func(i, j int) bool {
+				return tree.children[parent][i].Name < tree.children[parent][j].Name
+			})
+			parent = tree.nextIno
+		}
+	}
+	return tree
+}
+
+func (t *testListTree) lookup(parentID uint64, name string) (uint64, uint32, error) {
+	for _, child := range t.children[parentID] {
+		if child.Name == name {
+			return child.Inode, child.Type, nil
+		}
+	}
+	return 0, 0, syscall.ENOENT
+}
+
+func (t *testListTree) readDir(parentID uint64, marker, prefix string, limit uint64) (children []proto.Dentry, infos []*proto.InodeInfo, next string, err error) {
+	t.reads[parentID]++
+	for _, child := range t.children[parentID] {
+		if child.Name <= marker || !strings.HasPrefix(child.Name, prefix) {
+			continue
+		}
+		if uint64(len(children)) >= limit {
+			return children, nil, children[len(children)-1].Name, nil
+		}
+		children = append(children, child)
+	}
+	return children, nil, "", nil
+}
+
+func (t *testListTree) list(prefix, marker, delimiter string, maxKeys uint64) (keys []string, nextMarker string) {
+	var scanner = &listScanner{
+		prefix:     prefix,
+		marker:     marker,
+		delimiter:  delimiter,
+		maxKeys:    maxKeys,
+		readDir:    t.readDir,
+		lookup:     t.lookup,
+		inodeInfos: make(map[uint64]*proto.InodeInfo),
+		prefixMap:  PrefixMap(make(map[string]struct{})),
+	}
+	var parentID, dirs = uint64(proto.RootIno), []string{}
+	if idx := strings.LastIndex(prefix, pathSep); idx >= 0 {
+		for _, name := range strings.Split(prefix[:idx], pathSep) {
+			parentID, _, _ = t.lookup(parentID, name)
+			dirs = append(dirs, name)
+		}
+	}
+	if err := scanner.scan(parentID, dirs); err != nil {
+		panic(err)
+	}
+	for _, info := range scanner.fileInfos {
+		keys = append(keys, info.Path)
+	}
+	for _, commonPrefix := range scanner.prefixMap.Prefixes() {
+		keys = append(keys, commonPrefix)
+	}
+	return keys, scanner.nextMarker
+}
+
+func TestListScanner(t *testing.T) {
+	var tree = newTestListTree("a/x", "a/y/z", "b", "c/", "c/d/e", "d")
+
+	// the keys are listed in order
+	keys, nextMarker := tree.list("", "", "", 100)
+	var expected = []string{"a/", "a/x", "a/y/", "a/y/z", "b", "c/", "c/d/", "c/d/e", "d"}
+	if !reflect.DeepEqual(keys, expected) || nextMarker != "" {
+		t.Fatalf("unexpected keys: %v %v", keys, nextMarker)
+	}
+	checkListPages(t, tree, expected)
+
+	// the directories whose keys are all before the marker are not read
+	tree.reads = make(map[uint64]int)
+	if keys, _ = tree.list("", "c/d/e", "", 100); !reflect.DeepEqual(keys, []string{"c/d/e", "d"}) {
+		t.Fatalf("unexpected keys after marker: %v", keys)
+	}
+	if inode, _, _ := tree.lookup(proto.RootIno, "a"); tree.reads[inode] != 0 {
+		t.Fatalf("directory before marker should not be read")
+	}
+
+	if keys, _ = tree.list("", "", pathSep, 100); !reflect.DeepEqual(keys, []string{"b", "d", "a/", "c/"}) {
+		t.Fatalf("unexpected keys with delimiter: %v", keys)
+	}
+	if keys, _ = tree.list("c/", "", pathSep, 100); !reflect.DeepEqual(keys, []string{"c/", "c/d/"}) {
+		t.Fatalf("unexpected keys with prefix: %v", keys)
+	}
+
+	// the keys in the directory named with the prefix of the marker may be after the marker
+	tree = newTestListTree("a-b", "a/x")
+	if keys, _ = tree.list("", "a-b", "", 100); !reflect.DeepEqual(keys, []string{"a-b", "a/", "a/x"}) {
+		t.Fatalf("unexpected keys after marker: %v", keys)
+	}
+
+	// the siblings named with the name of the directory followed by the characters less than the
+	// path separator are listed before the keys in the directory
+	tree = newTestListTree("a/x", "a-b", "a-/y", "a.c", "a0", "c")
+	expected = []string{"a-/", "a-/y", "a-b", "a.c", "a/", "a/x", "a0", "c"}
+	if keys, _ = tree.list("", "", "", 100); !reflect.DeepEqual(keys, expected) {
+		t.Fatalf("unexpected keys with siblings: %v", keys)
+	}
+	checkListPages(t, tree, expected)
+}
+
+// checkListPages checks that paging with the inclusive next marker returns every key once in order.
+func checkListPages(t *testing.T, tree *testListTree, expected []string) {
+	for maxKeys := uint64(1); maxKeys <= uint64(len(expected)); maxKeys++ {
+		var all []string
+		for marker := ""; ; {
+			keys, nextMarker := tree.list("", marker, "", maxKeys)
+			if len(all) > len(expected) {
+				t.Fatalf("paging of %v does not end: %v", maxKeys, all)
+			}
+			all = append(all, keys...)
+			if marker = nextMarker; marker == "" {
+				break
+			}
+		}
+		if !reflect.DeepEqual(all, expected) {
+			t.Fatalf("unexpected keys of pages of %v: %v", maxKeys, all)
+		}
+	}
+}
@@ -0,0 +1,670 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util"
+	"github.com/chubaofs/chubaofs/util/errors"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// errDeleteMarker is returned if the requested version of the object is a delete marker.
+var errDeleteMarker = errors.New("version is a delete marker")
+
+// FSVersion is a version of the object returned by ListObjectVersions.
+type FSVersion struct {
+	*FSFileInfo
+	IsLatest     bool
+	DeleteMarker bool
+}
+
+type ListObjectVersionsOption struct {
+	Prefix          string
+	Delimiter       string
+	KeyMarker       string
+	VersionIDMarker string
+	MaxKeys         uint64
+}
+
+type ListObjectVersionsResult struct {
+	Versions            []*FSVersion
+	CommonPrefixes      []string
+	NextKeyMarker       string
+	NextVersionIDMarker string
+	Truncated           bool
+}
+
+// Version IDs are ordered by the time they are created.
+func newVersionID() string {
+	return fmt.Sprintf("%016x%08x", time.Now().UnixNano(), rand.Uint32())
+}
+
+func containsVersionInode(versions []*proto.ObjectVersionInfo, inode uint64) bool {
+	for _, version := range versions {
+		if !version.DeleteMarker && version.Inode == inode {
+			return true
+		}
+	}
+	return false
+}
+
+// lookupObjectParent returns the parent directory and the name of the object
+// without making any directory. Directories are not versioned, so EINVAL is
+// returned for the path of a directory.
+func (v *Volume) lookupObjectParent(path string) (parentID uint64, name string, err error) {
+	var pathItems = NewPathIterator(path).ToSlice()
+	if len(pathItems) == 0 || pathItems[len(pathItems)-1].IsDirectory {
+		return 0, "", syscall.EINVAL
+	}
+	var dirs = make([]string, 0, len(pathItems)-1)
+	for _, pathItem := range pathItems[:len(pathItems)-1] {
+		dirs = append(dirs, pathItem.Name)
+	}
+	if parentID, err = v.lookupDirectories(dirs, false); err == syscall.EEXIST {
+		// a file is found in the middle of the path
+		err = syscall.ENOENT
+	}
+	if err != nil {
+		return
+	}
+	name = pathItems[len(pathItems)-1].Name
+	return
+}
+
+// findVersion returns the version of the ID in the versions, or nil if there
+// is no such version.
+func findVersion(versions []*proto.ObjectVersionInfo, versionID string) *proto.ObjectVersionInfo {
+	for _, version := range versions {
+		if version.VersionID == versionID {
+			return version
+		}
+	}
+	return nil
+}
+
+// releaseInode unlinks and evicts the inode of a removed version of the object,
+// unless it is still kept by another version or linked by the dentry of it.
+func (v *Volume) releaseInode(parentID uint64, name string, inode uint64) {
+	versions, err := v.mw.GetObjectVersions_ll(parentID, name)
+	if err != nil {
+		log.LogWarnf("releaseInode: get versions fail: volume(%v) parentID(%v) name(%v) inode(%v) err(%v)",
+			v.name, parentID, name, inode, err)
+		return
+	}
+	if containsVersionInode(versions, inode) {
+		return
+	}
+	var current uint64
+	if current, _, err = v.mw.Lookup_ll(parentID, name); err != nil && err != syscall.ENOENT {
+		log.LogWarnf("releaseInode: lookup fail: volume(%v) parentID(%v) name(%v) inode(%v) err(%v)",
+			v.name, parentID, name, inode, err)
+		return
+	}
+	if current == inode {
+		return
+	}
+	log.LogWarnf("releaseInode: unlink inode: volume(%v) inode(%v)", v.name, inode)
+	if _, err := v.mw.InodeUnlink_ll(inode); err != nil {
+		log.LogWarnf("releaseInode: unlink inode fail: volume(%v) inode(%v) err(%v)", v.name, inode, err)
+	}
+	log.LogWarnf("releaseInode: evict inode: volume(%v) inode(%v)", v.name, inode)
+	if err := v.mw.Evict(inode); err != nil {
+		log.LogWarnf("releaseInode: evict inode fail: volume(%v) inode(%v) err(%v)", v.name, inode, err)
+	}
+}
+
+// recordCurrentVersion records the inode of the dentry as the null version of the
+// object if it is not kept by any version yet, which is the case of the object
+// written before the versioning is configured.
+// It returns the versions of the object from the newest to the oldest.
+func (v *Volume) recordCurrentVersion(parentID uint64, name string, inode uint64) (versions []*proto.ObjectVersionInfo, err error) {
+	if versions, err = v.mw.GetObjectVersions_ll(parentID, name); err != nil {
+		return
+	}
+	if inode == 0 || containsVersionInode(versions, inode) {
+		return
+	}
+	var inoInfo *proto.InodeInfo
+	if inoInfo, err = v.mw.InodeGet_ll(inode); err != nil {
+		return
+	}
+	var null = &proto.ObjectVersionInfo{
+		ParentID:   parentID,
+		Name:       name,
+		VersionID:  proto.NullVersionID,
+		Inode:      inode,
+		ModifyTime: inoInfo.ModifyTime.UnixNano(),
+	}
+	var replaced *proto.ObjectVersionInfo
+	if replaced, err = v.mw.PutObjectVersion_ll(null); err != nil {
+		log.LogErrorf("recordCurrentVersion: put null version fail: volume(%v) parentID(%v) name(%v) inode(%v) err(%v)",
+			v.name, parentID, name, inode, err)
+		return
+	}
+	if replaced != nil && !replaced.DeleteMarker && replaced.Inode != inode {
+		v.releaseInode(parentID, name, replaced.Inode)
+	}
+	return v.mw.GetObjectVersions_ll(parentID, name)
+}
+
+// applyInodeToObject makes the inode the content of the object. If the versioning
+// is configured for the bucket, the replaced inode is kept by a version of the
+// object instead of being released, and the ID of the new version is returned.
+func (v *Volume) applyInodeToObject(parentID uint64, name string, inode uint64) (versionID string, err error) {
+	var versioning *VersioningConfiguration
+	if versioning, err = v.metaLoader.loadVersioning(); err != nil {
+		return
+	}
+	if !versioning.Configured() {
+		err = v.applyInodeToDEntry(parentID, name, inode)
+		return
+	}
+
+	var existInode uint64
+	var existMode uint32
+	existInode, existMode, err = v.mw.Lookup_ll(parentID, name)
+	if err != nil && err != syscall.ENOENT {
+		log.LogErrorf("applyInodeToObject: meta lookup fail: parentID(%v) name(%v) err(%v)", parentID, name, err)
+		return
+	}
+	var exist = err == nil
+	if exist && os.FileMode(existMode).IsDir() {
+		log.LogErrorf("applyInodeToObject: target mode conflict: parentID(%v) name(%v) mode(%v)",
+			parentID, name, os.FileMode(existMode).String())
+		err = syscall.EINVAL
+		return
+	}
+	var versions []*proto.ObjectVersionInfo
+	if versions, err = v.recordCurrentVersion(parentID, name, existInode); err != nil {
+		return
+	}
+
+	versionID = proto.NullVersionID
+	if versioning.Enabled() {
+		versionID = newVersionID()
+	} else if err = v.checkVersionLock(versions, proto.NullVersionID, false); err != nil {
+		// the null version is overwritten
+		return
+	}
+	var version = &proto.ObjectVersionInfo{
+		ParentID:   parentID,
+		Name:       name,
+		VersionID:  versionID,
+		Inode:      inode,
+		ModifyTime: time.Now().UnixNano(),
+	}
+	// The inode replaced by the new one is still kept by its version,
+	// so the dentry is updated without unlinking it.
+	var replaced = findVersion(versions, versionID)
+	if err = v.mw.PutObjectVersionWithDentry_ll(version, existInode, DefaultFileMode); err != nil {
+		log.LogErrorf("applyInodeToObject: put version fail: volume(%v) version(%v) existInode(%v) err(%v)",
+			v.name, version, existInode, err)
+		return
+	}
+	// The null version is overwritten while the versioning is suspended.
+	if replaced != nil && !replaced.DeleteMarker && replaced.Inode != inode {
+		v.releaseInode(parentID, name, replaced.Inode)
+	}
+	return
+}
+
+// DeleteObject deletes the object of the path. If the version ID is specified, the
+// version is removed permanently. Otherwise, a delete marker is created as the
+// newest version of the object if the versioning is configured for the bucket.
+// It returns the ID of the removed version or the created delete marker, and
+// whether it is a delete marker. The object protected by the object lock can not
+// be removed, unless it is only protected by a bypassed governance retention.
+//
+// Notes:
+// Same as DeletePath, this method will not return syscall.ENOENT error.
+func (v *Volume) DeleteObject(path, versionID string, bypassGovernance bool) (deletedVersionID string, deleteMarker bool, err error) {
+	defer func() {
+		// Audit behavior
+		log.LogInfof("Audit: DeleteObject: volume(%v) path(%v) versionID(%v) deletedVersionID(%v) deleteMarker(%v) err(%v)",
+			v.name, path, versionID, deletedVersionID, deleteMarker, err)
+	}()
+	var versioning *VersioningConfiguration
+	if versioning, err = v.metaLoader.loadVersioning(); err != nil {
+		return
+	}
+	if versionID == "" && !versioning.Configured() {
+		err = v.deletePath(path, bypassGovernance)
+		return
+	}
+
+	var parentID uint64
+	var name string
+	parentID, name, err = v.lookupObjectParent(path)
+	if err == syscall.EINVAL {
+		// directories are not versioned
+		err = v.DeletePath(path)
+		return
+	}
+	if err == syscall.ENOENT {
+		err = nil
+		return
+	}
+	if err != nil {
+		return
+	}
+
+	var inode uint64
+	var mode uint32
+	if inode, mode, err = v.mw.Lookup_ll(parentID, name); err != nil && err != syscall.ENOENT {
+		return
+	}
+	err = nil
+	if os.FileMode(mode).IsDir() {
+		return
+	}
+	var versions []*proto.ObjectVersionInfo
+	if versions, err = v.recordCurrentVersion(parentID, name, inode); err != nil {
+		return
+	}
+
+	if versionID != "" {
+		if err = v.checkVersionLock(versions, versionID, bypassGovernance); err != nil {
+			return
+		}
+		var deleted = findVersion(versions, versionID)
+		if deleted == nil {
+			return versionID, false, nil
+		}
+		// The dentry is pointed to the newest of the remaining versions, or
+		// removed if it is a delete marker or no version is left.
+		var current uint64
+		for _, version := range versions {
+			if version != deleted {
+				if !version.DeleteMarker {
+					current = version.Inode
+				}
+				break
+			}
+		}
+		if err = v.mw.DeleteObjectVersionWithDentry_ll(parentID, name, versionID, inode, current, DefaultFileMode); err != nil {
+			log.LogErrorf("DeleteObject: delete version fail: volume(%v) parentID(%v) name(%v) versionID(%v) inode(%v) current(%v) err(%v)",
+				v.name, parentID, name, versionID, inode, current, err)
+			return
+		}
+		if !deleted.DeleteMarker {
+			v.releaseInode(parentID, name, deleted.Inode)
+		}
+		return versionID, deleted.DeleteMarker, nil
+	}
+
+	deletedVersionID = proto.NullVersionID
+	if versioning.Enabled() {
+		deletedVersionID = newVersionID()
+	} else if err = v.checkVersionLock(versions, proto.NullVersionID, bypassGovernance); err != nil {
+		// the null version is replaced by the delete marker
+		return
+	}
+	var marker = &proto.ObjectVersionInfo{
+		ParentID:     parentID,
+		Name:         name,
+		VersionID:    deletedVersionID,
+		ModifyTime:   time.Now().UnixNano(),
+		DeleteMarker: true,
+	}
+	var replaced = findVersion(versions, deletedVersionID)
+	if err = v.mw.PutObjectVersionWithDentry_ll(marker, inode, DefaultFileMode); err != nil {
+		log.LogErrorf("DeleteObject: put delete marker fail: volume(%v) marker(%v) inode(%v) err(%v)",
+			v.name, marker, inode, err)
+		return
+	}
+	// The null version is overwritten by the delete marker while the versioning is suspended.
+	if replaced != nil && !replaced.DeleteMarker {
+		v.releaseInode(parentID, name, replaced.Inode)
+	}
+	return deletedVersionID, true, nil
+}
+
+// lookupObjectVersion returns the specified version of the object,
+// or syscall.ENOENT if there is no such version.
+func (v *Volume) lookupObjectVersion(path, versionID string) (version *proto.ObjectVersionInfo, err error) {
+	var parentID uint64
+	var name string
+	if parentID, name, err = v.lookupObjectParent(path); err != nil {
+		if err == syscall.EINVAL {
+			err = syscall.ENOENT
+		}
+		return
+	}
+	var versions []*proto.ObjectVersionInfo
+	if versions, err = v.mw.GetObjectVersions_ll(parentID, name); err != nil {
+		return
+	}
+	for _, version = range versions {
+		if version.VersionID == versionID {
+			return
+		}
+	}
+	if versionID == proto.NullVersionID {
+		// The object written before the versioning is configured is the null version.
+		var inode uint64
+		var mode uint32
+		inode, mode, err = v.mw.Lookup_ll(parentID, name)
+		if err != nil {
+			return nil, err
+		}
+		if !os.FileMode(mode).IsDir() && !containsVersionInode(versions, inode) {
+			return &proto.ObjectVersionInfo{
+				ParentID:  parentID,
+				Name:      name,
+				VersionID: proto.NullVersionID,
+				Inode:     inode,
+			}, nil
+		}
+	}
+	return nil, syscall.ENOENT
+}
+
+// objectVersionID returns the version ID of the inode of the object,
+// or an empty string if the versioning has never been configured.
+func (v *Volume) objectVersionID(parentID uint64, name string, inode uint64) (versionID string, err error) {
+	var versioning *VersioningConfiguration
+	if versioning, err = v.metaLoader.loadVersioning(); err != nil || !versioning.Configured() {
+		return
+	}
+	var versions []*proto.ObjectVersionInfo
+	if versions, err = v.mw.GetObjectVersions_ll(parentID, name); err != nil {
+		return
+	}
+	for _, version := range versions {
+		if !version.DeleteMarker && version.Inode == inode {
+			return version.VersionID, nil
+		}
+	}
+	return proto.NullVersionID, nil
+}
+
+// ObjectVersionMeta returns the meta of the specified version of the object,
+// or the current version if the version ID is empty.
+func (v *Volume) ObjectVersionMeta(path, versionID string) (info *FSFileInfo, err error) {
+	if versionID == "" {
+		return v.ObjectMeta(path)
+	}
+	var version *proto.ObjectVersionInfo
+	if version, err = v.lookupObjectVersion(path, versionID); err != nil {
+		return
+	}
+	if version.DeleteMarker {
+		return nil, errDeleteMarker
+	}
+	var inoInfo *proto.InodeInfo
+	if inoInfo, err = v.mw.InodeGet_ll(version.Inode); err != nil {
+		log.LogErrorf("ObjectVersionMeta: get inode fail: volume(%v) path(%v) version(%v) err(%v)",
+			v.name, path, version, err)
+		return
+	}
+	if info, err = v.inodeMeta(path, inoInfo); err != nil {
+		return
+	}
+	info.VersionID = version.VersionID
+	return
+}
+
+// ReadFileVersion reads the data of the specified version of the object,
+// or the current version if the version ID is empty. The customer key must
+// be specified if the object is encrypted with a customer key.
+func (v *Volume) ReadFileVersion(path, versionID string, writer io.Writer, offset, size uint64, sse *SSEOption) error {
+	if versionID == "" {
+		return v.readFile(path, writer, offset, size, sse)
+	}
+	version, err := v.lookupObjectVersion(path, versionID)
+	if err != nil {
+		return err
+	}
+	if version.DeleteMarker {
+		return errDeleteMarker
+	}
+	return v.readInode(path, version.Inode, writer, offset, size, sse)
+}
+
+// ListObjectVersions lists the versions of the objects which match the prefix and
+// delimiter criteria, ordered by the key and then from the newest to the oldest.
+// Directories are listed as objects with only the null version.
+func (v *Volume) ListObjectVersions(opt *ListObjectVersionsOption) (result *ListObjectVersionsResult, err error) {
+	result = &ListObjectVersionsResult{}
+
+	var parentID uint64
+	var dirs []string
+	parentID, dirs, err = v.findParentId(opt.Prefix)
+	// The method returns an ENOENT error, indicating that there
+	// are no files or directories matching the prefix.
+	if err == syscall.ENOENT {
+		return result, nil
+	}
+	if err != nil {
+		log.LogErrorf("ListObjectVersions: find parent ID fail, prefix(%v) err(%v)", opt.Prefix, err)
+		return nil, err
+	}
+
+	var scanner = &versionScanner{
+		v:         v,
+		opt:       opt,
+		result:    result,
+		prefixMap: PrefixMap(make(map[string]struct{})),
+	}
+	if err = scanner.scan(parentID, dirs); err != nil {
+		log.LogErrorf("ListObjectVersions: volume scan fail: volume(%v) err(%v)", v.name, err)
+		return nil, err
+	}
+	if !result.Truncated {
+		result.NextKeyMarker = ""
+		result.NextVersionIDMarker = ""
+	}
+	result.CommonPrefixes = scanner.prefixMap.Prefixes()
+
+	// Supplementary file information of the versions except the delete markers.
+	var fileInfos = make([]*FSFileInfo, 0, len(result.Versions))
+	for _, version := range result.Versions {
+		if !version.DeleteMarker {
+			fileInfos = append(fileInfos, version.FSFileInfo)
+		}
+	}
+	if err = v.supplyListFileInfo(fileInfos, nil); err != nil {
+		log.LogErrorf("ListObjectVersions: supply list file info fail: volume(%v) err(%v)", v.name, err)
+		return nil, err
+	}
+
+	log.LogDebugf("ListObjectVersions: volume(%v) opt(%v) versions(%v) prefixes(%v) truncated(%v)",
+		v.name, *opt, len(result.Versions), len(result.CommonPrefixes), result.Truncated)
+	return
+}
+
+// versionScanner scans the directories recursively in the same way as recursiveScan,
+// but lists every version of the objects. Both of the key marker and the version ID
+// marker point to the last entry returned, which is excluded from the result.
+type versionScanner struct {
+	v         *Volume
+	opt       *ListObjectVersionsOption
+	result    *ListObjectVersionsResult
+	prefixMap PrefixMap
+	rc        uint64
+}
+
+func (s *versionScanner) add(version *FSVersion) bool {
+	if s.rc >= s.opt.MaxKeys {
+		s.result.Truncated = true
+		return false
+	}
+	s.result.Versions = append(s.result.Versions, version)
+	s.result.NextKeyMarker = version.Path
+	s.result.NextVersionIDMarker = version.VersionID
+	s.rc++
+	return true
+}
+
+func (s *versionScanner) addPrefix(prefix string) bool {
+	if s.rc >= s.opt.MaxKeys {
+		s.result.Truncated = true
+		return false
+	}
+	s.prefixMap.AddPrefix(prefix)
+	s.result.NextKeyMarker = prefix
+	s.result.NextVersionIDMarker = ""
+	s.rc++
+	return true
+}
+
+func (s *versionScanner) scan(parentID uint64, dirs []string) (err error) {
+	var (
+		prefix    = s.opt.Prefix
+		delimiter = s.opt.Delimiter
+		marker    = s.opt.KeyMarker
+	)
+
+	var currentPath = strings.Join(dirs, pathSep) + pathSep
+	if len(dirs) > 0 && prefix != "" && strings.HasSuffix(currentPath, prefix) && currentPath > marker {
+		// Directory entries that meet the exact prefix match are returned as well.
+		if !s.add(newDirectoryVersion(currentPath, parentID)) {
+			return
+		}
+	}
+
+	var children []proto.Dentry
+	children, err = s.v.mw.ReadDir_ll(parentID)
+	if err == syscall.ENOENT {
+		return nil
+	}
+	if err != nil {
+		return
+	}
+	var versions []*proto.ObjectVersionInfo
+	if versions, err = s.v.mw.ListObjectVersions_ll(parentID, "", 0); err != nil {
+		return
+	}
+
+	// Merge the names of the dentries and the versions, the latter of which
+	// includes the objects whose newest versions are delete markers.
+	var (
+		names      = make([]string, 0, len(children))
+		dentries   = make(map[string]proto.Dentry, len(children))
+		versionMap = make(map[string][]*proto.ObjectVersionInfo)
+	)
+	for _, child := range children {
+		names = append(names, child.Name)
+		dentries[child.Name] = child
+	}
+	for _, version := range versions {
+		if _, found := versionMap[version.Name]; !found {
+			if _, found = dentries[version.Name]; !found {
+				names = append(names, version.Name)
+			}
+		}
+		versionMap[version.Name] = append(versionMap[version.Name], version)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		child, hasDentry := dentries[name]
+		var isDir = hasDentry && os.FileMode(child.Type).IsDir()
+		var path = strings.Join(append(dirs, name), pathSep)
+		if isDir {
+			path += pathSep
+		}
+		if prefix != "" && !strings.HasPrefix(path, prefix) {
+			continue
+		}
+
+		if delimiter != "" {
+			var nonPrefixPart = strings.Replace(path, prefix, "", 1)
+			if idx := strings.Index(nonPrefixPart, delimiter); idx >= 0 {
+				var commonPrefix = prefix + util.SubString(nonPrefixPart, 0, idx) + delimiter
+				if s.prefixMap.contain(commonPrefix) || (marker != "" && commonPrefix <= marker) {
+					continue
+				}
+				if !s.addPrefix(commonPrefix) {
+					return
+				}
+				continue
+			}
+		}
+
+		if isDir {
+			if path > marker {
+				if !s.add(newDirectoryVersion(path, child.Inode)) {
+					return
+				}
+			} else if !strings.HasPrefix(marker, path) {
+				continue
+			}
+			if err = s.scan(child.Inode, append(dirs, name)); err != nil || s.result.Truncated {
+				return
+			}
+			continue
+		}
+
+		if !s.scanObject(path, child.Inode, versionMap[name]) {
+			return
+		}
+	}
+	return
+}
+
+// scanObject adds the versions of the object, the inode of which is given if the
+// dentry exists. It returns false if the result is truncated.
+func (s *versionScanner) scanObject(path string, inode uint64, versions []*proto.ObjectVersionInfo) bool {
+	if s.opt.KeyMarker != "" && path < s.opt.KeyMarker {
+		return true
+	}
+	if inode != 0 && !containsVersionInode(versions, inode) {
+		// The object written before the versioning is configured is the null version.
+		versions = append([]*proto.ObjectVersionInfo{{VersionID: proto.NullVersionID, Inode: inode}}, versions...)
+	}
+	var skipping = path == s.opt.KeyMarker
+	for i, version := range versions {
+		if skipping {
+			skipping = version.VersionID != s.opt.VersionIDMarker
+			continue
+		}
+		var fsVersion = &FSVersion{
+			FSFileInfo: &FSFileInfo{
+				Path:       path,
+				Inode:      version.Inode,
+				ModifyTime: time.Unix(0, version.ModifyTime),
+				VersionID:  version.VersionID,
+			},
+			IsLatest:     i == 0,
+			DeleteMarker: version.DeleteMarker,
+		}
+		if !s.add(fsVersion) {
+			return false
+		}
+	}
+	return true
+}
+
+func newDirectoryVersion(path string, inode uint64) *FSVersion {
+	return &FSVersion{
+		FSFileInfo: &FSFileInfo{
+			Path:      path,
+			Inode:     inode,
+			VersionID: proto.NullVersionID,
+		},
+		IsLatest: true,
+	}
+}
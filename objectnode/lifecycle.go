@@ -0,0 +1,275 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+// https://docs.aws.amazon.com/AmazonS3/latest/dev/object-lifecycle-mgmt.html
+
+import (
+	"encoding/xml"
+	"time"
+
+	"github.com/chubaofs/chubaofs/util/errors"
+)
+
+const (
+	LifecycleStatusEnabled  = "Enabled"
+	LifecycleStatusDisabled = "Disabled"
+
+	lifecycleMaxRules  = 1000
+	lifecycleMaxIDSize = 255
+	lifecycleDay       = 24 * time.Hour
+)
+
+var (
+	errLifecycleTransitionUnsupported = errors.New("lifecycle transition is not supported")
+)
+
+type LifecycleConfiguration struct {
+	XMLName xml.Name         `xml:"LifecycleConfiguration"`
+	Rules   []*LifecycleRule `xml:"Rule"`
+}
+
+type LifecycleRule struct {
+	ID     string           `xml:"ID,omitempty"`
+	Status string           `xml:"Status"`
+	Prefix string           `xml:"Prefix,omitempty"` // deprecated, replaced by Filter
+	Filter *LifecycleFilter `xml:"Filter,omitempty"`
+
+	Expiration                     *LifecycleExpiration            `xml:"Expiration,omitempty"`
+	Transitions                    []*LifecycleTransition          `xml:"Transition,omitempty"`
+	AbortIncompleteMultipartUpload *AbortIncompleteMultipartUpload `xml:"AbortIncompleteMultipartUpload,omitempty"`
+}
+
+type LifecycleFilter struct {
+	Prefix string                `xml:"Prefix,omitempty"`
+	Tag    *Tag                  `xml:"Tag,omitempty"`
+	And    *LifecycleFilterAndOp `xml:"And,omitempty"`
+}
+
+type LifecycleFilterAndOp struct {
+	Prefix string `xml:"Prefix,omitempty"`
+	Tags   []Tag  `xml:"Tag,omitempty"`
+}
+
+type LifecycleExpiration struct {
+	Days int    `xml:"Days,omitempty"`
+	Date string `xml:"Date,omitempty"` // ISO 8601 format at midnight UTC
+}
+
+// LifecycleTransition is only parsed to be rejected, the objects of a volume
+// have no storage class to transition to.
+type LifecycleTransition struct {
+	Days         int    `xml:"Days,omitempty"`
+	Date         string `xml:"Date,omitempty"`
+	StorageClass string `xml:"StorageClass,omitempty"`
+}
+
+type AbortIncompleteMultipartUpload struct {
+	DaysAfterInitiation int `xml:"DaysAfterInitiation"`
+}
+
+// Enabled returns true if the rule is applied by the lifecycle scanner.
+func (rule *LifecycleRule) Enabled() bool {
+	return rule.Status == LifecycleStatusEnabled
+}
+
+// KeyPrefix returns the prefix of the keys of the objects the rule applies to.
+func (rule *LifecycleRule) KeyPrefix() string {
+	if rule.Filter == nil {
+		return rule.Prefix
+	}
+	if rule.Filter.And != nil {
+		return rule.Filter.And.Prefix
+	}
+	return rule.Filter.Prefix
+}
+
+// Tags returns the tags an object must have for the rule to apply to it.
+func (rule *LifecycleRule) Tags() []Tag {
+	if rule.Filter == nil {
+		return nil
+	}
+	if rule.Filter.And != nil {
+		return rule.Filter.And.Tags
+	}
+	if rule.Filter.Tag != nil {
+		return []Tag{*rule.Filter.Tag}
+	}
+	return nil
+}
+
+// MatchTags returns true if all of the tags of the rule are set on the object.
+func (rule *LifecycleRule) MatchTags(tagging *Tagging) bool {
+	for _, tag := range rule.Tags() {
+		var found bool
+		if tagging != nil {
+			for _, t := range tagging.TagSet {
+				if t.Key == tag.Key && t.Value == tag.Value {
+					found = true
+					break
+				}
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func (rule *LifecycleRule) validate() error {
+	if len(rule.ID) > lifecycleMaxIDSize {
+		return errors.New("rule ID is too long")
+	}
+	if rule.Status != LifecycleStatusEnabled && rule.Status != LifecycleStatusDisabled {
+		return errors.New("invalid rule status")
+	}
+	if rule.Filter != nil {
+		if rule.Prefix != "" {
+			return errors.New("both prefix and filter are specified")
+		}
+		if rule.Filter.And != nil && (rule.Filter.Tag != nil || rule.Filter.Prefix != "") {
+			return errors.New("filter and operator combined with other predicates")
+		}
+		if rule.Filter.Tag != nil && rule.Filter.Prefix != "" {
+			return errors.New("filter with both prefix and tag")
+		}
+	}
+	if len(rule.Transitions) > 0 {
+		return errLifecycleTransitionUnsupported
+	}
+	if rule.Expiration == nil && rule.AbortIncompleteMultipartUpload == nil {
+		return errors.New("no lifecycle action is specified")
+	}
+	if rule.Expiration != nil {
+		if err := rule.Expiration.validate(); err != nil {
+			return err
+		}
+	}
+	if rule.AbortIncompleteMultipartUpload != nil {
+		if rule.AbortIncompleteMultipartUpload.DaysAfterInitiation <= 0 {
+			return errors.New("days after initiation must be a positive integer")
+		}
+		if len(rule.Tags()) > 0 {
+			return errors.New("abort incomplete multipart upload can not be specified with tags")
+		}
+	}
+	return nil
+}
+
+func (expiration *LifecycleExpiration) validate() error {
+	if (expiration.Days > 0) == (expiration.Date != "") {
+		return errors.New("either days or date of the expiration must be specified")
+	}
+	if expiration.Days < 0 {
+		return errors.New("expiration days must be a positive integer")
+	}
+	if expiration.Date != "" {
+		date, err := time.Parse(time.RFC3339, expiration.Date)
+		if err != nil {
+			return errors.New("invalid expiration date")
+		}
+		if !date.Equal(date.Truncate(lifecycleDay)) {
+			return errors.New("expiration date must be at midnight UTC")
+		}
+	}
+	return nil
+}
+
+// Expired returns true if the object modified at the given time is expired.
+// Same as S3, the expiration time computed from the days is rounded up to the
+// next midnight UTC.
+func (expiration *LifecycleExpiration) Expired(modifyTime, now time.Time) bool {
+	if expiration.Date != "" {
+		date, err := time.Parse(time.RFC3339, expiration.Date)
+		return err == nil && !now.Before(date)
+	}
+	if expiration.Days <= 0 {
+		return false
+	}
+	expireTime := modifyTime.Add(time.Duration(expiration.Days) * lifecycleDay)
+	if midnight := expireTime.Truncate(lifecycleDay); midnight.Before(expireTime) {
+		expireTime = midnight.Add(lifecycleDay)
+	}
+	return !now.Before(expireTime)
+}
+
+// Expired returns true if the multipart upload initiated at the given time is
+// to be aborted.
+func (abort *AbortIncompleteMultipartUpload) Expired(initTime, now time.Time) bool {
+	return abort.DaysAfterInitiation > 0 &&
+		!now.Before(initTime.Add(time.Duration(abort.DaysAfterInitiation)*lifecycleDay))
+}
+
+func (lifecycle *LifecycleConfiguration) validate() error {
+	if len(lifecycle.Rules) == 0 || len(lifecycle.Rules) > lifecycleMaxRules {
+		return errors.New("invalid number of lifecycle rules")
+	}
+	var ids = make(map[string]struct{})
+	for _, rule := range lifecycle.Rules {
+		if rule == nil {
+			return errors.New("empty lifecycle rule")
+		}
+		if rule.ID != "" {
+			if _, exist := ids[rule.ID]; exist {
+				return errors.New("duplicated lifecycle rule ID")
+			}
+			ids[rule.ID] = struct{}{}
+		}
+		if err := rule.validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EnabledRules returns the rules applied by the lifecycle scanner.
+func (lifecycle *LifecycleConfiguration) EnabledRules() []*LifecycleRule {
+	if lifecycle == nil {
+		return nil
+	}
+	var rules = make([]*LifecycleRule, 0, len(lifecycle.Rules))
+	for _, rule := range lifecycle.Rules {
+		if rule.Enabled() {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+func parseLifecycleConfig(bytes []byte) (lifecycle *LifecycleConfiguration, err error) {
+	lifecycle = &LifecycleConfiguration{}
+	if err = xml.Unmarshal(bytes, lifecycle); err != nil {
+		return
+	}
+	if err = lifecycle.validate(); err != nil {
+		return nil, err
+	}
+	return
+}
+
+func storeBucketLifecycle(bytes []byte, vol *Volume) (err error) {
+	if err = vol.store.Put(vol.name, bucketRootPath, XAttrKeyOSSLifecycle, bytes); err != nil {
+		return
+	}
+	return nil
+}
+
+func deleteBucketLifecycle(vol *Volume) (err error) {
+	if err = vol.store.Delete(vol.name, bucketRootPath, XAttrKeyOSSLifecycle); err != nil {
+		return err
+	}
+	return nil
+}
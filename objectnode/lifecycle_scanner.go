@@ -0,0 +1,256 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"math"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/sdk/master"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+const (
+	defaultLifecycleScanInterval = time.Hour
+	lifecycleScanBatch           = 1000
+)
+
+// LifecycleScanner applies the lifecycle rules of the buckets periodically.
+// Every object node runs a scanner, and the buckets are scanned by the scanner
+// which holds the lifecycle lock of the bucket, so that a bucket is scanned by
+// only one object node at a time. The lock is a lease renewed by the meta
+// wrapper, and it is taken over by another object node once the holder is down.
+type LifecycleScanner struct {
+	mc       *master.MasterClient
+	vm       *VolumeManager
+	interval time.Duration
+
+	closeOnce sync.Once
+	closeCh   chan struct{}
+}
+
+func NewLifecycleScanner(mc *master.MasterClient, vm *VolumeManager, interval time.Duration) *LifecycleScanner {
+	if interval <= 0 {
+		interval = defaultLifecycleScanInterval
+	}
+	return &LifecycleScanner{
+		mc:       mc,
+		vm:       vm,
+		interval: interval,
+		closeCh:  make(chan struct{}),
+	}
+}
+
+func (s *LifecycleScanner) Start() {
+	go s.run()
+}
+
+func (s *LifecycleScanner) Stop() {
+	s.closeOnce.Do(func() {
+		close(s.closeCh)
+	})
+}
+
+func (s *LifecycleScanner) stopped() bool {
+	select {
+	case <-s.closeCh:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s *LifecycleScanner) run() {
+	t := time.NewTimer(s.interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			s.scan()
+			t.Reset(s.interval)
+		case <-s.closeCh:
+			return
+		}
+	}
+}
+
+func (s *LifecycleScanner) scan() {
+	vols, err := s.mc.AdminAPI().ListVols("")
+	if err != nil {
+		log.LogErrorf("LifecycleScanner: list volumes fail: err(%v)", err)
+		return
+	}
+	for _, volInfo := range vols {
+		if s.stopped() {
+			return
+		}
+		s.scanVolume(volInfo.Name)
+	}
+}
+
+func (s *LifecycleScanner) scanVolume(volName string) {
+	vol, err := s.vm.Volume(volName)
+	if err != nil {
+		log.LogWarnf("LifecycleScanner: load volume fail: volume(%v) err(%v)", volName, err)
+		return
+	}
+	var lifecycle *LifecycleConfiguration
+	if lifecycle, err = vol.metaLoader.loadLifecycle(); err != nil {
+		log.LogErrorf("LifecycleScanner: load lifecycle fail: volume(%v) err(%v)", volName, err)
+		return
+	}
+	var rules = lifecycle.EnabledRules()
+	if len(rules) == 0 {
+		return
+	}
+	if !vol.tryLifecycleLock() {
+		log.LogDebugf("LifecycleScanner: volume is scanned by others: volume(%v)", volName)
+		return
+	}
+
+	var now = time.Now()
+	log.LogInfof("LifecycleScanner: scan volume: volume(%v) rules(%v)", volName, len(rules))
+	for _, rule := range rules {
+		if s.stopped() {
+			return
+		}
+		if rule.Expiration != nil {
+			if err = s.expireObjects(vol, rule, now); err != nil {
+				log.LogErrorf("LifecycleScanner: expire objects fail: volume(%v) rule(%v) err(%v)",
+					volName, rule.ID, err)
+			}
+		}
+		if rule.AbortIncompleteMultipartUpload != nil {
+			if err = s.abortMultiparts(vol, rule, now); err != nil {
+				log.LogErrorf("LifecycleScanner: abort multipart uploads fail: volume(%v) rule(%v) err(%v)",
+					volName, rule.ID, err)
+			}
+		}
+	}
+}
+
+// expireObjects deletes the current versions of the expired objects matching
+// the rule. A delete marker is created instead if the versioning is configured
+// for the bucket, the same as deleted by a user.
+func (s *LifecycleScanner) expireObjects(vol *Volume, rule *LifecycleRule, now time.Time) (err error) {
+	var opt = &ListFilesV2Option{
+		Prefix:  rule.KeyPrefix(),
+		MaxKeys: lifecycleScanBatch,
+	}
+	var result *ListFilesV2Result
+	for !s.stopped() {
+		if result, err = vol.ListFilesV2(opt); err != nil {
+			return
+		}
+		for _, info := range result.Files {
+			if info.Mode.IsDir() || !rule.Expiration.Expired(info.ModifyTime, now) {
+				continue
+			}
+			if len(rule.Tags()) > 0 {
+				var tagging *Tagging
+				if tagging, err = vol.objectTagging(info.Path); err == syscall.ENOENT {
+					err = nil
+					continue
+				}
+				if err != nil {
+					return
+				}
+				if !rule.MatchTags(tagging) {
+					continue
+				}
+			}
+			if _, _, err = vol.DeleteObject(info.Path, "", false); err == errObjectLocked {
+				// the locked objects are kept until they are unlocked
+				err = nil
+				continue
+			}
+			if err != nil {
+				return
+			}
+			log.LogInfof("LifecycleScanner: object expired: volume(%v) rule(%v) path(%v) mtime(%v)",
+				vol.Name(), rule.ID, info.Path, info.ModifyTime)
+		}
+		if !result.Truncated {
+			return
+		}
+		opt.ContToken = result.NextToken
+	}
+	return
+}
+
+// abortMultiparts aborts the multipart uploads matching the rule which are
+// initiated earlier than the days of the rule.
+func (s *LifecycleScanner) abortMultiparts(vol *Volume, rule *LifecycleRule, now time.Time) (err error) {
+	var prefix = rule.KeyPrefix()
+	var keyMarker, idMarker = prefix, ""
+	var sessions []*proto.MultipartInfo
+	for !s.stopped() {
+		if sessions, err = vol.mw.ListMultipart_ll(prefix, "", keyMarker, idMarker, lifecycleScanBatch); err != nil {
+			return
+		}
+		var next bool
+		for _, session := range sessions {
+			// the marker is included by the result
+			if session.Path == keyMarker && session.ID == idMarker {
+				continue
+			}
+			next = true
+			keyMarker, idMarker = session.Path, session.ID
+			if !rule.AbortIncompleteMultipartUpload.Expired(session.InitTime, now) {
+				continue
+			}
+			if err = vol.AbortMultipart(session.Path, session.ID); err != nil && err != syscall.ENOENT {
+				return
+			}
+			err = nil
+			log.LogInfof("LifecycleScanner: multipart upload aborted: volume(%v) rule(%v) path(%v) multipartID(%v) initTime(%v)",
+				vol.Name(), rule.ID, session.Path, session.ID, session.InitTime)
+		}
+		if !next {
+			return
+		}
+	}
+	return
+}
+
+// tryLifecycleLock tries to acquire the lifecycle lock of the bucket, which
+// is a write lock on the whole range of the root directory. The directories
+// can not be locked by the POSIX write locks of the users, so the lock does
+// not conflict with them. It returns true if the lock is held by the node.
+func (v *Volume) tryLifecycleLock() bool {
+	var lock = &proto.LockInfo{
+		Type:  proto.LockWrite,
+		Start: 0,
+		End:   math.MaxUint64,
+	}
+	if err := v.mw.SetLock_ll(proto.RootIno, lock); err != nil {
+		if err != syscall.EAGAIN {
+			log.LogWarnf("tryLifecycleLock: set lock fail: volume(%v) err(%v)", v.name, err)
+		}
+		return false
+	}
+	return true
+}
+
+func (v *Volume) objectTagging(path string) (tagging *Tagging, err error) {
+	var xattrInfo *proto.XAttrInfo
+	if xattrInfo, err = v.GetXAttr(path, XAttrKeyOSSTagging); err != nil {
+		return
+	}
+	return ParseTagging(string(xattrInfo.Get(XAttrKeyOSSTagging)))
+}
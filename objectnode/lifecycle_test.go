@@ -0,0 +1,105 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLifecycle_Parse(t *testing.T) {
+	var raw = `<LifecycleConfiguration>
+  <Rule>
+    <ID>expire-logs</ID>
+    <Filter>
+      <And>
+        <Prefix>logs/</Prefix>
+        <Tag><Key>class</Key><Value>tmp</Value></Tag>
+      </And>
+    </Filter>
+    <Status>Enabled</Status>
+    <Expiration><Days>30</Days></Expiration>
+  </Rule>
+  <Rule>
+    <ID>abort-uploads</ID>
+    <Prefix>uploads/</Prefix>
+    <Status>Disabled</Status>
+    <AbortIncompleteMultipartUpload><DaysAfterInitiation>7</DaysAfterInitiation></AbortIncompleteMultipartUpload>
+  </Rule>
+</LifecycleConfiguration>`
+	lifecycle, err := parseLifecycleConfig([]byte(raw))
+	if err != nil {
+		t.Fatalf("parse lifecycle fail: err(%v)", err)
+	}
+	rules := lifecycle.EnabledRules()
+	if len(rules) != 1 || rules[0].ID != "expire-logs" {
+		t.Fatalf("unexpected enabled rules: %v", rules)
+	}
+	if prefix := rules[0].KeyPrefix(); prefix != "logs/" {
+		t.Fatalf("unexpected prefix: %v", prefix)
+	}
+	if prefix := lifecycle.Rules[1].KeyPrefix(); prefix != "uploads/" {
+		t.Fatalf("unexpected prefix: %v", prefix)
+	}
+	if !rules[0].MatchTags(&Tagging{TagSet: []Tag{{Key: "a", Value: "b"}, {Key: "class", Value: "tmp"}}}) {
+		t.Fatalf("tags should match")
+	}
+	if rules[0].MatchTags(&Tagging{TagSet: []Tag{{Key: "class", Value: "hot"}}}) || rules[0].MatchTags(nil) {
+		t.Fatalf("tags should not match")
+	}
+
+	var invalids = []string{
+		`<LifecycleConfiguration></LifecycleConfiguration>`,
+		`<LifecycleConfiguration><Rule><Status>Enabled</Status></Rule></LifecycleConfiguration>`,
+		`<LifecycleConfiguration><Rule><Status>On</Status><Expiration><Days>1</Days></Expiration></Rule></LifecycleConfiguration>`,
+		`<LifecycleConfiguration><Rule><Status>Enabled</Status><Expiration><Days>1</Days><Date>2020-01-01T00:00:00Z</Date></Expiration></Rule></LifecycleConfiguration>`,
+		`<LifecycleConfiguration><Rule><Status>Enabled</Status><Expiration><Date>2020-01-01T08:00:00Z</Date></Expiration></Rule></LifecycleConfiguration>`,
+		`<LifecycleConfiguration><Rule><Status>Enabled</Status><Filter><Tag><Key>k</Key><Value>v</Value></Tag></Filter><AbortIncompleteMultipartUpload><DaysAfterInitiation>1</DaysAfterInitiation></AbortIncompleteMultipartUpload></Rule></LifecycleConfiguration>`,
+	}
+	for _, invalid := range invalids {
+		if _, err = parseLifecycleConfig([]byte(invalid)); err == nil {
+			t.Fatalf("invalid lifecycle is parsed: %v", invalid)
+		}
+	}
+	var transition = `<LifecycleConfiguration><Rule><Status>Enabled</Status><Transition><Days>1</Days><StorageClass>GLACIER</StorageClass></Transition></Rule></LifecycleConfiguration>`
+	if _, err = parseLifecycleConfig([]byte(transition)); err != errLifecycleTransitionUnsupported {
+		t.Fatalf("transition should be unsupported: err(%v)", err)
+	}
+}
+
+func TestLifecycle_Expired(t *testing.T) {
+	var mtime = time.Date(2020, 1, 1, 10, 0, 0, 0, time.UTC)
+	var expiration = &LifecycleExpiration{Days: 1}
+	// expired at the midnight after one day
+	if expiration.Expired(mtime, time.Date(2020, 1, 2, 23, 59, 59, 0, time.UTC)) {
+		t.Fatalf("object should not be expired")
+	}
+	if !expiration.Expired(mtime, time.Date(2020, 1, 3, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("object should be expired")
+	}
+
+	expiration = &LifecycleExpiration{Date: "2020-02-01T00:00:00.000Z"}
+	if expiration.Expired(mtime, time.Date(2020, 1, 31, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("object should not be expired")
+	}
+	if !expiration.Expired(mtime, time.Date(2020, 2, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("object should be expired")
+	}
+
+	var abort = &AbortIncompleteMultipartUpload{DaysAfterInitiation: 2}
+	if abort.Expired(mtime, mtime.Add(47*time.Hour)) || !abort.Expired(mtime, mtime.Add(48*time.Hour)) {
+		t.Fatalf("unexpected multipart upload expiration")
+	}
+}
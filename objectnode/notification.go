@@ -0,0 +1,204 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+// https://docs.aws.amazon.com/AmazonS3/latest/dev/NotificationHowTo.html
+
+import (
+	"encoding/xml"
+	"strings"
+
+	"github.com/chubaofs/chubaofs/util/errors"
+)
+
+// Event types of the bucket notifications
+const (
+	EventObjectCreatedAll                     = "s3:ObjectCreated:*"
+	EventObjectCreatedPut                     = "s3:ObjectCreated:Put"
+	EventObjectCreatedPost                    = "s3:ObjectCreated:Post"
+	EventObjectCreatedCopy                    = "s3:ObjectCreated:Copy"
+	EventObjectCreatedCompleteMultipartUpload = "s3:ObjectCreated:CompleteMultipartUpload"
+	EventObjectRemovedAll                     = "s3:ObjectRemoved:*"
+	EventObjectRemovedDelete                  = "s3:ObjectRemoved:Delete"
+	EventObjectRemovedDeleteMarkerCreated     = "s3:ObjectRemoved:DeleteMarkerCreated"
+
+	eventTypePrefix = "s3:"
+
+	notificationFilterPrefix = "prefix"
+	notificationFilterSuffix = "suffix"
+
+	notificationMaxConfigurations = 100
+)
+
+var notificationEvents = map[string]struct{}{
+	EventObjectCreatedAll:                     {},
+	EventObjectCreatedPut:                     {},
+	EventObjectCreatedPost:                    {},
+	EventObjectCreatedCopy:                    {},
+	EventObjectCreatedCompleteMultipartUpload: {},
+	EventObjectRemovedAll:                     {},
+	EventObjectRemovedDelete:                  {},
+	EventObjectRemovedDeleteMarkerCreated:     {},
+}
+
+var (
+	errNotificationUnsupported   = errors.New("only queue configurations are supported")
+	errNotificationTooMany       = errors.New("too many notification configurations")
+	errNotificationDuplicatedID  = errors.New("duplicated notification configuration ID")
+	errNotificationInvalidEvent  = errors.New("invalid notification event")
+	errNotificationInvalidFilter = errors.New("invalid notification filter rule")
+	errNotificationUnknownTarget = errors.New("unknown notification destination")
+)
+
+// NotificationConfiguration is the notification configuration of a bucket. The events
+// are delivered to the targets configured for the object nodes, which are referred by
+// the ARNs of the queue configurations. The topic and cloud function configurations
+// are parsed only to be rejected.
+type NotificationConfiguration struct {
+	XMLName                     xml.Name              `xml:"NotificationConfiguration"`
+	QueueConfigurations         []*QueueConfiguration `xml:"QueueConfiguration,omitempty"`
+	TopicConfigurations         []struct{}            `xml:"TopicConfiguration,omitempty"`
+	CloudFunctionConfigurations []struct{}            `xml:"CloudFunctionConfiguration,omitempty"`
+}
+
+type QueueConfiguration struct {
+	ID     string              `xml:"Id,omitempty"`
+	Queue  string              `xml:"Queue"`
+	Events []string            `xml:"Event"`
+	Filter *NotificationFilter `xml:"Filter,omitempty"`
+}
+
+type NotificationFilter struct {
+	Rules []*NotificationFilterRule `xml:"S3Key>FilterRule"`
+}
+
+type NotificationFilterRule struct {
+	Name  string `xml:"Name"`
+	Value string `xml:"Value"`
+}
+
+// Empty returns true if no event is delivered.
+func (c *NotificationConfiguration) Empty() bool {
+	return c == nil || len(c.QueueConfigurations) == 0
+}
+
+func (c *NotificationConfiguration) validate(targetExists func(arn string) bool) error {
+	if len(c.TopicConfigurations) > 0 || len(c.CloudFunctionConfigurations) > 0 {
+		return errNotificationUnsupported
+	}
+	if len(c.QueueConfigurations) > notificationMaxConfigurations {
+		return errNotificationTooMany
+	}
+	var ids = make(map[string]struct{})
+	for _, queue := range c.QueueConfigurations {
+		if queue == nil {
+			return errNotificationInvalidEvent
+		}
+		if queue.ID != "" {
+			if _, exist := ids[queue.ID]; exist {
+				return errNotificationDuplicatedID
+			}
+			ids[queue.ID] = struct{}{}
+		}
+		if err := queue.validate(); err != nil {
+			return err
+		}
+		if !targetExists(queue.Queue) {
+			return errNotificationUnknownTarget
+		}
+	}
+	return nil
+}
+
+func (queue *QueueConfiguration) validate() error {
+	if len(queue.Events) == 0 {
+		return errNotificationInvalidEvent
+	}
+	for _, event := range queue.Events {
+		if _, valid := notificationEvents[event]; !valid {
+			return errNotificationInvalidEvent
+		}
+	}
+	if queue.Filter == nil {
+		return nil
+	}
+	var names = make(map[string]struct{})
+	for _, rule := range queue.Filter.Rules {
+		var name = strings.ToLower(rule.Name)
+		if name != notificationFilterPrefix && name != notificationFilterSuffix {
+			return errNotificationInvalidFilter
+		}
+		if _, exist := names[name]; exist {
+			return errNotificationInvalidFilter
+		}
+		names[name] = struct{}{}
+	}
+	return nil
+}
+
+// Match returns true if the event of the object with the key is delivered following the configuration.
+func (queue *QueueConfiguration) Match(eventName, key string) bool {
+	var matched bool
+	for _, event := range queue.Events {
+		if event == eventName || strings.HasSuffix(event, ":*") && strings.HasPrefix(eventName, strings.TrimSuffix(event, "*")) {
+			matched = true
+			break
+		}
+	}
+	if !matched {
+		return false
+	}
+	if queue.Filter == nil {
+		return true
+	}
+	for _, rule := range queue.Filter.Rules {
+		switch strings.ToLower(rule.Name) {
+		case notificationFilterPrefix:
+			if !strings.HasPrefix(key, rule.Value) {
+				return false
+			}
+		case notificationFilterSuffix:
+			if !strings.HasSuffix(key, rule.Value) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func parseNotificationConfig(bytes []byte, targetExists func(arn string) bool) (*NotificationConfiguration, error) {
+	var notification = &NotificationConfiguration{}
+	if err := xml.Unmarshal(bytes, notification); err != nil {
+		return nil, err
+	}
+	if err := notification.validate(targetExists); err != nil {
+		return nil, err
+	}
+	return notification, nil
+}
+
+func storeBucketNotification(bytes []byte, vol *Volume) (err error) {
+	if err = vol.store.Put(vol.name, bucketRootPath, XAttrKeyOSSNotification, bytes); err != nil {
+		return
+	}
+	return nil
+}
+
+func deleteBucketNotification(vol *Volume) (err error) {
+	if err = vol.store.Delete(vol.name, bucketRootPath, XAttrKeyOSSNotification); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// Get bucket notification configuration
+// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetBucketNotificationConfiguration.html
+func (o *ObjectNode) getBucketNotificationHandler(w http.ResponseWriter, r *http.Request) {
+	var err error
+	var param = ParseRequestParam(r)
+	if param.Bucket() == "" {
+		_ = NoSuchBucket.ServeResponse(w, r)
+		return
+	}
+	var vol *Volume
+	if vol, err = o.vm.Volume(param.Bucket()); err != nil {
+		_ = NoSuchBucket.ServeResponse(w, r)
+		return
+	}
+
+	var notification *NotificationConfiguration
+	if notification, err = vol.metaLoader.loadNotification(); err != nil {
+		_ = InternalErrorCode(err).ServeResponse(w, r)
+		return
+	}
+	// an empty configuration is returned if the notification is not configured
+	if notification == nil {
+		notification = &NotificationConfiguration{}
+	}
+	var data []byte
+	if data, err = MarshalXMLEntity(notification); err != nil {
+		_ = InternalErrorCode(err).ServeResponse(w, r)
+		return
+	}
+
+	w.Header()[HeaderNameContentType] = []string{HeaderValueContentTypeXML}
+	w.Header()[HeaderNameContentLength] = []string{strconv.Itoa(len(data))}
+	_, _ = w.Write(data)
+	return
+}
+
+// Put bucket notification configuration
+// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_PutBucketNotificationConfiguration.html
+func (o *ObjectNode) putBucketNotificationHandler(w http.ResponseWriter, r *http.Request) {
+	var err error
+	var param = ParseRequestParam(r)
+	if param.Bucket() == "" {
+		_ = NoSuchBucket.ServeResponse(w, r)
+		return
+	}
+	var vol *Volume
+	if vol, err = o.vm.Volume(param.Bucket()); err != nil {
+		_ = NoSuchBucket.ServeResponse(w, r)
+		return
+	}
+
+	var bytes []byte
+	if bytes, err = ioutil.ReadAll(r.Body); err != nil && err != io.EOF {
+		_ = InternalErrorCode(err).ServeResponse(w, r)
+		return
+	}
+	var notification *NotificationConfiguration
+	if notification, err = parseNotificationConfig(bytes, o.notifier.TargetExists); err != nil {
+		log.LogErrorf("putBucketNotificationHandler: parse notification config fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), vol.Name(), err)
+		if _, ok := err.(*xml.SyntaxError); ok {
+			_ = MalformedXML.ServeResponse(w, r)
+			return
+		}
+		if err == errNotificationUnknownTarget {
+			_ = InvalidNotificationDestination.ServeResponse(w, r)
+			return
+		}
+		_ = InvalidNotificationConfiguration.ServeResponse(w, r)
+		return
+	}
+
+	// the notification is disabled by an empty configuration
+	if notification.Empty() {
+		if err = deleteBucketNotification(vol); err != nil {
+			_ = InternalErrorCode(err).ServeResponse(w, r)
+			return
+		}
+		vol.metaLoader.storeNotification(nil)
+		log.LogInfof("Audit: delete bucket notification: requestID(%v) volume(%v)", GetRequestID(r), vol.Name())
+		return
+	}
+
+	var newBytes []byte
+	if newBytes, err = xml.Marshal(notification); err != nil {
+		_ = InternalErrorCode(err).ServeResponse(w, r)
+		return
+	}
+	if err = storeBucketNotification(newBytes, vol); err != nil {
+		log.LogErrorf("putBucketNotificationHandler: store notification config fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), vol.Name(), err)
+		_ = InternalErrorCode(err).ServeResponse(w, r)
+		return
+	}
+	vol.metaLoader.storeNotification(notification)
+
+	log.LogInfof("Audit: put bucket notification: requestID(%v) volume(%v) configurations(%v)",
+		GetRequestID(r), vol.Name(), len(notification.QueueConfigurations))
+	return
+}
+
+// notifyObjectEvent sends the event of the object to the targets of the bucket notification.
+func (o *ObjectNode) notifyObjectEvent(r *http.Request, vol *Volume, eventName, key string, size int64, etag, versionID string) {
+	var now = time.Now().UTC()
+	var record = &EventRecord{
+		EventVersion:      notificationEventVersion,
+		EventSource:       notificationEventSource,
+		AwsRegion:         o.region,
+		EventTime:         now.Format(time.RFC3339Nano),
+		EventName:         strings.TrimPrefix(eventName, eventTypePrefix),
+		UserIdentity:      EventIdentity{PrincipalID: ParseRequestParam(r).AccessKey()},
+		RequestParameters: map[string]string{"sourceIPAddress": getRequestIP(r)},
+		ResponseElements:  map[string]string{HeaderNameXAmzRequestId: GetRequestID(r)},
+		S3: EventEntity{
+			SchemaVersion: notificationEventSchemaVersion,
+			Bucket: EventBucket{
+				Name:          vol.Name(),
+				OwnerIdentity: EventIdentity{PrincipalID: vol.Owner()},
+				ARN:           replicationBucketARNPrefix + vol.Name(),
+			},
+			Object: EventObject{
+				Key:       key,
+				Size:      size,
+				ETag:      etag,
+				VersionID: versionID,
+				Sequencer: fmt.Sprintf("%016X", now.UnixNano()),
+			},
+		},
+	}
+	o.notifier.Notify(vol, record)
+}
@@ -0,0 +1,448 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/chubaofs/chubaofs/util/errors"
+	"github.com/chubaofs/chubaofs/util/exporter"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+const (
+	defaultNotificationQueueLimit = 100000
+
+	notificationTargetARNPrefix    = "arn:chubaofs:sqs::"
+	notificationTargetARNSuffix    = ":webhook"
+	notificationEventFileSuffix    = ".event"
+	notificationTempFileSuffix     = ".tmp"
+	notificationSendTimeout        = 10 * time.Second
+	notificationRetryInterval      = time.Second
+	notificationMaxRetryInterval   = time.Minute
+	notificationMetricsInterval    = 10 * time.Second
+	notificationEventVersion       = "2.1"
+	notificationEventSource        = "chubaofs:s3"
+	notificationEventSchemaVersion = "1.0"
+)
+
+const (
+	metricNotificationBacklog = "notification_backlog"
+	metricNotificationSent    = "notification_sent"
+	metricNotificationFailed  = "notification_failed"
+)
+
+var (
+	errNotificationQueueFull = errors.New("notification queue is full")
+)
+
+// EventMessage is the message posted to the targets, which follows the event message
+// structure of the S3 bucket notifications.
+type EventMessage struct {
+	Records []*EventRecord `json:"Records"`
+}
+
+type EventRecord struct {
+	EventVersion      string            `json:"eventVersion"`
+	EventSource       string            `json:"eventSource"`
+	AwsRegion         string            `json:"awsRegion"`
+	EventTime         string            `json:"eventTime"`
+	EventName         string            `json:"eventName"`
+	UserIdentity      EventIdentity     `json:"userIdentity"`
+	RequestParameters map[string]string `json:"requestParameters"`
+	ResponseElements  map[string]string `json:"responseElements"`
+	S3                EventEntity       `json:"s3"`
+}
+
+type EventIdentity struct {
+	PrincipalID string `json:"principalId"`
+}
+
+type EventEntity struct {
+	SchemaVersion   string      `json:"s3SchemaVersion"`
+	ConfigurationID string      `json:"configurationId"`
+	Bucket          EventBucket `json:"bucket"`
+	Object          EventObject `json:"object"`
+}
+
+type EventBucket struct {
+	Name          string        `json:"name"`
+	OwnerIdentity EventIdentity `json:"ownerIdentity"`
+	ARN           string        `json:"arn"`
+}
+
+type EventObject struct {
+	Key       string `json:"key"`
+	Size      int64  `json:"size,omitempty"`
+	ETag      string `json:"eTag,omitempty"`
+	VersionID string `json:"versionId,omitempty"`
+	Sequencer string `json:"sequencer"`
+}
+
+// WebhookConfig is the configuration of the webhook target of the notifications.
+type WebhookConfig struct {
+	ID         string `json:"id"`
+	Endpoint   string `json:"endpoint"`
+	AuthToken  string `json:"authToken"`
+	QueueDir   string `json:"queueDir"`
+	QueueLimit int    `json:"queueLimit"`
+}
+
+// eventQueue is a durable queue of the events keeping each event in a file of the
+// queue directory. The files are named by the time of the events, so that the events
+// are delivered in the order they occur, and written to temporary files and renamed,
+// so that partially written events are never delivered.
+type eventQueue struct {
+	dir   string
+	limit int
+	count int64
+	seq   uint64
+}
+
+func newEventQueue(dir string, limit int) (q *eventQueue, err error) {
+	if limit <= 0 {
+		limit = defaultNotificationQueueLimit
+	}
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return
+	}
+	q = &eventQueue{dir: dir, limit: limit}
+	var infos []os.FileInfo
+	if infos, err = ioutil.ReadDir(dir); err != nil {
+		return
+	}
+	for _, info := range infos {
+		if strings.HasSuffix(info.Name(), notificationTempFileSuffix) {
+			_ = os.Remove(filepath.Join(dir, info.Name()))
+			continue
+		}
+		if strings.HasSuffix(info.Name(), notificationEventFileSuffix) {
+			q.count++
+		}
+	}
+	return
+}
+
+func (q *eventQueue) put(data []byte) (err error) {
+	if atomic.LoadInt64(&q.count) >= int64(q.limit) {
+		return errNotificationQueueFull
+	}
+	var name = fmt.Sprintf("%020d-%010d", time.Now().UnixNano(), atomic.AddUint64(&q.seq, 1))
+	var tempPath = filepath.Join(q.dir, name+notificationTempFileSuffix)
+	if err = ioutil.WriteFile(tempPath, data, 0644); err != nil {
+		_ = os.Remove(tempPath)
+		return
+	}
+	if err = os.Rename(tempPath, filepath.Join(q.dir, name+notificationEventFileSuffix)); err != nil {
+		_ = os.Remove(tempPath)
+		return
+	}
+	atomic.AddInt64(&q.count, 1)
+	return
+}
+
+// list returns the names of the events in the queue in order.
+func (q *eventQueue) list() (names []string, err error) {
+	var infos []os.FileInfo
+	if infos, err = ioutil.ReadDir(q.dir); err != nil {
+		return
+	}
+	names = make([]string, 0, len(infos))
+	for _, info := range infos {
+		if strings.HasSuffix(info.Name(), notificationEventFileSuffix) {
+			names = append(names, info.Name())
+		}
+	}
+	sort.Strings(names)
+	return
+}
+
+func (q *eventQueue) get(name string) ([]byte, error) {
+	return ioutil.ReadFile(filepath.Join(q.dir, name))
+}
+
+func (q *eventQueue) remove(name string) (err error) {
+	if err = os.Remove(filepath.Join(q.dir, name)); err != nil && !os.IsNotExist(err) {
+		return
+	}
+	atomic.AddInt64(&q.count, -1)
+	return nil
+}
+
+func (q *eventQueue) len() int64 {
+	return atomic.LoadInt64(&q.count)
+}
+
+// WebhookTarget posts the event messages to the HTTP endpoint. The events are kept
+// in the durable queue until they are accepted by the endpoint, and retried with
+// backoff while the endpoint is unavailable, so that no event is lost if the object
+// node is restarted.
+type WebhookTarget struct {
+	id        string
+	arn       string
+	endpoint  string
+	authToken string
+	queue     *eventQueue
+	client    *http.Client
+
+	notifyCh  chan struct{}
+	closeOnce sync.Once
+	closeCh   chan struct{}
+}
+
+func NewWebhookTarget(config *WebhookConfig) (target *WebhookTarget, err error) {
+	if config.ID == "" || config.Endpoint == "" || config.QueueDir == "" {
+		return nil, fmt.Errorf("invalid webhook target: id(%v) endpoint(%v) queueDir(%v)",
+			config.ID, config.Endpoint, config.QueueDir)
+	}
+	var queue *eventQueue
+	if queue, err = newEventQueue(config.QueueDir, config.QueueLimit); err != nil {
+		return
+	}
+	target = &WebhookTarget{
+		id:        config.ID,
+		arn:       notificationTargetARNPrefix + config.ID + notificationTargetARNSuffix,
+		endpoint:  config.Endpoint,
+		authToken: config.AuthToken,
+		queue:     queue,
+		client:    &http.Client{Timeout: notificationSendTimeout},
+		notifyCh:  make(chan struct{}, 1),
+		closeCh:   make(chan struct{}),
+	}
+	return
+}
+
+// ARN returns the identifier of the target used in the queue configurations of the buckets.
+func (t *WebhookTarget) ARN() string {
+	return t.arn
+}
+
+func (t *WebhookTarget) Start() {
+	go t.run()
+}
+
+func (t *WebhookTarget) Stop() {
+	t.closeOnce.Do(func() {
+		close(t.closeCh)
+	})
+}
+
+// Send puts the message into the queue to be delivered.
+func (t *WebhookTarget) Send(message *EventMessage) (err error) {
+	var data []byte
+	if data, err = json.Marshal(message); err != nil {
+		return
+	}
+	if err = t.queue.put(data); err != nil {
+		return
+	}
+	select {
+	case t.notifyCh <- struct{}{}:
+	default:
+	}
+	return
+}
+
+func (t *WebhookTarget) run() {
+	var interval time.Duration
+	for {
+		if err := t.deliver(); err != nil {
+			if interval *= 2; interval == 0 {
+				interval = notificationRetryInterval
+			} else if interval > notificationMaxRetryInterval {
+				interval = notificationMaxRetryInterval
+			}
+			log.LogWarnf("WebhookTarget: deliver events fail and retry: target(%v) endpoint(%v) interval(%v) err(%v)",
+				t.id, t.endpoint, interval, err)
+			var timer = time.NewTimer(interval)
+			select {
+			case <-timer.C:
+			case <-t.closeCh:
+				timer.Stop()
+				return
+			}
+			continue
+		}
+		interval = 0
+		select {
+		case <-t.notifyCh:
+		case <-t.closeCh:
+			return
+		}
+	}
+}
+
+// deliver posts the events in the queue in order until the queue is empty or the
+// delivery fails.
+func (t *WebhookTarget) deliver() (err error) {
+	var names []string
+	if names, err = t.queue.list(); err != nil {
+		return
+	}
+	for _, name := range names {
+		select {
+		case <-t.closeCh:
+			return nil
+		default:
+		}
+		var data []byte
+		if data, err = t.queue.get(name); err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return
+		}
+		if err = t.post(data); err != nil {
+			exporter.NewCounter(metricNotificationFailed).Add(1)
+			return
+		}
+		exporter.NewCounter(metricNotificationSent).Add(1)
+		if err = t.queue.remove(name); err != nil {
+			return
+		}
+	}
+	return
+}
+
+func (t *WebhookTarget) post(data []byte) (err error) {
+	var req *http.Request
+	if req, err = http.NewRequest(http.MethodPost, t.endpoint, bytes.NewReader(data)); err != nil {
+		return
+	}
+	req.Header.Set(HeaderNameContentType, HeaderValueContentTypeJSON)
+	if t.authToken != "" {
+		req.Header.Set(HeaderNameAuthorization, "Bearer "+t.authToken)
+	}
+	var resp *http.Response
+	if resp, err = t.client.Do(req); err != nil {
+		return
+	}
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+	_ = resp.Body.Close()
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("unexpected status: %v", resp.Status)
+	}
+	return
+}
+
+// Notifier delivers the events of the objects to the targets following the
+// notification configurations of the buckets.
+type Notifier struct {
+	targets   map[string]*WebhookTarget // mapping: ARN -> target
+	closeOnce sync.Once
+	closeCh   chan struct{}
+}
+
+func NewNotifier(configs []*WebhookConfig) (notifier *Notifier, err error) {
+	notifier = &Notifier{
+		targets: make(map[string]*WebhookTarget),
+		closeCh: make(chan struct{}),
+	}
+	for _, config := range configs {
+		var target *WebhookTarget
+		if target, err = NewWebhookTarget(config); err != nil {
+			return nil, err
+		}
+		if _, exist := notifier.targets[target.ARN()]; exist {
+			return nil, fmt.Errorf("duplicated webhook target: id(%v)", config.ID)
+		}
+		notifier.targets[target.ARN()] = target
+	}
+	return
+}
+
+func (n *Notifier) Start() {
+	for _, target := range n.targets {
+		target.Start()
+	}
+	go n.reportMetrics()
+}
+
+func (n *Notifier) Stop() {
+	n.closeOnce.Do(func() {
+		close(n.closeCh)
+		for _, target := range n.targets {
+			target.Stop()
+		}
+	})
+}
+
+// TargetExists returns true if the target with the ARN is configured.
+func (n *Notifier) TargetExists(arn string) bool {
+	_, exist := n.targets[arn]
+	return exist
+}
+
+// Notify sends the event to the targets of the queue configurations of the bucket matching the event.
+// The key of the object in the record is escaped in the messages sent.
+func (n *Notifier) Notify(vol *Volume, record *EventRecord) {
+	if len(n.targets) == 0 {
+		return
+	}
+	notification, err := vol.metaLoader.loadNotification()
+	if err != nil || notification.Empty() {
+		return
+	}
+	var eventName = eventTypePrefix + record.EventName
+	for _, queue := range notification.QueueConfigurations {
+		if !queue.Match(eventName, record.S3.Object.Key) {
+			continue
+		}
+		var target, exist = n.targets[queue.Queue]
+		if !exist {
+			log.LogWarnf("Notifier: target not found: volume(%v) configuration(%v) target(%v)",
+				vol.Name(), queue.ID, queue.Queue)
+			continue
+		}
+		var matched = *record
+		matched.S3.ConfigurationID = queue.ID
+		matched.S3.Object.Key = url.QueryEscape(record.S3.Object.Key)
+		if err = target.Send(&EventMessage{Records: []*EventRecord{&matched}}); err != nil {
+			log.LogErrorf("Notifier: send event fail: volume(%v) key(%v) event(%v) target(%v) err(%v)",
+				vol.Name(), record.S3.Object.Key, eventName, queue.Queue, err)
+			exporter.NewCounter(metricNotificationFailed).Add(1)
+		}
+	}
+}
+
+func (n *Notifier) reportMetrics() {
+	t := time.NewTicker(notificationMetricsInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			var backlog int64
+			for _, target := range n.targets {
+				backlog += target.queue.len()
+			}
+			exporter.NewGauge(metricNotificationBacklog).Set(float64(backlog))
+		case <-n.closeCh:
+			return
+		}
+	}
+}
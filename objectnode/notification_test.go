@@ -0,0 +1,151 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const testNotificationTarget = "arn:chubaofs:sqs::pipeline:webhook"
+
+func testNotificationTargetExists(arn string) bool {
+	return arn == testNotificationTarget
+}
+
+func TestNotification_Parse(t *testing.T) {
+	var raw = `<NotificationConfiguration>
+  <QueueConfiguration>
+    <Id>images</Id>
+    <Queue>arn:chubaofs:sqs::pipeline:webhook</Queue>
+    <Event>s3:ObjectCreated:*</Event>
+    <Filter><S3Key>
+      <FilterRule><Name>prefix</Name><Value>images/</Value></FilterRule>
+      <FilterRule><Name>Suffix</Name><Value>.jpg</Value></FilterRule>
+    </S3Key></Filter>
+  </QueueConfiguration>
+  <QueueConfiguration>
+    <Id>removed</Id>
+    <Queue>arn:chubaofs:sqs::pipeline:webhook</Queue>
+    <Event>s3:ObjectRemoved:Delete</Event>
+  </QueueConfiguration>
+</NotificationConfiguration>`
+	notification, err := parseNotificationConfig([]byte(raw), testNotificationTargetExists)
+	if err != nil {
+		t.Fatalf("parse notification fail: err(%v)", err)
+	}
+	var images, removed = notification.QueueConfigurations[0], notification.QueueConfigurations[1]
+	if !images.Match(EventObjectCreatedPut, "images/a.jpg") ||
+		!images.Match(EventObjectCreatedCompleteMultipartUpload, "images/b.jpg") {
+		t.Fatalf("created events should match")
+	}
+	if images.Match(EventObjectCreatedPut, "images/a.png") || images.Match(EventObjectCreatedPut, "docs/a.jpg") ||
+		images.Match(EventObjectRemovedDelete, "images/a.jpg") {
+		t.Fatalf("events should not match")
+	}
+	if !removed.Match(EventObjectRemovedDelete, "a") || removed.Match(EventObjectRemovedDeleteMarkerCreated, "a") {
+		t.Fatalf("unexpected match of removed events")
+	}
+
+	var invalids = []string{
+		`<NotificationConfiguration><TopicConfiguration><Topic>t</Topic><Event>s3:ObjectCreated:*</Event>` +
+			`</TopicConfiguration></NotificationConfiguration>`,
+		`<NotificationConfiguration><QueueConfiguration><Queue>arn:chubaofs:sqs::pipeline:webhook</Queue>` +
+			`<Event>s3:ObjectRestore:*</Event></QueueConfiguration></NotificationConfiguration>`,
+		`<NotificationConfiguration><QueueConfiguration><Queue>arn:chubaofs:sqs::pipeline:webhook</Queue>` +
+			`<Event>s3:ObjectCreated:*</Event><Filter><S3Key><FilterRule><Name>regex</Name><Value>a</Value>` +
+			`</FilterRule></S3Key></Filter></QueueConfiguration></NotificationConfiguration>`,
+	}
+	for _, raw := range invalids {
+		if _, err = parseNotificationConfig([]byte(raw), testNotificationTargetExists); err == nil {
+			t.Fatalf("notification should be invalid: %v", raw)
+		}
+	}
+	raw = `<NotificationConfiguration><QueueConfiguration><Queue>arn:chubaofs:sqs::unknown:webhook</Queue>` +
+		`<Event>s3:ObjectCreated:*</Event></QueueConfiguration></NotificationConfiguration>`
+	if _, err = parseNotificationConfig([]byte(raw), testNotificationTargetExists); err != errNotificationUnknownTarget {
+		t.Fatalf("destination should be unknown: err(%v)", err)
+	}
+}
+
+func TestNotification_Webhook(t *testing.T) {
+	var received = make(chan *EventMessage, 10)
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the first request fails to verify the event is retried
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if r.Header.Get(HeaderNameAuthorization) != "Bearer token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		var message = &EventMessage{}
+		data, _ := ioutil.ReadAll(r.Body)
+		if err := json.Unmarshal(data, message); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received <- message
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "notification")
+	if err != nil {
+		t.Fatalf("create queue dir fail: err(%v)", err)
+	}
+	defer os.RemoveAll(dir)
+
+	target, err := NewWebhookTarget(&WebhookConfig{ID: "pipeline", Endpoint: server.URL, AuthToken: "token", QueueDir: dir})
+	if err != nil {
+		t.Fatalf("create webhook target fail: err(%v)", err)
+	}
+	if target.ARN() != testNotificationTarget {
+		t.Fatalf("unexpected target ARN: %v", target.ARN())
+	}
+	// the events are queued before the target is started
+	for _, key := range []string{"a", "b"} {
+		var record = &EventRecord{EventName: "ObjectCreated:Put", S3: EventEntity{Object: EventObject{Key: key}}}
+		if err = target.Send(&EventMessage{Records: []*EventRecord{record}}); err != nil {
+			t.Fatalf("send event fail: err(%v)", err)
+		}
+	}
+	target.Start()
+	defer target.Stop()
+
+	for _, key := range []string{"a", "b"} {
+		select {
+		case message := <-received:
+			if len(message.Records) != 1 || message.Records[0].S3.Object.Key != key {
+				t.Fatalf("unexpected event: %v", message.Records[0])
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatalf("event of key %v not received", key)
+		}
+	}
+	for i := 0; i < 100 && target.queue.len() > 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if target.queue.len() != 0 {
+		t.Fatalf("events should be removed from queue: %v", target.queue.len())
+	}
+}
@@ -0,0 +1,156 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+// https://docs.aws.amazon.com/AmazonS3/latest/dev/object-lock.html
+
+import (
+	"encoding/xml"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/errors"
+)
+
+var (
+	errObjectLocked           = errors.New("object is protected by object lock")
+	errInvalidRetentionPeriod = errors.New("retain until date must be in the future")
+	errInvalidObjectLock      = errors.New("invalid object lock")
+)
+
+// ObjectLockOption is the object lock applied to a new object.
+type ObjectLockOption struct {
+	Retention *proto.Retention
+	LegalHold string
+}
+
+type ObjectRetention struct {
+	XMLName         xml.Name `xml:"Retention"`
+	Mode            string   `xml:"Mode"`
+	RetainUntilDate string   `xml:"RetainUntilDate"`
+}
+
+type ObjectLegalHold struct {
+	XMLName xml.Name `xml:"LegalHold"`
+	Status  string   `xml:"Status"`
+}
+
+func newObjectRetention(retention *proto.Retention) *ObjectRetention {
+	return &ObjectRetention{
+		Mode:            retention.Mode,
+		RetainUntilDate: formatTimeISO(time.Unix(retention.RetainUntil, 0)),
+	}
+}
+
+// parseRetention parses the retention of the mode and the retain until date,
+// which must be in the future.
+func parseRetention(mode, untilDate string, now time.Time) (*proto.Retention, error) {
+	if mode != proto.RetentionModeGovernance && mode != proto.RetentionModeCompliance {
+		return nil, errInvalidObjectLock
+	}
+	until, err := time.Parse(time.RFC3339, untilDate)
+	if err != nil {
+		return nil, errInvalidObjectLock
+	}
+	if !until.After(now) {
+		return nil, errInvalidRetentionPeriod
+	}
+	return &proto.Retention{Mode: mode, RetainUntil: until.Unix()}, nil
+}
+
+func parseObjectRetention(bytes []byte, now time.Time) (*proto.Retention, error) {
+	var retention = &ObjectRetention{}
+	if err := xml.Unmarshal(bytes, retention); err != nil {
+		return nil, err
+	}
+	return parseRetention(retention.Mode, retention.RetainUntilDate, now)
+}
+
+func parseObjectLegalHold(bytes []byte) (status string, err error) {
+	var legalHold = &ObjectLegalHold{}
+	if err = xml.Unmarshal(bytes, legalHold); err != nil {
+		return
+	}
+	if !proto.ValidLegalHold(legalHold.Status) {
+		return "", errInvalidObjectLock
+	}
+	return legalHold.Status, nil
+}
+
+// parseObjectLockOption parses the object lock headers of a request writing a
+// new object. It returns nil if no object lock header is specified.
+func parseObjectLockOption(header http.Header, now time.Time) (opt *ObjectLockOption, err error) {
+	var (
+		mode      = header.Get(HeaderNameXAmzObjectLockMode)
+		untilDate = header.Get(HeaderNameXAmzObjectLockRetainUntilDate)
+		legalHold = header.Get(HeaderNameXAmzObjectLockLegalHold)
+	)
+	if mode == "" && untilDate == "" && legalHold == "" {
+		return nil, nil
+	}
+	opt = &ObjectLockOption{}
+	if mode != "" || untilDate != "" {
+		if opt.Retention, err = parseRetention(mode, untilDate, now); err != nil {
+			return nil, err
+		}
+	}
+	if legalHold != "" {
+		if !proto.ValidLegalHold(legalHold) {
+			return nil, errInvalidObjectLock
+		}
+		opt.LegalHold = legalHold
+	}
+	return
+}
+
+func bypassGovernanceRetention(r *http.Request) bool {
+	return strings.ToLower(r.Header.Get(HeaderNameXAmzBypassGovernanceRetention)) == "true"
+}
+
+// setObjectLockFileInfo sets the object lock of the object from its extend attributes.
+func setObjectLockFileInfo(info *FSFileInfo, xattrs map[string]string) {
+	if raw := xattrs[proto.RetentionXAttrKey]; raw != "" {
+		if retention, err := proto.ParseRetention(raw); err == nil {
+			info.Retention = retention
+		}
+	}
+	info.LegalHold = xattrs[proto.LegalHoldXAttrKey]
+}
+
+// setObjectLockResponseHeaders sets the object lock headers of the response.
+func setObjectLockResponseHeaders(w http.ResponseWriter, info *FSFileInfo) {
+	if info.Retention != nil {
+		w.Header()[HeaderNameXAmzObjectLockMode] = []string{info.Retention.Mode}
+		w.Header()[HeaderNameXAmzObjectLockRetainUntilDate] = []string{formatTimeISO(time.Unix(info.Retention.RetainUntil, 0))}
+	}
+	if info.LegalHold != "" {
+		w.Header()[HeaderNameXAmzObjectLockLegalHold] = []string{info.LegalHold}
+	}
+}
+
+func objectLockErrorCode(err error) *ErrorCode {
+	switch err {
+	case errObjectLocked:
+		return ObjectLocked
+	case errInvalidRetentionPeriod:
+		return InvalidRetentionPeriod
+	case errInvalidObjectLock:
+		return InvalidObjectLock
+	default:
+		return InternalErrorCode(err)
+	}
+}
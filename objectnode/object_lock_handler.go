@@ -0,0 +1,245 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// canBypassGovernance returns true if the requester is allowed to bypass the
+// governance retention, which is limited to the owner of the bucket and the
+// administrators.
+func (o *ObjectNode) canBypassGovernance(param *RequestParam, vol *Volume) bool {
+	userInfo, err := o.getUserInfoByAccessKey(param.AccessKey())
+	if err == nil {
+		return userInfo.UserType == proto.UserTypeRoot || userInfo.UserType == proto.UserTypeAdmin ||
+			userInfo.Policy.IsOwn(param.Bucket())
+	}
+	if err == proto.ErrAccessKeyNotExists || err == proto.ErrUserNotExists {
+		ak, _ := vol.OSSSecure()
+		return ak == param.AccessKey()
+	}
+	return false
+}
+
+// objectLockLookupErrorCode returns the error code of the failure looking up
+// the version of the object.
+func objectLockLookupErrorCode(w http.ResponseWriter, err error, versionID string) *ErrorCode {
+	switch err {
+	case syscall.ENOENT:
+		if versionID != "" {
+			return NoSuchVersion
+		}
+		return NoSuchKey
+	case errDeleteMarker:
+		w.Header()[HeaderNameXAmzDeleteMarker] = []string{"true"}
+		return MethodNotAllowed
+	default:
+		return objectLockErrorCode(err)
+	}
+}
+
+// Get object retention
+// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetObjectRetention.html
+func (o *ObjectNode) getObjectRetentionHandler(w http.ResponseWriter, r *http.Request) {
+	var err error
+	var param = ParseRequestParam(r)
+	if param.Bucket() == "" {
+		_ = NoSuchBucket.ServeResponse(w, r)
+		return
+	}
+	if param.Object() == "" {
+		_ = InvalidKey.ServeResponse(w, r)
+		return
+	}
+	var vol *Volume
+	if vol, err = o.vm.Volume(param.Bucket()); err != nil {
+		_ = NoSuchBucket.ServeResponse(w, r)
+		return
+	}
+
+	var versionID = r.URL.Query().Get(ParamVersionId)
+	var retention *proto.Retention
+	if retention, _, err = vol.GetObjectLock(param.Object(), versionID); err != nil {
+		log.LogErrorf("getObjectRetentionHandler: get object lock fail: requestID(%v) volume(%v) path(%v) versionID(%v) err(%v)",
+			GetRequestID(r), vol.Name(), param.Object(), versionID, err)
+		_ = objectLockLookupErrorCode(w, err, versionID).ServeResponse(w, r)
+		return
+	}
+	if retention == nil {
+		_ = NoSuchObjectLockConfiguration.ServeResponse(w, r)
+		return
+	}
+	var data []byte
+	if data, err = MarshalXMLEntity(newObjectRetention(retention)); err != nil {
+		_ = InternalErrorCode(err).ServeResponse(w, r)
+		return
+	}
+
+	w.Header()[HeaderNameContentType] = []string{HeaderValueContentTypeXML}
+	w.Header()[HeaderNameContentLength] = []string{strconv.Itoa(len(data))}
+	_, _ = w.Write(data)
+	return
+}
+
+// Put object retention
+// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_PutObjectRetention.html
+func (o *ObjectNode) putObjectRetentionHandler(w http.ResponseWriter, r *http.Request) {
+	var err error
+	var param = ParseRequestParam(r)
+	if param.Bucket() == "" {
+		_ = NoSuchBucket.ServeResponse(w, r)
+		return
+	}
+	if param.Object() == "" {
+		_ = InvalidKey.ServeResponse(w, r)
+		return
+	}
+	var vol *Volume
+	if vol, err = o.vm.Volume(param.Bucket()); err != nil {
+		_ = NoSuchBucket.ServeResponse(w, r)
+		return
+	}
+
+	var bytes []byte
+	if bytes, err = ioutil.ReadAll(r.Body); err != nil && err != io.EOF {
+		_ = InternalErrorCode(err).ServeResponse(w, r)
+		return
+	}
+	var retention *proto.Retention
+	if retention, err = parseObjectRetention(bytes, time.Now()); err != nil {
+		log.LogErrorf("putObjectRetentionHandler: parse retention fail: requestID(%v) volume(%v) path(%v) err(%v)",
+			GetRequestID(r), vol.Name(), param.Object(), err)
+		if err == errInvalidObjectLock || err == errInvalidRetentionPeriod {
+			_ = objectLockErrorCode(err).ServeResponse(w, r)
+			return
+		}
+		_ = MalformedXML.ServeResponse(w, r)
+		return
+	}
+
+	var versionID = r.URL.Query().Get(ParamVersionId)
+	var bypass = bypassGovernanceRetention(r) && o.canBypassGovernance(param, vol)
+	if err = vol.PutObjectRetention(param.Object(), versionID, retention, bypass); err != nil {
+		log.LogErrorf("putObjectRetentionHandler: put retention fail: requestID(%v) volume(%v) path(%v) versionID(%v) err(%v)",
+			GetRequestID(r), vol.Name(), param.Object(), versionID, err)
+		_ = objectLockLookupErrorCode(w, err, versionID).ServeResponse(w, r)
+		return
+	}
+
+	log.LogInfof("Audit: put object retention: requestID(%v) volume(%v) path(%v) versionID(%v) retention(%v)",
+		GetRequestID(r), vol.Name(), param.Object(), versionID, retention)
+	return
+}
+
+// Get object legal hold
+// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetObjectLegalHold.html
+func (o *ObjectNode) getObjectLegalHoldHandler(w http.ResponseWriter, r *http.Request) {
+	var err error
+	var param = ParseRequestParam(r)
+	if param.Bucket() == "" {
+		_ = NoSuchBucket.ServeResponse(w, r)
+		return
+	}
+	if param.Object() == "" {
+		_ = InvalidKey.ServeResponse(w, r)
+		return
+	}
+	var vol *Volume
+	if vol, err = o.vm.Volume(param.Bucket()); err != nil {
+		_ = NoSuchBucket.ServeResponse(w, r)
+		return
+	}
+
+	var versionID = r.URL.Query().Get(ParamVersionId)
+	var legalHold string
+	if _, legalHold, err = vol.GetObjectLock(param.Object(), versionID); err != nil {
+		log.LogErrorf("getObjectLegalHoldHandler: get object lock fail: requestID(%v) volume(%v) path(%v) versionID(%v) err(%v)",
+			GetRequestID(r), vol.Name(), param.Object(), versionID, err)
+		_ = objectLockLookupErrorCode(w, err, versionID).ServeResponse(w, r)
+		return
+	}
+	if legalHold == "" {
+		_ = NoSuchObjectLockConfiguration.ServeResponse(w, r)
+		return
+	}
+	var data []byte
+	if data, err = MarshalXMLEntity(&ObjectLegalHold{Status: legalHold}); err != nil {
+		_ = InternalErrorCode(err).ServeResponse(w, r)
+		return
+	}
+
+	w.Header()[HeaderNameContentType] = []string{HeaderValueContentTypeXML}
+	w.Header()[HeaderNameContentLength] = []string{strconv.Itoa(len(data))}
+	_, _ = w.Write(data)
+	return
+}
+
+// Put object legal hold
+// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_PutObjectLegalHold.html
+func (o *ObjectNode) putObjectLegalHoldHandler(w http.ResponseWriter, r *http.Request) {
+	var err error
+	var param = ParseRequestParam(r)
+	if param.Bucket() == "" {
+		_ = NoSuchBucket.ServeResponse(w, r)
+		return
+	}
+	if param.Object() == "" {
+		_ = InvalidKey.ServeResponse(w, r)
+		return
+	}
+	var vol *Volume
+	if vol, err = o.vm.Volume(param.Bucket()); err != nil {
+		_ = NoSuchBucket.ServeResponse(w, r)
+		return
+	}
+
+	var bytes []byte
+	if bytes, err = ioutil.ReadAll(r.Body); err != nil && err != io.EOF {
+		_ = InternalErrorCode(err).ServeResponse(w, r)
+		return
+	}
+	var status string
+	if status, err = parseObjectLegalHold(bytes); err != nil {
+		log.LogErrorf("putObjectLegalHoldHandler: parse legal hold fail: requestID(%v) volume(%v) path(%v) err(%v)",
+			GetRequestID(r), vol.Name(), param.Object(), err)
+		if err == errInvalidObjectLock {
+			_ = InvalidObjectLock.ServeResponse(w, r)
+			return
+		}
+		_ = MalformedXML.ServeResponse(w, r)
+		return
+	}
+
+	var versionID = r.URL.Query().Get(ParamVersionId)
+	if err = vol.PutObjectLegalHold(param.Object(), versionID, status); err != nil {
+		log.LogErrorf("putObjectLegalHoldHandler: put legal hold fail: requestID(%v) volume(%v) path(%v) versionID(%v) err(%v)",
+			GetRequestID(r), vol.Name(), param.Object(), versionID, err)
+		_ = objectLockLookupErrorCode(w, err, versionID).ServeResponse(w, r)
+		return
+	}
+
+	log.LogInfof("Audit: put object legal hold: requestID(%v) volume(%v) path(%v) versionID(%v) status(%v)",
+		GetRequestID(r), vol.Name(), param.Object(), versionID, status)
+	return
+}
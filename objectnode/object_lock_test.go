@@ -0,0 +1,88 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+func TestObjectLock_ParseRetention(t *testing.T) {
+	var now = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	var raw = []byte(`<Retention><Mode>COMPLIANCE</Mode><RetainUntilDate>2020-02-01T00:00:00Z</RetainUntilDate></Retention>`)
+	retention, err := parseObjectRetention(raw, now)
+	if err != nil {
+		t.Fatalf("parse retention fail: err(%v)", err)
+	}
+	if retention.Mode != proto.RetentionModeCompliance || retention.RetainUntil != now.AddDate(0, 1, 0).Unix() {
+		t.Fatalf("unexpected retention: %v", retention)
+	}
+	if parsed, err := proto.ParseRetention(retention.String()); err != nil || *parsed != *retention {
+		t.Fatalf("unexpected stored retention: retention(%v) err(%v)", parsed, err)
+	}
+	if r := newObjectRetention(retention); r.RetainUntilDate != "2020-02-01T00:00:00.000Z" || r.Mode != retention.Mode {
+		t.Fatalf("unexpected retention entity: %v", r)
+	}
+
+	raw = []byte(`<Retention><Mode>GOVERNANCE</Mode><RetainUntilDate>2019-12-31T00:00:00Z</RetainUntilDate></Retention>`)
+	if _, err = parseObjectRetention(raw, now); err != errInvalidRetentionPeriod {
+		t.Fatalf("expired retention should be rejected: err(%v)", err)
+	}
+	raw = []byte(`<Retention><Mode>UNKNOWN</Mode><RetainUntilDate>2020-02-01T00:00:00Z</RetainUntilDate></Retention>`)
+	if _, err = parseObjectRetention(raw, now); err != errInvalidObjectLock {
+		t.Fatalf("unknown mode should be rejected: err(%v)", err)
+	}
+
+	if status, err := parseObjectLegalHold([]byte(`<LegalHold><Status>ON</Status></LegalHold>`)); err != nil || status != proto.LegalHoldOn {
+		t.Fatalf("unexpected legal hold: status(%v) err(%v)", status, err)
+	}
+	if _, err = parseObjectLegalHold([]byte(`<LegalHold><Status>on</Status></LegalHold>`)); err != errInvalidObjectLock {
+		t.Fatalf("invalid legal hold should be rejected: err(%v)", err)
+	}
+}
+
+func TestObjectLock_ParseOption(t *testing.T) {
+	var now = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	var header = http.Header{}
+	if opt, err := parseObjectLockOption(header, now); err != nil || opt != nil {
+		t.Fatalf("unexpected option: opt(%v) err(%v)", opt, err)
+	}
+	header.Set(HeaderNameXAmzObjectLockLegalHold, proto.LegalHoldOn)
+	opt, err := parseObjectLockOption(header, now)
+	if err != nil || opt.Retention != nil || opt.LegalHold != proto.LegalHoldOn {
+		t.Fatalf("unexpected option: opt(%v) err(%v)", opt, err)
+	}
+	header.Set(HeaderNameXAmzObjectLockMode, proto.RetentionModeGovernance)
+	if _, err = parseObjectLockOption(header, now); err != errInvalidObjectLock {
+		t.Fatalf("retention without date should be rejected: err(%v)", err)
+	}
+	header.Set(HeaderNameXAmzObjectLockRetainUntilDate, "2021-01-01T00:00:00Z")
+	if opt, err = parseObjectLockOption(header, now); err != nil || opt.Retention == nil ||
+		opt.Retention.Mode != proto.RetentionModeGovernance {
+		t.Fatalf("unexpected option: opt(%v) err(%v)", opt, err)
+	}
+
+	var info = &FSFileInfo{}
+	setObjectLockFileInfo(info, map[string]string{
+		proto.RetentionXAttrKey: opt.Retention.String(),
+		proto.LegalHoldXAttrKey: proto.LegalHoldOn,
+	})
+	if info.Retention == nil || *info.Retention != *opt.Retention || info.LegalHold != proto.LegalHoldOn {
+		t.Fatalf("unexpected file info: retention(%v) legalHold(%v)", info.Retention, info.LegalHold)
+	}
+}
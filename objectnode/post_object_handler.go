@@ -0,0 +1,388 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/errors"
+	"github.com/chubaofs/chubaofs/util/log"
+	"github.com/gorilla/mux"
+)
+
+// The total size of the form fields preceding the file is limited to 20KB, same as S3.
+const maxPostPreDataLength = 20 * 1024
+
+var (
+	errPostMalformed         = errors.New("malformed POST request")
+	errPostPreDataTooLarge   = errors.New("POST form fields preceding the file are too large")
+	errPostFileMissing       = errors.New("POST request has no file")
+	errPostSignatureMismatch = errors.New("POST signature does not match")
+)
+
+// Post object
+// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/RESTObjectPOST.html
+func (o *ObjectNode) postObjectHandler(w http.ResponseWriter, r *http.Request) {
+	var err error
+	var errorCode *ErrorCode
+	defer func() {
+		if errorCode != nil {
+			_ = errorCode.ServeResponse(w, r)
+		}
+	}()
+
+	var param = ParseRequestParam(r)
+	if param.Bucket() == "" {
+		errorCode = InvalidBucketName
+		return
+	}
+	var vol *Volume
+	if vol, err = o.getVol(param.Bucket()); err != nil {
+		log.LogErrorf("postObjectHandler: load volume fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), param.Bucket(), err)
+		errorCode = NoSuchBucket
+		return
+	}
+
+	var reader *multipart.Reader
+	if reader, err = r.MultipartReader(); err != nil {
+		errorCode = MalformedPOSTRequest
+		return
+	}
+	var form map[string]string
+	var file *multipart.Part
+	if form, file, err = readPostForm(reader); err != nil {
+		log.LogErrorf("postObjectHandler: read form fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), vol.Name(), err)
+		switch err {
+		case errPostPreDataTooLarge:
+			errorCode = MaxPostPreDataLengthExceeded
+		case errPostFileMissing:
+			errorCode = IncorrectNumberOfFilesInPostRequest
+		default:
+			errorCode = MalformedPOSTRequest
+		}
+		return
+	}
+	defer file.Close()
+	form[PostFormFieldBucket] = param.Bucket()
+
+	var key = form[PostFormFieldKey]
+	if key == "" {
+		errorCode = InvalidKey
+		return
+	}
+	key = strings.Replace(key, postFormFileNameVariable, file.FileName(), -1)
+
+	var accessKey string
+	var policy *PostPolicy
+	if accessKey, policy, err = o.authenticatePostForm(vol, form); err != nil {
+		log.LogWarnf("postObjectHandler: authenticate fail: requestID(%v) volume(%v) key(%v) remote(%v) err(%v)",
+			GetRequestID(r), vol.Name(), key, getRequestIP(r), err)
+		switch err {
+		case errPostPolicyInvalid:
+			errorCode = InvalidPolicyDocument
+		case errPostPolicyExpired:
+			errorCode = PostPolicyExpired
+		case errPostSignatureMismatch, errPostPolicyCondition, errPostPolicyFieldMissing:
+			errorCode = AccessDenied
+		default:
+			errorCode = InternalErrorCode(err)
+		}
+		return
+	}
+
+	// The request is authorized as the put object request of the key by the
+	// user of the access key, or anonymously if it is not signed.
+	mux.Vars(r)["object"] = key
+	SetRequestAccessKey(r, accessKey)
+	SetRequestAction(r, proto.OSSPutObjectAction)
+	o.policyCheck(func(w http.ResponseWriter, r *http.Request) {
+		o.postObject(w, r, vol, key, form, file, policy)
+	})(w, r)
+}
+
+func (o *ObjectNode) postObject(w http.ResponseWriter, r *http.Request, vol *Volume, key string,
+	form map[string]string, file *multipart.Part, policy *PostPolicy) {
+	var err error
+	var errorCode *ErrorCode
+	defer func() {
+		if errorCode != nil {
+			_ = errorCode.ServeResponse(w, r)
+		}
+	}()
+
+	// The form fields are used as the headers of the put object request.
+	var header = make(http.Header)
+	for field, value := range form {
+		header.Set(field, value)
+	}
+	if header.Get(HeaderNameContentType) == "" {
+		header.Set(HeaderNameContentType, file.Header.Get(HeaderNameContentType))
+	}
+
+	var tagging *Tagging
+	if raw := form[PostFormFieldTagging]; raw != "" {
+		tagging = NewTagging()
+		if err = xml.Unmarshal([]byte(raw), tagging); err != nil {
+			errorCode = MalformedXML
+			return
+		}
+		var validateRes bool
+		if validateRes, errorCode = tagging.Validate(); !validateRes {
+			return
+		}
+	}
+	var cacheControl = header.Get(HeaderNameCacheControl)
+	if len(cacheControl) > 0 && !ValidateCacheControl(cacheControl) {
+		errorCode = InvalidCacheArgument
+		return
+	}
+	var expires = header.Get(HeaderNameExpires)
+	if len(expires) > 0 && !ValidateCacheExpires(expires) {
+		errorCode = InvalidCacheArgument
+		return
+	}
+	var formRequest = r.WithContext(r.Context())
+	formRequest.Header = header
+	var sse *SSEOption
+	if sse, err = requestSSEOption(formRequest, vol); err != nil {
+		errorCode = sseErrorCode(err)
+		return
+	}
+	var objectLock *ObjectLockOption
+	if objectLock, err = parseObjectLockOption(header, time.Now()); err != nil {
+		errorCode = objectLockErrorCode(err)
+		return
+	}
+
+	var fileReader = &postPolicyReader{reader: file, max: -1}
+	if policy != nil {
+		fileReader.min, fileReader.max = policy.ContentLengthMin, policy.ContentLengthMax
+	}
+
+	// Audit file write
+	log.LogInfof("Audit: post object: requestID(%v) remote(%v) volume(%v) path(%v) type(%v)",
+		GetRequestID(r), getRequestIP(r), vol.Name(), key, header.Get(HeaderNameContentType))
+
+	var fsFileInfo *FSFileInfo
+	var opt = &PutFileOption{
+		MIMEType:     header.Get(HeaderNameContentType),
+		Disposition:  header.Get(HeaderNameContentDisposition),
+		Tagging:      tagging,
+		Metadata:     ParseUserDefinedMetadata(header),
+		CacheControl: cacheControl,
+		Expires:      expires,
+		SSE:          sse,
+		ObjectLock:   objectLock,
+	}
+	fsFileInfo, err = vol.PutObject(key, fileReader, opt)
+	switch {
+	case err == errPostEntityTooLarge:
+		errorCode = EntityTooLarge
+		return
+	case err == errPostEntityTooSmall:
+		errorCode = EntityTooSmall
+		return
+	case err == syscall.EINVAL:
+		errorCode = ObjectModeConflict
+		return
+	case err == errObjectLocked:
+		errorCode = ObjectLocked
+		return
+	case isSSEError(err):
+		errorCode = sseErrorCode(err)
+		return
+	case err != nil:
+		log.LogErrorf("postObjectHandler: put object fail: requestId(%v) volume(%v) path(%v) remote(%v) err(%v)",
+			GetRequestID(r), vol.Name(), key, getRequestIP(r), err)
+		errorCode = InternalErrorCode(err)
+		return
+	}
+	o.replicator.SubmitPut(vol, fsFileInfo)
+	o.notifyObjectEvent(r, vol, EventObjectCreatedPost, key, fsFileInfo.Size, fsFileInfo.ETag, fsFileInfo.VersionID)
+
+	// set response header
+	var etag = wrapUnescapedQuot(fsFileInfo.ETag)
+	var location = postObjectLocation(r, vol.Name(), key)
+	w.Header()[HeaderNameETag] = []string{etag}
+	w.Header()[HeaderNameLocation] = []string{location}
+	if len(fsFileInfo.VersionID) > 0 {
+		w.Header()[HeaderNameXAmzVersionId] = []string{fsFileInfo.VersionID}
+	}
+	setSSEResponseHeaders(w, fsFileInfo.SSE, fsFileInfo.SSECustomerKeyMD5)
+	setObjectLockResponseHeaders(w, fsFileInfo)
+
+	var redirect = form[PostFormFieldSuccessActionRedirect]
+	if redirect == "" {
+		redirect = form[PostFormFieldRedirect]
+	}
+	if redirectURL, parseErr := url.Parse(redirect); redirect != "" && parseErr == nil {
+		var query = redirectURL.Query()
+		query.Set(PostFormFieldBucket, vol.Name())
+		query.Set(PostFormFieldKey, key)
+		query.Set("etag", etag)
+		redirectURL.RawQuery = query.Encode()
+		http.Redirect(w, r, redirectURL.String(), http.StatusSeeOther)
+		return
+	}
+
+	switch form[PostFormFieldSuccessActionStatus] {
+	case strconv.Itoa(http.StatusOK):
+		w.Header()[HeaderNameContentLength] = []string{"0"}
+		w.WriteHeader(http.StatusOK)
+	case strconv.Itoa(http.StatusCreated):
+		var data []byte
+		if data, err = MarshalXMLEntity(&PostResponse{Location: location, Bucket: vol.Name(), Key: key, ETag: etag}); err != nil {
+			errorCode = InternalErrorCode(err)
+			return
+		}
+		w.Header()[HeaderNameContentType] = []string{HeaderValueContentTypeXML}
+		w.Header()[HeaderNameContentLength] = []string{strconv.Itoa(len(data))}
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write(data)
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+	return
+}
+
+// readPostForm reads the form fields preceding the file, and returns the fields with
+// lower case names and the part of the file. The fields following the file are ignored.
+func readPostForm(reader *multipart.Reader) (form map[string]string, file *multipart.Part, err error) {
+	form = make(map[string]string)
+	var remaining int64 = maxPostPreDataLength
+	for {
+		var part *multipart.Part
+		if part, err = reader.NextPart(); err == io.EOF {
+			return nil, nil, errPostFileMissing
+		}
+		if err != nil {
+			return nil, nil, errPostMalformed
+		}
+		var name = strings.ToLower(part.FormName())
+		if name == "" {
+			_ = part.Close()
+			continue
+		}
+		if name == PostFormFieldFile {
+			return form, part, nil
+		}
+		var value []byte
+		value, err = ioutil.ReadAll(io.LimitReader(part, remaining+1))
+		_ = part.Close()
+		if err != nil {
+			return nil, nil, errPostMalformed
+		}
+		if remaining -= int64(len(value)); remaining < 0 {
+			return nil, nil, errPostPreDataTooLarge
+		}
+		form[name] = string(value)
+	}
+}
+
+// authenticatePostForm authenticates the POST request with the signature of the policy in
+// the form fields, which is signed with signature algorithm V4 or V2, and checks the form
+// fields with the policy. It returns an empty access key if the request is not signed.
+func (o *ObjectNode) authenticatePostForm(vol *Volume, form map[string]string) (accessKey string, policy *PostPolicy, err error) {
+	var expected, signature string
+	var encodedPolicy = form[PostFormFieldPolicy]
+	switch {
+	case form[PostFormFieldXAmzSignature] != "":
+		if form[PostFormFieldXAmzAlgorithm] != SignatureV4Algorithm {
+			return "", nil, errPostSignatureMismatch
+		}
+		var req = &signatureRequestV4{}
+		if err = req.parseCredential(form[PostFormFieldXAmzCredential]); err != nil {
+			return "", nil, errPostSignatureMismatch
+		}
+		accessKey = req.Credential.AccessKey
+		var secretKey string
+		if secretKey, err = o.postFormSecretKey(vol, accessKey); err != nil {
+			return
+		}
+		var signingKey = buildSigningKey(SCHEME, secretKey, req.Credential.Date, req.Credential.Region,
+			req.Credential.Service, req.Credential.Request)
+		expected = hex.EncodeToString(sign(encodedPolicy, signingKey))
+		signature = form[PostFormFieldXAmzSignature]
+	case form[PostFormFieldSignature] != "":
+		accessKey = form[PostFormFieldAWSAccessKeyId]
+		var secretKey string
+		if secretKey, err = o.postFormSecretKey(vol, accessKey); err != nil {
+			return
+		}
+		var mac = hmac.New(sha1.New, []byte(secretKey))
+		mac.Write([]byte(encodedPolicy))
+		expected = base64.StdEncoding.EncodeToString(mac.Sum(nil))
+		signature = form[PostFormFieldSignature]
+	default:
+		// anonymous request authorized by the bucket policy
+		return "", nil, nil
+	}
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return "", nil, errPostSignatureMismatch
+	}
+	if policy, err = parsePostPolicy(encodedPolicy); err != nil {
+		return
+	}
+	if err = policy.Check(form, time.Now()); err != nil {
+		return
+	}
+	return
+}
+
+// postFormSecretKey returns the secret key of the access key, which is the key of a
+// user, or the key bound to the volume for compatibility.
+func (o *ObjectNode) postFormSecretKey(vol *Volume, accessKey string) (secretKey string, err error) {
+	var userInfo *proto.UserInfo
+	if userInfo, err = o.getUserInfoByAccessKey(accessKey); err == nil {
+		return userInfo.SecretKey, nil
+	}
+	if err == proto.ErrUserNotExists || err == proto.ErrAccessKeyNotExists {
+		if ak, sk := vol.OSSSecure(); ak == accessKey {
+			return sk, nil
+		}
+		return "", errPostSignatureMismatch
+	}
+	log.LogErrorf("postFormSecretKey: get secretKey from master fail: accessKey(%v) err(%v)", accessKey, err)
+	return
+}
+
+func postObjectLocation(r *http.Request, bucket, key string) string {
+	var scheme = "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	var location = url.URL{Scheme: scheme, Host: r.Host, Path: "/" + bucket + "/" + key}
+	if strings.HasPrefix(r.Host, bucket+".") {
+		// virtual hosted-style request
+		location.Path = "/" + key
+	}
+	return location.String()
+}
@@ -0,0 +1,239 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+// https://docs.aws.amazon.com/AmazonS3/latest/API/sigv4-HTTPPOSTConstructPolicy.html
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chubaofs/chubaofs/util/errors"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// Condition match types of the POST policy
+const (
+	PostPolicyEquals             = "eq"
+	PostPolicyStartsWith         = "starts-with"
+	PostPolicyContentLengthRange = "content-length-range"
+)
+
+// Form fields of the POST object request
+const (
+	PostFormFieldFile                  = "file"
+	PostFormFieldKey                   = "key"
+	PostFormFieldBucket                = "bucket"
+	PostFormFieldPolicy                = "policy"
+	PostFormFieldSignature             = "signature"
+	PostFormFieldAWSAccessKeyId        = "awsaccesskeyid"
+	PostFormFieldXAmzSignature         = "x-amz-signature"
+	PostFormFieldXAmzAlgorithm         = "x-amz-algorithm"
+	PostFormFieldXAmzCredential        = "x-amz-credential"
+	PostFormFieldXAmzDate              = "x-amz-date"
+	PostFormFieldSuccessActionStatus   = "success_action_status"
+	PostFormFieldSuccessActionRedirect = "success_action_redirect"
+	PostFormFieldRedirect              = "redirect"
+	PostFormFieldTagging               = "tagging"
+
+	postFormFieldIgnorePrefix = "x-ignore-"
+	postFormFileNameVariable  = "${filename}"
+)
+
+var (
+	errPostPolicyExpired      = errors.New("POST policy expired")
+	errPostPolicyInvalid      = errors.New("invalid POST policy document")
+	errPostPolicyCondition    = errors.New("POST form does not meet the policy conditions")
+	errPostPolicyFieldMissing = errors.New("POST form field not covered by the policy conditions")
+	errPostEntityTooLarge     = errors.New("POST file is larger than the content length range")
+	errPostEntityTooSmall     = errors.New("POST file is smaller than the content length range")
+)
+
+type PostPolicyConditionFunc func(value, expected string) bool
+
+var PostPolicyConditionFuncMap = map[string]PostPolicyConditionFunc{
+	PostPolicyEquals:     PostPolicyEqualsFunc,
+	PostPolicyStartsWith: PostPolicyStartsWithFunc,
+}
+
+func PostPolicyEqualsFunc(value, expected string) bool {
+	return value == expected
+}
+
+func PostPolicyStartsWithFunc(value, expected string) bool {
+	return strings.HasPrefix(value, expected)
+}
+
+// postFormFieldExempted returns true if the form field is not required to be
+// covered by the conditions of the policy.
+func postFormFieldExempted(field string) bool {
+	switch field {
+	case PostFormFieldFile, PostFormFieldPolicy, PostFormFieldSignature, PostFormFieldAWSAccessKeyId,
+		PostFormFieldXAmzSignature:
+		return true
+	}
+	return strings.HasPrefix(field, postFormFieldIgnorePrefix)
+}
+
+type PostPolicyCondition struct {
+	MatchType string
+	Field     string // lower case name of the form field without the leading '$'
+	Value     string
+}
+
+// PostPolicy is the policy document of the POST object request, which specifies
+// the conditions of the form fields and the size of the file to be uploaded.
+type PostPolicy struct {
+	Expiration       time.Time
+	Conditions       []*PostPolicyCondition
+	ContentLengthMin int64
+	ContentLengthMax int64 // negative if the content length is not limited
+}
+
+func parsePostPolicy(encoded string) (policy *PostPolicy, err error) {
+	var data []byte
+	if data, err = base64.StdEncoding.DecodeString(encoded); err != nil {
+		return nil, errPostPolicyInvalid
+	}
+	var document = struct {
+		Expiration string        `json:"expiration"`
+		Conditions []interface{} `json:"conditions"`
+	}{}
+	if err = json.Unmarshal(data, &document); err != nil {
+		return nil, errPostPolicyInvalid
+	}
+	policy = &PostPolicy{ContentLengthMax: -1}
+	if policy.Expiration, err = time.Parse(time.RFC3339Nano, document.Expiration); err != nil {
+		return nil, errPostPolicyInvalid
+	}
+	for _, raw := range document.Conditions {
+		switch condition := raw.(type) {
+		case map[string]interface{}:
+			// {"field": "value"} is the same as ["eq", "$field", "value"]
+			for field, value := range condition {
+				var str, ok = value.(string)
+				if !ok {
+					return nil, errPostPolicyInvalid
+				}
+				policy.Conditions = append(policy.Conditions, &PostPolicyCondition{
+					MatchType: PostPolicyEquals,
+					Field:     strings.ToLower(strings.TrimPrefix(field, "$")),
+					Value:     str,
+				})
+			}
+		case []interface{}:
+			if len(condition) != 3 {
+				return nil, errPostPolicyInvalid
+			}
+			var matchType, ok = condition[0].(string)
+			if !ok {
+				return nil, errPostPolicyInvalid
+			}
+			matchType = strings.ToLower(matchType)
+			if matchType == PostPolicyContentLengthRange {
+				if policy.ContentLengthMin, err = postPolicyInteger(condition[1]); err != nil {
+					return nil, err
+				}
+				if policy.ContentLengthMax, err = postPolicyInteger(condition[2]); err != nil {
+					return nil, err
+				}
+				if policy.ContentLengthMin < 0 || policy.ContentLengthMax < policy.ContentLengthMin {
+					return nil, errPostPolicyInvalid
+				}
+				continue
+			}
+			if _, exist := PostPolicyConditionFuncMap[matchType]; !exist {
+				return nil, errPostPolicyInvalid
+			}
+			field, fieldOK := condition[1].(string)
+			value, valueOK := condition[2].(string)
+			if !fieldOK || !valueOK || !strings.HasPrefix(field, "$") {
+				return nil, errPostPolicyInvalid
+			}
+			policy.Conditions = append(policy.Conditions, &PostPolicyCondition{
+				MatchType: matchType,
+				Field:     strings.ToLower(strings.TrimPrefix(field, "$")),
+				Value:     value,
+			})
+		default:
+			return nil, errPostPolicyInvalid
+		}
+	}
+	return policy, nil
+}
+
+func postPolicyInteger(value interface{}) (int64, error) {
+	switch v := value.(type) {
+	case float64:
+		return int64(v), nil
+	case string:
+		i, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return 0, errPostPolicyInvalid
+		}
+		return i, nil
+	default:
+		return 0, errPostPolicyInvalid
+	}
+}
+
+// Check checks the form fields with lower case names against the policy. Every form field
+// must be covered by the conditions except the exempted ones.
+func (p *PostPolicy) Check(form map[string]string, now time.Time) error {
+	if now.After(p.Expiration) {
+		return errPostPolicyExpired
+	}
+	var covered = make(map[string]struct{})
+	for _, condition := range p.Conditions {
+		if !PostPolicyConditionFuncMap[condition.MatchType](form[condition.Field], condition.Value) {
+			log.LogDebugf("PostPolicy: condition not met: condition(%v %v %v) value(%v)",
+				condition.MatchType, condition.Field, condition.Value, form[condition.Field])
+			return errPostPolicyCondition
+		}
+		covered[condition.Field] = struct{}{}
+	}
+	for field := range form {
+		if _, exist := covered[field]; !exist && !postFormFieldExempted(field) && field != PostFormFieldBucket {
+			log.LogDebugf("PostPolicy: form field not covered: field(%v)", field)
+			return errPostPolicyFieldMissing
+		}
+	}
+	return nil
+}
+
+// postPolicyReader reads the file of the POST request, and fails if the size is out
+// of the content length range of the policy.
+type postPolicyReader struct {
+	reader io.Reader
+	min    int64
+	max    int64
+	size   int64
+}
+
+func (r *postPolicyReader) Read(p []byte) (n int, err error) {
+	n, err = r.reader.Read(p)
+	r.size += int64(n)
+	if r.max >= 0 && r.size > r.max {
+		return n, errPostEntityTooLarge
+	}
+	if err == io.EOF && r.size < r.min {
+		return n, errPostEntityTooSmall
+	}
+	return
+}
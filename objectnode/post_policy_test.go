@@ -0,0 +1,136 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io/ioutil"
+	"mime/multipart"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPostPolicy_Check(t *testing.T) {
+	var document = `{
+  "expiration": "2030-01-01T00:00:00.000Z",
+  "conditions": [
+    {"bucket": "photos"},
+    ["starts-with", "$key", "user/alice/"],
+    ["eq", "$Content-Type", "image/jpeg"],
+    ["starts-with", "$x-amz-meta-tag", ""],
+    ["content-length-range", 1, 1024]
+  ]
+}`
+	policy, err := parsePostPolicy(base64.StdEncoding.EncodeToString([]byte(document)))
+	if err != nil {
+		t.Fatalf("parse policy fail: err(%v)", err)
+	}
+	if policy.ContentLengthMin != 1 || policy.ContentLengthMax != 1024 {
+		t.Fatalf("unexpected content length range: %v %v", policy.ContentLengthMin, policy.ContentLengthMax)
+	}
+	var now = time.Date(2029, 1, 1, 0, 0, 0, 0, time.UTC)
+	var form = map[string]string{
+		"bucket":          "photos",
+		"key":             "user/alice/a.jpg",
+		"content-type":    "image/jpeg",
+		"x-amz-meta-tag":  "holiday",
+		"policy":          "...",
+		"x-amz-signature": "...",
+		"x-ignore-field":  "...",
+	}
+	if err = policy.Check(form, now); err != nil {
+		t.Fatalf("check form fail: err(%v)", err)
+	}
+	if err = policy.Check(form, now.AddDate(2, 0, 0)); err != errPostPolicyExpired {
+		t.Fatalf("policy should be expired: err(%v)", err)
+	}
+	form["key"] = "user/bob/a.jpg"
+	if err = policy.Check(form, now); err != errPostPolicyCondition {
+		t.Fatalf("key should not match: err(%v)", err)
+	}
+	form["key"] = "user/alice/a.jpg"
+	form["acl"] = "public-read"
+	if err = policy.Check(form, now); err != errPostPolicyFieldMissing {
+		t.Fatalf("field should not be covered: err(%v)", err)
+	}
+
+	var invalids = []string{
+		`{"expiration": "tomorrow", "conditions": []}`,
+		`{"expiration": "2030-01-01T00:00:00Z", "conditions": [["regex", "$key", "a"]]}`,
+		`{"expiration": "2030-01-01T00:00:00Z", "conditions": [["eq", "key", "a"]]}`,
+		`{"expiration": "2030-01-01T00:00:00Z", "conditions": [["content-length-range", 10, 1]]}`,
+	}
+	for _, raw := range invalids {
+		if _, err = parsePostPolicy(base64.StdEncoding.EncodeToString([]byte(raw))); err != errPostPolicyInvalid {
+			t.Fatalf("policy should be invalid: %v", raw)
+		}
+	}
+}
+
+func TestPostPolicy_Reader(t *testing.T) {
+	var read = func(data string, min, max int64) error {
+		_, err := ioutil.ReadAll(&postPolicyReader{reader: strings.NewReader(data), min: min, max: max})
+		return err
+	}
+	if err := read("hello", 1, 5); err != nil {
+		t.Fatalf("read fail: err(%v)", err)
+	}
+	if err := read("hello", 0, 4); err != errPostEntityTooLarge {
+		t.Fatalf("file should be too large: err(%v)", err)
+	}
+	if err := read("hello", 6, -1); err != errPostEntityTooSmall {
+		t.Fatalf("file should be too small: err(%v)", err)
+	}
+}
+
+func TestPostForm_Read(t *testing.T) {
+	var buf = new(bytes.Buffer)
+	var writer = multipart.NewWriter(buf)
+	_ = writer.WriteField("Key", "uploads/${filename}")
+	_ = writer.WriteField("Content-Type", "text/plain")
+	part, _ := writer.CreateFormFile("file", "a.txt")
+	_, _ = part.Write([]byte("hello"))
+	_ = writer.WriteField("ignored", "after file")
+	_ = writer.Close()
+
+	form, file, err := readPostForm(multipart.NewReader(buf, writer.Boundary()))
+	if err != nil {
+		t.Fatalf("read form fail: err(%v)", err)
+	}
+	if form["key"] != "uploads/${filename}" || form["content-type"] != "text/plain" || len(form) != 2 {
+		t.Fatalf("unexpected form: %v", form)
+	}
+	if data, _ := ioutil.ReadAll(file); string(data) != "hello" || file.FileName() != "a.txt" {
+		t.Fatalf("unexpected file: name(%v) data(%v)", file.FileName(), string(data))
+	}
+
+	buf.Reset()
+	writer = multipart.NewWriter(buf)
+	_ = writer.WriteField("key", "a")
+	_ = writer.Close()
+	if _, _, err = readPostForm(multipart.NewReader(buf, writer.Boundary())); err != errPostFileMissing {
+		t.Fatalf("file should be missing: err(%v)", err)
+	}
+
+	buf.Reset()
+	writer = multipart.NewWriter(buf)
+	_ = writer.WriteField("x-amz-meta-data", strings.Repeat("a", maxPostPreDataLength+1))
+	_ = writer.Close()
+	if _, _, err = readPostForm(multipart.NewReader(buf, writer.Boundary())); err != errPostPreDataTooLarge {
+		t.Fatalf("form should be too large: err(%v)", err)
+	}
+}
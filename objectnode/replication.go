@@ -0,0 +1,285 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+// https://docs.aws.amazon.com/AmazonS3/latest/dev/replication.html
+
+import (
+	"encoding/xml"
+	"strings"
+
+	"github.com/chubaofs/chubaofs/util/errors"
+)
+
+const (
+	ReplicationRuleStatusEnabled  = "Enabled"
+	ReplicationRuleStatusDisabled = "Disabled"
+
+	// Replication status of the objects
+	ReplicationStatusPending   = "PENDING"
+	ReplicationStatusCompleted = "COMPLETED"
+	ReplicationStatusFailed    = "FAILED"
+	ReplicationStatusReplica   = "REPLICA"
+
+	replicationMaxRules        = 1000
+	replicationMaxIDSize       = 255
+	replicationBucketARNPrefix = "arn:aws:s3:::"
+)
+
+var (
+	errReplicationNoRule              = errors.New("invalid number of replication rules")
+	errReplicationDuplicatedID        = errors.New("duplicated replication rule ID")
+	errReplicationDuplicatedPriority  = errors.New("duplicated replication rule priority")
+	errReplicationInvalidStatus       = errors.New("invalid replication rule status")
+	errReplicationInvalidFilter       = errors.New("invalid replication rule filter")
+	errReplicationInvalidDestination  = errors.New("invalid replication destination")
+	errReplicationDeleteMarkerWithTag = errors.New("delete marker replication can not be enabled with tags")
+)
+
+type ReplicationConfiguration struct {
+	XMLName xml.Name           `xml:"ReplicationConfiguration"`
+	Role    string             `xml:"Role,omitempty"`
+	Rules   []*ReplicationRule `xml:"Rule"`
+}
+
+// ReplicationRule describes the objects to be replicated and the destination of them.
+// The filter is the same as the filter of the lifecycle rules.
+type ReplicationRule struct {
+	ID                      string                   `xml:"ID,omitempty"`
+	Priority                int                      `xml:"Priority,omitempty"`
+	Status                  string                   `xml:"Status"`
+	Prefix                  string                   `xml:"Prefix,omitempty"` // deprecated, replaced by Filter
+	Filter                  *LifecycleFilter         `xml:"Filter,omitempty"`
+	DeleteMarkerReplication *DeleteMarkerReplication `xml:"DeleteMarkerReplication,omitempty"`
+	Destination             *ReplicationDestination  `xml:"Destination"`
+}
+
+type DeleteMarkerReplication struct {
+	Status string `xml:"Status"`
+}
+
+// ReplicationDestination is the bucket the objects are replicated to. Besides the
+// bucket, the endpoint of the object storage interface of the destination cluster
+// and the credential to access the bucket are required, which are the extensions
+// to replicate the objects across the clusters. The secret key is never returned.
+type ReplicationDestination struct {
+	Bucket       string `xml:"Bucket"` // name or ARN of the bucket
+	StorageClass string `xml:"StorageClass,omitempty"`
+	Endpoint     string `xml:"Endpoint"`
+	Region       string `xml:"Region,omitempty"`
+	AccessKey    string `xml:"AccessKey"`
+	SecretKey    string `xml:"SecretKey,omitempty"`
+}
+
+// BucketName returns the name of the destination bucket.
+func (dest *ReplicationDestination) BucketName() string {
+	return strings.TrimPrefix(dest.Bucket, replicationBucketARNPrefix)
+}
+
+func (dest *ReplicationDestination) validate() error {
+	if dest.BucketName() == "" || dest.Endpoint == "" || dest.AccessKey == "" || dest.SecretKey == "" {
+		return errReplicationInvalidDestination
+	}
+	if dest.StorageClass != "" && dest.StorageClass != StorageClassStandard {
+		return errReplicationInvalidDestination
+	}
+	return nil
+}
+
+// Enabled returns true if the objects are replicated following the rule.
+func (rule *ReplicationRule) Enabled() bool {
+	return rule.Status == ReplicationRuleStatusEnabled
+}
+
+// ReplicateDeletes returns true if the deletions of the objects are replicated.
+func (rule *ReplicationRule) ReplicateDeletes() bool {
+	return rule.DeleteMarkerReplication != nil && rule.DeleteMarkerReplication.Status == ReplicationRuleStatusEnabled
+}
+
+// filter returns a lifecycle rule with the same filter, which matches the keys
+// and the tags the same way.
+func (rule *ReplicationRule) filter() *LifecycleRule {
+	return &LifecycleRule{Prefix: rule.Prefix, Filter: rule.Filter}
+}
+
+// Match returns true if the object with the key and the tags is replicated following the rule.
+func (rule *ReplicationRule) Match(key string, tagging *Tagging) bool {
+	var filter = rule.filter()
+	return strings.HasPrefix(key, filter.KeyPrefix()) && filter.MatchTags(tagging)
+}
+
+// HasTags returns true if the rule requires the objects to have some tags.
+func (rule *ReplicationRule) HasTags() bool {
+	return len(rule.filter().Tags()) > 0
+}
+
+func (rule *ReplicationRule) validate() error {
+	if len(rule.ID) > replicationMaxIDSize {
+		return errors.New("rule ID is too long")
+	}
+	if rule.Status != ReplicationRuleStatusEnabled && rule.Status != ReplicationRuleStatusDisabled {
+		return errReplicationInvalidStatus
+	}
+	if rule.Filter != nil {
+		if rule.Prefix != "" {
+			return errReplicationInvalidFilter
+		}
+		if rule.Filter.And != nil && (rule.Filter.Tag != nil || rule.Filter.Prefix != "") {
+			return errReplicationInvalidFilter
+		}
+		if rule.Filter.Tag != nil && rule.Filter.Prefix != "" {
+			return errReplicationInvalidFilter
+		}
+	}
+	if rule.DeleteMarkerReplication != nil {
+		var status = rule.DeleteMarkerReplication.Status
+		if status != ReplicationRuleStatusEnabled && status != ReplicationRuleStatusDisabled {
+			return errReplicationInvalidStatus
+		}
+		// the tags of the deleted objects are unknown
+		if rule.ReplicateDeletes() && rule.HasTags() {
+			return errReplicationDeleteMarkerWithTag
+		}
+	}
+	if rule.Destination == nil {
+		return errReplicationInvalidDestination
+	}
+	return rule.Destination.validate()
+}
+
+func (replication *ReplicationConfiguration) validate() error {
+	if len(replication.Rules) == 0 || len(replication.Rules) > replicationMaxRules {
+		return errReplicationNoRule
+	}
+	var ids = make(map[string]struct{})
+	var priorities = make(map[int]struct{})
+	for _, rule := range replication.Rules {
+		if rule == nil {
+			return errReplicationNoRule
+		}
+		if rule.ID != "" {
+			if _, exist := ids[rule.ID]; exist {
+				return errReplicationDuplicatedID
+			}
+			ids[rule.ID] = struct{}{}
+		}
+		if rule.Priority != 0 {
+			if _, exist := priorities[rule.Priority]; exist {
+				return errReplicationDuplicatedPriority
+			}
+			priorities[rule.Priority] = struct{}{}
+		}
+		if err := rule.validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Rule returns the enabled rule with the highest priority which the object with
+// the key and the tags is replicated following, or nil if there is none.
+func (replication *ReplicationConfiguration) Rule(key string, tagging *Tagging) *ReplicationRule {
+	if replication == nil {
+		return nil
+	}
+	var matched *ReplicationRule
+	for _, rule := range replication.Rules {
+		if !rule.Enabled() || !rule.Match(key, tagging) {
+			continue
+		}
+		if matched == nil || rule.Priority > matched.Priority {
+			matched = rule
+		}
+	}
+	return matched
+}
+
+// HasTags returns true if any of the enabled rules requires the objects to have some tags.
+func (replication *ReplicationConfiguration) HasTags() bool {
+	if replication == nil {
+		return false
+	}
+	for _, rule := range replication.Rules {
+		if rule.Enabled() && rule.HasTags() {
+			return true
+		}
+	}
+	return false
+}
+
+// Masked returns a copy of the configuration without the secret keys of the destinations.
+func (replication *ReplicationConfiguration) Masked() *ReplicationConfiguration {
+	var masked = &ReplicationConfiguration{
+		Role:  replication.Role,
+		Rules: make([]*ReplicationRule, 0, len(replication.Rules)),
+	}
+	for _, rule := range replication.Rules {
+		var r = *rule
+		if rule.Destination != nil {
+			var dest = *rule.Destination
+			dest.SecretKey = ""
+			r.Destination = &dest
+		}
+		masked.Rules = append(masked.Rules, &r)
+	}
+	return masked
+}
+
+// inheritSecretKeys fills the secret keys omitted in the configuration with the ones of
+// the previous configuration with the same endpoints and access keys, so that a configuration
+// returned without the secret keys can be put back.
+func (replication *ReplicationConfiguration) inheritSecretKeys(previous *ReplicationConfiguration) {
+	if previous == nil {
+		return
+	}
+	for _, rule := range replication.Rules {
+		if rule == nil || rule.Destination == nil || rule.Destination.SecretKey != "" {
+			continue
+		}
+		for _, prev := range previous.Rules {
+			if prev.Destination != nil && prev.Destination.Endpoint == rule.Destination.Endpoint &&
+				prev.Destination.AccessKey == rule.Destination.AccessKey {
+				rule.Destination.SecretKey = prev.Destination.SecretKey
+				break
+			}
+		}
+	}
+}
+
+func parseReplicationConfig(bytes []byte, previous *ReplicationConfiguration) (*ReplicationConfiguration, error) {
+	var replication = &ReplicationConfiguration{}
+	if err := xml.Unmarshal(bytes, replication); err != nil {
+		return nil, err
+	}
+	replication.inheritSecretKeys(previous)
+	if err := replication.validate(); err != nil {
+		return nil, err
+	}
+	return replication, nil
+}
+
+func storeBucketReplication(bytes []byte, vol *Volume) (err error) {
+	if err = vol.store.Put(vol.name, bucketRootPath, XAttrKeyOSSReplication, bytes); err != nil {
+		return
+	}
+	return nil
+}
+
+func deleteBucketReplication(vol *Volume) (err error) {
+	if err = vol.store.Delete(vol.name, bucketRootPath, XAttrKeyOSSReplication); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	replicationTaskFileSuffix = ".task"
+	replicationTempFileSuffix = ".tmp"
+)
+
+// replicationQueue is a durable queue of the replication tasks keeping each task in
+// a file of the queue directory. The files are named by the time the tasks are
+// submitted, so that the tasks are recovered in order, and written to temporary files
+// and renamed, so that partially written tasks are never recovered.
+type replicationQueue struct {
+	dir   string
+	limit int
+	count int64
+	seq   uint64
+}
+
+func newReplicationQueue(dir string, limit int) (q *replicationQueue, err error) {
+	if limit <= 0 {
+		limit = defaultReplicationQueueSize
+	}
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return
+	}
+	q = &replicationQueue{dir: dir, limit: limit}
+	var infos []os.FileInfo
+	if infos, err = ioutil.ReadDir(dir); err != nil {
+		return
+	}
+	for _, info := range infos {
+		if strings.HasSuffix(info.Name(), replicationTempFileSuffix) {
+			_ = os.Remove(filepath.Join(dir, info.Name()))
+			continue
+		}
+		if strings.HasSuffix(info.Name(), replicationTaskFileSuffix) {
+			q.count++
+		}
+	}
+	return
+}
+
+// put writes the task to the queue and returns the name of it.
+func (q *replicationQueue) put(data []byte) (name string, err error) {
+	if atomic.LoadInt64(&q.count) >= int64(q.limit) {
+		return "", errReplicationQueueFull
+	}
+	var prefix = fmt.Sprintf("%020d-%010d", time.Now().UnixNano(), atomic.AddUint64(&q.seq, 1))
+	var tempPath = filepath.Join(q.dir, prefix+replicationTempFileSuffix)
+	if err = ioutil.WriteFile(tempPath, data, 0644); err != nil {
+		_ = os.Remove(tempPath)
+		return
+	}
+	if err = os.Rename(tempPath, filepath.Join(q.dir, prefix+replicationTaskFileSuffix)); err != nil {
+		_ = os.Remove(tempPath)
+		return
+	}
+	atomic.AddInt64(&q.count, 1)
+	return prefix + replicationTaskFileSuffix, nil
+}
+
+// list returns the names of the tasks in the queue in order.
+func (q *replicationQueue) list() (names []string, err error) {
+	var infos []os.FileInfo
+	if infos, err = ioutil.ReadDir(q.dir); err != nil {
+		return
+	}
+	names = make([]string, 0, len(infos))
+	for _, info := range infos {
+		if strings.HasSuffix(info.Name(), replicationTaskFileSuffix) {
+			names = append(names, info.Name())
+		}
+	}
+	sort.Strings(names)
+	return
+}
+
+func (q *replicationQueue) get(name string) ([]byte, error) {
+	return ioutil.ReadFile(filepath.Join(q.dir, name))
+}
+
+func (q *replicationQueue) remove(name string) (err error) {
+	if err = os.Remove(filepath.Join(q.dir, name)); err != nil && !os.IsNotExist(err) {
+		return
+	}
+	atomic.AddInt64(&q.count, -1)
+	return nil
+}
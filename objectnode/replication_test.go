@@ -0,0 +1,162 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"encoding/xml"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestReplication_Parse(t *testing.T) {
+	var raw = `<ReplicationConfiguration>
+  <Rule>
+    <ID>logs</ID>
+    <Priority>1</Priority>
+    <Status>Enabled</Status>
+    <Filter><Prefix>logs/</Prefix></Filter>
+    <DeleteMarkerReplication><Status>Enabled</Status></DeleteMarkerReplication>
+    <Destination>
+      <Bucket>arn:aws:s3:::backup</Bucket>
+      <Endpoint>objectnode.dr.chubao.io</Endpoint>
+      <AccessKey>ak</AccessKey>
+      <SecretKey>sk</SecretKey>
+    </Destination>
+  </Rule>
+  <Rule>
+    <ID>tagged</ID>
+    <Priority>2</Priority>
+    <Status>Enabled</Status>
+    <Filter><And><Prefix>logs/</Prefix><Tag><Key>dr</Key><Value>true</Value></Tag></And></Filter>
+    <Destination>
+      <Bucket>tagged</Bucket>
+      <Endpoint>objectnode.dr.chubao.io</Endpoint>
+      <AccessKey>ak</AccessKey>
+      <SecretKey>sk</SecretKey>
+    </Destination>
+  </Rule>
+</ReplicationConfiguration>`
+	replication, err := parseReplicationConfig([]byte(raw), nil)
+	if err != nil {
+		t.Fatalf("parse replication fail: err(%v)", err)
+	}
+	if rule := replication.Rule("data/a", nil); rule != nil {
+		t.Fatalf("rule should not match: %v", rule.ID)
+	}
+	rule := replication.Rule("logs/a", nil)
+	if rule == nil || rule.ID != "logs" || rule.Destination.BucketName() != "backup" || !rule.ReplicateDeletes() {
+		t.Fatalf("unexpected rule: %v", rule)
+	}
+	var tagging = &Tagging{TagSet: []Tag{{Key: "dr", Value: "true"}}}
+	if rule = replication.Rule("logs/a", tagging); rule == nil || rule.ID != "tagged" {
+		t.Fatalf("rule with higher priority should match: %v", rule)
+	}
+
+	// the secret keys are omitted from the returned configuration and kept if put back
+	var data []byte
+	if data, err = xml.Marshal(replication.Masked()); err != nil {
+		t.Fatalf("marshal replication fail: err(%v)", err)
+	}
+	if replication.Rules[0].Destination.SecretKey != "sk" {
+		t.Fatalf("secret key should not be changed")
+	}
+	var masked *ReplicationConfiguration
+	if masked, err = parseReplicationConfig(data, nil); err == nil {
+		t.Fatalf("secret key should be required")
+	}
+	if masked, err = parseReplicationConfig(data, replication); err != nil {
+		t.Fatalf("parse masked replication fail: err(%v)", err)
+	}
+	if masked.Rules[1].Destination.SecretKey != "sk" {
+		t.Fatalf("secret key should be inherited")
+	}
+}
+
+func TestReplication_Validate(t *testing.T) {
+	var destination = `<Destination><Bucket>backup</Bucket><Endpoint>dr</Endpoint>` +
+		`<AccessKey>ak</AccessKey><SecretKey>sk</SecretKey></Destination>`
+	var invalids = []string{
+		`<ReplicationConfiguration></ReplicationConfiguration>`,
+		`<ReplicationConfiguration><Rule><Status>On</Status>` + destination + `</Rule></ReplicationConfiguration>`,
+		`<ReplicationConfiguration><Rule><Status>Enabled</Status></Rule></ReplicationConfiguration>`,
+		`<ReplicationConfiguration><Rule><Status>Enabled</Status><Destination><Bucket>backup</Bucket>` +
+			`</Destination></Rule></ReplicationConfiguration>`,
+		`<ReplicationConfiguration><Rule><Status>Enabled</Status><Prefix>a</Prefix><Filter><Prefix>b</Prefix></Filter>` +
+			destination + `</Rule></ReplicationConfiguration>`,
+		`<ReplicationConfiguration><Rule><Status>Enabled</Status><Filter><Tag><Key>k</Key><Value>v</Value></Tag></Filter>` +
+			`<DeleteMarkerReplication><Status>Enabled</Status></DeleteMarkerReplication>` + destination +
+			`</Rule></ReplicationConfiguration>`,
+		`<ReplicationConfiguration><Rule><Priority>1</Priority><Status>Enabled</Status>` + destination + `</Rule>` +
+			`<Rule><Priority>1</Priority><Status>Enabled</Status>` + destination + `</Rule></ReplicationConfiguration>`,
+	}
+	for _, raw := range invalids {
+		if _, err := parseReplicationConfig([]byte(raw), nil); err == nil {
+			t.Fatalf("replication should be invalid: %v", raw)
+		}
+	}
+}
+
+func TestReplicator_Queue(t *testing.T) {
+	dir, err := ioutil.TempDir("", "replication")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	r, err := NewReplicator(nil, 4, 8, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dest = &ReplicationDestination{Bucket: "arn:aws:s3:::dest"}
+	var tasks = []*replicationTask{
+		{op: replicationOpPut, volume: "vol", key: "a", inode: 1, dest: dest},
+		{op: replicationOpDelete, volume: "vol", key: "a", dest: dest},
+	}
+	for _, task := range tasks {
+		if err = r.enqueue(task); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the queue of the worker is full
+	if err = r.enqueue(&replicationTask{op: replicationOpPut, volume: "vol", key: "a", inode: 2, dest: dest}); err != errReplicationQueueFull {
+		t.Fatalf("enqueue to the full worker err(%v)", err)
+	}
+	// the tasks of a key are dispatched to the same worker in order
+	taskCh := r.taskCh("a")
+	if len(taskCh) != 2 || <-taskCh != tasks[0] || <-taskCh != tasks[1] {
+		t.Fatalf("tasks of the key are not dispatched to the same worker")
+	}
+
+	// the tasks are kept in the queue directory without the destination until they are removed
+	names, err := r.queue.list()
+	if err != nil || len(names) != 2 || names[0] != tasks[0].name || names[1] != tasks[1].name {
+		t.Fatalf("queued tasks(%v) err(%v)", names, err)
+	}
+	data, err := r.queue.get(names[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := `{"op":0,"volume":"vol","key":"a","inode":1}`; string(data) != expected {
+		t.Fatalf("queued task(%s) expected(%v)", data, expected)
+	}
+	r.remove(tasks[0])
+	recovered, err := NewReplicator(nil, 4, 8, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names, err = recovered.queue.list(); err != nil || len(names) != 1 || names[0] != tasks[1].name {
+		t.Fatalf("recovered tasks(%v) err(%v)", names, err)
+	}
+}
@@ -0,0 +1,673 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/chubaofs/chubaofs/util"
+	"github.com/chubaofs/chubaofs/util/errors"
+	"github.com/chubaofs/chubaofs/util/exporter"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+const (
+	defaultReplicationWorkers   = 8
+	defaultReplicationQueueSize = 100000
+	defaultReplicationRegion    = "default"
+
+	replicationMaxRetries         = 10
+	replicationRetryInterval      = 5 * time.Second
+	replicationMaxRetryInterval   = 10 * time.Minute
+	replicationMetricsInterval    = 10 * time.Second
+	replicationMultipartThreshold = 128 * util.MB
+	replicationPartSize           = 64 * util.MB
+
+	replicationUnsignedPayload = "UNSIGNED-PAYLOAD"
+)
+
+const (
+	metricReplicationBacklog   = "replication_backlog"
+	metricReplicationRetrying  = "replication_retrying"
+	metricReplicationCompleted = "replication_completed"
+	metricReplicationRetry     = "replication_retry"
+	metricReplicationFailed    = "replication_failed"
+)
+
+var (
+	errReplicationQueueFull   = errors.New("replication queue is full")
+	errReplicationSSECustomer = errors.New("objects encrypted with customer keys can not be replicated")
+	errReplicationStopped     = errors.New("replicator is stopped")
+)
+
+type replicationOp int
+
+const (
+	replicationOpPut replicationOp = iota
+	replicationOpDelete
+)
+
+func (op replicationOp) String() string {
+	switch op {
+	case replicationOpPut:
+		return "put"
+	case replicationOpDelete:
+		return "delete"
+	default:
+		return "unknown"
+	}
+}
+
+type replicationTask struct {
+	op      replicationOp
+	volume  string
+	key     string
+	inode   uint64 // inode of the object to be replicated, zero for the deletions
+	dest    *ReplicationDestination
+	retries int
+	name    string // name of the task in the durable queue
+}
+
+// replicationRecord is the task kept in the durable queue. The destination is
+// resolved by the replication rules again when the task is recovered, so that
+// the credentials of the destination are never written to the queue.
+type replicationRecord struct {
+	Op     replicationOp `json:"op"`
+	Volume string        `json:"volume"`
+	Key    string        `json:"key"`
+	Inode  uint64        `json:"inode,omitempty"`
+}
+
+func (t *replicationTask) String() string {
+	return fmt.Sprintf("op(%v) volume(%v) key(%v) inode(%v) endpoint(%v) bucket(%v) retries(%v)",
+		t.op, t.volume, t.key, t.inode, t.dest.Endpoint, t.dest.BucketName(), t.retries)
+}
+
+// Replicator replicates the objects to the destination buckets of the replication
+// rules asynchronously. The objects written or deleted through the object node are
+// submitted to the replication queue, and replicated by the workers through the
+// object storage interface of the destination clusters. The failed replications
+// are retried with backoff, and the objects are marked as failed to be replicated
+// once the retries are exhausted or the queue is full.
+//
+// The tasks of a key are always replicated by the same worker chosen by the hash
+// of the key, so that they are replicated in the order they are submitted, and the
+// state of the source is checked again before the task is replicated, since a
+// retried task may be passed by the later ones.
+//
+// The tasks are kept in the durable queue of the queue directory until they are
+// completed or failed, and recovered when the object node is started. Without the
+// queue directory the queue is kept in memory only, so the objects pending
+// replication when the object node is stopped stay in the pending status until
+// they are written again.
+type Replicator struct {
+	vm      *VolumeManager
+	taskChs []chan *replicationTask // queue of each worker
+	queue   *replicationQueue       // durable queue of the tasks, nil if kept in memory
+	clients sync.Map                // mapping: endpoint and credential -> *s3.S3
+	started string                  // tasks queued before it are recovered
+
+	retrying int64 // number of the tasks waiting to be retried
+	running  int64 // number of the tasks being replicated
+
+	ctx       context.Context
+	cancel    context.CancelFunc
+	closeOnce sync.Once
+	closeCh   chan struct{}
+}
+
+// NewReplicator returns the replicator with the durable queue in the queue
+// directory, or with the queue kept in memory if the directory is empty.
+func NewReplicator(vm *VolumeManager, workers, queueSize int, queueDir string) (r *Replicator, err error) {
+	if workers <= 0 {
+		workers = defaultReplicationWorkers
+	}
+	if queueSize <= 0 {
+		queueSize = defaultReplicationQueueSize
+	}
+	var chSize = queueSize / workers
+	if chSize < 1 {
+		chSize = 1
+	}
+	r = &Replicator{
+		vm:      vm,
+		taskChs: make([]chan *replicationTask, workers),
+		closeCh: make(chan struct{}),
+	}
+	for i := range r.taskChs {
+		r.taskChs[i] = make(chan *replicationTask, chSize)
+	}
+	if queueDir != "" {
+		if r.queue, err = newReplicationQueue(queueDir, queueSize); err != nil {
+			return nil, err
+		}
+	}
+	r.ctx, r.cancel = context.WithCancel(context.Background())
+	return
+}
+
+func (r *Replicator) Start() {
+	for _, taskCh := range r.taskChs {
+		go r.run(taskCh)
+	}
+	if r.queue != nil {
+		r.started = fmt.Sprintf("%020d", time.Now().UnixNano())
+		go r.recover()
+	}
+	go r.reportMetrics()
+}
+
+func (r *Replicator) Stop() {
+	r.closeOnce.Do(func() {
+		close(r.closeCh)
+		r.cancel()
+	})
+}
+
+func (r *Replicator) stopped() bool {
+	select {
+	case <-r.closeCh:
+		return true
+	default:
+		return false
+	}
+}
+
+// Backlog returns the number of the tasks not replicated yet.
+func (r *Replicator) Backlog() int64 {
+	var backlog = atomic.LoadInt64(&r.retrying) + atomic.LoadInt64(&r.running)
+	for _, taskCh := range r.taskChs {
+		backlog += int64(len(taskCh))
+	}
+	return backlog
+}
+
+// destination returns the destination of the replication rule matching the object,
+// or nil if the object is not to be replicated.
+func (r *Replicator) destination(vol *Volume, op replicationOp, key string) (dest *ReplicationDestination, err error) {
+	var replication *ReplicationConfiguration
+	if replication, err = vol.metaLoader.loadReplication(); err != nil || replication == nil {
+		return
+	}
+	var tagging *Tagging
+	if op == replicationOpPut && replication.HasTags() {
+		if tagging, err = vol.objectTagging(key); err != nil {
+			log.LogWarnf("Replicator: load object tagging fail: volume(%v) key(%v) err(%v)", vol.Name(), key, err)
+			err = nil
+		}
+	}
+	var rule = replication.Rule(key, tagging)
+	if rule == nil || (op == replicationOpDelete && !rule.ReplicateDeletes()) {
+		return
+	}
+	return rule.Destination, nil
+}
+
+// SubmitPut submits the object written to the volume to be replicated if it matches
+// any enabled replication rule of the bucket, and marks the object as pending.
+func (r *Replicator) SubmitPut(vol *Volume, info *FSFileInfo) {
+	if info == nil || info.Mode.IsDir() {
+		return
+	}
+	dest, err := r.destination(vol, replicationOpPut, info.Path)
+	if err != nil || dest == nil {
+		return
+	}
+	var task = &replicationTask{
+		op:     replicationOpPut,
+		volume: vol.Name(),
+		key:    info.Path,
+		inode:  info.Inode,
+		dest:   dest,
+	}
+	// the status is set before the task is submitted, so that it is never
+	// overwritten after the object is replicated
+	if err = vol.setReplicationStatus(info.Inode, ReplicationStatusPending); err != nil {
+		return
+	}
+	info.ReplicationStatus = ReplicationStatusPending
+	if err = r.enqueue(task); err != nil {
+		log.LogWarnf("Replicator: submit fail: task(%v) err(%v)", task, err)
+		exporter.NewCounter(metricReplicationFailed).Add(1)
+		if err = vol.setReplicationStatus(info.Inode, ReplicationStatusFailed); err == nil {
+			info.ReplicationStatus = ReplicationStatusFailed
+		}
+	}
+}
+
+// SubmitDelete submits the deletion of the object to be replicated if it matches
+// any enabled replication rule of the bucket with the delete marker replication.
+func (r *Replicator) SubmitDelete(vol *Volume, key string) {
+	dest, err := r.destination(vol, replicationOpDelete, key)
+	if err != nil || dest == nil {
+		return
+	}
+	var task = &replicationTask{
+		op:     replicationOpDelete,
+		volume: vol.Name(),
+		key:    key,
+		dest:   dest,
+	}
+	if err = r.enqueue(task); err != nil {
+		log.LogWarnf("Replicator: submit fail: task(%v) err(%v)", task, err)
+		exporter.NewCounter(metricReplicationFailed).Add(1)
+	}
+}
+
+// enqueue keeps the task in the durable queue, and dispatches it to the worker.
+func (r *Replicator) enqueue(task *replicationTask) (err error) {
+	if r.stopped() {
+		return errReplicationStopped
+	}
+	if r.queue != nil {
+		var data []byte
+		if data, err = json.Marshal(&replicationRecord{Op: task.op, Volume: task.volume, Key: task.key, Inode: task.inode}); err != nil {
+			return
+		}
+		if task.name, err = r.queue.put(data); err != nil {
+			return
+		}
+	}
+	if err = r.dispatch(task); err != nil {
+		r.remove(task)
+	}
+	return
+}
+
+// dispatch submits the task to the worker of the key without blocking.
+func (r *Replicator) dispatch(task *replicationTask) error {
+	if r.stopped() {
+		return errReplicationStopped
+	}
+	select {
+	case r.taskCh(task.key) <- task:
+		return nil
+	default:
+		return errReplicationQueueFull
+	}
+}
+
+// taskCh returns the queue of the worker replicating the tasks of the key.
+func (r *Replicator) taskCh(key string) chan *replicationTask {
+	var h = fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return r.taskChs[h.Sum32()%uint32(len(r.taskChs))]
+}
+
+// remove removes the task completed or failed from the durable queue.
+func (r *Replicator) remove(task *replicationTask) {
+	if r.queue == nil || task.name == "" {
+		return
+	}
+	if err := r.queue.remove(task.name); err != nil {
+		log.LogWarnf("Replicator: remove task fail: task(%v) err(%v)", task, err)
+	}
+}
+
+// recover dispatches the tasks left in the durable queue by the last run, the
+// destinations of which are resolved by the current replication rules.
+func (r *Replicator) recover() {
+	names, err := r.queue.list()
+	if err != nil {
+		log.LogErrorf("Replicator: list queue fail: err(%v)", err)
+		return
+	}
+	var recovered int
+	for _, name := range names {
+		if name >= r.started {
+			// submitted since the replicator is started
+			break
+		}
+		var data []byte
+		if data, err = r.queue.get(name); err != nil {
+			log.LogWarnf("Replicator: read task fail: name(%v) err(%v)", name, err)
+			continue
+		}
+		var record = new(replicationRecord)
+		if err = json.Unmarshal(data, record); err != nil {
+			log.LogWarnf("Replicator: unmarshal task fail: name(%v) err(%v)", name, err)
+			_ = r.queue.remove(name)
+			continue
+		}
+		var task = &replicationTask{op: record.Op, volume: record.Volume, key: record.Key, inode: record.Inode, name: name}
+		var vol *Volume
+		if vol, err = r.vm.Volume(task.volume); err != nil {
+			// kept to be recovered by the next run
+			log.LogWarnf("Replicator: load volume fail: name(%v) volume(%v) err(%v)", name, task.volume, err)
+			continue
+		}
+		if task.dest, err = r.destination(vol, task.op, task.key); err != nil {
+			log.LogWarnf("Replicator: load replication fail: name(%v) volume(%v) err(%v)", name, task.volume, err)
+			continue
+		}
+		if task.dest == nil {
+			// the replication rule has been removed
+			_ = r.queue.remove(name)
+			continue
+		}
+		select {
+		case r.taskCh(task.key) <- task:
+			recovered++
+		case <-r.closeCh:
+			return
+		}
+	}
+	log.LogInfof("Replicator: recover complete: tasks(%v)", recovered)
+}
+
+func (r *Replicator) run(taskCh chan *replicationTask) {
+	for {
+		select {
+		case task := <-taskCh:
+			atomic.AddInt64(&r.running, 1)
+			r.process(task)
+			atomic.AddInt64(&r.running, -1)
+		case <-r.closeCh:
+			return
+		}
+	}
+}
+
+func (r *Replicator) reportMetrics() {
+	t := time.NewTicker(replicationMetricsInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			exporter.NewGauge(metricReplicationBacklog).Set(float64(r.Backlog()))
+			exporter.NewGauge(metricReplicationRetrying).Set(float64(atomic.LoadInt64(&r.retrying)))
+		case <-r.closeCh:
+			return
+		}
+	}
+}
+
+func (r *Replicator) process(task *replicationTask) {
+	var err error
+	switch task.op {
+	case replicationOpPut:
+		err = r.replicatePut(task)
+	case replicationOpDelete:
+		err = r.replicateDelete(task)
+	}
+	if err == nil {
+		log.LogDebugf("Replicator: replicate complete: task(%v)", task)
+		exporter.NewCounter(metricReplicationCompleted).Add(1)
+		r.remove(task)
+		return
+	}
+	if r.stopped() {
+		return
+	}
+	if err != errReplicationSSECustomer && task.retries < replicationMaxRetries {
+		log.LogWarnf("Replicator: replicate fail and retry: task(%v) err(%v)", task, err)
+		exporter.NewCounter(metricReplicationRetry).Add(1)
+		r.retry(task)
+		return
+	}
+	log.LogErrorf("Replicator: replicate fail: task(%v) err(%v)", task, err)
+	r.fail(task)
+}
+
+// retry submits the task again after the backoff interval doubled by each retry.
+func (r *Replicator) retry(task *replicationTask) {
+	var interval = replicationRetryInterval << uint(task.retries)
+	if interval > replicationMaxRetryInterval || interval <= 0 {
+		interval = replicationMaxRetryInterval
+	}
+	task.retries++
+	atomic.AddInt64(&r.retrying, 1)
+	time.AfterFunc(interval, func() {
+		atomic.AddInt64(&r.retrying, -1)
+		if err := r.dispatch(task); err != nil && err != errReplicationStopped {
+			log.LogErrorf("Replicator: retry fail: task(%v) err(%v)", task, err)
+			r.fail(task)
+		}
+	})
+}
+
+func (r *Replicator) fail(task *replicationTask) {
+	r.remove(task)
+	exporter.NewCounter(metricReplicationFailed).Add(1)
+	exporter.Warning(fmt.Sprintf("replicate object fail: %v", task))
+	if task.op != replicationOpPut {
+		return
+	}
+	if vol, err := r.vm.Volume(task.volume); err == nil {
+		_ = vol.setReplicationStatus(task.inode, ReplicationStatusFailed)
+	}
+}
+
+// client returns the client of the object storage interface of the destination.
+func (r *Replicator) client(dest *ReplicationDestination) *s3.S3 {
+	var key = dest.Endpoint + "/" + dest.AccessKey + "/" + dest.SecretKey
+	if value, ok := r.clients.Load(key); ok {
+		return value.(*s3.S3)
+	}
+	var region = dest.Region
+	if region == "" {
+		region = defaultReplicationRegion
+	}
+	sess := session.Must(session.NewSession())
+	ac := aws.NewConfig()
+	ac.Endpoint = aws.String(dest.Endpoint)
+	ac.DisableSSL = aws.Bool(true) // used unless the scheme is specified by the endpoint
+	ac.Region = aws.String(region)
+	ac.Credentials = credentials.NewStaticCredentials(dest.AccessKey, dest.SecretKey, "")
+	ac.S3ForcePathStyle = aws.Bool(true)
+	ac.MaxRetries = aws.Int(0) // retried by the replicator since the bodies are streamed
+	value, _ := r.clients.LoadOrStore(key, s3.New(sess, ac))
+	return value.(*s3.S3)
+}
+
+// replicaRequest marks the request as a replication, and the data is streamed
+// without the signature of the payload.
+func replicaRequest(req *request.Request) {
+	req.HTTPRequest.Header.Set(HeaderNameXAmzReplicationStatus, ReplicationStatusReplica)
+	req.HTTPRequest.Header.Set(HeaderNameXAmzContentHash, replicationUnsignedPayload)
+}
+
+func (r *Replicator) replicatePut(task *replicationTask) (err error) {
+	var vol *Volume
+	if vol, err = r.vm.Volume(task.volume); err != nil {
+		return
+	}
+	var info *FSFileInfo
+	if info, err = vol.ObjectMeta(task.key); err == syscall.ENOENT {
+		// the object has been deleted
+		return nil
+	}
+	if err != nil {
+		return
+	}
+	if info.Inode != task.inode {
+		// the object has been overwritten and submitted again
+		return nil
+	}
+	if info.SSECustomerKeyMD5 != "" {
+		return errReplicationSSECustomer
+	}
+	var tagging *Tagging
+	if tagging, err = vol.objectTagging(info.Path); err != nil {
+		return
+	}
+
+	var client = r.client(task.dest)
+	if info.Size > replicationMultipartThreshold {
+		err = r.putMultipart(client, vol, info, tagging, task.dest)
+	} else {
+		err = r.putObject(client, vol, info, tagging, task.dest)
+	}
+	if err != nil {
+		return
+	}
+	return vol.setReplicationStatus(info.Inode, ReplicationStatusCompleted)
+}
+
+func (r *Replicator) putObject(client *s3.S3, vol *Volume, info *FSFileInfo, tagging *Tagging, dest *ReplicationDestination) (err error) {
+	var reader = vol.objectReader(info.Path, info.Inode, 0, uint64(info.Size))
+	defer reader.Close()
+	var input = &s3.PutObjectInput{
+		Bucket:        aws.String(dest.BucketName()),
+		Key:           aws.String(info.Path),
+		Body:          aws.ReadSeekCloser(reader),
+		ContentLength: aws.Int64(info.Size),
+		Metadata:      aws.StringMap(info.Metadata),
+	}
+	if info.MIMEType != "" {
+		input.ContentType = aws.String(info.MIMEType)
+	}
+	if info.Disposition != "" {
+		input.ContentDisposition = aws.String(info.Disposition)
+	}
+	if info.CacheControl != "" {
+		input.CacheControl = aws.String(info.CacheControl)
+	}
+	if tagging != nil && len(tagging.TagSet) > 0 {
+		input.Tagging = aws.String(tagging.Encode())
+	}
+	if info.SSE != "" {
+		input.ServerSideEncryption = aws.String(info.SSE)
+	}
+	_, err = client.PutObjectWithContext(r.ctx, input, replicaRequest, replicaExpires(info.Expires))
+	return
+}
+
+func (r *Replicator) putMultipart(client *s3.S3, vol *Volume, info *FSFileInfo, tagging *Tagging, dest *ReplicationDestination) (err error) {
+	var bucket, key = aws.String(dest.BucketName()), aws.String(info.Path)
+	var input = &s3.CreateMultipartUploadInput{
+		Bucket:   bucket,
+		Key:      key,
+		Metadata: aws.StringMap(info.Metadata),
+	}
+	if info.MIMEType != "" {
+		input.ContentType = aws.String(info.MIMEType)
+	}
+	if info.Disposition != "" {
+		input.ContentDisposition = aws.String(info.Disposition)
+	}
+	if info.CacheControl != "" {
+		input.CacheControl = aws.String(info.CacheControl)
+	}
+	if tagging != nil && len(tagging.TagSet) > 0 {
+		input.Tagging = aws.String(tagging.Encode())
+	}
+	if info.SSE != "" {
+		input.ServerSideEncryption = aws.String(info.SSE)
+	}
+	var output *s3.CreateMultipartUploadOutput
+	if output, err = client.CreateMultipartUploadWithContext(r.ctx, input, replicaRequest, replicaExpires(info.Expires)); err != nil {
+		return
+	}
+	var uploadID = output.UploadId
+	defer func() {
+		if err != nil {
+			_, _ = client.AbortMultipartUpload(&s3.AbortMultipartUploadInput{Bucket: bucket, Key: key, UploadId: uploadID})
+		}
+	}()
+
+	var parts = make([]*s3.CompletedPart, 0, info.Size/replicationPartSize+1)
+	for offset, partNumber := int64(0), int64(1); offset < info.Size; offset, partNumber = offset+replicationPartSize, partNumber+1 {
+		var size = info.Size - offset
+		if size > replicationPartSize {
+			size = replicationPartSize
+		}
+		var reader = vol.objectReader(info.Path, info.Inode, uint64(offset), uint64(size))
+		var partOutput *s3.UploadPartOutput
+		partOutput, err = client.UploadPartWithContext(r.ctx, &s3.UploadPartInput{
+			Bucket:        bucket,
+			Key:           key,
+			UploadId:      uploadID,
+			PartNumber:    aws.Int64(partNumber),
+			Body:          aws.ReadSeekCloser(reader),
+			ContentLength: aws.Int64(size),
+		}, replicaRequest)
+		_ = reader.Close()
+		if err != nil {
+			return
+		}
+		parts = append(parts, &s3.CompletedPart{ETag: partOutput.ETag, PartNumber: aws.Int64(partNumber)})
+	}
+	_, err = client.CompleteMultipartUploadWithContext(r.ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          bucket,
+		Key:             key,
+		UploadId:        uploadID,
+		MultipartUpload: &s3.CompletedMultipartUpload{Parts: parts},
+	}, replicaRequest)
+	return
+}
+
+// replicaExpires returns the option setting the expires header of the replica,
+// which is kept in the raw format.
+func replicaExpires(expires string) request.Option {
+	return func(req *request.Request) {
+		if expires != "" {
+			req.HTTPRequest.Header.Set(HeaderNameExpires, expires)
+		}
+	}
+}
+
+func (r *Replicator) replicateDelete(task *replicationTask) (err error) {
+	var vol *Volume
+	if vol, err = r.vm.Volume(task.volume); err != nil {
+		return
+	}
+	if _, err = vol.ObjectMeta(task.key); err == nil {
+		// the object has been written again and submitted again
+		return nil
+	}
+	if err != syscall.ENOENT {
+		return
+	}
+	var client = r.client(task.dest)
+	_, err = client.DeleteObjectWithContext(r.ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(task.dest.BucketName()),
+		Key:    aws.String(task.key),
+	}, replicaRequest)
+	return
+}
+
+// isReplicaRequest returns true if the request is a replication from another cluster,
+// which is not replicated again.
+func isReplicaRequest(r *http.Request) bool {
+	return r.Header.Get(HeaderNameXAmzReplicationStatus) == ReplicationStatusReplica
+}
+
+// requestReplicationStatus returns the replication status of the object written by the request.
+func requestReplicationStatus(r *http.Request) string {
+	if isReplicaRequest(r) {
+		return ReplicationStatusReplica
+	}
+	return ""
+}
+
+func setReplicationResponseHeaders(w http.ResponseWriter, info *FSFileInfo) {
+	if info.ReplicationStatus != "" {
+		w.Header()[HeaderNameXAmzReplicationStatus] = []string{info.ReplicationStatus}
+	}
+}
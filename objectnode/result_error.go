@@ -96,6 +96,11 @@ var (
 	ObjectModeConflict                  = &ErrorCode{ErrorCode: "ObjectModeConflict", ErrorMessage: "Object already exists but file mode conflicts", StatusCode: http.StatusConflict}
 	NotModified                         = &ErrorCode{ErrorCode: "MaxContentLength", ErrorMessage: "Not modified.", StatusCode: http.StatusNotModified}
 	NoSuchUpload                        = &ErrorCode{ErrorCode: "NoSuchUpload", ErrorMessage: "The specified upload does not exist.", StatusCode: http.StatusNotFound}
+	NoSuchVersion                       = &ErrorCode{ErrorCode: "NoSuchVersion", ErrorMessage: "The specified version does not exist.", StatusCode: http.StatusNotFound}
+	MethodNotAllowed                    = &ErrorCode{ErrorCode: "MethodNotAllowed", ErrorMessage: "The specified method is not allowed against this resource.", StatusCode: http.StatusMethodNotAllowed}
+	IllegalVersioningConfiguration      = &ErrorCode{ErrorCode: "IllegalVersioningConfigurationException", ErrorMessage: "The versioning configuration specified in the request is invalid.", StatusCode: http.StatusBadRequest}
+	NoSuchLifecycleConfiguration        = &ErrorCode{ErrorCode: "NoSuchLifecycleConfiguration", ErrorMessage: "The lifecycle configuration does not exist.", StatusCode: http.StatusNotFound}
+	MalformedXML                        = &ErrorCode{ErrorCode: "MalformedXML", ErrorMessage: "The XML you provided was not well-formed or did not validate against our published schema.", StatusCode: http.StatusBadRequest}
 	OverMaxRecordSize                   = &ErrorCode{ErrorCode: "OverMaxRecordSize", ErrorMessage: "The length of a record in the input or result is greater than maxCharsPerRecord of 1 MB.", StatusCode: http.StatusBadRequest}
 	CopySourceSizeTooLarge              = &ErrorCode{ErrorCode: "InvalidRequest", ErrorMessage: "The specified copy source is larger than the maximum allowable size for a copy source: 5368709120", StatusCode: http.StatusBadRequest}
 	InvalidPartOrder                    = &ErrorCode{ErrorCode: "InvalidPartOrder", ErrorMessage: "The list of parts was not in ascending order. Parts list must be specified in order by part number.", StatusCode: http.StatusBadRequest}
@@ -104,6 +109,36 @@ var (
 	TagsGreaterThen10                   = &ErrorCode{ErrorCode: "BadRequest", ErrorMessage: "Object tags cannot be greater than 10", StatusCode: http.StatusBadRequest}
 	InvalidTagKey                       = &ErrorCode{ErrorCode: "InvalidTag", ErrorMessage: "The TagKey you have provided is invalid", StatusCode: http.StatusBadRequest}
 	InvalidTagValue                     = &ErrorCode{ErrorCode: "InvalidTag", ErrorMessage: "The TagValue you have provided is invalid", StatusCode: http.StatusBadRequest}
+	InvalidEncryptionAlgorithm          = &ErrorCode{ErrorCode: "InvalidEncryptionAlgorithmError", ErrorMessage: "The encryption request you specified is not valid. The valid value is AES256.", StatusCode: http.StatusBadRequest}
+	InvalidSSECustomerKey               = &ErrorCode{ErrorCode: "InvalidArgument", ErrorMessage: "The secret key was invalid for the specified algorithm.", StatusCode: http.StatusBadRequest}
+	SSECustomerKeyMD5Mismatch           = &ErrorCode{ErrorCode: "InvalidArgument", ErrorMessage: "The calculated MD5 hash of the key did not match the hash that was provided.", StatusCode: http.StatusBadRequest}
+	SSECustomerKeyRequired              = &ErrorCode{ErrorCode: "InvalidRequest", ErrorMessage: "The object was stored using a form of Server Side Encryption. The correct parameters must be provided to retrieve the object.", StatusCode: http.StatusBadRequest}
+	SSECustomerKeyMismatch              = &ErrorCode{ErrorCode: "AccessDenied", ErrorMessage: "The provided encryption key does not match the key used to encrypt the object.", StatusCode: http.StatusForbidden}
+	SSENotApplicable                    = &ErrorCode{ErrorCode: "InvalidRequest", ErrorMessage: "The encryption parameters are not applicable to this object.", StatusCode: http.StatusBadRequest}
+	SSEUnavailable                      = &ErrorCode{ErrorCode: "NotImplemented", ErrorMessage: "Server side encryption with managed keys is not enabled.", StatusCode: http.StatusNotImplemented}
+	NoSuchEncryptionConfiguration       = &ErrorCode{ErrorCode: "ServerSideEncryptionConfigurationNotFoundError", ErrorMessage: "The server side encryption configuration was not found.", StatusCode: http.StatusNotFound}
+	ObjectLocked                        = &ErrorCode{ErrorCode: "AccessDenied", ErrorMessage: "Access Denied because object protected by object lock.", StatusCode: http.StatusForbidden}
+	NoSuchObjectLockConfiguration       = &ErrorCode{ErrorCode: "NoSuchObjectLockConfiguration", ErrorMessage: "The specified object does not have a ObjectLock configuration.", StatusCode: http.StatusNotFound}
+	InvalidRetentionPeriod              = &ErrorCode{ErrorCode: "InvalidArgument", ErrorMessage: "The retain until date must be in the future.", StatusCode: http.StatusBadRequest}
+	InvalidObjectLock                   = &ErrorCode{ErrorCode: "InvalidArgument", ErrorMessage: "The object lock mode, retain until date or legal hold status is invalid.", StatusCode: http.StatusBadRequest}
+	NoSuchWebsiteConfiguration          = &ErrorCode{ErrorCode: "NoSuchWebsiteConfiguration", ErrorMessage: "The specified bucket does not have a website configuration.", StatusCode: http.StatusNotFound}
+	InvalidWebsiteConfiguration         = &ErrorCode{ErrorCode: "InvalidArgument", ErrorMessage: "The website configuration is invalid.", StatusCode: http.StatusBadRequest}
+	ReplicationConfigurationNotFound    = &ErrorCode{ErrorCode: "ReplicationConfigurationNotFoundError", ErrorMessage: "The replication configuration was not found.", StatusCode: http.StatusNotFound}
+	InvalidReplicationConfiguration     = &ErrorCode{ErrorCode: "InvalidArgument", ErrorMessage: "The replication configuration is invalid.", StatusCode: http.StatusBadRequest}
+	InvalidNotificationConfiguration    = &ErrorCode{ErrorCode: "InvalidArgument", ErrorMessage: "The notification configuration is invalid.", StatusCode: http.StatusBadRequest}
+	InvalidNotificationDestination      = &ErrorCode{ErrorCode: "InvalidArgument", ErrorMessage: "Unable to validate the following destination configurations.", StatusCode: http.StatusBadRequest}
+	MalformedPOSTRequest                = &ErrorCode{ErrorCode: "MalformedPOSTRequest", ErrorMessage: "The body of your POST request is not well-formed multipart/form-data.", StatusCode: http.StatusBadRequest}
+	MaxPostPreDataLengthExceeded        = &ErrorCode{ErrorCode: "MaxPostPreDataLengthExceededError", ErrorMessage: "Your POST request fields preceding the upload file were too large.", StatusCode: http.StatusBadRequest}
+	InvalidPolicyDocument               = &ErrorCode{ErrorCode: "InvalidPolicyDocument", ErrorMessage: "The content of the form does not meet the conditions specified in the policy document.", StatusCode: http.StatusBadRequest}
+	PostPolicyExpired                   = &ErrorCode{ErrorCode: "AccessDenied", ErrorMessage: "Invalid according to Policy: Policy expired.", StatusCode: http.StatusForbidden}
+	InvalidExpressionType               = &ErrorCode{ErrorCode: "InvalidExpressionType", ErrorMessage: "The ExpressionType is invalid. Only SQL expressions are supported.", StatusCode: http.StatusBadRequest}
+	InvalidRequestParameter             = &ErrorCode{ErrorCode: "InvalidRequestParameter", ErrorMessage: "The value of a parameter in SelectRequest element is invalid.", StatusCode: http.StatusBadRequest}
+	ParseSelectFailure                  = &ErrorCode{ErrorCode: "ParseSelectFailure", ErrorMessage: "The SQL expression contains an error.", StatusCode: http.StatusBadRequest}
+	CSVParsingError                     = &ErrorCode{ErrorCode: "CSVParsingError", ErrorMessage: "Encountered an error parsing the CSV file.", StatusCode: http.StatusBadRequest}
+	JSONParsingError                    = &ErrorCode{ErrorCode: "JSONParsingError", ErrorMessage: "Encountered an error parsing the JSON file.", StatusCode: http.StatusBadRequest}
+	CastFailed                          = &ErrorCode{ErrorCode: "CastFailed", ErrorMessage: "Attempt to convert from one data type to another using CAST failed in the SQL expression.", StatusCode: http.StatusBadRequest}
+	InvalidDataType                     = &ErrorCode{ErrorCode: "InvalidDataType", ErrorMessage: "The SQL expression contains an invalid data type.", StatusCode: http.StatusBadRequest}
+	DivisionByZero                      = &ErrorCode{ErrorCode: "DivisionByZero", ErrorMessage: "Division by zero in the SQL expression.", StatusCode: http.StatusBadRequest}
 )
 
 func HttpStatusErrorCode(code int) *ErrorCode {
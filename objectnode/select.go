@@ -0,0 +1,317 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+// https://docs.aws.amazon.com/AmazonS3/latest/API/API_SelectObjectContent.html
+
+import (
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/chubaofs/chubaofs/util/errors"
+)
+
+const (
+	SelectExpressionTypeSQL = "SQL"
+
+	SelectCompressionNone  = "NONE"
+	SelectCompressionGZIP  = "GZIP"
+	SelectCompressionBZIP2 = "BZIP2"
+
+	SelectFileHeaderUse    = "USE"
+	SelectFileHeaderIgnore = "IGNORE"
+	SelectFileHeaderNone   = "NONE"
+
+	SelectJSONTypeDocument = "DOCUMENT"
+	SelectJSONTypeLines    = "LINES"
+
+	SelectQuoteFieldsAlways   = "ALWAYS"
+	SelectQuoteFieldsAsNeeded = "ASNEEDED"
+
+	// size of the payload of each Records event
+	selectRecordsChunkSize = 128 * 1024
+)
+
+var (
+	errSelectInvalidExpressionType = errors.New("invalid expression type of select request")
+	errSelectInvalidParameter      = errors.New("invalid parameter of select request")
+)
+
+type SelectCSVInput struct {
+	FileHeaderInfo             string `xml:"FileHeaderInfo,omitempty"`
+	Comments                   string `xml:"Comments,omitempty"`
+	QuoteEscapeCharacter       string `xml:"QuoteEscapeCharacter,omitempty"`
+	RecordDelimiter            string `xml:"RecordDelimiter,omitempty"`
+	FieldDelimiter             string `xml:"FieldDelimiter,omitempty"`
+	QuoteCharacter             string `xml:"QuoteCharacter,omitempty"`
+	AllowQuotedRecordDelimiter bool   `xml:"AllowQuotedRecordDelimiter,omitempty"`
+}
+
+func (c *SelectCSVInput) recordDelimiter() string {
+	if c.RecordDelimiter == "" {
+		return "\n"
+	}
+	return c.RecordDelimiter
+}
+
+func (c *SelectCSVInput) fieldDelimiter() rune {
+	if c.FieldDelimiter == "" {
+		return ','
+	}
+	return []rune(c.FieldDelimiter)[0]
+}
+
+type SelectJSONInput struct {
+	Type string `xml:"Type,omitempty"`
+}
+
+type SelectInputSerialization struct {
+	CompressionType string           `xml:"CompressionType,omitempty"`
+	CSV             *SelectCSVInput  `xml:"CSV,omitempty"`
+	JSON            *SelectJSONInput `xml:"JSON,omitempty"`
+	Parquet         *struct{}        `xml:"Parquet,omitempty"`
+}
+
+type SelectCSVOutput struct {
+	QuoteFields          string `xml:"QuoteFields,omitempty"`
+	QuoteEscapeCharacter string `xml:"QuoteEscapeCharacter,omitempty"`
+	RecordDelimiter      string `xml:"RecordDelimiter,omitempty"`
+	FieldDelimiter       string `xml:"FieldDelimiter,omitempty"`
+	QuoteCharacter       string `xml:"QuoteCharacter,omitempty"`
+}
+
+type SelectJSONOutput struct {
+	RecordDelimiter string `xml:"RecordDelimiter,omitempty"`
+}
+
+type SelectOutputSerialization struct {
+	CSV  *SelectCSVOutput  `xml:"CSV,omitempty"`
+	JSON *SelectJSONOutput `xml:"JSON,omitempty"`
+}
+
+type SelectRequestProgress struct {
+	Enabled bool `xml:"Enabled"`
+}
+
+type SelectScanRange struct {
+	Start *int64 `xml:"Start,omitempty"`
+	End   *int64 `xml:"End,omitempty"`
+}
+
+type SelectObjectContentRequest struct {
+	XMLName             xml.Name                  `xml:"SelectObjectContentRequest"`
+	Expression          string                    `xml:"Expression"`
+	ExpressionType      string                    `xml:"ExpressionType"`
+	InputSerialization  SelectInputSerialization  `xml:"InputSerialization"`
+	OutputSerialization SelectOutputSerialization `xml:"OutputSerialization"`
+	RequestProgress     SelectRequestProgress     `xml:"RequestProgress"`
+	ScanRange           *SelectScanRange          `xml:"ScanRange,omitempty"`
+
+	statement *selectStatement
+}
+
+func parseSelectRequest(data []byte) (request *SelectObjectContentRequest, err error) {
+	request = &SelectObjectContentRequest{}
+	if err = xml.Unmarshal(data, request); err != nil {
+		return nil, err
+	}
+	if strings.ToUpper(request.ExpressionType) != SelectExpressionTypeSQL {
+		return nil, errSelectInvalidExpressionType
+	}
+	if err = request.validate(); err != nil {
+		return nil, err
+	}
+	if request.statement, err = parseSelectSQL(request.Expression); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+func (r *SelectObjectContentRequest) validate() error {
+	// scanning a range of the object is not supported
+	if r.ScanRange != nil {
+		return errSelectInvalidParameter
+	}
+	var input, output = &r.InputSerialization, &r.OutputSerialization
+	switch strings.ToUpper(input.CompressionType) {
+	case "", SelectCompressionNone, SelectCompressionGZIP, SelectCompressionBZIP2:
+	default:
+		return errSelectInvalidParameter
+	}
+	if input.Parquet != nil || (input.CSV == nil) == (input.JSON == nil) || (output.CSV == nil) == (output.JSON == nil) {
+		return errSelectInvalidParameter
+	}
+	if csv := input.CSV; csv != nil {
+		switch strings.ToUpper(csv.FileHeaderInfo) {
+		case "", SelectFileHeaderUse, SelectFileHeaderIgnore, SelectFileHeaderNone:
+		default:
+			return errSelectInvalidParameter
+		}
+		// only the double quote is supported as the quote character of the CSV input
+		if csv.QuoteCharacter != "" && csv.QuoteCharacter != "\"" ||
+			csv.QuoteEscapeCharacter != "" && csv.QuoteEscapeCharacter != "\"" {
+			return errSelectInvalidParameter
+		}
+		var delimiter = csv.fieldDelimiter()
+		if utf8.RuneCountInString(csv.FieldDelimiter) > 1 || delimiter == '"' || delimiter == '\r' || delimiter == '\n' ||
+			utf8.RuneCountInString(csv.RecordDelimiter) > 2 || utf8.RuneCountInString(csv.Comments) > 1 ||
+			csv.Comments != "" && []rune(csv.Comments)[0] == delimiter {
+			return errSelectInvalidParameter
+		}
+	}
+	if json := input.JSON; json != nil {
+		switch strings.ToUpper(json.Type) {
+		case SelectJSONTypeDocument, SelectJSONTypeLines:
+		default:
+			return errSelectInvalidParameter
+		}
+	}
+	if csv := output.CSV; csv != nil {
+		switch strings.ToUpper(csv.QuoteFields) {
+		case "", SelectQuoteFieldsAlways, SelectQuoteFieldsAsNeeded:
+		default:
+			return errSelectInvalidParameter
+		}
+	}
+	return nil
+}
+
+// selectCountingReader counts the bytes read through it.
+type selectCountingReader struct {
+	reader io.Reader
+	count  int64
+}
+
+func (r *selectCountingReader) Read(p []byte) (n int, err error) {
+	n, err = r.reader.Read(p)
+	r.count += int64(n)
+	return
+}
+
+// selectOutput buffers the result records and writes them as Records events in chunks.
+type selectOutput struct {
+	writer    selectRecordWriter
+	buf       *bytes.Buffer
+	stream    *selectEventStream
+	progress  bool
+	stats     func() *SelectStats
+	bytesSent int64
+}
+
+func (o *selectOutput) Write(names []string, values []interface{}) error {
+	if err := o.writer.Write(names, values); err != nil {
+		return err
+	}
+	if o.buf.Len() >= selectRecordsChunkSize {
+		return o.flush()
+	}
+	return nil
+}
+
+func (o *selectOutput) flush() (err error) {
+	if o.buf.Len() == 0 {
+		return nil
+	}
+	if err = o.stream.writeRecords(o.buf.Bytes()); err != nil {
+		return
+	}
+	o.bytesSent += int64(o.buf.Len())
+	o.buf.Reset()
+	if o.progress {
+		err = o.stream.writeStats(selectEventProgress, o.stats())
+	}
+	return
+}
+
+// execute evaluates the SQL expression over the records of the object read from the reader,
+// and writes the results to the event stream. The errors which occur after the response
+// has been started are written to the event stream as error messages.
+func (r *SelectObjectContentRequest) execute(reader io.Reader, stream *selectEventStream) (err error) {
+	var scanned = &selectCountingReader{reader: reader}
+	var processed = &selectCountingReader{reader: scanned}
+	switch strings.ToUpper(r.InputSerialization.CompressionType) {
+	case SelectCompressionGZIP:
+		var gzipReader *gzip.Reader
+		if gzipReader, err = gzip.NewReader(scanned); err != nil {
+			return errSelectInvalidParameter
+		}
+		defer gzipReader.Close()
+		processed.reader = gzipReader
+	case SelectCompressionBZIP2:
+		processed.reader = bzip2.NewReader(scanned)
+	}
+
+	var records selectRecordReader
+	if r.InputSerialization.CSV != nil {
+		records = newSelectCSVReader(r.InputSerialization.CSV, processed)
+	} else {
+		records = newSelectJSONReader(processed)
+	}
+
+	var output = &selectOutput{buf: new(bytes.Buffer), stream: stream, progress: r.RequestProgress.Enabled}
+	if r.OutputSerialization.CSV != nil {
+		output.writer = newSelectCSVWriter(r.OutputSerialization.CSV, output.buf)
+	} else {
+		output.writer = newSelectJSONWriter(r.OutputSerialization.JSON, output.buf)
+	}
+	output.stats = func() *SelectStats {
+		return &SelectStats{BytesScanned: scanned.count, BytesProcessed: processed.count, BytesReturned: output.bytesSent}
+	}
+
+	if err = r.statement.execute(records, output); err != nil {
+		return
+	}
+	if err = output.flush(); err != nil {
+		return
+	}
+	if err = stream.writeStats(selectEventStats, output.stats()); err != nil {
+		return
+	}
+	return stream.writeEnd()
+}
+
+func selectErrorCode(err error) *ErrorCode {
+	if perr, ok := err.(*SelectParseError); ok {
+		return &ErrorCode{ErrorCode: ParseSelectFailure.ErrorCode, ErrorMessage: perr.Error(), StatusCode: http.StatusBadRequest}
+	}
+	switch err {
+	case errSelectInvalidExpressionType:
+		return InvalidExpressionType
+	case errSelectInvalidParameter:
+		return InvalidRequestParameter
+	case errSelectRecordTooLarge:
+		return OverMaxRecordSize
+	case errSelectCSVParsing:
+		return CSVParsingError
+	case errSelectJSONParsing:
+		return JSONParsingError
+	case errSelectCastFailed:
+		return CastFailed
+	case errSelectInvalidDataType:
+		return InvalidDataType
+	case errSelectDivisionByZero:
+		return DivisionByZero
+	}
+	if _, ok := err.(*xml.SyntaxError); ok {
+		return MalformedXML
+	}
+	return InternalErrorCode(err)
+}
@@ -0,0 +1,722 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"encoding/json"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/chubaofs/chubaofs/util/errors"
+)
+
+// The values of the SQL expressions are nil (NULL or missing), bool, int64, float64, string,
+// *selectObject and []interface{}. The strings are converted to numbers implicitly when
+// they are computed or compared with numbers, since all the fields of CSV are strings.
+
+var (
+	errSelectInvalidDataType = errors.New("invalid data type in SQL expression")
+	errSelectCastFailed      = errors.New("CAST failed in SQL expression")
+	errSelectDivisionByZero  = errors.New("division by zero in SQL expression")
+)
+
+var selectAggregateFuncs = map[string]struct{}{
+	"COUNT": {}, "SUM": {}, "AVG": {}, "MIN": {}, "MAX": {},
+}
+
+// number of arguments of the functions, negative if variable
+var selectFuncArities = map[string]int{
+	"LOWER": 1, "UPPER": 1, "TRIM": 1, "CHAR_LENGTH": 1, "CHARACTER_LENGTH": 1, "COALESCE": -1,
+}
+
+var selectCastTypes = map[string]bool{
+	"INT": true, "INTEGER": true, "FLOAT": true, "DECIMAL": true, "NUMERIC": true,
+	"STRING": true, "VARCHAR": true, "BOOL": true, "BOOLEAN": true,
+}
+
+type selectExpr interface {
+	eval(record *selectRecord) (interface{}, error)
+}
+
+type selectProjection struct {
+	expr  selectExpr
+	alias string
+}
+
+type selectStatement struct {
+	projections []*selectProjection // empty if all the columns are selected
+	aggregates  []*selectAggregate
+	alias       string
+	where       selectExpr
+	limit       int64 // negative if not limited
+}
+
+// selectRecordWriter writes the result records of the statement.
+type selectRecordWriter interface {
+	Write(names []string, values []interface{}) error
+}
+
+func (s *selectStatement) execute(reader selectRecordReader, writer selectRecordWriter) (err error) {
+	var returned int64
+	for s.limit < 0 || returned < s.limit || len(s.aggregates) > 0 {
+		var record *selectRecord
+		if record, err = reader.Read(); err == io.EOF {
+			break
+		}
+		if err != nil {
+			return
+		}
+		if s.where != nil {
+			var value interface{}
+			if value, err = s.where.eval(record); err != nil {
+				return
+			}
+			if value != true {
+				continue
+			}
+		}
+		if len(s.aggregates) > 0 {
+			for _, aggregate := range s.aggregates {
+				if err = aggregate.accumulate(record); err != nil {
+					return
+				}
+			}
+			continue
+		}
+		if err = s.project(record, writer); err != nil {
+			return
+		}
+		returned++
+	}
+	if len(s.aggregates) > 0 && s.limit != 0 {
+		// the aggregate functions produce a single record
+		return s.project(nil, writer)
+	}
+	return nil
+}
+
+func (s *selectStatement) project(record *selectRecord, writer selectRecordWriter) (err error) {
+	if len(s.projections) == 0 {
+		names, values := record.columns()
+		return writer.Write(names, values)
+	}
+	var names = make([]string, len(s.projections))
+	var values = make([]interface{}, len(s.projections))
+	for i, projection := range s.projections {
+		if values[i], err = projection.expr.eval(record); err != nil {
+			return
+		}
+		switch {
+		case projection.alias != "":
+			names[i] = projection.alias
+		case isSelectColumn(projection.expr):
+			var path = projection.expr.(*selectColumn).path
+			names[i] = path[len(path)-1].name
+		default:
+			names[i] = "_" + strconv.Itoa(i+1)
+		}
+	}
+	return writer.Write(names, values)
+}
+
+func isSelectColumn(expr selectExpr) bool {
+	_, ok := expr.(*selectColumn)
+	return ok
+}
+
+type selectLiteral struct {
+	value interface{}
+}
+
+func (e *selectLiteral) eval(record *selectRecord) (interface{}, error) {
+	return e.value, nil
+}
+
+type selectColumnPart struct {
+	name   string
+	quoted bool // quoted names are case sensitive
+}
+
+func (p selectColumnPart) match(name string) bool {
+	if p.quoted {
+		return p.name == name
+	}
+	return strings.EqualFold(p.name, name)
+}
+
+// position returns the index of the positional column name like _1.
+func (p selectColumnPart) position() (int, bool) {
+	if p.quoted || !strings.HasPrefix(p.name, "_") {
+		return 0, false
+	}
+	index, err := strconv.Atoi(p.name[1:])
+	if err != nil || index < 1 {
+		return 0, false
+	}
+	return index - 1, true
+}
+
+type selectColumn struct {
+	path []selectColumnPart
+}
+
+func (e *selectColumn) eval(record *selectRecord) (interface{}, error) {
+	if record == nil {
+		return nil, nil
+	}
+	return record.lookup(e.path), nil
+}
+
+type selectLogical struct {
+	op          string
+	left, right selectExpr
+}
+
+func (e *selectLogical) eval(record *selectRecord) (interface{}, error) {
+	left, err := e.left.eval(record)
+	if err != nil {
+		return nil, err
+	}
+	var short = e.op == "OR" // short circuit value
+	if l := selectBool(left); l != nil && *l == short {
+		return short, nil
+	}
+	right, err := e.right.eval(record)
+	if err != nil {
+		return nil, err
+	}
+	var l, r = selectBool(left), selectBool(right)
+	switch {
+	case r != nil && *r == short:
+		return short, nil
+	case l == nil || r == nil:
+		return nil, nil
+	}
+	return !short, nil
+}
+
+type selectNot struct {
+	expr selectExpr
+}
+
+func (e *selectNot) eval(record *selectRecord) (interface{}, error) {
+	value, err := e.expr.eval(record)
+	if err != nil {
+		return nil, err
+	}
+	if b := selectBool(value); b != nil {
+		return !*b, nil
+	}
+	return nil, nil
+}
+
+type selectComparison struct {
+	op          string
+	left, right selectExpr
+}
+
+func (e *selectComparison) eval(record *selectRecord) (interface{}, error) {
+	left, err := e.left.eval(record)
+	if err != nil {
+		return nil, err
+	}
+	right, err := e.right.eval(record)
+	if err != nil {
+		return nil, err
+	}
+	if left == nil || right == nil {
+		return nil, nil
+	}
+	c, ok := selectCompare(left, right)
+	if !ok {
+		return e.op == "!=" || e.op == "<>", nil
+	}
+	switch e.op {
+	case "=":
+		return c == 0, nil
+	case "!=", "<>":
+		return c != 0, nil
+	case "<":
+		return c < 0, nil
+	case "<=":
+		return c <= 0, nil
+	case ">":
+		return c > 0, nil
+	default:
+		return c >= 0, nil
+	}
+}
+
+type selectIsNull struct {
+	expr selectExpr
+	not  bool
+}
+
+func (e *selectIsNull) eval(record *selectRecord) (interface{}, error) {
+	value, err := e.expr.eval(record)
+	if err != nil {
+		return nil, err
+	}
+	return (value == nil) != e.not, nil
+}
+
+type selectLike struct {
+	expr    selectExpr
+	pattern selectExpr
+	escape  selectExpr
+	not     bool
+
+	// the compiled regular expression of the last pattern
+	lastPattern string
+	lastRegexp  *regexp.Regexp
+}
+
+func (e *selectLike) eval(record *selectRecord) (interface{}, error) {
+	var values = make([]interface{}, 3)
+	var err error
+	for i, expr := range []selectExpr{e.expr, e.pattern, e.escape} {
+		if expr == nil {
+			continue
+		}
+		if values[i], err = expr.eval(record); err != nil {
+			return nil, err
+		}
+		if values[i] == nil {
+			return nil, nil
+		}
+	}
+	var pattern, escape = selectString(values[1]), selectString(values[2])
+	if utf8.RuneCountInString(escape) > 1 {
+		return nil, errSelectInvalidDataType
+	}
+	if e.lastRegexp == nil || e.lastPattern != pattern+"\x00"+escape {
+		if e.lastRegexp, err = compileSelectLike(pattern, escape); err != nil {
+			return nil, err
+		}
+		e.lastPattern = pattern + "\x00" + escape
+	}
+	return e.lastRegexp.MatchString(selectString(values[0])) != e.not, nil
+}
+
+// compileSelectLike converts the LIKE pattern, in which '%' matches any characters and
+// '_' matches a single character, to the regular expression.
+func compileSelectLike(pattern, escape string) (*regexp.Regexp, error) {
+	var sb strings.Builder
+	sb.WriteString("(?s)^")
+	var escaped bool
+	for _, c := range pattern {
+		switch {
+		case escaped:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+			escaped = false
+		case escape != "" && string(c) == escape:
+			escaped = true
+		case c == '%':
+			sb.WriteString(".*")
+		case c == '_':
+			sb.WriteString(".")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	if escaped {
+		return nil, errSelectInvalidDataType
+	}
+	sb.WriteString("$")
+	return regexp.Compile(sb.String())
+}
+
+type selectIn struct {
+	expr selectExpr
+	list []selectExpr
+	not  bool
+}
+
+func (e *selectIn) eval(record *selectRecord) (interface{}, error) {
+	value, err := e.expr.eval(record)
+	if err != nil || value == nil {
+		return nil, err
+	}
+	for _, expr := range e.list {
+		var item interface{}
+		if item, err = expr.eval(record); err != nil {
+			return nil, err
+		}
+		if c, ok := selectCompare(value, item); ok && c == 0 {
+			return !e.not, nil
+		}
+	}
+	return e.not, nil
+}
+
+type selectBetween struct {
+	expr      selectExpr
+	low, high selectExpr
+	not       bool
+}
+
+func (e *selectBetween) eval(record *selectRecord) (interface{}, error) {
+	var values = make([]interface{}, 3)
+	var err error
+	for i, expr := range []selectExpr{e.expr, e.low, e.high} {
+		if values[i], err = expr.eval(record); err != nil {
+			return nil, err
+		}
+		if values[i] == nil {
+			return nil, nil
+		}
+	}
+	low, lowOK := selectCompare(values[0], values[1])
+	high, highOK := selectCompare(values[0], values[2])
+	return (lowOK && highOK && low >= 0 && high <= 0) != e.not, nil
+}
+
+type selectArithmetic struct {
+	op          string
+	left, right selectExpr
+}
+
+func (e *selectArithmetic) eval(record *selectRecord) (interface{}, error) {
+	left, err := e.left.eval(record)
+	if err != nil {
+		return nil, err
+	}
+	right, err := e.right.eval(record)
+	if err != nil {
+		return nil, err
+	}
+	if left == nil || right == nil {
+		return nil, nil
+	}
+	if e.op == "||" {
+		return selectString(left) + selectString(right), nil
+	}
+	l, lOK := selectNumber(left)
+	r, rOK := selectNumber(right)
+	if !lOK || !rOK {
+		return nil, errSelectInvalidDataType
+	}
+	li, lInt := l.(int64)
+	ri, rInt := r.(int64)
+	if lInt && rInt {
+		switch e.op {
+		case "+":
+			return li + ri, nil
+		case "-":
+			return li - ri, nil
+		case "*":
+			return li * ri, nil
+		}
+		if ri == 0 {
+			return nil, errSelectDivisionByZero
+		}
+		if e.op == "/" {
+			return li / ri, nil
+		}
+		return li % ri, nil
+	}
+	var lf, rf = selectFloat(l), selectFloat(r)
+	switch e.op {
+	case "+":
+		return lf + rf, nil
+	case "-":
+		return lf - rf, nil
+	case "*":
+		return lf * rf, nil
+	}
+	if rf == 0 {
+		return nil, errSelectDivisionByZero
+	}
+	if e.op == "/" {
+		return lf / rf, nil
+	}
+	return float64(int64(lf) % int64(rf)), nil
+}
+
+type selectCast struct {
+	expr selectExpr
+	typ  string
+}
+
+func (e *selectCast) eval(record *selectRecord) (interface{}, error) {
+	value, err := e.expr.eval(record)
+	if err != nil || value == nil {
+		return nil, err
+	}
+	switch e.typ {
+	case "INT", "INTEGER":
+		if n, ok := selectNumber(value); ok {
+			if f, isFloat := n.(float64); isFloat {
+				return int64(f), nil
+			}
+			return n, nil
+		}
+	case "FLOAT", "DECIMAL", "NUMERIC":
+		if n, ok := selectNumber(value); ok {
+			return selectFloat(n), nil
+		}
+	case "STRING", "VARCHAR":
+		return selectString(value), nil
+	case "BOOL", "BOOLEAN":
+		if b := selectBool(value); b != nil {
+			return *b, nil
+		}
+		if n, ok := selectNumber(value); ok {
+			return selectFloat(n) != 0, nil
+		}
+	}
+	return nil, errSelectCastFailed
+}
+
+type selectFunction struct {
+	name string
+	args []selectExpr
+}
+
+func (e *selectFunction) eval(record *selectRecord) (interface{}, error) {
+	var values = make([]interface{}, len(e.args))
+	var err error
+	for i, arg := range e.args {
+		if values[i], err = arg.eval(record); err != nil {
+			return nil, err
+		}
+	}
+	if e.name == "COALESCE" {
+		for _, value := range values {
+			if value != nil {
+				return value, nil
+			}
+		}
+		return nil, nil
+	}
+	if values[0] == nil {
+		return nil, nil
+	}
+	var str = selectString(values[0])
+	switch e.name {
+	case "LOWER":
+		return strings.ToLower(str), nil
+	case "UPPER":
+		return strings.ToUpper(str), nil
+	case "TRIM":
+		return strings.TrimSpace(str), nil
+	default:
+		return int64(utf8.RuneCountInString(str)), nil
+	}
+}
+
+// selectAggregate is the aggregate function, which accumulates the values of all the
+// records matching the condition before it is evaluated.
+type selectAggregate struct {
+	name string
+	arg  selectExpr // nil for COUNT(*)
+
+	count int64
+	sum   interface{} // int64 or float64
+	value interface{} // value of MIN or MAX
+}
+
+func (e *selectAggregate) accumulate(record *selectRecord) error {
+	if e.arg == nil {
+		e.count++
+		return nil
+	}
+	value, err := e.arg.eval(record)
+	if err != nil || value == nil {
+		return err
+	}
+	if n, ok := selectNumber(value); ok {
+		value = n
+	}
+	switch e.name {
+	case "SUM", "AVG":
+		if !selectIsNumber(value) {
+			return errSelectInvalidDataType
+		}
+		if e.sum == nil {
+			e.sum = value
+		} else if si, ok := e.sum.(int64); ok && selectIsInt(value) {
+			e.sum = si + value.(int64)
+		} else {
+			e.sum = selectFloat(e.sum) + selectFloat(value)
+		}
+	case "MIN", "MAX":
+		if e.value == nil {
+			e.value = value
+		} else if c, ok := selectCompare(value, e.value); ok && (e.name == "MIN" && c < 0 || e.name == "MAX" && c > 0) {
+			e.value = value
+		}
+	}
+	e.count++
+	return nil
+}
+
+func (e *selectAggregate) eval(record *selectRecord) (interface{}, error) {
+	switch e.name {
+	case "COUNT":
+		return e.count, nil
+	case "SUM":
+		return e.sum, nil
+	case "AVG":
+		if e.count == 0 {
+			return nil, nil
+		}
+		return selectFloat(e.sum) / float64(e.count), nil
+	default:
+		return e.value, nil
+	}
+}
+
+// selectBool returns the boolean value, or nil if the value is not boolean.
+func selectBool(value interface{}) *bool {
+	var b bool
+	switch v := value.(type) {
+	case bool:
+		b = v
+	case string:
+		switch strings.ToLower(strings.TrimSpace(v)) {
+		case "true":
+			b = true
+		case "false":
+			b = false
+		default:
+			return nil
+		}
+	default:
+		return nil
+	}
+	return &b
+}
+
+func selectIsNumber(value interface{}) bool {
+	switch value.(type) {
+	case int64, float64:
+		return true
+	}
+	return false
+}
+
+func selectIsInt(value interface{}) bool {
+	_, ok := value.(int64)
+	return ok
+}
+
+// selectNumber converts the value to int64 or float64.
+func selectNumber(value interface{}) (interface{}, bool) {
+	switch v := value.(type) {
+	case int64, float64:
+		return v, true
+	case string:
+		var s = strings.TrimSpace(v)
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return i, true
+		}
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f, true
+		}
+	}
+	return nil, false
+}
+
+func selectFloat(value interface{}) float64 {
+	switch v := value.(type) {
+	case int64:
+		return float64(v)
+	case float64:
+		return v
+	}
+	return 0
+}
+
+func selectString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		data, _ := json.Marshal(v)
+		return string(data)
+	}
+}
+
+// selectCompare compares the values, and returns false if they are not comparable.
+// The values are compared as numbers if any of them is a number.
+func selectCompare(a, b interface{}) (int, bool) {
+	if selectIsNumber(a) || selectIsNumber(b) {
+		na, aOK := selectNumber(a)
+		nb, bOK := selectNumber(b)
+		if !aOK || !bOK {
+			return 0, false
+		}
+		if ia, ok := na.(int64); ok {
+			if ib, ok := nb.(int64); ok {
+				return compareInt64(ia, ib), true
+			}
+		}
+		var fa, fb = selectFloat(na), selectFloat(nb)
+		switch {
+		case fa < fb:
+			return -1, true
+		case fa > fb:
+			return 1, true
+		}
+		return 0, true
+	}
+	if ba, ok := a.(bool); ok {
+		if bb := selectBool(b); bb != nil {
+			return compareInt64(boolToInt64(ba), boolToInt64(*bb)), true
+		}
+		return 0, false
+	}
+	if bb, ok := b.(bool); ok {
+		if ba := selectBool(a); ba != nil {
+			return compareInt64(boolToInt64(*ba), boolToInt64(bb)), true
+		}
+		return 0, false
+	}
+	sa, aOK := a.(string)
+	sb, bOK := b.(string)
+	if !aOK || !bOK {
+		return 0, false
+	}
+	return strings.Compare(sa, sb), true
+}
+
+func compareInt64(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func boolToInt64(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,143 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+// https://docs.aws.amazon.com/AmazonS3/latest/API/RESTSelectObjectAppendix.html
+//
+// Each message of the event stream is encoded as:
+//
+//   | total length (4) | headers length (4) | prelude CRC (4) | headers | payload | message CRC (4) |
+//
+// and each header is encoded as:
+//
+//   | name length (1) | name | value type (1) | value length (2) | value |
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/xml"
+	"hash/crc32"
+	"io"
+	"net/http"
+)
+
+const (
+	selectEventHeaderEventType   = ":event-type"
+	selectEventHeaderContentType = ":content-type"
+	selectEventHeaderMessageType = ":message-type"
+	selectEventHeaderErrorCode   = ":error-code"
+	selectEventHeaderErrorMsg    = ":error-message"
+
+	selectEventValueTypeString = 7
+
+	selectMessageTypeEvent = "event"
+	selectMessageTypeError = "error"
+
+	selectEventRecords  = "Records"
+	selectEventProgress = "Progress"
+	selectEventStats    = "Stats"
+	selectEventEnd      = "End"
+)
+
+// SelectStats is the payload of the Stats and Progress events.
+type SelectStats struct {
+	BytesScanned   int64 `xml:"BytesScanned"`
+	BytesProcessed int64 `xml:"BytesProcessed"`
+	BytesReturned  int64 `xml:"BytesReturned"`
+}
+
+type selectEventHeader struct {
+	name  string
+	value string
+}
+
+// selectEventStream writes the messages of the event stream to the response.
+type selectEventStream struct {
+	writer  io.Writer
+	flusher http.Flusher
+}
+
+func newSelectEventStream(w http.ResponseWriter) *selectEventStream {
+	var stream = &selectEventStream{writer: w}
+	stream.flusher, _ = w.(http.Flusher)
+	return stream
+}
+
+func encodeSelectMessage(headers []selectEventHeader, payload []byte) []byte {
+	var headerBuf = new(bytes.Buffer)
+	for _, header := range headers {
+		headerBuf.WriteByte(byte(len(header.name)))
+		headerBuf.WriteString(header.name)
+		headerBuf.WriteByte(selectEventValueTypeString)
+		_ = binary.Write(headerBuf, binary.BigEndian, uint16(len(header.value)))
+		headerBuf.WriteString(header.value)
+	}
+	var totalLength = 12 + headerBuf.Len() + len(payload) + 4
+	var message = make([]byte, 12, totalLength)
+	binary.BigEndian.PutUint32(message[0:4], uint32(totalLength))
+	binary.BigEndian.PutUint32(message[4:8], uint32(headerBuf.Len()))
+	binary.BigEndian.PutUint32(message[8:12], crc32.ChecksumIEEE(message[0:8]))
+	message = append(message, headerBuf.Bytes()...)
+	message = append(message, payload...)
+	var crc = make([]byte, 4)
+	binary.BigEndian.PutUint32(crc, crc32.ChecksumIEEE(message))
+	return append(message, crc...)
+}
+
+func (s *selectEventStream) writeMessage(headers []selectEventHeader, payload []byte) error {
+	if _, err := s.writer.Write(encodeSelectMessage(headers, payload)); err != nil {
+		return err
+	}
+	if s.flusher != nil {
+		s.flusher.Flush()
+	}
+	return nil
+}
+
+func (s *selectEventStream) writeEvent(eventType, contentType string, payload []byte) error {
+	var headers = []selectEventHeader{{name: selectEventHeaderEventType, value: eventType}}
+	if contentType != "" {
+		headers = append(headers, selectEventHeader{name: selectEventHeaderContentType, value: contentType})
+	}
+	headers = append(headers, selectEventHeader{name: selectEventHeaderMessageType, value: selectMessageTypeEvent})
+	return s.writeMessage(headers, payload)
+}
+
+func (s *selectEventStream) writeRecords(payload []byte) error {
+	return s.writeEvent(selectEventRecords, HeaderValueTypeStream, payload)
+}
+
+func (s *selectEventStream) writeStats(eventType string, stats *SelectStats) error {
+	var payload, err = xml.Marshal(struct {
+		XMLName xml.Name
+		*SelectStats
+	}{XMLName: xml.Name{Local: eventType}, SelectStats: stats})
+	if err != nil {
+		return err
+	}
+	return s.writeEvent(eventType, HeaderValueContentTypeXML, payload)
+}
+
+func (s *selectEventStream) writeEnd() error {
+	return s.writeEvent(selectEventEnd, "", nil)
+}
+
+func (s *selectEventStream) writeError(errorCode *ErrorCode) error {
+	return s.writeMessage([]selectEventHeader{
+		{name: selectEventHeaderErrorCode, value: errorCode.ErrorCode},
+		{name: selectEventHeaderErrorMsg, value: errorCode.ErrorMessage},
+		{name: selectEventHeaderMessageType, value: selectMessageTypeError},
+	}, nil)
+}
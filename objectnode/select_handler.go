@@ -0,0 +1,121 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"syscall"
+
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// Select object content
+// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_SelectObjectContent.html
+func (o *ObjectNode) selectObjectContentHandler(w http.ResponseWriter, r *http.Request) {
+	var (
+		err       error
+		errorCode *ErrorCode
+	)
+
+	defer func() {
+		if errorCode != nil {
+			_ = errorCode.ServeResponse(w, r)
+			return
+		}
+	}()
+
+	var param = ParseRequestParam(r)
+	if param.Bucket() == "" {
+		errorCode = InvalidBucketName
+		return
+	}
+	if param.Object() == "" {
+		errorCode = InvalidKey
+		return
+	}
+	var vol *Volume
+	if vol, err = o.getVol(param.Bucket()); err != nil {
+		errorCode = NoSuchBucket
+		return
+	}
+
+	var bytes []byte
+	if bytes, err = ioutil.ReadAll(r.Body); err != nil && err != io.EOF {
+		errorCode = InternalErrorCode(err)
+		return
+	}
+	var request *SelectObjectContentRequest
+	if request, err = parseSelectRequest(bytes); err != nil {
+		log.LogDebugf("selectObjectContentHandler: parse request fail: requestID(%v) volume(%v) path(%v) err(%v)",
+			GetRequestID(r), vol.Name(), param.Object(), err)
+		errorCode = selectErrorCode(err)
+		return
+	}
+
+	var fileInfo *FSFileInfo
+	fileInfo, err = vol.ObjectMeta(param.Object())
+	if err == syscall.ENOENT {
+		errorCode = NoSuchKey
+		return
+	}
+	if err == errDeleteMarker {
+		w.Header()[HeaderNameXAmzDeleteMarker] = []string{"true"}
+		errorCode = MethodNotAllowed
+		return
+	}
+	if err != nil {
+		log.LogErrorf("selectObjectContentHandler: get file meta fail: requestID(%v) volume(%v) path(%v) err(%v)",
+			GetRequestID(r), vol.Name(), param.Object(), err)
+		errorCode = InternalErrorCode(err)
+		return
+	}
+
+	// Checking the customer key of the server side encryption
+	var sse *SSEOption
+	if sse, err = parseSSEOption(r.Header); err == nil {
+		err = checkObjectSSE(fileInfo, sse)
+	}
+	if err != nil {
+		errorCode = sseErrorCode(err)
+		return
+	}
+
+	// The object is streamed to the evaluation through the pipe, which is closed when the
+	// evaluation finishes, so that the reading stops early if the LIMIT is reached.
+	var reader, writer = io.Pipe()
+	defer reader.Close()
+	go func() {
+		var readErr error
+		if fileInfo.Size > 0 {
+			readErr = vol.ReadFileVersion(param.Object(), "", writer, 0, uint64(fileInfo.Size), sse)
+		}
+		_ = writer.CloseWithError(readErr)
+	}()
+
+	w.Header()[HeaderNameContentType] = []string{HeaderValueTypeStream}
+	w.WriteHeader(http.StatusOK)
+	var stream = newSelectEventStream(w)
+	if err = request.execute(reader, stream); err != nil {
+		log.LogWarnf("selectObjectContentHandler: select fail: requestID(%v) volume(%v) path(%v) expression(%v) err(%v)",
+			GetRequestID(r), vol.Name(), param.Object(), request.Expression, err)
+		// the response has been started, so the error is sent as a message of the event stream
+		_ = stream.writeError(selectErrorCode(err))
+		return
+	}
+	log.LogDebugf("selectObjectContentHandler: select finish: requestID(%v) volume(%v) path(%v) expression(%v)",
+		GetRequestID(r), vol.Name(), param.Object(), request.Expression)
+}
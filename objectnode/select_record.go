@@ -0,0 +1,411 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/chubaofs/chubaofs/util/errors"
+)
+
+// maxSelectRecordSize is the max size of a record in the input or result.
+const maxSelectRecordSize = 1 << 20
+
+var (
+	errSelectRecordTooLarge = errors.New("record is larger than 1 MB")
+	errSelectCSVParsing     = errors.New("parse CSV record fail")
+	errSelectJSONParsing    = errors.New("parse JSON record fail")
+)
+
+// selectObject is the JSON object which keeps the order of the keys.
+type selectObject struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func newSelectObject() *selectObject {
+	return &selectObject{values: make(map[string]interface{})}
+}
+
+func (o *selectObject) set(key string, value interface{}) {
+	if _, exist := o.values[key]; !exist {
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = value
+}
+
+func (o *selectObject) lookup(part selectColumnPart) (interface{}, bool) {
+	if value, exist := o.values[part.name]; exist {
+		return value, true
+	}
+	if !part.quoted {
+		for _, key := range o.keys {
+			if strings.EqualFold(key, part.name) {
+				return o.values[key], true
+			}
+		}
+	}
+	return nil, false
+}
+
+func (o *selectObject) MarshalJSON() ([]byte, error) {
+	var buf = bytes.NewBufferString("{")
+	for i, key := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		if err := writeSelectJSON(buf, key); err != nil {
+			return nil, err
+		}
+		buf.WriteByte(':')
+		if err := writeSelectJSON(buf, o.values[key]); err != nil {
+			return nil, err
+		}
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func writeSelectJSON(buf *bytes.Buffer, value interface{}) error {
+	var encoder = json.NewEncoder(buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return err
+	}
+	// remove the new line appended by the encoder
+	buf.Truncate(buf.Len() - 1)
+	return nil
+}
+
+// selectRecord is a record of the CSV or JSON object.
+type selectRecord struct {
+	csv    bool
+	names  []string    // names of the CSV columns from the header
+	fields []string    // fields of the CSV record
+	value  interface{} // value of the JSON record
+}
+
+func (r *selectRecord) lookup(path []selectColumnPart) interface{} {
+	if r.csv {
+		if len(path) != 1 {
+			return nil
+		}
+		for i, name := range r.names {
+			if path[0].match(name) && i < len(r.fields) {
+				return r.fields[i]
+			}
+		}
+		if index, ok := path[0].position(); ok && index < len(r.fields) {
+			return r.fields[index]
+		}
+		return nil
+	}
+	var value = r.value
+	for _, part := range path {
+		var object, ok = value.(*selectObject)
+		if !ok {
+			return nil
+		}
+		if value, ok = object.lookup(part); !ok {
+			return nil
+		}
+	}
+	return value
+}
+
+// columns returns all the columns of the record for SELECT *.
+func (r *selectRecord) columns() (names []string, values []interface{}) {
+	if r.csv {
+		names = make([]string, len(r.fields))
+		values = make([]interface{}, len(r.fields))
+		for i, field := range r.fields {
+			if i < len(r.names) {
+				names[i] = r.names[i]
+			} else {
+				names[i] = "_" + strconv.Itoa(i+1)
+			}
+			values[i] = field
+		}
+		return
+	}
+	if object, ok := r.value.(*selectObject); ok {
+		values = make([]interface{}, len(object.keys))
+		for i, key := range object.keys {
+			values[i] = object.values[key]
+		}
+		return object.keys, values
+	}
+	return []string{"_1"}, []interface{}{r.value}
+}
+
+type selectRecordReader interface {
+	Read() (*selectRecord, error)
+}
+
+// selectDelimitedReader splits the data by the record delimiter, and limits the size of
+// each record. The records are joined with new lines, so that they can be parsed by the
+// CSV reader.
+type selectDelimitedReader struct {
+	scanner *bufio.Scanner
+	buf     []byte
+}
+
+func newSelectDelimitedReader(reader io.Reader, delimiter string) *selectDelimitedReader {
+	var scanner = bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxSelectRecordSize)
+	scanner.Split(func(data []byte, atEOF bool) (advance int, token []byte, err error) {
+		if i := bytes.Index(data, []byte(delimiter)); i >= 0 {
+			return i + len(delimiter), data[:i], nil
+		}
+		if atEOF && len(data) > 0 {
+			return len(data), data, nil
+		}
+		return 0, nil, nil
+	})
+	return &selectDelimitedReader{scanner: scanner}
+}
+
+func (r *selectDelimitedReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if !r.scanner.Scan() {
+			if err := r.scanner.Err(); err == bufio.ErrTooLong {
+				return 0, errSelectRecordTooLarge
+			} else if err != nil {
+				return 0, err
+			}
+			return 0, io.EOF
+		}
+		r.buf = append(append(r.buf[:0], r.scanner.Bytes()...), '\n')
+	}
+	var n = copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+type selectCSVReader struct {
+	reader     *csv.Reader
+	headerInfo string
+	names      []string
+	started    bool
+}
+
+func newSelectCSVReader(input *SelectCSVInput, reader io.Reader) *selectCSVReader {
+	var csvReader = csv.NewReader(newSelectDelimitedReader(reader, input.recordDelimiter()))
+	csvReader.Comma = input.fieldDelimiter()
+	if input.Comments != "" {
+		csvReader.Comment = []rune(input.Comments)[0]
+	}
+	csvReader.FieldsPerRecord = -1
+	return &selectCSVReader{reader: csvReader, headerInfo: strings.ToUpper(input.FileHeaderInfo)}
+}
+
+func (r *selectCSVReader) Read() (*selectRecord, error) {
+	if !r.started {
+		r.started = true
+		if r.headerInfo == SelectFileHeaderUse || r.headerInfo == SelectFileHeaderIgnore {
+			names, err := r.read()
+			if err != nil {
+				return nil, err
+			}
+			if r.headerInfo == SelectFileHeaderUse {
+				r.names = names
+			}
+		}
+	}
+	fields, err := r.read()
+	if err != nil {
+		return nil, err
+	}
+	return &selectRecord{csv: true, names: r.names, fields: fields}, nil
+}
+
+func (r *selectCSVReader) read() ([]string, error) {
+	fields, err := r.reader.Read()
+	if perr, ok := err.(*csv.ParseError); ok {
+		if perr.Err == errSelectRecordTooLarge {
+			return nil, errSelectRecordTooLarge
+		}
+		return nil, errSelectCSVParsing
+	}
+	return fields, err
+}
+
+// selectJSONReader reads the JSON records of both DOCUMENT and LINES types, since
+// the decoder reads the values one by one regardless of the new lines.
+type selectJSONReader struct {
+	decoder *json.Decoder
+	offset  int64
+}
+
+func newSelectJSONReader(reader io.Reader) *selectJSONReader {
+	var decoder = json.NewDecoder(reader)
+	decoder.UseNumber()
+	return &selectJSONReader{decoder: decoder}
+}
+
+func (r *selectJSONReader) Read() (*selectRecord, error) {
+	value, err := r.decode()
+	if _, ok := err.(*json.SyntaxError); ok || err == io.ErrUnexpectedEOF {
+		return nil, errSelectJSONParsing
+	}
+	if err != nil {
+		return nil, err
+	}
+	var offset = r.decoder.InputOffset()
+	if offset-r.offset > maxSelectRecordSize {
+		return nil, errSelectRecordTooLarge
+	}
+	r.offset = offset
+	return &selectRecord{value: value}, nil
+}
+
+func (r *selectJSONReader) decode() (interface{}, error) {
+	token, err := r.decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch t := token.(type) {
+	case json.Delim:
+		switch t {
+		case '{':
+			var object = newSelectObject()
+			for r.decoder.More() {
+				if token, err = r.decoder.Token(); err != nil {
+					return nil, err
+				}
+				var value interface{}
+				if value, err = r.decode(); err != nil {
+					return nil, err
+				}
+				object.set(token.(string), value)
+			}
+			_, err = r.decoder.Token()
+			return object, err
+		case '[':
+			var array = make([]interface{}, 0)
+			for r.decoder.More() {
+				var value interface{}
+				if value, err = r.decode(); err != nil {
+					return nil, err
+				}
+				array = append(array, value)
+			}
+			_, err = r.decoder.Token()
+			return array, err
+		}
+		return nil, errSelectJSONParsing
+	case json.Number:
+		if i, err := t.Int64(); err == nil {
+			return i, nil
+		}
+		return t.Float64()
+	default:
+		// string, bool or nil
+		return t, nil
+	}
+}
+
+// selectCSVWriter writes the result records in CSV format.
+type selectCSVWriter struct {
+	buf             *bytes.Buffer
+	fieldDelimiter  string
+	recordDelimiter string
+	quote           string
+	quoteEscape     string
+	quoteAlways     bool
+}
+
+func newSelectCSVWriter(output *SelectCSVOutput, buf *bytes.Buffer) *selectCSVWriter {
+	var writer = &selectCSVWriter{
+		buf:             buf,
+		fieldDelimiter:  output.FieldDelimiter,
+		recordDelimiter: output.RecordDelimiter,
+		quote:           output.QuoteCharacter,
+		quoteEscape:     output.QuoteEscapeCharacter,
+		quoteAlways:     strings.ToUpper(output.QuoteFields) == SelectQuoteFieldsAlways,
+	}
+	if writer.fieldDelimiter == "" {
+		writer.fieldDelimiter = ","
+	}
+	if writer.recordDelimiter == "" {
+		writer.recordDelimiter = "\n"
+	}
+	if writer.quote == "" {
+		writer.quote = "\""
+	}
+	if writer.quoteEscape == "" {
+		writer.quoteEscape = writer.quote
+	}
+	return writer
+}
+
+func (w *selectCSVWriter) Write(names []string, values []interface{}) error {
+	var start = w.buf.Len()
+	for i, value := range values {
+		if i > 0 {
+			w.buf.WriteString(w.fieldDelimiter)
+		}
+		var field = selectString(value)
+		if w.quoteAlways || strings.Contains(field, w.fieldDelimiter) || strings.Contains(field, w.quote) ||
+			strings.Contains(field, w.recordDelimiter) || strings.ContainsAny(field, "\r\n") {
+			w.buf.WriteString(w.quote)
+			w.buf.WriteString(strings.Replace(field, w.quote, w.quoteEscape+w.quote, -1))
+			w.buf.WriteString(w.quote)
+		} else {
+			w.buf.WriteString(field)
+		}
+	}
+	w.buf.WriteString(w.recordDelimiter)
+	if w.buf.Len()-start > maxSelectRecordSize {
+		return errSelectRecordTooLarge
+	}
+	return nil
+}
+
+// selectJSONWriter writes the result records in JSON format.
+type selectJSONWriter struct {
+	buf             *bytes.Buffer
+	recordDelimiter string
+}
+
+func newSelectJSONWriter(output *SelectJSONOutput, buf *bytes.Buffer) *selectJSONWriter {
+	var writer = &selectJSONWriter{buf: buf, recordDelimiter: output.RecordDelimiter}
+	if writer.recordDelimiter == "" {
+		writer.recordDelimiter = "\n"
+	}
+	return writer
+}
+
+func (w *selectJSONWriter) Write(names []string, values []interface{}) error {
+	var object = newSelectObject()
+	for i, name := range names {
+		object.set(name, values[i])
+	}
+	var start = w.buf.Len()
+	if err := writeSelectJSON(w.buf, object); err != nil {
+		return err
+	}
+	w.buf.WriteString(w.recordDelimiter)
+	if w.buf.Len()-start > maxSelectRecordSize {
+		return errSelectRecordTooLarge
+	}
+	return nil
+}
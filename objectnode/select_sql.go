@@ -0,0 +1,621 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+// https://docs.aws.amazon.com/AmazonS3/latest/dev/s3-glacier-select-sql-reference-select.html
+//
+// The SQL subset supported by the select object content:
+//
+//   SELECT * | expression [[AS] alias] [, ...]
+//   FROM S3Object [[AS] alias]
+//   [WHERE condition]
+//   [LIMIT number]
+//
+// The expressions consist of the column references, the literals, the arithmetic and
+// comparison operators, AND, OR, NOT, LIKE, IN, BETWEEN, IS [NOT] NULL, CAST, the string
+// functions (LOWER, UPPER, TRIM, CHAR_LENGTH, COALESCE), and the aggregate functions
+// (COUNT, SUM, AVG, MIN, MAX), which can not be mixed with the columns in the projections.
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type selectTokenType int
+
+const (
+	selectTokenEOF selectTokenType = iota
+	selectTokenIdent
+	selectTokenQuotedIdent
+	selectTokenString
+	selectTokenNumber
+	selectTokenOperator
+)
+
+type selectToken struct {
+	typ  selectTokenType
+	text string
+	pos  int
+}
+
+// SelectParseError is the error of parsing the SQL expression.
+type SelectParseError struct {
+	Pos     int
+	Message string
+}
+
+func (e *SelectParseError) Error() string {
+	return fmt.Sprintf("%v at position %v", e.Message, e.Pos)
+}
+
+var selectKeywords = map[string]struct{}{
+	"SELECT": {}, "FROM": {}, "WHERE": {}, "LIMIT": {}, "AND": {}, "OR": {}, "NOT": {}, "LIKE": {},
+	"ESCAPE": {}, "IS": {}, "NULL": {}, "IN": {}, "BETWEEN": {}, "AS": {}, "CAST": {}, "TRUE": {},
+	"FALSE": {},
+}
+
+func lexSelectSQL(sql string) (tokens []selectToken, err error) {
+	var runes = []rune(sql)
+	var i = 0
+	for i < len(runes) {
+		var c = runes[i]
+		var start = i
+		switch {
+		case unicode.IsSpace(c):
+			i++
+			continue
+		case unicode.IsLetter(c) || c == '_':
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') {
+				i++
+			}
+			tokens = append(tokens, selectToken{typ: selectTokenIdent, text: string(runes[start:i]), pos: start})
+		case c == '"' || c == '\'':
+			// the quote in the quoted identifier or string is escaped by doubling it
+			var sb strings.Builder
+			i++
+			for {
+				if i >= len(runes) {
+					return nil, &SelectParseError{Pos: start, Message: "unterminated quoted text"}
+				}
+				if runes[i] == c {
+					if i+1 < len(runes) && runes[i+1] == c {
+						sb.WriteRune(c)
+						i += 2
+						continue
+					}
+					i++
+					break
+				}
+				sb.WriteRune(runes[i])
+				i++
+			}
+			var typ = selectTokenString
+			if c == '"' {
+				typ = selectTokenQuotedIdent
+			}
+			tokens = append(tokens, selectToken{typ: typ, text: sb.String(), pos: start})
+		case unicode.IsDigit(c) || c == '.' && i+1 < len(runes) && unicode.IsDigit(runes[i+1]):
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			if i < len(runes) && (runes[i] == 'e' || runes[i] == 'E') {
+				i++
+				if i < len(runes) && (runes[i] == '+' || runes[i] == '-') {
+					i++
+				}
+				for i < len(runes) && unicode.IsDigit(runes[i]) {
+					i++
+				}
+			}
+			tokens = append(tokens, selectToken{typ: selectTokenNumber, text: string(runes[start:i]), pos: start})
+		default:
+			if i+1 < len(runes) {
+				switch op := string(runes[i : i+2]); op {
+				case "<=", ">=", "<>", "!=", "||":
+					tokens = append(tokens, selectToken{typ: selectTokenOperator, text: op, pos: start})
+					i += 2
+					continue
+				}
+			}
+			if !strings.ContainsRune("=<>+-*/%(),.;", c) {
+				return nil, &SelectParseError{Pos: start, Message: fmt.Sprintf("unexpected character '%c'", c)}
+			}
+			tokens = append(tokens, selectToken{typ: selectTokenOperator, text: string(c), pos: start})
+			i++
+		}
+	}
+	tokens = append(tokens, selectToken{typ: selectTokenEOF, pos: len(runes)})
+	return
+}
+
+type selectParser struct {
+	tokens []selectToken
+	pos    int
+
+	inProjection bool
+	inAggregate  bool
+	aggregates   []*selectAggregate
+	columns      bool // true if any column is referred out of the aggregates in the projections
+	columnRefs   []*selectColumn
+}
+
+func parseSelectSQL(sql string) (stmt *selectStatement, err error) {
+	var p = &selectParser{}
+	if p.tokens, err = lexSelectSQL(sql); err != nil {
+		return
+	}
+	return p.parseStatement()
+}
+
+func (p *selectParser) peek() selectToken {
+	return p.tokens[p.pos]
+}
+
+func (p *selectParser) next() selectToken {
+	var t = p.tokens[p.pos]
+	if t.typ != selectTokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *selectParser) errorf(format string, args ...interface{}) error {
+	return &SelectParseError{Pos: p.peek().pos, Message: fmt.Sprintf(format, args...)}
+}
+
+// isKeyword returns true if the next token is the keyword.
+func (p *selectParser) isKeyword(keyword string) bool {
+	var t = p.peek()
+	return t.typ == selectTokenIdent && strings.EqualFold(t.text, keyword)
+}
+
+func (p *selectParser) acceptKeyword(keyword string) bool {
+	if p.isKeyword(keyword) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *selectParser) expectKeyword(keyword string) error {
+	if !p.acceptKeyword(keyword) {
+		return p.errorf("expected %v", keyword)
+	}
+	return nil
+}
+
+func (p *selectParser) isOperator(op string) bool {
+	var t = p.peek()
+	return t.typ == selectTokenOperator && t.text == op
+}
+
+func (p *selectParser) acceptOperator(op string) bool {
+	if p.isOperator(op) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *selectParser) expectOperator(op string) error {
+	if !p.acceptOperator(op) {
+		return p.errorf("expected '%v'", op)
+	}
+	return nil
+}
+
+// acceptAlias accepts the optional alias following the keyword AS or not.
+func (p *selectParser) acceptAlias() (alias string, err error) {
+	var as = p.acceptKeyword("AS")
+	var t = p.peek()
+	if t.typ == selectTokenQuotedIdent {
+		p.pos++
+		return t.text, nil
+	}
+	if t.typ == selectTokenIdent {
+		if _, keyword := selectKeywords[strings.ToUpper(t.text)]; !keyword {
+			p.pos++
+			return t.text, nil
+		}
+	}
+	if as {
+		return "", p.errorf("expected alias")
+	}
+	return "", nil
+}
+
+func (p *selectParser) parseStatement() (stmt *selectStatement, err error) {
+	stmt = &selectStatement{limit: -1}
+	if err = p.expectKeyword("SELECT"); err != nil {
+		return nil, err
+	}
+	if !p.acceptOperator("*") {
+		p.inProjection = true
+		for {
+			var projection = &selectProjection{}
+			if projection.expr, err = p.parseExpr(); err != nil {
+				return nil, err
+			}
+			if projection.alias, err = p.acceptAlias(); err != nil {
+				return nil, err
+			}
+			stmt.projections = append(stmt.projections, projection)
+			if !p.acceptOperator(",") {
+				break
+			}
+		}
+		p.inProjection = false
+	}
+	if len(p.aggregates) > 0 && p.columns {
+		return nil, p.errorf("columns can not be mixed with aggregate functions")
+	}
+	stmt.aggregates = p.aggregates
+
+	if err = p.expectKeyword("FROM"); err != nil {
+		return nil, err
+	}
+	if t := p.next(); t.typ != selectTokenIdent || !strings.EqualFold(t.text, "S3Object") {
+		return nil, &SelectParseError{Pos: t.pos, Message: "expected S3Object"}
+	}
+	if stmt.alias, err = p.acceptAlias(); err != nil {
+		return nil, err
+	}
+
+	if p.acceptKeyword("WHERE") {
+		var aggregates = len(p.aggregates)
+		if stmt.where, err = p.parseExpr(); err != nil {
+			return nil, err
+		}
+		if len(p.aggregates) > aggregates {
+			return nil, p.errorf("aggregate functions are not allowed in WHERE")
+		}
+	}
+	if p.acceptKeyword("LIMIT") {
+		var t = p.next()
+		if t.typ != selectTokenNumber {
+			return nil, &SelectParseError{Pos: t.pos, Message: "expected number of LIMIT"}
+		}
+		if stmt.limit, err = strconv.ParseInt(t.text, 10, 64); err != nil || stmt.limit < 0 {
+			return nil, &SelectParseError{Pos: t.pos, Message: "invalid number of LIMIT"}
+		}
+	}
+	p.acceptOperator(";")
+	if p.peek().typ != selectTokenEOF {
+		return nil, p.errorf("unexpected token '%v'", p.peek().text)
+	}
+	// strip the table name or alias qualifying the columns, e.g. s.name or S3Object.name
+	for _, column := range p.columnRefs {
+		if len(column.path) > 1 && (strings.EqualFold(column.path[0].name, "S3Object") ||
+			stmt.alias != "" && strings.EqualFold(column.path[0].name, stmt.alias)) {
+			column.path = column.path[1:]
+		}
+	}
+	return stmt, nil
+}
+
+func (p *selectParser) parseExpr() (selectExpr, error) {
+	return p.parseOr()
+}
+
+func (p *selectParser) parseOr() (expr selectExpr, err error) {
+	if expr, err = p.parseAnd(); err != nil {
+		return
+	}
+	for p.acceptKeyword("OR") {
+		var right selectExpr
+		if right, err = p.parseAnd(); err != nil {
+			return
+		}
+		expr = &selectLogical{op: "OR", left: expr, right: right}
+	}
+	return
+}
+
+func (p *selectParser) parseAnd() (expr selectExpr, err error) {
+	if expr, err = p.parseNot(); err != nil {
+		return
+	}
+	for p.acceptKeyword("AND") {
+		var right selectExpr
+		if right, err = p.parseNot(); err != nil {
+			return
+		}
+		expr = &selectLogical{op: "AND", left: expr, right: right}
+	}
+	return
+}
+
+func (p *selectParser) parseNot() (expr selectExpr, err error) {
+	if p.acceptKeyword("NOT") {
+		if expr, err = p.parseNot(); err != nil {
+			return
+		}
+		return &selectNot{expr: expr}, nil
+	}
+	return p.parsePredicate()
+}
+
+func (p *selectParser) parsePredicate() (expr selectExpr, err error) {
+	if expr, err = p.parseAdditive(); err != nil {
+		return
+	}
+	var t = p.peek()
+	if t.typ == selectTokenOperator {
+		switch t.text {
+		case "=", "!=", "<>", "<", "<=", ">", ">=":
+			p.pos++
+			var right selectExpr
+			if right, err = p.parseAdditive(); err != nil {
+				return
+			}
+			return &selectComparison{op: t.text, left: expr, right: right}, nil
+		}
+		return
+	}
+	if p.acceptKeyword("IS") {
+		var not = p.acceptKeyword("NOT")
+		if err = p.expectKeyword("NULL"); err != nil {
+			return
+		}
+		return &selectIsNull{expr: expr, not: not}, nil
+	}
+	var not = p.acceptKeyword("NOT")
+	switch {
+	case p.acceptKeyword("LIKE"):
+		var like = &selectLike{expr: expr, not: not}
+		if like.pattern, err = p.parseAdditive(); err != nil {
+			return
+		}
+		if p.acceptKeyword("ESCAPE") {
+			if like.escape, err = p.parseAdditive(); err != nil {
+				return
+			}
+		}
+		return like, nil
+	case p.acceptKeyword("IN"):
+		var in = &selectIn{expr: expr, not: not}
+		if err = p.expectOperator("("); err != nil {
+			return
+		}
+		for {
+			var item selectExpr
+			if item, err = p.parseExpr(); err != nil {
+				return
+			}
+			in.list = append(in.list, item)
+			if !p.acceptOperator(",") {
+				break
+			}
+		}
+		if err = p.expectOperator(")"); err != nil {
+			return
+		}
+		return in, nil
+	case p.acceptKeyword("BETWEEN"):
+		var between = &selectBetween{expr: expr, not: not}
+		if between.low, err = p.parseAdditive(); err != nil {
+			return
+		}
+		if err = p.expectKeyword("AND"); err != nil {
+			return
+		}
+		if between.high, err = p.parseAdditive(); err != nil {
+			return
+		}
+		return between, nil
+	}
+	if not {
+		return nil, p.errorf("expected LIKE, IN or BETWEEN")
+	}
+	return
+}
+
+func (p *selectParser) parseAdditive() (expr selectExpr, err error) {
+	if expr, err = p.parseMultiplicative(); err != nil {
+		return
+	}
+	for p.isOperator("+") || p.isOperator("-") || p.isOperator("||") {
+		var op = p.next().text
+		var right selectExpr
+		if right, err = p.parseMultiplicative(); err != nil {
+			return
+		}
+		expr = &selectArithmetic{op: op, left: expr, right: right}
+	}
+	return
+}
+
+func (p *selectParser) parseMultiplicative() (expr selectExpr, err error) {
+	if expr, err = p.parseUnary(); err != nil {
+		return
+	}
+	for p.isOperator("*") || p.isOperator("/") || p.isOperator("%") {
+		var op = p.next().text
+		var right selectExpr
+		if right, err = p.parseUnary(); err != nil {
+			return
+		}
+		expr = &selectArithmetic{op: op, left: expr, right: right}
+	}
+	return
+}
+
+func (p *selectParser) parseUnary() (expr selectExpr, err error) {
+	if p.acceptOperator("-") {
+		if expr, err = p.parseUnary(); err != nil {
+			return
+		}
+		return &selectArithmetic{op: "-", left: &selectLiteral{value: int64(0)}, right: expr}, nil
+	}
+	if p.acceptOperator("+") {
+		return p.parseUnary()
+	}
+	return p.parsePrimary()
+}
+
+func (p *selectParser) parsePrimary() (expr selectExpr, err error) {
+	var t = p.peek()
+	switch t.typ {
+	case selectTokenNumber:
+		p.pos++
+		if i, err := strconv.ParseInt(t.text, 10, 64); err == nil {
+			return &selectLiteral{value: i}, nil
+		}
+		var f float64
+		if f, err = strconv.ParseFloat(t.text, 64); err != nil {
+			return nil, &SelectParseError{Pos: t.pos, Message: "invalid number"}
+		}
+		return &selectLiteral{value: f}, nil
+	case selectTokenString:
+		p.pos++
+		return &selectLiteral{value: t.text}, nil
+	case selectTokenQuotedIdent:
+		return p.parseColumn()
+	case selectTokenOperator:
+		if p.acceptOperator("(") {
+			if expr, err = p.parseExpr(); err != nil {
+				return
+			}
+			if err = p.expectOperator(")"); err != nil {
+				return
+			}
+			return expr, nil
+		}
+		return nil, p.errorf("unexpected token '%v'", t.text)
+	case selectTokenIdent:
+		var name = strings.ToUpper(t.text)
+		switch name {
+		case "TRUE", "FALSE":
+			p.pos++
+			return &selectLiteral{value: name == "TRUE"}, nil
+		case "NULL":
+			p.pos++
+			return &selectLiteral{value: nil}, nil
+		case "CAST":
+			p.pos++
+			return p.parseCast()
+		}
+		if _, keyword := selectKeywords[name]; keyword {
+			return nil, p.errorf("unexpected keyword %v", t.text)
+		}
+		if p.tokens[p.pos+1].typ == selectTokenOperator && p.tokens[p.pos+1].text == "(" {
+			p.pos += 2
+			if _, aggregate := selectAggregateFuncs[name]; aggregate {
+				return p.parseAggregate(name)
+			}
+			return p.parseFunction(name)
+		}
+		return p.parseColumn()
+	}
+	return nil, p.errorf("unexpected end of expression")
+}
+
+func (p *selectParser) parseColumn() (expr selectExpr, err error) {
+	var column = &selectColumn{}
+	for {
+		var t = p.next()
+		switch t.typ {
+		case selectTokenIdent:
+			column.path = append(column.path, selectColumnPart{name: t.text})
+		case selectTokenQuotedIdent:
+			column.path = append(column.path, selectColumnPart{name: t.text, quoted: true})
+		default:
+			return nil, &SelectParseError{Pos: t.pos, Message: "expected column name"}
+		}
+		if !p.acceptOperator(".") {
+			break
+		}
+	}
+	if p.inProjection && !p.inAggregate {
+		p.columns = true
+	}
+	p.columnRefs = append(p.columnRefs, column)
+	return column, nil
+}
+
+func (p *selectParser) parseCast() (expr selectExpr, err error) {
+	var cast = &selectCast{}
+	if err = p.expectOperator("("); err != nil {
+		return
+	}
+	if cast.expr, err = p.parseExpr(); err != nil {
+		return
+	}
+	if err = p.expectKeyword("AS"); err != nil {
+		return
+	}
+	var t = p.next()
+	if t.typ != selectTokenIdent {
+		return nil, &SelectParseError{Pos: t.pos, Message: "expected data type"}
+	}
+	if cast.typ = strings.ToUpper(t.text); !selectCastTypes[cast.typ] {
+		return nil, &SelectParseError{Pos: t.pos, Message: fmt.Sprintf("unsupported data type %v", t.text)}
+	}
+	if err = p.expectOperator(")"); err != nil {
+		return
+	}
+	return cast, nil
+}
+
+func (p *selectParser) parseFunction(name string) (expr selectExpr, err error) {
+	var arity, supported = selectFuncArities[name]
+	if !supported {
+		return nil, p.errorf("unsupported function %v", name)
+	}
+	var function = &selectFunction{name: name}
+	if !p.acceptOperator(")") {
+		for {
+			var arg selectExpr
+			if arg, err = p.parseExpr(); err != nil {
+				return
+			}
+			function.args = append(function.args, arg)
+			if !p.acceptOperator(",") {
+				break
+			}
+		}
+		if err = p.expectOperator(")"); err != nil {
+			return
+		}
+	}
+	if arity >= 0 && len(function.args) != arity || arity < 0 && len(function.args) == 0 {
+		return nil, p.errorf("invalid number of arguments of %v", name)
+	}
+	return function, nil
+}
+
+func (p *selectParser) parseAggregate(name string) (expr selectExpr, err error) {
+	if p.inAggregate {
+		return nil, p.errorf("aggregate functions can not be nested")
+	}
+	var aggregate = &selectAggregate{name: name}
+	if name == "COUNT" && p.acceptOperator("*") {
+		// COUNT(*) counts all the records
+	} else {
+		p.inAggregate = true
+		aggregate.arg, err = p.parseExpr()
+		p.inAggregate = false
+		if err != nil {
+			return
+		}
+	}
+	if err = p.expectOperator(")"); err != nil {
+		return
+	}
+	p.aggregates = append(p.aggregates, aggregate)
+	return aggregate, nil
+}
@@ -0,0 +1,188 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"hash/crc32"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testSelectCSV = `name,city,age,score
+alice,beijing,30,8.5
+bob,shanghai,25,7
+carol,beijing,41,9.25
+dave,"shen,zhen",35,6
+`
+
+const testSelectJSON = `{"name": "alice", "age": 30, "address": {"city": "beijing"}}
+{"name": "bob", "age": 25, "address": {"city": "shanghai"}}
+{"name": "carol", "age": 41, "address": {"city": "beijing"}, "tags": ["admin"]}
+`
+
+type testSelectMessage struct {
+	headers map[string]string
+	payload []byte
+}
+
+func decodeTestSelectMessages(t *testing.T, data []byte) (messages []*testSelectMessage) {
+	for len(data) > 0 {
+		var total = binary.BigEndian.Uint32(data[0:4])
+		var headerLength = binary.BigEndian.Uint32(data[4:8])
+		if crc32.ChecksumIEEE(data[0:8]) != binary.BigEndian.Uint32(data[8:12]) ||
+			crc32.ChecksumIEEE(data[:total-4]) != binary.BigEndian.Uint32(data[total-4:total]) {
+			t.Fatalf("invalid CRC of message")
+		}
+		var message = &testSelectMessage{headers: make(map[string]string)}
+		var headers = data[12 : 12+headerLength]
+		for len(headers) > 0 {
+			var nameLength = int(headers[0])
+			var name = string(headers[1 : 1+nameLength])
+			var valueLength = int(binary.BigEndian.Uint16(headers[2+nameLength : 4+nameLength]))
+			message.headers[name] = string(headers[4+nameLength : 4+nameLength+valueLength])
+			headers = headers[4+nameLength+valueLength:]
+		}
+		message.payload = data[12+headerLength : total-4]
+		messages = append(messages, message)
+		data = data[total:]
+	}
+	return
+}
+
+func testSelect(t *testing.T, request, object string) (records string, messages []*testSelectMessage) {
+	req, err := parseSelectRequest([]byte(request))
+	if err != nil {
+		t.Fatalf("parse select request fail: err(%v)", err)
+	}
+	var recorder = httptest.NewRecorder()
+	if err = req.execute(strings.NewReader(object), newSelectEventStream(recorder)); err != nil {
+		t.Fatalf("execute select fail: err(%v)", err)
+	}
+	messages = decodeTestSelectMessages(t, recorder.Body.Bytes())
+	for _, message := range messages {
+		if message.headers[selectEventHeaderEventType] == selectEventRecords {
+			records += string(message.payload)
+		}
+	}
+	if last := messages[len(messages)-1]; last.headers[selectEventHeaderEventType] != selectEventEnd {
+		t.Fatalf("last message should be End: %v", last.headers)
+	}
+	return
+}
+
+func testSelectRequest(expression, input, output string) string {
+	return `<SelectObjectContentRequest><Expression>` + expression + `</Expression>` +
+		`<ExpressionType>SQL</ExpressionType><InputSerialization>` + input + `</InputSerialization>` +
+		`<OutputSerialization>` + output + `</OutputSerialization></SelectObjectContentRequest>`
+}
+
+func TestSelect_CSV(t *testing.T) {
+	var input = `<CSV><FileHeaderInfo>USE</FileHeaderInfo></CSV>`
+	var cases = []struct {
+		expression string
+		expected   string
+	}{
+		{"SELECT * FROM S3Object LIMIT 1", "alice,beijing,30,8.5\n"},
+		{"SELECT s.name, s.age + 1 FROM S3Object s WHERE s.city = 'beijing' AND age &gt; 35", "carol,42\n"},
+		{"SELECT name FROM S3Object WHERE score &gt;= 7 AND name LIKE '%o%'", "bob\ncarol\n"},
+		{"SELECT _1, UPPER(city) FROM S3Object WHERE age BETWEEN 30 AND 35", "alice,BEIJING\ndave,\"SHEN,ZHEN\"\n"},
+		{"SELECT name FROM S3Object WHERE city IN ('shanghai', 'shen,zhen') OR CAST(score AS FLOAT) &gt; 9", "bob\ncarol\ndave\n"},
+		{"SELECT COUNT(*), SUM(age), MIN(name), MAX(score), AVG(age) FROM S3Object WHERE city = 'beijing'", "2,71,alice,9.25,35.5\n"},
+		{"SELECT COUNT(*) FROM S3Object WHERE NOT (age &lt; 100)", "0\n"},
+	}
+	for _, c := range cases {
+		records, _ := testSelect(t, testSelectRequest(c.expression, input, `<CSV/>`), testSelectCSV)
+		if records != c.expected {
+			t.Fatalf("unexpected records of %v: %q", c.expression, records)
+		}
+	}
+
+	// custom delimiters, gzip compression and statistics
+	var buf = new(bytes.Buffer)
+	var gzipWriter = gzip.NewWriter(buf)
+	_, _ = gzipWriter.Write([]byte("1|a;2|b;3|c"))
+	_ = gzipWriter.Close()
+	var request = testSelectRequest("SELECT _2 FROM S3Object WHERE _1 &lt;&gt; 2",
+		`<CompressionType>GZIP</CompressionType><CSV><RecordDelimiter>;</RecordDelimiter><FieldDelimiter>|</FieldDelimiter></CSV>`,
+		`<CSV><QuoteFields>ALWAYS</QuoteFields><RecordDelimiter>;</RecordDelimiter></CSV>`)
+	records, messages := testSelect(t, request, buf.String())
+	if records != `"a";"c";` {
+		t.Fatalf("unexpected records: %q", records)
+	}
+	var stats = string(messages[len(messages)-2].payload)
+	if !strings.Contains(stats, "<BytesProcessed>11</BytesProcessed>") || !strings.Contains(stats, "<BytesReturned>8</BytesReturned>") {
+		t.Fatalf("unexpected stats: %v", stats)
+	}
+}
+
+func TestSelect_JSON(t *testing.T) {
+	var input = `<JSON><Type>LINES</Type></JSON>`
+	var output = `<JSON/>`
+	var cases = []struct {
+		expression string
+		expected   string
+	}{
+		{"SELECT * FROM S3Object s WHERE s.address.city = 'shanghai'",
+			`{"name":"bob","age":25,"address":{"city":"shanghai"}}` + "\n"},
+		{"SELECT s.name AS n, s.tags FROM S3Object s WHERE s.tags IS NOT NULL", `{"n":"carol","tags":["admin"]}` + "\n"},
+		{"SELECT COUNT(s.tags), MAX(s.age) AS oldest FROM S3Object s", `{"_1":1,"oldest":41}` + "\n"},
+		{"SELECT name || '@' || address.city FROM S3Object LIMIT 2", `{"_1":"alice@beijing"}` + "\n" + `{"_1":"bob@shanghai"}` + "\n"},
+	}
+	for _, c := range cases {
+		records, _ := testSelect(t, testSelectRequest(c.expression, input, output), testSelectJSON)
+		if records != c.expected {
+			t.Fatalf("unexpected records of %v: %q", c.expression, records)
+		}
+	}
+}
+
+func TestSelect_Errors(t *testing.T) {
+	var input = `<CSV><FileHeaderInfo>USE</FileHeaderInfo></CSV>`
+	var invalids = map[string]*ErrorCode{
+		testSelectRequest("SELECT * FROM S3Object", input, `<CSV/>`)[:20]:                                    MalformedXML,
+		strings.Replace(testSelectRequest("SELECT * FROM S3Object", input, `<CSV/>`), ">SQL<", ">XPATH<", 1): InvalidExpressionType,
+		testSelectRequest("SELECT * FROM S3Object", `<Parquet/>`, `<CSV/>`):                                  InvalidRequestParameter,
+		testSelectRequest("SELECT * FROM S3Object", input, ``):                                               InvalidRequestParameter,
+		testSelectRequest("SELECT * FROM table", input, `<CSV/>`):                                            ParseSelectFailure,
+		testSelectRequest("SELECT name, COUNT(*) FROM S3Object", input, `<CSV/>`):                            ParseSelectFailure,
+		testSelectRequest("SELECT * FROM S3Object WHERE COUNT(*) &gt; 1", input, `<CSV/>`):                   ParseSelectFailure,
+		testSelectRequest("SELECT * FROM S3Object WHERE name = 'a", input, `<CSV/>`):                         ParseSelectFailure,
+	}
+	for request, expected := range invalids {
+		if _, err := parseSelectRequest([]byte(request)); selectErrorCode(err).ErrorCode != expected.ErrorCode {
+			t.Fatalf("unexpected error of %v: err(%v)", request, err)
+		}
+	}
+
+	// the evaluation error is written to the event stream
+	req, err := parseSelectRequest([]byte(testSelectRequest("SELECT age / 0 FROM S3Object", input, `<CSV/>`)))
+	if err != nil {
+		t.Fatalf("parse select request fail: err(%v)", err)
+	}
+	var recorder = httptest.NewRecorder()
+	if err = req.execute(strings.NewReader(testSelectCSV), newSelectEventStream(recorder)); err != errSelectDivisionByZero {
+		t.Fatalf("execute should fail: err(%v)", err)
+	}
+	var stream = newSelectEventStream(recorder)
+	_ = stream.writeError(selectErrorCode(err))
+	var messages = decodeTestSelectMessages(t, recorder.Body.Bytes())
+	if len(messages) != 1 || messages[0].headers[selectEventHeaderErrorCode] != DivisionByZero.ErrorCode {
+		t.Fatalf("unexpected error messages: %v", messages)
+	}
+}
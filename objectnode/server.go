@@ -16,12 +16,14 @@ package objectnode
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
 	"regexp"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/chubaofs/chubaofs/util/config"
 	"github.com/chubaofs/chubaofs/util/exporter"
@@ -77,6 +79,81 @@ const (
 	// The configuration in the example will allow ObjectNode to automatically resolve "* .object.chubao.io".
 	configDomains = "domains"
 
+	// String array configuration item, used to configure the domain names of the website endpoints.
+	// The requests to "<bucket>.<website domain>" are served as the static websites of the buckets with
+	// the website configurations, which are anonymous GET and HEAD requests authorized by the bucket policies.
+	// The website domains must not be covered by the domains of the object storage interface.
+	// Example:
+	//		{
+	//			"websiteDomains": [
+	//				"website.chubao.io"
+	//			]
+	//		}
+	configWebsiteDomains = "websiteDomains"
+
+	// Integer type configuration item, used to configure the interval in seconds of the lifecycle scanner,
+	// which expires the objects and aborts the incomplete multipart uploads following the lifecycle rules
+	// of the buckets. The default value is 3600.
+	// Example:
+	//		{
+	//			"lifecycleScanInterval": 3600
+	//		}
+	configLifecycleScanInterval = "lifecycleScanInterval"
+
+	// Integer type configuration items, used to configure the number of the workers replicating the objects
+	// to the destination buckets of the replication rules, and the size of the replication queue. The objects
+	// are marked as failed to be replicated once the queue is full. The default values are 8 and 100000.
+	// The string type configuration item is used to configure the directory keeping the replication queue,
+	// so that the objects pending replication are recovered once the object node is restarted. The queue
+	// is kept in memory only if it is not configured.
+	// Example:
+	//		{
+	//			"replicationWorkers": 8,
+	//			"replicationQueueSize": 100000,
+	//			"replicationQueueDir": "/var/lib/chubaofs/objectnode/replication"
+	//		}
+	configReplicationWorkers   = "replicationWorkers"
+	configReplicationQueueSize = "replicationQueueSize"
+	configReplicationQueueDir  = "replicationQueueDir"
+
+	// Object array configuration item, used to configure the webhook targets of the bucket notifications.
+	// The events of the objects are kept in the queue directories of the targets until they are posted to
+	// the endpoints, and the events are dropped once the number of the events in the queue reaches the limit.
+	// The targets are referred by the queue configurations of the buckets with "arn:chubaofs:sqs::<id>:webhook".
+	// Example:
+	//		{
+	//			"notificationWebhooks": [
+	//				{
+	//					"id": "pipeline",
+	//					"endpoint": "http://pipeline.chubao.io/events",
+	//					"authToken": "...",
+	//					"queueDir": "/var/chubaofs/objectnode/notification/pipeline",
+	//					"queueLimit": 100000
+	//				}
+	//			]
+	//		}
+	configNotificationWebhooks = "notificationWebhooks"
+
+	// String array configuration item, used to configure the addresses of the authnodes. The server side
+	// encryption with the keys managed by the authnode (SSE-S3) is enabled only if it is configured, and the
+	// data keys of the objects are generated and decrypted by the authnode with the client ID and key.
+	// Example:
+	//		{
+	//			"authNodes": [
+	//				"authnode1.chubao.io",
+	//				"authnode2.chubao.io"
+	//			],
+	//			"enableHTTPS": false,
+	//			"certFile": "",
+	//			"clientID": "objectnode",
+	//			"clientKey": "..."
+	//		}
+	configAuthNodes   = "authNodes"
+	configEnableHTTPS = "enableHTTPS"
+	configCertFile    = "certFile"
+	configClientID    = "clientID"
+	configClientKey   = "clientKey"
+
 	disabledActions               = "disabledActions"
 	configSignatureIgnoredActions = "signatureIgnoredActions"
 )
@@ -103,6 +180,12 @@ type ObjectNode struct {
 	state      uint32
 	wg         sync.WaitGroup
 	userStore  UserInfoStore
+	lcScanner  *LifecycleScanner
+	replicator *Replicator
+	notifier   *Notifier
+
+	websiteDomains   []string  // domains of the website endpoints
+	websiteWildcards Wildcards // wildcards of the website endpoints
 
 	signatureIgnoredActions proto.Actions // signature ignored actions
 	disabledActions         proto.Actions // disabled actions
@@ -145,6 +228,14 @@ func (o *ObjectNode) loadConfig(cfg *config.Config) (err error) {
 	}
 	log.LogInfof("loadConfig: setup config: %v(%v)", configDomains, domains)
 
+	// parse website domain
+	websiteDomains := cfg.GetStringSlice(configWebsiteDomains)
+	o.websiteDomains = websiteDomains
+	if o.websiteWildcards, err = NewWildcards(websiteDomains); err != nil {
+		return
+	}
+	log.LogInfof("loadConfig: setup config: %v(%v)", configWebsiteDomains, websiteDomains)
+
 	// parse master config
 	masters := cfg.GetStringSlice(configMasterAddr)
 	if len(masters) == 0 {
@@ -180,6 +271,48 @@ func (o *ObjectNode) loadConfig(cfg *config.Config) (err error) {
 	o.vm = NewVolumeManager(masters, strict)
 	o.userStore = NewUserInfoStore(masters, strict)
 
+	// parse authnode config for the server side encryption
+	if authNodes := cfg.GetStringSlice(configAuthNodes); len(authNodes) > 0 {
+		clientID := cfg.GetString(configClientID)
+		log.LogInfof("loadConfig: server side encryption: authNodes(%v) clientID(%v)", authNodes, clientID)
+		o.vm.SetDataKeyClient(NewDataKeyClient(authNodes, cfg.GetBool(configEnableHTTPS),
+			cfg.GetString(configCertFile), clientID, cfg.GetString(configClientKey)))
+	}
+
+	// parse lifecycle scan interval
+	lcScanInterval := time.Duration(cfg.GetInt64(configLifecycleScanInterval)) * time.Second
+	log.LogInfof("loadConfig: lifecycle scan interval: %v", lcScanInterval)
+	o.lcScanner = NewLifecycleScanner(o.mc, o.vm, lcScanInterval)
+
+	// parse replication config
+	replicationWorkers := int(cfg.GetInt64(configReplicationWorkers))
+	replicationQueueSize := int(cfg.GetInt64(configReplicationQueueSize))
+	replicationQueueDir := cfg.GetString(configReplicationQueueDir)
+	log.LogInfof("loadConfig: replication: workers(%v) queueSize(%v) queueDir(%v)",
+		replicationWorkers, replicationQueueSize, replicationQueueDir)
+	if o.replicator, err = NewReplicator(o.vm, replicationWorkers, replicationQueueSize, replicationQueueDir); err != nil {
+		return
+	}
+
+	// parse notification webhooks config
+	var webhooks = make([]*WebhookConfig, 0)
+	if rawWebhooks := cfg.GetSlice(configNotificationWebhooks); len(rawWebhooks) > 0 {
+		var data []byte
+		if data, err = json.Marshal(rawWebhooks); err != nil {
+			return
+		}
+		if err = json.Unmarshal(data, &webhooks); err != nil {
+			return
+		}
+	}
+	for _, webhook := range webhooks {
+		log.LogInfof("loadConfig: notification webhook: id(%v) endpoint(%v) queueDir(%v) queueLimit(%v)",
+			webhook.ID, webhook.Endpoint, webhook.QueueDir, webhook.QueueLimit)
+	}
+	if o.notifier, err = NewNotifier(webhooks); err != nil {
+		return
+	}
+
 	return
 }
 
@@ -213,6 +346,10 @@ func handleStart(s common.Server, cfg *config.Config) (err error) {
 		return
 	}
 
+	o.lcScanner.Start()
+	o.replicator.Start()
+	o.notifier.Start()
+
 	exporter.Init(cfg.GetString("role"), cfg)
 	exporter.RegistConsul(ci.Cluster, cfg.GetString("role"), cfg)
 
@@ -226,6 +363,15 @@ func handleShutdown(s common.Server) {
 		return
 	}
 	o.shutdownRestAPI()
+	if o.lcScanner != nil {
+		o.lcScanner.Stop()
+	}
+	if o.replicator != nil {
+		o.replicator.Stop()
+	}
+	if o.notifier != nil {
+		o.notifier.Stop()
+	}
 }
 
 func (o *ObjectNode) startMuxRestAPI() (err error) {
@@ -240,9 +386,20 @@ func (o *ObjectNode) startMuxRestAPI() (err error) {
 		o.contentMiddleware,
 	)
 
+	var handler http.Handler = router
+	if len(o.websiteDomains) > 0 {
+		websiteRouter := mux.NewRouter().SkipClean(true)
+		o.registerWebsiteRouters(websiteRouter)
+		websiteRouter.Use(
+			o.corsMiddleware,
+			o.traceMiddleware,
+		)
+		handler = o.websiteDispatcher(websiteRouter, router)
+	}
+
 	var server = &http.Server{
 		Addr:    ":" + o.listen,
-		Handler: router,
+		Handler: handler,
 	}
 
 	go func() {
@@ -0,0 +1,303 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+// https://docs.aws.amazon.com/AmazonS3/latest/dev/serv-side-encryption.html
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"net/http"
+
+	"github.com/chubaofs/chubaofs/proto"
+	authSDK "github.com/chubaofs/chubaofs/sdk/auth"
+	"github.com/chubaofs/chubaofs/util/errors"
+)
+
+const (
+	SSEAlgorithmAES256 = "AES256"
+
+	sseKeySize = 32
+)
+
+var (
+	errSSEInvalidAlgorithm       = errors.New("invalid server side encryption algorithm")
+	errSSEInvalidCustomerKey     = errors.New("invalid server side encryption customer key")
+	errSSECustomerKeyMD5Mismatch = errors.New("server side encryption customer key MD5 mismatch")
+	errSSECustomerKeyRequired    = errors.New("server side encryption customer key required")
+	errSSECustomerKeyMismatch    = errors.New("server side encryption customer key mismatch")
+	errSSENotApplicable          = errors.New("server side encryption not applicable")
+	errSSEUnavailable            = errors.New("server side encryption with managed keys unavailable")
+)
+
+// SSEOption is the server side encryption requested for an object.
+// Every encrypted object has its own data key. The data key is encrypted by
+// the master key of the volume kept by the authnode if no customer key is
+// provided (SSE-S3), or by the customer key which is never stored (SSE-C).
+type SSEOption struct {
+	CustomerKey    []byte
+	CustomerKeyMD5 string // base64 encoded
+}
+
+// Customer returns true if the object is encrypted with a customer key.
+func (opt *SSEOption) Customer() bool {
+	return opt != nil && len(opt.CustomerKey) > 0
+}
+
+// parseSSEOption parses the encryption headers of a request writing or reading
+// an object. It returns nil if no encryption header is specified.
+func parseSSEOption(header http.Header) (opt *SSEOption, err error) {
+	if opt, err = parseSSECustomerKey(header, HeaderNameXAmzSSECustomerAlgorithm,
+		HeaderNameXAmzSSECustomerKey, HeaderNameXAmzSSECustomerKeyMD5); err != nil {
+		return
+	}
+	var algorithm = header.Get(HeaderNameXAmzServerSideEncryption)
+	if opt != nil {
+		if algorithm != "" {
+			return nil, errSSENotApplicable
+		}
+		return
+	}
+	switch algorithm {
+	case "":
+		return nil, nil
+	case SSEAlgorithmAES256:
+		return &SSEOption{}, nil
+	default:
+		return nil, errSSEInvalidAlgorithm
+	}
+}
+
+// parseCopySourceSSEOption parses the customer key headers of the source
+// object of a copy request.
+func parseCopySourceSSEOption(header http.Header) (*SSEOption, error) {
+	return parseSSECustomerKey(header, HeaderNameXAmzCopySourceSSECustomerAlgorithm,
+		HeaderNameXAmzCopySourceSSECustomerKey, HeaderNameXAmzCopySourceSSECustomerKeyMD5)
+}
+
+func parseSSECustomerKey(header http.Header, algorithmName, keyName, keyMD5Name string) (opt *SSEOption, err error) {
+	var (
+		algorithm = header.Get(algorithmName)
+		key       = header.Get(keyName)
+		keyMD5    = header.Get(keyMD5Name)
+	)
+	if algorithm == "" && key == "" && keyMD5 == "" {
+		return nil, nil
+	}
+	if algorithm != SSEAlgorithmAES256 {
+		return nil, errSSEInvalidAlgorithm
+	}
+	var rawKey []byte
+	if rawKey, err = base64.StdEncoding.DecodeString(key); err != nil || len(rawKey) != sseKeySize {
+		return nil, errSSEInvalidCustomerKey
+	}
+	var sum = md5.Sum(rawKey)
+	if keyMD5 != base64.StdEncoding.EncodeToString(sum[:]) {
+		return nil, errSSECustomerKeyMD5Mismatch
+	}
+	return &SSEOption{CustomerKey: rawKey, CustomerKeyMD5: keyMD5}, nil
+}
+
+// requestSSEOption returns the encryption of the object written by the request,
+// which is the default encryption of the bucket if no header is specified.
+func requestSSEOption(r *http.Request, vol *Volume) (opt *SSEOption, err error) {
+	if opt, err = parseSSEOption(r.Header); err != nil || opt != nil {
+		return
+	}
+	var encryption *ServerSideEncryptionConfiguration
+	if encryption, err = vol.metaLoader.loadEncryption(); err != nil {
+		return
+	}
+	return encryption.DefaultSSE(), nil
+}
+
+// checkObjectSSE checks the customer key of a request reading the object.
+func checkObjectSSE(info *FSFileInfo, opt *SSEOption) error {
+	if info.SSECustomerKeyMD5 == "" {
+		if opt.Customer() {
+			return errSSENotApplicable
+		}
+		return nil
+	}
+	if !opt.Customer() {
+		return errSSECustomerKeyRequired
+	}
+	if opt.CustomerKeyMD5 != info.SSECustomerKeyMD5 {
+		return errSSECustomerKeyMismatch
+	}
+	return nil
+}
+
+// customerETag returns the ETag of an object encrypted with a customer key.
+// Same as S3, it is not the MD5 of the data, so that nothing about the data
+// is told to the ones who do not have the key.
+func customerETag(md5Value string, dataKey []byte) string {
+	var h = md5.New()
+	h.Write([]byte(md5Value))
+	h.Write(dataKey)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// setSSEFileInfo sets the encryption of the object from its extend attributes.
+func setSSEFileInfo(info *FSFileInfo, xattrs map[string]string) {
+	if xattrs[XAttrKeyOSSSSECustomerKey] != "" {
+		info.SSECustomerKeyMD5 = xattrs[XAttrKeyOSSSSECustomerKeyMD5]
+	} else if xattrs[proto.CryptKeyXAttrKey] != "" {
+		info.SSE = SSEAlgorithmAES256
+	}
+}
+
+// setSSEResponseHeaders sets the encryption headers of the response, which are
+// the algorithm of the managed keys, or the MD5 of the customer key.
+func setSSEResponseHeaders(w http.ResponseWriter, algorithm, customerKeyMD5 string) {
+	if algorithm != "" {
+		w.Header()[HeaderNameXAmzServerSideEncryption] = []string{algorithm}
+	}
+	if customerKeyMD5 != "" {
+		w.Header()[HeaderNameXAmzSSECustomerAlgorithm] = []string{SSEAlgorithmAES256}
+		w.Header()[HeaderNameXAmzSSECustomerKeyMD5] = []string{customerKeyMD5}
+	}
+}
+
+func sseErrorCode(err error) *ErrorCode {
+	switch err {
+	case errSSEInvalidAlgorithm:
+		return InvalidEncryptionAlgorithm
+	case errSSEInvalidCustomerKey:
+		return InvalidSSECustomerKey
+	case errSSECustomerKeyMD5Mismatch:
+		return SSECustomerKeyMD5Mismatch
+	case errSSECustomerKeyRequired:
+		return SSECustomerKeyRequired
+	case errSSECustomerKeyMismatch:
+		return SSECustomerKeyMismatch
+	case errSSENotApplicable:
+		return SSENotApplicable
+	case errSSEUnavailable:
+		return SSEUnavailable
+	default:
+		return InternalErrorCode(err)
+	}
+}
+
+// isSSEError returns true if the error is caused by the encryption parameters
+// of the request.
+func isSSEError(err error) bool {
+	switch err {
+	case errSSEInvalidAlgorithm, errSSEInvalidCustomerKey, errSSECustomerKeyMD5Mismatch,
+		errSSECustomerKeyRequired, errSSECustomerKeyMismatch, errSSENotApplicable, errSSEUnavailable:
+		return true
+	default:
+		return false
+	}
+}
+
+// DataKeyClient generates and decrypts the data keys of the objects encrypted
+// with the keys managed by the authnode, which share the master keys of the
+// volumes with the files encrypted by the clients.
+type DataKeyClient struct {
+	ac        *authSDK.AuthClient
+	clientID  string
+	clientKey string
+}
+
+func NewDataKeyClient(authNodes []string, enableHTTPS bool, certFile, clientID, clientKey string) *DataKeyClient {
+	return &DataKeyClient{
+		ac:        authSDK.NewAuthClient(authNodes, enableHTTPS, certFile),
+		clientID:  clientID,
+		clientKey: clientKey,
+	}
+}
+
+// GenDataKey returns a new data key of the volume, together with the data key
+// encrypted by the master key of the volume.
+func (c *DataKeyClient) GenDataKey(volName string) (plainKey []byte, cipherKey string, err error) {
+	if c == nil {
+		return nil, "", errSSEUnavailable
+	}
+	return c.ac.API().GenDataKey(c.clientID, c.clientKey, volName)
+}
+
+// DecryptDataKey returns the data key decrypted by the master key of the volume.
+func (c *DataKeyClient) DecryptDataKey(volName, cipherKey string) (plainKey []byte, err error) {
+	if c == nil {
+		return nil, errSSEUnavailable
+	}
+	return c.ac.API().DecryptDataKey(c.clientID, c.clientKey, volName, cipherKey)
+}
+
+type ServerSideEncryptionConfiguration struct {
+	XMLName xml.Name                    `xml:"ServerSideEncryptionConfiguration"`
+	Rules   []*ServerSideEncryptionRule `xml:"Rule"`
+}
+
+type ServerSideEncryptionRule struct {
+	ApplySSEByDefault *ApplySSEByDefault `xml:"ApplyServerSideEncryptionByDefault"`
+}
+
+type ApplySSEByDefault struct {
+	SSEAlgorithm   string `xml:"SSEAlgorithm"`
+	KMSMasterKeyID string `xml:"KMSMasterKeyID,omitempty"`
+}
+
+// DefaultSSE returns the encryption applied to the objects written without
+// encryption headers, or nil if the bucket is not encrypted by default.
+func (encryption *ServerSideEncryptionConfiguration) DefaultSSE() *SSEOption {
+	if encryption == nil || len(encryption.Rules) == 0 {
+		return nil
+	}
+	return &SSEOption{}
+}
+
+func (encryption *ServerSideEncryptionConfiguration) validate() error {
+	if len(encryption.Rules) != 1 {
+		return errors.New("exactly one encryption rule must be specified")
+	}
+	var rule = encryption.Rules[0]
+	if rule == nil || rule.ApplySSEByDefault == nil {
+		return errors.New("no default encryption is specified")
+	}
+	if rule.ApplySSEByDefault.SSEAlgorithm != SSEAlgorithmAES256 || rule.ApplySSEByDefault.KMSMasterKeyID != "" {
+		return errSSEInvalidAlgorithm
+	}
+	return nil
+}
+
+func parseEncryptionConfig(bytes []byte) (encryption *ServerSideEncryptionConfiguration, err error) {
+	encryption = &ServerSideEncryptionConfiguration{}
+	if err = xml.Unmarshal(bytes, encryption); err != nil {
+		return
+	}
+	if err = encryption.validate(); err != nil {
+		return nil, err
+	}
+	return
+}
+
+func storeBucketEncryption(bytes []byte, vol *Volume) (err error) {
+	if err = vol.store.Put(vol.name, bucketRootPath, XAttrKeyOSSEncryption, bytes); err != nil {
+		return
+	}
+	return nil
+}
+
+func deleteBucketEncryption(vol *Volume) (err error) {
+	if err = vol.store.Delete(vol.name, bucketRootPath, XAttrKeyOSSEncryption); err != nil {
+		return err
+	}
+	return nil
+}
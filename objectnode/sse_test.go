@@ -0,0 +1,104 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/base64"
+	"net/http"
+	"testing"
+)
+
+func TestSSE_ParseOption(t *testing.T) {
+	var key = bytes.Repeat([]byte{0x5a}, sseKeySize)
+	var sum = md5.Sum(key)
+	var keyMD5 = base64.StdEncoding.EncodeToString(sum[:])
+
+	var header = http.Header{}
+	if opt, err := parseSSEOption(header); err != nil || opt != nil {
+		t.Fatalf("unexpected option: opt(%v) err(%v)", opt, err)
+	}
+	header.Set(HeaderNameXAmzServerSideEncryption, SSEAlgorithmAES256)
+	if opt, err := parseSSEOption(header); err != nil || opt == nil || opt.Customer() {
+		t.Fatalf("unexpected option: opt(%v) err(%v)", opt, err)
+	}
+
+	header = http.Header{}
+	header.Set(HeaderNameXAmzSSECustomerAlgorithm, SSEAlgorithmAES256)
+	header.Set(HeaderNameXAmzSSECustomerKey, base64.StdEncoding.EncodeToString(key))
+	header.Set(HeaderNameXAmzSSECustomerKeyMD5, keyMD5)
+	opt, err := parseSSEOption(header)
+	if err != nil || !opt.Customer() || !bytes.Equal(opt.CustomerKey, key) || opt.CustomerKeyMD5 != keyMD5 {
+		t.Fatalf("unexpected option: opt(%v) err(%v)", opt, err)
+	}
+	if err = checkObjectSSE(&FSFileInfo{SSECustomerKeyMD5: keyMD5}, opt); err != nil {
+		t.Fatalf("check object encryption fail: err(%v)", err)
+	}
+	if err = checkObjectSSE(&FSFileInfo{SSECustomerKeyMD5: keyMD5}, nil); err != errSSECustomerKeyRequired {
+		t.Fatalf("customer key should be required: err(%v)", err)
+	}
+	if err = checkObjectSSE(&FSFileInfo{SSE: SSEAlgorithmAES256}, opt); err != errSSENotApplicable {
+		t.Fatalf("customer key should not be applicable: err(%v)", err)
+	}
+
+	header.Set(HeaderNameXAmzServerSideEncryption, SSEAlgorithmAES256)
+	if _, err = parseSSEOption(header); err != errSSENotApplicable {
+		t.Fatalf("both encryptions should not be applicable: err(%v)", err)
+	}
+	header.Del(HeaderNameXAmzServerSideEncryption)
+	header.Set(HeaderNameXAmzSSECustomerKeyMD5, base64.StdEncoding.EncodeToString(make([]byte, md5.Size)))
+	if _, err = parseSSEOption(header); err != errSSECustomerKeyMD5Mismatch {
+		t.Fatalf("customer key MD5 should mismatch: err(%v)", err)
+	}
+	header.Set(HeaderNameXAmzSSECustomerKey, base64.StdEncoding.EncodeToString(key[:16]))
+	if _, err = parseSSEOption(header); err != errSSEInvalidCustomerKey {
+		t.Fatalf("customer key should be invalid: err(%v)", err)
+	}
+	header.Set(HeaderNameXAmzSSECustomerAlgorithm, "aws:kms")
+	if _, err = parseSSEOption(header); err != errSSEInvalidAlgorithm {
+		t.Fatalf("algorithm should be invalid: err(%v)", err)
+	}
+}
+
+func TestSSE_ParseEncryptionConfig(t *testing.T) {
+	var raw = `<ServerSideEncryptionConfiguration>
+  <Rule>
+    <ApplyServerSideEncryptionByDefault><SSEAlgorithm>AES256</SSEAlgorithm></ApplyServerSideEncryptionByDefault>
+  </Rule>
+</ServerSideEncryptionConfiguration>`
+	encryption, err := parseEncryptionConfig([]byte(raw))
+	if err != nil {
+		t.Fatalf("parse encryption fail: err(%v)", err)
+	}
+	if opt := encryption.DefaultSSE(); opt == nil || opt.Customer() {
+		t.Fatalf("unexpected default encryption: %v", opt)
+	}
+
+	var kms = `<ServerSideEncryptionConfiguration><Rule><ApplyServerSideEncryptionByDefault><SSEAlgorithm>aws:kms</SSEAlgorithm><KMSMasterKeyID>k</KMSMasterKeyID></ApplyServerSideEncryptionByDefault></Rule></ServerSideEncryptionConfiguration>`
+	if _, err = parseEncryptionConfig([]byte(kms)); err != errSSEInvalidAlgorithm {
+		t.Fatalf("algorithm should be invalid: err(%v)", err)
+	}
+	var invalids = []string{
+		`<ServerSideEncryptionConfiguration></ServerSideEncryptionConfiguration>`,
+		`<ServerSideEncryptionConfiguration><Rule></Rule></ServerSideEncryptionConfiguration>`,
+		`<ServerSideEncryptionConfiguration`,
+	}
+	for _, invalid := range invalids {
+		if _, err = parseEncryptionConfig([]byte(invalid)); err == nil {
+			t.Fatalf("invalid encryption is parsed: %v", invalid)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+// https://docs.aws.amazon.com/AmazonS3/latest/dev/Versioning.html
+
+import (
+	"encoding/xml"
+
+	"github.com/chubaofs/chubaofs/util/errors"
+)
+
+const (
+	VersioningStatusEnabled   = "Enabled"
+	VersioningStatusSuspended = "Suspended"
+)
+
+type VersioningConfiguration struct {
+	XMLName xml.Name `xml:"VersioningConfiguration"`
+	Status  string   `xml:"Status,omitempty"`
+}
+
+// Enabled returns true if new versions are created for the objects written.
+func (versioning *VersioningConfiguration) Enabled() bool {
+	return versioning != nil && versioning.Status == VersioningStatusEnabled
+}
+
+// Configured returns true if the versioning has ever been enabled for the bucket.
+// Once enabled, the versioning of a bucket can only be suspended, and the versions
+// of the objects are kept.
+func (versioning *VersioningConfiguration) Configured() bool {
+	return versioning != nil && versioning.Status != ""
+}
+
+func parseVersioningConfig(bytes []byte) (versioning *VersioningConfiguration, err error) {
+	versioning = &VersioningConfiguration{}
+	if err = xml.Unmarshal(bytes, versioning); err != nil {
+		return
+	}
+	if versioning.Status != VersioningStatusEnabled && versioning.Status != VersioningStatusSuspended {
+		return nil, errors.New("invalid versioning status")
+	}
+	return
+}
+
+func storeBucketVersioning(bytes []byte, vol *Volume) (err error) {
+	if err = vol.store.Put(vol.name, bucketRootPath, XAttrKeyOSSVersioning, bytes); err != nil {
+		return
+	}
+	return nil
+}
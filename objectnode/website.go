@@ -0,0 +1,244 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+// https://docs.aws.amazon.com/AmazonS3/latest/dev/WebsiteHosting.html
+
+import (
+	"encoding/xml"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/chubaofs/chubaofs/util/errors"
+)
+
+const (
+	websiteProtocolHTTP  = "http"
+	websiteProtocolHTTPS = "https"
+
+	// The maximum number of the routing rules of a website configuration.
+	websiteMaxRoutingRules = 50
+)
+
+var (
+	errWebsiteIndexDocumentRequired = errors.New("index document or redirect all requests must be specified")
+	errWebsiteRedirectAllConflict   = errors.New("redirect all requests can not be specified with other elements")
+	errWebsiteInvalidSuffix         = errors.New("index document suffix must be non-empty without slash")
+	errWebsiteInvalidProtocol       = errors.New("protocol must be http or https")
+	errWebsiteInvalidRedirectCode   = errors.New("http redirect code must be 3XX")
+	errWebsiteInvalidRedirect       = errors.New("invalid redirect of routing rule")
+	errWebsiteTooManyRoutingRules   = errors.New("too many routing rules")
+)
+
+type WebsiteConfiguration struct {
+	XMLName               xml.Name               `xml:"WebsiteConfiguration"`
+	IndexDocument         *IndexDocument         `xml:"IndexDocument,omitempty"`
+	ErrorDocument         *ErrorDocument         `xml:"ErrorDocument,omitempty"`
+	RedirectAllRequestsTo *RedirectAllRequestsTo `xml:"RedirectAllRequestsTo,omitempty"`
+	RoutingRules          []*RoutingRule         `xml:"RoutingRules>RoutingRule,omitempty"`
+}
+
+type IndexDocument struct {
+	Suffix string `xml:"Suffix"`
+}
+
+type ErrorDocument struct {
+	Key string `xml:"Key"`
+}
+
+type RedirectAllRequestsTo struct {
+	HostName string `xml:"HostName"`
+	Protocol string `xml:"Protocol,omitempty"`
+}
+
+type RoutingRule struct {
+	Condition *RoutingRuleCondition `xml:"Condition,omitempty"`
+	Redirect  *RoutingRuleRedirect  `xml:"Redirect"`
+}
+
+type RoutingRuleCondition struct {
+	HttpErrorCodeReturnedEquals string `xml:"HttpErrorCodeReturnedEquals,omitempty"`
+	KeyPrefixEquals             string `xml:"KeyPrefixEquals,omitempty"`
+}
+
+type RoutingRuleRedirect struct {
+	HostName             string `xml:"HostName,omitempty"`
+	HttpRedirectCode     string `xml:"HttpRedirectCode,omitempty"`
+	Protocol             string `xml:"Protocol,omitempty"`
+	ReplaceKeyPrefixWith string `xml:"ReplaceKeyPrefixWith,omitempty"`
+	ReplaceKeyWith       string `xml:"ReplaceKeyWith,omitempty"`
+}
+
+func validWebsiteProtocol(protocol string) bool {
+	return protocol == "" || protocol == websiteProtocolHTTP || protocol == websiteProtocolHTTPS
+}
+
+func (c *WebsiteConfiguration) Validate() error {
+	if c.RedirectAllRequestsTo != nil {
+		if c.IndexDocument != nil || c.ErrorDocument != nil || len(c.RoutingRules) > 0 {
+			return errWebsiteRedirectAllConflict
+		}
+		if c.RedirectAllRequestsTo.HostName == "" {
+			return errWebsiteInvalidRedirect
+		}
+		if !validWebsiteProtocol(c.RedirectAllRequestsTo.Protocol) {
+			return errWebsiteInvalidProtocol
+		}
+		return nil
+	}
+	if c.IndexDocument == nil {
+		return errWebsiteIndexDocumentRequired
+	}
+	if c.IndexDocument.Suffix == "" || strings.Contains(c.IndexDocument.Suffix, "/") {
+		return errWebsiteInvalidSuffix
+	}
+	if len(c.RoutingRules) > websiteMaxRoutingRules {
+		return errWebsiteTooManyRoutingRules
+	}
+	for _, rule := range c.RoutingRules {
+		if err := rule.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *RoutingRule) Validate() error {
+	var redirect = r.Redirect
+	if redirect == nil || *redirect == (RoutingRuleRedirect{}) {
+		return errWebsiteInvalidRedirect
+	}
+	if redirect.ReplaceKeyPrefixWith != "" && redirect.ReplaceKeyWith != "" {
+		return errWebsiteInvalidRedirect
+	}
+	if !validWebsiteProtocol(redirect.Protocol) {
+		return errWebsiteInvalidProtocol
+	}
+	if redirect.HttpRedirectCode != "" {
+		if code, err := strconv.Atoi(redirect.HttpRedirectCode); err != nil || code < 300 || code > 399 {
+			return errWebsiteInvalidRedirectCode
+		}
+	}
+	if r.Condition != nil && r.Condition.HttpErrorCodeReturnedEquals != "" {
+		if code, err := strconv.Atoi(r.Condition.HttpErrorCodeReturnedEquals); err != nil || code < 400 || code > 599 {
+			return errWebsiteInvalidRedirect
+		}
+	}
+	return nil
+}
+
+// match returns true if the rule is applied to the key with the returned status code,
+// which is zero before the object is read.
+func (r *RoutingRule) match(key string, statusCode int) bool {
+	if r.Condition == nil {
+		return statusCode == 0
+	}
+	if !strings.HasPrefix(key, r.Condition.KeyPrefixEquals) {
+		return false
+	}
+	if r.Condition.HttpErrorCodeReturnedEquals == "" {
+		return statusCode == 0
+	}
+	return r.Condition.HttpErrorCodeReturnedEquals == strconv.Itoa(statusCode)
+}
+
+// location returns the redirect location of the key and the redirect code.
+func (r *RoutingRule) location(req *http.Request, key string) (location string, code int) {
+	var redirect = r.Redirect
+	var protocol, host = redirect.Protocol, redirect.HostName
+	if protocol == "" {
+		protocol = requestProtocol(req)
+	}
+	if host == "" {
+		host = req.Host
+	}
+	if redirect.ReplaceKeyWith != "" {
+		key = redirect.ReplaceKeyWith
+	} else if redirect.ReplaceKeyPrefixWith != "" || r.Condition != nil && r.Condition.KeyPrefixEquals != "" {
+		var prefix string
+		if r.Condition != nil {
+			prefix = r.Condition.KeyPrefixEquals
+		}
+		key = redirect.ReplaceKeyPrefixWith + strings.TrimPrefix(key, prefix)
+	}
+	code = http.StatusMovedPermanently
+	if redirect.HttpRedirectCode != "" {
+		code, _ = strconv.Atoi(redirect.HttpRedirectCode)
+	}
+	return protocol + "://" + host + "/" + key, code
+}
+
+// routingRule returns the first routing rule applied to the key with the returned status code.
+func (c *WebsiteConfiguration) routingRule(key string, statusCode int) *RoutingRule {
+	for _, rule := range c.RoutingRules {
+		if rule.match(key, statusCode) {
+			return rule
+		}
+	}
+	return nil
+}
+
+// redirectAllLocation returns the location which all of the requests are redirected to.
+func (c *WebsiteConfiguration) redirectAllLocation(req *http.Request, key string) string {
+	var protocol = c.RedirectAllRequestsTo.Protocol
+	if protocol == "" {
+		protocol = requestProtocol(req)
+	}
+	return protocol + "://" + c.RedirectAllRequestsTo.HostName + "/" + key
+}
+
+// indexKey returns the key of the index document if the key is directory-like.
+func (c *WebsiteConfiguration) indexKey(key string) string {
+	if key == "" || strings.HasSuffix(key, "/") {
+		return key + c.IndexDocument.Suffix
+	}
+	return key
+}
+
+func requestProtocol(r *http.Request) string {
+	if r.TLS != nil {
+		return websiteProtocolHTTPS
+	}
+	if forwarded := strings.ToLower(r.Header.Get(HeaderNameXForwardedProto)); forwarded == websiteProtocolHTTPS {
+		return forwarded
+	}
+	return websiteProtocolHTTP
+}
+
+func parseWebsiteConfig(bytes []byte) (*WebsiteConfiguration, error) {
+	var config = &WebsiteConfiguration{}
+	if err := xml.Unmarshal(bytes, config); err != nil {
+		return nil, err
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+func storeBucketWebsite(bytes []byte, vol *Volume) (err error) {
+	if err = vol.store.Put(vol.name, bucketRootPath, XAttrKeyOSSWebsite, bytes); err != nil {
+		return
+	}
+	return nil
+}
+
+func deleteBucketWebsite(vol *Volume) (err error) {
+	if err = vol.store.Delete(vol.name, bucketRootPath, XAttrKeyOSSWebsite); err != nil {
+		return err
+	}
+	return nil
+}
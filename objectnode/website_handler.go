@@ -0,0 +1,287 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// Get bucket website
+// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_GetBucketWebsite.html
+func (o *ObjectNode) getBucketWebsiteHandler(w http.ResponseWriter, r *http.Request) {
+	var err error
+	var param = ParseRequestParam(r)
+	if param.Bucket() == "" {
+		_ = NoSuchBucket.ServeResponse(w, r)
+		return
+	}
+	var vol *Volume
+	if vol, err = o.vm.Volume(param.Bucket()); err != nil {
+		_ = NoSuchBucket.ServeResponse(w, r)
+		return
+	}
+
+	var website *WebsiteConfiguration
+	if website, err = vol.metaLoader.loadWebsite(); err != nil {
+		_ = InternalErrorCode(err).ServeResponse(w, r)
+		return
+	}
+	if website == nil {
+		_ = NoSuchWebsiteConfiguration.ServeResponse(w, r)
+		return
+	}
+	var data []byte
+	if data, err = MarshalXMLEntity(website); err != nil {
+		_ = InternalErrorCode(err).ServeResponse(w, r)
+		return
+	}
+
+	w.Header()[HeaderNameContentType] = []string{HeaderValueContentTypeXML}
+	w.Header()[HeaderNameContentLength] = []string{strconv.Itoa(len(data))}
+	_, _ = w.Write(data)
+	return
+}
+
+// Put bucket website
+// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_PutBucketWebsite.html
+func (o *ObjectNode) putBucketWebsiteHandler(w http.ResponseWriter, r *http.Request) {
+	var err error
+	var param = ParseRequestParam(r)
+	if param.Bucket() == "" {
+		_ = NoSuchBucket.ServeResponse(w, r)
+		return
+	}
+	var vol *Volume
+	if vol, err = o.vm.Volume(param.Bucket()); err != nil {
+		_ = NoSuchBucket.ServeResponse(w, r)
+		return
+	}
+
+	var bytes []byte
+	if bytes, err = ioutil.ReadAll(r.Body); err != nil && err != io.EOF {
+		_ = InternalErrorCode(err).ServeResponse(w, r)
+		return
+	}
+	var website *WebsiteConfiguration
+	if website, err = parseWebsiteConfig(bytes); err != nil {
+		log.LogErrorf("putBucketWebsiteHandler: parse website config fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), vol.Name(), err)
+		if _, ok := err.(*xml.SyntaxError); ok {
+			_ = MalformedXML.ServeResponse(w, r)
+			return
+		}
+		_ = InvalidWebsiteConfiguration.ServeResponse(w, r)
+		return
+	}
+
+	var newBytes []byte
+	if newBytes, err = xml.Marshal(website); err != nil {
+		_ = InternalErrorCode(err).ServeResponse(w, r)
+		return
+	}
+	if err = storeBucketWebsite(newBytes, vol); err != nil {
+		log.LogErrorf("putBucketWebsiteHandler: store website config fail: requestID(%v) volume(%v) err(%v)",
+			GetRequestID(r), vol.Name(), err)
+		_ = InternalErrorCode(err).ServeResponse(w, r)
+		return
+	}
+	vol.metaLoader.storeWebsite(website)
+
+	log.LogInfof("Audit: put bucket website: requestID(%v) volume(%v) rules(%v)",
+		GetRequestID(r), vol.Name(), len(website.RoutingRules))
+	return
+}
+
+// Delete bucket website
+// API reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_DeleteBucketWebsite.html
+func (o *ObjectNode) deleteBucketWebsiteHandler(w http.ResponseWriter, r *http.Request) {
+	var err error
+	var param = ParseRequestParam(r)
+	if param.Bucket() == "" {
+		_ = NoSuchBucket.ServeResponse(w, r)
+		return
+	}
+	var vol *Volume
+	if vol, err = o.vm.Volume(param.Bucket()); err != nil {
+		_ = NoSuchBucket.ServeResponse(w, r)
+		return
+	}
+
+	if err = deleteBucketWebsite(vol); err != nil {
+		_ = InternalErrorCode(err).ServeResponse(w, r)
+		return
+	}
+	vol.metaLoader.storeWebsite(nil)
+
+	log.LogInfof("Audit: delete bucket website: requestID(%v) volume(%v)", GetRequestID(r), vol.Name())
+	w.WriteHeader(http.StatusNoContent)
+	return
+}
+
+// websiteDispatcher returns a handler dispatching the requests to the website endpoints
+// to the website handler, and the others to the object storage interface.
+func (o *ObjectNode) websiteDispatcher(website, api http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, is := o.websiteWildcards.Parse(r.Host); is {
+			website.ServeHTTP(w, r)
+			return
+		}
+		api.ServeHTTP(w, r)
+	})
+}
+
+func (o *ObjectNode) websiteMethodNotAllowedHandler(w http.ResponseWriter, r *http.Request) {
+	_ = MethodNotAllowed.ServeResponse(w, r)
+	return
+}
+
+// Website endpoint
+// The website endpoint serves the anonymous requests reading the objects of the
+// buckets with the website configurations. The directory-like keys are resolved
+// to the index documents, and the error document is returned with the status
+// code if the object is not found or the access is denied.
+func (o *ObjectNode) websiteHandler(w http.ResponseWriter, r *http.Request) {
+	var err error
+	var param = ParseRequestParam(r)
+	var vol *Volume
+	if vol, err = o.vm.Volume(param.Bucket()); err != nil {
+		_ = NoSuchBucket.ServeResponse(w, r)
+		return
+	}
+	var website *WebsiteConfiguration
+	if website, err = vol.metaLoader.loadWebsite(); err != nil {
+		_ = InternalErrorCode(err).ServeResponse(w, r)
+		return
+	}
+	if website == nil {
+		_ = NoSuchWebsiteConfiguration.ServeResponse(w, r)
+		return
+	}
+
+	var key = param.Object()
+	if website.RedirectAllRequestsTo != nil {
+		websiteRedirect(w, website.redirectAllLocation(r, key), http.StatusMovedPermanently)
+		return
+	}
+	if rule := website.routingRule(key, 0); rule != nil {
+		location, code := rule.location(r, key)
+		websiteRedirect(w, location, code)
+		return
+	}
+
+	var errorCode = o.serveWebsiteObject(w, r, vol, website.indexKey(key), http.StatusOK)
+	if errorCode == nil {
+		return
+	}
+	// a directory-like key without the trailing slash is redirected to its index document
+	if errorCode == NoSuchKey && key != "" && !strings.HasSuffix(key, "/") {
+		if info, err := vol.ObjectMeta(website.indexKey(key + "/")); err == nil && !info.Mode.IsDir() {
+			websiteRedirect(w, "/"+key+"/", http.StatusFound)
+			return
+		}
+	}
+	log.LogDebugf("websiteHandler: serve object fail: requestID(%v) volume(%v) key(%v) status(%v)",
+		GetRequestID(r), vol.Name(), key, errorCode.StatusCode)
+	if rule := website.routingRule(key, errorCode.StatusCode); rule != nil {
+		location, code := rule.location(r, key)
+		websiteRedirect(w, location, code)
+		return
+	}
+	if website.ErrorDocument != nil && website.ErrorDocument.Key != "" &&
+		o.serveWebsiteObject(w, r, vol, website.ErrorDocument.Key, errorCode.StatusCode) == nil {
+		return
+	}
+	_ = errorCode.ServeResponse(w, r)
+	return
+}
+
+func websiteRedirect(w http.ResponseWriter, location string, code int) {
+	w.Header()[HeaderNameLocation] = []string{location}
+	w.WriteHeader(code)
+}
+
+// websiteAllowed returns true if the anonymous request is allowed by the bucket policy.
+func websiteAllowed(vol *Volume, param *RequestParam) bool {
+	policy, err := vol.metaLoader.loadPolicy()
+	if err != nil || policy == nil || policy.IsEmpty() {
+		return false
+	}
+	return policy.IsAllowed(param, false)
+}
+
+// serveWebsiteObject writes the object to the response with the status code,
+// or returns the error code if the object can not be served.
+func (o *ObjectNode) serveWebsiteObject(w http.ResponseWriter, r *http.Request, vol *Volume, key string, statusCode int) *ErrorCode {
+	var param = ParseRequestParam(r)
+	param.object = key
+	param.resource = param.bucket + "/" + key
+	if !websiteAllowed(vol, param) {
+		return AccessDenied
+	}
+
+	var err error
+	var info *FSFileInfo
+	if info, err = vol.ObjectMeta(key); err == syscall.ENOENT || err == nil && info.Mode.IsDir() {
+		return NoSuchKey
+	}
+	if err != nil {
+		log.LogErrorf("serveWebsiteObject: get file meta fail: requestID(%v) volume(%v) path(%v) err(%v)",
+			GetRequestID(r), vol.Name(), key, err)
+		return InternalErrorCode(err)
+	}
+	// the objects encrypted with the customer keys can not be read anonymously
+	if err = checkObjectSSE(info, nil); err != nil {
+		return AccessDenied
+	}
+
+	if len(info.MIMEType) > 0 {
+		w.Header()[HeaderNameContentType] = []string{info.MIMEType}
+	} else {
+		w.Header()[HeaderNameContentType] = []string{HeaderValueTypeStream}
+	}
+	if len(info.Disposition) > 0 {
+		w.Header()[HeaderNameContentDisposition] = []string{info.Disposition}
+	}
+	if len(info.CacheControl) > 0 {
+		w.Header()[HeaderNameCacheControl] = []string{info.CacheControl}
+	}
+	if len(info.Expires) > 0 {
+		w.Header()[HeaderNameExpires] = []string{info.Expires}
+	}
+	for name, value := range info.Metadata {
+		w.Header()[HeaderNameXAmzMetaPrefix+name] = []string{value}
+	}
+	w.Header()[HeaderNameContentLength] = []string{strconv.FormatInt(info.Size, 10)}
+	w.Header()[HeaderNameETag] = []string{wrapUnescapedQuot(info.ETag)}
+	w.Header()[HeaderNameLastModified] = []string{formatTimeRFC1123(info.ModifyTime)}
+	w.WriteHeader(statusCode)
+	if r.Method == http.MethodHead {
+		return nil
+	}
+
+	if err = vol.ReadFile(key, w, 0, uint64(info.Size)); err != nil {
+		log.LogErrorf("serveWebsiteObject: read file fail: requestID(%v) volume(%v) path(%v) err(%v)",
+			GetRequestID(r), vol.Name(), key, err)
+	}
+	return nil
+}
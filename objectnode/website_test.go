@@ -0,0 +1,102 @@
+// Copyright 2019 The ChubaoFS Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package objectnode
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWebsite_Parse(t *testing.T) {
+	var raw = `<WebsiteConfiguration>
+  <IndexDocument><Suffix>index.html</Suffix></IndexDocument>
+  <ErrorDocument><Key>error.html</Key></ErrorDocument>
+  <RoutingRules>
+    <RoutingRule>
+      <Condition><KeyPrefixEquals>docs/</KeyPrefixEquals></Condition>
+      <Redirect><ReplaceKeyPrefixWith>documents/</ReplaceKeyPrefixWith></Redirect>
+    </RoutingRule>
+    <RoutingRule>
+      <Condition><HttpErrorCodeReturnedEquals>404</HttpErrorCodeReturnedEquals></Condition>
+      <Redirect><HostName>example.com</HostName><Protocol>https</Protocol><HttpRedirectCode>302</HttpRedirectCode></Redirect>
+    </RoutingRule>
+  </RoutingRules>
+</WebsiteConfiguration>`
+	website, err := parseWebsiteConfig([]byte(raw))
+	if err != nil {
+		t.Fatalf("parse website fail: err(%v)", err)
+	}
+	if key := website.indexKey(""); key != "index.html" {
+		t.Fatalf("unexpected index key: %v", key)
+	}
+	if key := website.indexKey("a/b/"); key != "a/b/index.html" {
+		t.Fatalf("unexpected index key: %v", key)
+	}
+	if key := website.indexKey("a/b"); key != "a/b" {
+		t.Fatalf("unexpected index key: %v", key)
+	}
+
+	var r = httptest.NewRequest(http.MethodGet, "http://bucket.website.chubao.io/docs/a.html", nil)
+	rule := website.routingRule("docs/a.html", 0)
+	if rule == nil {
+		t.Fatalf("routing rule should match")
+	}
+	if location, code := rule.location(r, "docs/a.html"); location != "http://bucket.website.chubao.io/documents/a.html" ||
+		code != http.StatusMovedPermanently {
+		t.Fatalf("unexpected redirect: location(%v) code(%v)", location, code)
+	}
+	if rule = website.routingRule("a.html", 0); rule != nil {
+		t.Fatalf("routing rule should not match")
+	}
+	if rule = website.routingRule("a.html", http.StatusNotFound); rule == nil {
+		t.Fatalf("error routing rule should match")
+	}
+	if location, code := rule.location(r, "a.html"); location != "https://example.com/a.html" || code != http.StatusFound {
+		t.Fatalf("unexpected redirect: location(%v) code(%v)", location, code)
+	}
+}
+
+func TestWebsite_Validate(t *testing.T) {
+	var invalids = []string{
+		`<WebsiteConfiguration><ErrorDocument><Key>error.html</Key></ErrorDocument></WebsiteConfiguration>`,
+		`<WebsiteConfiguration><IndexDocument><Suffix>a/index.html</Suffix></IndexDocument></WebsiteConfiguration>`,
+		`<WebsiteConfiguration><IndexDocument><Suffix>index.html</Suffix></IndexDocument>` +
+			`<RedirectAllRequestsTo><HostName>example.com</HostName></RedirectAllRequestsTo></WebsiteConfiguration>`,
+		`<WebsiteConfiguration><RedirectAllRequestsTo><HostName>example.com</HostName><Protocol>ftp</Protocol>` +
+			`</RedirectAllRequestsTo></WebsiteConfiguration>`,
+		`<WebsiteConfiguration><IndexDocument><Suffix>index.html</Suffix></IndexDocument><RoutingRules><RoutingRule>` +
+			`<Redirect><HttpRedirectCode>200</HttpRedirectCode></Redirect></RoutingRule></RoutingRules></WebsiteConfiguration>`,
+		`<WebsiteConfiguration><IndexDocument><Suffix>index.html</Suffix></IndexDocument><RoutingRules><RoutingRule>` +
+			`<Redirect><ReplaceKeyWith>a</ReplaceKeyWith><ReplaceKeyPrefixWith>b</ReplaceKeyPrefixWith></Redirect>` +
+			`</RoutingRule></RoutingRules></WebsiteConfiguration>`,
+	}
+	for _, raw := range invalids {
+		if _, err := parseWebsiteConfig([]byte(raw)); err == nil {
+			t.Fatalf("invalid website configuration should be rejected: %v", raw)
+		}
+	}
+
+	website, err := parseWebsiteConfig([]byte(`<WebsiteConfiguration><RedirectAllRequestsTo>` +
+		`<HostName>example.com</HostName></RedirectAllRequestsTo></WebsiteConfiguration>`))
+	if err != nil {
+		t.Fatalf("parse website fail: err(%v)", err)
+	}
+	var r = httptest.NewRequest(http.MethodGet, "http://bucket.website.chubao.io/a/b.html", nil)
+	r.Header.Set(HeaderNameXForwardedProto, "https")
+	if location := website.redirectAllLocation(r, "a/b.html"); location != "https://example.com/a/b.html" {
+		t.Fatalf("unexpected redirect location: %v", location)
+	}
+}
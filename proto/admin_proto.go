@@ -29,10 +29,27 @@ const (
 	AdminUpdateVol                 = "/vol/update"
 	AdminVolShrink                 = "/vol/shrink"
 	AdminVolExpand                 = "/vol/expand"
+	AdminCreateSnapshot            = "/vol/snapshot/create"
+	AdminDeleteSnapshot            = "/vol/snapshot/delete"
+	AdminListSnapshots             = "/vol/snapshot/list"
+	AdminSetQuota                  = "/quota/set"
+	AdminDeleteQuota               = "/quota/delete"
+	AdminListQuotas                = "/quota/list"
+	AdminGetQuota                  = "/quota/get"
 	AdminCreateVol                 = "/admin/createVol"
 	AdminGetVol                    = "/admin/getVol"
 	AdminClusterFreeze             = "/cluster/freeze"
 	AdminClusterStat               = "/cluster/stat"
+	AdminDataBalanceStart          = "/dataBalance/start"
+	AdminDataBalanceStop           = "/dataBalance/stop"
+	AdminDataBalanceStatus         = "/dataBalance/status"
+	AdminMetaBalancePlan           = "/metaBalance/plan"
+	AdminMetaBalanceStart          = "/metaBalance/start"
+	AdminMetaBalanceStop           = "/metaBalance/stop"
+	AdminMetaBalanceStatus         = "/metaBalance/status"
+	AdminUpgradeStart              = "/upgrade/start"
+	AdminUpgradeStop               = "/upgrade/stop"
+	AdminUpgradeStatus             = "/upgrade/status"
 	AdminGetIP                = "/admin/getIp"
 	AdminCreateMetaPartition      = "/metaPartition/create"
 	AdminSetMetaNodeThreshold     = "/threshold/set"
@@ -80,10 +97,17 @@ const (
 	GetMetaNode                    = "/metaNode/get"
 	AdminUpdateMetaNode            = "/metaNode/update"
 	AdminUpdateDataNode            = "/dataNode/update"
+	StartDataNodeMaintenance       = "/dataNode/maintenance/start"
+	StopDataNodeMaintenance        = "/dataNode/maintenance/stop"
+	StartMetaNodeMaintenance       = "/metaNode/maintenance/start"
+	StopMetaNodeMaintenance        = "/metaNode/maintenance/stop"
 	AdminGetInvalidNodes           = "/invalid/nodes"
 	AdminLoadMetaPartition         = "/metaPartition/load"
 	AdminDiagnoseMetaPartition     = "/metaPartition/diagnose"
 	AdminDecommissionMetaPartition = "/metaPartition/decommission"
+	AdminMergeMetaPartition        = "/metaPartition/merge"
+	AdminResplitMetaPartition      = "/metaPartition/resplit"
+	AdminMetaMoveStatus            = "/metaPartition/moveStatus"
 	AdminAddMetaReplica            = "/metaReplica/add"
 	AdminDeleteMetaReplica         = "/metaReplica/delete"
 
@@ -152,6 +176,8 @@ type CreateDataPartitionRequest struct {
 	Members       []Peer
 	Hosts         []string
 	CreateType    int
+	ECDataNum     uint8 // number of data shards if the partition is erasure-coded
+	ECParityNum   uint8
 }
 
 // CreateDataPartitionResponse defines the response to the request of creating a data partition.
@@ -250,6 +276,12 @@ type LoadMetaPartitionMetricResponse struct {
 type HeartBeatRequest struct {
 	CurrTime   int64
 	MasterAddr string
+	Quotas     []*QuotaHeartBeatInfo `json:",omitempty"`
+	TrashDays  map[string]uint32     `json:",omitempty"` // volume name -> days to keep the deleted files
+
+	// the volumes with snapshots, whose extents are not overwritten in place
+	SnapshotVols    []string `json:",omitempty"`
+	SnapshotVolsVer int64    `json:",omitempty"`
 }
 
 // PartitionReport defines the partition report.
@@ -265,6 +297,15 @@ type PartitionReport struct {
 	NeedCompare     bool
 }
 
+// DiskReport defines the report of the space of the disk on the data node.
+type DiskReport struct {
+	Path      string
+	Total     uint64
+	Used      uint64
+	Available uint64
+	Status    int
+}
+
 // DataNodeHeartbeatResponse defines the response to the data node heartbeat.
 type DataNodeHeartbeatResponse struct {
 	Total               uint64
@@ -279,6 +320,8 @@ type DataNodeHeartbeatResponse struct {
 	Status              uint8
 	Result              string
 	BadDisks            []string
+	DiskReports         []*DiskReport `json:",omitempty"`
+	Version             string        `json:",omitempty"` // version of the running data node
 }
 
 // MetaPartitionReport defines the meta partition report.
@@ -292,6 +335,8 @@ type MetaPartitionReport struct {
 	VolName     string
 	InodeCnt    uint64
 	DentryCnt   uint64
+	RequestRate float64       `json:",omitempty"` // requests per second served by the replica
+	QuotaUsages []*QuotaUsage `json:",omitempty"`
 }
 
 // MetaNodeHeartbeatResponse defines the response to the meta node heartbeat request.
@@ -302,6 +347,7 @@ type MetaNodeHeartbeatResponse struct {
 	MetaPartitionReports []*MetaPartitionReport
 	Status               uint8
 	Result               string
+	Version              string `json:",omitempty"` // version of the running meta node
 }
 
 // DeleteFileRequest defines the request to delete a file.
@@ -363,6 +409,54 @@ type MetaPartitionDecommissionResponse struct {
 	Result      string
 }
 
+// MetaSnapshotRequest defines the request to create or delete a snapshot of a meta partition.
+type MetaSnapshotRequest struct {
+	PartitionID uint64
+	VolName     string
+	SnapshotID  uint64
+}
+
+// FreezeMetaPartitionRequest defines the request to freeze or unfreeze a meta partition.
+// A frozen meta partition refuses the changes of its items, so that they can be moved to another one.
+type FreezeMetaPartitionRequest struct {
+	PartitionID uint64
+	VolName     string
+	Freeze      bool
+}
+
+// MoveMetaItemsRequest defines the request to move the items of the inodes in range from a frozen
+// meta partition to the target one. The request is sent repeatedly to poll the progress.
+type MoveMetaItemsRequest struct {
+	PartitionID       uint64
+	VolName           string
+	Start             uint64
+	End               uint64
+	TargetPartitionID uint64
+	TargetHosts       []string
+}
+
+// MoveMetaItemsResponse defines the progress of moving the items.
+type MoveMetaItemsResponse struct {
+	Status string
+	Moved  uint64 // number of the moved items
+	Result string
+}
+
+// TrimMetaPartitionRequest defines the request to set the end of a meta partition, and remove the
+// items of the inodes after the end.
+type TrimMetaPartitionRequest struct {
+	PartitionID uint64
+	VolName     string
+	End         uint64
+}
+
+// SnapshotInfo defines the snapshot of a volume.
+type SnapshotInfo struct {
+	ID         uint64
+	Name       string
+	CreateTime int64
+}
+
 // MetaPartitionLoadRequest defines the request to load meta partition.
 type MetaPartitionLoadRequest struct {
 	PartitionID uint64
@@ -388,6 +482,8 @@ type DataPartitionResponse struct {
 	LeaderAddr  string
 	Epoch       uint64
 	IsRecover   bool
+	ECDataNum   uint8 `json:",omitempty"`
+	ECParityNum uint8 `json:",omitempty"`
 }
 
 // DataPartitionsView defines the view of a data partition
@@ -490,6 +586,10 @@ type SimpleVolView struct {
 	DpSelectorName     string
 	DpSelectorParm     string
 	DefaultZonePrior   bool
+	SnapshotCnt        int
+	TrashDays          uint32
+	ECDataNum          uint8
+	ECParityNum        uint8
 }
 type NodeSetInfo struct {
 	ID        uint64
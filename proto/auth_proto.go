@@ -67,6 +67,9 @@ const (
 	AdminAddRaftNode    = "/admin/addraftnode"
 	AdminRemoveRaftNode = "/admin/removeraftnode"
 
+	// Data key APIs
+	ClientDataKey = "/client/datakey"
+
 	// Object node APIs
 	OSAddCaps    = "/os/addcaps"
 	OSDeleteCaps = "/os/deletecaps"
@@ -190,6 +193,18 @@ const (
 	// MsgAuthOSGetCapsResp response type from ObjectNode to get caps
 	MsgAuthOSGetCapsResp MsgType = MsgAuthBase + 0x63001
 
+	// MsgAuthGenDataKeyReq request type for a new data key of a volume
+	MsgAuthGenDataKeyReq MsgType = MsgAuthBase + 0x71000
+
+	// MsgAuthGenDataKeyResp response type for a new data key of a volume
+	MsgAuthGenDataKeyResp MsgType = MsgAuthBase + 0x71001
+
+	// MsgAuthDecryptDataKeyReq request type for decrypting a data key of a volume
+	MsgAuthDecryptDataKeyReq MsgType = MsgAuthBase + 0x72000
+
+	// MsgAuthDecryptDataKeyResp response type for decrypting a data key of a volume
+	MsgAuthDecryptDataKeyResp MsgType = MsgAuthBase + 0x72001
+
 	// MsgMasterAPIAccessReq request type for master api access
 	MsgMasterAPIAccessReq MsgType = 0x60000
 
@@ -220,6 +235,8 @@ var MsgType2ResourceMap = map[MsgType]string{
 	MsgAuthOSAddCapsReq:      "auth:osaddcaps",
 	MsgAuthOSDeleteCapsReq:   "auth:osdeletecaps",
 	MsgAuthOSGetCapsReq:      "auth:osgetcaps",
+	MsgAuthGenDataKeyReq:     "auth:gendatakey",
+	MsgAuthDecryptDataKeyReq: "auth:decryptdatakey",
 
 	MsgMasterFetchVolViewReq: "master:getvol",
 }
@@ -307,6 +324,27 @@ type AuthOSAccessKeyResp struct {
 	AKCaps  keystore.AccessKeyCaps `json:"access_key_caps"`
 }
 
+// CryptKeyXAttrKey is the extend attribute of an encrypted file, which holds
+// the data key of the file encrypted by the master key of the volume.
+const CryptKeyXAttrKey = "cfs.crypt.key"
+
+// AuthDataKeyReq defines Auth API request for generating or decrypting a
+// data key by the master key of the volume, which never leaves the authnode.
+type AuthDataKeyReq struct {
+	APIReq    APIAccessReq `json:"api_req"`
+	VolName   string       `json:"vol_name"`
+	CipherKey string       `json:"cipher_key"`
+}
+
+// AuthDataKeyResp defines Auth API response of a data key, which is encrypted
+// by the session key as a whole.
+type AuthDataKeyResp struct {
+	APIResp   APIAccessResp `json:"api_resp"`
+	VolName   string        `json:"vol_name"`
+	PlainKey  []byte        `json:"plain_key"`
+	CipherKey string        `json:"cipher_key"`
+}
+
 // IsValidServiceID determine the validity of a serviceID
 func IsValidServiceID(serviceID string) (err error) {
 	if serviceID != AuthServiceID && serviceID != MasterServiceID && serviceID != MetaServiceID && serviceID != DataServiceID {
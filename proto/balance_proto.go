@@ -0,0 +1,163 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package proto
+
+// Status of the data balance task
+const (
+	DataBalanceTaskPending   = "pending"
+	DataBalanceTaskAdding    = "adding"
+	DataBalanceTaskRepairing = "repairing"
+	DataBalanceTaskRemoving  = "removing"
+	DataBalanceTaskFinished  = "finished"
+	DataBalanceTaskFailed    = "failed"
+)
+
+// DataBalanceConfig defines the parameters of the data balance.
+type DataBalanceConfig struct {
+	Threshold      float64 // max difference of the usage ratios of the data nodes in a zone
+	MaxConcurrency int     // max number of the data partitions migrating at the same time
+	MaxBandwidth   uint64  // max average bytes per second of the data partitions to migrate, 0 for unlimited
+}
+
+// DataBalanceTask defines the migration of a data replica from the source data node to the target.
+type DataBalanceTask struct {
+	PartitionID uint64
+	VolName     string
+	Source      string
+	Target      string
+	Size        uint64
+	Status      string
+	StartTime   int64
+	EndTime     int64
+	Msg         string
+}
+
+// DataBalanceDiskUsage defines the space usage of a disk on the data node.
+type DataBalanceDiskUsage struct {
+	Path       string
+	Total      uint64
+	Used       uint64
+	UsageRatio float64
+}
+
+// DataBalanceNodeUsage defines the space usage of a data node and its disks.
+type DataBalanceNodeUsage struct {
+	Addr       string
+	Total      uint64
+	Used       uint64
+	UsageRatio float64
+	DiskSkew   float64 // difference between the max and min usage ratios of the disks
+	Disks      []*DataBalanceDiskUsage
+}
+
+// DataBalanceZoneUsage defines the space usage of the data nodes in a zone.
+type DataBalanceZoneUsage struct {
+	ZoneName   string
+	UsageRatio float64
+	Skew       float64 // difference between the max and min usage ratios of the data nodes
+	Nodes      []*DataBalanceNodeUsage
+}
+
+// DataBalanceStatus defines the status of the data balance.
+type DataBalanceStatus struct {
+	Running       bool
+	Config        DataBalanceConfig
+	StartTime     int64
+	FinishedCount uint64
+	FailedCount   uint64
+	MovedBytes    uint64
+	Tasks         []*DataBalanceTask // the tasks in progress
+	RecentTasks   []*DataBalanceTask // the tasks finished or failed recently
+	Zones         []*DataBalanceZoneUsage
+}
+
+// Status of the meta balance task
+const (
+	MetaBalanceTaskPending      = "pending"
+	MetaBalanceTaskAdding       = "adding"
+	MetaBalanceTaskCatchingUp   = "catchingUp"
+	MetaBalanceTaskRemoving     = "removing"
+	MetaBalanceTaskTransferring = "transferring"
+	MetaBalanceTaskFinished     = "finished"
+	MetaBalanceTaskFailed       = "failed"
+)
+
+// Reason of the meta balance task
+const (
+	MetaBalanceByMemory      = "memory"
+	MetaBalanceByRequestRate = "requestRate"
+)
+
+// MetaBalanceConfig defines the parameters of the meta balance.
+type MetaBalanceConfig struct {
+	MemThreshold   float64 // max difference of the memory usage ratios of the meta nodes in a zone
+	RateThreshold  float64 // max difference of the request rates of the meta nodes in a zone, relative to the average
+	MaxConcurrency int     // max number of the meta partitions migrating at the same time
+}
+
+// MetaBalanceTask defines the migration of a meta replica from the source meta node to the target,
+// or the transfer of the leader for the request rate.
+type MetaBalanceTask struct {
+	PartitionID uint64
+	VolName     string
+	Source      string
+	Target      string
+	Reason      string
+	Memory      uint64  // estimated memory used by the replica
+	RequestRate float64 // requests per second served by the replica
+	Status      string
+	StartTime   int64
+	EndTime     int64
+	Msg         string
+}
+
+// MetaBalanceNodeUsage defines the memory usage and the request rate of a meta node.
+type MetaBalanceNodeUsage struct {
+	Addr           string
+	Total          uint64
+	Used           uint64
+	UsageRatio     float64
+	RequestRate    float64
+	PartitionCount int
+}
+
+// MetaBalanceZoneUsage defines the memory usage and the request rates of the meta nodes in a zone.
+type MetaBalanceZoneUsage struct {
+	ZoneName    string
+	UsageRatio  float64
+	Skew        float64 // difference between the max and min memory usage ratios of the meta nodes
+	RequestRate float64 // average request rate of the meta nodes
+	RateSkew    float64 // difference between the max and min request rates, relative to the average
+	Nodes       []*MetaBalanceNodeUsage
+}
+
+// MetaBalancePlan defines the migrations planned by the meta balance without executing them.
+type MetaBalancePlan struct {
+	Config MetaBalanceConfig
+	Tasks  []*MetaBalanceTask
+	Zones  []*MetaBalanceZoneUsage
+}
+
+// MetaBalanceStatus defines the status of the meta balance.
+type MetaBalanceStatus struct {
+	Running       bool
+	Config        MetaBalanceConfig
+	StartTime     int64
+	FinishedCount uint64
+	FailedCount   uint64
+	Tasks         []*MetaBalanceTask // the tasks in progress
+	RecentTasks   []*MetaBalanceTask // the tasks finished or failed recently
+	Zones         []*MetaBalanceZoneUsage
+}
@@ -0,0 +1,54 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package proto
+
+import (
+	"github.com/chubaofs/chubaofs/util"
+)
+
+// The layout of an extent of an erasure-coded data partition.
+// Every block of the extent, which is ECBlockSize of the file data, is a
+// stripe split into the data shards of the same size, the last of which is
+// padded with zeros, and the parity shards are computed from them. The i-th
+// shard of every stripe is appended to the extent of the same ID on the i-th
+// host of the partition, so a host holds a shard of every stripe, and the
+// extent offset of the file data is mapped to the shard by ECShardLocation.
+const (
+	ECBlockSize = util.BlockSize
+
+	// ECMaxShards is the max number of data and parity shards of a volume.
+	ECMaxShards = 16
+)
+
+// ECShardSize returns the size of a shard of every stripe.
+func ECShardSize(dataNum uint8) int {
+	return (ECBlockSize + int(dataNum) - 1) / int(dataNum)
+}
+
+// ECShardLocation returns the data shard which holds the byte at the logical
+// offset of the extent, and the offset of it in the extent of the shard.
+func ECShardLocation(dataNum uint8, offset uint64) (shard int, shardOffset uint64) {
+	shardSize := uint64(ECShardSize(dataNum))
+	block, inBlock := offset/ECBlockSize, offset%ECBlockSize
+	shard = int(inBlock / shardSize)
+	shardOffset = block*shardSize + inBlock%shardSize
+	return
+}
+
+// IsValidECShards returns true if the numbers of data and parity shards are
+// valid for an erasure-coded volume.
+func IsValidECShards(dataNum, parityNum int) bool {
+	return dataNum > 0 && parityNum > 0 && dataNum+parityNum <= ECMaxShards
+}
@@ -82,6 +82,19 @@ var (
 	ErrInvalidSecretKey                = errors.New("invalid secret key")
 	ErrIsOwner                         = errors.New("user owns the volume")
 	ErrZoneNum                         = errors.New("zone num not qualified")
+	ErrSnapshotNotExists               = errors.New("snapshot not exists")
+	ErrDuplicateSnapshot               = errors.New("duplicate snapshot")
+	ErrTooManySnapshots                = errors.New("too many snapshots")
+	ErrQuotaNotExists                  = errors.New("quota not exists")
+	ErrDataBalanceRunning              = errors.New("data balance is running")
+	ErrDataBalanceNotRunning           = errors.New("data balance is not running")
+	ErrMetaBalanceRunning              = errors.New("meta balance is running")
+	ErrMetaBalanceNotRunning           = errors.New("meta balance is not running")
+	ErrMetaMoveRunning                 = errors.New("meta partitions of the volume are being moved")
+	ErrNoAdjacentMetaPartition         = errors.New("no adjacent meta partition")
+	ErrUpgradeRunning                  = errors.New("upgrade is running")
+	ErrUpgradeNotRunning               = errors.New("upgrade is not running")
+	ErrNodeInMaintenance               = errors.New("node is in maintenance")
 )
 
 // http response error code and error message definitions
@@ -146,6 +159,19 @@ const (
 	ErrCodeInvalidSecretKey
 	ErrCodeIsOwner
 	ErrCodeZoneNumError
+	ErrCodeSnapshotNotExists
+	ErrCodeDuplicateSnapshot
+	ErrCodeTooManySnapshots
+	ErrCodeQuotaNotExists
+	ErrCodeDataBalanceRunning
+	ErrCodeDataBalanceNotRunning
+	ErrCodeMetaBalanceRunning
+	ErrCodeMetaBalanceNotRunning
+	ErrCodeMetaMoveRunning
+	ErrCodeNoAdjacentMetaPartition
+	ErrCodeUpgradeRunning
+	ErrCodeUpgradeNotRunning
+	ErrCodeNodeInMaintenance
 )
 
 // Err2CodeMap error map to code
@@ -208,6 +234,19 @@ var Err2CodeMap = map[error]int32{
 	ErrInvalidSecretKey:                ErrCodeInvalidSecretKey,
 	ErrIsOwner:                         ErrCodeIsOwner,
 	ErrZoneNum:							ErrCodeZoneNumError,
+	ErrSnapshotNotExists:               ErrCodeSnapshotNotExists,
+	ErrDuplicateSnapshot:               ErrCodeDuplicateSnapshot,
+	ErrTooManySnapshots:                ErrCodeTooManySnapshots,
+	ErrQuotaNotExists:                  ErrCodeQuotaNotExists,
+	ErrDataBalanceRunning:              ErrCodeDataBalanceRunning,
+	ErrDataBalanceNotRunning:           ErrCodeDataBalanceNotRunning,
+	ErrMetaBalanceRunning:              ErrCodeMetaBalanceRunning,
+	ErrMetaBalanceNotRunning:           ErrCodeMetaBalanceNotRunning,
+	ErrMetaMoveRunning:                 ErrCodeMetaMoveRunning,
+	ErrNoAdjacentMetaPartition:         ErrCodeNoAdjacentMetaPartition,
+	ErrUpgradeRunning:                  ErrCodeUpgradeRunning,
+	ErrUpgradeNotRunning:               ErrCodeUpgradeNotRunning,
+	ErrNodeInMaintenance:               ErrCodeNodeInMaintenance,
 }
 
 func ParseErrorCode(code int32) error {
@@ -277,6 +316,19 @@ var code2ErrMap = map[int32]error{
 	ErrCodeInvalidSecretKey:                ErrInvalidSecretKey,
 	ErrCodeIsOwner:                         ErrIsOwner,
 	ErrCodeZoneNumError:					ErrZoneNum,
+	ErrCodeSnapshotNotExists:               ErrSnapshotNotExists,
+	ErrCodeDuplicateSnapshot:               ErrDuplicateSnapshot,
+	ErrCodeTooManySnapshots:                ErrTooManySnapshots,
+	ErrCodeQuotaNotExists:                  ErrQuotaNotExists,
+	ErrCodeDataBalanceRunning:              ErrDataBalanceRunning,
+	ErrCodeDataBalanceNotRunning:           ErrDataBalanceNotRunning,
+	ErrCodeMetaBalanceRunning:              ErrMetaBalanceRunning,
+	ErrCodeMetaBalanceNotRunning:           ErrMetaBalanceNotRunning,
+	ErrCodeMetaMoveRunning:                 ErrMetaMoveRunning,
+	ErrCodeNoAdjacentMetaPartition:         ErrNoAdjacentMetaPartition,
+	ErrCodeUpgradeRunning:                  ErrUpgradeRunning,
+	ErrCodeUpgradeNotRunning:               ErrUpgradeNotRunning,
+	ErrCodeNodeInMaintenance:               ErrNodeInMaintenance,
 }
 
 type GeneralResp struct {
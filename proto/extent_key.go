@@ -31,6 +31,7 @@ var (
 	ExtentLength          = 40
 	ExtentKeyChecksumSize = 4
 	ExtentV2Length        = ExtentKeyHeaderSize + ExtentLength + ExtentKeyChecksumSize
+	ExtentNonceHeader     = []byte("EKN1")
 	InvalidKey            = errors.New("invalid key error")
 	InvalidKeyHeader      = errors.New("invalid extent v2 key header error")
 	InvalidKeyCheckSum    = errors.New("invalid extent v2 key checksum error")
@@ -44,6 +45,7 @@ type ExtentKey struct {
 	ExtentOffset uint64
 	Size         uint32
 	CRC          uint32
+	Nonce        uint64 // the nonce of the encrypted data, 0 if not encrypted by the extent key
 }
 
 // String returns the string format of the extentKey.
@@ -113,6 +115,27 @@ func (k *ExtentKey) UnmarshalBinary(buf *bytes.Buffer) (err error) {
 	return
 }
 
+// MarshalBinaryWithNonce marshals the binary format of the extent key
+// followed by the nonce.
+func (k *ExtentKey) MarshalBinaryWithNonce() ([]byte, error) {
+	data, err := k.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, 8)
+	binary.BigEndian.PutUint64(nonce, k.Nonce)
+	return append(data, nonce...), nil
+}
+
+// UnmarshalBinaryWithNonce unmarshals the binary format of the extent key
+// followed by the nonce.
+func (k *ExtentKey) UnmarshalBinaryWithNonce(buf *bytes.Buffer) (err error) {
+	if err = k.UnmarshalBinary(buf); err != nil {
+		return
+	}
+	return binary.Read(buf, binary.BigEndian, &k.Nonce)
+}
+
 func (k *ExtentKey) CheckSum() uint32 {
 	sign := crc32.NewIEEE()
 	buf, err := k.MarshalBinary()
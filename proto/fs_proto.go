@@ -132,12 +132,13 @@ func (d Dentry) String() string {
 
 // CreateInodeRequest defines the request to create an inode.
 type CreateInodeRequest struct {
-	VolName     string `json:"vol"`
-	PartitionID uint64 `json:"pid"`
-	Mode        uint32 `json:"mode"`
-	Uid         uint32 `json:"uid"`
-	Gid         uint32 `json:"gid"`
-	Target      []byte `json:"tgt"`
+	VolName     string   `json:"vol"`
+	PartitionID uint64   `json:"pid"`
+	Mode        uint32   `json:"mode"`
+	Uid         uint32   `json:"uid"`
+	Gid         uint32   `json:"gid"`
+	Target      []byte   `json:"tgt"`
+	QuotaIds    []uint64 `json:"qids,omitempty"`
 }
 
 // CreateInodeResponse defines the response to the request of creating an inode.
@@ -219,7 +220,8 @@ type UpdateDentryRequest struct {
 
 // UpdateDentryResponse defines the response to the request of updating a dentry.
 type UpdateDentryResponse struct {
-	Inode uint64 `json:"ino"` // old inode number
+	Inode     uint64 `json:"ino"`                 // old inode number
+	Versioned bool   `json:"versioned,omitempty"` // the old inode is kept by a version of the object
 }
 
 // DeleteDentryRequest define the request tp delete a dentry.
@@ -239,7 +241,8 @@ type BatchDeleteDentryRequest struct {
 
 // DeleteDentryResponse defines the response to the request of deleting a dentry.
 type DeleteDentryResponse struct {
-	Inode uint64 `json:"ino"`
+	Inode     uint64 `json:"ino"`
+	Versioned bool   `json:"versioned,omitempty"` // the inode is kept by a version of the object
 }
 
 // BatchDeleteDentryResponse defines the response to the request of deleting a dentry.
@@ -256,6 +259,7 @@ type LookupRequest struct {
 	PartitionID uint64 `json:"pid"`
 	ParentID    uint64 `json:"pino"`
 	Name        string `json:"name"`
+	SnapshotID  uint64 `json:"snap,omitempty"`
 }
 
 // LookupResponse defines the response for the loopup request.
@@ -269,6 +273,7 @@ type InodeGetRequest struct {
 	VolName     string `json:"vol"`
 	PartitionID uint64 `json:"pid"`
 	Inode       uint64 `json:"ino"`
+	SnapshotID  uint64 `json:"snap,omitempty"`
 }
 
 // InodeGetResponse defines the response to the InodeGetRequest.
@@ -281,6 +286,7 @@ type BatchInodeGetRequest struct {
 	VolName     string   `json:"vol"`
 	PartitionID uint64   `json:"pid"`
 	Inodes      []uint64 `json:"inos"`
+	SnapshotID  uint64   `json:"snap,omitempty"`
 }
 
 // BatchInodeGetResponse defines the response to the request of getting the inode in batch.
@@ -293,6 +299,7 @@ type ReadDirRequest struct {
 	VolName     string `json:"vol"`
 	PartitionID uint64 `json:"pid"`
 	ParentID    uint64 `json:"pino"`
+	SnapshotID  uint64 `json:"snap,omitempty"`
 }
 
 // ReadDirResponse defines the response to the request of reading dir.
@@ -300,6 +307,26 @@ type ReadDirResponse struct {
 	Children []Dentry `json:"children"`
 }
 
+// ReadDirPlusRequest defines the request to read at most Limit dentries of the dir,
+// the names of which are after Marker and start with Prefix, in the order of the names.
+type ReadDirPlusRequest struct {
+	VolName     string `json:"vol"`
+	PartitionID uint64 `json:"pid"`
+	ParentID    uint64 `json:"pino"`
+	Marker      string `json:"marker,omitempty"`
+	Prefix      string `json:"prefix,omitempty"`
+	Limit       uint64 `json:"limit"`
+	SnapshotID  uint64 `json:"snap,omitempty"`
+}
+
+// ReadDirPlusResponse defines the response to the request of reading dir in range.
+// Infos contains the inodes of the children stored in the same meta partition as the dir.
+type ReadDirPlusResponse struct {
+	Children []Dentry     `json:"children"`
+	Infos    []*InodeInfo `json:"infos"`
+	More     bool         `json:"more"` // true if there are more dentries after the children
+}
+
 // AppendExtentKeyRequest defines the request to append an extent key.
 type AppendExtentKeyRequest struct {
 	VolName     string    `json:"vol"`
@@ -321,6 +348,7 @@ type GetExtentsRequest struct {
 	VolName     string `json:"vol"`
 	PartitionID uint64 `json:"pid"`
 	Inode       uint64 `json:"ino"`
+	SnapshotID  uint64 `json:"snap,omitempty"`
 }
 
 // GetExtentsResponse defines the response to the request of getting extents.
@@ -394,6 +422,7 @@ type GetXAttrRequest struct {
 	PartitionId uint64 `json:"pid"`
 	Inode       uint64 `json:"ino"`
 	Key         string `json:"key"`
+	SnapshotID  uint64 `json:"snap,omitempty"`
 }
 
 type GetXAttrResponse struct {
@@ -415,6 +444,7 @@ type ListXAttrRequest struct {
 	VolName     string `json:"vol"`
 	PartitionId uint64 `json:"pid"`
 	Inode       uint64 `json:"ino"`
+	SnapshotID  uint64 `json:"snap,omitempty"`
 }
 
 type ListXAttrResponse struct {
@@ -429,6 +459,7 @@ type BatchGetXAttrRequest struct {
 	PartitionId uint64   `json:"pid"`
 	Inodes      []uint64 `json:"inos"`
 	Keys        []string `json:"keys"`
+	SnapshotID  uint64   `json:"snap,omitempty"`
 }
 
 type BatchGetXAttrResponse struct {
@@ -437,6 +468,141 @@ type BatchGetXAttrResponse struct {
 	XAttrs      []*XAttrInfo
 }
 
+// Lock types of file locks, same as F_RDLCK, F_WRLCK and F_UNLCK on Linux.
+const (
+	LockRead   uint32 = 0
+	LockWrite  uint32 = 1
+	LockUnlock uint32 = 2
+)
+
+// LockInfo defines a byte range lock or a whole file lock held on an inode.
+// A lock is owned by the pair of Client and Owner, and End is inclusive.
+type LockInfo struct {
+	Client string `json:"cli"`
+	Owner  uint64 `json:"owner"`
+	Pid    uint32 `json:"pid"`
+	Start  uint64 `json:"start"`
+	End    uint64 `json:"end"`
+	Type   uint32 `json:"type"`
+	Flock  bool   `json:"flock"`
+}
+
+func (l *LockInfo) String() string {
+	if l == nil {
+		return ""
+	}
+	return fmt.Sprintf("Client(%v)Owner(%v)Pid(%v)Range[%v,%v]Type(%v)Flock(%v)",
+		l.Client, l.Owner, l.Pid, l.Start, l.End, l.Type, l.Flock)
+}
+
+type SetLockRequest struct {
+	VolName     string   `json:"vol"`
+	PartitionId uint64   `json:"pid"`
+	Inode       uint64   `json:"ino"`
+	Lock        LockInfo `json:"lock"`
+	Lease       uint32   `json:"lease"` // seconds
+}
+
+type GetLockRequest struct {
+	VolName     string   `json:"vol"`
+	PartitionId uint64   `json:"pid"`
+	Inode       uint64   `json:"ino"`
+	Lock        LockInfo `json:"lock"`
+}
+
+type GetLockResponse struct {
+	Lock LockInfo `json:"lock"`
+}
+
+type RenewLockRequest struct {
+	VolName     string `json:"vol"`
+	PartitionId uint64 `json:"pid"`
+	Client      string `json:"cli"`
+	Lease       uint32 `json:"lease"` // seconds
+}
+
+type RenewLockResponse struct {
+	Count uint32 `json:"count"`
+}
+
+// Operations of a meta transaction item.
+const (
+	TxOpCreateDentry uint8 = iota // create the dentry, or replace OldInode with Inode
+	TxOpDeleteDentry
+	TxOpUnlinkInode
+	TxOpSetQuotaIds         // replace the directory quotas of the inode with QuotaIds
+	TxOpPutObjectVersion    // put Version of the object ParentID/Name
+	TxOpDeleteObjectVersion // delete Version of the object ParentID/Name
+)
+
+// States of a meta transaction.
+const (
+	TxStatePrepared uint8 = iota
+	TxStateCommitted
+	TxStateAborted
+)
+
+// TxItem defines a change made by a meta transaction in a meta partition.
+type TxItem struct {
+	PartitionID uint64             `json:"pid"`
+	Op          uint8              `json:"op"`
+	ParentID    uint64             `json:"pino"`
+	Name        string             `json:"name"`
+	Inode       uint64             `json:"ino"`
+	Type        uint32             `json:"type"`
+	OldInode    uint64             `json:"oino"`
+	QuotaIds    []uint64           `json:"qids,omitempty"`
+	Version     *ObjectVersionInfo `json:"ver,omitempty"`
+}
+
+func (ti *TxItem) String() string {
+	if ti == nil {
+		return ""
+	}
+	return fmt.Sprintf("PartitionID(%v)Op(%v)ParentID(%v)Name(%v)Inode(%v)Type(%v)OldInode(%v)QuotaIds(%v)Version(%v)",
+		ti.PartitionID, ti.Op, ti.ParentID, ti.Name, ti.Inode, ti.Type, ti.OldInode, ti.QuotaIds, ti.Version)
+}
+
+// TxInfo defines a meta transaction across meta partitions. The partition
+// TmID is the transaction manager which decides whether the transaction
+// commits, and the others only follow the decision.
+type TxInfo struct {
+	TxID    string            `json:"tx"`
+	TmID    uint64            `json:"tm"`
+	Items   []*TxItem         `json:"items"`
+	Members map[uint64]string `json:"members"` // partition ID -> hosts separated by comma, leader first
+}
+
+type TxPrepareRequest struct {
+	VolName     string  `json:"vol"`
+	PartitionId uint64  `json:"pid"`
+	Tx          *TxInfo `json:"tx"`
+}
+
+type TxCommitRequest struct {
+	VolName     string `json:"vol"`
+	PartitionId uint64 `json:"pid"`
+	TxID        string `json:"tx"`
+	TmID        uint64 `json:"tm"`
+}
+
+type TxAbortRequest struct {
+	VolName     string `json:"vol"`
+	PartitionId uint64 `json:"pid"`
+	TxID        string `json:"tx"`
+	TmID        uint64 `json:"tm"`
+}
+
+type TxGetStatusRequest struct {
+	VolName     string `json:"vol"`
+	PartitionId uint64 `json:"pid"`
+	TxID        string `json:"tx"`
+}
+
+type TxGetStatusResponse struct {
+	State uint8 `json:"state"`
+}
+
 type MultipartInfo struct {
 	ID       string               `json:"id"`
 	Path     string               `json:"path"`
@@ -0,0 +1,60 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package proto
+
+// Types of the meta move task
+const (
+	MetaMoveMerge   = "merge"   // merge the partition into the previous adjacent one
+	MetaMoveResplit = "resplit" // move the tail of the partition to a new one
+)
+
+// Status of the meta move task
+const (
+	MetaMoveTaskCreating  = "creating"
+	MetaMoveTaskFreezing  = "freezing"
+	MetaMoveTaskMoving    = "moving"
+	MetaMoveTaskSwitching = "switching"
+	MetaMoveTaskFinished  = "finished"
+	MetaMoveTaskFailed    = "failed"
+)
+
+// Status of moving the items on the meta node
+const (
+	MetaItemsMoving = "moving"
+	MetaItemsMoved  = "moved"
+	MetaItemsFailed = "failed"
+)
+
+// MetaMoveTask defines the move of the items of the inodes in range from the source meta partition
+// to the target one.
+type MetaMoveTask struct {
+	VolName   string
+	Type      string
+	Source    uint64 // ID of the meta partition the items are moved from
+	Target    uint64 // ID of the meta partition the items are moved to
+	Start     uint64
+	End       uint64
+	Status    string
+	Moved     uint64
+	StartTime int64
+	EndTime   int64
+	Msg       string
+}
+
+// MetaMoveStatus defines the status of the meta move tasks.
+type MetaMoveStatus struct {
+	Tasks       []*MetaMoveTask
+	RecentTasks []*MetaMoveTask
+}
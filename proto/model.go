@@ -40,6 +40,9 @@ type MetaNodeInfo struct {
 	MetaPartitionCount        int
 	NodeSetID                 uint64
 	PersistenceMetaPartitions []uint64
+	Version                   string
+	Upgrading                 bool
+	MaintenanceUntil          int64 // unix time when the maintenance of the node ends, 0 if not in maintenance
 }
 
 // DataNode stores all the information about a data node
@@ -61,6 +64,9 @@ type DataNodeInfo struct {
 	NodeSetID                 uint64
 	PersistenceDataPartitions []uint64
 	BadDisks                  []string
+	Version                   string
+	Upgrading                 bool
+	MaintenanceUntil          int64 // unix time when the maintenance of the node ends, 0 if not in maintenance
 }
 
 // MetaPartition defines the structure of a meta partition
@@ -156,6 +162,8 @@ type VolStatInfo struct {
 	UsedSize    uint64
 	UsedRatio   string
 	EnableToken bool
+	DirQuotaCnt int
+	TrashDays   uint32
 }
 
 // DataPartition represents the structure of storing the file contents.
@@ -47,6 +47,12 @@ const (
 	EnableXattr
 	NearRead
 	EnablePosixACL
+	EnableFileLock
+	Snapshot
+	ReadCacheSize
+	DiskCacheDir
+	DiskCacheSize
+	EnableEncryption
 
 	MaxMountOption
 )
@@ -112,6 +118,12 @@ func InitMountOptions(opts []MountOption) {
 	opts[MaxCPUs] = MountOption{"maxcpus", "The maximum number of CPUs that can be executing", "", int64(-1)}
 	opts[EnableXattr] = MountOption{"enableXattr", "Enable xattr support", "", false}
 	opts[EnablePosixACL] = MountOption{"enablePosixACL", "enable posix ACL support", "", false}
+	opts[EnableFileLock] = MountOption{"enableFileLock", "Enable cluster-wide POSIX and flock file locks", "", false}
+	opts[Snapshot] = MountOption{"snapshot", "Mount the snapshot of the volume read-only", "", ""}
+	opts[ReadCacheSize] = MountOption{"readCacheSize", "Size of the client read cache in MB", "", int64(0)}
+	opts[DiskCacheDir] = MountOption{"diskCacheDir", "Directory of the client disk cache", "", ""}
+	opts[DiskCacheSize] = MountOption{"diskCacheSize", "Size of the client disk cache in MB", "", int64(0)}
+	opts[EnableEncryption] = MountOption{"enableEncryption", "Encrypt the data of the new files, which requires authenticate", "", false}
 
 	for i := 0; i < MaxMountOption; i++ {
 		flag.StringVar(&opts[i].cmdlineValue, opts[i].keyword, "", opts[i].description)
@@ -241,4 +253,10 @@ type MountOptions struct {
 	EnableXattr    bool
 	NearRead       bool
 	EnablePosixACL bool
+	EnableFileLock bool
+	Snapshot       string
+	ReadCacheSize  int64
+	DiskCacheDir   string
+	DiskCacheSize  int64
+	Encryption     bool
 }
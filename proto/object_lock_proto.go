@@ -0,0 +1,96 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package proto
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The extend attributes of an inode protected by the object lock (WORM).
+// An inode is locked while it is under a legal hold or in its retention period,
+// and the meta node refuses to unlink, truncate or append to a locked inode.
+const (
+	RetentionXAttrKey = "cfs.retention"
+	LegalHoldXAttrKey = "cfs.legal-hold"
+)
+
+const (
+	// The retention in governance mode can be changed or removed by the users
+	// with the special permission, which is checked by the object node.
+	RetentionModeGovernance = "GOVERNANCE"
+	// The retention in compliance mode can not be removed or shortened by
+	// anyone until it expires.
+	RetentionModeCompliance = "COMPLIANCE"
+
+	LegalHoldOn  = "ON"
+	LegalHoldOff = "OFF"
+)
+
+// Retention is the retention period of an inode, which is kept in the extend
+// attribute formatted as "MODE:UNIX".
+type Retention struct {
+	Mode        string
+	RetainUntil int64 // unix time in seconds
+}
+
+func ParseRetention(raw string) (*Retention, error) {
+	i := strings.IndexByte(raw, ':')
+	if i < 0 {
+		return nil, fmt.Errorf("invalid retention: %v", raw)
+	}
+	until, err := strconv.ParseInt(raw[i+1:], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid retention: %v", raw)
+	}
+	r := &Retention{Mode: raw[:i], RetainUntil: until}
+	if r.Mode != RetentionModeGovernance && r.Mode != RetentionModeCompliance {
+		return nil, fmt.Errorf("invalid retention mode: %v", r.Mode)
+	}
+	return r, nil
+}
+
+func (r *Retention) String() string {
+	return r.Mode + ":" + strconv.FormatInt(r.RetainUntil, 10)
+}
+
+// Active returns true if the retention period is not expired.
+func (r *Retention) Active(now time.Time) bool {
+	return r != nil && now.Unix() < r.RetainUntil
+}
+
+// Compliance returns true if the retention is in compliance mode and not expired.
+func (r *Retention) Compliance(now time.Time) bool {
+	return r.Active(now) && r.Mode == RetentionModeCompliance
+}
+
+func ValidLegalHold(status string) bool {
+	return status == LegalHoldOn || status == LegalHoldOff
+}
+
+// IsObjectLocked returns true if an inode with the values of the object lock
+// extend attributes is locked.
+func IsObjectLocked(retention, legalHold string, now time.Time) bool {
+	if legalHold == LegalHoldOn {
+		return true
+	}
+	if retention == "" {
+		return false
+	}
+	r, err := ParseRetention(retention)
+	return err == nil && r.Active(now)
+}
@@ -100,6 +100,17 @@ const (
 	OpMetaBatchGetXAttr      uint8 = 0x39
 	OpMetaExtentAddWithCheck uint8 = 0x3A // Append extent key with discard extents check
 
+	// Operations: file locks
+	OpMetaSetLock   uint8 = 0x3B
+	OpMetaGetLock   uint8 = 0x3C
+	OpMetaRenewLock uint8 = 0x3D
+
+	// Operations: read the dentries of the directory in range
+	OpMetaReadDirPlus uint8 = 0x3E
+
+	// Operations: MetaNode Leader -> MetaNode Leader of the partitions of the child inodes
+	OpMetaGetSummaryChanges uint8 = 0x3F
+
 	// Operations: Master -> MetaNode
 	OpCreateMetaPartition           uint8 = 0x40
 	OpMetaNodeHeartbeat             uint8 = 0x41
@@ -110,6 +121,11 @@ const (
 	OpAddMetaPartitionRaftMember    uint8 = 0x46
 	OpRemoveMetaPartitionRaftMember uint8 = 0x47
 	OpMetaPartitionTryToLeader      uint8 = 0x48
+	OpCreateMetaSnapshot            uint8 = 0x49
+	OpDeleteMetaSnapshot            uint8 = 0x4A
+	OpFreezeMetaPartition           uint8 = 0x4B
+	OpMoveMetaItems                 uint8 = 0x4C
+	OpTrimMetaPartition             uint8 = 0x4D
 
 	// Operations: Master -> DataNode
 	OpCreateDataPartition           uint8 = 0x60
@@ -132,13 +148,33 @@ const (
 
 	OpBatchDeleteExtent uint8 = 0x75 // SDK to MetaNode
 
+	// Operations: object versions
+	OpPutObjectVersion    uint8 = 0x76
+	OpDeleteObjectVersion uint8 = 0x77
+	OpGetObjectVersions   uint8 = 0x78
+	OpListObjectVersions  uint8 = 0x79
+
+	// Operations: meta transactions
+	OpMetaTxPrepare   uint8 = 0x50
+	OpMetaTxCommit    uint8 = 0x51
+	OpMetaTxAbort     uint8 = 0x52
+	OpMetaTxGetStatus uint8 = 0x53
+
 	//Operations: MetaNode Leader -> MetaNode Follower
 	OpMetaBatchDeleteInode  uint8 = 0x90
 	OpMetaBatchDeleteDentry uint8 = 0x91
 	OpMetaBatchUnlinkInode  uint8 = 0x92
 	OpMetaBatchEvictInode   uint8 = 0x93
 
+	//Operations: MetaNode Leader -> MetaNode Leader of another partition
+	OpMetaApplyMovedItems uint8 = 0x94
+
 	// Commons
+	OpInodeOutOfRange    uint8 = 0xEC
+	OpCopyOnWriteErr     uint8 = 0xED
+	OpQuotaExceededErr   uint8 = 0xEE
+	OpTxAbortedErr       uint8 = 0xEF
+	OpLockConflictErr    uint8 = 0xF1
 	OpConflictExtentsErr uint8 = 0xF2
 	OpIntraGroupNetErr   uint8 = 0xF3
 	OpArgMismatchErr     uint8 = 0xF4
@@ -357,6 +393,18 @@ func (p *Packet) GetOpMsg() (m string) {
 		m = "OpRemoveMetaPartitionRaftMember"
 	case OpMetaPartitionTryToLeader:
 		m = "OpMetaPartitionTryToLeader"
+	case OpCreateMetaSnapshot:
+		m = "OpCreateMetaSnapshot"
+	case OpDeleteMetaSnapshot:
+		m = "OpDeleteMetaSnapshot"
+	case OpFreezeMetaPartition:
+		m = "OpFreezeMetaPartition"
+	case OpMoveMetaItems:
+		m = "OpMoveMetaItems"
+	case OpTrimMetaPartition:
+		m = "OpTrimMetaPartition"
+	case OpMetaApplyMovedItems:
+		m = "OpMetaApplyMovedItems"
 	case OpDataPartitionTryToLeader:
 		m = "OpDataPartitionTryToLeader"
 	case OpMetaDeleteInode:
@@ -375,6 +423,16 @@ func (p *Packet) GetOpMsg() (m string) {
 		m = "OpMetaListXAttr"
 	case OpMetaBatchGetXAttr:
 		m = "OpMetaBatchGetXAttr"
+	case OpMetaSetLock:
+		m = "OpMetaSetLock"
+	case OpMetaGetLock:
+		m = "OpMetaGetLock"
+	case OpMetaRenewLock:
+		m = "OpMetaRenewLock"
+	case OpMetaReadDirPlus:
+		m = "OpMetaReadDirPlus"
+	case OpMetaGetSummaryChanges:
+		m = "OpMetaGetSummaryChanges"
 	case OpCreateMultipart:
 		m = "OpCreateMultipart"
 	case OpGetMultipart:
@@ -387,6 +445,22 @@ func (p *Packet) GetOpMsg() (m string) {
 		m = "OpListMultiparts"
 	case OpBatchDeleteExtent:
 		m = "OpBatchDeleteExtent"
+	case OpPutObjectVersion:
+		m = "OpPutObjectVersion"
+	case OpDeleteObjectVersion:
+		m = "OpDeleteObjectVersion"
+	case OpGetObjectVersions:
+		m = "OpGetObjectVersions"
+	case OpListObjectVersions:
+		m = "OpListObjectVersions"
+	case OpMetaTxPrepare:
+		m = "OpMetaTxPrepare"
+	case OpMetaTxCommit:
+		m = "OpMetaTxCommit"
+	case OpMetaTxAbort:
+		m = "OpMetaTxAbort"
+	case OpMetaTxGetStatus:
+		m = "OpMetaTxGetStatus"
 	}
 	return
 }
@@ -398,6 +472,16 @@ func (p *Packet) GetResultMsg() (m string) {
 	}
 
 	switch p.ResultCode {
+	case OpInodeOutOfRange:
+		m = "InodeOutOfRange: " + string(p.Data)
+	case OpCopyOnWriteErr:
+		m = "CopyOnWriteErr"
+	case OpQuotaExceededErr:
+		m = "QuotaExceededErr"
+	case OpTxAbortedErr:
+		m = "TxAbortedErr"
+	case OpLockConflictErr:
+		m = "LockConflictErr"
 	case OpConflictExtentsErr:
 		m = "ConflictExtentsErr"
 	case OpIntraGroupNetErr:
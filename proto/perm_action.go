@@ -56,6 +56,7 @@ const (
 	OSSDeleteObjectAction  Action = OSSActionPrefix + "DeleteObject"
 	OSSDeleteObjectsAction Action = OSSActionPrefix + "DeleteObjects"
 	OSSHeadObjectAction    Action = OSSActionPrefix + "HeadObject"
+	OSSPostObjectAction    Action = OSSActionPrefix + "PostObject"
 
 	// Bucket actions
 	OSSCreateBucketAction Action = OSSActionPrefix + "CreateBucket"
@@ -145,6 +146,9 @@ const (
 	// Object restore actions
 	OSSRestoreObjectAction Action = OSSActionPrefix + "RestoreObject" // unsupported
 
+	// Object select actions
+	OSSSelectObjectContentAction Action = OSSActionPrefix + "SelectObjectContent"
+
 	// Public access block actions
 	OSSGetPublicAccessBlockAction    Action = OSSActionPrefix + "GetPublicAccessBlock"   // unsupported
 	OSSPutPublicAccessBlockAction    Action = OSSActionPrefix + "PutPublicAccessBlock"   // unsupported
@@ -159,6 +163,10 @@ const (
 	OSSPutBucketReplicationAction    Action = OSSActionPrefix + "PutBucketReplicationAction"    // unsupported
 	OSSDeleteBucketReplicationAction Action = OSSActionPrefix + "DeleteBucketReplicationAction" // unsupported
 
+	// Bucket notification actions
+	OSSGetBucketNotificationAction Action = OSSActionPrefix + "GetBucketNotification"
+	OSSPutBucketNotificationAction Action = OSSActionPrefix + "PutBucketNotification"
+
 	// constants for POSIX file system interface
 	POSIXReadAction  Action = POSIXActionPrefix + "Read"
 	POSIXWriteAction Action = POSIXActionPrefix + "Write"
@@ -176,6 +184,7 @@ var (
 		OSSDeleteObjectAction,
 		OSSDeleteObjectsAction,
 		OSSHeadObjectAction,
+		OSSPostObjectAction,
 		OSSCreateBucketAction,
 		OSSDeleteBucketAction,
 		OSSHeadBucketAction,
@@ -227,6 +236,7 @@ var (
 		OSSPutBucketWebsiteAction,
 		OSSDeleteBucketWebsiteAction,
 		OSSRestoreObjectAction,
+		OSSSelectObjectContentAction,
 		OSSGetPublicAccessBlockAction,
 		OSSPutPublicAccessBlockAction,
 		OSSDeletePublicAccessBlockAction,
@@ -235,6 +245,8 @@ var (
 		OSSGetBucketReplicationAction,
 		OSSPutBucketReplicationAction,
 		OSSDeleteBucketReplicationAction,
+		OSSGetBucketNotificationAction,
+		OSSPutBucketNotificationAction,
 		OSSOptionsObjectAction,
 
 		// POSIX file system interface actions
@@ -399,6 +411,7 @@ var (
 			OSSGetObjectLegalHoldAction,
 			OSSGetObjectRetentionAction,
 			OSSGetBucketEncryptionAction,
+			OSSSelectObjectContentAction,
 
 			// file system interface
 			POSIXReadAction,
@@ -433,6 +446,7 @@ var (
 			OSSGetObjectRetentionAction,
 			OSSPutObjectRetentionAction,
 			OSSGetBucketEncryptionAction,
+			OSSSelectObjectContentAction,
 
 			// POSIX file system interface actions
 			POSIXReadAction,
@@ -0,0 +1,127 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package proto
+
+import (
+	"strconv"
+	"strings"
+)
+
+// QuotaXAttrKey is the extend attribute of an inode which holds the IDs of the
+// directory quotas it belongs to.
+const QuotaXAttrKey = "cfs.quota"
+
+type QuotaType uint8
+
+const (
+	QuotaTypeInvalid QuotaType = 0x0
+	QuotaTypeDir     QuotaType = 0x1
+	QuotaTypeUid     QuotaType = 0x2
+	QuotaTypeGid     QuotaType = 0x3
+)
+
+func ParseQuotaType(s string) QuotaType {
+	switch s {
+	case "dir":
+		return QuotaTypeDir
+	case "uid":
+		return QuotaTypeUid
+	case "gid":
+		return QuotaTypeGid
+	default:
+	}
+	return QuotaTypeInvalid
+}
+
+func (t QuotaType) Valid() bool {
+	switch t {
+	case QuotaTypeDir,
+		QuotaTypeUid,
+		QuotaTypeGid:
+		return true
+	default:
+	}
+	return false
+}
+
+func (t QuotaType) String() string {
+	switch t {
+	case QuotaTypeDir:
+		return "dir"
+	case QuotaTypeUid:
+		return "uid"
+	case QuotaTypeGid:
+		return "gid"
+	default:
+	}
+	return "invalid"
+}
+
+// QuotaInfo defines the quota of a directory, a user or a group of a volume.
+// A zero limit means unlimited.
+type QuotaInfo struct {
+	QuotaID    uint64
+	Type       QuotaType
+	Target     uint64 // root inode of the directory, uid or gid
+	MaxBytes   uint64
+	MaxInodes  uint64
+	UsedBytes  uint64
+	UsedInodes uint64
+	CreateTime int64
+}
+
+func (q *QuotaInfo) BytesExceeded() bool {
+	return q.MaxBytes > 0 && q.UsedBytes >= q.MaxBytes
+}
+
+func (q *QuotaInfo) InodesExceeded() bool {
+	return q.MaxInodes > 0 && q.UsedInodes >= q.MaxInodes
+}
+
+// QuotaHeartBeatInfo defines the quota sent to the meta nodes with the heartbeat.
+type QuotaHeartBeatInfo struct {
+	VolName        string
+	QuotaID        uint64
+	Type           QuotaType
+	Target         uint64
+	BytesExceeded  bool
+	InodesExceeded bool
+}
+
+// QuotaUsage defines the usage of a quota in a meta partition.
+type QuotaUsage struct {
+	QuotaID    uint64
+	UsedBytes  uint64
+	UsedInodes uint64
+}
+
+// FormatQuotaIds encodes the quota IDs to the value of QuotaXAttrKey.
+func FormatQuotaIds(ids []uint64) string {
+	strs := make([]string, 0, len(ids))
+	for _, id := range ids {
+		strs = append(strs, strconv.FormatUint(id, 10))
+	}
+	return strings.Join(strs, ",")
+}
+
+// ParseQuotaIds decodes the value of QuotaXAttrKey, the invalid IDs are ignored.
+func ParseQuotaIds(value string) (ids []uint64) {
+	for _, str := range strings.Split(value, ",") {
+		if id, err := strconv.ParseUint(str, 10, 64); err == nil && id != 0 {
+			ids = append(ids, id)
+		}
+	}
+	return
+}
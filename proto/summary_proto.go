@@ -0,0 +1,126 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package proto
+
+import (
+	"encoding/json"
+	"strconv"
+)
+
+// SummaryXAttrKey is the extend attribute of a directory inode which holds
+// the summary maintained by the meta node.
+const SummaryXAttrKey = "cfs.summary"
+
+// The virtual extend attributes of a directory, which are answered from the
+// summary of the directory.
+const (
+	SummaryXAttrFiles    = "cfs.dir.files"
+	SummaryXAttrSubdirs  = "cfs.dir.subdirs"
+	SummaryXAttrBytes    = "cfs.dir.bytes"
+	SummaryXAttrRFiles   = "cfs.dir.rfiles"
+	SummaryXAttrRSubdirs = "cfs.dir.rsubdirs"
+	SummaryXAttrRBytes   = "cfs.dir.rbytes"
+)
+
+var SummaryXAttrs = []string{
+	SummaryXAttrFiles,
+	SummaryXAttrSubdirs,
+	SummaryXAttrBytes,
+	SummaryXAttrRFiles,
+	SummaryXAttrRSubdirs,
+	SummaryXAttrRBytes,
+}
+
+// DirSummary defines the summary of a directory. Files, Subdirs and Bytes
+// count the direct children of the directory, and the recursive ones count
+// the whole directory tree.
+type DirSummary struct {
+	Files    uint64 `json:"files"`
+	Subdirs  uint64 `json:"subdirs"`
+	Bytes    uint64 `json:"bytes"`
+	RFiles   uint64 `json:"rfiles"`
+	RSubdirs uint64 `json:"rsubdirs"`
+	RBytes   uint64 `json:"rbytes"`
+}
+
+// ParseDirSummary decodes the value of SummaryXAttrKey.
+func ParseDirSummary(value []byte) (*DirSummary, error) {
+	s := &DirSummary{}
+	if err := json.Unmarshal(value, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Marshal encodes the summary to the value of SummaryXAttrKey.
+func (s *DirSummary) Marshal() []byte {
+	data, _ := json.Marshal(s)
+	return data
+}
+
+// XAttr returns the value of the virtual extend attribute.
+func (s *DirSummary) XAttr(name string) (value string, ok bool) {
+	var n uint64
+	switch name {
+	case SummaryXAttrFiles:
+		n = s.Files
+	case SummaryXAttrSubdirs:
+		n = s.Subdirs
+	case SummaryXAttrBytes:
+		n = s.Bytes
+	case SummaryXAttrRFiles:
+		n = s.RFiles
+	case SummaryXAttrRSubdirs:
+		n = s.RSubdirs
+	case SummaryXAttrRBytes:
+		n = s.RBytes
+	default:
+		return "", false
+	}
+	return strconv.FormatUint(n, 10), true
+}
+
+// IsSummaryXAttr returns true if the extend attribute is maintained by the
+// meta node rather than the users.
+func IsSummaryXAttr(name string) bool {
+	if name == SummaryXAttrKey {
+		return true
+	}
+	for _, key := range SummaryXAttrs {
+		if name == key {
+			return true
+		}
+	}
+	return false
+}
+
+// GetSummaryChangesRequest gets the inodes of the partition of which the size
+// or the summary changed after the sequence of the change log.
+type GetSummaryChangesRequest struct {
+	VolName     string `json:"vol"`
+	PartitionID uint64 `json:"pid"`
+	Epoch       uint64 `json:"epoch"`
+	Seq         uint64 `json:"seq"`
+}
+
+// GetSummaryChangesResponse returns the changed inodes and the current
+// sequence of the change log. If Reset is true, the changes after the
+// requested sequence are unknown, and all inodes should be taken as changed.
+type GetSummaryChangesResponse struct {
+	Epoch  uint64   `json:"epoch"`
+	Seq    uint64   `json:"seq"`
+	Reset  bool     `json:"reset"`
+	Inodes []uint64 `json:"inos"`
+}
@@ -0,0 +1,68 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package proto
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// TrashDirName is the hidden directory under the root of the volume which
+// holds the deleted files if the trash of the volume is enabled.
+const TrashDirName = ".cfs_trash"
+
+// TrashXAttrKey is the extend attribute of a deleted file which holds the
+// TrashInfo of it.
+const TrashXAttrKey = "cfs.trash"
+
+// TrashInfo defines a deleted file in the trash.
+// The Path is empty if the path of the file is unknown when deleted, and the
+// file is restored to the original parent then.
+type TrashInfo struct {
+	Inode      uint64
+	ParentIno  uint64
+	Name       string
+	Path       string `json:",omitempty"`
+	DeleteTime int64
+	Size       uint64 `json:",omitempty"`
+}
+
+// TrashEntryName returns the name of the deleted file in the trash directory.
+func TrashEntryName(ino uint64, deleteTime int64) string {
+	return fmt.Sprintf("%v_%v", ino, deleteTime)
+}
+
+// ParseTrashEntryName decodes the name of the deleted file in the trash
+// directory.
+func ParseTrashEntryName(name string) (ino uint64, deleteTime int64, ok bool) {
+	parts := strings.Split(name, "_")
+	if len(parts) != 2 {
+		return
+	}
+	var err error
+	if ino, err = strconv.ParseUint(parts[0], 10, 64); err != nil {
+		return
+	}
+	if deleteTime, err = strconv.ParseInt(parts[1], 10, 64); err != nil {
+		return
+	}
+	return ino, deleteTime, true
+}
+
+// IsTrashDentry returns true if the dentry is the trash directory of the volume.
+func IsTrashDentry(parentID uint64, name string) bool {
+	return parentID == RootIno && name == TrashDirName
+}
@@ -0,0 +1,61 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package proto
+
+// Roles of the nodes to upgrade
+const (
+	UpgradeDataNode = "datanode"
+	UpgradeMetaNode = "metanode"
+)
+
+// Status of upgrading a node
+const (
+	UpgradeNodePending      = "pending"
+	UpgradeNodeTransferring = "transferring" // transferring the raft leaders to the other replicas
+	UpgradeNodeWaiting      = "waiting"      // waiting for the node to be restarted with the version
+	UpgradeNodeFinished     = "finished"
+	UpgradeNodeSkipped      = "skipped" // the node has been running the version
+	UpgradeNodeFailed       = "failed"
+	UpgradeNodeCanceled     = "canceled"
+)
+
+// UpgradeConfig defines the parameters of the rolling upgrade.
+type UpgradeConfig struct {
+	Role       string   // role of the nodes to upgrade, datanode or metanode
+	Version    string   // version reported by the nodes after they are upgraded
+	Nodes      []string // addresses of the nodes to upgrade in order, all the nodes of the role if empty
+	TimeoutSec int64    // max time to wait for a node to come back with the version
+}
+
+// UpgradeNodeTask defines the upgrade of a node.
+type UpgradeNodeTask struct {
+	Addr       string
+	OldVersion string
+	Status     string
+	Leaders    int // raft leaders left on the node after transferring
+	StartTime  int64
+	EndTime    int64
+	Msg        string
+}
+
+// UpgradeStatus defines the status of the rolling upgrade.
+type UpgradeStatus struct {
+	Running   bool
+	Config    UpgradeConfig
+	StartTime int64
+	EndTime   int64
+	Current   string // address of the node being upgraded
+	Nodes     []*UpgradeNodeTask
+}
@@ -0,0 +1,83 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package proto
+
+// NullVersionID is the version ID of the object written while the
+// versioning of the bucket is not enabled.
+const NullVersionID = "null"
+
+// ObjectVersionInfo defines a version of the object, which is kept by the meta
+// partition of the parent directory of the object. A delete marker has no
+// inode.
+type ObjectVersionInfo struct {
+	ParentID     uint64 `json:"pino"`
+	Name         string `json:"name"`
+	VersionID    string `json:"vid"`
+	Inode        uint64 `json:"ino"`
+	ModifyTime   int64  `json:"mt"` // unix nano
+	DeleteMarker bool   `json:"dm"`
+}
+
+type PutObjectVersionRequest struct {
+	VolName     string             `json:"vol"`
+	PartitionId uint64             `json:"pid"`
+	Version     *ObjectVersionInfo `json:"ver"`
+}
+
+// PutObjectVersionResponse returns the version replaced by the put one of
+// the same version ID, which is the null version.
+type PutObjectVersionResponse struct {
+	Replaced *ObjectVersionInfo `json:"rep"`
+}
+
+type DeleteObjectVersionRequest struct {
+	VolName     string `json:"vol"`
+	PartitionId uint64 `json:"pid"`
+	ParentID    uint64 `json:"pino"`
+	Name        string `json:"name"`
+	VersionID   string `json:"vid"`
+}
+
+type DeleteObjectVersionResponse struct {
+	Deleted *ObjectVersionInfo `json:"del"`
+}
+
+// GetObjectVersionsRequest returns the versions of the object, from the
+// newest to the oldest.
+type GetObjectVersionsRequest struct {
+	VolName     string `json:"vol"`
+	PartitionId uint64 `json:"pid"`
+	ParentID    uint64 `json:"pino"`
+	Name        string `json:"name"`
+}
+
+type GetObjectVersionsResponse struct {
+	Versions []*ObjectVersionInfo `json:"vers"`
+}
+
+// ListObjectVersionsRequest returns the versions of the objects in the
+// directory whose names are not less than the marker, ordered by the name
+// and then from the newest to the oldest.
+type ListObjectVersionsRequest struct {
+	VolName     string `json:"vol"`
+	PartitionId uint64 `json:"pid"`
+	ParentID    uint64 `json:"pino"`
+	Marker      string `json:"mk"`
+	Max         uint64 `json:"max"`
+}
+
+type ListObjectVersionsResponse struct {
+	Versions []*ObjectVersionInfo `json:"vers"`
+}
@@ -88,6 +88,8 @@ func (p *FollowerPacket) identificationErrorResultCode(errLog string, errMsg str
 		p.ResultCode = proto.OpAgain
 	} else if strings.Contains(errMsg, raft.ErrNotLeader.Error()) {
 		p.ResultCode = proto.OpTryOtherAddr
+	} else if strings.Contains(errMsg, storage.CopyOnWriteError.Error()) {
+		p.ResultCode = proto.OpCopyOnWriteErr
 	} else {
 		p.ResultCode = proto.OpIntraGroupNetErr
 	}
@@ -304,6 +306,8 @@ func (p *Packet) identificationErrorResultCode(errLog string, errMsg string) {
 		p.ResultCode = proto.OpAgain
 	} else if strings.Contains(errMsg, raft.ErrNotLeader.Error()) {
 		p.ResultCode = proto.OpTryOtherAddr
+	} else if strings.Contains(errMsg, storage.CopyOnWriteError.Error()) {
+		p.ResultCode = proto.OpCopyOnWriteErr
 	} else {
 		p.ResultCode = proto.OpIntraGroupNetErr
 	}
@@ -0,0 +1,43 @@
+package auth
+
+import (
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+// GenDataKey returns a new data key of the volume, together with the data key
+// encrypted by the master key of the volume.
+func (api *API) GenDataKey(clientID, clientKey, volName string) (plainKey []byte, cipherKey string, err error) {
+	var resp *proto.AuthDataKeyResp
+	if resp, err = api.dataKeyRequest(clientID, clientKey, volName, "", proto.MsgAuthGenDataKeyReq); err != nil {
+		return
+	}
+	return resp.PlainKey, resp.CipherKey, nil
+}
+
+// DecryptDataKey returns the data key decrypted by the master key of the volume.
+func (api *API) DecryptDataKey(clientID, clientKey, volName, cipherKey string) (plainKey []byte, err error) {
+	var resp *proto.AuthDataKeyResp
+	if resp, err = api.dataKeyRequest(clientID, clientKey, volName, cipherKey, proto.MsgAuthDecryptDataKeyReq); err != nil {
+		return
+	}
+	return resp.PlainKey, nil
+}
+
+// dataKeyRequest serves the request by the ticket of the auth service, which
+// is renewed once if the request fails, e.g. the ticket is expired.
+func (api *API) dataKeyRequest(clientID, clientKey, volName, cipherKey string, reqType proto.MsgType) (resp *proto.AuthDataKeyResp, err error) {
+	api.ac.Lock()
+	defer api.ac.Unlock()
+	for retry := 0; retry < 2; retry++ {
+		if api.ac.ticket == nil {
+			if api.ac.ticket, err = api.GetTicket(clientID, clientKey, proto.AuthServiceID); err != nil {
+				return
+			}
+		}
+		if resp, err = api.ac.serveDataKeyRequest(clientID, clientKey, api.ac.ticket, volName, cipherKey, reqType); err == nil {
+			return
+		}
+		api.ac.ticket = nil
+	}
+	return
+}
@@ -181,6 +181,42 @@ func (c *AuthClient) serveAdminRequest(id, key string, ticket *auth.Ticket, keyI
 	return &resp.KeyInfo, err
 }
 
+func (c *AuthClient) serveDataKeyRequest(id, key string, ticket *auth.Ticket, volName, cipherKey string, reqType proto.MsgType) (resp *proto.AuthDataKeyResp, err error) {
+	var (
+		sessionKey []byte
+		ts         int64
+		respData   []byte
+	)
+	apiReq := &proto.APIAccessReq{
+		Type:      reqType,
+		ClientID:  id,
+		ServiceID: proto.AuthServiceID,
+		Ticket:    ticket.Ticket,
+	}
+	if sessionKey, err = cryptoutil.Base64Decode(ticket.SessionKey); err != nil {
+		return nil, err
+	}
+	if apiReq.Verifier, ts, err = cryptoutil.GenVerifier(sessionKey); err != nil {
+		return nil, err
+	}
+	message := &proto.AuthDataKeyReq{
+		APIReq:    *apiReq,
+		VolName:   volName,
+		CipherKey: cipherKey,
+	}
+	if respData, err = c.request(id, key, sessionKey, message, proto.ClientDataKey, proto.AuthServiceID); err != nil {
+		return
+	}
+	resp = new(proto.AuthDataKeyResp)
+	if err = json.Unmarshal(respData, resp); err != nil {
+		return nil, err
+	}
+	if err = proto.VerifyAPIRespComm(&resp.APIResp, reqType, id, proto.AuthServiceID, ts); err != nil {
+		return nil, err
+	}
+	return
+}
+
 func loadCertfile(path string) (caCert []byte, err error) {
 	caCert, err = ioutil.ReadFile(path)
 	if err != nil {
@@ -0,0 +1,486 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stream
+
+import (
+	"container/list"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+const (
+	DiskCacheBlockSize   = util.BlockSize
+	DefaultDiskCacheSize = 10 * util.GB
+
+	diskCacheHeaderSize = 4
+	diskCacheTmpSuffix  = ".tmp"
+)
+
+// The file data is cached in the local directory by blocks of the extents.
+// A block is identified by the partition, the extent and the range in the
+// extent, together with the CRC of the extent key it is read through, so
+// that the blocks of the replaced extent keys are never hit again. The CRC
+// of an extent key is cleared by the client overwriting the extent in place,
+// and the extent keys without the CRC are never cached, so the overwrites of
+// the other clients are perceived once the extent keys are refreshed. The
+// file of a block is named <dir>/<partition>/<extent>_<offset>_<size>_<ekcrc>,
+// whose content is the CRC of the data followed by the data, which is
+// verified every time it is loaded. Once all the blocks of an extent key are
+// cached, the CRCs of them are combined and checked against the CRC of the
+// extent key. The modification time of the files keeps the LRU order across
+// remounts.
+type diskCacheKey struct {
+	partitionID uint64
+	extentID    uint64
+	offset      uint64
+	size        uint32
+	crc         uint32
+}
+
+func (k diskCacheKey) dir(root string) string {
+	return path.Join(root, strconv.FormatUint(k.partitionID, 10))
+}
+
+func (k diskCacheKey) name() string {
+	return fmt.Sprintf("%v_%v_%v_%v", k.extentID, k.offset, k.size, k.crc)
+}
+
+// diskCacheBlockKey returns the key of the block containing the offset in
+// the extent.
+func diskCacheBlockKey(ek *proto.ExtentKey, offset uint64) diskCacheKey {
+	ekEnd := ek.ExtentOffset + uint64(ek.Size)
+	blockStart := offset / DiskCacheBlockSize * DiskCacheBlockSize
+	if blockStart < ek.ExtentOffset {
+		blockStart = ek.ExtentOffset
+	}
+	blockEnd := (offset/DiskCacheBlockSize + 1) * DiskCacheBlockSize
+	if blockEnd > ekEnd {
+		blockEnd = ekEnd
+	}
+	return diskCacheKey{
+		partitionID: ek.PartitionId,
+		extentID:    ek.ExtentId,
+		offset:      blockStart,
+		size:        uint32(blockEnd - blockStart),
+		crc:         ek.CRC,
+	}
+}
+
+func parseDiskCacheKey(partitionID uint64, name string) (k diskCacheKey, err error) {
+	k.partitionID = partitionID
+	_, err = fmt.Sscanf(name, "%d_%d_%d_%d", &k.extentID, &k.offset, &k.size, &k.crc)
+	return
+}
+
+type diskCacheExtent struct {
+	partitionID uint64
+	extentID    uint64
+}
+
+// DiskCacheStats defines the statistics of the disk cache.
+type DiskCacheStats struct {
+	Dir       string
+	Capacity  int64
+	Used      int64
+	Blocks    int
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+	Corrupted uint64
+}
+
+// DiskCache defines the LRU cache of the file data in a local directory.
+type DiskCache struct {
+	hits      uint64
+	misses    uint64
+	evictions uint64
+	corrupted uint64
+
+	sync.Mutex
+	dir      string
+	capacity int64
+	used     int64
+	lru      *list.List
+	blocks   map[diskCacheKey]*list.Element
+	extents  map[diskCacheExtent]map[diskCacheKey]struct{}
+}
+
+// NewDiskCache returns a new disk cache of the directory, and loads the
+// blocks cached by the previous mount.
+func NewDiskCache(dir string, capacity int64) (c *DiskCache, err error) {
+	if capacity <= 0 {
+		capacity = DefaultDiskCacheSize
+	}
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return
+	}
+	c = &DiskCache{
+		dir:      dir,
+		capacity: capacity,
+		lru:      list.New(),
+		blocks:   make(map[diskCacheKey]*list.Element),
+		extents:  make(map[diskCacheExtent]map[diskCacheKey]struct{}),
+	}
+	if err = c.load(); err != nil {
+		return nil, err
+	}
+	log.LogInfof("NewDiskCache: dir(%v) capacity(%v) used(%v) blocks(%v)", dir, capacity, c.used, c.lru.Len())
+	return
+}
+
+func (c *DiskCache) load() (err error) {
+	type cachedFile struct {
+		key   diskCacheKey
+		mtime time.Time
+	}
+	var files []cachedFile
+	dirs, err := ioutil.ReadDir(c.dir)
+	if err != nil {
+		return
+	}
+	for _, d := range dirs {
+		partitionID, e := strconv.ParseUint(d.Name(), 10, 64)
+		if !d.IsDir() || e != nil {
+			continue
+		}
+		infos, e := ioutil.ReadDir(path.Join(c.dir, d.Name()))
+		if e != nil {
+			return e
+		}
+		for _, info := range infos {
+			key, e := parseDiskCacheKey(partitionID, info.Name())
+			if e != nil || info.Size() != int64(key.size)+diskCacheHeaderSize {
+				// unfinished or unknown files
+				os.Remove(path.Join(c.dir, d.Name(), info.Name()))
+				continue
+			}
+			files = append(files, cachedFile{key: key, mtime: info.ModTime()})
+		}
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].mtime.Before(files[j].mtime) })
+	for _, f := range files {
+		c.insert(f.key)
+	}
+	c.shrink()
+	return
+}
+
+// Get reads the cached block into the data, which is of the size of the block.
+func (c *DiskCache) Get(key diskCacheKey, data []byte) bool {
+	c.Lock()
+	element, ok := c.blocks[key]
+	if ok {
+		c.lru.MoveToFront(element)
+	}
+	c.Unlock()
+	if !ok {
+		atomic.AddUint64(&c.misses, 1)
+		return false
+	}
+
+	filename := path.Join(key.dir(c.dir), key.name())
+	content, err := ioutil.ReadFile(filename)
+	if err != nil || len(content) != len(data)+diskCacheHeaderSize ||
+		binary.BigEndian.Uint32(content) != crc32.ChecksumIEEE(content[diskCacheHeaderSize:]) {
+		log.LogWarnf("DiskCache Get: corrupted block(%v) err(%v)", filename, err)
+		atomic.AddUint64(&c.corrupted, 1)
+		atomic.AddUint64(&c.misses, 1)
+		c.Evict(key)
+		return false
+	}
+	copy(data, content[diskCacheHeaderSize:])
+	now := time.Now()
+	os.Chtimes(filename, now, now)
+	atomic.AddUint64(&c.hits, 1)
+	return true
+}
+
+// Put writes the block to the cache directory, and evicts the least recently
+// used blocks if the cache is full.
+func (c *DiskCache) Put(key diskCacheKey, data []byte) {
+	if int64(len(data)) > c.capacity {
+		return
+	}
+	c.Lock()
+	_, ok := c.blocks[key]
+	c.Unlock()
+	if ok {
+		return
+	}
+
+	dir := key.dir(c.dir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		log.LogWarnf("DiskCache Put: dir(%v) err(%v)", dir, err)
+		return
+	}
+	content := make([]byte, diskCacheHeaderSize+len(data))
+	binary.BigEndian.PutUint32(content, crc32.ChecksumIEEE(data))
+	copy(content[diskCacheHeaderSize:], data)
+	filename := path.Join(dir, key.name())
+	tmpname := filename + diskCacheTmpSuffix
+	if err := ioutil.WriteFile(tmpname, content, 0644); err != nil {
+		log.LogWarnf("DiskCache Put: file(%v) err(%v)", tmpname, err)
+		os.Remove(tmpname)
+		return
+	}
+	if err := os.Rename(tmpname, filename); err != nil {
+		log.LogWarnf("DiskCache Put: file(%v) err(%v)", filename, err)
+		os.Remove(tmpname)
+		return
+	}
+
+	c.Lock()
+	defer c.Unlock()
+	if _, ok = c.blocks[key]; !ok {
+		c.insert(key)
+		c.shrink()
+	}
+}
+
+// Evict removes the block from the cache.
+func (c *DiskCache) Evict(key diskCacheKey) {
+	c.Lock()
+	defer c.Unlock()
+	if element, ok := c.blocks[key]; ok {
+		c.remove(element)
+	}
+}
+
+// EvictExtent removes all the cached blocks of the extent, which is
+// overwritten by the client.
+func (c *DiskCache) EvictExtent(partitionID, extentID uint64) {
+	c.Lock()
+	defer c.Unlock()
+	for key := range c.extents[diskCacheExtent{partitionID, extentID}] {
+		c.remove(c.blocks[key])
+	}
+}
+
+// Verify checks the cached blocks of the extent key against the CRC of it if
+// all of them are cached, and evicts them on mismatch, e.g. the extent is
+// overwritten by a client not clearing the CRC. It returns false only on
+// mismatch.
+func (c *DiskCache) Verify(ek *proto.ExtentKey) bool {
+	var keys []diskCacheKey
+	c.Lock()
+	for offset := ek.ExtentOffset; offset < ek.ExtentOffset+uint64(ek.Size); {
+		key := diskCacheBlockKey(ek, offset)
+		if _, ok := c.blocks[key]; !ok {
+			c.Unlock()
+			return true
+		}
+		keys = append(keys, key)
+		offset = key.offset + uint64(key.size)
+	}
+	c.Unlock()
+
+	var crc uint32
+	for _, key := range keys {
+		blockCRC, err := c.readBlockCRC(key)
+		if err != nil {
+			// the block is evicted meanwhile
+			return true
+		}
+		crc = crc32Combine(crc, blockCRC, int64(key.size))
+	}
+	if crc == ek.CRC {
+		return true
+	}
+	log.LogWarnf("DiskCache Verify: ek(%v) mismatches the cached blocks, crc(%v)", ek, crc)
+	atomic.AddUint64(&c.corrupted, 1)
+	for _, key := range keys {
+		c.Evict(key)
+	}
+	return false
+}
+
+func (c *DiskCache) readBlockCRC(key diskCacheKey) (crc uint32, err error) {
+	f, err := os.Open(path.Join(key.dir(c.dir), key.name()))
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	header := make([]byte, diskCacheHeaderSize)
+	if _, err = io.ReadFull(f, header); err != nil {
+		return
+	}
+	return binary.BigEndian.Uint32(header), nil
+}
+
+// crc32Combine returns the IEEE CRC of the concatenation of two blocks from
+// the CRCs of them and the length of the second one, as crc32_combine of zlib.
+func crc32Combine(crc1, crc2 uint32, len2 int64) uint32 {
+	if len2 <= 0 {
+		return crc1
+	}
+	even := make([]uint32, 32) // the operator of 2^n zero bits
+	odd := make([]uint32, 32)  // the operator of 2^(n+1) zero bits
+	odd[0] = 0xedb88320
+	row := uint32(1)
+	for n := 1; n < 32; n++ {
+		odd[n] = row
+		row <<= 1
+	}
+	gf2MatrixSquare(even, odd)
+	gf2MatrixSquare(odd, even)
+	for {
+		gf2MatrixSquare(even, odd)
+		if len2&1 != 0 {
+			crc1 = gf2MatrixTimes(even, crc1)
+		}
+		len2 >>= 1
+		if len2 == 0 {
+			break
+		}
+		gf2MatrixSquare(odd, even)
+		if len2&1 != 0 {
+			crc1 = gf2MatrixTimes(odd, crc1)
+		}
+		len2 >>= 1
+		if len2 == 0 {
+			break
+		}
+	}
+	return crc1 ^ crc2
+}
+
+func gf2MatrixTimes(mat []uint32, vec uint32) (sum uint32) {
+	for i := 0; vec != 0; i, vec = i+1, vec>>1 {
+		if vec&1 != 0 {
+			sum ^= mat[i]
+		}
+	}
+	return
+}
+
+func gf2MatrixSquare(square, mat []uint32) {
+	for n := 0; n < 32; n++ {
+		square[n] = gf2MatrixTimes(mat, mat[n])
+	}
+}
+
+// Stats returns the statistics of the cache.
+func (c *DiskCache) Stats() *DiskCacheStats {
+	c.Lock()
+	defer c.Unlock()
+	return &DiskCacheStats{
+		Dir:       c.dir,
+		Capacity:  c.capacity,
+		Used:      c.used,
+		Blocks:    c.lru.Len(),
+		Hits:      atomic.LoadUint64(&c.hits),
+		Misses:    atomic.LoadUint64(&c.misses),
+		Evictions: atomic.LoadUint64(&c.evictions),
+		Corrupted: atomic.LoadUint64(&c.corrupted),
+	}
+}
+
+func (c *DiskCache) insert(key diskCacheKey) {
+	c.blocks[key] = c.lru.PushFront(key)
+	extent := diskCacheExtent{key.partitionID, key.extentID}
+	keys, ok := c.extents[extent]
+	if !ok {
+		keys = make(map[diskCacheKey]struct{})
+		c.extents[extent] = keys
+	}
+	keys[key] = struct{}{}
+	c.used += int64(key.size) + diskCacheHeaderSize
+}
+
+func (c *DiskCache) shrink() {
+	for c.used > c.capacity && c.lru.Len() > 0 {
+		c.remove(c.lru.Back())
+		atomic.AddUint64(&c.evictions, 1)
+	}
+}
+
+func (c *DiskCache) remove(element *list.Element) {
+	key := element.Value.(diskCacheKey)
+	c.lru.Remove(element)
+	delete(c.blocks, key)
+	extent := diskCacheExtent{key.partitionID, key.extentID}
+	if keys, ok := c.extents[extent]; ok {
+		delete(keys, key)
+		if len(keys) == 0 {
+			delete(c.extents, extent)
+		}
+	}
+	c.used -= int64(key.size) + diskCacheHeaderSize
+	filename := path.Join(key.dir(c.dir), key.name())
+	if err := os.Remove(filename); err != nil && !os.IsNotExist(err) {
+		log.LogWarnf("DiskCache remove: file(%v) err(%v)", filename, err)
+	}
+}
+
+// readCached reads the request by the blocks of the extent, which are loaded
+// from the disk cache if cached, otherwise read from the data nodes and
+// cached. The extent key without the CRC is read from the data nodes
+// directly.
+func (reader *ExtentReader) readCached(req *ExtentRequest) (readBytes int, err error) {
+	var (
+		cache  = reader.diskCache
+		ek     = reader.key
+		offset = uint64(req.FileOffset) - ek.FileOffset + ek.ExtentOffset
+		block  = make([]byte, DiskCacheBlockSize)
+	)
+	if ek.CRC == 0 {
+		return reader.read(req)
+	}
+	for readBytes < req.Size {
+		key := diskCacheBlockKey(ek, offset)
+		data := block[:key.size]
+		if !cache.Get(key, data) {
+			blockReq := NewExtentRequest(int(key.offset-ek.ExtentOffset+ek.FileOffset), len(data), data, ek)
+			var n int
+			if n, err = reader.read(blockReq); err != nil {
+				return
+			}
+			if n < len(data) {
+				err = fmt.Errorf("readCached: short read, ino(%v) ek(%v) offset(%v) size(%v) read(%v)",
+					reader.inode, ek, key.offset, len(data), n)
+				return
+			}
+			cache.Put(key, data)
+			cache.Verify(ek)
+		}
+		n := copy(req.Data[readBytes:req.Size], data[offset-key.offset:])
+		readBytes += n
+		offset += uint64(n)
+	}
+	return
+}
+
+// DiskCacheStats returns the statistics of the disk cache, or nil if the
+// disk cache is not enabled.
+func (client *ExtentClient) DiskCacheStats() *DiskCacheStats {
+	if client.diskCache == nil {
+		return nil
+	}
+	return client.diskCache.Stats()
+}
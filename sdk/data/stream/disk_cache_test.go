@@ -0,0 +1,160 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stream
+
+import (
+	"bytes"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"testing"
+)
+
+func TestDiskCache_CRC32Combine(t *testing.T) {
+	data := make([]byte, 3*DiskCacheBlockSize+100)
+	rand.Read(data)
+	for _, split := range []int{0, 1, DiskCacheBlockSize, len(data) - 1, len(data)} {
+		crc := crc32Combine(crc32.ChecksumIEEE(data[:split]), crc32.ChecksumIEEE(data[split:]), int64(len(data)-split))
+		if crc != crc32.ChecksumIEEE(data) {
+			t.Fatalf("split(%v) combined crc(%v) expected(%v)", split, crc, crc32.ChecksumIEEE(data))
+		}
+	}
+}
+
+// newDiskCacheClients returns two clients with the disk caches of their own
+// of a file, the extent key of which has the CRC of the data.
+func newDiskCacheClients(t *testing.T, node *mockDataNode, data []byte) (clients [2]*ExtentClient, inode *mockInode, stop func()) {
+	var stops []func()
+	stop = func() {
+		for _, f := range stops {
+			f()
+		}
+	}
+	for i := range clients {
+		dir, err := ioutil.TempDir("", "diskcache")
+		if err != nil {
+			t.Fatal(err)
+		}
+		stops = append(stops, func() { os.RemoveAll(dir) })
+		config := &ExtentConfig{DiskCacheDir: dir, DiskCacheSize: 16}
+		var f func()
+		if i == 0 {
+			clients[i], inode, f = newMockExtentClient(t, config, node, data)
+			inode.eks[0].CRC = crc32.ChecksumIEEE(data)
+		} else {
+			clients[i], f = newMockInodeClient(t, config, node, inode)
+		}
+		stops = append(stops, f)
+		if err = clients[i].OpenStream(1); err != nil {
+			stop()
+			t.Fatal(err)
+		}
+	}
+	return
+}
+
+func readAll(t *testing.T, client *ExtentClient, size int) []byte {
+	buf := make([]byte, size)
+	n, err := client.Read(1, buf, 0, size)
+	if err != nil && err != io.EOF {
+		t.Fatalf("read size(%v) err(%v)", size, err)
+	}
+	return buf[:n]
+}
+
+func TestDiskCache_Overwrite(t *testing.T) {
+	const blocks = 3
+	node := newMockDataNode(t)
+	defer node.listener.Close()
+	data := make([]byte, blocks*DiskCacheBlockSize-100)
+	rand.Read(data)
+	clients, inode, stop := newDiskCacheClients(t, node, data)
+	defer stop()
+	writer, reader := clients[0], clients[1]
+
+	for i := 0; i < 2; i++ {
+		if got := readAll(t, reader, len(data)); !bytes.Equal(got, data) {
+			t.Fatalf("read(%v) mismatch", i)
+		}
+	}
+	if stats := reader.DiskCacheStats(); stats.Blocks != blocks || stats.Hits != blocks || stats.Corrupted != 0 {
+		t.Fatalf("stats(%+v) after reading twice", stats)
+	}
+
+	// the overwrite of the other client clears the CRC of the extent key
+	changed := append([]byte(nil), data...)
+	copy(changed[DiskCacheBlockSize:], []byte("overwrite"))
+	if _, err := writer.Write(1, DiskCacheBlockSize, []byte("overwrite"), 0); err != nil {
+		t.Fatal(err)
+	}
+	_, _, eks, _ := inode.getExtents(1)
+	if len(eks) != 1 || eks[0].CRC != 0 {
+		t.Fatalf("extent keys(%v) after the overwrite", eks)
+	}
+	if err := reader.GetStreamer(1).GetExtents(); err != nil {
+		t.Fatal(err)
+	}
+	if got := readAll(t, reader, len(data)); !bytes.Equal(got, changed) {
+		t.Fatalf("stale data is read after the overwrite")
+	}
+	// the extent key without the CRC is not cached
+	if stats := reader.DiskCacheStats(); stats.Blocks != blocks || stats.Hits != blocks || stats.Misses != blocks {
+		t.Fatalf("stats(%+v) after the overwrite", stats)
+	}
+	if got := readAll(t, writer, len(data)); !bytes.Equal(got, changed) {
+		t.Fatalf("stale data is read by the writer")
+	}
+	if stats := writer.DiskCacheStats(); stats.Blocks != 0 {
+		t.Fatalf("stats(%+v) of the writer", stats)
+	}
+}
+
+func TestDiskCache_Verify(t *testing.T) {
+	const blocks = 3
+	node := newMockDataNode(t)
+	defer node.listener.Close()
+	data := make([]byte, blocks*DiskCacheBlockSize)
+	rand.Read(data)
+	clients, _, stop := newDiskCacheClients(t, node, data)
+	defer stop()
+	client := clients[0]
+
+	// the extent is changed without clearing the CRC of the extent key
+	changed := append([]byte(nil), data...)
+	changed[len(changed)-1]++
+	node.setExtent(1, changed)
+	buf := make([]byte, DiskCacheBlockSize)
+	for offset := 0; offset < len(data); offset += DiskCacheBlockSize {
+		if _, err := client.Read(1, buf, offset, len(buf)); err != nil {
+			t.Fatal(err)
+		}
+		if stats := client.DiskCacheStats(); offset+len(buf) < len(data) && stats.Blocks != offset/DiskCacheBlockSize+1 {
+			t.Fatalf("stats(%+v) at offset(%v)", stats, offset)
+		}
+	}
+	if stats := client.DiskCacheStats(); stats.Blocks != 0 || stats.Corrupted != 1 {
+		t.Fatalf("mismatched blocks are not evicted, stats(%+v)", stats)
+	}
+
+	// the blocks matching the CRC of the extent key are kept
+	node.setExtent(1, data)
+	readAll(t, client, len(data))
+	readAll(t, client, len(data))
+	if stats := client.DiskCacheStats(); stats.Blocks != blocks || stats.Corrupted != 1 || stats.Hits != blocks {
+		t.Fatalf("stats(%+v) of the matched blocks", stats)
+	}
+}
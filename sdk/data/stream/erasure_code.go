@@ -0,0 +1,229 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stream
+
+import (
+	"fmt"
+	"hash/crc32"
+	"sync"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/sdk/data/wrapper"
+	"github.com/chubaofs/chubaofs/util"
+	"github.com/chubaofs/chubaofs/util/ec"
+	"github.com/chubaofs/chubaofs/util/errors"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// The encoders are shared by the partitions of the same shard numbers.
+var encoders sync.Map // (dataNum<<8 | parityNum) -> *ec.Encoder
+
+func getEncoder(dp *wrapper.DataPartition) (*ec.Encoder, error) {
+	key := int(dp.ECDataNum)<<8 | int(dp.ECParityNum)
+	if enc, ok := encoders.Load(key); ok {
+		return enc.(*ec.Encoder), nil
+	}
+	enc, err := ec.New(int(dp.ECDataNum), int(dp.ECParityNum))
+	if err != nil {
+		return nil, err
+	}
+	encoders.Store(key, enc)
+	return enc, nil
+}
+
+// encodeStripe splits the data of a block into the data shards, and computes
+// the parity shards.
+func encodeStripe(dp *wrapper.DataPartition, data []byte) (shards [][]byte, err error) {
+	enc, err := getEncoder(dp)
+	if err != nil {
+		return
+	}
+	shardSize := proto.ECShardSize(dp.ECDataNum)
+	buf := make([]byte, shardSize*(enc.DataShards()+enc.ParityShards()))
+	shards = make([][]byte, enc.DataShards()+enc.ParityShards())
+	for i := range shards {
+		shards[i] = buf[i*shardSize : (i+1)*shardSize]
+	}
+	copy(buf, data)
+	err = enc.Encode(shards)
+	return
+}
+
+// writeECPacket encodes the packet, which is a block of the extent, and
+// writes the shards to the hosts of the erasure-coded partition in parallel.
+// It succeeds only if all of the shards are written, otherwise the packet is
+// recovered to another extent as usual.
+func (eh *ExtentHandler) writeECPacket(packet *Packet) (err error) {
+	dp := eh.dp
+	if packet.ExtentOffset%proto.ECBlockSize != 0 {
+		return errors.New(fmt.Sprintf("writeECPacket: unaligned packet(%v)", packet))
+	}
+	var shards [][]byte
+	if shards, err = encodeStripe(dp, packet.Data[:packet.Size]); err != nil {
+		return
+	}
+	shardOffset := packet.ExtentOffset / proto.ECBlockSize * int64(proto.ECShardSize(dp.ECDataNum))
+
+	var (
+		wg   sync.WaitGroup
+		errs = make([]error, len(dp.Hosts))
+	)
+	for i, host := range dp.Hosts {
+		wg.Add(1)
+		go func(i int, host string) {
+			defer wg.Done()
+			errs[i] = writeShard(host, packet, shards[i], shardOffset)
+		}(i, host)
+	}
+	wg.Wait()
+	for i, e := range errs {
+		if e != nil {
+			return errors.Trace(e, "writeECPacket: shard(%v) host(%v) packet(%v)", i, dp.Hosts[i], packet)
+		}
+	}
+	packet.CRC = crc32.ChecksumIEEE(packet.Data[:packet.Size])
+	return
+}
+
+// writeShard appends the shard to the extent on the host without forwarding.
+func writeShard(host string, packet *Packet, shard []byte, shardOffset int64) (err error) {
+	p := new(Packet)
+	p.ReqID = proto.GenerateRequestID()
+	p.Magic = proto.ProtoMagic
+	p.Opcode = packet.Opcode
+	p.PartitionID = packet.PartitionID
+	p.ExtentType = proto.NormalExtentType
+	p.ExtentID = packet.ExtentID
+	p.ExtentOffset = shardOffset
+	p.RemainingFollowers = 0
+	p.inode = packet.inode
+	p.KernelOffset = packet.KernelOffset
+	p.Data = shard
+	p.Size = uint32(len(shard))
+
+	conn, err := StreamConnPool.GetConnect(host)
+	if err != nil {
+		return
+	}
+	defer func() {
+		StreamConnPool.PutConnect(conn, err != nil)
+	}()
+	if err = p.writeToConn(conn); err != nil {
+		return
+	}
+	reply := NewReply(p.ReqID, p.PartitionID, p.ExtentID)
+	if err = reply.ReadFromConn(conn, proto.ReadDeadlineTime); err != nil {
+		return
+	}
+	if reply.ResultCode != proto.OpOk || !p.isValidWriteReply(reply) || reply.CRC != p.CRC {
+		err = errors.New(fmt.Sprintf("writeShard: reply NOK, req(%v) reply(%v)", p, reply))
+	}
+	return
+}
+
+// readShard reads the range of the shard extent on the host.
+func readShard(host string, dp *wrapper.DataPartition, key *proto.ExtentKey, shardOffset uint64, data []byte) (err error) {
+	p := NewReadPacket(key, int(shardOffset), len(data), 0, 0, true)
+	conn, err := StreamConnPool.GetConnect(host)
+	if err != nil {
+		return
+	}
+	defer func() {
+		StreamConnPool.PutConnect(conn, err != nil)
+	}()
+	if err = p.WriteToConn(conn); err != nil {
+		return
+	}
+	var readBytes int
+	for readBytes < len(data) {
+		reply := NewReply(p.ReqID, dp.PartitionID, p.ExtentID)
+		bufSize := util.Min(util.ReadBlockSize, len(data)-readBytes)
+		reply.Data = data[readBytes : readBytes+bufSize]
+		if err = reply.readFromConn(conn, proto.ReadDeadlineTime); err != nil {
+			return
+		}
+		if reply.ResultCode != proto.OpOk || !p.isValidReadReply(reply) ||
+			reply.CRC != crc32.ChecksumIEEE(reply.Data[:reply.Size]) {
+			return errors.New(fmt.Sprintf("readShard: reply NOK, req(%v) reply(%v)", p, reply))
+		}
+		readBytes += int(reply.Size)
+	}
+	return
+}
+
+// readEC reads the data from the data shards, and reconstructs the stripe from
+// the other shards if a data shard is unavailable.
+func (reader *ExtentReader) readEC(req *ExtentRequest) (readBytes int, err error) {
+	var (
+		dp        = reader.dp
+		offset    = uint64(req.FileOffset) - reader.key.FileOffset + reader.key.ExtentOffset
+		shardSize = uint64(proto.ECShardSize(dp.ECDataNum))
+	)
+	for readBytes < req.Size {
+		shard, shardOffset := proto.ECShardLocation(dp.ECDataNum, offset)
+		inBlock := offset % proto.ECBlockSize
+		size := util.Min(req.Size-readBytes, int(shardSize-shardOffset%shardSize))
+		size = util.Min(size, int(proto.ECBlockSize-inBlock))
+		data := req.Data[readBytes : readBytes+size]
+		if e := readShard(dp.Hosts[shard], dp, reader.key, shardOffset, data); e != nil {
+			log.LogWarnf("readEC: degraded read, ino(%v) dp(%v) shard(%v) host(%v) err(%v)",
+				reader.inode, dp.PartitionID, shard, dp.Hosts[shard], e)
+			if err = reader.readDegraded(offset, data); err != nil {
+				return
+			}
+		}
+		readBytes += size
+		offset += uint64(size)
+	}
+	return
+}
+
+// readDegraded reads the stripe of the offset from all of the hosts, and
+// reconstructs the data from any shards of the number of data shards.
+func (reader *ExtentReader) readDegraded(offset uint64, data []byte) (err error) {
+	dp := reader.dp
+	enc, err := getEncoder(dp)
+	if err != nil {
+		return
+	}
+	var (
+		shardSize   = proto.ECShardSize(dp.ECDataNum)
+		shardOffset = offset / proto.ECBlockSize * uint64(shardSize)
+		shards      = make([][]byte, len(dp.Hosts))
+		wg          sync.WaitGroup
+	)
+	for i, host := range dp.Hosts {
+		wg.Add(1)
+		go func(i int, host string) {
+			defer wg.Done()
+			shard := make([]byte, shardSize)
+			if e := readShard(host, dp, reader.key, shardOffset, shard); e != nil {
+				log.LogWarnf("readDegraded: ino(%v) dp(%v) shard(%v) host(%v) err(%v)", reader.inode, dp.PartitionID, i, host, e)
+				return
+			}
+			shards[i] = shard
+		}(i, host)
+	}
+	wg.Wait()
+	if err = enc.Reconstruct(shards); err != nil {
+		return errors.Trace(err, "readDegraded: ino(%v) dp(%v) offset(%v)", reader.inode, dp.PartitionID, offset)
+	}
+	inBlock := int(offset % proto.ECBlockSize)
+	for copied := 0; copied < len(data); {
+		pos := inBlock + copied
+		copied += copy(data[copied:], shards[pos/shardSize][pos%shardSize:])
+	}
+	return
+}
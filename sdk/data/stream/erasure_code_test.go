@@ -0,0 +1,90 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stream
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/sdk/data/wrapper"
+	"github.com/chubaofs/chubaofs/util"
+)
+
+func TestErasureCode_WriteAndRead(t *testing.T) {
+	const dataNum, parityNum, blocks = 4, 2, 3
+	dp := &wrapper.DataPartition{}
+	dp.PartitionID, dp.ECDataNum, dp.ECParityNum = 1, dataNum, parityNum
+	servers := make([]*mockDataNode, dataNum+parityNum)
+	for i := range servers {
+		servers[i] = newMockDataNode(t)
+		defer servers[i].listener.Close()
+		dp.Hosts = append(dp.Hosts, servers[i].addr())
+	}
+
+	// the last block is partial, and the last data shard of it is padded
+	data := make([]byte, blocks*proto.ECBlockSize-1000)
+	rand.Read(data)
+	eh := &ExtentHandler{dp: dp}
+	for offset := 0; offset < len(data); offset += proto.ECBlockSize {
+		packet := &Packet{}
+		packet.Opcode = proto.OpWrite
+		packet.PartitionID = dp.PartitionID
+		packet.ExtentID = 1
+		packet.ExtentOffset = int64(offset)
+		packet.Data = data[offset:util.Min(len(data), offset+proto.ECBlockSize)]
+		packet.Size = uint32(len(packet.Data))
+		if err := eh.writeECPacket(packet); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i, s := range servers {
+		if size := len(s.extent(1)); size != blocks*proto.ECShardSize(dataNum) {
+			t.Fatalf("shard(%v) size(%v)", i, size)
+		}
+	}
+
+	key := &proto.ExtentKey{PartitionId: dp.PartitionID, ExtentId: 1, Size: uint32(len(data))}
+	reader := NewExtentReader(1, key, dp, true)
+	read := func(offset, size int) ([]byte, error) {
+		req := &ExtentRequest{FileOffset: offset, Size: size, Data: make([]byte, size), ExtentKey: key}
+		_, err := reader.Read(req)
+		return req.Data, err
+	}
+	// the ranges cross the shards and the blocks
+	ranges := [][2]int{{0, len(data)}, {1000, proto.ECShardSize(dataNum)}, {proto.ECBlockSize - 10, 20}, {len(data) - 5000, 5000}}
+	check := func(state string) {
+		for _, r := range ranges {
+			got, err := read(r[0], r[1])
+			if err != nil {
+				t.Fatalf("%v: read offset(%v) size(%v) err(%v)", state, r[0], r[1], err)
+			}
+			if !bytes.Equal(got, data[r[0]:r[0]+r[1]]) {
+				t.Fatalf("%v: read offset(%v) size(%v) mismatch", state, r[0], r[1])
+			}
+		}
+	}
+	check("all shards")
+
+	// the data is reconstructed with any of the number of data shards
+	servers[0].setDown()
+	servers[dataNum-1].setDown()
+	check("degraded")
+	servers[dataNum].setDown()
+	if _, err := read(0, proto.ECBlockSize); err == nil {
+		t.Fatalf("read with less shards than data shards")
+	}
+}
@@ -23,6 +23,7 @@ import (
 
 	"github.com/chubaofs/chubaofs/proto"
 	"github.com/chubaofs/chubaofs/sdk/data/wrapper"
+	"github.com/chubaofs/chubaofs/util"
 	"github.com/chubaofs/chubaofs/util/errors"
 	"github.com/chubaofs/chubaofs/util/exporter"
 	"github.com/chubaofs/chubaofs/util/log"
@@ -83,6 +84,9 @@ type ExtentConfig struct {
 	NearRead          bool
 	ReadRate          int64
 	WriteRate         int64
+	ReadCacheSize     int64 // in MB, disabled if not positive
+	DiskCacheDir      string
+	DiskCacheSize     int64 // in MB
 	OnAppendExtentKey AppendExtentKeyFunc
 	OnGetExtents      GetExtentsFunc
 	OnTruncate        TruncateFunc
@@ -97,6 +101,10 @@ type ExtentClient struct {
 	readLimiter  *rate.Limiter
 	writeLimiter *rate.Limiter
 
+	readCache *ReadCache // may be nil if disabled
+	diskCache *DiskCache // may be nil if disabled
+	fileKeys  sync.Map   // inode -> *FileCipher of the encrypted files
+
 	dataWrapper     *wrapper.Wrapper
 	appendExtentKey AppendExtentKeyFunc
 	getExtents      GetExtentsFunc
@@ -144,6 +152,15 @@ retry:
 	client.readLimiter = rate.NewLimiter(readLimit, defaultReadLimitBurst)
 	client.writeLimiter = rate.NewLimiter(writeLimit, defaultWriteLimitBurst)
 
+	if config.ReadCacheSize > 0 {
+		client.readCache = NewReadCache(config.ReadCacheSize * util.MB)
+	}
+	if config.DiskCacheDir != "" {
+		if client.diskCache, err = NewDiskCache(config.DiskCacheDir, config.DiskCacheSize*util.MB); err != nil {
+			return nil, errors.Trace(err, "Init disk cache failed!")
+		}
+	}
+
 	return
 }
 
@@ -16,6 +16,7 @@ package stream
 
 import (
 	"fmt"
+	"hash/crc32"
 	"net"
 	"sync/atomic"
 	"time"
@@ -87,6 +88,10 @@ type ExtentHandler struct {
 	key   *proto.ExtentKey
 	dirty bool // indicate if open handler is dirty.
 
+	// The nonce of the extent key of an encrypted file, assigned in
+	// NewExtentHandler.
+	nonce uint64
+
 	// Created in receiver ONLY in recovery status.
 	// Will not be changed once assigned.
 	recoverHandler *ExtentHandler
@@ -121,6 +126,9 @@ func NewExtentHandler(stream *Streamer, offset int, storeMode int) *ExtentHandle
 		doneSender:   make(chan struct{}),
 		doneReceiver: make(chan struct{}),
 	}
+	if stream.client.fileCipher(stream.inode) != nil {
+		eh.nonce = newFileNonce()
+	}
 
 	go eh.receiver()
 	go eh.sender()
@@ -238,7 +246,13 @@ func (eh *ExtentHandler) sender() {
 
 			//log.LogDebugf("ExtentHandler sender: extent allocated, eh(%v) dp(%v) extID(%v) packet(%v)", eh, eh.dp, eh.extID, packet.GetUniqueLogId())
 
-			if err = packet.writeToConn(eh.conn); err != nil {
+			eh.encrypt(packet)
+			if eh.dp.IsErasureCoded() {
+				err = eh.writeECPacket(packet)
+			} else {
+				err = packet.writeToConn(eh.conn)
+			}
+			if err != nil {
 				log.LogWarnf("sender writeTo: failed, eh(%v) err(%v) packet(%v)", eh, err, packet)
 				eh.setClosed()
 				eh.setRecovery()
@@ -297,31 +311,36 @@ func (eh *ExtentHandler) processReply(packet *Packet) {
 		return
 	}
 
-	reply := NewReply(packet.ReqID, packet.PartitionID, packet.ExtentID)
-	err := reply.ReadFromConn(eh.conn, proto.ReadDeadlineTime)
-	if err != nil {
-		eh.processReplyError(packet, err.Error())
-		return
-	}
+	// The shards of an erasure-coded partition are written and replied in
+	// the sender.
+	reply := packet
+	if !eh.dp.IsErasureCoded() {
+		reply = NewReply(packet.ReqID, packet.PartitionID, packet.ExtentID)
+		err := reply.ReadFromConn(eh.conn, proto.ReadDeadlineTime)
+		if err != nil {
+			eh.processReplyError(packet, err.Error())
+			return
+		}
 
-	log.LogDebugf("processReply: get reply, eh(%v) packet(%v) reply(%v)", eh, packet, reply)
+		log.LogDebugf("processReply: get reply, eh(%v) packet(%v) reply(%v)", eh, packet, reply)
 
-	if reply.ResultCode != proto.OpOk {
-		errmsg := fmt.Sprintf("reply NOK: reply(%v)", reply)
-		eh.processReplyError(packet, errmsg)
-		return
-	}
+		if reply.ResultCode != proto.OpOk {
+			errmsg := fmt.Sprintf("reply NOK: reply(%v)", reply)
+			eh.processReplyError(packet, errmsg)
+			return
+		}
 
-	if !packet.isValidWriteReply(reply) {
-		errmsg := fmt.Sprintf("request and reply does not match: reply(%v)", reply)
-		eh.processReplyError(packet, errmsg)
-		return
-	}
+		if !packet.isValidWriteReply(reply) {
+			errmsg := fmt.Sprintf("request and reply does not match: reply(%v)", reply)
+			eh.processReplyError(packet, errmsg)
+			return
+		}
 
-	if reply.CRC != packet.CRC {
-		errmsg := fmt.Sprintf("inconsistent CRC: reqCRC(%v) replyCRC(%v) reply(%v) ", packet.CRC, reply.CRC, reply)
-		eh.processReplyError(packet, errmsg)
-		return
+		if reply.CRC != packet.CRC {
+			errmsg := fmt.Sprintf("inconsistent CRC: reqCRC(%v) replyCRC(%v) reply(%v) ", packet.CRC, reply.CRC, reply)
+			eh.processReplyError(packet, errmsg)
+			return
+		}
 	}
 
 	eh.dp.RecordWrite(packet.StartT)
@@ -345,10 +364,13 @@ func (eh *ExtentHandler) processReply(packet *Packet) {
 			ExtentId:     extID,
 			ExtentOffset: extOffset,
 			Size:         packet.Size,
+			Nonce:        eh.nonce,
 		}
 	} else {
 		eh.key.Size += packet.Size
 	}
+	// the CRC of the extent key covers all of the data appended through it
+	eh.key.CRC = crc32.Update(eh.key.CRC, crc32.IEEETable, packet.Data[:packet.Size])
 
 	proto.Buffers.Put(packet.Data)
 	packet.Data = nil
@@ -376,7 +398,9 @@ func (eh *ExtentHandler) flush() (err error) {
 		return
 	}
 
-	if eh.storeMode == proto.TinyExtentType {
+	// A block of an erasure-coded extent is encoded as a stripe, so the
+	// following writes can not be appended to a partial block.
+	if eh.storeMode == proto.TinyExtentType || (eh.dp != nil && eh.dp.IsErasureCoded()) {
 		eh.setClosed()
 	}
 
@@ -461,6 +485,7 @@ func (eh *ExtentHandler) recoverPacket(packet *Packet) error {
 		return errors.New(fmt.Sprintf("recoverPacket failed: reach max error limit, eh(%v) packet(%v)", eh, packet))
 	}
 
+	eh.decryptPacket(packet)
 	handler := eh.recoverHandler
 	if handler == nil {
 		// Always use normal extent store mode for recovery.
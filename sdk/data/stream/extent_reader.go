@@ -31,6 +31,7 @@ type ExtentReader struct {
 	key          *proto.ExtentKey
 	dp           *wrapper.DataPartition
 	followerRead bool
+	diskCache    *DiskCache // may be nil if disabled
 }
 
 // NewExtentReader returns a new extent reader.
@@ -51,6 +52,16 @@ func (reader *ExtentReader) String() (m string) {
 
 // Read reads the extent request.
 func (reader *ExtentReader) Read(req *ExtentRequest) (readBytes int, err error) {
+	if reader.diskCache != nil {
+		return reader.readCached(req)
+	}
+	return reader.read(req)
+}
+
+func (reader *ExtentReader) read(req *ExtentRequest) (readBytes int, err error) {
+	if reader.dp.IsErasureCoded() {
+		return reader.readEC(req)
+	}
 	offset := req.FileOffset - int(reader.key.FileOffset) + int(reader.key.ExtentOffset)
 	size := req.Size
 
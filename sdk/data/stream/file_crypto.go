@@ -0,0 +1,162 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stream
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/chubaofs/chubaofs/proto"
+)
+
+// The data of an encrypted file is encrypted by AES-CTR with the data key of
+// the file before it is written to the data nodes. Every extent key written
+// by the client is encrypted with a random nonce of its own, which is kept in
+// the extent key, and the counter of a block of the cipher is the offset of
+// it in the extent key divided by the block size, so that any range of an
+// extent key is encrypted and decrypted independently, and the size of the
+// data is not changed. The data of an encrypted file is never overwritten in
+// place, but written to a new extent key with a new nonce, so the key stream
+// is never reused. The holes of the file are not encrypted, which are read as
+// zeros. The extent keys without the nonces, which are written by the
+// earlier versions, are encrypted with the zero nonce and the counter of the
+// file offset.
+
+// FileCipher encrypts and decrypts the data of a file.
+type FileCipher struct {
+	block cipher.Block
+}
+
+// NewFileCipher returns a FileCipher of the data key.
+func NewFileCipher(key []byte) (*FileCipher, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return &FileCipher{block: block}, nil
+}
+
+// xorExtent encrypts or decrypts the data at the file offset of the extent
+// key in place.
+func (c *FileCipher) xorExtent(ek *proto.ExtentKey, offset uint64, data []byte) {
+	if ek.Nonce == 0 {
+		xorKeyStream(c.block, 0, offset, data)
+		return
+	}
+	xorKeyStream(c.block, ek.Nonce, offset-ek.FileOffset, data)
+}
+
+// SetFileKey sets the data key of the encrypted file.
+func (client *ExtentClient) SetFileKey(inode uint64, key []byte) error {
+	c, err := NewFileCipher(key)
+	if err != nil {
+		return err
+	}
+	client.fileKeys.Store(inode, c)
+	return nil
+}
+
+// HasFileKey returns true if the data key of the file is set.
+func (client *ExtentClient) HasFileKey(inode uint64) bool {
+	_, ok := client.fileKeys.Load(inode)
+	return ok
+}
+
+// RemoveFileKey removes the data key of the file.
+func (client *ExtentClient) RemoveFileKey(inode uint64) {
+	client.fileKeys.Delete(inode)
+}
+
+func (client *ExtentClient) fileCipher(inode uint64) *FileCipher {
+	c, ok := client.fileKeys.Load(inode)
+	if !ok {
+		return nil
+	}
+	return c.(*FileCipher)
+}
+
+// xorKeyStream encrypts or decrypts the data at the offset of an extent key
+// in place.
+func xorKeyStream(block cipher.Block, nonce, offset uint64, data []byte) {
+	iv := make([]byte, aes.BlockSize)
+	binary.BigEndian.PutUint64(iv[:8], nonce)
+	binary.BigEndian.PutUint64(iv[aes.BlockSize-8:], offset/aes.BlockSize)
+	stream := cipher.NewCTR(block, iv)
+	if skip := offset % aes.BlockSize; skip > 0 {
+		pad := make([]byte, skip)
+		stream.XORKeyStream(pad, pad)
+	}
+	stream.XORKeyStream(data, data)
+}
+
+// newFileNonce returns a random nonce of the extent key, which is never 0.
+func newFileNonce() (nonce uint64) {
+	buf := make([]byte, 8)
+	for nonce == 0 {
+		if _, err := rand.Read(buf); err != nil {
+			panic(err)
+		}
+		nonce = binary.BigEndian.Uint64(buf)
+	}
+	return
+}
+
+// encrypt encrypts the packet by the nonce of the extent handler in place
+// before it is sent.
+func (eh *ExtentHandler) encrypt(packet *Packet) {
+	c := eh.stream.client.fileCipher(eh.inode)
+	if c == nil || eh.nonce == 0 {
+		return
+	}
+	xorKeyStream(c.block, eh.nonce, packet.KernelOffset-uint64(eh.fileOffset), packet.Data[:packet.Size])
+	packet.nonce = eh.nonce
+}
+
+// decryptPacket restores the packet encrypted by the extent handler, which is
+// to be encrypted again by the recovery handler with the nonce of its own.
+func (eh *ExtentHandler) decryptPacket(packet *Packet) {
+	c := eh.stream.client.fileCipher(eh.inode)
+	if c == nil || packet.nonce == 0 {
+		return
+	}
+	xorKeyStream(c.block, packet.nonce, packet.KernelOffset-uint64(eh.fileOffset), packet.Data[:packet.Size])
+	packet.nonce = 0
+}
+
+// decrypt decrypts the data read from the extent key of the file in place.
+func (s *Streamer) decrypt(ek *proto.ExtentKey, offset int, data []byte) {
+	if c := s.client.fileCipher(s.inode); c != nil {
+		c.xorExtent(ek, uint64(offset), data)
+	}
+}
+
+// Decrypt decrypts the data read from the file at the offset by the cipher,
+// which is not set to the client, in place. The extent keys of the data are
+// the ones in the extent cache of the opened file.
+func (client *ExtentClient) Decrypt(inode uint64, c *FileCipher, offset int, data []byte) error {
+	s := client.GetStreamer(inode)
+	if s == nil {
+		return fmt.Errorf("Decrypt: stream is not opened yet, ino(%v)", inode)
+	}
+	for _, req := range s.extents.PrepareReadRequests(offset, len(data), data) {
+		if req.ExtentKey != nil {
+			c.xorExtent(req.ExtentKey, uint64(req.FileOffset), req.Data)
+		}
+	}
+	return nil
+}
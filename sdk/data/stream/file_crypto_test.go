@@ -0,0 +1,138 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stream
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util"
+)
+
+// newCryptoClient returns a client of an empty encrypted file.
+func newCryptoClient(t *testing.T, node *mockDataNode, key []byte) (client *ExtentClient, inode *mockInode, stop func()) {
+	inode = &mockInode{gen: 1}
+	client, stop = newMockInodeClient(t, &ExtentConfig{}, node, inode)
+	if err := client.OpenStream(1); err != nil {
+		stop()
+		t.Fatal(err)
+	}
+	if err := client.SetFileKey(1, key); err != nil {
+		stop()
+		t.Fatal(err)
+	}
+	return
+}
+
+func writeAndFlush(t *testing.T, client *ExtentClient, offset int, data []byte) {
+	if _, err := client.Write(1, offset, data, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Flush(1); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func xorBytes(a, b []byte) []byte {
+	c := make([]byte, len(a))
+	for i := range a {
+		c[i] = a[i] ^ b[i]
+	}
+	return c
+}
+
+func TestFileCrypto_Rewrite(t *testing.T) {
+	node := newMockDataNode(t)
+	defer node.listener.Close()
+	key := bytes.Repeat([]byte{0x5a}, 32)
+	client, inode, stop := newCryptoClient(t, node, key)
+	defer stop()
+
+	plain := make([]byte, util.DefaultTinySizeLimit+1000)
+	rand.Read(plain)
+	writeAndFlush(t, client, 0, plain)
+	if got := readAll(t, client, len(plain)); !bytes.Equal(got, plain) {
+		t.Fatalf("read mismatch after the write")
+	}
+	_, _, eks, _ := inode.getExtents(1)
+	if len(eks) != 1 || eks[0].Nonce == 0 {
+		t.Fatalf("extent keys(%v) of the encrypted file", eks)
+	}
+	first := eks[0]
+	ciphertext := node.extent(first.ExtentId)
+	if bytes.Equal(ciphertext[:100], plain[:100]) {
+		t.Fatalf("data is not encrypted")
+	}
+
+	// the rewrite is written to a new extent key with a new nonce
+	rewritten := make([]byte, len(plain))
+	rand.Read(rewritten)
+	writeAndFlush(t, client, 0, rewritten)
+	if got := readAll(t, client, len(plain)); !bytes.Equal(got, rewritten) {
+		t.Fatalf("read mismatch after the rewrite")
+	}
+	_, _, eks, _ = inode.getExtents(1)
+	if len(eks) != 1 || eks[0].ExtentId == first.ExtentId || eks[0].Nonce == 0 || eks[0].Nonce == first.Nonce {
+		t.Fatalf("extent keys(%v) after the rewrite, first(%v)", eks, first)
+	}
+	if !bytes.Equal(node.extent(first.ExtentId), ciphertext) {
+		t.Fatalf("encrypted extent is overwritten in place")
+	}
+	if bytes.Equal(xorBytes(node.extent(eks[0].ExtentId)[:100], ciphertext[:100]), xorBytes(rewritten[:100], plain[:100])) {
+		t.Fatalf("key stream is reused by the rewrite")
+	}
+
+	// the data read by a client without the key is decrypted by the extent keys
+	reader, readerStop := newMockInodeClient(t, &ExtentConfig{}, node, inode)
+	defer readerStop()
+	if err := reader.OpenStream(1); err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewFileCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := readAll(t, reader, len(plain))
+	if err = reader.Decrypt(1, c, 0, data); err != nil || !bytes.Equal(data, rewritten) {
+		t.Fatalf("decrypt mismatch, err(%v)", err)
+	}
+}
+
+func TestFileCrypto_Recovery(t *testing.T) {
+	node := newMockDataNode(t)
+	defer node.listener.Close()
+	client, inode, stop := newCryptoClient(t, node, bytes.Repeat([]byte{0xa5}, 32))
+	defer stop()
+
+	// the packets sent by the failed handler are encrypted again by the
+	// recovery handler
+	node.setFailWrites(1)
+	plain := make([]byte, util.DefaultTinySizeLimit+1000)
+	rand.Read(plain)
+	writeAndFlush(t, client, 0, plain)
+	if got := readAll(t, client, len(plain)); !bytes.Equal(got, plain) {
+		t.Fatalf("read mismatch after the recovery")
+	}
+	_, _, eks, _ := inode.getExtents(1)
+	nonces := make(map[uint64]proto.ExtentKey)
+	for _, ek := range eks {
+		if prev, ok := nonces[ek.Nonce]; ek.Nonce == 0 || ok {
+			t.Fatalf("nonce of extent key(%v) is reused by(%v)", ek, prev)
+		}
+		nonces[ek.Nonce] = ek
+	}
+}
@@ -0,0 +1,260 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stream
+
+import (
+	"encoding/json"
+	"hash/crc32"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util"
+)
+
+// mockDataNode keeps the extents of a host in memory, and serves the writes
+// and the reads of them.
+type mockDataNode struct {
+	sync.Mutex
+	listener   net.Listener
+	extents    map[uint64][]byte
+	down       bool
+	nextExtent uint64
+	failWrites int // the number of the next writes to fail
+}
+
+func newMockDataNode(t *testing.T) *mockDataNode {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &mockDataNode{listener: l, extents: make(map[uint64][]byte), nextExtent: 1024}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go s.serveConn(conn)
+		}
+	}()
+	return s
+}
+
+func (s *mockDataNode) addr() string {
+	return s.listener.Addr().String()
+}
+
+func (s *mockDataNode) setDown() {
+	s.Lock()
+	defer s.Unlock()
+	s.down = true
+}
+
+func (s *mockDataNode) setFailWrites(n int) {
+	s.Lock()
+	defer s.Unlock()
+	s.failWrites = n
+}
+
+func (s *mockDataNode) extent(extentID uint64) []byte {
+	s.Lock()
+	defer s.Unlock()
+	return append([]byte(nil), s.extents[extentID]...)
+}
+
+func (s *mockDataNode) setExtent(extentID uint64, data []byte) {
+	s.Lock()
+	defer s.Unlock()
+	s.extents[extentID] = append([]byte(nil), data...)
+}
+
+func (s *mockDataNode) serveConn(conn net.Conn) {
+	defer conn.Close()
+	for {
+		p := proto.NewPacket()
+		if err := p.ReadFromConn(conn, proto.NoReadDeadlineTime); err != nil {
+			return
+		}
+		if err := s.handle(conn, p); err != nil {
+			return
+		}
+	}
+}
+
+func (s *mockDataNode) handle(conn net.Conn, p *proto.Packet) (err error) {
+	s.Lock()
+	defer s.Unlock()
+	extent := s.extents[p.ExtentID]
+	isRead := p.Opcode == proto.OpStreamRead || p.Opcode == proto.OpStreamFollowerRead
+	switch {
+	case s.down:
+		p.ResultCode = proto.OpErr
+	case p.Opcode == proto.OpCreateExtent:
+		p.ExtentID = s.nextExtent
+		s.nextExtent++
+		p.ResultCode = proto.OpOk
+	case (p.Opcode == proto.OpWrite || p.Opcode == proto.OpRandomWrite) && s.failWrites > 0:
+		s.failWrites--
+		p.ResultCode = proto.OpErr
+	case p.Opcode == proto.OpWrite || p.Opcode == proto.OpRandomWrite:
+		if end := int(p.ExtentOffset) + len(p.Data); end > len(extent) {
+			extent = append(extent, make([]byte, end-len(extent))...)
+		}
+		copy(extent[p.ExtentOffset:], p.Data)
+		s.extents[p.ExtentID] = extent
+		p.ResultCode = proto.OpOk
+	case isRead && int(p.ExtentOffset)+int(p.Size) <= len(extent):
+		offset, end := int(p.ExtentOffset), int(p.ExtentOffset)+int(p.Size)
+		for offset < end {
+			reply := *p
+			reply.ResultCode = proto.OpOk
+			reply.ExtentOffset = int64(offset)
+			reply.Data = extent[offset:util.Min(end, offset+util.ReadBlockSize)]
+			reply.Size = uint32(len(reply.Data))
+			reply.CRC = crc32.ChecksumIEEE(reply.Data)
+			if err = reply.WriteToConn(conn); err != nil {
+				return
+			}
+			offset += len(reply.Data)
+		}
+		return
+	default:
+		p.ResultCode = proto.OpErr
+	}
+	p.Size, p.Data = 0, nil
+	return p.WriteToConn(conn)
+}
+
+// newMockMaster serves the volume view of a single data partition on the hosts.
+func newMockMaster(volName string, hosts []string) *httptest.Server {
+	reply := func(w http.ResponseWriter, data interface{}) {
+		body, _ := json.Marshal(&proto.HTTPReply{Code: proto.ErrCodeSuccess, Msg: "success", Data: data})
+		w.Write(body)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(proto.AdminGetIP, func(w http.ResponseWriter, r *http.Request) {
+		reply(w, &proto.ClusterInfo{Cluster: "test", Ip: "127.0.0.1"})
+	})
+	mux.HandleFunc(proto.AdminGetVol, func(w http.ResponseWriter, r *http.Request) {
+		reply(w, &proto.SimpleVolView{Name: volName})
+	})
+	mux.HandleFunc(proto.ClientDataPartitions, func(w http.ResponseWriter, r *http.Request) {
+		dp := &proto.DataPartitionResponse{PartitionID: 1, Status: proto.ReadWrite, ReplicaNum: uint8(len(hosts)),
+			Hosts: hosts, LeaderAddr: hosts[0]}
+		reply(w, &proto.DataPartitionsView{DataPartitions: []*proto.DataPartitionResponse{dp}})
+	})
+	mux.HandleFunc(proto.AdminGetCluster, func(w http.ResponseWriter, r *http.Request) {
+		reply(w, &proto.ClusterView{})
+	})
+	return httptest.NewServer(mux)
+}
+
+// mockInode is the metadata of a file, the extents of which are only changed
+// by the tests or truncated by the client.
+type mockInode struct {
+	sync.Mutex
+	gen  uint64
+	size uint64
+	eks  []proto.ExtentKey
+}
+
+func (i *mockInode) getExtents(ino uint64) (gen, size uint64, eks []proto.ExtentKey, err error) {
+	i.Lock()
+	defer i.Unlock()
+	return i.gen, i.size, append([]proto.ExtentKey(nil), i.eks...), nil
+}
+
+func (i *mockInode) truncate(ino, size uint64) error {
+	i.Lock()
+	defer i.Unlock()
+	eks := i.eks[:0]
+	for _, ek := range i.eks {
+		if ek.FileOffset >= size {
+			continue
+		}
+		if ek.FileOffset+uint64(ek.Size) > size {
+			ek.Size = uint32(size - ek.FileOffset)
+		}
+		eks = append(eks, ek)
+	}
+	i.eks, i.size = eks, size
+	i.gen++
+	return nil
+}
+
+// appendExtentKey replaces the extent keys covered by the appended one.
+func (i *mockInode) appendExtentKey(ino uint64, ek proto.ExtentKey, discard []proto.ExtentKey) error {
+	i.Lock()
+	defer i.Unlock()
+	end := ek.FileOffset + uint64(ek.Size)
+	eks := make([]proto.ExtentKey, 0, len(i.eks)+1)
+	for _, key := range i.eks {
+		if key.FileOffset < ek.FileOffset || key.FileOffset+uint64(key.Size) > end {
+			eks = append(eks, key)
+		}
+	}
+	eks = append(eks, ek)
+	sort.Slice(eks, func(m, n int) bool { return eks[m].FileOffset < eks[n].FileOffset })
+	i.eks = eks
+	if end > i.size {
+		i.size = end
+	}
+	i.gen++
+	return nil
+}
+
+// bump changes the generation of the inode, as if the extents are changed by
+// another client.
+func (i *mockInode) bump() {
+	i.Lock()
+	defer i.Unlock()
+	i.gen++
+}
+
+// newMockExtentClient returns a client of a file, the data of which is the
+// extent 1 on the data node, and the function to stop the client.
+func newMockExtentClient(t *testing.T, config *ExtentConfig, node *mockDataNode, data []byte) (client *ExtentClient, inode *mockInode, stop func()) {
+	node.setExtent(1, data)
+	inode = &mockInode{gen: 1, size: uint64(len(data)),
+		eks: []proto.ExtentKey{{PartitionId: 1, ExtentId: 1, Size: uint32(len(data))}}}
+	client, stop = newMockInodeClient(t, config, node, inode)
+	return
+}
+
+// newMockInodeClient returns another client of the file of the inode.
+func newMockInodeClient(t *testing.T, config *ExtentConfig, node *mockDataNode, inode *mockInode) (client *ExtentClient, stop func()) {
+	master := newMockMaster("test", []string{node.addr()})
+	config.Volume = "test"
+	config.Masters = []string{strings.TrimPrefix(master.URL, "http://")}
+	config.OnGetExtents = inode.getExtents
+	config.OnTruncate = inode.truncate
+	config.OnAppendExtentKey = inode.appendExtentKey
+	var err error
+	if client, err = NewExtentClient(config); err != nil {
+		master.Close()
+		t.Fatal(err)
+	}
+	stop = func() {
+		client.Close()
+		master.Close()
+	}
+	return
+}
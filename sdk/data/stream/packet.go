@@ -31,6 +31,7 @@ type Packet struct {
 	proto.Packet
 	inode    uint64
 	errCount int
+	nonce    uint64 // the nonce the data is encrypted with, 0 if not encrypted
 }
 
 // String returns the string format of the packet.
@@ -0,0 +1,278 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stream
+
+import (
+	"container/list"
+	"io"
+	"sync"
+	"sync/atomic"
+
+	"github.com/chubaofs/chubaofs/util"
+	"github.com/chubaofs/chubaofs/util/exporter"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+const (
+	ReadCacheBlockSize = util.BlockSize
+
+	// the max number of blocks read ahead of a sequential read
+	MaxReadAheadBlocks = 32
+)
+
+const (
+	MetricReadCacheHit  = "readCacheHit"
+	MetricReadCacheMiss = "readCacheMiss"
+)
+
+type cacheKey struct {
+	inode uint64
+	index uint64
+}
+
+// A cached block is valid only if both of the generation of the extent cache
+// and the epoch of the streamer are not changed since it is read. The former
+// is changed by the extents refreshed from the meta node, and the latter by
+// the writes and truncates of the local streamer.
+type cacheBlock struct {
+	key   cacheKey
+	gen   uint64
+	epoch uint64
+	data  []byte
+}
+
+// ReadCache defines the LRU cache of the file data blocks shared by the streamers.
+type ReadCache struct {
+	sync.Mutex
+	capacity int
+	lru      *list.List
+	blocks   map[cacheKey]*list.Element
+	inodes   map[uint64]map[uint64]*list.Element
+	inflight map[cacheKey]struct{}
+}
+
+// NewReadCache returns a new read cache of the size in bytes.
+func NewReadCache(size int64) *ReadCache {
+	return &ReadCache{
+		capacity: int(size / ReadCacheBlockSize),
+		lru:      list.New(),
+		blocks:   make(map[cacheKey]*list.Element),
+		inodes:   make(map[uint64]map[uint64]*list.Element),
+		inflight: make(map[cacheKey]struct{}),
+	}
+}
+
+// Get returns the data of the block if it is cached and still valid.
+func (c *ReadCache) Get(inode, index, gen, epoch uint64) (data []byte, ok bool) {
+	c.Lock()
+	defer c.Unlock()
+	element, found := c.blocks[cacheKey{inode, index}]
+	if !found {
+		return nil, false
+	}
+	block := element.Value.(*cacheBlock)
+	if block.gen != gen || block.epoch != epoch {
+		c.remove(element)
+		return nil, false
+	}
+	c.lru.MoveToFront(element)
+	return block.data, true
+}
+
+// Put caches the data of the block, and evicts the least recently used blocks
+// if the cache is full.
+func (c *ReadCache) Put(inode, index, gen, epoch uint64, data []byte) {
+	c.Lock()
+	defer c.Unlock()
+	key := cacheKey{inode, index}
+	if element, found := c.blocks[key]; found {
+		c.remove(element)
+	}
+	element := c.lru.PushFront(&cacheBlock{key: key, gen: gen, epoch: epoch, data: data})
+	c.blocks[key] = element
+	blocks, found := c.inodes[inode]
+	if !found {
+		blocks = make(map[uint64]*list.Element)
+		c.inodes[inode] = blocks
+	}
+	blocks[index] = element
+	for c.lru.Len() > c.capacity {
+		c.remove(c.lru.Back())
+	}
+}
+
+// Evict removes all the cached blocks of the inode.
+func (c *ReadCache) Evict(inode uint64) {
+	c.Lock()
+	defer c.Unlock()
+	for _, element := range c.inodes[inode] {
+		c.remove(element)
+	}
+}
+
+// Len returns the number of the cached blocks.
+func (c *ReadCache) Len() int {
+	c.Lock()
+	defer c.Unlock()
+	return c.lru.Len()
+}
+
+func (c *ReadCache) remove(element *list.Element) {
+	block := element.Value.(*cacheBlock)
+	c.lru.Remove(element)
+	delete(c.blocks, block.key)
+	if blocks, ok := c.inodes[block.key.inode]; ok {
+		delete(blocks, block.key.index)
+		if len(blocks) == 0 {
+			delete(c.inodes, block.key.inode)
+		}
+	}
+}
+
+// startFetch marks the block in flight, and returns false if it is already
+// being read ahead.
+func (c *ReadCache) startFetch(inode, index uint64) bool {
+	c.Lock()
+	defer c.Unlock()
+	key := cacheKey{inode, index}
+	if _, ok := c.inflight[key]; ok {
+		return false
+	}
+	if _, ok := c.blocks[key]; ok {
+		return false
+	}
+	c.inflight[key] = struct{}{}
+	return true
+}
+
+func (c *ReadCache) endFetch(inode, index uint64) {
+	c.Lock()
+	delete(c.inflight, cacheKey{inode, index})
+	c.Unlock()
+}
+
+func (c *ReadCache) maxReadAhead() int {
+	return util.Min(MaxReadAheadBlocks, c.capacity/4)
+}
+
+// readAhead tracks the sequential reads of a streamer. The window is doubled
+// by every sequential read, and reset by a random one.
+type readAhead struct {
+	sync.Mutex
+	nextOffset int
+	window     int
+}
+
+// update records the read, and returns the number of blocks to read ahead.
+func (ra *readAhead) update(offset, size, max int) int {
+	ra.Lock()
+	defer ra.Unlock()
+	if offset == ra.nextOffset && offset != 0 {
+		if ra.window == 0 {
+			ra.window = 1
+		} else {
+			ra.window = util.Min(ra.window*2, max)
+		}
+	} else {
+		ra.window = 0
+	}
+	ra.nextOffset = offset + size
+	return ra.window
+}
+
+// invalidateReadCache drops the cached blocks of the streamer after the file
+// data is changed locally.
+func (s *Streamer) invalidateReadCache() {
+	if s.client.readCache == nil {
+		return
+	}
+	atomic.AddUint64(&s.cacheEpoch, 1)
+	s.client.readCache.Evict(s.inode)
+}
+
+// cachedRead serves the read from the read cache by blocks, reads the missed
+// blocks from the data nodes, and reads ahead the following blocks if the
+// reads are sequential.
+func (s *Streamer) cachedRead(data []byte, offset int, size int) (total int, err error) {
+	cache := s.client.readCache
+	filesize, gen := s.extents.Size()
+	if offset >= filesize {
+		return s.readDirect(data, offset, size)
+	}
+	epoch := atomic.LoadUint64(&s.cacheEpoch)
+	end := util.Min(offset+size, filesize)
+	first, last := offset/ReadCacheBlockSize, (end-1)/ReadCacheBlockSize
+
+	for index := first; index <= last; index++ {
+		block, ok := cache.Get(s.inode, uint64(index), gen, epoch)
+		if ok {
+			exporter.NewCounter(MetricReadCacheHit).Add(1)
+		} else {
+			exporter.NewCounter(MetricReadCacheMiss).Add(1)
+			if block, err = s.readBlock(index, filesize); err != nil {
+				return
+			}
+			cache.Put(s.inode, uint64(index), gen, epoch, block)
+		}
+		blockOffset := index * ReadCacheBlockSize
+		start := util.Max(offset, blockOffset) - blockOffset
+		if start >= len(block) {
+			break
+		}
+		total += copy(data[total:end-offset], block[start:])
+		if len(block) < ReadCacheBlockSize {
+			break
+		}
+	}
+
+	if window := s.readAhead.update(offset, size, cache.maxReadAhead()); window > 0 {
+		s.prefetch(last+1, window, filesize, gen, epoch)
+	}
+	if offset+size > filesize || total < end-offset {
+		err = io.EOF
+	}
+	log.LogDebugf("cachedRead: ino(%v) offset(%v) size(%v) filesize(%v) total(%v) err(%v)", s.inode, offset, size, filesize, total, err)
+	return
+}
+
+// readBlock reads the block of the index from the data nodes.
+func (s *Streamer) readBlock(index int, filesize int) (block []byte, err error) {
+	blockOffset := index * ReadCacheBlockSize
+	block = make([]byte, util.Min(ReadCacheBlockSize, filesize-blockOffset))
+	n, err := s.readDirect(block, blockOffset, len(block))
+	if err == io.EOF {
+		err = nil
+	}
+	return block[:n], err
+}
+
+// prefetch reads the blocks of the window asynchronously into the read cache.
+func (s *Streamer) prefetch(first, window, filesize int, gen, epoch uint64) {
+	cache := s.client.readCache
+	for index := first; index < first+window && index*ReadCacheBlockSize < filesize; index++ {
+		if !cache.startFetch(s.inode, uint64(index)) {
+			continue
+		}
+		go func(index int) {
+			defer cache.endFetch(s.inode, uint64(index))
+			block, err := s.readBlock(index, filesize)
+			if err != nil {
+				log.LogWarnf("prefetch: ino(%v) block(%v) err(%v)", s.inode, index, err)
+				return
+			}
+			cache.Put(s.inode, uint64(index), gen, epoch, block)
+		}(index)
+	}
+}
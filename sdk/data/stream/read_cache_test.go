@@ -0,0 +1,217 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stream
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestReadCache_GetPut(t *testing.T) {
+	cache := NewReadCache(3 * ReadCacheBlockSize)
+	for index := uint64(0); index < 3; index++ {
+		cache.Put(1, index, 1, 1, []byte{byte(index)})
+	}
+	if _, ok := cache.Get(1, 0, 1, 1); !ok {
+		t.Fatalf("block 0 is not cached")
+	}
+	// the least recently used block 1 is evicted
+	cache.Put(2, 0, 1, 1, []byte{0})
+	if _, ok := cache.Get(1, 1, 1, 1); ok || cache.Len() != 3 {
+		t.Fatalf("block 1 is not evicted, len(%v)", cache.Len())
+	}
+
+	// the block is dropped if the extents are refreshed or the file is changed locally since it is read
+	if _, ok := cache.Get(1, 0, 2, 1); ok {
+		t.Fatalf("block of the old generation is returned")
+	}
+	if _, ok := cache.Get(1, 2, 1, 2); ok {
+		t.Fatalf("block of the old epoch is returned")
+	}
+	if _, ok := cache.Get(1, 0, 1, 1); ok || cache.Len() != 1 {
+		t.Fatalf("invalid blocks are not dropped, len(%v)", cache.Len())
+	}
+
+	cache.Put(1, 0, 1, 1, []byte{0})
+	cache.Evict(1)
+	if _, ok := cache.Get(1, 0, 1, 1); ok {
+		t.Fatalf("block of the evicted inode is returned")
+	}
+	if _, ok := cache.Get(2, 0, 1, 1); !ok {
+		t.Fatalf("block of the other inode is evicted")
+	}
+}
+
+func TestReadCache_Fetch(t *testing.T) {
+	cache := NewReadCache(16 * ReadCacheBlockSize)
+	if !cache.startFetch(1, 0) || cache.startFetch(1, 0) {
+		t.Fatalf("block in flight is fetched twice")
+	}
+	cache.endFetch(1, 0)
+	cache.Put(1, 0, 1, 1, []byte{0})
+	if cache.startFetch(1, 0) {
+		t.Fatalf("cached block is fetched")
+	}
+	if max := cache.maxReadAhead(); max != 4 {
+		t.Fatalf("max read ahead(%v) of 16 blocks", max)
+	}
+
+	ra := &readAhead{}
+	steps := []struct {
+		offset, window int
+	}{
+		{0, 0},
+		{10, 1},  // sequential
+		{20, 2},  // doubled
+		{30, 4},  // doubled
+		{40, 4},  // limited by the max
+		{100, 0}, // random
+		{110, 1},
+	}
+	for _, step := range steps {
+		if window := ra.update(step.offset, 10, 4); window != step.window {
+			t.Fatalf("read offset(%v) window(%v) expected(%v)", step.offset, window, step.window)
+		}
+	}
+}
+
+func TestReadCache_Streamer(t *testing.T) {
+	const ino, blocks = 1, 8
+	node := newMockDataNode(t)
+	defer node.listener.Close()
+	data := make([]byte, blocks*ReadCacheBlockSize-100)
+	rand.Read(data)
+	client, inode, stop := newMockExtentClient(t, &ExtentConfig{ReadCacheSize: 16}, node, data)
+	defer stop()
+	if err := client.OpenStream(ino); err != nil {
+		t.Fatal(err)
+	}
+	s := client.GetStreamer(ino)
+	cache := client.readCache
+
+	read := func(offset, size int) []byte {
+		buf := make([]byte, size)
+		n, err := client.Read(ino, buf, offset, size)
+		if err != nil && err != io.EOF {
+			t.Fatalf("read offset(%v) size(%v) err(%v)", offset, size, err)
+		}
+		return buf[:n]
+	}
+	check := func(state string, expected []byte) {
+		if got := read(0, len(data)); !bytes.Equal(got, expected) {
+			t.Fatalf("%v: read(%v) mismatch, expected(%v)", state, len(got), len(expected))
+		}
+	}
+	check("cold", data)
+	if cache.Len() != blocks {
+		t.Fatalf("cached blocks(%v)", cache.Len())
+	}
+
+	// the data changed on the data node is not read until the extents are refreshed
+	changed := append([]byte(nil), data...)
+	changed[0]++
+	node.setExtent(1, changed)
+	check("cached", data)
+	inode.bump()
+	if err := s.GetExtents(); err != nil {
+		t.Fatal(err)
+	}
+	check("refreshed", changed)
+
+	// the local overwrite changes the epoch of the streamer
+	epoch := atomic.LoadUint64(&s.cacheEpoch)
+	copy(changed[ReadCacheBlockSize:], []byte("overwrite"))
+	if _, err := client.Write(ino, ReadCacheBlockSize, []byte("overwrite"), 0); err != nil {
+		t.Fatal(err)
+	}
+	if atomic.LoadUint64(&s.cacheEpoch) == epoch || cache.Len() != 0 {
+		t.Fatalf("cache is not invalidated by write, epoch(%v) len(%v)", epoch, cache.Len())
+	}
+	check("written", changed)
+
+	// a block prefetched before the write is dropped
+	_, gen := s.extents.Size()
+	cache.Put(ino, 2, gen, epoch, data[2*ReadCacheBlockSize:3*ReadCacheBlockSize])
+	if got := read(2*ReadCacheBlockSize, 10); !bytes.Equal(got, changed[2*ReadCacheBlockSize:2*ReadCacheBlockSize+10]) {
+		t.Fatalf("block prefetched before the write is read")
+	}
+
+	// the truncate drops the blocks beyond the new size
+	size := 3*ReadCacheBlockSize + 10
+	if err := client.Truncate(ino, size); err != nil {
+		t.Fatal(err)
+	}
+	check("truncated", changed[:size])
+}
+
+func TestReadCache_Prefetch(t *testing.T) {
+	const ino, blocks = 1, 16
+	node := newMockDataNode(t)
+	defer node.listener.Close()
+	data := make([]byte, blocks*ReadCacheBlockSize)
+	rand.Read(data)
+	client, _, stop := newMockExtentClient(t, &ExtentConfig{ReadCacheSize: 16}, node, data)
+	defer stop()
+	if err := client.OpenStream(ino); err != nil {
+		t.Fatal(err)
+	}
+	s := client.GetStreamer(ino)
+	cache := client.readCache
+
+	// wait for the blocks to be prefetched
+	waitFor := func(indexes ...int) {
+		_, gen := s.extents.Size()
+		epoch := atomic.LoadUint64(&s.cacheEpoch)
+		for _, index := range indexes {
+			for i := 0; ; i++ {
+				if block, ok := cache.Get(ino, uint64(index), gen, epoch); ok {
+					if !bytes.Equal(block, data[index*ReadCacheBlockSize:(index+1)*ReadCacheBlockSize]) {
+						t.Fatalf("prefetched block(%v) mismatch", index)
+					}
+					break
+				}
+				if i == 100 {
+					t.Fatalf("block(%v) is not prefetched", index)
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+		}
+	}
+	buf := make([]byte, ReadCacheBlockSize)
+	// the window is 1 and 2 blocks after the first and the second sequential reads
+	for offset := 0; offset < 3*ReadCacheBlockSize; offset += ReadCacheBlockSize {
+		if _, err := client.Read(ino, buf, offset, len(buf)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	waitFor(3, 4)
+	_, gen := s.extents.Size()
+	epoch := atomic.LoadUint64(&s.cacheEpoch)
+	if _, ok := cache.Get(ino, 5, gen, epoch); ok {
+		t.Fatalf("block beyond the window is prefetched")
+	}
+	// a random read stops the read ahead
+	if _, err := client.Read(ino, buf, 10*ReadCacheBlockSize, len(buf)); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if _, ok := cache.Get(ino, 11, gen, epoch); ok {
+		t.Fatalf("block is prefetched after the random read")
+	}
+}
@@ -28,6 +28,7 @@ import (
 
 var (
 	TryOtherAddrError = errors.New("TryOtherAddrError")
+	CopyOnWriteError  = errors.New("CopyOnWriteError")
 )
 
 const (
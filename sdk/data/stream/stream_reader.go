@@ -27,6 +27,8 @@ import (
 // One inode corresponds to one streamer. All the requests to the same inode will be queued.
 // TODO rename streamer here is not a good name as it also handles overwrites, not just stream write.
 type Streamer struct {
+	cacheEpoch uint64 // changed by the local writes to invalidate the read cache
+
 	client *ExtentClient
 	inode  uint64
 
@@ -48,6 +50,8 @@ type Streamer struct {
 	done    chan struct{}    // stream writer is being closed
 
 	writeLock sync.Mutex
+
+	readAhead readAhead
 }
 
 // NewStreamer returns a new streamer.
@@ -81,10 +85,18 @@ func (s *Streamer) GetExtentReader(ek *proto.ExtentKey) (*ExtentReader, error) {
 		return nil, err
 	}
 	reader := NewExtentReader(s.inode, ek, partition, s.client.dataWrapper.FollowerRead())
+	reader.diskCache = s.client.diskCache
 	return reader, nil
 }
 
 func (s *Streamer) read(data []byte, offset int, size int) (total int, err error) {
+	if s.client.readCache != nil {
+		return s.cachedRead(data, offset, size)
+	}
+	return s.readDirect(data, offset, size)
+}
+
+func (s *Streamer) readDirect(data []byte, offset int, size int) (total int, err error) {
 	var (
 		readBytes       int
 		reader          *ExtentReader
@@ -146,6 +158,7 @@ func (s *Streamer) read(data []byte, offset int, size int) (total int, err error
 				break
 			}
 			readBytes, err = reader.Read(req)
+			s.decrypt(req.ExtentKey, req.FileOffset, req.Data[:readBytes])
 			log.LogDebugf("Stream read: ino(%v) req(%v) readBytes(%v) err(%v)", s.inode, req, readBytes, err)
 			total += readBytes
 			if err != nil || readBytes < req.Size {
@@ -242,9 +242,11 @@ func (s *Streamer) handleRequest(request interface{}) {
 		request.done <- struct{}{}
 	case *WriteRequest:
 		request.writeBytes, request.err = s.write(request.data, request.fileOffset, request.size, request.flags)
+		s.invalidateReadCache()
 		request.done <- struct{}{}
 	case *TruncRequest:
 		request.err = s.truncate(request.size)
+		s.invalidateReadCache()
 		request.done <- struct{}{}
 	case *FlushRequest:
 		request.err = s.flush()
@@ -254,6 +256,9 @@ func (s *Streamer) handleRequest(request interface{}) {
 		request.done <- struct{}{}
 	case *EvictRequest:
 		request.err = s.evict()
+		if request.err == nil {
+			s.invalidateReadCache()
+		}
 		request.done <- struct{}{}
 	default:
 	}
@@ -295,8 +300,21 @@ func (s *Streamer) write(data []byte, offset, size, flags int) (total int, err e
 
 	for _, req := range requests {
 		var writeSize int
-		if req.ExtentKey != nil {
+		// Overwriting is copy-on-write if the volume has snapshots. A snapshot
+		// is known by the client after the next volume view update, while the
+		// data nodes reject the overwrites once the snapshot is created, and
+		// the rest of the request is written to a new extent then.
+		// The erasure-coded extents are write-once, so they are always
+		// overwritten by copy-on-write, and so are the encrypted files, the
+		// new data of which is encrypted with a new nonce.
+		if req.ExtentKey != nil && !s.client.dataWrapper.HasSnapshot() && !s.client.dataWrapper.IsErasureCoded() &&
+			s.client.fileCipher(s.inode) == nil {
 			writeSize, err = s.doOverwrite(req, direct)
+			if err == CopyOnWriteError {
+				var size int
+				size, err = s.doWrite(req.Data[writeSize:], req.FileOffset+writeSize, req.Size-writeSize, direct)
+				writeSize += size
+			}
 		} else {
 			writeSize, err = s.doWrite(req.Data, req.FileOffset, req.Size, direct)
 		}
@@ -340,6 +358,15 @@ func (s *Streamer) doOverwrite(req *ExtentRequest, direct bool) (total int, err
 		errors.Trace(err, "doOverwrite: ino(%v) failed to get datapartition, ek(%v)", s.inode, req.ExtentKey)
 		return
 	}
+	if s.client.diskCache != nil {
+		defer s.client.diskCache.EvictExtent(dp.PartitionID, req.ExtentKey.ExtentId)
+	}
+	// The open handler appending to the extent would append the extent key
+	// with the CRC of the data before the overwrite again.
+	if s.handler != nil && s.handler.key != nil && s.handler.key.PartitionId == req.ExtentKey.PartitionId &&
+		s.handler.key.ExtentId == req.ExtentKey.ExtentId {
+		s.closeOpenHandler()
+	}
 
 	sc := NewStreamConn(dp, false)
 
@@ -376,6 +403,10 @@ func (s *Streamer) doOverwrite(req *ExtentRequest, direct bool) (total int, err
 		reqPacket.Data = nil
 		log.LogDebugf("doOverwrite: ino(%v) req(%v) reqPacket(%v) err(%v) replyPacket(%v)", s.inode, req, reqPacket, err, replyPacket)
 
+		if err == nil && replyPacket.ResultCode == proto.OpCopyOnWriteErr {
+			err = CopyOnWriteError
+			break
+		}
 		if err != nil || replyPacket.ResultCode != proto.OpOk {
 			err = errors.New(fmt.Sprintf("doOverwrite: failed or reply NOK: err(%v) ino(%v) req(%v) replyPacket(%v)", err, s.inode, req, replyPacket))
 			break
@@ -389,6 +420,28 @@ func (s *Streamer) doOverwrite(req *ExtentRequest, direct bool) (total int, err
 		total += packSize
 	}
 
+	if total > 0 && req.ExtentKey.CRC != 0 {
+		if e := s.clearExtentCRC(req.ExtentKey); e != nil && err == nil {
+			err = e
+		}
+	}
+	return
+}
+
+// clearExtentCRC updates the extent key with the CRC cleared, since the CRC
+// does not match the overwritten data any more. It also changes the generation
+// of the inode, so the other clients refresh the extent key, and stop reading
+// the blocks cached by the old CRC.
+func (s *Streamer) clearExtentCRC(key *proto.ExtentKey) (err error) {
+	ek := *key
+	ek.CRC = 0
+	discard := s.extents.Append(&ek, true)
+	if err = s.client.appendExtentKey(s.inode, ek, discard); err != nil {
+		return errors.Trace(err, "clearExtentCRC: ino(%v) ek(%v)", s.inode, ek)
+	}
+	if len(discard) > 0 {
+		s.extents.RemoveDiscard(discard)
+	}
 	return
 }
 
@@ -398,7 +451,7 @@ func (s *Streamer) doWrite(data []byte, offset, size int, direct bool) (total in
 		storeMode int
 	)
 
-	if offset+size > s.tinySizeLimit() {
+	if offset+size > s.tinySizeLimit() || s.client.dataWrapper.IsErasureCoded() {
 		storeMode = proto.NormalExtentType
 	} else {
 		storeMode = proto.TinyExtentType
@@ -125,6 +125,12 @@ func (dp *DataPartition) String() string {
 		dp.PartitionID, dp.Status, dp.ReplicaNum, dp.PartitionType, dp.Hosts, dp.NearHosts)
 }
 
+// IsErasureCoded returns true if the data partition is erasure-coded, in which
+// case the i-th host holds the i-th shard of the extents.
+func (dp *DataPartition) IsErasureCoded() bool {
+	return dp.ECDataNum > 0
+}
+
 func (dp *DataPartition) CheckAllHostsIsAvail(exclude map[string]struct{}) {
 	var (
 		conn net.Conn
@@ -19,6 +19,7 @@ import (
 	"net"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/chubaofs/chubaofs/proto"
@@ -50,6 +51,8 @@ type Wrapper struct {
 	dpSelectorChanged     bool
 	dpSelectorName        string
 	dpSelectorParm        string
+	snapshotCnt           int32
+	ecDataNum             uint8
 	mc                    *masterSDK.MasterClient
 	stopOnce              sync.Once
 	stopC                 chan struct{}
@@ -127,6 +130,8 @@ func (w *Wrapper) getSimpleVolView() (err error) {
 	w.followerRead = view.FollowerRead
 	w.dpSelectorName = view.DpSelectorName
 	w.dpSelectorParm = view.DpSelectorParm
+	atomic.StoreInt32(&w.snapshotCnt, int32(view.SnapshotCnt))
+	w.ecDataNum = view.ECDataNum
 
 	log.LogInfof("getSimpleVolView: get volume simple info: ID(%v) name(%v) owner(%v) status(%v) capacity(%v) "+
 		"metaReplicas(%v) dataReplicas(%v) mpCnt(%v) dpCnt(%v) followerRead(%v) createTime(%v) dpSelectorName(%v) "+
@@ -173,9 +178,27 @@ func (w *Wrapper) updateSimpleVolView() (err error) {
 		w.Unlock()
 	}
 
+	if cnt := int32(view.SnapshotCnt); atomic.LoadInt32(&w.snapshotCnt) != cnt {
+		log.LogInfof("updateSimpleVolView: update snapshotCnt from old(%v) to new(%v)",
+			atomic.LoadInt32(&w.snapshotCnt), cnt)
+		atomic.StoreInt32(&w.snapshotCnt, cnt)
+	}
+
 	return nil
 }
 
+// HasSnapshot returns true if the volume has snapshots, in which case the
+// extents must not be overwritten since they may be shared with snapshots.
+func (w *Wrapper) HasSnapshot() bool {
+	return atomic.LoadInt32(&w.snapshotCnt) > 0
+}
+
+// IsErasureCoded returns true if the data partitions of the volume are
+// erasure-coded, in which case the files are write-once.
+func (w *Wrapper) IsErasureCoded() bool {
+	return w.ecDataNum > 0
+}
+
 func (w *Wrapper) updateDataPartition(isInit bool) (err error) {
 
 	var dpv *proto.DataPartitionsView
@@ -18,6 +18,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/chubaofs/chubaofs/proto"
 )
@@ -157,6 +158,50 @@ func (api *AdminAPI) DecommissionMetaPartition(metaPartitionID uint64, nodeAddr
 	return
 }
 
+func (api *AdminAPI) MergeMetaPartition(volName string, metaPartitionID uint64) (task *proto.MetaMoveTask, err error) {
+	var request = newAPIRequest(http.MethodGet, proto.AdminMergeMetaPartition)
+	request.addParam("name", volName)
+	request.addParam("id", strconv.FormatUint(metaPartitionID, 10))
+	var buf []byte
+	if buf, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	task = &proto.MetaMoveTask{}
+	if err = json.Unmarshal(buf, task); err != nil {
+		return
+	}
+	return
+}
+
+func (api *AdminAPI) ResplitMetaPartition(volName string, metaPartitionID, start uint64) (task *proto.MetaMoveTask, err error) {
+	var request = newAPIRequest(http.MethodGet, proto.AdminResplitMetaPartition)
+	request.addParam("name", volName)
+	request.addParam("id", strconv.FormatUint(metaPartitionID, 10))
+	request.addParam("start", strconv.FormatUint(start, 10))
+	var buf []byte
+	if buf, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	task = &proto.MetaMoveTask{}
+	if err = json.Unmarshal(buf, task); err != nil {
+		return
+	}
+	return
+}
+
+func (api *AdminAPI) GetMetaMoveStatus() (status *proto.MetaMoveStatus, err error) {
+	var request = newAPIRequest(http.MethodGet, proto.AdminMetaMoveStatus)
+	var buf []byte
+	if buf, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	status = &proto.MetaMoveStatus{}
+	if err = json.Unmarshal(buf, status); err != nil {
+		return
+	}
+	return
+}
+
 func (api *AdminAPI) DeleteDataReplica(dataPartitionID uint64, nodeAddr string) (err error) {
 	var request = newAPIRequest(http.MethodGet, proto.AdminDeleteDataReplica)
 	request.addParam("id", strconv.FormatUint(dataPartitionID, 10))
@@ -207,7 +252,7 @@ func (api *AdminAPI) DeleteVolume(volName, authKey string) (err error) {
 	return
 }
 
-func (api *AdminAPI) UpdateVolume(volName string, capacity uint64, replicas int, followerRead, authenticate, enableToken bool, authKey, zoneName string) (err error) {
+func (api *AdminAPI) UpdateVolume(volName string, capacity uint64, replicas int, followerRead, authenticate, enableToken bool, authKey, zoneName string, trashDays uint32) (err error) {
 	var request = newAPIRequest(http.MethodGet, proto.AdminUpdateVol)
 	request.addParam("name", volName)
 	request.addParam("authKey", authKey)
@@ -217,6 +262,7 @@ func (api *AdminAPI) UpdateVolume(volName string, capacity uint64, replicas int,
 	request.addParam("enableToken", strconv.FormatBool(enableToken))
 	request.addParam("authenticate", strconv.FormatBool(authenticate))
 	request.addParam("zoneName", zoneName)
+	request.addParam("trashDays", strconv.FormatUint(uint64(trashDays), 10))
 	if _, err = api.mc.serveRequest(request); err != nil {
 		return
 	}
@@ -245,8 +291,109 @@ func (api *AdminAPI) VolExpand(volName string, capacity uint64, authKey string)
 	return
 }
 
+func (api *AdminAPI) CreateSnapshot(volName, authKey, snapName string) (snap *proto.SnapshotInfo, err error) {
+	var request = newAPIRequest(http.MethodGet, proto.AdminCreateSnapshot)
+	request.addParam("name", volName)
+	request.addParam("authKey", authKey)
+	request.addParam("snapshot", snapName)
+	var buf []byte
+	if buf, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	snap = &proto.SnapshotInfo{}
+	if err = json.Unmarshal(buf, snap); err != nil {
+		return
+	}
+	return
+}
+
+func (api *AdminAPI) DeleteSnapshot(volName, authKey, snapName string) (err error) {
+	var request = newAPIRequest(http.MethodGet, proto.AdminDeleteSnapshot)
+	request.addParam("name", volName)
+	request.addParam("authKey", authKey)
+	request.addParam("snapshot", snapName)
+	if _, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	return
+}
+
+func (api *AdminAPI) ListSnapshots(volName string) (snapshots []*proto.SnapshotInfo, err error) {
+	var request = newAPIRequest(http.MethodGet, proto.AdminListSnapshots)
+	request.addParam("name", volName)
+	var buf []byte
+	if buf, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	snapshots = make([]*proto.SnapshotInfo, 0)
+	if err = json.Unmarshal(buf, &snapshots); err != nil {
+		return
+	}
+	return
+}
+
+func (api *AdminAPI) SetQuota(volName, authKey string, quotaType proto.QuotaType, target, maxBytes, maxInodes uint64) (quota *proto.QuotaInfo, err error) {
+	var request = newAPIRequest(http.MethodGet, proto.AdminSetQuota)
+	request.addParam("name", volName)
+	request.addParam("authKey", authKey)
+	request.addParam("type", quotaType.String())
+	request.addParam("target", strconv.FormatUint(target, 10))
+	request.addParam("maxBytes", strconv.FormatUint(maxBytes, 10))
+	request.addParam("maxInodes", strconv.FormatUint(maxInodes, 10))
+	var buf []byte
+	if buf, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	quota = &proto.QuotaInfo{}
+	if err = json.Unmarshal(buf, quota); err != nil {
+		return
+	}
+	return
+}
+
+func (api *AdminAPI) DeleteQuota(volName, authKey string, quotaID uint64) (err error) {
+	var request = newAPIRequest(http.MethodGet, proto.AdminDeleteQuota)
+	request.addParam("name", volName)
+	request.addParam("authKey", authKey)
+	request.addParam("quotaId", strconv.FormatUint(quotaID, 10))
+	if _, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	return
+}
+
+func (api *AdminAPI) ListQuotas(volName string) (quotas []*proto.QuotaInfo, err error) {
+	var request = newAPIRequest(http.MethodGet, proto.AdminListQuotas)
+	request.addParam("name", volName)
+	var buf []byte
+	if buf, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	quotas = make([]*proto.QuotaInfo, 0)
+	if err = json.Unmarshal(buf, &quotas); err != nil {
+		return
+	}
+	return
+}
+
+func (api *AdminAPI) GetQuota(volName string, quotaID uint64) (quota *proto.QuotaInfo, err error) {
+	var request = newAPIRequest(http.MethodGet, proto.AdminGetQuota)
+	request.addParam("name", volName)
+	request.addParam("quotaId", strconv.FormatUint(quotaID, 10))
+	var buf []byte
+	if buf, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	quota = &proto.QuotaInfo{}
+	if err = json.Unmarshal(buf, quota); err != nil {
+		return
+	}
+	return
+}
+
 func (api *AdminAPI) CreateVolume(volName, owner string, mpCount int,
-	dpSize uint64, capacity uint64, replicas int, followerRead bool, zoneName string, crossZone bool) (err error) {
+	dpSize uint64, capacity uint64, replicas int, followerRead bool, zoneName string, crossZone bool,
+	ecDataNum, ecParityNum int) (err error) {
 	var request = newAPIRequest(http.MethodGet, proto.AdminCreateVol)
 	request.addParam("name", volName)
 	request.addParam("owner", owner)
@@ -256,6 +403,10 @@ func (api *AdminAPI) CreateVolume(volName, owner string, mpCount int,
 	request.addParam("followerRead", strconv.FormatBool(followerRead))
 	request.addParam("zoneName", zoneName)
 	request.addParam("crossZone", strconv.FormatBool(crossZone))
+	if ecDataNum > 0 {
+		request.addParam("ecDataNum", strconv.Itoa(ecDataNum))
+		request.addParam("ecParityNum", strconv.Itoa(ecParityNum))
+	}
 	if _, err = api.mc.serveRequest(request); err != nil {
 		return
 	}
@@ -333,6 +484,138 @@ func (api *AdminAPI) IsFreezeCluster(isFreeze bool) (err error) {
 	return
 }
 
+func (api *AdminAPI) StartDataBalance(config proto.DataBalanceConfig) (err error) {
+	var request = newAPIRequest(http.MethodGet, proto.AdminDataBalanceStart)
+	if config.Threshold > 0 {
+		request.addParam("threshold", strconv.FormatFloat(config.Threshold, 'f', 6, 64))
+	}
+	if config.MaxConcurrency > 0 {
+		request.addParam("maxConcurrency", strconv.Itoa(config.MaxConcurrency))
+	}
+	request.addParam("maxBandwidth", strconv.FormatUint(config.MaxBandwidth, 10))
+	if _, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	return
+}
+
+func (api *AdminAPI) StopDataBalance() (err error) {
+	var request = newAPIRequest(http.MethodGet, proto.AdminDataBalanceStop)
+	if _, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	return
+}
+
+func (api *AdminAPI) GetDataBalanceStatus() (status *proto.DataBalanceStatus, err error) {
+	var request = newAPIRequest(http.MethodGet, proto.AdminDataBalanceStatus)
+	var buf []byte
+	if buf, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	status = &proto.DataBalanceStatus{}
+	if err = json.Unmarshal(buf, status); err != nil {
+		return
+	}
+	return
+}
+
+func addMetaBalanceParams(request *request, config proto.MetaBalanceConfig) {
+	if config.MemThreshold > 0 {
+		request.addParam("threshold", strconv.FormatFloat(config.MemThreshold, 'f', 6, 64))
+	}
+	if config.RateThreshold > 0 {
+		request.addParam("rateThreshold", strconv.FormatFloat(config.RateThreshold, 'f', 6, 64))
+	}
+	if config.MaxConcurrency > 0 {
+		request.addParam("maxConcurrency", strconv.Itoa(config.MaxConcurrency))
+	}
+}
+
+func (api *AdminAPI) PlanMetaBalance(config proto.MetaBalanceConfig, count int) (plan *proto.MetaBalancePlan, err error) {
+	var request = newAPIRequest(http.MethodGet, proto.AdminMetaBalancePlan)
+	addMetaBalanceParams(request, config)
+	if count > 0 {
+		request.addParam("count", strconv.Itoa(count))
+	}
+	var buf []byte
+	if buf, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	plan = &proto.MetaBalancePlan{}
+	if err = json.Unmarshal(buf, plan); err != nil {
+		return
+	}
+	return
+}
+
+func (api *AdminAPI) StartMetaBalance(config proto.MetaBalanceConfig) (err error) {
+	var request = newAPIRequest(http.MethodGet, proto.AdminMetaBalanceStart)
+	addMetaBalanceParams(request, config)
+	if _, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	return
+}
+
+func (api *AdminAPI) StopMetaBalance() (err error) {
+	var request = newAPIRequest(http.MethodGet, proto.AdminMetaBalanceStop)
+	if _, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	return
+}
+
+func (api *AdminAPI) GetMetaBalanceStatus() (status *proto.MetaBalanceStatus, err error) {
+	var request = newAPIRequest(http.MethodGet, proto.AdminMetaBalanceStatus)
+	var buf []byte
+	if buf, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	status = &proto.MetaBalanceStatus{}
+	if err = json.Unmarshal(buf, status); err != nil {
+		return
+	}
+	return
+}
+
+func (api *AdminAPI) StartUpgrade(config proto.UpgradeConfig) (err error) {
+	var request = newAPIRequest(http.MethodGet, proto.AdminUpgradeStart)
+	request.addParam("role", config.Role)
+	request.addParam("version", config.Version)
+	if len(config.Nodes) > 0 {
+		request.addParam("hosts", strings.Join(config.Nodes, ","))
+	}
+	if config.TimeoutSec > 0 {
+		request.addParam("timeout", strconv.FormatInt(config.TimeoutSec, 10))
+	}
+	if _, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	return
+}
+
+func (api *AdminAPI) StopUpgrade() (err error) {
+	var request = newAPIRequest(http.MethodGet, proto.AdminUpgradeStop)
+	if _, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	return
+}
+
+func (api *AdminAPI) GetUpgradeStatus() (status *proto.UpgradeStatus, err error) {
+	var request = newAPIRequest(http.MethodGet, proto.AdminUpgradeStatus)
+	var buf []byte
+	if buf, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	status = &proto.UpgradeStatus{}
+	if err = json.Unmarshal(buf, status); err != nil {
+		return
+	}
+	return
+}
+
 func (api *AdminAPI) SetMetaNodeThreshold(threshold float64) (err error) {
 	var request = newAPIRequest(http.MethodGet, proto.AdminSetMetaNodeThreshold)
 	request.addParam("threshold", strconv.FormatFloat(threshold, 'f', 6, 64))
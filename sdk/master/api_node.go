@@ -124,3 +124,45 @@ func (api *NodeAPI) MetaNodeDecommission(nodeAddr string) (err error) {
 	}
 	return
 }
+
+func (api *NodeAPI) StartDataNodeMaintenance(nodeAddr string, durationSec int64) (err error) {
+	var request = newAPIRequest(http.MethodGet, proto.StartDataNodeMaintenance)
+	request.addParam("addr", nodeAddr)
+	if durationSec > 0 {
+		request.addParam("duration", strconv.FormatInt(durationSec, 10))
+	}
+	if _, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	return
+}
+
+func (api *NodeAPI) StopDataNodeMaintenance(nodeAddr string) (err error) {
+	var request = newAPIRequest(http.MethodGet, proto.StopDataNodeMaintenance)
+	request.addParam("addr", nodeAddr)
+	if _, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	return
+}
+
+func (api *NodeAPI) StartMetaNodeMaintenance(nodeAddr string, durationSec int64) (err error) {
+	var request = newAPIRequest(http.MethodGet, proto.StartMetaNodeMaintenance)
+	request.addParam("addr", nodeAddr)
+	if durationSec > 0 {
+		request.addParam("duration", strconv.FormatInt(durationSec, 10))
+	}
+	if _, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	return
+}
+
+func (api *NodeAPI) StopMetaNodeMaintenance(nodeAddr string) (err error) {
+	var request = newAPIRequest(http.MethodGet, proto.StopMetaNodeMaintenance)
+	request.addParam("addr", nodeAddr)
+	if _, err = api.mc.serveRequest(request); err != nil {
+		return
+	}
+	return
+}
@@ -85,6 +85,13 @@ func (mw *MetaWrapper) Statfs() (total, used uint64) {
 }
 
 func (mw *MetaWrapper) Create_ll(parentID uint64, name string, mode, uid, gid uint32, target []byte) (*proto.InodeInfo, error) {
+	if proto.IsTrashDentry(parentID, name) {
+		return nil, syscall.EPERM
+	}
+	return mw.create(parentID, name, mode, uid, gid, target)
+}
+
+func (mw *MetaWrapper) create(parentID uint64, name string, mode, uid, gid uint32, target []byte) (*proto.InodeInfo, error) {
 	var (
 		status       int
 		err          error
@@ -99,6 +106,11 @@ func (mw *MetaWrapper) Create_ll(parentID uint64, name string, mode, uid, gid ui
 		return nil, syscall.ENOENT
 	}
 
+	var quotaIds []uint64
+	if mw.hasDirQuota() {
+		quotaIds = mw.getInodeQuotaIds(parentMP, parentID)
+	}
+
 	// Create Inode
 
 	//	mp = mw.getLatestPartition()
@@ -127,10 +139,13 @@ func (mw *MetaWrapper) Create_ll(parentID uint64, name string, mode, uid, gid ui
 	for i := 0; i < length; i++ {
 		index := (int(epoch) + i) % length
 		mp = rwPartitions[index]
-		status, info, err = mw.icreate(mp, mode, uid, gid, target)
+		status, info, err = mw.icreate(mp, mode, uid, gid, target, quotaIds)
 		if err == nil && status == statusOK {
 			goto create_dentry
 		}
+		if err == nil && status == statusQuotaExceeded {
+			return nil, syscall.EDQUOT
+		}
 	}
 	return nil, syscall.ENOMEM
 
@@ -149,6 +164,9 @@ create_dentry:
 }
 
 func (mw *MetaWrapper) Lookup_ll(parentID uint64, name string) (inode uint64, mode uint32, err error) {
+	if proto.IsTrashDentry(parentID, name) {
+		return 0, 0, syscall.ENOENT
+	}
 	parentMP := mw.getPartitionByInode(parentID)
 	if parentMP == nil {
 		log.LogErrorf("Lookup_ll: No parent partition, parentID(%v) name(%v)", parentID, name)
@@ -324,6 +342,10 @@ func (mw *MetaWrapper) Delete_ll(parentID uint64, name string, isDir bool) (*pro
 		mp     *MetaPartition
 	)
 
+	if proto.IsTrashDentry(parentID, name) {
+		return nil, syscall.EPERM
+	}
+
 	parentMP := mw.getPartitionByInode(parentID)
 	if parentMP == nil {
 		log.LogErrorf("Delete_ll: No parent partition, parentID(%v) name(%v)", parentID, name)
@@ -352,13 +374,18 @@ func (mw *MetaWrapper) Delete_ll(parentID uint64, name string, isDir bool) (*pro
 		}
 	}
 
-	status, inode, err = mw.ddelete(parentMP, parentID, name)
+	var versioned bool
+	status, inode, versioned, err = mw.ddelete(parentMP, parentID, name)
 	if err != nil || status != statusOK {
 		if status == statusNoent {
 			return nil, nil
 		}
 		return nil, statusToErrno(status)
 	}
+	if versioned {
+		// the inode is kept by a version of the object
+		return nil, nil
+	}
 
 	// dentry is deleted successfully but inode is not, still returns success.
 	mp = mw.getPartitionByInode(inode)
@@ -374,9 +401,12 @@ func (mw *MetaWrapper) Delete_ll(parentID uint64, name string, isDir bool) (*pro
 	return info, nil
 }
 
+// Rename_ll renames the dentry atomically, even if the dentries and the
+// overwritten inode are in different meta partitions.
 func (mw *MetaWrapper) Rename_ll(srcParentID uint64, srcName string, dstParentID uint64, dstName string) (err error) {
-	var oldInode uint64
-
+	if proto.IsTrashDentry(srcParentID, srcName) || proto.IsTrashDentry(dstParentID, dstName) {
+		return syscall.EPERM
+	}
 	srcParentMP := mw.getPartitionByInode(srcParentID)
 	if srcParentMP == nil {
 		return syscall.ENOENT
@@ -391,67 +421,60 @@ func (mw *MetaWrapper) Rename_ll(srcParentID uint64, srcName string, dstParentID
 	if err != nil || status != statusOK {
 		return statusToErrno(status)
 	}
-	srcMP := mw.getPartitionByInode(inode)
-	if srcMP == nil {
-		return syscall.ENOENT
-	}
 
-	status, _, err = mw.ilink(srcMP, inode)
-	if err != nil || status != statusOK {
+	// look up for the dst ino to be overwritten
+	status, oldInode, _, err := mw.lookup(dstParentMP, dstParentID, dstName)
+	if err != nil || (status != statusOK && status != statusNoent) {
 		return statusToErrno(status)
 	}
-
-	// create dentry in dst parent
-	status, err = mw.dcreate(dstParentMP, dstParentID, dstName, inode, mode)
-	if err != nil {
-		return syscall.EAGAIN
+	if status == statusNoent {
+		oldInode = 0
+	} else if oldInode == inode {
+		// both are links to the same inode
+		return nil
+	} else if !proto.IsRegular(mode) {
+		// Note that only regular files are allowed to be overwritten.
+		return syscall.EEXIST
 	}
 
-	// Note that only regular files are allowed to be overwritten.
-	if status == statusExist && proto.IsRegular(mode) {
-		status, oldInode, err = mw.dupdate(dstParentMP, dstParentID, dstName, inode)
-		if err != nil {
-			return syscall.EAGAIN
+	tx := mw.newTx(dstParentMP)
+	if srcParentID != dstParentID && mw.hasDirQuota() {
+		if err = mw.addRenameQuotaItem(tx, srcParentMP, srcParentID, dstParentMP, dstParentID, inode, mode); err != nil {
+			return
 		}
 	}
-
-	if status != statusOK {
-		mw.iunlink(srcMP, inode)
-		return statusToErrno(status)
-	}
-
-	// delete dentry from src parent
-	status, _, err = mw.ddelete(srcParentMP, srcParentID, srcName)
-	if err != nil {
-		return statusToErrno(status)
-	} else if status != statusOK {
-		var (
-			sts int
-			e   error
-		)
-		if oldInode == 0 {
-			sts, _, e = mw.ddelete(dstParentMP, dstParentID, dstName)
-		} else {
-			sts, _, e = mw.dupdate(dstParentMP, dstParentID, dstName, oldInode)
-		}
-		if e == nil && sts == statusOK {
-			mw.iunlink(srcMP, inode)
+	tx.addItem(srcParentMP, &proto.TxItem{
+		Op:       proto.TxOpDeleteDentry,
+		ParentID: srcParentID,
+		Name:     srcName,
+		Inode:    inode,
+	})
+	tx.addItem(dstParentMP, &proto.TxItem{
+		Op:       proto.TxOpCreateDentry,
+		ParentID: dstParentID,
+		Name:     dstName,
+		Inode:    inode,
+		Type:     mode,
+		OldInode: oldInode,
+	})
+	var versioned bool
+	if oldInode != 0 {
+		if versioned, err = mw.versionedInode(dstParentMP, dstParentID, dstName, oldInode); err != nil {
+			return
 		}
-		return statusToErrno(status)
 	}
-
-	mw.iunlink(srcMP, inode)
-
-	if oldInode != 0 {
+	if oldInode != 0 && !versioned {
+		// unlink and evict oldInode to avoid oldInode becomes orphan inode
 		inodeMP := mw.getPartitionByInode(oldInode)
-		if inodeMP != nil {
-			mw.iunlink(inodeMP, oldInode)
-			// evict oldInode to avoid oldInode becomes orphan inode
-			mw.ievict(inodeMP, oldInode)
+		if inodeMP == nil {
+			return syscall.EAGAIN
 		}
+		tx.addItem(inodeMP, &proto.TxItem{
+			Op:    proto.TxOpUnlinkInode,
+			Inode: oldInode,
+		})
 	}
-
-	return nil
+	return mw.runTx(tx)
 }
 
 func (mw *MetaWrapper) ReadDir_ll(parentID uint64) ([]proto.Dentry, error) {
@@ -464,9 +487,36 @@ func (mw *MetaWrapper) ReadDir_ll(parentID uint64) ([]proto.Dentry, error) {
 	if err != nil || status != statusOK {
 		return nil, statusToErrno(status)
 	}
+	if parentID == proto.RootIno {
+		children = hideTrashDentry(children)
+	}
 	return children, nil
 }
 
+// ReadDirPlus_ll reads at most limit dentries of the directory, the names of which are after
+// the marker and start with the prefix, in the order of the names. The inode infos of the
+// children stored in the same meta partition as the directory are returned as well. The next
+// marker to continue reading is returned if there are more dentries, or empty otherwise.
+func (mw *MetaWrapper) ReadDirPlus_ll(parentID uint64, marker, prefix string, limit uint64) (children []proto.Dentry, infos []*proto.InodeInfo, next string, err error) {
+	parentMP := mw.getPartitionByInode(parentID)
+	if parentMP == nil {
+		return nil, nil, "", syscall.ENOENT
+	}
+
+	status, resp, err := mw.readdirplus(parentMP, parentID, marker, prefix, limit)
+	if err != nil || status != statusOK {
+		return nil, nil, "", statusToErrno(status)
+	}
+	children = resp.Children
+	if resp.More && len(children) > 0 {
+		next = children[len(children)-1].Name
+	}
+	if parentID == proto.RootIno {
+		children = hideTrashDentry(children)
+	}
+	return children, resp.Infos, next, nil
+}
+
 func (mw *MetaWrapper) DentryCreate_ll(parentID uint64, name string, inode uint64, mode uint32) error {
 	parentMP := mw.getPartitionByInode(parentID)
 	if parentMP == nil {
@@ -480,6 +530,9 @@ func (mw *MetaWrapper) DentryCreate_ll(parentID uint64, name string, inode uint6
 	return nil
 }
 
+// DentryUpdate_ll points the dentry to the inode, and returns the old inode
+// to be released by the caller, which is 0 if it is kept by a version of the
+// object.
 func (mw *MetaWrapper) DentryUpdate_ll(parentID uint64, name string, inode uint64) (oldInode uint64, err error) {
 	parentMP := mw.getPartitionByInode(parentID)
 	if parentMP == nil {
@@ -487,11 +540,15 @@ func (mw *MetaWrapper) DentryUpdate_ll(parentID uint64, name string, inode uint6
 		return
 	}
 	var status int
-	status, oldInode, err = mw.dupdate(parentMP, parentID, name, inode)
+	var versioned bool
+	status, oldInode, versioned, err = mw.dupdate(parentMP, parentID, name, inode)
 	if err != nil || status != statusOK {
 		err = statusToErrno(status)
 		return
 	}
+	if versioned {
+		oldInode = 0
+	}
 	return
 }
 
@@ -635,10 +692,13 @@ func (mw *MetaWrapper) InodeCreate_ll(mode, uid, gid uint32, target []byte) (*pr
 	for i := 0; i < length; i++ {
 		index := (int(epoch) + i) % length
 		mp = rwPartitions[index]
-		status, info, err = mw.icreate(mp, mode, uid, gid, target)
+		status, info, err = mw.icreate(mp, mode, uid, gid, target, nil)
 		if err == nil && status == statusOK {
 			return info, nil
 		}
+		if err == nil && status == statusQuotaExceeded {
+			return nil, syscall.EDQUOT
+		}
 	}
 	return nil, syscall.ENOMEM
 }
@@ -884,6 +944,49 @@ func (mw *MetaWrapper) XAttrGet_ll(inode uint64, name string) (*proto.XAttrInfo,
 	return xAttr, nil
 }
 
+// IsObjectLocked_ll returns true if the file is under a legal hold or in its
+// retention period, which can neither be changed nor be removed.
+func (mw *MetaWrapper) IsObjectLocked_ll(inode uint64) (bool, error) {
+	mp := mw.getPartitionByInode(inode)
+	if mp == nil {
+		return false, syscall.EAGAIN
+	}
+	xattrs, err := mw.batchGetXAttr(mp, []uint64{inode}, []string{proto.RetentionXAttrKey, proto.LegalHoldXAttrKey})
+	if err != nil {
+		return false, err
+	}
+	for _, xattr := range xattrs {
+		if xattr.Inode == inode && proto.IsObjectLocked(xattr.XAttrs[proto.RetentionXAttrKey],
+			xattr.XAttrs[proto.LegalHoldXAttrKey], time.Now()) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// DirSummary_ll returns the summary of the directory maintained by the meta
+// node, which returns ENODATA if the summary has not been counted yet.
+func (mw *MetaWrapper) DirSummary_ll(inode uint64) (*proto.DirSummary, error) {
+	mp := mw.getPartitionByInode(inode)
+	if mp == nil {
+		log.LogErrorf("DirSummary_ll: no such partition, ino(%v)", inode)
+		return nil, syscall.ENOENT
+	}
+	value, status, err := mw.getXAttr(mp, inode, proto.SummaryXAttrKey)
+	if err != nil || status != statusOK {
+		return nil, statusToErrno(status)
+	}
+	if len(value) == 0 {
+		return nil, syscall.ENODATA
+	}
+	summary, err := proto.ParseDirSummary([]byte(value))
+	if err != nil {
+		log.LogErrorf("DirSummary_ll: ino(%v) value(%v) err(%v)", inode, value, err)
+		return nil, syscall.EIO
+	}
+	return summary, nil
+}
+
 // XAttrDel_ll is a low-level meta api that deletes specified xattr.
 func (mw *MetaWrapper) XAttrDel_ll(inode uint64, name string) error {
 	var err error
@@ -15,8 +15,10 @@
 package meta
 
 import (
+	"encoding/json"
 	"fmt"
 	"net"
+	"strconv"
 	"syscall"
 	"time"
 
@@ -70,6 +72,12 @@ func (mw *MetaWrapper) sendToMetaPartition(mp *MetaPartition, req *proto.Packet)
 	errs := make(map[int]error, len(mp.Members))
 	var j int
 
+	if mw.snapshotID != 0 && !isSnapshotReadOp(req.Opcode) {
+		log.LogWarnf("sendToMetaPartition: refuse to change snapshot(%v), req(%v)", mw.snapshot, req)
+		req.ResultCode = proto.OpNotPerm
+		return req, nil
+	}
+
 	addr = mp.LeaderAddr
 	if addr == "" {
 		err = errors.New(fmt.Sprintf("sendToMetaPartition failed: leader addr empty, req(%v) mp(%v)", req, mp))
@@ -119,10 +127,42 @@ out:
 	if err != nil || resp == nil {
 		return nil, errors.New(fmt.Sprintf("sendToMetaPartition failed: req(%v) mp(%v) errs(%v) resp(%v)", req, mp, errs, resp))
 	}
+	if resp.ResultCode == proto.OpInodeOutOfRange {
+		return mw.redirectToMetaPartition(mp, req, resp)
+	}
 	log.LogDebugf("sendToMetaPartition successful: req(%v) mc(%v) resp(%v)", req, mc, resp)
 	return resp, nil
 }
 
+// redirectToMetaPartition refreshes the view of the meta partitions, and sends the request again
+// to the partition serving the inode, since the request is rejected by the partition the range of
+// which has been shrunk by a merge or a re-split. The rejection is returned as is if the inode is
+// still served by the same partition in the refreshed view.
+func (mw *MetaWrapper) redirectToMetaPartition(mp *MetaPartition, req, resp *proto.Packet) (*proto.Packet, error) {
+	mw.triggerAndWaitForceUpdate()
+	ino, err := strconv.ParseUint(string(resp.Data), 10, 64)
+	if err != nil {
+		return resp, nil
+	}
+	target := mw.getPartitionByInode(ino)
+	if target == nil || target.PartitionID == mp.PartitionID {
+		log.LogWarnf("redirectToMetaPartition: no other partition: req(%v) mp(%v) ino(%v)", req, mp, ino)
+		return resp, nil
+	}
+	// the partition ID is carried by both the packet and the request
+	var body map[string]json.RawMessage
+	if err = json.Unmarshal(req.Data, &body); err != nil {
+		return resp, nil
+	}
+	body["pid"] = json.RawMessage(strconv.FormatUint(target.PartitionID, 10))
+	if err = req.MarshalData(body); err != nil {
+		return resp, nil
+	}
+	req.PartitionID = target.PartitionID
+	log.LogInfof("redirectToMetaPartition: req(%v) from mp(%v) to mp(%v) ino(%v)", req, mp, target, ino)
+	return mw.sendToMetaPartition(target, req)
+}
+
 func (mc *MetaConn) send(req *proto.Packet) (resp *proto.Packet, err error) {
 	err = req.WriteToConn(mc.conn)
 	if err != nil {
@@ -141,3 +181,14 @@ func (mc *MetaConn) send(req *proto.Packet) (resp *proto.Packet, err error) {
 	}
 	return resp, nil
 }
+
+// isSnapshotReadOp returns true if the operation is served by a snapshot.
+func isSnapshotReadOp(opcode uint8) bool {
+	switch opcode {
+	case proto.OpMetaLookup, proto.OpMetaInodeGet, proto.OpMetaBatchInodeGet, proto.OpMetaReadDir,
+		proto.OpMetaReadDirPlus, proto.OpMetaExtentsList, proto.OpMetaGetXAttr, proto.OpMetaBatchGetXAttr,
+		proto.OpMetaListXAttr:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,148 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package meta
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+const (
+	// LockLease is the lease in seconds of the file locks held by a client.
+	// Locks are released by the meta nodes if not renewed within the lease,
+	// e.g. the client crashed.
+	LockLease         = 30
+	LockRenewInterval = 10 * time.Second
+)
+
+// lockHolder tracks the meta partitions in which the client holds file locks,
+// and keeps the leases of them.
+type lockHolder struct {
+	sync.Mutex
+	once   sync.Once
+	client string
+	// partition ID -> sequence of the latest lock request
+	partitions map[uint64]uint64
+	seq        uint64
+}
+
+func (mw *MetaWrapper) lockClient() string {
+	mw.locks.once.Do(func() {
+		mw.locks.client = fmt.Sprintf("%v:%v:%v", mw.localIP, os.Getpid(), time.Now().UnixNano())
+		mw.locks.partitions = make(map[uint64]uint64)
+		go mw.renewLocks()
+	})
+	return mw.locks.client
+}
+
+func (mw *MetaWrapper) holdLock(pid uint64) {
+	mw.locks.Lock()
+	mw.locks.seq++
+	mw.locks.partitions[pid] = mw.locks.seq
+	mw.locks.Unlock()
+}
+
+func (mw *MetaWrapper) holdsLock(pid uint64) bool {
+	mw.locks.Lock()
+	defer mw.locks.Unlock()
+	_, ok := mw.locks.partitions[pid]
+	return ok
+}
+
+func (mw *MetaWrapper) renewLocks() {
+	t := time.NewTicker(LockRenewInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			mw.locks.Lock()
+			partitions := make(map[uint64]uint64, len(mw.locks.partitions))
+			for pid, seq := range mw.locks.partitions {
+				partitions[pid] = seq
+			}
+			mw.locks.Unlock()
+
+			for pid, seq := range partitions {
+				mp := mw.getPartitionByID(pid)
+				if mp == nil {
+					continue
+				}
+				count, status, err := mw.renewLock(mp, mw.locks.client)
+				if err != nil || status != statusOK {
+					log.LogWarnf("renewLocks: mp(%v) status(%v) err(%v)", mp, status, err)
+					continue
+				}
+				if count > 0 {
+					continue
+				}
+				// no lock is held in the partition anymore
+				mw.locks.Lock()
+				if mw.locks.partitions[pid] == seq {
+					delete(mw.locks.partitions, pid)
+				}
+				mw.locks.Unlock()
+			}
+		case <-mw.closeCh:
+			return
+		}
+	}
+}
+
+// SetLock_ll acquires or releases a file lock on the inode without waiting.
+// It returns EAGAIN if a conflicting lock is held by others.
+func (mw *MetaWrapper) SetLock_ll(inode uint64, lock *proto.LockInfo) error {
+	mp := mw.getPartitionByInode(inode)
+	if mp == nil {
+		log.LogErrorf("SetLock_ll: no such partition, inode(%v)", inode)
+		return syscall.ENOENT
+	}
+	lock.Client = mw.lockClient()
+	if lock.Type == proto.LockUnlock && !mw.holdsLock(mp.PartitionID) {
+		// nothing to release
+		return nil
+	}
+	status, err := mw.setLock(mp, inode, lock)
+	if err != nil || status != statusOK {
+		return statusToErrno(status)
+	}
+	if lock.Type != proto.LockUnlock {
+		mw.holdLock(mp.PartitionID)
+	}
+	log.LogDebugf("SetLock_ll: volume(%v) inode(%v) lock(%v)", mw.volname, inode, lock)
+	return nil
+}
+
+// GetLock_ll returns the first lock which conflicts with the given one, or a
+// lock of type LockUnlock if there is none.
+func (mw *MetaWrapper) GetLock_ll(inode uint64, lock *proto.LockInfo) (*proto.LockInfo, error) {
+	mp := mw.getPartitionByInode(inode)
+	if mp == nil {
+		log.LogErrorf("GetLock_ll: no such partition, inode(%v)", inode)
+		return nil, syscall.ENOENT
+	}
+	lock.Client = mw.lockClient()
+	conflict, status, err := mw.getLock(mp, inode, lock)
+	if err != nil || status != statusOK {
+		return nil, statusToErrno(status)
+	}
+	log.LogDebugf("GetLock_ll: volume(%v) inode(%v) lock(%v) conflict(%v)", mw.volname, inode, lock, conflict)
+	return conflict, nil
+}
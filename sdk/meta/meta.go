@@ -47,6 +47,9 @@ const (
 	statusInval
 	statusNotPerm
 	statusConflictExtents
+	statusLockConflict
+	statusTxAborted
+	statusQuotaExceeded
 )
 
 const (
@@ -76,6 +79,8 @@ type MetaConfig struct {
 	TicketMess       auth.TicketMess
 	ValidateOwner    bool
 	OnAsyncTaskError AsyncTaskErrorFunc
+	// Snapshot is the name of the snapshot to read, the volume is read-only if set.
+	Snapshot string
 }
 
 type MetaWrapper struct {
@@ -110,6 +115,15 @@ type MetaWrapper struct {
 	totalSize uint64
 	usedSize  uint64
 
+	// The number of directory quotas of the volume, the new inodes belong to
+	// the directory quotas of their parents if set.
+	dirQuotaCnt int32
+
+	// Days to keep the deleted files in the trash, 0 means the trash is
+	// disabled, and trashIno caches the inode of the trash directory.
+	trashDays uint32
+	trashIno  uint64
+
 	authenticate bool
 	Ticket       auth.Ticket
 	accessToken  proto.APIAccessReq
@@ -126,6 +140,14 @@ type MetaWrapper struct {
 	// Used to trigger and throttle instant partition updates
 	forceUpdate      chan struct{}
 	forceUpdateLimit *rate.Limiter
+
+	// File locks held by this client
+	locks lockHolder
+
+	// The snapshot read by this client, all of the operations changing
+	// metadata are refused if set.
+	snapshot   string
+	snapshotID uint64
 }
 
 //the ticket from authnode
@@ -159,6 +181,7 @@ func NewMetaWrapper(config *MetaConfig) (*MetaWrapper, error) {
 	mw.volname = config.Volume
 	mw.owner = config.Owner
 	mw.ownerValidation = config.ValidateOwner
+	mw.snapshot = config.Snapshot
 	mw.mc = masterSDK.NewMasterClient(config.Masters, false)
 	mw.onAsyncTaskError = config.OnAsyncTaskError
 	mw.conns = util.NewConnectPool()
@@ -175,7 +198,7 @@ func NewMetaWrapper(config *MetaConfig) (*MetaWrapper, error) {
 		err = mw.initMetaWrapper()
 		// When initializing the volume, if the master explicitly responds that the specified
 		// volume does not exist, it will not retry.
-		if err == proto.ErrVolNotExists {
+		if err == proto.ErrVolNotExists || err == proto.ErrSnapshotNotExists {
 			return nil, err
 		}
 		if err != nil {
@@ -207,6 +230,10 @@ func (mw *MetaWrapper) initMetaWrapper() (err error) {
 		return err
 	}
 
+	if err = mw.updateSnapshotID(); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -253,7 +280,7 @@ func parseStatus(result uint8) (status int) {
 		status = statusNoent
 	case proto.OpInodeFullErr:
 		status = statusFull
-	case proto.OpAgain:
+	case proto.OpAgain, proto.OpInodeOutOfRange:
 		status = statusAgain
 	case proto.OpArgMismatchErr:
 		status = statusInval
@@ -261,6 +288,12 @@ func parseStatus(result uint8) (status int) {
 		status = statusNotPerm
 	case proto.OpConflictExtentsErr:
 		status = statusConflictExtents
+	case proto.OpLockConflictErr:
+		status = statusLockConflict
+	case proto.OpTxAbortedErr:
+		status = statusTxAborted
+	case proto.OpQuotaExceededErr:
+		status = statusQuotaExceeded
 	default:
 		status = statusError
 	}
@@ -288,6 +321,12 @@ func statusToErrno(status int) error {
 		return syscall.EAGAIN
 	case statusConflictExtents:
 		return syscall.EIO
+	case statusLockConflict:
+		return syscall.EAGAIN
+	case statusTxAborted:
+		return syscall.EAGAIN
+	case statusQuotaExceeded:
+		return syscall.EDQUOT
 	default:
 	}
 	return syscall.EIO
@@ -28,7 +28,7 @@ import (
 // API implementations
 //
 
-func (mw *MetaWrapper) icreate(mp *MetaPartition, mode, uid, gid uint32, target []byte) (status int, info *proto.InodeInfo, err error) {
+func (mw *MetaWrapper) icreate(mp *MetaPartition, mode, uid, gid uint32, target []byte, quotaIds []uint64) (status int, info *proto.InodeInfo, err error) {
 	req := &proto.CreateInodeRequest{
 		VolName:     mw.volname,
 		PartitionID: mp.PartitionID,
@@ -36,6 +36,7 @@ func (mw *MetaWrapper) icreate(mp *MetaPartition, mode, uid, gid uint32, target
 		Uid:         uid,
 		Gid:         gid,
 		Target:      target,
+		QuotaIds:    quotaIds,
 	}
 
 	packet := proto.NewPacketReqID()
@@ -204,9 +205,9 @@ func (mw *MetaWrapper) dcreate(mp *MetaPartition, parentID uint64, name string,
 	return
 }
 
-func (mw *MetaWrapper) dupdate(mp *MetaPartition, parentID uint64, name string, newInode uint64) (status int, oldInode uint64, err error) {
+func (mw *MetaWrapper) dupdate(mp *MetaPartition, parentID uint64, name string, newInode uint64) (status int, oldInode uint64, versioned bool, err error) {
 	if parentID == newInode {
-		return statusExist, 0, nil
+		return statusExist, 0, false, nil
 	}
 
 	req := &proto.UpdateDentryRequest{
@@ -250,10 +251,10 @@ func (mw *MetaWrapper) dupdate(mp *MetaPartition, parentID uint64, name string,
 		return
 	}
 	log.LogDebugf("dupdate: packet(%v) mp(%v) req(%v) oldIno(%v)", packet, mp, *req, resp.Inode)
-	return statusOK, resp.Inode, nil
+	return statusOK, resp.Inode, resp.Versioned, nil
 }
 
-func (mw *MetaWrapper) ddelete(mp *MetaPartition, parentID uint64, name string) (status int, inode uint64, err error) {
+func (mw *MetaWrapper) ddelete(mp *MetaPartition, parentID uint64, name string) (status int, inode uint64, versioned bool, err error) {
 	req := &proto.DeleteDentryRequest{
 		VolName:     mw.volname,
 		PartitionID: mp.PartitionID,
@@ -294,13 +295,14 @@ func (mw *MetaWrapper) ddelete(mp *MetaPartition, parentID uint64, name string)
 		return
 	}
 	log.LogDebugf("ddelete: packet(%v) mp(%v) req(%v) ino(%v)", packet, mp, *req, resp.Inode)
-	return statusOK, resp.Inode, nil
+	return statusOK, resp.Inode, resp.Versioned, nil
 }
 
 func (mw *MetaWrapper) lookup(mp *MetaPartition, parentID uint64, name string) (status int, inode uint64, mode uint32, err error) {
 	req := &proto.LookupRequest{
 		VolName:     mw.volname,
 		PartitionID: mp.PartitionID,
+		SnapshotID:  mw.snapshotID,
 		ParentID:    parentID,
 		Name:        name,
 	}
@@ -350,6 +352,7 @@ func (mw *MetaWrapper) iget(mp *MetaPartition, inode uint64) (status int, info *
 	req := &proto.InodeGetRequest{
 		VolName:     mw.volname,
 		PartitionID: mp.PartitionID,
+		SnapshotID:  mw.snapshotID,
 		Inode:       inode,
 	}
 
@@ -396,6 +399,7 @@ func (mw *MetaWrapper) batchIget(wg *sync.WaitGroup, mp *MetaPartition, inodes [
 	req := &proto.BatchInodeGetRequest{
 		VolName:     mw.volname,
 		PartitionID: mp.PartitionID,
+		SnapshotID:  mw.snapshotID,
 		Inodes:      inodes,
 	}
 
@@ -445,6 +449,7 @@ func (mw *MetaWrapper) readdir(mp *MetaPartition, parentID uint64) (status int,
 	req := &proto.ReadDirRequest{
 		VolName:     mw.volname,
 		PartitionID: mp.PartitionID,
+		SnapshotID:  mw.snapshotID,
 		ParentID:    parentID,
 	}
 
@@ -485,6 +490,53 @@ func (mw *MetaWrapper) readdir(mp *MetaPartition, parentID uint64) (status int,
 	return statusOK, resp.Children, nil
 }
 
+func (mw *MetaWrapper) readdirplus(mp *MetaPartition, parentID uint64, marker, prefix string, limit uint64) (status int, resp *proto.ReadDirPlusResponse, err error) {
+	req := &proto.ReadDirPlusRequest{
+		VolName:     mw.volname,
+		PartitionID: mp.PartitionID,
+		SnapshotID:  mw.snapshotID,
+		ParentID:    parentID,
+		Marker:      marker,
+		Prefix:      prefix,
+		Limit:       limit,
+	}
+
+	packet := proto.NewPacketReqID()
+	packet.Opcode = proto.OpMetaReadDirPlus
+	packet.PartitionID = mp.PartitionID
+	err = packet.MarshalData(req)
+	if err != nil {
+		log.LogErrorf("readdirplus: req(%v) err(%v)", *req, err)
+		return
+	}
+
+	metric := exporter.NewTPCnt(packet.GetOpMsg())
+	defer func() {
+		metric.SetWithLabels(err, map[string]string{exporter.Vol: mw.volname})
+	}()
+
+	packet, err = mw.sendToMetaPartition(mp, packet)
+	if err != nil {
+		log.LogErrorf("readdirplus: packet(%v) mp(%v) req(%v) err(%v)", packet, mp, *req, err)
+		return
+	}
+
+	status = parseStatus(packet.ResultCode)
+	if status != statusOK {
+		log.LogErrorf("readdirplus: packet(%v) mp(%v) req(%v) result(%v)", packet, mp, *req, packet.GetResultMsg())
+		return
+	}
+
+	resp = new(proto.ReadDirPlusResponse)
+	err = packet.UnmarshalData(resp)
+	if err != nil {
+		log.LogErrorf("readdirplus: packet(%v) mp(%v) err(%v) PacketData(%v)", packet, mp, err, string(packet.Data))
+		return
+	}
+	log.LogDebugf("readdirplus: packet(%v) mp(%v) req(%v) children(%v) more(%v)", packet, mp, *req, len(resp.Children), resp.More)
+	return statusOK, resp, nil
+}
+
 func (mw *MetaWrapper) appendExtentKey(mp *MetaPartition, inode uint64, extent proto.ExtentKey, discard []proto.ExtentKey) (status int, err error) {
 	req := &proto.AppendExtentKeyWithCheckRequest{
 		VolName:        mw.volname,
@@ -525,6 +577,7 @@ func (mw *MetaWrapper) getExtents(mp *MetaPartition, inode uint64) (status int,
 	req := &proto.GetExtentsRequest{
 		VolName:     mw.volname,
 		PartitionID: mp.PartitionID,
+		SnapshotID:  mw.snapshotID,
 		Inode:       inode,
 	}
 
@@ -992,6 +1045,7 @@ func (mw *MetaWrapper) getXAttr(mp *MetaPartition, inode uint64, name string) (v
 	req := &proto.GetXAttrRequest{
 		VolName:     mw.volname,
 		PartitionId: mp.PartitionID,
+		SnapshotID:  mw.snapshotID,
 		Inode:       inode,
 		Key:         name,
 	}
@@ -1075,6 +1129,7 @@ func (mw *MetaWrapper) listXAttr(mp *MetaPartition, inode uint64) (keys []string
 	req := &proto.ListXAttrRequest{
 		VolName:     mw.volname,
 		PartitionId: mp.PartitionID,
+		SnapshotID:  mw.snapshotID,
 		Inode:       inode,
 	}
 
@@ -1170,6 +1225,7 @@ func (mw *MetaWrapper) batchGetXAttr(mp *MetaPartition, inodes []uint64, keys []
 	req := &proto.BatchGetXAttrRequest{
 		VolName:     mw.volname,
 		PartitionId: mp.PartitionID,
+		SnapshotID:  mw.snapshotID,
 		Inodes:      inodes,
 		Keys:        keys,
 	}
@@ -1207,3 +1263,430 @@ func (mw *MetaWrapper) batchGetXAttr(mp *MetaPartition, inodes []uint64, keys []
 
 	return resp.XAttrs, nil
 }
+
+func (mw *MetaWrapper) setLock(mp *MetaPartition, inode uint64, lock *proto.LockInfo) (status int, err error) {
+	req := &proto.SetLockRequest{
+		VolName:     mw.volname,
+		PartitionId: mp.PartitionID,
+		Inode:       inode,
+		Lock:        *lock,
+		Lease:       LockLease,
+	}
+
+	packet := proto.NewPacketReqID()
+	packet.Opcode = proto.OpMetaSetLock
+	packet.PartitionID = mp.PartitionID
+	err = packet.MarshalData(req)
+	if err != nil {
+		log.LogErrorf("setLock: req(%v) err(%v)", *req, err)
+		return
+	}
+
+	metric := exporter.NewTPCnt(packet.GetOpMsg())
+	defer func() {
+		metric.SetWithLabels(err, map[string]string{exporter.Vol: mw.volname})
+	}()
+
+	packet, err = mw.sendToMetaPartition(mp, packet)
+	if err != nil {
+		log.LogErrorf("setLock: packet(%v) mp(%v) req(%v) err(%v)", packet, mp, *req, err)
+		return
+	}
+
+	status = parseStatus(packet.ResultCode)
+	if status == statusLockConflict {
+		log.LogDebugf("setLock: conflict, packet(%v) mp(%v) req(%v)", packet, mp, *req)
+		return
+	}
+	if status != statusOK {
+		log.LogErrorf("setLock: packet(%v) mp(%v) req(%v) result(%v)", packet, mp, *req, packet.GetResultMsg())
+		return
+	}
+
+	log.LogDebugf("setLock: packet(%v) mp(%v) req(%v) result(%v)", packet, mp, *req, packet.GetResultMsg())
+	return
+}
+
+func (mw *MetaWrapper) getLock(mp *MetaPartition, inode uint64, lock *proto.LockInfo) (conflict *proto.LockInfo, status int, err error) {
+	req := &proto.GetLockRequest{
+		VolName:     mw.volname,
+		PartitionId: mp.PartitionID,
+		Inode:       inode,
+		Lock:        *lock,
+	}
+
+	packet := proto.NewPacketReqID()
+	packet.Opcode = proto.OpMetaGetLock
+	packet.PartitionID = mp.PartitionID
+	err = packet.MarshalData(req)
+	if err != nil {
+		log.LogErrorf("getLock: req(%v) err(%v)", *req, err)
+		return
+	}
+
+	metric := exporter.NewTPCnt(packet.GetOpMsg())
+	defer func() {
+		metric.SetWithLabels(err, map[string]string{exporter.Vol: mw.volname})
+	}()
+
+	packet, err = mw.sendToMetaPartition(mp, packet)
+	if err != nil {
+		log.LogErrorf("getLock: packet(%v) mp(%v) req(%v) err(%v)", packet, mp, *req, err)
+		return
+	}
+
+	status = parseStatus(packet.ResultCode)
+	if status != statusOK {
+		log.LogErrorf("getLock: packet(%v) mp(%v) req(%v) result(%v)", packet, mp, *req, packet.GetResultMsg())
+		return
+	}
+
+	resp := new(proto.GetLockResponse)
+	if err = packet.UnmarshalData(resp); err != nil {
+		log.LogErrorf("getLock: packet(%v) mp(%v) req(%v) err(%v) PacketData(%v)", packet, mp, *req, err, string(packet.Data))
+		return
+	}
+	conflict = &resp.Lock
+
+	log.LogDebugf("getLock: packet(%v) mp(%v) req(%v) result(%v)", packet, mp, *req, packet.GetResultMsg())
+	return
+}
+
+func (mw *MetaWrapper) renewLock(mp *MetaPartition, client string) (count uint32, status int, err error) {
+	req := &proto.RenewLockRequest{
+		VolName:     mw.volname,
+		PartitionId: mp.PartitionID,
+		Client:      client,
+		Lease:       LockLease,
+	}
+
+	packet := proto.NewPacketReqID()
+	packet.Opcode = proto.OpMetaRenewLock
+	packet.PartitionID = mp.PartitionID
+	err = packet.MarshalData(req)
+	if err != nil {
+		log.LogErrorf("renewLock: req(%v) err(%v)", *req, err)
+		return
+	}
+
+	metric := exporter.NewTPCnt(packet.GetOpMsg())
+	defer func() {
+		metric.SetWithLabels(err, map[string]string{exporter.Vol: mw.volname})
+	}()
+
+	packet, err = mw.sendToMetaPartition(mp, packet)
+	if err != nil {
+		log.LogErrorf("renewLock: packet(%v) mp(%v) req(%v) err(%v)", packet, mp, *req, err)
+		return
+	}
+
+	status = parseStatus(packet.ResultCode)
+	if status != statusOK {
+		log.LogErrorf("renewLock: packet(%v) mp(%v) req(%v) result(%v)", packet, mp, *req, packet.GetResultMsg())
+		return
+	}
+
+	resp := new(proto.RenewLockResponse)
+	if err = packet.UnmarshalData(resp); err != nil {
+		log.LogErrorf("renewLock: packet(%v) mp(%v) req(%v) err(%v) PacketData(%v)", packet, mp, *req, err, string(packet.Data))
+		return
+	}
+	count = resp.Count
+
+	log.LogDebugf("renewLock: packet(%v) mp(%v) req(%v) count(%v)", packet, mp, *req, count)
+	return
+}
+
+func (mw *MetaWrapper) txPrepare(mp *MetaPartition, tx *proto.TxInfo) (status int, err error) {
+	req := &proto.TxPrepareRequest{
+		VolName:     mw.volname,
+		PartitionId: mp.PartitionID,
+		Tx:          tx,
+	}
+
+	packet := proto.NewPacketReqID()
+	packet.Opcode = proto.OpMetaTxPrepare
+	packet.PartitionID = mp.PartitionID
+	err = packet.MarshalData(req)
+	if err != nil {
+		log.LogErrorf("txPrepare: req(%v) err(%v)", *req, err)
+		return
+	}
+
+	metric := exporter.NewTPCnt(packet.GetOpMsg())
+	defer func() {
+		metric.SetWithLabels(err, map[string]string{exporter.Vol: mw.volname})
+	}()
+
+	packet, err = mw.sendToMetaPartition(mp, packet)
+	if err != nil {
+		log.LogErrorf("txPrepare: packet(%v) mp(%v) req(%v) err(%v)", packet, mp, *req, err)
+		return
+	}
+
+	status = parseStatus(packet.ResultCode)
+	if status != statusOK {
+		log.LogErrorf("txPrepare: packet(%v) mp(%v) req(%v) result(%v)", packet, mp, *req, packet.GetResultMsg())
+		return
+	}
+
+	log.LogDebugf("txPrepare: packet(%v) mp(%v) req(%v)", packet, mp, *req)
+	return
+}
+
+func (mw *MetaWrapper) txCommit(mp *MetaPartition, tx *proto.TxInfo) (status int, err error) {
+	req := &proto.TxCommitRequest{
+		VolName:     mw.volname,
+		PartitionId: mp.PartitionID,
+		TxID:        tx.TxID,
+		TmID:        tx.TmID,
+	}
+
+	packet := proto.NewPacketReqID()
+	packet.Opcode = proto.OpMetaTxCommit
+	packet.PartitionID = mp.PartitionID
+	err = packet.MarshalData(req)
+	if err != nil {
+		log.LogErrorf("txCommit: req(%v) err(%v)", *req, err)
+		return
+	}
+
+	metric := exporter.NewTPCnt(packet.GetOpMsg())
+	defer func() {
+		metric.SetWithLabels(err, map[string]string{exporter.Vol: mw.volname})
+	}()
+
+	packet, err = mw.sendToMetaPartition(mp, packet)
+	if err != nil {
+		log.LogErrorf("txCommit: packet(%v) mp(%v) req(%v) err(%v)", packet, mp, *req, err)
+		return
+	}
+
+	status = parseStatus(packet.ResultCode)
+	if status != statusOK {
+		log.LogErrorf("txCommit: packet(%v) mp(%v) req(%v) result(%v)", packet, mp, *req, packet.GetResultMsg())
+		return
+	}
+
+	log.LogDebugf("txCommit: packet(%v) mp(%v) req(%v)", packet, mp, *req)
+	return
+}
+
+func (mw *MetaWrapper) txAbort(mp *MetaPartition, tx *proto.TxInfo) (status int, err error) {
+	req := &proto.TxAbortRequest{
+		VolName:     mw.volname,
+		PartitionId: mp.PartitionID,
+		TxID:        tx.TxID,
+		TmID:        tx.TmID,
+	}
+
+	packet := proto.NewPacketReqID()
+	packet.Opcode = proto.OpMetaTxAbort
+	packet.PartitionID = mp.PartitionID
+	err = packet.MarshalData(req)
+	if err != nil {
+		log.LogErrorf("txAbort: req(%v) err(%v)", *req, err)
+		return
+	}
+
+	metric := exporter.NewTPCnt(packet.GetOpMsg())
+	defer func() {
+		metric.SetWithLabels(err, map[string]string{exporter.Vol: mw.volname})
+	}()
+
+	packet, err = mw.sendToMetaPartition(mp, packet)
+	if err != nil {
+		log.LogErrorf("txAbort: packet(%v) mp(%v) req(%v) err(%v)", packet, mp, *req, err)
+		return
+	}
+
+	status = parseStatus(packet.ResultCode)
+	if status != statusOK {
+		log.LogErrorf("txAbort: packet(%v) mp(%v) req(%v) result(%v)", packet, mp, *req, packet.GetResultMsg())
+		return
+	}
+
+	log.LogDebugf("txAbort: packet(%v) mp(%v) req(%v)", packet, mp, *req)
+	return
+}
+
+func (mw *MetaWrapper) putObjectVersion(mp *MetaPartition, version *proto.ObjectVersionInfo) (replaced *proto.ObjectVersionInfo, status int, err error) {
+	req := &proto.PutObjectVersionRequest{
+		VolName:     mw.volname,
+		PartitionId: mp.PartitionID,
+		Version:     version,
+	}
+
+	packet := proto.NewPacketReqID()
+	packet.Opcode = proto.OpPutObjectVersion
+	packet.PartitionID = mp.PartitionID
+	err = packet.MarshalData(req)
+	if err != nil {
+		log.LogErrorf("putObjectVersion: req(%v) err(%v)", *req, err)
+		return
+	}
+
+	metric := exporter.NewTPCnt(packet.GetOpMsg())
+	defer func() {
+		metric.SetWithLabels(err, map[string]string{exporter.Vol: mw.volname})
+	}()
+
+	packet, err = mw.sendToMetaPartition(mp, packet)
+	if err != nil {
+		log.LogErrorf("putObjectVersion: packet(%v) mp(%v) req(%v) err(%v)", packet, mp, *req, err)
+		return
+	}
+
+	status = parseStatus(packet.ResultCode)
+	if status != statusOK {
+		log.LogErrorf("putObjectVersion: packet(%v) mp(%v) req(%v) result(%v)", packet, mp, *req, packet.GetResultMsg())
+		return
+	}
+
+	resp := new(proto.PutObjectVersionResponse)
+	if err = packet.UnmarshalData(resp); err != nil {
+		log.LogErrorf("putObjectVersion: packet(%v) mp(%v) req(%v) err(%v) PacketData(%v)", packet, mp, *req, err, string(packet.Data))
+		return
+	}
+	replaced = resp.Replaced
+
+	log.LogDebugf("putObjectVersion: packet(%v) mp(%v) req(%v) result(%v)", packet, mp, *req, packet.GetResultMsg())
+	return
+}
+
+func (mw *MetaWrapper) deleteObjectVersion(mp *MetaPartition, parentID uint64, name, versionID string) (deleted *proto.ObjectVersionInfo, status int, err error) {
+	req := &proto.DeleteObjectVersionRequest{
+		VolName:     mw.volname,
+		PartitionId: mp.PartitionID,
+		ParentID:    parentID,
+		Name:        name,
+		VersionID:   versionID,
+	}
+
+	packet := proto.NewPacketReqID()
+	packet.Opcode = proto.OpDeleteObjectVersion
+	packet.PartitionID = mp.PartitionID
+	err = packet.MarshalData(req)
+	if err != nil {
+		log.LogErrorf("deleteObjectVersion: req(%v) err(%v)", *req, err)
+		return
+	}
+
+	metric := exporter.NewTPCnt(packet.GetOpMsg())
+	defer func() {
+		metric.SetWithLabels(err, map[string]string{exporter.Vol: mw.volname})
+	}()
+
+	packet, err = mw.sendToMetaPartition(mp, packet)
+	if err != nil {
+		log.LogErrorf("deleteObjectVersion: packet(%v) mp(%v) req(%v) err(%v)", packet, mp, *req, err)
+		return
+	}
+
+	status = parseStatus(packet.ResultCode)
+	if status != statusOK {
+		log.LogErrorf("deleteObjectVersion: packet(%v) mp(%v) req(%v) result(%v)", packet, mp, *req, packet.GetResultMsg())
+		return
+	}
+
+	resp := new(proto.DeleteObjectVersionResponse)
+	if err = packet.UnmarshalData(resp); err != nil {
+		log.LogErrorf("deleteObjectVersion: packet(%v) mp(%v) req(%v) err(%v) PacketData(%v)", packet, mp, *req, err, string(packet.Data))
+		return
+	}
+	deleted = resp.Deleted
+
+	log.LogDebugf("deleteObjectVersion: packet(%v) mp(%v) req(%v) result(%v)", packet, mp, *req, packet.GetResultMsg())
+	return
+}
+
+func (mw *MetaWrapper) getObjectVersions(mp *MetaPartition, parentID uint64, name string) (versions []*proto.ObjectVersionInfo, status int, err error) {
+	req := &proto.GetObjectVersionsRequest{
+		VolName:     mw.volname,
+		PartitionId: mp.PartitionID,
+		ParentID:    parentID,
+		Name:        name,
+	}
+
+	packet := proto.NewPacketReqID()
+	packet.Opcode = proto.OpGetObjectVersions
+	packet.PartitionID = mp.PartitionID
+	err = packet.MarshalData(req)
+	if err != nil {
+		log.LogErrorf("getObjectVersions: req(%v) err(%v)", *req, err)
+		return
+	}
+
+	metric := exporter.NewTPCnt(packet.GetOpMsg())
+	defer func() {
+		metric.SetWithLabels(err, map[string]string{exporter.Vol: mw.volname})
+	}()
+
+	packet, err = mw.sendToMetaPartition(mp, packet)
+	if err != nil {
+		log.LogErrorf("getObjectVersions: packet(%v) mp(%v) req(%v) err(%v)", packet, mp, *req, err)
+		return
+	}
+
+	status = parseStatus(packet.ResultCode)
+	if status != statusOK {
+		log.LogErrorf("getObjectVersions: packet(%v) mp(%v) req(%v) result(%v)", packet, mp, *req, packet.GetResultMsg())
+		return
+	}
+
+	resp := new(proto.GetObjectVersionsResponse)
+	if err = packet.UnmarshalData(resp); err != nil {
+		log.LogErrorf("getObjectVersions: packet(%v) mp(%v) req(%v) err(%v) PacketData(%v)", packet, mp, *req, err, string(packet.Data))
+		return
+	}
+	versions = resp.Versions
+
+	log.LogDebugf("getObjectVersions: packet(%v) mp(%v) req(%v) result(%v)", packet, mp, *req, packet.GetResultMsg())
+	return
+}
+
+func (mw *MetaWrapper) listObjectVersions(mp *MetaPartition, parentID uint64, marker string, max uint64) (versions []*proto.ObjectVersionInfo, status int, err error) {
+	req := &proto.ListObjectVersionsRequest{
+		VolName:     mw.volname,
+		PartitionId: mp.PartitionID,
+		ParentID:    parentID,
+		Marker:      marker,
+		Max:         max,
+	}
+
+	packet := proto.NewPacketReqID()
+	packet.Opcode = proto.OpListObjectVersions
+	packet.PartitionID = mp.PartitionID
+	err = packet.MarshalData(req)
+	if err != nil {
+		log.LogErrorf("listObjectVersions: req(%v) err(%v)", *req, err)
+		return
+	}
+
+	metric := exporter.NewTPCnt(packet.GetOpMsg())
+	defer func() {
+		metric.SetWithLabels(err, map[string]string{exporter.Vol: mw.volname})
+	}()
+
+	packet, err = mw.sendToMetaPartition(mp, packet)
+	if err != nil {
+		log.LogErrorf("listObjectVersions: packet(%v) mp(%v) req(%v) err(%v)", packet, mp, *req, err)
+		return
+	}
+
+	status = parseStatus(packet.ResultCode)
+	if status != statusOK {
+		log.LogErrorf("listObjectVersions: packet(%v) mp(%v) req(%v) result(%v)", packet, mp, *req, packet.GetResultMsg())
+		return
+	}
+
+	resp := new(proto.ListObjectVersionsResponse)
+	if err = packet.UnmarshalData(resp); err != nil {
+		log.LogErrorf("listObjectVersions: packet(%v) mp(%v) req(%v) err(%v) PacketData(%v)", packet, mp, *req, err, string(packet.Data))
+		return
+	}
+	versions = resp.Versions
+
+	log.LogDebugf("listObjectVersions: packet(%v) mp(%v) req(%v) result(%v)", packet, mp, *req, packet.GetResultMsg())
+	return
+}
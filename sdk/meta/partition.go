@@ -17,6 +17,7 @@ package meta
 import (
 	"fmt"
 	"github.com/chubaofs/chubaofs/util/btree"
+	"github.com/chubaofs/chubaofs/util/log"
 )
 
 type MetaPartition struct {
@@ -67,6 +68,23 @@ func (mw *MetaWrapper) replaceOrInsertPartition(mp *MetaPartition) {
 	return
 }
 
+// deleteMergedPartitions removes the partitions missing in the view, which have been merged into
+// the previous ones.
+func (mw *MetaWrapper) deleteMergedPartitions(partitions []*MetaPartition) {
+	var ids = make(map[uint64]bool, len(partitions))
+	for _, mp := range partitions {
+		ids[mp.PartitionID] = true
+	}
+	mw.Lock()
+	defer mw.Unlock()
+	for id, mp := range mw.partitions {
+		if !ids[id] {
+			log.LogInfof("deleteMergedPartitions: mp(%v)", mp)
+			mw.deletePartition(mp)
+		}
+	}
+}
+
 func (mw *MetaWrapper) getPartitionByID(id uint64) *MetaPartition {
 	mw.RLock()
 	defer mw.RUnlock()
@@ -0,0 +1,160 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package meta
+
+import (
+	"os"
+	"sync/atomic"
+	"syscall"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+func (mw *MetaWrapper) hasDirQuota() bool {
+	return atomic.LoadInt32(&mw.dirQuotaCnt) > 0
+}
+
+// getInodeQuotaIds returns the IDs of the directory quotas the inode belongs to.
+func (mw *MetaWrapper) getInodeQuotaIds(mp *MetaPartition, inode uint64) []uint64 {
+	value, status, err := mw.getXAttr(mp, inode, proto.QuotaXAttrKey)
+	if err != nil || status != statusOK {
+		log.LogWarnf("getInodeQuotaIds: inode(%v) err(%v) status(%v)", inode, err, status)
+		return nil
+	}
+	return proto.ParseQuotaIds(value)
+}
+
+// ApplyDirQuota adds the directory quota to all of the inodes in the
+// directory tree of the root inode, and returns the number of the inodes.
+// The inodes created afterwards belong to the quota of their parents.
+func (mw *MetaWrapper) ApplyDirQuota(rootIno, quotaID uint64) (count int, err error) {
+	return mw.walkDirQuota(rootIno, func(ids []uint64) ([]uint64, bool) {
+		for _, id := range ids {
+			if id == quotaID {
+				return ids, false
+			}
+		}
+		return append(ids, quotaID), true
+	})
+}
+
+// RevokeDirQuota removes the directory quota from all of the inodes in the
+// directory tree of the root inode.
+func (mw *MetaWrapper) RevokeDirQuota(rootIno, quotaID uint64) (count int, err error) {
+	return mw.walkDirQuota(rootIno, func(ids []uint64) ([]uint64, bool) {
+		for i, id := range ids {
+			if id == quotaID {
+				return append(ids[:i], ids[i+1:]...), true
+			}
+		}
+		return ids, false
+	})
+}
+
+func (mw *MetaWrapper) walkDirQuota(rootIno uint64, update func(ids []uint64) ([]uint64, bool)) (count int, err error) {
+	queue := []proto.Dentry{{Inode: rootIno, Type: proto.Mode(os.ModeDir)}}
+	for len(queue) > 0 {
+		dentry := queue[0]
+		queue = queue[1:]
+		mp := mw.getPartitionByInode(dentry.Inode)
+		if mp == nil {
+			return count, syscall.ENOENT
+		}
+		ids, changed := update(mw.getInodeQuotaIds(mp, dentry.Inode))
+		if changed {
+			if err = mw.updateInodeQuotaIds(mp, dentry.Inode, ids); err != nil {
+				log.LogErrorf("walkDirQuota: inode(%v) quotaIds(%v) err(%v)", dentry.Inode, ids, err)
+				return
+			}
+		}
+		count++
+		if !proto.IsDir(dentry.Type) {
+			continue
+		}
+		var children []proto.Dentry
+		if children, err = mw.ReadDir_ll(dentry.Inode); err != nil {
+			return
+		}
+		queue = append(queue, children...)
+	}
+	return
+}
+
+func (mw *MetaWrapper) updateInodeQuotaIds(mp *MetaPartition, inode uint64, ids []uint64) error {
+	var (
+		status int
+		err    error
+	)
+	if len(ids) == 0 {
+		status, err = mw.removeXAttr(mp, inode, proto.QuotaXAttrKey)
+	} else {
+		status, err = mw.setXAttr(mp, inode, []byte(proto.QuotaXAttrKey), []byte(proto.FormatQuotaIds(ids)))
+	}
+	if err != nil {
+		return err
+	}
+	if status != statusOK {
+		return statusToErrno(status)
+	}
+	return nil
+}
+
+// addRenameQuotaItem moves the inode renamed to another directory from the
+// directory quotas of the source parent to the ones of the destination parent
+// in the rename transaction. A directory is not moved between the quotas,
+// since the quotas of the whole directory tree can not be changed in one
+// transaction. EXDEV is returned instead, like the project quotas of the local
+// file systems, so that the directory is copied by mv.
+func (mw *MetaWrapper) addRenameQuotaItem(tx *metaTx, srcParentMP *MetaPartition, srcParentID uint64,
+	dstParentMP *MetaPartition, dstParentID, inode uint64, mode uint32) error {
+	srcIds := mw.getInodeQuotaIds(srcParentMP, srcParentID)
+	dstIds := mw.getInodeQuotaIds(dstParentMP, dstParentID)
+	removed, added := diffQuotaIds(srcIds, dstIds), diffQuotaIds(dstIds, srcIds)
+	if len(removed) == 0 && len(added) == 0 {
+		return nil
+	}
+	if proto.IsDir(mode) {
+		return syscall.EXDEV
+	}
+	inodeMP := mw.getPartitionByInode(inode)
+	if inodeMP == nil {
+		return syscall.EAGAIN
+	}
+	// the quotas of the inode itself are kept
+	ids := append(diffQuotaIds(mw.getInodeQuotaIds(inodeMP, inode), removed), added...)
+	tx.addItem(inodeMP, &proto.TxItem{
+		Op:       proto.TxOpSetQuotaIds,
+		Inode:    inode,
+		QuotaIds: diffQuotaIds(ids, nil),
+	})
+	return nil
+}
+
+// diffQuotaIds returns the distinct IDs of a which are not in b.
+func diffQuotaIds(a, b []uint64) []uint64 {
+	excluded := make(map[uint64]bool, len(b))
+	for _, id := range b {
+		excluded[id] = true
+	}
+	ids := make([]uint64, 0, len(a))
+	for _, id := range a {
+		if !excluded[id] {
+			excluded[id] = true
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
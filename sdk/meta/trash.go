@@ -0,0 +1,302 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package meta
+
+import (
+	"encoding/json"
+	"os"
+	gopath "path"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// TrashEnabled returns true if the deleted files of the volume are moved to
+// the trash.
+func (mw *MetaWrapper) TrashEnabled() bool {
+	return atomic.LoadUint32(&mw.trashDays) > 0
+}
+
+func hideTrashDentry(children []proto.Dentry) []proto.Dentry {
+	for i, child := range children {
+		if child.Name == proto.TrashDirName {
+			return append(children[:i], children[i+1:]...)
+		}
+	}
+	return children
+}
+
+// trashDir returns the inode of the trash directory, which is created on the
+// first deletion if create is true.
+func (mw *MetaWrapper) trashDir(create bool) (uint64, error) {
+	if ino := atomic.LoadUint64(&mw.trashIno); ino != 0 {
+		return ino, nil
+	}
+	rootMP := mw.getPartitionByInode(proto.RootIno)
+	if rootMP == nil {
+		return 0, syscall.ENOENT
+	}
+	status, ino, _, err := mw.lookup(rootMP, proto.RootIno, proto.TrashDirName)
+	if err != nil || (status != statusOK && status != statusNoent) {
+		return 0, statusToErrno(status)
+	}
+	if status == statusNoent {
+		if !create {
+			return 0, syscall.ENOENT
+		}
+		info, err := mw.create(proto.RootIno, proto.TrashDirName, proto.Mode(os.ModeDir|0700), 0, 0, nil)
+		if err == syscall.EEXIST {
+			return mw.trashDir(false)
+		}
+		if err != nil {
+			return 0, err
+		}
+		ino = info.Inode
+	}
+	atomic.StoreUint64(&mw.trashIno, ino)
+	return ino, nil
+}
+
+// MoveToTrash_ll moves the file to the trash instead of deleting it if the
+// trash of the volume is enabled, and returns false if the file should be
+// deleted as usual. The directories, the files with hard links and the
+// files in the trash are not moved.
+func (mw *MetaWrapper) MoveToTrash_ll(parentID uint64, name string, path string) (moved bool, err error) {
+	if !mw.TrashEnabled() || proto.IsTrashDentry(parentID, name) {
+		return false, nil
+	}
+	parentMP := mw.getPartitionByInode(parentID)
+	if parentMP == nil {
+		return false, syscall.ENOENT
+	}
+	status, ino, mode, err := mw.lookup(parentMP, parentID, name)
+	if err != nil || status != statusOK {
+		return false, statusToErrno(status)
+	}
+	if proto.IsDir(mode) {
+		return false, nil
+	}
+	mp := mw.getPartitionByInode(ino)
+	if mp == nil {
+		return false, syscall.EAGAIN
+	}
+	status, info, err := mw.iget(mp, ino)
+	if err != nil || status != statusOK {
+		return false, statusToErrno(status)
+	}
+	if info.Nlink > 1 {
+		return false, nil
+	}
+	// the files locked by the object lock can not be deleted
+	locked, err := mw.IsObjectLocked_ll(ino)
+	if err != nil {
+		return false, err
+	}
+	if locked {
+		return false, syscall.EPERM
+	}
+	trashIno, err := mw.trashDir(true)
+	if err != nil {
+		log.LogErrorf("MoveToTrash_ll: get trash dir failed, err(%v)", err)
+		return false, err
+	}
+	if parentID == trashIno {
+		return false, nil
+	}
+
+	now := time.Now().Unix()
+	trashInfo := &proto.TrashInfo{
+		Inode:      ino,
+		ParentIno:  parentID,
+		Name:       name,
+		Path:       path,
+		DeleteTime: now,
+		Size:       info.Size,
+	}
+	value, err := json.Marshal(trashInfo)
+	if err != nil {
+		return false, err
+	}
+	status, err = mw.setXAttr(mp, ino, []byte(proto.TrashXAttrKey), value)
+	if err != nil || status != statusOK {
+		return false, statusToErrno(status)
+	}
+	if err = mw.Rename_ll(parentID, name, trashIno, proto.TrashEntryName(ino, now)); err != nil {
+		log.LogErrorf("MoveToTrash_ll: parentID(%v) name(%v) ino(%v) err(%v)", parentID, name, ino, err)
+		return false, err
+	}
+	log.LogDebugf("MoveToTrash_ll: parentID(%v) name(%v) ino(%v) path(%v)", parentID, name, ino, path)
+	return true, nil
+}
+
+// ListTrash_ll returns the deleted files in the trash of the volume.
+func (mw *MetaWrapper) ListTrash_ll() ([]*proto.TrashInfo, error) {
+	trashIno, err := mw.trashDir(false)
+	if err == syscall.ENOENT {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	children, err := mw.ReadDir_ll(trashIno)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		infos = make([]*proto.TrashInfo, 0, len(children))
+		inos  = make([]uint64, 0, len(children))
+	)
+	for _, child := range children {
+		ino, deleteTime, ok := proto.ParseTrashEntryName(child.Name)
+		if !ok || ino != child.Inode {
+			continue
+		}
+		infos = append(infos, &proto.TrashInfo{Inode: ino, DeleteTime: deleteTime})
+		inos = append(inos, ino)
+	}
+	xattrs, err := mw.BatchGetXAttr(inos, []string{proto.TrashXAttrKey})
+	if err != nil {
+		return nil, err
+	}
+	saved := make(map[uint64]*proto.TrashInfo, len(xattrs))
+	for _, xattr := range xattrs {
+		info := &proto.TrashInfo{}
+		if err := json.Unmarshal(xattr.Get(proto.TrashXAttrKey), info); err == nil {
+			saved[xattr.Inode] = info
+		}
+	}
+	for i, info := range infos {
+		if s, ok := saved[info.Inode]; ok {
+			infos[i] = s
+		}
+	}
+	return infos, nil
+}
+
+// getTrashEntry finds the entry of the deleted file in the trash.
+func (mw *MetaWrapper) getTrashEntry(ino uint64) (trashIno uint64, name string, err error) {
+	if trashIno, err = mw.trashDir(false); err != nil {
+		return
+	}
+	var children []proto.Dentry
+	if children, err = mw.ReadDir_ll(trashIno); err != nil {
+		return
+	}
+	for _, child := range children {
+		if i, _, ok := proto.ParseTrashEntryName(child.Name); ok && i == ino && child.Inode == ino {
+			return trashIno, child.Name, nil
+		}
+	}
+	return 0, "", syscall.ENOENT
+}
+
+// RestoreTrash_ll moves the deleted file back to the path, which is the
+// original path of the file if empty. The missing parent directories are
+// created, and it fails with EEXIST if the path exists.
+func (mw *MetaWrapper) RestoreTrash_ll(ino uint64, path string) (restored string, err error) {
+	trashIno, name, err := mw.getTrashEntry(ino)
+	if err != nil {
+		return
+	}
+	info := &proto.TrashInfo{}
+	xattr, err := mw.XAttrGet_ll(ino, proto.TrashXAttrKey)
+	if err != nil {
+		return
+	}
+	if err = json.Unmarshal(xattr.Get(proto.TrashXAttrKey), info); err != nil {
+		log.LogErrorf("RestoreTrash_ll: ino(%v) invalid trash info, err(%v)", ino, err)
+		return "", syscall.EINVAL
+	}
+
+	var (
+		parentID = info.ParentIno
+		fileName = info.Name
+	)
+	if path == "" {
+		path = info.Path
+	}
+	if path != "" {
+		dir, base := gopath.Split(gopath.Clean("/" + path))
+		if base == "" {
+			return "", syscall.EINVAL
+		}
+		if parentID, err = mw.mkdirAll(dir); err != nil {
+			return
+		}
+		fileName = base
+		restored = gopath.Join(dir, base)
+	}
+	if _, _, err = mw.Lookup_ll(parentID, fileName); err == nil {
+		return "", syscall.EEXIST
+	} else if err != syscall.ENOENT {
+		return
+	}
+	if err = mw.Rename_ll(trashIno, name, parentID, fileName); err != nil {
+		return
+	}
+	if e := mw.XAttrDel_ll(ino, proto.TrashXAttrKey); e != nil {
+		log.LogWarnf("RestoreTrash_ll: remove trash info of ino(%v) failed, err(%v)", ino, e)
+	}
+	log.LogDebugf("RestoreTrash_ll: ino(%v) parentID(%v) name(%v)", ino, parentID, fileName)
+	return
+}
+
+// mkdirAll looks up the directory path, and creates the missing directories.
+func (mw *MetaWrapper) mkdirAll(dir string) (uint64, error) {
+	ino := proto.RootIno
+	for _, name := range strings.Split(dir, "/") {
+		if name == "" {
+			continue
+		}
+		child, mode, err := mw.Lookup_ll(ino, name)
+		if err == syscall.ENOENT {
+			var info *proto.InodeInfo
+			info, err = mw.Create_ll(ino, name, proto.Mode(os.ModeDir|0755), 0, 0, nil)
+			if err == syscall.EEXIST {
+				child, mode, err = mw.Lookup_ll(ino, name)
+			} else if err == nil {
+				child, mode = info.Inode, info.Mode
+			}
+		}
+		if err != nil {
+			return 0, err
+		}
+		if !proto.IsDir(mode) {
+			return 0, syscall.ENOTDIR
+		}
+		ino = child
+	}
+	return ino, nil
+}
+
+// PurgeTrash_ll deletes the file from the trash permanently.
+func (mw *MetaWrapper) PurgeTrash_ll(ino uint64) error {
+	trashIno, name, err := mw.getTrashEntry(ino)
+	if err != nil {
+		return err
+	}
+	info, err := mw.Delete_ll(trashIno, name, false)
+	if err != nil {
+		return err
+	}
+	if info != nil {
+		return mw.Evict(ino)
+	}
+	return nil
+}
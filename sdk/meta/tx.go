@@ -0,0 +1,91 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package meta
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+var txSeq uint64
+
+// metaTx is a meta transaction under construction. The partition of the
+// transaction manager decides the result, and the others follow it.
+type metaTx struct {
+	info *proto.TxInfo
+	mps  []*MetaPartition // the transaction manager goes first
+}
+
+func (mw *MetaWrapper) newTx(tm *MetaPartition) *metaTx {
+	tx := &metaTx{
+		info: &proto.TxInfo{
+			TxID:    fmt.Sprintf("%v_%v_%v", mw.localIP, time.Now().UnixNano(), atomic.AddUint64(&txSeq, 1)),
+			TmID:    tm.PartitionID,
+			Members: make(map[uint64]string),
+		},
+	}
+	tx.addMember(tm)
+	return tx
+}
+
+func (tx *metaTx) addMember(mp *MetaPartition) {
+	if _, ok := tx.info.Members[mp.PartitionID]; ok {
+		return
+	}
+	hosts := make([]string, 0, len(mp.Members))
+	if mp.LeaderAddr != "" {
+		hosts = append(hosts, mp.LeaderAddr)
+	}
+	for _, addr := range mp.Members {
+		if addr != mp.LeaderAddr {
+			hosts = append(hosts, addr)
+		}
+	}
+	tx.info.Members[mp.PartitionID] = strings.Join(hosts, HostsSeparator)
+	tx.mps = append(tx.mps, mp)
+}
+
+func (tx *metaTx) addItem(mp *MetaPartition, item *proto.TxItem) {
+	item.PartitionID = mp.PartitionID
+	tx.info.Items = append(tx.info.Items, item)
+	tx.addMember(mp)
+}
+
+// runTx prepares every partition of the transaction and then commits it
+// through the transaction manager. The transaction is aborted if any of the
+// partitions fails to prepare, in which case nothing is changed.
+func (mw *MetaWrapper) runTx(tx *metaTx) error {
+	tm := tx.mps[0]
+	for _, mp := range tx.mps {
+		status, err := mw.txPrepare(mp, tx.info)
+		if err != nil || status != statusOK {
+			log.LogWarnf("runTx: prepare failed, tx(%v) mp(%v) status(%v) err(%v)", tx.info.TxID, mp, status, err)
+			mw.txAbort(tm, tx.info)
+			return statusToErrno(status)
+		}
+	}
+	status, err := mw.txCommit(tm, tx.info)
+	if err != nil || status != statusOK {
+		log.LogWarnf("runTx: commit failed, tx(%v) status(%v) err(%v)", tx.info.TxID, status, err)
+		return statusToErrno(status)
+	}
+	log.LogDebugf("runTx: tx(%v) items(%v)", tx.info.TxID, tx.info.Items)
+	return nil
+}
@@ -0,0 +1,168 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package meta
+
+import (
+	"syscall"
+
+	"github.com/chubaofs/chubaofs/proto"
+	"github.com/chubaofs/chubaofs/util/log"
+)
+
+// PutObjectVersion_ll records the version of the object in the partition of
+// the parent directory. It returns the stored version replaced by it, which
+// has the same version ID.
+func (mw *MetaWrapper) PutObjectVersion_ll(version *proto.ObjectVersionInfo) (*proto.ObjectVersionInfo, error) {
+	mp := mw.getPartitionByInode(version.ParentID)
+	if mp == nil {
+		log.LogErrorf("PutObjectVersion_ll: no such partition, parentID(%v)", version.ParentID)
+		return nil, syscall.ENOENT
+	}
+	replaced, status, err := mw.putObjectVersion(mp, version)
+	if err != nil || status != statusOK {
+		return nil, statusToErrno(status)
+	}
+	return replaced, nil
+}
+
+// DeleteObjectVersion_ll removes the version of the object and returns it.
+// The inode of the version is not released.
+func (mw *MetaWrapper) DeleteObjectVersion_ll(parentID uint64, name, versionID string) (*proto.ObjectVersionInfo, error) {
+	mp := mw.getPartitionByInode(parentID)
+	if mp == nil {
+		log.LogErrorf("DeleteObjectVersion_ll: no such partition, parentID(%v)", parentID)
+		return nil, syscall.ENOENT
+	}
+	deleted, status, err := mw.deleteObjectVersion(mp, parentID, name, versionID)
+	if err != nil || status != statusOK {
+		return nil, statusToErrno(status)
+	}
+	return deleted, nil
+}
+
+// PutObjectVersionWithDentry_ll records the version of the object, and points
+// the dentry of the object from the old inode to the inode of the version in
+// one transaction. The dentry is created if the old inode is 0, or removed if
+// the version is a delete marker.
+func (mw *MetaWrapper) PutObjectVersionWithDentry_ll(version *proto.ObjectVersionInfo, oldInode uint64, mode uint32) error {
+	mp := mw.getPartitionByInode(version.ParentID)
+	if mp == nil {
+		log.LogErrorf("PutObjectVersionWithDentry_ll: no such partition, parentID(%v)", version.ParentID)
+		return syscall.ENOENT
+	}
+	tx := mw.newTx(mp)
+	tx.addItem(mp, &proto.TxItem{
+		Op:       proto.TxOpPutObjectVersion,
+		ParentID: version.ParentID,
+		Name:     version.Name,
+		Version:  version,
+	})
+	var inode uint64
+	if !version.DeleteMarker {
+		inode = version.Inode
+	}
+	addObjectDentryItem(tx, mp, version.ParentID, version.Name, oldInode, inode, mode)
+	return mw.runTx(tx)
+}
+
+// DeleteObjectVersionWithDentry_ll removes the version of the object, and
+// points the dentry of the object from the old inode to the current inode in
+// one transaction, either of which is 0 if there is no such dentry. The inode
+// of the version is not released.
+func (mw *MetaWrapper) DeleteObjectVersionWithDentry_ll(parentID uint64, name, versionID string, oldInode, current uint64, mode uint32) error {
+	mp := mw.getPartitionByInode(parentID)
+	if mp == nil {
+		log.LogErrorf("DeleteObjectVersionWithDentry_ll: no such partition, parentID(%v)", parentID)
+		return syscall.ENOENT
+	}
+	tx := mw.newTx(mp)
+	tx.addItem(mp, &proto.TxItem{
+		Op:       proto.TxOpDeleteObjectVersion,
+		ParentID: parentID,
+		Name:     name,
+		Version:  &proto.ObjectVersionInfo{ParentID: parentID, Name: name, VersionID: versionID},
+	})
+	addObjectDentryItem(tx, mp, parentID, name, oldInode, current, mode)
+	return mw.runTx(tx)
+}
+
+// addObjectDentryItem adds the item which points the dentry from the old
+// inode to the inode. Neither of them is unlinked, which is kept by its
+// version.
+func addObjectDentryItem(tx *metaTx, mp *MetaPartition, parentID uint64, name string, oldInode, inode uint64, mode uint32) {
+	switch {
+	case oldInode == inode:
+	case inode == 0:
+		tx.addItem(mp, &proto.TxItem{
+			Op:       proto.TxOpDeleteDentry,
+			ParentID: parentID,
+			Name:     name,
+			Inode:    oldInode,
+		})
+	default:
+		tx.addItem(mp, &proto.TxItem{
+			Op:       proto.TxOpCreateDentry,
+			ParentID: parentID,
+			Name:     name,
+			Inode:    inode,
+			Type:     mode,
+			OldInode: oldInode,
+		})
+	}
+}
+
+// versionedInode returns true if the inode is kept by a version of the object.
+func (mw *MetaWrapper) versionedInode(mp *MetaPartition, parentID uint64, name string, inode uint64) (bool, error) {
+	versions, status, err := mw.getObjectVersions(mp, parentID, name)
+	if err != nil || status != statusOK {
+		return false, statusToErrno(status)
+	}
+	for _, version := range versions {
+		if !version.DeleteMarker && version.Inode == inode {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// GetObjectVersions_ll returns the versions of the object from the newest to
+// the oldest.
+func (mw *MetaWrapper) GetObjectVersions_ll(parentID uint64, name string) ([]*proto.ObjectVersionInfo, error) {
+	mp := mw.getPartitionByInode(parentID)
+	if mp == nil {
+		log.LogErrorf("GetObjectVersions_ll: no such partition, parentID(%v)", parentID)
+		return nil, syscall.ENOENT
+	}
+	versions, status, err := mw.getObjectVersions(mp, parentID, name)
+	if err != nil || status != statusOK {
+		return nil, statusToErrno(status)
+	}
+	return versions, nil
+}
+
+// ListObjectVersions_ll returns the versions of at most max objects in the
+// directory whose names are not less than the marker.
+func (mw *MetaWrapper) ListObjectVersions_ll(parentID uint64, marker string, max uint64) ([]*proto.ObjectVersionInfo, error) {
+	mp := mw.getPartitionByInode(parentID)
+	if mp == nil {
+		log.LogErrorf("ListObjectVersions_ll: no such partition, parentID(%v)", parentID)
+		return nil, syscall.ENOENT
+	}
+	versions, status, err := mw.listObjectVersions(mp, parentID, marker, max)
+	if err != nil || status != statusOK {
+		return nil, statusToErrno(status)
+	}
+	return versions, nil
+}
@@ -136,10 +136,33 @@ func (mw *MetaWrapper) updateVolStatInfo() (err error) {
 	}
 	atomic.StoreUint64(&mw.totalSize, info.TotalSize)
 	atomic.StoreUint64(&mw.usedSize, info.UsedSize)
+	atomic.StoreInt32(&mw.dirQuotaCnt, int32(info.DirQuotaCnt))
+	atomic.StoreUint32(&mw.trashDays, info.TrashDays)
 	log.LogInfof("VolStatInfo: info(%v)", info)
 	return
 }
 
+// updateSnapshotID resolves the ID of the snapshot to read.
+func (mw *MetaWrapper) updateSnapshotID() (err error) {
+	if mw.snapshot == "" || mw.snapshotID != 0 {
+		return
+	}
+	var snapshots []*proto.SnapshotInfo
+	if snapshots, err = mw.mc.AdminAPI().ListSnapshots(mw.volname); err != nil {
+		log.LogWarnf("updateSnapshotID: list snapshots fail: volume(%v) err(%v)", mw.volname, err)
+		return
+	}
+	for _, snap := range snapshots {
+		if snap.Name == mw.snapshot {
+			mw.snapshotID = snap.ID
+			log.LogInfof("updateSnapshotID: volume(%v) snapshot(%v) id(%v)", mw.volname, snap.Name, snap.ID)
+			return
+		}
+	}
+	log.LogErrorf("updateSnapshotID: snapshot not found: volume(%v) snapshot(%v)", mw.volname, mw.snapshot)
+	return proto.ErrSnapshotNotExists
+}
+
 func (mw *MetaWrapper) updateMetaPartitions() error {
 	view, err := mw.fetchVolumeView()
 	if err != nil {
@@ -172,6 +195,9 @@ func (mw *MetaWrapper) updateMetaPartitions() error {
 			rwPartitions = append(rwPartitions, mp)
 		}
 	}
+	if len(view.MetaPartitions) > 0 {
+		mw.deleteMergedPartitions(view.MetaPartitions)
+	}
 	mw.ossSecure = view.OSSSecure
 	mw.volCreateTime = view.CreateTime
 
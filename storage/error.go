@@ -33,6 +33,7 @@ var (
 	ExtentIsFullError         = errors.New("extent is full")
 	BrokenExtentError         = errors.New("extent has been broken")
 	BrokenDiskError           = errors.New("disk has broken")
+	CopyOnWriteError          = errors.New("extent can not be overwritten in place")
 )
 
 func NewParameterMismatchErr(msg string) (err error) {
@@ -0,0 +1,221 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package ec implements the systematic Reed-Solomon erasure code used by the
+// erasure-coded data partitions. The data is split into data shards, and the
+// parity shards are computed from them, so that any data shards of the same
+// number are enough to reconstruct all of the shards.
+package ec
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	// MaxShards is the max number of data and parity shards in total.
+	MaxShards = 256
+)
+
+var (
+	ErrInvalidShardNum = errors.New("invalid number of shards")
+	ErrShardSize       = errors.New("shards of different sizes")
+	ErrTooFewShards    = errors.New("too few shards to reconstruct")
+)
+
+// Encoder encodes and reconstructs the shards of a stripe.
+type Encoder struct {
+	dataShards   int
+	parityShards int
+	// The encoding matrix, the top rows of which is an identity matrix so
+	// that the data shards are kept as is.
+	matrix matrix
+	parity matrix
+}
+
+// New returns an encoder with the given number of data and parity shards.
+func New(dataShards, parityShards int) (*Encoder, error) {
+	if dataShards <= 0 || parityShards <= 0 || dataShards+parityShards > MaxShards {
+		return nil, ErrInvalidShardNum
+	}
+	total := dataShards + parityShards
+	vm := vandermondeMatrix(total, dataShards)
+	top, err := vm[:dataShards].invert()
+	if err != nil {
+		return nil, err
+	}
+	m := vm.multiply(top)
+	return &Encoder{
+		dataShards:   dataShards,
+		parityShards: parityShards,
+		matrix:       m,
+		parity:       m[dataShards:],
+	}, nil
+}
+
+// DataShards returns the number of data shards.
+func (e *Encoder) DataShards() int {
+	return e.dataShards
+}
+
+// ParityShards returns the number of parity shards.
+func (e *Encoder) ParityShards() int {
+	return e.parityShards
+}
+
+// Encode computes the parity shards from the data shards. The shards must
+// contain all of the data and parity shards of the same size.
+func (e *Encoder) Encode(shards [][]byte) error {
+	if len(shards) != e.dataShards+e.parityShards {
+		return ErrInvalidShardNum
+	}
+	size, err := shardSize(shards, false)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < e.parityShards; i++ {
+		out := shards[e.dataShards+i][:size]
+		for j := range out {
+			out[j] = 0
+		}
+		for j := 0; j < e.dataShards; j++ {
+			gfMulSlice(e.parity[i][j], shards[j], out)
+		}
+	}
+	return nil
+}
+
+// Reconstruct recomputes the missing shards, which are the ones of zero
+// length, from any available shards of the number of data shards. The
+// missing shards are allocated if the capacity is not enough.
+func (e *Encoder) Reconstruct(shards [][]byte) error {
+	if len(shards) != e.dataShards+e.parityShards {
+		return ErrInvalidShardNum
+	}
+	size, err := shardSize(shards, true)
+	if err != nil {
+		return err
+	}
+
+	var (
+		rows    = make(matrix, 0, e.dataShards)
+		present = make([][]byte, 0, e.dataShards)
+		missing bool
+	)
+	for i, shard := range shards {
+		if len(shard) == 0 {
+			missing = true
+			continue
+		}
+		if len(rows) < e.dataShards {
+			rows = append(rows, e.matrix[i])
+			present = append(present, shard)
+		}
+	}
+	if !missing {
+		return nil
+	}
+	if len(rows) < e.dataShards {
+		return ErrTooFewShards
+	}
+	decode, err := rows.invert()
+	if err != nil {
+		return err
+	}
+	for i := 0; i < e.dataShards; i++ {
+		if len(shards[i]) != 0 {
+			continue
+		}
+		shards[i] = allocShard(shards[i], size)
+		for j := 0; j < e.dataShards; j++ {
+			gfMulSlice(decode[i][j], present[j], shards[i])
+		}
+	}
+	for i := 0; i < e.parityShards; i++ {
+		out := shards[e.dataShards+i]
+		if len(out) != 0 {
+			continue
+		}
+		out = allocShard(out, size)
+		for j := 0; j < e.dataShards; j++ {
+			gfMulSlice(e.parity[i][j], shards[j], out)
+		}
+		shards[e.dataShards+i] = out
+	}
+	return nil
+}
+
+// Verify returns true if the parity shards match the data shards.
+func (e *Encoder) Verify(shards [][]byte) (bool, error) {
+	if len(shards) != e.dataShards+e.parityShards {
+		return false, ErrInvalidShardNum
+	}
+	size, err := shardSize(shards, false)
+	if err != nil {
+		return false, err
+	}
+	out := make([]byte, size)
+	for i := 0; i < e.parityShards; i++ {
+		for j := range out {
+			out[j] = 0
+		}
+		for j := 0; j < e.dataShards; j++ {
+			gfMulSlice(e.parity[i][j], shards[j], out)
+		}
+		for j, v := range out {
+			if shards[e.dataShards+i][j] != v {
+				return false, nil
+			}
+		}
+	}
+	return true, nil
+}
+
+// String returns the string format of the encoder.
+func (e *Encoder) String() string {
+	return fmt.Sprintf("RS(%v+%v)", e.dataShards, e.parityShards)
+}
+
+// shardSize returns the size of the shards, which must be all the same except
+// the missing ones if allowMissing is true.
+func shardSize(shards [][]byte, allowMissing bool) (size int, err error) {
+	for _, shard := range shards {
+		if len(shard) == 0 {
+			if allowMissing {
+				continue
+			}
+			return 0, ErrShardSize
+		}
+		if size == 0 {
+			size = len(shard)
+		} else if len(shard) != size {
+			return 0, ErrShardSize
+		}
+	}
+	if size == 0 {
+		return 0, ErrShardSize
+	}
+	return
+}
+
+func allocShard(shard []byte, size int) []byte {
+	if cap(shard) < size {
+		return make([]byte, size)
+	}
+	shard = shard[:size]
+	for i := range shard {
+		shard[i] = 0
+	}
+	return shard
+}
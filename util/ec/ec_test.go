@@ -0,0 +1,62 @@
+// Copyright 2018 The Chubao Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package ec
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+func TestEncoder_Reconstruct(t *testing.T) {
+	enc, err := New(6, 3)
+	if err != nil {
+		t.Fatalf("new encoder: %v", err)
+	}
+	shards := make([][]byte, 9)
+	for i := range shards {
+		shards[i] = make([]byte, 1024)
+		if i < 6 {
+			rand.Read(shards[i])
+		}
+	}
+	if err = enc.Encode(shards); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	if ok, err := enc.Verify(shards); err != nil || !ok {
+		t.Fatalf("verify: ok(%v) err(%v)", ok, err)
+	}
+
+	origin := make([][]byte, len(shards))
+	for i := range shards {
+		origin[i] = append([]byte{}, shards[i]...)
+	}
+	// lose one data shard and two parity shards
+	shards[1], shards[6], shards[8] = nil, nil, nil
+	if err = enc.Reconstruct(shards); err != nil {
+		t.Fatalf("reconstruct: %v", err)
+	}
+	for i := range shards {
+		if !bytes.Equal(shards[i], origin[i]) {
+			t.Fatalf("shard %v mismatch after reconstruction", i)
+		}
+	}
+
+	// lose four shards
+	shards[0], shards[2], shards[3], shards[7] = nil, nil, nil, nil
+	if err = enc.Reconstruct(shards); err != ErrTooFewShards {
+		t.Fatalf("expect error(%v) actual(%v)", ErrTooFewShards, err)
+	}
+}